@@ -0,0 +1,151 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/bethropolis/localgo/pkg/cli"
+	"github.com/bethropolis/localgo/pkg/config"
+	"github.com/bethropolis/localgo/pkg/help"
+	"github.com/bethropolis/localgo/pkg/send"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var (
+	benchto          string
+	benchip          string
+	benchfingerprint string
+	benchport        int
+	benchsize        string
+	benchtimeout     int
+)
+
+// benchOutput is what `bench --json` prints.
+type benchOutput struct {
+	Device         string  `json:"device"`
+	Address        string  `json:"address"`
+	Bytes          int64   `json:"bytes"`
+	Seconds        float64 `json:"seconds"`
+	BytesPerSecond float64 `json:"bytesPerSecond"`
+	BitsPerSecond  float64 `json:"bitsPerSecond"`
+}
+
+var benchCmd = &cobra.Command{
+	Use:          "bench",
+	Short:        "Measure transfer speed to another LocalGo device",
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		size, err := config.ParseSize(benchsize)
+		if err != nil {
+			return err
+		}
+		if size <= 0 {
+			return fmt.Errorf("--size must be greater than zero")
+		}
+		if benchip != "" && benchto != "" {
+			return fmt.Errorf("cannot use both --to and --ip")
+		}
+
+		device, err := findDevice(benchto, benchip, benchfingerprint, benchport, !cli.JSONOutput())
+		if err != nil {
+			return err
+		}
+		address := net.JoinHostPort(device.IP, strconv.Itoa(device.Port))
+		if !cli.JSONOutput() {
+			cli.PrintHeader(fmt.Sprintf("Speed test to %s (%s)", device.Alias, address))
+			cli.PrintInfo("Sending %s of generated data; the receiver discards it", cli.FormatBytes(size))
+		}
+
+		ctx := context.Background()
+		if benchtimeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, time.Duration(benchtimeout)*time.Second)
+			defer cancel()
+		}
+
+		data := &benchReader{remaining: size}
+		var result send.SendResult
+		err = send.SendToDevice(ctx, Cfg, device, nil, zap.S().Named("send"),
+			send.WithStream("localgo-bench.bin", data, size), send.WithBenchmark(), send.WithResult(&result))
+		finished := time.Now()
+		if errors.Is(err, send.ErrBenchmarkUnsupported) {
+			return err
+		}
+		if errors.Is(err, send.ErrDeclined) || err == nil && result.Count(send.FileRejected) > 0 {
+			err = fmt.Errorf("speed test declined by %s", device.Alias)
+		}
+		if err != nil {
+			return fmt.Errorf("speed test failed: %w", err)
+		}
+
+		// Time only the upload, not connecting or waiting for the receiver
+		// to accept.
+		elapsed := finished.Sub(data.started)
+		if data.started.IsZero() || elapsed <= 0 {
+			return fmt.Errorf("speed test finished without sending any data")
+		}
+		out := benchOutput{
+			Device:         device.Alias,
+			Address:        address,
+			Bytes:          size,
+			Seconds:        elapsed.Seconds(),
+			BytesPerSecond: float64(size) / elapsed.Seconds(),
+		}
+		out.BitsPerSecond = out.BytesPerSecond * 8
+
+		if cli.JSONOutput() {
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(out)
+		}
+		cli.PrintSuccess("Sent %s in %s", cli.FormatBytes(size), cli.FormatDuration(elapsed))
+		fmt.Printf("  Throughput: %s/s (%.1f Mbit/s)\n", cli.FormatBytes(int64(out.BytesPerSecond)), out.BitsPerSecond/1e6)
+		return nil
+	},
+}
+
+// benchReader yields remaining zero bytes and records when the first one was
+// read, which is when the upload starts.
+type benchReader struct {
+	remaining int64
+	started   time.Time
+}
+
+func (r *benchReader) Read(p []byte) (int, error) {
+	if r.started.IsZero() {
+		r.started = time.Now()
+	}
+	if r.remaining <= 0 {
+		return 0, io.EOF
+	}
+	if int64(len(p)) > r.remaining {
+		p = p[:r.remaining]
+	}
+	clear(p)
+	r.remaining -= int64(len(p))
+	return len(p), nil
+}
+
+func init() {
+	rootCmd.AddCommand(benchCmd)
+	benchCmd.Flags().StringVar(&benchto, "to", "", "Alias of the device to test (omit to pick interactively)")
+	benchCmd.Flags().StringVar(&benchip, "ip", "", "Device IP (with optional :port, skips discovery)")
+	benchCmd.Flags().StringVar(&benchfingerprint, "fingerprint", "", "Choose between devices sharing an alias by fingerprint prefix")
+	benchCmd.Flags().IntVar(&benchport, "port", 0, "Device port (default: from discovery, else the configured port)")
+	benchCmd.Flags().StringVar(&benchsize, "size", "100MB", "Amount of data to send, e.g. 500MB or 1GB")
+	benchCmd.Flags().IntVar(&benchtimeout, "timeout", 600, "Give up after this many seconds (0 = no limit)")
+
+	benchCmd.SetHelpFunc(func(cmd *cobra.Command, args []string) {
+		if h := help.GetCommandHelp("bench"); h != nil {
+			help.ShowCommandHelp(*h)
+		}
+	})
+}
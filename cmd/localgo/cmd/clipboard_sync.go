@@ -0,0 +1,165 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/bethropolis/localgo/pkg/cli"
+	"github.com/bethropolis/localgo/pkg/clipboard"
+	"github.com/bethropolis/localgo/pkg/config"
+	"github.com/bethropolis/localgo/pkg/help"
+	"github.com/bethropolis/localgo/pkg/model"
+	"github.com/bethropolis/localgo/pkg/ping"
+	"github.com/bethropolis/localgo/pkg/send"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var (
+	clipsyncto            string
+	clipsyncip            string
+	clipsynctofingerprint string
+	clipsyncfingerprint   string
+	clipsyncport          int
+	clipsyncimages        bool
+	clipsyncmaxsize       string
+	clipsyncmingap        time.Duration
+	clipsyncinterval      time.Duration
+)
+
+var clipboardSyncCmd = &cobra.Command{
+	Use:          "clipboard-sync",
+	Short:        "Send clipboard changes to a trusted device",
+	SilenceUsage: true,
+	Args:         cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if clipsyncfingerprint != "" && clipsynctofingerprint != "" {
+			return fmt.Errorf("cannot use both --fingerprint and --to-fingerprint")
+		}
+		if clipsynctofingerprint != "" {
+			if len(clipsynctofingerprint) < 4 {
+				return fmt.Errorf("--to-fingerprint needs at least 4 characters")
+			}
+			clipsyncfingerprint = clipsynctofingerprint
+		}
+		if clipsyncip != "" && clipsyncto != "" {
+			return fmt.Errorf("cannot use both --ip and --to")
+		}
+		if clipsyncip == "" && clipsyncto == "" && clipsyncfingerprint == "" {
+			return fmt.Errorf("no recipient: use --to, --to-fingerprint or --ip")
+		}
+		maxSize, err := config.ParseSize(clipsyncmaxsize)
+		if err != nil {
+			return fmt.Errorf("invalid --max-size: %w", err)
+		}
+		if !clipboard.Available() {
+			return fmt.Errorf("clipboard unavailable: no supported tool found (install xclip, xsel, wl-clipboard, or set clipboard_read_cmd)")
+		}
+		if clipsyncimages && !clipboard.ImageAvailable() {
+			if cmd.Flags().Changed("images") {
+				cli.PrintWarning("Images cannot be read from the clipboard here; only text will be sent")
+			}
+			clipsyncimages = false
+		}
+
+		// Check the recipient up front, so a typo or an untrusted device
+		// fails now rather than at the first copy.
+		syncer := &clipboardSync{}
+		if err := syncer.resolve(); err != nil {
+			return err
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+		defer stop()
+
+		what := "text"
+		if clipsyncimages {
+			what = "text and images"
+		}
+		cli.PrintInfo("Sending copied %s to %s (Ctrl+C to stop)", what, syncer.device.Alias)
+		watcher := clipboard.NewWatcher(clipboard.WatchOptions{
+			Images:       clipsyncimages,
+			MaxSize:      maxSize,
+			PollInterval: clipsyncinterval,
+			MinGap:       clipsyncmingap,
+		}, zap.S().Named("clipboard"))
+		watcher.Run(ctx, func(c clipboard.Content) { syncer.send(ctx, c) })
+		return nil
+	},
+}
+
+// clipboardSync sends clipboard content to one trusted device.
+type clipboardSync struct {
+	device *model.Device
+}
+
+// resolve looks up the recipient and checks that it is trusted. A device
+// given by IP is trusted by the certificate it presents over HTTPS.
+func (s *clipboardSync) resolve() error {
+	device, err := findDevice(clipsyncto, clipsyncip, clipsyncfingerprint, clipsyncport, !cli.Headless())
+	if err != nil {
+		return err
+	}
+	if clipsyncip != "" {
+		r := ping.Probe(context.Background(), device.IP, device.Port, model.ProtocolTypeHTTPS, 1, 5*time.Second)
+		if !r.Reachable() {
+			return fmt.Errorf("%s: cannot check its certificate over HTTPS: %s", clipsyncip, r.Error)
+		}
+		if err := r.VerifyFingerprint(clipsyncfingerprint); err != nil {
+			return fmt.Errorf("%s: %w", clipsyncip, err)
+		}
+		device.Fingerprint, device.Protocol = r.CertFingerprint, model.ProtocolTypeHTTPS
+	}
+	if device.Fingerprint == "" || !Cfg.IsTrusted(device.Fingerprint) {
+		return fmt.Errorf("%s is not a trusted device: add its fingerprint to trusted_fingerprints (localgo config edit) to sync your clipboard to it", device.Alias)
+	}
+	s.device = device
+	return nil
+}
+
+// send sends one clipboard change. When it fails, the device is looked up
+// again for the next one.
+func (s *clipboardSync) send(ctx context.Context, c clipboard.Content) {
+	if s.device == nil {
+		if err := s.resolve(); err != nil {
+			cli.PrintError("Clipboard not sent: %v", err)
+			return
+		}
+	}
+	name, data, what := "clipboard.txt", []byte(c.Text), "text"
+	if c.Image != nil {
+		name, data, what = "clipboard-"+time.Now().Format("20060102-150405")+".png", c.Image, "image"
+	}
+	err := send.SendToDevice(ctx, Cfg, s.device, nil, zap.S().Named("send"), send.WithInMemoryFile(name, data))
+	if ctx.Err() != nil {
+		return
+	}
+	if err != nil {
+		cli.PrintError("Clipboard %s not sent to %s: %v", what, s.device.Alias, err)
+		s.device = nil
+		return
+	}
+	cli.PrintSuccess("Sent clipboard %s (%s) to %s", what, cli.FormatBytes(int64(len(data))), s.device.Alias)
+}
+
+func init() {
+	rootCmd.AddCommand(clipboardSyncCmd)
+	clipboardSyncCmd.Flags().StringVar(&clipsyncto, "to", "", "Target device alias")
+	clipboardSyncCmd.Flags().StringVar(&clipsyncip, "ip", "", "Target device IP (with optional :port, skips discovery)")
+	clipboardSyncCmd.Flags().StringVar(&clipsynctofingerprint, "to-fingerprint", "", "Target device by certificate fingerprint prefix")
+	clipboardSyncCmd.Flags().StringVar(&clipsyncfingerprint, "fingerprint", "", "Fingerprint (or prefix) of the target, to choose between devices sharing an alias")
+	clipboardSyncCmd.Flags().IntVar(&clipsyncport, "port", 0, "Target device port")
+	clipboardSyncCmd.Flags().BoolVar(&clipsyncimages, "images", true, "Send copied images as well as text")
+	clipboardSyncCmd.Flags().StringVar(&clipsyncmaxsize, "max-size", "10MB", "Skip clipboard content larger than this")
+	clipboardSyncCmd.Flags().DurationVar(&clipsyncmingap, "min-gap", clipboard.DefaultMinGap, "Least time between two sends; the latest change is sent after it")
+	clipboardSyncCmd.Flags().DurationVar(&clipsyncinterval, "interval", clipboard.DefaultPollInterval, "How often to check the clipboard")
+
+	clipboardSyncCmd.SetHelpFunc(func(cmd *cobra.Command, args []string) {
+		if h := help.GetCommandHelp("clipboard-sync"); h != nil {
+			help.ShowCommandHelp(*h)
+		}
+	})
+}
@@ -1,13 +1,19 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"runtime"
 	"strconv"
 	"strings"
 
+	"github.com/bethropolis/localgo/pkg/cli"
+	"github.com/bethropolis/localgo/pkg/config"
 	"github.com/bethropolis/localgo/pkg/help"
+	"github.com/bethropolis/localgo/pkg/logging"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
@@ -17,81 +23,60 @@ var configCmd = &cobra.Command{
 	Short: "Manage LocalGo configuration",
 }
 
+var configFileOnly bool
+
 var configGetCmd = &cobra.Command{
-	Use:   "get <key>",
-	Short: "Get a config value",
-	Args:  cobra.ExactArgs(1),
+	Use:          "get <key>",
+	SilenceUsage: true,
+	Short:        "Get a config value",
+	Args:         cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		v := viper.New()
-		v.SetConfigName("config")
-		v.SetConfigType("yaml")
-		v.AddConfigPath("$HOME/.config/localgo/")
-		v.AddConfigPath("$HOME/.local/etc/localgo/")
+		key := strings.ToLower(args[0])
+		setting, ok := config.LookupSetting(key)
+		if !ok {
+			return fmt.Errorf("unknown key %q: see `localgo config list`", key)
+		}
 
-		if err := v.ReadInConfig(); err != nil {
-			if _, ok := err.(viper.ConfigFileNotFoundError); !ok {
-				return fmt.Errorf("failed to read config: %w", err)
-			}
+		if !configFileOnly {
+			fmt.Println(effectiveValue(setting))
+			return nil
 		}
 
-		key := strings.ToLower(args[0])
-		if !v.InConfig(key) && !v.IsSet(key) {
+		v, err := readConfigFile()
+		if err != nil {
+			return err
+		}
+		if !v.IsSet(key) {
 			return fmt.Errorf("key %q not found in config", key)
 		}
-
 		fmt.Println(v.GetString(key))
 		return nil
 	},
 }
 
 var configSetCmd = &cobra.Command{
-	Use:   "set <key> <value>",
-	Short: "Set a config value",
-	Args:  cobra.ExactArgs(2),
+	Use:          "set <key> <value>",
+	SilenceUsage: true,
+	Short:        "Set a config value",
+	Args:         cobra.ExactArgs(2),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		v := viper.New()
-		v.SetConfigName("config")
-		v.SetConfigType("yaml")
-		v.AddConfigPath("$HOME/.config/localgo/")
-		v.AddConfigPath("$HOME/.local/etc/localgo/")
-
-		if err := v.ReadInConfig(); err != nil {
-			if _, ok := err.(viper.ConfigFileNotFoundError); !ok {
-				return fmt.Errorf("failed to read config: %w", err)
-			}
-		}
-
 		key := strings.ToLower(args[0])
-
-		existingVal := v.Get(key)
-		switch existingVal.(type) {
-		case int, int64:
-			val, err := strconv.Atoi(args[1])
-			if err != nil {
-				return fmt.Errorf("invalid integer value %q: %w", args[1], err)
-			}
-			v.Set(key, val)
-		case bool:
-			val, err := strconv.ParseBool(args[1])
-			if err != nil {
-				return fmt.Errorf("invalid boolean value %q: %w", args[1], err)
-			}
-			v.Set(key, val)
-		case float64:
-			val, err := strconv.ParseFloat(args[1], 64)
-			if err != nil {
-				return fmt.Errorf("invalid float value %q: %w", args[1], err)
-			}
-			v.Set(key, val)
-		default:
-			v.Set(key, args[1])
+		setting, ok := config.LookupSetting(key)
+		if !ok {
+			return fmt.Errorf("unknown key %q: see `localgo config list`", key)
+		}
+		val, err := setting.Parse(args[1])
+		if err != nil {
+			return err
 		}
 
-		configPath := v.ConfigFileUsed()
-		if configPath == "" {
-			configPath = os.ExpandEnv("$HOME/.config/localgo/config.yaml")
+		v, err := readConfigFile()
+		if err != nil {
+			return err
 		}
+		v.Set(key, val)
 
+		configPath := configFilePath(v)
 		if err := os.MkdirAll(filepath.Dir(configPath), 0700); err != nil {
 			return fmt.Errorf("failed to create config directory: %w", err)
 		}
@@ -101,6 +86,9 @@ var configSetCmd = &cobra.Command{
 		}
 
 		fmt.Printf("Set %s = %q in %s\n", key, args[1], configPath)
+		if _, ok := os.LookupEnv(setting.EnvVar()); ok {
+			cli.PrintWarning("%s is set and overrides this value", setting.EnvVar())
+		}
 		return nil
 	},
 }
@@ -109,31 +97,78 @@ var configListCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List all config values",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		v := viper.New()
-		v.SetConfigName("config")
-		v.SetConfigType("yaml")
-		v.AddConfigPath("$HOME/.config/localgo/")
-		v.AddConfigPath("$HOME/.local/etc/localgo/")
+		v, err := readConfigFile()
+		if err != nil {
+			return err
+		}
 
-		if err := v.ReadInConfig(); err != nil {
-			if _, ok := err.(viper.ConfigFileNotFoundError); !ok {
-				return fmt.Errorf("failed to read config: %w", err)
+		if configFileOnly {
+			if len(v.AllSettings()) == 0 {
+				fmt.Println("(no config file found)")
+				return nil
 			}
+			for _, key := range v.AllKeys() {
+				val := v.Get(key)
+				if val == nil {
+					continue
+				}
+				fmt.Printf("%-25s %v\n", key, val)
+			}
+			return nil
 		}
 
-		settings := v.AllSettings()
-		if len(settings) == 0 {
-			fmt.Println("(no config file found)")
-			return nil
+		fmt.Printf("%-25s %-40s %s\n", "KEY", "VALUE", "SOURCE")
+		for _, setting := range config.Settings() {
+			val := effectiveValue(setting)
+			if val == "" {
+				val = "-"
+			}
+			fmt.Printf("%-25s %-40s %s\n", setting.Key, val, settingSource(setting, v))
 		}
+		return nil
+	},
+}
 
-		for _, key := range v.AllKeys() {
-			val := v.Get(key)
-			if val == nil {
-				continue
+var configEditCmd = &cobra.Command{
+	Use:          "edit",
+	SilenceUsage: true,
+	Short:        "Edit the config file in $EDITOR",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		v, err := readConfigFile()
+		if err != nil {
+			return err
+		}
+		configPath := configFilePath(v)
+		if _, err := os.Stat(configPath); os.IsNotExist(err) {
+			if err := os.MkdirAll(filepath.Dir(configPath), 0700); err != nil {
+				return fmt.Errorf("failed to create config directory: %w", err)
 			}
-			fmt.Printf("%-25s %v\n", key, val)
+			header := "# LocalGo configuration. Run `localgo config list` to see every key.\n"
+			if err := os.WriteFile(configPath, []byte(header), 0600); err != nil {
+				return fmt.Errorf("failed to create config file: %w", err)
+			}
+		}
+
+		editor := editorCommand()
+		c := exec.Command(editor[0], append(editor[1:], configPath)...)
+		c.Stdin, c.Stdout, c.Stderr = os.Stdin, os.Stdout, os.Stderr
+		if err := c.Run(); err != nil {
+			return fmt.Errorf("editor %s failed: %w", editor[0], err)
+		}
+
+		edited := viper.New()
+		edited.SetConfigFile(configPath)
+		edited.SetConfigType("yaml")
+		if err := edited.ReadInConfig(); err != nil {
+			return fmt.Errorf("%s is not valid YAML: %w", configPath, err)
+		}
+		if errs := config.ValidateFile(edited); len(errs) > 0 {
+			for _, err := range errs {
+				cli.PrintError("%v", err)
+			}
+			return fmt.Errorf("%s has %d problem(s): run `localgo config edit` again to fix them", configPath, len(errs))
 		}
+		cli.PrintSuccess("Saved %s", configPath)
 		return nil
 	},
 }
@@ -142,33 +177,128 @@ var configPathCmd = &cobra.Command{
 	Use:   "path",
 	Short: "Show config file path",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		v := viper.New()
+		v, err := readConfigFile()
+		if err != nil {
+			return err
+		}
+		fmt.Println(configFilePath(v))
+		return nil
+	},
+}
+
+// readConfigFile reads config.yaml alone, without defaults or environment
+// overrides. A missing file is not an error.
+func readConfigFile() (*viper.Viper, error) {
+	v := viper.New()
+	v.SetConfigType("yaml")
+	if cfgFile != "" {
+		v.SetConfigFile(cfgFile)
+	} else {
 		v.SetConfigName("config")
-		v.SetConfigType("yaml")
-		v.AddConfigPath("$HOME/.config/localgo/")
-		v.AddConfigPath("$HOME/.local/etc/localgo/")
+		for _, dir := range config.ConfigPaths() {
+			v.AddConfigPath(dir)
+		}
+	}
 
-		if err := v.ReadInConfig(); err != nil {
-			if _, ok := err.(viper.ConfigFileNotFoundError); !ok {
-				return fmt.Errorf("failed to read config: %w", err)
-			}
+	if err := v.ReadInConfig(); err != nil {
+		var notFound viper.ConfigFileNotFoundError
+		if !errors.As(err, &notFound) && !errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("failed to read config: %w", err)
+		}
+	}
+	return v, nil
+}
+
+// configFilePath returns the file v was read from, or where a new config
+// file is created.
+func configFilePath(v *viper.Viper) string {
+	if path := v.ConfigFileUsed(); path != "" {
+		return path
+	}
+	return config.DefaultConfigFile()
+}
+
+// settingFlags maps settings to the global flags that override them.
+var settingFlags = map[string]string{
+	"headless":   "headless",
+	"log_level":  "log-level",
+	"log_file":   "log-file",
+	"log_target": "log-target",
+}
+
+// effectiveValue returns the value a command would use for setting, after
+// defaults, the config file, environment variables and global flags.
+func effectiveValue(setting config.Setting) string {
+	if val, ok := setting.Effective(Cfg); ok {
+		if list, isList := val.([]string); isList {
+			return strings.Join(list, ",")
 		}
+		return fmt.Sprint(val)
+	}
 
-		path := v.ConfigFileUsed()
-		if path == "" {
-			fmt.Println("$HOME/.config/localgo/config.yaml")
-		} else {
-			fmt.Println(path)
+	opts := logOptions(Cfg != nil && Cfg.Headless)
+	switch setting.Key {
+	case "log_level":
+		if opts.Level == "" {
+			return "info"
 		}
-		return nil
-	},
+		return opts.Level
+	case "log_levels":
+		return opts.Levels
+	case "log_target":
+		if opts.Target == "" {
+			return logging.TargetFile
+		}
+		return opts.Target
+	case "log_file":
+		if opts.File == "" {
+			return logging.DefaultLogFile()
+		}
+		return opts.File
+	case "log_max_size":
+		return strconv.Itoa(opts.MaxSizeMB)
+	case "log_max_backups":
+		return strconv.Itoa(opts.MaxBackups)
+	}
+	return ViperCfg.GetString(setting.Key)
+}
+
+// settingSource reports where the effective value of setting comes from.
+func settingSource(setting config.Setting, file *viper.Viper) string {
+	if flag, ok := settingFlags[setting.Key]; ok && rootCmd.PersistentFlags().Changed(flag) {
+		return "flag --" + flag
+	}
+	if _, ok := os.LookupEnv(setting.EnvVar()); ok {
+		return "env " + setting.EnvVar()
+	}
+	if file.IsSet(setting.Key) {
+		return "file"
+	}
+	return "default"
+}
+
+// editorCommand returns the user's editor from $VISUAL or $EDITOR, split
+// into the program and its arguments.
+func editorCommand() []string {
+	for _, env := range []string{"VISUAL", "EDITOR"} {
+		if fields := strings.Fields(os.Getenv(env)); len(fields) > 0 {
+			return fields
+		}
+	}
+	if runtime.GOOS == "windows" {
+		return []string{"notepad"}
+	}
+	return []string{"vi"}
 }
 
 func init() {
 	configCmd.AddCommand(configGetCmd)
 	configCmd.AddCommand(configSetCmd)
 	configCmd.AddCommand(configListCmd)
+	configCmd.AddCommand(configEditCmd)
 	configCmd.AddCommand(configPathCmd)
+	configGetCmd.Flags().BoolVar(&configFileOnly, "file", false, "Show the value stored in the config file, ignoring overrides")
+	configListCmd.Flags().BoolVar(&configFileOnly, "file", false, "List only the values stored in the config file")
 	configCmd.SetHelpFunc(func(cmd *cobra.Command, args []string) {
 		if h := help.GetCommandHelp("config"); h != nil {
 			help.ShowCommandHelp(*h)
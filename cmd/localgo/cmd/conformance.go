@@ -0,0 +1,135 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/bethropolis/localgo/pkg/cli"
+	"github.com/bethropolis/localgo/pkg/conformance"
+	"github.com/bethropolis/localgo/pkg/help"
+	"github.com/bethropolis/localgo/pkg/model"
+	"github.com/bethropolis/localgo/pkg/network"
+	"github.com/charmbracelet/huh/spinner"
+	"github.com/spf13/cobra"
+)
+
+var (
+	conformancetarget   string
+	conformanceuseHTTP  bool
+	conformancetransfer bool
+	conformancepin      string
+	conformancetimeout  time.Duration
+)
+
+var conformanceCmd = &cobra.Command{
+	Use:          "conformance",
+	Short:        "Check a device against the LocalSend v2 protocol",
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if conformancetarget == "" {
+			return fmt.Errorf("--target is required")
+		}
+		device, err := parseDeviceAddress(conformancetarget, 0)
+		if err != nil {
+			return err
+		}
+		protocol := model.ProtocolTypeHTTPS
+		if conformanceuseHTTP {
+			protocol = model.ProtocolTypeHTTP
+		}
+		baseURL := fmt.Sprintf("%s://%s", protocol, network.URLHost(device.IP, device.Port))
+
+		self := Cfg.ToInfoDto()
+		register := Cfg.ToRegisterDto()
+		self.Port, self.Protocol = register.Port, register.Protocol
+		opts := conformance.Options{
+			Self:     self,
+			PIN:      conformancepin,
+			Transfer: conformancetransfer,
+			Timeout:  conformancetimeout,
+		}
+
+		var results []conformance.Result
+		run := func() { results = conformance.Run(context.Background(), baseURL, opts) }
+		if cli.JSONOutput() || cli.Headless() {
+			run()
+		} else {
+			title := "Running checks..."
+			if conformancetransfer {
+				title = "Running checks (accept the test file on the device)..."
+			}
+			_ = spinner.New().Title(title).Action(run).Run()
+		}
+
+		if cli.JSONOutput() {
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			if err := encoder.Encode(results); err != nil {
+				return err
+			}
+		} else {
+			printConformance(baseURL, results)
+		}
+
+		failed := 0
+		for _, r := range results {
+			if r.Status == conformance.StatusFail {
+				failed++
+			}
+		}
+		if failed > 0 {
+			return fmt.Errorf("%d check(s) failed", failed)
+		}
+		return nil
+	},
+}
+
+// printConformance prints each result with what the protocol requires of
+// violations, then a one-line verdict.
+func printConformance(baseURL string, results []conformance.Result) {
+	cli.PrintHeader(fmt.Sprintf("Conformance of %s", baseURL))
+	warnings := 0
+	for _, r := range results {
+		line := fmt.Sprintf("%-28s %s", r.Check, r.Message)
+		switch r.Status {
+		case conformance.StatusOK:
+			cli.PrintSuccess("%s", line)
+		case conformance.StatusSkip:
+			cli.PrintInfo("%s", line)
+		case conformance.StatusWarn:
+			warnings++
+			cli.PrintWarning("%s", line)
+		default:
+			cli.PrintError("%s", line)
+		}
+		if r.Spec != "" {
+			fmt.Printf("    expected: %s\n", r.Spec)
+		}
+	}
+	fmt.Println()
+	if !conformance.Failed(results) {
+		if warnings > 0 {
+			cli.PrintWarning("No violations, %d warning(s)", warnings)
+		} else {
+			cli.PrintSuccess("No violations found")
+		}
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(conformanceCmd)
+	conformanceCmd.Flags().StringVar(&conformancetarget, "target", "", "Device to check: IP address or hostname, with an optional :port")
+	conformanceCmd.Flags().BoolVar(&conformanceuseHTTP, "http", false, "Use HTTP instead of HTTPS")
+	conformanceCmd.Flags().BoolVar(&conformancetransfer, "transfer", false, "Also send a small test file, which the device may ask its user to accept")
+	conformanceCmd.Flags().StringVar(&conformancepin, "pin", "", "PIN for the test transfer")
+	conformanceCmd.Flags().DurationVar(&conformancetimeout, "timeout", 5*time.Second, "How long each request waits")
+
+	conformanceCmd.SetHelpFunc(func(cmd *cobra.Command, args []string) {
+		if h := help.GetCommandHelp("conformance"); h != nil {
+			help.ShowCommandHelp(*h)
+		}
+	})
+}
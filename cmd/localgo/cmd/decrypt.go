@@ -0,0 +1,153 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/bethropolis/localgo/pkg/cli"
+	"github.com/bethropolis/localgo/pkg/crypto"
+	"github.com/bethropolis/localgo/pkg/help"
+	"github.com/bethropolis/localgo/pkg/storage"
+	"github.com/spf13/cobra"
+)
+
+var (
+	keygenoutput    string
+	decryptidentity string
+	decryptoutput   string
+)
+
+var keygenCmd = &cobra.Command{
+	Use:          "keygen",
+	Short:        "Create a key pair for encrypting received files at rest",
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// stdout carries the key alone, for $(localgo keygen -o ...).
+		cli.SetPrintOutput(os.Stderr)
+		id, err := crypto.GenerateIdentity()
+		if err != nil {
+			return err
+		}
+		text := fmt.Sprintf("# created: %s\n# recipient: %s\n%s\n", time.Now().Format(time.RFC3339), id.Recipient(), id)
+		if keygenoutput == "" {
+			fmt.Print(text)
+			return nil
+		}
+		// Never replace an identity: files encrypted to it could no longer be read.
+		f, err := os.OpenFile(keygenoutput, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if err != nil {
+			return fmt.Errorf("failed to create identity file: %w", err)
+		}
+		if _, err := f.WriteString(text); err != nil {
+			f.Close()
+			return fmt.Errorf("failed to write identity file: %w", err)
+		}
+		if err := f.Close(); err != nil {
+			return fmt.Errorf("failed to write identity file: %w", err)
+		}
+		cli.PrintSuccess("Identity written to %s; keep it safe and off the receiving machine", keygenoutput)
+		fmt.Println(id.Recipient())
+		return nil
+	},
+}
+
+var decryptCmd = &cobra.Command{
+	Use:          "decrypt FILE...",
+	Short:        "Decrypt files received with encryption at rest",
+	Args:         cobra.MinimumNArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if decryptidentity == "" {
+			return fmt.Errorf("--identity is required")
+		}
+		if decryptoutput != "" && len(args) > 1 {
+			return fmt.Errorf("--output can only be used with a single file")
+		}
+		id, err := readIdentity(decryptidentity)
+		if err != nil {
+			return err
+		}
+		for _, path := range args {
+			out, err := decryptFile(path, decryptoutput, id)
+			if err != nil {
+				return fmt.Errorf("%s: %w", path, err)
+			}
+			if out != "-" {
+				cli.PrintSuccess("Decrypted %s to %s", path, out)
+			}
+		}
+		return nil
+	},
+}
+
+// readIdentity reads the identity in path, a file written by keygen. Lines
+// starting with # are comments.
+func readIdentity(path string) (*crypto.Identity, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read identity: %w", err)
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		return crypto.ParseIdentity(line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read identity: %w", err)
+	}
+	return nil, fmt.Errorf("no identity found in %s", path)
+}
+
+// decryptFile decrypts path to output, or next to it without the encrypted
+// extension, never replacing an existing file. Output "-" is stdout. It
+// returns where the plaintext was written; nothing is left behind if the
+// file turns out to be damaged.
+func decryptFile(path, output string, id *crypto.Identity) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	plain, err := crypto.NewDecryptReader(f, id)
+	if err != nil {
+		return "", err
+	}
+	if output == "-" {
+		_, err := io.Copy(os.Stdout, plain)
+		return output, err
+	}
+	if output == "" {
+		name := strings.TrimSuffix(filepath.Base(path), crypto.EncryptedExt)
+		output = storage.ResolveDuplicateFilename(filepath.Dir(path), name)
+	}
+	if err := storage.SaveStreamToFile(plain, output, nil); err != nil {
+		return "", err
+	}
+	return output, nil
+}
+
+func init() {
+	keygenCmd.Flags().StringVarP(&keygenoutput, "output", "o", "", "Write the identity to this file (default: stdout)")
+	decryptCmd.Flags().StringVarP(&decryptidentity, "identity", "i", "", "Identity file written by localgo keygen")
+	decryptCmd.Flags().StringVarP(&decryptoutput, "output", "o", "", "Write the plaintext here (- = stdout; default: next to the file, without .lgenc)")
+
+	for _, c := range []*cobra.Command{keygenCmd, decryptCmd} {
+		name := c.Name()
+		c.SetHelpFunc(func(cmd *cobra.Command, args []string) {
+			if h := help.GetCommandHelp(name); h != nil {
+				help.ShowCommandHelp(*h)
+			}
+		})
+		rootCmd.AddCommand(c)
+	}
+}
@@ -3,7 +3,9 @@ package cmd
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"slices"
+	"strconv"
 	"strings"
 	"time"
 
@@ -14,107 +16,202 @@ import (
 	"github.com/charmbracelet/huh/spinner"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/spf13/cobra"
+	"go.uber.org/zap"
 )
 
 var (
-	devicesjsonOutput bool
-	devicesProbe      bool
+	devicesProbe bool
+	devicesCache bool
+	devicesPort  int
 )
 
+// deviceEntry is one device listed by `devices`, with how this machine
+// regards it.
+type deviceEntry struct {
+	*model.Device
+	Favorite bool `json:"favorite"`
+	Trusted  bool `json:"trusted"`
+}
+
 var devicesCmd = &cobra.Command{
 	Use:   "devices",
-	Short: "Show recently discovered devices on the network",
+	Short: "Show known devices and whether they are online",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		peerCache := discovery.NewPeerCache(nil)
-		peers := peerCache.GetPeers()
-
-		if len(peers) == 0 {
-			cli.PrintInfo("No devices in local cache. Run 'localgo discover' or 'localgo scan' to find devices.")
-			return nil
+		source := "server"
+		var peers []*model.Device
+		if devicesCache || devicesProbe {
+			source = "cache"
+		} else {
+			port := devicesPort
+			if port == 0 {
+				port = Cfg.Port
+			}
+			if err := adminRequest(http.MethodGet, port, "/v1/devices", nil, &peers); err != nil {
+				zap.S().Debugf("Reading devices from the running server failed, using the peer cache: %v", err)
+				source = "cache"
+			}
 		}
 
-		if devicesProbe {
-			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
-			defer cancel()
-
-			_ = spinner.New().
-				Title("Probing cached devices...").
-				Action(func() {
-					discovery.ProbeCached(ctx, peerCache, func(d *model.Device) {}, nil)
-				}).
-				Run()
-
+		if source == "cache" {
+			peerCache := discovery.NewPeerCache(nil)
 			peers = peerCache.GetPeers()
-		}
-
-		if devicesjsonOutput {
-			return displayDevices(peers, true, false, "cache")
+			if devicesProbe && len(peers) > 0 {
+				ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+				defer cancel()
+				probe := func() {
+					discovery.ProbeCached(ctx, peerCache, func(d *model.Device) {}, nil)
+				}
+				if cli.MachineOutput() || cli.Headless() {
+					probe()
+				} else {
+					_ = spinner.New().Title("Probing cached devices...").Action(probe).Run()
+				}
+				peers = peerCache.GetPeers()
+			}
 		}
 
 		if Cfg != nil && Cfg.Private {
 			peers = anonymizeDeviceSlice(peers)
 		}
-
-		slices.SortFunc(peers, func(a, b *model.Device) int {
-			return b.GetLastSeen().Compare(a.GetLastSeen())
-		})
-
-		titleStyle := cli.HeaderStyle.Padding(0, 1).MarginBottom(1)
-		headerStyle := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("39"))
-		rowStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("255"))
-		mutedStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("242"))
-
-		fmt.Println(titleStyle.Render(cli.IconDevice+"  Recently Discovered Devices") + "\n")
-
-		colWidths := []int{18, 16, 10, 12, 16}
-
-		fmt.Printf("%s  %s  %s  %s  %s\n",
-			padRight(headerStyle.Render("ALIAS"), colWidths[0]),
-			padRight(headerStyle.Render("IP ADDRESS"), colWidths[1]),
-			padRight(headerStyle.Render("PORT"), colWidths[2]),
-			padRight(headerStyle.Render("TYPE"), colWidths[3]),
-			padRight(headerStyle.Render("LAST SEEN"), colWidths[4]),
-		)
-		fmt.Println(mutedStyle.Render(strings.Repeat("-", 80)))
-
-		now := time.Now()
+		// A device counts as online for as long as discovery keeps it.
+		online := discovery.DefaultServiceConfig().DeviceTimeout
+		entries := make([]deviceEntry, 0, len(peers))
 		for _, d := range peers {
-			lastSeenStr := "Unknown"
-			if !d.GetLastSeen().IsZero() {
-				diff := now.Sub(d.GetLastSeen())
-				if diff < 1*time.Minute {
-					lastSeenStr = cli.SuccessStyle.Render("Online")
-				} else if diff < 1*time.Hour {
-					lastSeenStr = fmt.Sprintf("%dm ago", int(diff.Minutes()))
-				} else if diff < 24*time.Hour {
-					lastSeenStr = fmt.Sprintf("%dh ago", int(diff.Hours()))
-				} else {
-					lastSeenStr = fmt.Sprintf("%dd ago", int(diff.Hours()/24))
+			d.Available = !d.GetLastSeen().IsZero() && !d.IsStale(online)
+			entries = append(entries, deviceEntry{Device: d, Favorite: Cfg.IsFavorite(d.Fingerprint), Trusted: Cfg.IsTrusted(d.Fingerprint)})
+		}
+		slices.SortStableFunc(entries, func(a, b deviceEntry) int {
+			if a.Favorite != b.Favorite {
+				if a.Favorite {
+					return -1
 				}
+				return 1
 			}
+			return b.GetLastSeen().Compare(a.GetLastSeen())
+		})
 
-			deviceTypeStr := string(d.DeviceType)
-			if len(deviceTypeStr) > 0 {
-				deviceTypeStr = strings.ToUpper(deviceTypeStr[:1]) + deviceTypeStr[1:]
+		writer := cli.NewOutputWriter(cli.Format())
+		switch writer.Format() {
+		case cli.FormatJSON, cli.FormatYAML:
+			result := map[string]interface{}{
+				"devices":   entries,
+				"count":     len(entries),
+				"source":    source,
+				"timestamp": time.Now().Format(time.RFC3339),
 			}
-
-			fmt.Printf("%s  %s  %s  %s  %s\n",
-				padRight(rowStyle.Render(cli.TruncateString(d.Alias, 16)), colWidths[0]),
-				padRight(rowStyle.Render(d.IP), colWidths[1]),
-				padRight(rowStyle.Render(fmt.Sprintf("%d", d.Port)), colWidths[2]),
-				padRight(rowStyle.Render(deviceTypeStr), colWidths[3]),
-				padRight(lastSeenStr, colWidths[4]),
-			)
+			if writer.Format() == cli.FormatYAML {
+				return writer.WriteYAML(result)
+			}
+			return writer.WriteJSON(result)
+		case cli.FormatCSV:
+			rows := make([][]string, len(entries))
+			for i, d := range entries {
+				rows[i] = append(cli.DeviceCSVRow(d.Device), strconv.FormatBool(d.Available), strconv.FormatBool(d.Favorite), strconv.FormatBool(d.Trusted))
+			}
+			return writer.WriteCSV(append(slices.Clone(cli.DeviceCSVHeader), "online", "favorite", "trusted"), rows)
+		case cli.FormatTemplate:
+			items := make([]any, len(entries))
+			for i, d := range entries {
+				items[i] = d
+			}
+			return writer.WriteTemplate(items...)
 		}
 
+		if len(entries) == 0 {
+			if source == "server" {
+				cli.PrintInfo("The running server has not seen any devices yet.")
+			} else {
+				cli.PrintInfo("No devices in local cache. Run 'localgo discover' or 'localgo scan' to find devices.")
+			}
+			return nil
+		}
+		printDeviceEntries(entries, source)
 		return nil
 	},
 }
 
+// printDeviceEntries prints the devices as a table.
+func printDeviceEntries(entries []deviceEntry, source string) {
+	titleStyle := cli.HeaderStyle.Padding(0, 1).MarginBottom(1)
+	headerStyle := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("39"))
+	rowStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("255"))
+	mutedStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("242"))
+
+	title := "Devices Seen by the Running Server"
+	if source == "cache" {
+		title = "Recently Discovered Devices"
+	}
+	fmt.Println(titleStyle.Render(cli.Label(cli.IconDevice, " "+title)) + "\n")
+
+	colWidths := []int{18, 16, 7, 10, 9, 10, 4}
+
+	fmt.Printf("%s  %s  %s  %s  %s  %s  %s  %s\n",
+		padRight(headerStyle.Render("ALIAS"), colWidths[0]),
+		padRight(headerStyle.Render("IP ADDRESS"), colWidths[1]),
+		padRight(headerStyle.Render("PORT"), colWidths[2]),
+		padRight(headerStyle.Render("TYPE"), colWidths[3]),
+		padRight(headerStyle.Render("STATUS"), colWidths[4]),
+		padRight(headerStyle.Render("LAST SEEN"), colWidths[5]),
+		padRight(headerStyle.Render("FAV"), colWidths[6]),
+		headerStyle.Render("TRUSTED"),
+	)
+	fmt.Println(mutedStyle.Render(strings.Repeat("-", 100)))
+
+	for _, d := range entries {
+		status := mutedStyle.Render("Offline")
+		if d.Available {
+			status = cli.SuccessStyle.Render("Online")
+		}
+		lastSeen := "Unknown"
+		if !d.GetLastSeen().IsZero() {
+			lastSeen = formatAge(time.Since(d.GetLastSeen()))
+		}
+
+		deviceTypeStr := string(d.DeviceType)
+		if len(deviceTypeStr) > 0 {
+			deviceTypeStr = strings.ToUpper(deviceTypeStr[:1]) + deviceTypeStr[1:]
+		}
+		favorite, trusted := "", ""
+		if d.Favorite {
+			favorite = cli.WarningStyle.Render(cli.IconStar)
+		}
+		if d.Trusted {
+			trusted = cli.SuccessStyle.Render(cli.Label(cli.IconLock, "yes"))
+		}
+
+		fmt.Printf("%s  %s  %s  %s  %s  %s  %s  %s\n",
+			padRight(rowStyle.Render(cli.TruncateString(d.Alias, 16)), colWidths[0]),
+			padRight(rowStyle.Render(d.IP), colWidths[1]),
+			padRight(rowStyle.Render(fmt.Sprintf("%d", d.Port)), colWidths[2]),
+			padRight(rowStyle.Render(deviceTypeStr), colWidths[3]),
+			padRight(status, colWidths[4]),
+			padRight(lastSeen, colWidths[5]),
+			padRight(favorite, colWidths[6]),
+			trusted,
+		)
+	}
+}
+
+// formatAge renders how long ago something happened, e.g. "just now",
+// "5m ago" or "3d ago".
+func formatAge(d time.Duration) string {
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		return fmt.Sprintf("%dm ago", int(d.Minutes()))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh ago", int(d.Hours()))
+	default:
+		return fmt.Sprintf("%dd ago", int(d.Hours()/24))
+	}
+}
+
 func init() {
 	rootCmd.AddCommand(devicesCmd)
-	devicesCmd.Flags().BoolVar(&devicesjsonOutput, "json", false, "Output in JSON format")
-	devicesCmd.Flags().BoolVar(&devicesProbe, "probe", false, "Probe cached devices to verify if they are currently online")
+	devicesCmd.Flags().BoolVar(&devicesProbe, "probe", false, "Probe cached devices to verify if they are currently online (implies --cache)")
+	devicesCmd.Flags().BoolVar(&devicesCache, "cache", false, "Read the peer cache even if a server is running")
+	devicesCmd.Flags().IntVar(&devicesPort, "port", 0, "Port of the running server (default: from config)")
 
 	devicesCmd.SetHelpFunc(func(cmd *cobra.Command, args []string) {
 		if h := help.GetCommandHelp("devices"); h != nil {
@@ -18,17 +18,19 @@ import (
 )
 
 var (
-	discovertimeout    int
-	discoverjsonOutput bool
-	discoverquiet      bool
+	discovertimeout int
 )
 
 var discoverCmd = &cobra.Command{
 	Use:   "discover",
 	Short: "Discover LocalGo devices on the network using multicast",
 	RunE: func(cmd *cobra.Command, args []string) error {
+		filter, err := deviceFilter()
+		if err != nil {
+			return err
+		}
 
-		if !discoverquiet {
+		if !cli.QuietOutput() {
 			cli.PrintHeader("Discovering devices")
 			cli.PrintInfo("Timeout: %ds", discovertimeout)
 			cli.PrintInfo("Multicast group: %s", Cfg.MulticastGroup)
@@ -48,16 +50,28 @@ var discoverCmd = &cobra.Command{
 		discoverySvcConfig.MulticastConfig.InterfaceName = Cfg.MulticastInterface
 		multicastDto := Cfg.ToMulticastDto(false)
 
-		multicast := discovery.NewMulticastDiscovery(discoverySvcConfig.MulticastConfig, multicastDto, zap.S())
+		logger := zap.S().Named("discovery")
+		multicast := discovery.NewMulticastDiscovery(discoverySvcConfig.MulticastConfig, multicastDto, logger)
 
-		peerCache := discovery.NewPeerCache(zap.S())
+		peerCache := discovery.NewPeerCache(logger)
 		multicast.SetPeerCache(peerCache)
 
-		discoverySvc := discovery.NewService(discoverySvcConfig, multicast, zap.S())
+		discoverySvc := discovery.NewService(discoverySvcConfig, multicast, logger)
 		discoverySvc.SetPeerCache(peerCache)
 
-		discoverySvc.AddDeviceHandler(func(device *model.Device) {
-			if !discoverquiet {
+		// Perform discovery
+		discoverCtx, cancel := context.WithTimeout(context.Background(), time.Duration(discovertimeout)*time.Second)
+		defer cancel()
+
+		deviceEvents, _ := discoverySvc.Subscribe(discoverCtx)
+		printed := make(chan struct{})
+		go func() {
+			defer close(printed)
+			for ev := range deviceEvents {
+				device := ev.Device
+				if ev.Type != discovery.DeviceFound || cli.QuietOutput() || !filter.Match(device) {
+					continue
+				}
 				alias := device.Alias
 				if Cfg.Private {
 					alias = cli.AnonymizedAlias(device)
@@ -65,16 +79,12 @@ var discoverCmd = &cobra.Command{
 				zap.S().Infof("Found: %s (%s) [%s] Port: %d", alias, device.IP, device.Protocol, device.Port)
 				cli.PrintSuccess("Found: %s (%s) [%s] Port: %d", alias, device.IP, device.Protocol, device.Port)
 			}
-		})
-
-		// Perform discovery
-		discoverCtx, cancel := context.WithTimeout(context.Background(), time.Duration(discovertimeout)*time.Second)
-		defer cancel()
+		}()
 
 		var foundDevices []*model.Device
 		var discErr error
 
-		if !discoverquiet {
+		if !cli.QuietOutput() {
 			_ = spinner.New().
 				Title(fmt.Sprintf("Searching for devices on multicast group %s...", Cfg.MulticastGroup)).
 				Action(func() {
@@ -84,15 +94,16 @@ var discoverCmd = &cobra.Command{
 		} else {
 			foundDevices, discErr = discoverySvc.Discover(discoverCtx, Cfg.ToMulticastDto(false))
 		}
+		<-printed // the subscription ends with discoverCtx
 
-		if discErr != nil && !discoverquiet {
+		if discErr != nil && !cli.QuietOutput() {
 			zap.S().Warnf("Discovery completed with warnings: %v", discErr)
 			cli.PrintWarning("Discovery completed with warnings: %v", discErr)
 		}
 
 		// Fallback: if multicast finds nothing, try HTTP subnet scan
 		if len(foundDevices) == 0 {
-			if !discoverquiet {
+			if !cli.QuietOutput() {
 				cli.PrintInfo("Multicast returned no devices. Falling back to HTTP subnet scan...")
 			}
 			localIPs, ipErr := network.GetLocalIPAddresses()
@@ -105,7 +116,7 @@ var discoverCmd = &cobra.Command{
 					}
 				}
 				registerDto := Cfg.ToRegisterDto()
-				httpDiscoverer := discovery.NewHTTPDiscovery(nil, registerDto, nil, zap.S())
+				httpDiscoverer := discovery.NewHTTPDiscovery(nil, registerDto, nil, logger)
 
 				scanCtx, scanCancel := context.WithTimeout(context.Background(), time.Duration(discovertimeout)*time.Second)
 				defer scanCancel()
@@ -133,20 +144,30 @@ var discoverCmd = &cobra.Command{
 			})
 		}
 
-		if !discoverquiet && len(foundDevices) == 0 {
+		if !cli.QuietOutput() && len(foundDevices) == 0 {
 			zap.S().Warnf("No devices discovered")
-			cli.PrintWarning("No devices discovered. Check your firewall or network.")
+			cli.PrintWarning("No devices discovered. Run `localgo doctor` to check your network and firewall.")
 		}
 
-		return displayDevices(foundDevices, discoverjsonOutput, discoverquiet, "multicast discovery")
+		// Copies, since the discovery service may still update its devices.
+		for i, d := range foundDevices {
+			foundDevices[i] = d.Snapshot()
+		}
+		if n := len(foundDevices); !filter.Empty() {
+			foundDevices = filter.Apply(foundDevices)
+			if !cli.QuietOutput() && n > 0 && len(foundDevices) < n {
+				cli.PrintInfo("%d of %d device(s) match the filters", len(foundDevices), n)
+			}
+		}
+		annotateDevices(foundDevices, annotateTimeout)
+		return displayDevices(foundDevices, "multicast discovery")
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(discoverCmd)
 	discoverCmd.Flags().IntVar(&discovertimeout, "timeout", 10, "Discovery timeout in seconds")
-	discoverCmd.Flags().BoolVar(&discoverjsonOutput, "json", false, "Output in JSON format")
-	discoverCmd.Flags().BoolVar(&discoverquiet, "quiet", false, "Quiet mode")
+	addFilterFlags(discoverCmd)
 
 	discoverCmd.SetHelpFunc(func(cmd *cobra.Command, args []string) {
 		if h := help.GetCommandHelp("discover"); h != nil {
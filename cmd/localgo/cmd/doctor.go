@@ -0,0 +1,123 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/bethropolis/localgo/pkg/cli"
+	"github.com/bethropolis/localgo/pkg/doctor"
+	"github.com/bethropolis/localgo/pkg/help"
+	"github.com/charmbracelet/huh/spinner"
+	"github.com/spf13/cobra"
+)
+
+var (
+	doctortimeout   time.Duration
+	doctorport      int
+	doctorinterface string
+)
+
+var doctorCmd = &cobra.Command{
+	Use:          "doctor",
+	Short:        "Diagnose network and setup problems",
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		port := Cfg.Port
+		if cmd.Flags().Changed("port") {
+			port = doctorport
+		}
+		iface := Cfg.MulticastInterface
+		if doctorinterface != "" {
+			iface = doctorinterface
+		}
+		opts := doctor.Options{
+			Port:           port,
+			MulticastGroup: Cfg.MulticastGroup,
+			MulticastPort:  discoveryPort(),
+			Interface:      iface,
+			HTTPS:          Cfg.HttpsEnabled,
+			Alias:          Cfg.Alias,
+			Security:       Cfg.SecurityContext,
+			Identity:       Cfg.Identity,
+			SecurityPath:   Cfg.SecurityPath,
+			CertFile:       Cfg.CustomTLSCertPath,
+			KeyFile:        Cfg.CustomTLSKeyPath,
+			DownloadDir:    Cfg.DownloadDir,
+			Timeout:        doctortimeout,
+		}
+
+		var findings []doctor.Finding
+		run := func() { findings = doctor.Run(context.Background(), opts) }
+		if cli.JSONOutput() || cli.Headless() {
+			run()
+		} else {
+			_ = spinner.New().Title("Running checks...").Action(run).Run()
+		}
+
+		if cli.JSONOutput() {
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			if err := encoder.Encode(findings); err != nil {
+				return err
+			}
+		} else {
+			printFindings(findings)
+		}
+
+		failed := 0
+		for _, f := range findings {
+			if f.Status == doctor.StatusFail {
+				failed++
+			}
+		}
+		if failed > 0 {
+			return fmt.Errorf("%d check(s) failed", failed)
+		}
+		return nil
+	},
+}
+
+// printFindings prints each finding with its hint, then a one-line verdict.
+func printFindings(findings []doctor.Finding) {
+	cli.PrintHeader("LocalGo Doctor")
+	warnings := 0
+	for _, f := range findings {
+		line := fmt.Sprintf("%-13s %s", f.Check, f.Message)
+		switch f.Status {
+		case doctor.StatusOK:
+			cli.PrintSuccess("%s", line)
+		case doctor.StatusWarn:
+			warnings++
+			cli.PrintWarning("%s", line)
+		default:
+			cli.PrintError("%s", line)
+		}
+		if f.Hint != "" {
+			fmt.Printf("    %s\n", f.Hint)
+		}
+	}
+	fmt.Println()
+	if !doctor.Failed(findings) {
+		if warnings > 0 {
+			cli.PrintWarning("No failures, %d warning(s)", warnings)
+		} else {
+			cli.PrintSuccess("Everything looks fine")
+		}
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(doctorCmd)
+	doctorCmd.Flags().DurationVar(&doctortimeout, "timeout", time.Second, "How long each network probe waits")
+	doctorCmd.Flags().IntVar(&doctorport, "port", 0, "Server port to check (default: the configured port)")
+	doctorCmd.Flags().StringVar(&doctorinterface, "interface", "", "Check only this network interface")
+
+	doctorCmd.SetHelpFunc(func(cmd *cobra.Command, args []string) {
+		if h := help.GetCommandHelp("doctor"); h != nil {
+			help.ShowCommandHelp(*h)
+		}
+	})
+}
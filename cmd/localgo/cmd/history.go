@@ -5,7 +5,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/bethropolis/localgo/pkg/cli"
 	"github.com/bethropolis/localgo/pkg/history"
@@ -19,8 +21,9 @@ var (
 )
 
 var historyCmd = &cobra.Command{
-	Use:   "history",
-	Short: "Show file transfer history log",
+	Use:          "history",
+	Short:        "Show file transfer history log",
+	SilenceUsage: true,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		path := Cfg.HistoryFile
 		if path == "" {
@@ -40,26 +43,12 @@ var historyCmd = &cobra.Command{
 			return nil
 		}
 
-		file, err := os.Open(path)
+		entries, err := readHistory(path)
 		if err != nil {
-			if os.IsNotExist(err) {
-				cli.PrintInfo("No transfer history found.")
-				return nil
-			}
-			return fmt.Errorf("failed to open history file: %w", err)
-		}
-		defer file.Close()
-
-		var entries []history.Entry
-		scanner := bufio.NewScanner(file)
-		for scanner.Scan() {
-			var entry history.Entry
-			if err := json.Unmarshal(scanner.Bytes(), &entry); err == nil {
-				entries = append(entries, entry)
-			}
+			return err
 		}
-
-		if len(entries) == 0 {
+		writer := cli.NewOutputWriter(cli.Format())
+		if len(entries) == 0 && !cli.MachineOutput() {
 			cli.PrintInfo("No transfer history found.")
 			return nil
 		}
@@ -75,56 +64,113 @@ var historyCmd = &cobra.Command{
 		for i, j := 0, len(displayEntries)-1; i < j; i, j = i+1, j-1 {
 			displayEntries[i], displayEntries[j] = displayEntries[j], displayEntries[i]
 		}
+		if Cfg.Private {
+			for i := range displayEntries {
+				if displayEntries[i].SenderAlias != "Anonymous" {
+					displayEntries[i].SenderAlias = cli.AnonymizeString(displayEntries[i].SenderAlias)
+				}
+			}
+		}
 
-		titleStyle := cli.HeaderStyle.Padding(0, 1).MarginBottom(1)
-		headerStyle := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("39"))
-		rowStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("255"))
-		mutedStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("242"))
-
-		fmt.Println(titleStyle.Render(cli.IconFolderOpen+"  File Transfer History") + "\n")
-
-		// Column Width definitions
-		colWidths := []int{12, 16, 25, 10, 12} // Time, Sender, File, Size, Status
-
-		// Print Header
-		fmt.Printf("%s  %s  %s  %s  %s\n",
-			padRight(headerStyle.Render("TIME"), colWidths[0]),
-			padRight(headerStyle.Render("SENDER"), colWidths[1]),
-			padRight(headerStyle.Render("FILE NAME"), colWidths[2]),
-			padRight(headerStyle.Render("SIZE"), colWidths[3]),
-			padRight(headerStyle.Render("STATUS"), colWidths[4]),
-		)
-		fmt.Println(mutedStyle.Render(strings.Repeat("-", 80)))
-
-		for _, entry := range displayEntries {
-			senderAlias := entry.SenderAlias
-			if Cfg.Private && senderAlias != "Anonymous" {
-				senderAlias = cli.AnonymizeString(entry.SenderAlias)
+		switch writer.Format() {
+		case cli.FormatJSON, cli.FormatYAML:
+			if displayEntries == nil {
+				displayEntries = []history.Entry{}
 			}
-			tStr := entry.Timestamp.Local().Format("01-02 15:04")
-
-			statusColored := entry.Status
-			switch entry.Status {
-			case "received":
-				statusColored = cli.SuccessStyle.Render("Received")
-			case "clipboard":
-				statusColored = cli.InfoStyle.Render("Clipboard")
-			case "failed":
-				statusColored = cli.ErrorStyle.Render("Failed")
+			if writer.Format() == cli.FormatYAML {
+				return writer.WriteYAML(displayEntries)
 			}
-
-			fmt.Printf("%s  %s  %s  %s  %s\n",
-				padRight(mutedStyle.Render(tStr), colWidths[0]),
-				padRight(rowStyle.Render(cli.TruncateString(senderAlias, 14)), colWidths[1]),
-				padRight(rowStyle.Render(cli.TruncateString(entry.FileName, 23)), colWidths[2]),
-				padRight(rowStyle.Render(cli.FormatBytes(entry.FileSize)), colWidths[3]),
-				padRight(statusColored, colWidths[4]),
-			)
+			return writer.WriteJSON(displayEntries)
+		case cli.FormatCSV:
+			rows := make([][]string, len(displayEntries))
+			for i, e := range displayEntries {
+				rows[i] = []string{e.Timestamp.Format(time.RFC3339), e.SenderAlias, e.SenderIP, e.FileName, e.FilePath,
+					strconv.FormatInt(e.FileSize, 10), e.FileType, e.Status}
+			}
+			return writer.WriteCSV([]string{"timestamp", "sender_alias", "sender_ip", "file_name", "file_path", "file_size", "file_type", "status"}, rows)
+		case cli.FormatTemplate:
+			items := make([]any, len(displayEntries))
+			for i, e := range displayEntries {
+				items[i] = e
+			}
+			return writer.WriteTemplate(items...)
 		}
+		printHistoryEntries(displayEntries)
 		return nil
 	},
 }
 
+// readHistory reads the entries in the history file, oldest first. A missing
+// file has none; lines that do not parse are skipped.
+func readHistory(path string) ([]history.Entry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to open history file: %w", err)
+	}
+	defer file.Close()
+
+	var entries []history.Entry
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var entry history.Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err == nil {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+// printHistoryEntries prints the entries as a table.
+func printHistoryEntries(displayEntries []history.Entry) {
+	titleStyle := cli.HeaderStyle.Padding(0, 1).MarginBottom(1)
+	headerStyle := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("39"))
+	rowStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("255"))
+	mutedStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("242"))
+
+	fmt.Println(titleStyle.Render(cli.Label(cli.IconFolderOpen, " File Transfer History")) + "\n")
+
+	// Column Width definitions
+	colWidths := []int{12, 16, 25, 10, 12} // Time, Sender, File, Size, Status
+
+	// Print Header
+	fmt.Printf("%s  %s  %s  %s  %s\n",
+		padRight(headerStyle.Render("TIME"), colWidths[0]),
+		padRight(headerStyle.Render("SENDER"), colWidths[1]),
+		padRight(headerStyle.Render("FILE NAME"), colWidths[2]),
+		padRight(headerStyle.Render("SIZE"), colWidths[3]),
+		padRight(headerStyle.Render("STATUS"), colWidths[4]),
+	)
+	fmt.Println(mutedStyle.Render(strings.Repeat("-", 80)))
+
+	for _, entry := range displayEntries {
+		senderAlias := entry.SenderAlias
+		tStr := entry.Timestamp.Local().Format("01-02 15:04")
+
+		statusColored := entry.Status
+		switch entry.Status {
+		case "received":
+			statusColored = cli.SuccessStyle.Render("Received")
+		case "clipboard":
+			statusColored = cli.InfoStyle.Render("Clipboard")
+		case "failed":
+			statusColored = cli.ErrorStyle.Render("Failed")
+		case "quarantined":
+			statusColored = cli.ErrorStyle.Render("Quarantined")
+		}
+
+		fmt.Printf("%s  %s  %s  %s  %s\n",
+			padRight(mutedStyle.Render(tStr), colWidths[0]),
+			padRight(rowStyle.Render(cli.TruncateString(senderAlias, 14)), colWidths[1]),
+			padRight(rowStyle.Render(cli.TruncateString(entry.FileName, 23)), colWidths[2]),
+			padRight(rowStyle.Render(cli.FormatBytes(entry.FileSize)), colWidths[3]),
+			padRight(statusColored, colWidths[4]),
+		)
+	}
+}
+
 func init() {
 	historyCmd.Flags().IntVar(&historyLimit, "limit", 10, "Maximum number of entries to display")
 	historyCmd.Flags().BoolVar(&historyClear, "clear", false, "Clear all transfer history logs")
@@ -1,28 +1,51 @@
 package cmd
 
 import (
+	"context"
+	"crypto/x509"
 	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/bethropolis/localgo/pkg/cli"
 	"github.com/bethropolis/localgo/pkg/config"
+	"github.com/bethropolis/localgo/pkg/crypto"
 	"github.com/bethropolis/localgo/pkg/help"
+	"github.com/bethropolis/localgo/pkg/model"
+	"github.com/bethropolis/localgo/pkg/ping"
 	"github.com/spf13/cobra"
+	"go.uber.org/zap"
 )
 
-var infojsonOutput bool
+var (
+	inforemote     string
+	infoport       int
+	infotimeout    int
+	inforegenerate bool
+)
 
 var infoCmd = &cobra.Command{
-	Use:   "info",
-	Short: "Show device information and configuration",
+	Use:          "info",
+	Short:        "Show device information and configuration",
+	SilenceUsage: true,
 	RunE: func(cmd *cobra.Command, args []string) error {
+		writer := cli.NewOutputWriter(cli.Format())
+		defer writer.Flush()
 
-		format := cli.FormatTable
-		if infojsonOutput {
-			format = cli.FormatJSON
+		if inforemote != "" {
+			info, err := remoteInfo(inforemote)
+			if err != nil {
+				return err
+			}
+			return writer.WriteRemoteDeviceInfo(info)
+		}
+		if inforegenerate {
+			if err := regenerateCertificate(); err != nil {
+				return err
+			}
 		}
-
-		writer := cli.NewOutputWriter(format)
-		defer writer.Flush()
 
 		deviceModel := "Unknown"
 		if Cfg.DeviceModel != nil {
@@ -46,14 +69,111 @@ var infoCmd = &cobra.Command{
 			HasPin:        Cfg.PIN != "",
 			MulticastAddr: fmt.Sprintf("%s:%d", Cfg.MulticastGroup, Cfg.Port),
 		}
+		if cert, err := deviceCertificate(); err == nil {
+			info.CertExpires = cert.NotAfter.Format(time.DateOnly)
+			info.CertWarning = crypto.ExpiryStatus(cert, time.Now())
+		}
+		history, err := crypto.FingerprintHistory(Cfg.SecurityPath)
+		if err != nil {
+			return err
+		}
+		for _, retired := range history {
+			info.PreviousFingerprints = append(info.PreviousFingerprints, retired.Fingerprint)
+		}
 
 		return writer.WriteDeviceInfo(info)
 	},
 }
 
+// regenerateCertificate replaces the generated TLS identity with a new one
+// for the current alias, keeping the old fingerprint in the history.
+func regenerateCertificate() error {
+	if Cfg.Identity != nil || Cfg.CustomTLSCertPath != "" {
+		return fmt.Errorf("--regenerate-cert only replaces the generated identity; renew the certificate given with --tls-cert instead")
+	}
+	old := Cfg.SecurityContext.CertificateHash
+	sc, err := crypto.RegenerateSecurityContext(Cfg.SecurityPath, Cfg.Alias, zap.S().Named("crypto"))
+	if err != nil {
+		return fmt.Errorf("failed to regenerate certificate: %w", err)
+	}
+	Cfg.SecurityContext = sc
+	cli.PrintSuccess("New TLS certificate saved to %s; the old fingerprint %s is kept in %s", Cfg.SecurityPath, old, crypto.FingerprintHistoryPath(Cfg.SecurityPath))
+	if entries := Cfg.FingerprintEntries(old); len(entries) > 0 {
+		cli.PrintWarning("This config names the old fingerprint in %s; those entries no longer match this device. Replace them with %s.", strings.Join(entries, ", "), sc.CertificateHash)
+	}
+	cli.PrintWarning("Devices that list the old fingerprint in trusted_fingerprints, accept_rules or favorites, or in receive --from, no longer trust or recognize this one. Give them the new fingerprint %s.", sc.CertificateHash)
+	cli.PrintInfo("Restart a running server to use it.")
+	return nil
+}
+
+// deviceCertificate parses the certificate the device serves.
+func deviceCertificate() (*x509.Certificate, error) {
+	pair, err := Cfg.TLSCertificate()
+	if err != nil {
+		return nil, err
+	}
+	return x509.ParseCertificate(pair.Certificate[0])
+}
+
+// remoteInfo asks the device at target, an IP address (with optional :port)
+// or an alias, for its /info, trying the protocol it was discovered with
+// first. The answer must match the fingerprint seen during discovery.
+func remoteInfo(target string) (cli.RemoteDeviceInfo, error) {
+	host, _, err := net.SplitHostPort(target)
+	if err != nil {
+		host = target
+	}
+	var device *model.Device
+	if net.ParseIP(host) != nil {
+		device, err = parseDeviceAddress(target, infoport)
+	} else {
+		device, err = findDevice(target, "", "", infoport, !cli.MachineOutput())
+	}
+	if err != nil {
+		return cli.RemoteDeviceInfo{}, err
+	}
+
+	protocols := []model.ProtocolType{model.ProtocolTypeHTTPS, model.ProtocolTypeHTTP}
+	if device.Protocol == model.ProtocolTypeHTTP {
+		protocols[0], protocols[1] = protocols[1], protocols[0]
+	}
+	address := net.JoinHostPort(device.IP, strconv.Itoa(device.Port))
+	var lastErr string
+	for _, protocol := range protocols {
+		r := ping.Probe(context.Background(), device.IP, device.Port, protocol, 1, time.Duration(infotimeout)*time.Second)
+		if !r.Reachable() {
+			lastErr = r.Error
+			continue
+		}
+		if err := r.VerifyFingerprint(device.Fingerprint); err != nil {
+			return cli.RemoteDeviceInfo{}, fmt.Errorf("%s: %w", address, err)
+		}
+		deviceModel := "Unknown"
+		if r.Info.DeviceModel != nil {
+			deviceModel = *r.Info.DeviceModel
+		}
+		return cli.RemoteDeviceInfo{
+			Alias:       r.Info.Alias,
+			Version:     r.Info.Version,
+			DeviceModel: deviceModel,
+			DeviceType:  string(r.Info.DeviceType),
+			Fingerprint: r.Info.Fingerprint,
+			Address:     address,
+			Protocol:    string(protocol),
+			Download:    r.Info.Download,
+			Verified:    r.CertFingerprint != "",
+		}, nil
+	}
+	return cli.RemoteDeviceInfo{}, fmt.Errorf("%s did not answer over HTTPS or HTTP: %s", address, lastErr)
+}
+
 func init() {
 	rootCmd.AddCommand(infoCmd)
-	infoCmd.Flags().BoolVar(&infojsonOutput, "json", false, "Output in JSON format")
+	infoCmd.Flags().StringVar(&inforemote, "remote", "", "Show another device's information instead (alias or IP with optional :port)")
+	infoCmd.Flags().IntVar(&infoport, "port", 0, "Port of the remote device (default: from discovery, else the configured port)")
+	infoCmd.Flags().IntVar(&infotimeout, "timeout", 5, "Timeout for the remote request in seconds")
+	infoCmd.Flags().BoolVar(&inforegenerate, "regenerate-cert", false, "Replace the TLS certificate with a new one for the current alias (changes the fingerprint)")
+	infoCmd.MarkFlagsMutuallyExclusive("remote", "regenerate-cert")
 
 	infoCmd.SetHelpFunc(func(cmd *cobra.Command, args []string) {
 		if h := help.GetCommandHelp("info"); h != nil {
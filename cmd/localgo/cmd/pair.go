@@ -0,0 +1,127 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/bethropolis/localgo/pkg/cli"
+	"github.com/bethropolis/localgo/pkg/help"
+	"github.com/bethropolis/localgo/pkg/model"
+	"github.com/bethropolis/localgo/pkg/network"
+	"github.com/bethropolis/localgo/pkg/pairing"
+	"github.com/bethropolis/localgo/pkg/ping"
+	"github.com/bethropolis/localgo/pkg/relay"
+	"github.com/spf13/cobra"
+)
+
+var (
+	pairip    string
+	pairport  int
+	pairrelay bool
+)
+
+var pairCmd = &cobra.Command{
+	Use:          "pair",
+	Short:        "Show a pairing code other devices can send to this one with",
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if pairrelay {
+			if pairip != "" {
+				return fmt.Errorf("--ip does not apply to relay codes")
+			}
+			client, err := newRelayClient()
+			if err != nil {
+				return err
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+			defer cancel()
+			code, err := client.NewCode(ctx)
+			if err != nil {
+				return err
+			}
+			printPairingCode(code.Code)
+			cli.PrintInfo("Valid until %s, while localgo serve is registered with the relay", code.Expires.Local().Format("15:04"))
+			return nil
+		}
+
+		if !Cfg.HttpsEnabled {
+			return fmt.Errorf("pairing codes need HTTPS, which lets the sender check this device's fingerprint")
+		}
+		var ip net.IP
+		if pairip != "" {
+			if ip = net.ParseIP(pairip); ip == nil {
+				return fmt.Errorf("invalid --ip address: %s", pairip)
+			}
+		} else {
+			var err error
+			if ip, err = network.PrimaryLANIP(); err != nil {
+				if ip, err = network.GetPreferredOutboundIP(); err != nil {
+					return fmt.Errorf("could not find this device's address, use --ip: %w", err)
+				}
+			}
+		}
+		port := pairport
+		if port == 0 {
+			port = Cfg.Port
+		}
+		cert, err := Cfg.TLSCertificate()
+		if err != nil {
+			return fmt.Errorf("failed to load TLS key pair: %w", err)
+		}
+		code, err := pairing.Encode(ip, port, relay.CertificateFingerprint(cert))
+		if err != nil {
+			return err
+		}
+		printPairingCode(code)
+		cli.PrintInfo("Reaches %s while localgo serve is running", net.JoinHostPort(ip.String(), fmt.Sprint(port)))
+		return nil
+	},
+}
+
+func printPairingCode(code string) {
+	if cli.QuietOutput() {
+		fmt.Println(code)
+		return
+	}
+	cli.PrintSuccess("Pairing code: %s", code)
+	cli.PrintInfo("On the other device: localgo send --code %s --file <file>", code)
+}
+
+// codeRecipient returns the device a direct pairing code leads to, once it
+// has proved it has the certificate the code names.
+func codeRecipient(ctx context.Context, code string) (*model.Device, error) {
+	d, err := pairing.Decode(code)
+	if err != nil {
+		return nil, err
+	}
+	r := ping.Probe(ctx, d.IP.String(), d.Port, model.ProtocolTypeHTTPS, 1, 5*time.Second)
+	if !r.Reachable() {
+		return nil, fmt.Errorf("no LocalSend device answers at %s: %s", net.JoinHostPort(d.IP.String(), fmt.Sprint(d.Port)), r.Error)
+	}
+	if err := r.VerifyFingerprint(d.Fingerprint); err != nil {
+		return nil, fmt.Errorf("the device at %s is not the one the pairing code is for: %w", d.IP, err)
+	}
+	return &model.Device{
+		Alias:       r.Info.Alias,
+		Version:     r.Info.Version,
+		IP:          d.IP.String(),
+		Port:        d.Port,
+		Protocol:    model.ProtocolTypeHTTPS,
+		Fingerprint: r.CertFingerprint,
+	}, nil
+}
+
+func init() {
+	pairCmd.Flags().StringVar(&pairip, "ip", "", "Address to put in the code (default: this device's LAN address)")
+	pairCmd.Flags().IntVar(&pairport, "port", 0, "Port to put in the code (default: from config)")
+	pairCmd.Flags().BoolVar(&pairrelay, "relay", false, "Get a short code from the relay in the config instead")
+	pairCmd.SetHelpFunc(func(cmd *cobra.Command, args []string) {
+		if h := help.GetCommandHelp("pair"); h != nil {
+			help.ShowCommandHelp(*h)
+		}
+	})
+	rootCmd.AddCommand(pairCmd)
+}
@@ -0,0 +1,178 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bethropolis/localgo/pkg/cli"
+	"github.com/bethropolis/localgo/pkg/help"
+	"github.com/bethropolis/localgo/pkg/model"
+	"github.com/bethropolis/localgo/pkg/ping"
+	"github.com/spf13/cobra"
+)
+
+var (
+	pingto          string
+	pingip          string
+	pingfingerprint string
+	pingport        int
+	pingcount       int
+	pingtimeout     int
+)
+
+// pingOutput is what `ping --json` prints.
+type pingOutput struct {
+	Device   string         `json:"device"`
+	Address  string         `json:"address"`
+	Verified bool           `json:"verified"` // the device proved its fingerprint
+	Error    string         `json:"error,omitempty"`
+	Results  []*ping.Result `json:"results"`
+}
+
+var pingCmd = &cobra.Command{
+	Use:          "ping",
+	Short:        "Check that a device is reachable and measure its latency",
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if pingcount < 1 {
+			return fmt.Errorf("--count must be at least 1")
+		}
+		if pingip != "" && pingto != "" {
+			return fmt.Errorf("cannot use both --to and --ip")
+		}
+
+		device, err := findDevice(pingto, pingip, pingfingerprint, pingport, !cli.JSONOutput())
+		if err != nil {
+			return err
+		}
+		expected := pingfingerprint
+		if expected == "" {
+			expected = device.Fingerprint
+		}
+		address := net.JoinHostPort(device.IP, strconv.Itoa(device.Port))
+
+		if !cli.JSONOutput() {
+			cli.PrintHeader(fmt.Sprintf("Pinging %s (%s)", device.Alias, address))
+		}
+		ctx := context.Background()
+		timeout := time.Duration(pingtimeout) * time.Second
+		var results []*ping.Result
+		for _, protocol := range []model.ProtocolType{model.ProtocolTypeHTTPS, model.ProtocolTypeHTTP} {
+			results = append(results, ping.Probe(ctx, device.IP, device.Port, protocol, pingcount, timeout))
+		}
+
+		out := pingOutput{Device: device.Alias, Address: address, Results: results}
+		err = checkPingResults(&out, expected)
+		if err != nil {
+			out.Error = err.Error()
+		}
+		if cli.JSONOutput() {
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			if encErr := encoder.Encode(out); encErr != nil {
+				return encErr
+			}
+		} else {
+			printPingResults(results, out.Verified, device.Protocol)
+		}
+		return err
+	},
+}
+
+// checkPingResults fails if no protocol answered, or if an answer does not
+// match expected or its own TLS certificate.
+func checkPingResults(out *pingOutput, expected string) error {
+	answered := 0
+	verified := expected != ""
+	for _, r := range out.Results {
+		if !r.Reachable() {
+			continue
+		}
+		answered++
+		if err := r.VerifyFingerprint(expected); err != nil {
+			return fmt.Errorf("%s: %w", r.Protocol, err)
+		}
+		// Over HTTPS the certificate proves the fingerprint even when
+		// none was expected.
+		if r.Protocol == model.ProtocolTypeHTTPS {
+			verified = true
+		}
+	}
+	if answered == 0 {
+		return fmt.Errorf("%s did not answer over HTTPS or HTTP", out.Address)
+	}
+	out.Verified = verified
+	return nil
+}
+
+// printPingResults prints the latency per protocol, then the details the
+// device reported. advertised is the protocol discovery reported, if any.
+func printPingResults(results []*ping.Result, verified bool, advertised model.ProtocolType) {
+	var info *model.InfoDto
+	var fingerprint string
+	for _, r := range results {
+		label := padRight(strings.ToUpper(string(r.Protocol)), 6)
+		if !r.Reachable() {
+			if advertised == r.Protocol {
+				cli.PrintError("%s no answer: %s", label, r.Error)
+			} else {
+				cli.PrintInfo("%s not served (%s)", label, r.Error)
+			}
+			continue
+		}
+		line := fmt.Sprintf("%s %d/%d answered, connect %.1f ms, round trip min/avg/max %.1f/%.1f/%.1f ms",
+			label, r.Received, r.Sent, r.SetupMs, r.MinMs, r.AvgMs, r.MaxMs)
+		if r.Received < r.Sent {
+			cli.PrintWarning("%s", line)
+		} else {
+			cli.PrintSuccess("%s", line)
+		}
+		if info == nil {
+			info = r.Info
+			fingerprint = r.Info.Fingerprint
+			if r.CertFingerprint != "" {
+				fingerprint = r.CertFingerprint
+			}
+		}
+	}
+	if info == nil {
+		return
+	}
+
+	deviceModel := "Unknown"
+	if info.DeviceModel != nil {
+		deviceModel = *info.DeviceModel
+	}
+	fmt.Println()
+	fmt.Printf("  Alias:       %s\n", info.Alias)
+	fmt.Printf("  Protocol:    v%s\n", info.Version)
+	fmt.Printf("  Device:      %s (%s)\n", deviceModel, info.DeviceType)
+	fmt.Printf("  Fingerprint: %s\n", fingerprint)
+	fmt.Printf("  Download:    %t\n", info.Download)
+	fmt.Println()
+	if verified {
+		cli.PrintSuccess("Fingerprint verified")
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(pingCmd)
+	pingCmd.Flags().StringVar(&pingto, "to", "", "Alias of the device to ping (omit to pick interactively)")
+	pingCmd.Flags().StringVar(&pingip, "ip", "", "Device IP or host name, e.g. mylaptop.local (with optional :port, skips discovery)")
+	pingCmd.Flags().StringVar(&pingfingerprint, "fingerprint", "", "Expected fingerprint (or prefix); also chooses between devices sharing an alias")
+	pingCmd.Flags().IntVar(&pingport, "port", 0, "Device port (default: from discovery, else the configured port)")
+	pingCmd.Flags().IntVar(&pingcount, "count", 3, "Requests per protocol")
+	pingCmd.Flags().IntVar(&pingtimeout, "timeout", 3, "Timeout per request in seconds")
+
+	pingCmd.SetHelpFunc(func(cmd *cobra.Command, args []string) {
+		if h := help.GetCommandHelp("ping"); h != nil {
+			help.ShowCommandHelp(*h)
+		}
+	})
+}
@@ -0,0 +1,378 @@
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bethropolis/localgo/pkg/cli"
+	"github.com/bethropolis/localgo/pkg/help"
+	"github.com/bethropolis/localgo/pkg/queue"
+	"github.com/bethropolis/localgo/pkg/send"
+	"github.com/spf13/cobra"
+)
+
+var (
+	queueport          int
+	queuefiles         []string
+	queueexcludes      []string
+	queueto            string
+	queueip            string
+	queuetofingerprint string
+	queuefingerprint   string
+	queuepriority      int
+	queueattempts      int
+	queueat            string
+	queueevery         time.Duration
+	queueskipdups      bool
+)
+
+var queueCmd = &cobra.Command{
+	Use:          "queue",
+	Short:        "Queue sends on the running server",
+	Long:         "Queue sends on the running server. The queue lives in memory: waiting and scheduled jobs are dropped when the server stops.",
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return queueListCmd.RunE(cmd, args)
+	},
+}
+
+var queueAddCmd = &cobra.Command{
+	Use:          "add",
+	Short:        "Queue files to send",
+	SilenceUsage: true,
+	Args:         cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if queueattempts < 1 {
+			return fmt.Errorf("--attempts must be at least 1")
+		}
+		return enqueueSend(queueServerPort(), queuefiles, queueip, queuetofingerprint, queueat, queueevery, queue.Job{
+			Excludes:       queueexcludes,
+			SkipDuplicates: queueskipdups,
+			To:             queueto,
+			Fingerprint:    queuefingerprint,
+			Priority:       queuepriority,
+			MaxAttempts:    queueattempts,
+		})
+	},
+}
+
+// enqueueSend fills in the files, recipient and schedule of job, adds it to
+// the queue of the server on port and reports the queued job. ip and
+// toFingerprint are the --ip and --to-fingerprint flags; at and every are
+// --at and --every.
+func enqueueSend(port int, files []string, ip, toFingerprint, at string, every time.Duration, job queue.Job) error {
+	if len(files) == 0 {
+		return fmt.Errorf("no file specified: use --file")
+	}
+	if job.Fingerprint != "" && toFingerprint != "" {
+		return fmt.Errorf("cannot use both --fingerprint and --to-fingerprint")
+	}
+	if toFingerprint != "" {
+		if len(toFingerprint) < 4 {
+			return fmt.Errorf("--to-fingerprint needs at least 4 characters")
+		}
+		job.Fingerprint = toFingerprint
+	}
+	switch {
+	case ip != "":
+		if job.To != "" {
+			return fmt.Errorf("cannot use both --ip and --to")
+		}
+		device, err := parseDeviceAddress(ip, job.Port)
+		if err != nil {
+			return err
+		}
+		job.IP, job.Port = device.IP, device.Port
+	case job.To == "" && job.Fingerprint == "":
+		return fmt.Errorf("no recipient: use --to, --to-fingerprint or --ip")
+	}
+	if at != "" {
+		t, err := queue.ParseAt(at, time.Now())
+		if err != nil {
+			return fmt.Errorf("--at: %w", err)
+		}
+		job.At = t
+	}
+	if every != 0 && every < queue.DefaultMinEvery {
+		return fmt.Errorf("--every must be at least a minute")
+	}
+	job.Every = every
+
+	// The server resolves paths from its own working directory, so
+	// send it absolute ones.
+	expanded, names, err := send.ExpandGlobsWithNames(files, job.Excludes)
+	if err != nil {
+		return err
+	}
+	if len(expanded) == 0 {
+		return fmt.Errorf("no files left to send after applying --exclude")
+	}
+	for _, file := range expanded {
+		abs, err := filepath.Abs(file)
+		if err != nil {
+			return err
+		}
+		if _, err := os.Stat(abs); err != nil {
+			return fmt.Errorf("file not found: %s", file)
+		}
+		job.Files = append(job.Files, abs)
+		if name, ok := names[file]; ok {
+			if job.Names == nil {
+				job.Names = make(map[string]string)
+			}
+			job.Names[abs] = name
+		}
+	}
+
+	var added queue.Job
+	if err := adminRequest(http.MethodPost, port, "/v1/queue", job, &added); err != nil {
+		return err
+	}
+	if cli.MachineOutput() {
+		return writeQueueResult(added)
+	}
+	cli.PrintSuccess("Queued job %d: %d file(s) to %s%s", added.ID, len(added.Files), added.Recipient(), describeSchedule(&added))
+	return nil
+}
+
+// describeSchedule tells when a scheduled job runs next and how often, or
+// returns "" for a job that runs once as soon as it can.
+func describeSchedule(j *queue.Job) string {
+	var s string
+	if j.Status == queue.StatusQueued && j.At.After(time.Now()) {
+		s = ", next run " + j.At.Local().Format("Mon 2006-01-02 15:04")
+	}
+	if j.Every > 0 {
+		// 24h0m0s reads better as 24h.
+		every := j.Every.String()
+		if strings.HasSuffix(every, "m0s") {
+			every = strings.TrimSuffix(every, "0s")
+		}
+		if strings.HasSuffix(every, "h0m") {
+			every = strings.TrimSuffix(every, "0m")
+		}
+		s += ", every " + every
+	}
+	return s
+}
+
+var queueListCmd = &cobra.Command{
+	Use:          "list",
+	Short:        "List queued, running and finished sends",
+	SilenceUsage: true,
+	Args:         cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var jobs []queue.Job
+		if err := adminRequest(http.MethodGet, queueServerPort(), "/v1/queue", nil, &jobs); err != nil {
+			return err
+		}
+		if cli.MachineOutput() {
+			return writeQueueResult(jobs)
+		}
+		printQueue(jobs)
+		return nil
+	},
+}
+
+var queueRemoveCmd = &cobra.Command{
+	Use:          "remove <id>...",
+	Short:        "Remove jobs from the queue, stopping them if they are being sent",
+	SilenceUsage: true,
+	Args:         cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return queueAction(args, "Removed job %d", func(id int, jobs *[]queue.Job) error {
+			return adminRequest(http.MethodDelete, queueServerPort(), fmt.Sprintf("/v1/queue?id=%d", id), nil, jobs)
+		})
+	},
+}
+
+var queueRetryCmd = &cobra.Command{
+	Use:          "retry [<id>...]",
+	Short:        "Retry failed jobs (all of them if no ID is given)",
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) == 0 {
+			var jobs []queue.Job
+			if err := adminRequest(http.MethodGet, queueServerPort(), "/v1/queue", nil, &jobs); err != nil {
+				return err
+			}
+			for _, j := range jobs {
+				if j.Status == queue.StatusFailed {
+					args = append(args, strconv.Itoa(j.ID))
+				}
+			}
+			if len(args) == 0 {
+				cli.PrintInfo("No failed jobs to retry")
+				return nil
+			}
+		}
+		return queueAction(args, "Retrying job %d", func(id int, jobs *[]queue.Job) error {
+			return adminRequest(http.MethodPost, queueServerPort(), fmt.Sprintf("/v1/queue/retry?id=%d", id), nil, jobs)
+		})
+	},
+}
+
+var queuePriorityCmd = &cobra.Command{
+	Use:          "priority <id> <priority>",
+	Short:        "Change the priority of a waiting job (higher runs first)",
+	SilenceUsage: true,
+	Args:         cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		priority, err := strconv.Atoi(args[1])
+		if err != nil {
+			return fmt.Errorf("invalid priority %q", args[1])
+		}
+		return queueAction(args[:1], fmt.Sprintf("Job %%d now has priority %d", priority), func(id int, jobs *[]queue.Job) error {
+			return adminRequest(http.MethodPost, queueServerPort(), fmt.Sprintf("/v1/queue/priority?id=%d&priority=%d", id, priority), nil, jobs)
+		})
+	},
+}
+
+// queueAction runs request for each job ID in args, printing done after
+// each, or the resulting job list once with --json or --format.
+func queueAction(args []string, done string, request func(id int, jobs *[]queue.Job) error) error {
+	var jobs []queue.Job
+	for _, arg := range args {
+		id, err := strconv.Atoi(arg)
+		if err != nil {
+			return fmt.Errorf("invalid job ID %q", arg)
+		}
+		jobs = nil
+		if err := request(id, &jobs); err != nil {
+			return err
+		}
+		if !cli.MachineOutput() {
+			cli.PrintSuccess(done, id)
+		}
+	}
+	if cli.MachineOutput() {
+		return writeQueueResult(jobs)
+	}
+	return nil
+}
+
+// writeQueueResult prints a job or job list in the --format chosen.
+func writeQueueResult(v any) error {
+	jobs, ok := v.([]queue.Job)
+	if !ok {
+		jobs = []queue.Job{v.(queue.Job)}
+	}
+	writer := cli.NewOutputWriter(cli.Format())
+	switch writer.Format() {
+	case cli.FormatYAML:
+		return writer.WriteYAML(v)
+	case cli.FormatCSV:
+		rows := make([][]string, len(jobs))
+		for i, j := range jobs {
+			var at, every string
+			if !j.At.IsZero() {
+				at = j.At.Format(time.RFC3339)
+			}
+			if j.Every > 0 {
+				every = j.Every.String()
+			}
+			rows[i] = []string{strconv.Itoa(j.ID), j.Status, strconv.Itoa(j.Priority), strconv.Itoa(j.Attempts), strconv.Itoa(j.MaxAttempts),
+				j.Recipient(), strings.Join(j.Files, ";"), at, every, j.Error}
+		}
+		return writer.WriteCSV([]string{"id", "status", "priority", "attempts", "maxAttempts", "to", "files", "at", "every", "error"}, rows)
+	case cli.FormatTemplate:
+		items := make([]any, len(jobs))
+		for i, j := range jobs {
+			items[i] = j
+		}
+		return writer.WriteTemplate(items...)
+	default:
+		return writer.WriteJSON(v)
+	}
+}
+
+func queueServerPort() int {
+	if queueport != 0 {
+		return queueport
+	}
+	return Cfg.Port
+}
+
+// printQueue prints the jobs, one line each, with why the last attempt of
+// a job failed below it.
+func printQueue(jobs []queue.Job) {
+	if len(jobs) == 0 {
+		cli.PrintInfo("The queue is empty")
+		return
+	}
+	cli.PrintHeader(fmt.Sprintf("Send queue (%d job(s))", len(jobs)))
+	fmt.Printf("  %s  %s  %s  %s  %s  %s\n", padRight("ID", 4), padRight("STATUS", 8), padRight("PRIO", 4), padRight("TRIES", 5), padRight("FILES", 24), "TO")
+	for _, j := range jobs {
+		files := filepath.Base(j.Files[0])
+		if len(j.Files) > 1 {
+			files = fmt.Sprintf("%s +%d", cli.TruncateString(files, 18), len(j.Files)-1)
+		}
+		line := fmt.Sprintf("%s  %s  %s  %s  %s  %s",
+			padRight(strconv.Itoa(j.ID), 4),
+			padRight(j.Status, 8),
+			padRight(strconv.Itoa(j.Priority), 4),
+			padRight(fmt.Sprintf("%d/%d", j.Attempts, j.MaxAttempts), 5),
+			padRight(cli.TruncateString(files, 24), 24),
+			j.Recipient())
+		switch j.Status {
+		case queue.StatusSent:
+			cli.PrintSuccess("%s", line)
+		case queue.StatusFailed:
+			cli.PrintError("%s", line)
+		case queue.StatusSending:
+			cli.PrintInfo("%s", line)
+		default:
+			fmt.Printf("  %s\n", line)
+		}
+		if schedule := describeSchedule(&j); schedule != "" {
+			runs := ""
+			if j.Runs > 0 {
+				runs = fmt.Sprintf(", %d run(s), last %s", j.Runs, j.LastStatus)
+			}
+			fmt.Printf("        %s%s\n", strings.TrimPrefix(schedule, ", "), runs)
+		}
+		if j.Error != "" && j.Status != queue.StatusSent {
+			detail := j.Error
+			switch {
+			case j.Status != queue.StatusQueued || j.NextAttempt.IsZero():
+			case j.Every > 0 && j.NextAttempt.Equal(j.At):
+				detail = "last run failed: " + detail
+			default:
+				detail = fmt.Sprintf("retrying in %s: %s", cli.FormatDuration(time.Until(j.NextAttempt).Round(time.Second)), detail)
+			}
+			fmt.Printf("        %s\n", strings.TrimSpace(detail))
+		}
+	}
+}
+
+func init() {
+	queueCmd.PersistentFlags().IntVar(&queueport, "port", 0, "Port of the running server (default: from config)")
+	queueAddCmd.Flags().StringSliceVar(&queuefiles, "file", []string{}, "File, directory, or glob pattern to send (supports **)")
+	queueAddCmd.Flags().StringSliceVar(&queueexcludes, "exclude", []string{}, "Glob pattern of files to skip (can be repeated)")
+	queueAddCmd.Flags().StringVar(&queueto, "to", "", "Target device alias")
+	queueAddCmd.Flags().StringVar(&queueip, "ip", "", "Target device IP (with optional :port)")
+	queueAddCmd.Flags().StringVar(&queuetofingerprint, "to-fingerprint", "", "Target device by certificate fingerprint prefix")
+	queueAddCmd.Flags().StringVar(&queuefingerprint, "fingerprint", "", "Fingerprint (or prefix) of the target, to choose between devices sharing an alias")
+	queueAddCmd.Flags().IntVar(&queuepriority, "priority", 0, "Priority: higher runs first")
+	queueAddCmd.Flags().IntVar(&queueattempts, "attempts", queue.DefaultAttempts, "Tries before the job is marked failed")
+	queueAddCmd.Flags().StringVar(&queueat, "at", "", "Send at this time (HH:MM, \"YYYY-MM-DD HH:MM\" or RFC 3339)")
+	queueAddCmd.Flags().DurationVar(&queueevery, "every", 0, "Send again at this interval (e.g. 24h), until removed")
+	queueAddCmd.Flags().BoolVar(&queueskipdups, "skip-duplicates", false, "Skip files already delivered unchanged to this device")
+
+	queueCmd.AddCommand(queueAddCmd)
+	queueCmd.AddCommand(queueListCmd)
+	queueCmd.AddCommand(queueRemoveCmd)
+	queueCmd.AddCommand(queueRetryCmd)
+	queueCmd.AddCommand(queuePriorityCmd)
+	queueCmd.SetHelpFunc(func(cmd *cobra.Command, args []string) {
+		if h := help.GetCommandHelp("queue"); h != nil {
+			help.ShowCommandHelp(*h)
+		}
+	})
+	rootCmd.AddCommand(queueCmd)
+}
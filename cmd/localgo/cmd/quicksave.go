@@ -0,0 +1,187 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/bethropolis/localgo/pkg/cli"
+	"github.com/bethropolis/localgo/pkg/config"
+	"github.com/bethropolis/localgo/pkg/help"
+	"github.com/bethropolis/localgo/pkg/httputil"
+	"github.com/bethropolis/localgo/pkg/model"
+	"github.com/spf13/cobra"
+)
+
+var quickSavePort int
+
+var quickSaveCmd = &cobra.Command{
+	Use:   "quick-save [on [DURATION] | off | status]",
+	Short: "Toggle quick save on the running server",
+	Long:  `Quick save auto-accepts every incoming transfer while it is on, like the official app's "Quick Save". Reject rules and the PIN still apply.`,
+	Args:  cobra.MaximumNArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		action := "status"
+		if len(args) > 0 {
+			action = strings.ToLower(args[0])
+		}
+
+		port := quickSavePort
+		if port == 0 {
+			port = Cfg.Port
+		}
+
+		var method, query string
+		switch action {
+		case "status":
+			method = http.MethodGet
+		case "on":
+			method = http.MethodPost
+			if len(args) == 2 {
+				d, err := parseQuickSave(args[1])
+				if err != nil {
+					return err
+				}
+				if d > 0 {
+					query = "?duration=" + url.QueryEscape(d.String())
+				}
+			}
+		case "off":
+			method = http.MethodDelete
+		default:
+			return fmt.Errorf("unknown action %q: use on, off, or status", args[0])
+		}
+		if len(args) == 2 && action != "on" {
+			return fmt.Errorf("a duration can only be given with on")
+		}
+
+		var status model.QuickSaveDto
+		if err := adminRequest(method, port, "/v1/quick-save"+query, nil, &status); err != nil {
+			return err
+		}
+		printQuickSaveStatus(&status)
+		return nil
+	},
+}
+
+// parseQuickSave parses a --quick-save value: "on" keeps quick save enabled
+// until it is turned off, anything else is a duration such as "10m".
+func parseQuickSave(s string) (time.Duration, error) {
+	switch strings.ToLower(s) {
+	case "on", "true":
+		return 0, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid quick save duration %q: use on or a duration like 10m", s)
+	}
+	return d, nil
+}
+
+// adminSocketHost stands for the admin socket in admin API URLs.
+const adminSocketHost = "localgo-admin-socket"
+
+// adminRequest calls path on the admin API of the server on this machine,
+// over the admin socket if it has one, else trying HTTPS first and falling
+// back to HTTP, and decodes the answer into out. A non-nil body is sent as
+// JSON.
+func adminRequest(method string, port int, path string, body, out any) error {
+	// Without a token the server answers 401, reported below.
+	token, _ := config.ReadAdminToken(Cfg.AdminTokenPath)
+	tr := &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}
+	client := &http.Client{Timeout: 3 * time.Second, Transport: tr}
+	bases := []string{fmt.Sprintf("https://127.0.0.1:%d", port), fmt.Sprintf("http://127.0.0.1:%d", port)}
+
+	// The socket belongs to the server of this config, so it is only tried
+	// for that server's port.
+	if socket := Cfg.AdminSocket; socket != "" && port == Cfg.Port {
+		if _, err := os.Stat(socket); err == nil {
+			var d net.Dialer
+			tr.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+				if addr == adminSocketHost+":80" {
+					return d.DialContext(ctx, "unix", socket)
+				}
+				return d.DialContext(ctx, network, addr)
+			}
+			bases = append([]string{"http://" + adminSocketHost}, bases...)
+		}
+	}
+
+	var data []byte
+	if body != nil {
+		var err error
+		if data, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+
+	var lastErr error
+	for _, base := range bases {
+		req, err := http.NewRequest(method, base+"/api/localgo"+path, bytes.NewReader(data))
+		if err != nil {
+			return err
+		}
+		if body != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode == http.StatusUnauthorized {
+			return fmt.Errorf("the server on port %d did not accept the admin token in %s; run this as the user running the server, with the same config directory", port, Cfg.AdminTokenPath)
+		}
+		if resp.StatusCode != http.StatusOK {
+			var apiErr httputil.Error
+			if json.NewDecoder(resp.Body).Decode(&apiErr) == nil && apiErr.Error != "" {
+				return fmt.Errorf("server: %s", apiErr.Error)
+			}
+			return fmt.Errorf("server returned status %d", resp.StatusCode)
+		}
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return fmt.Errorf("invalid response from server: %w", err)
+		}
+		return nil
+	}
+	return fmt.Errorf("no LocalGo server reachable on port %d: %w", port, lastErr)
+}
+
+func printQuickSaveStatus(status *model.QuickSaveDto) {
+	if !status.Enabled {
+		cli.PrintInfo("Quick save: off")
+		return
+	}
+	if status.Until == nil {
+		cli.PrintSuccess("Quick save: on")
+		return
+	}
+	until, err := time.Parse(time.RFC3339, *status.Until)
+	if err != nil {
+		cli.PrintSuccess("Quick save: on until %s", *status.Until)
+		return
+	}
+	cli.PrintSuccess("Quick save: on until %s (%s left)", until.Format("15:04:05"), cli.FormatDuration(time.Until(until).Round(time.Second)))
+}
+
+func init() {
+	rootCmd.AddCommand(quickSaveCmd)
+	quickSaveCmd.Flags().IntVar(&quickSavePort, "port", 0, "Port of the running server (default: from config)")
+	quickSaveCmd.SetHelpFunc(func(cmd *cobra.Command, args []string) {
+		if h := help.GetCommandHelp("quick-save"); h != nil {
+			help.ShowCommandHelp(*h)
+		}
+	})
+}
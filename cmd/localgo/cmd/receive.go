@@ -0,0 +1,239 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/bethropolis/localgo/pkg/cli"
+	"github.com/bethropolis/localgo/pkg/config"
+	"github.com/bethropolis/localgo/pkg/help"
+	"github.com/bethropolis/localgo/pkg/report"
+	"github.com/bethropolis/localgo/pkg/server"
+	"github.com/bethropolis/localgo/pkg/server/services"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+// Exit codes of the receive command besides 0 (success) and 1 (error).
+const (
+	receiveExitTimeout     = 2   // --timeout passed before the expected files arrived
+	receiveExitTooMany     = 3   // more files arrived than --expect
+	receiveExitInterrupted = 130 // stopped with Ctrl+C or SIGTERM
+)
+
+var (
+	receiveexpect     int
+	receivefrom       string
+	receivetimeout    time.Duration
+	receivedir        string
+	receiveport       int
+	receiveuseHTTP    bool
+	receivepin        string
+	receivealias      string
+	receiveautoAccept bool
+	receivereport     string
+)
+
+var receiveCmd = &cobra.Command{
+	Use:          "receive",
+	Short:        "Wait for an expected transfer, print the saved paths, and exit",
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if receiveexpect < 0 {
+			return fmt.Errorf("--expect must not be negative")
+		}
+
+		if cmd.Flags().Changed("port") {
+			if receiveport < 0 || receiveport > 65535 {
+				return fmt.Errorf("invalid port %d", receiveport)
+			}
+			Cfg.Port = receiveport
+		}
+		if receiveuseHTTP {
+			Cfg.HttpsEnabled = false
+		}
+		if receivepin != "" {
+			Cfg.PIN = receivepin
+		}
+		if receivealias != "" {
+			Cfg.Alias = receivealias
+		}
+		if receivedir != "" {
+			Cfg.DownloadDir = receivedir
+		}
+		if receiveautoAccept {
+			Cfg.AutoAccept = true
+		}
+		if receivefrom != "" {
+			// Only the named device may send. An alias is only what the
+			// sender claims, so only a device that proves a fingerprint is
+			// spared the prompt, after the configured rules.
+			Cfg.AllowedSenders = []string{receivefrom}
+			if config.IsFingerprintPrefix(receivefrom) {
+				Cfg.AcceptRules = append(Cfg.AcceptRules, config.AcceptRule{Fingerprints: []string{receivefrom}, Action: config.AcceptActionAccept})
+			}
+		}
+		if cli.QuietOutput() {
+			Cfg.Quiet = true
+		}
+		if err := checkHeadlessPolicy(false); err != nil {
+			return err
+		}
+
+		if err := os.MkdirAll(Cfg.DownloadDir, 0755); err != nil {
+			return fmt.Errorf("failed to create download directory: %w", err)
+		}
+
+		// Stdout carries the received paths; everything else goes to stderr.
+		cli.SetPrintOutput(os.Stderr)
+
+		sigCtx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+		defer stop()
+		ctx, cancel := context.WithCancel(sigCtx)
+		defer cancel()
+
+		srv := server.NewServer(Cfg, zap.S().Named("server"))
+		started := time.Now()
+
+		var (
+			mu       sync.Mutex
+			paths    []string
+			count    int
+			reports  []*report.Report
+			done     atomic.Bool
+			timedOut atomic.Bool
+		)
+		srv.GetReceiveService().AddFileHandler(func(f services.ReceivedFile) {
+			mu.Lock()
+			defer mu.Unlock()
+			count++
+			if f.Path != "<clipboard>" && f.Path != "<terminal>" {
+				paths = append(paths, f.Path)
+			}
+		})
+		srv.GetReceiveService().AddSummaryHandler(func(r *report.Report) {
+			if !cli.QuietOutput() {
+				printTransferSummary(r)
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			reports = append(reports, r)
+		})
+		srv.GetReceiveService().AddCompletionHandler(func(sessionID string) {
+			mu.Lock()
+			n := count
+			mu.Unlock()
+			if receiveexpect == 0 || n >= receiveexpect {
+				done.Store(true)
+				cancel()
+			}
+		})
+		if receivetimeout > 0 {
+			timer := time.AfterFunc(receivetimeout, func() {
+				timedOut.Store(true)
+				cancel()
+			})
+			defer timer.Stop()
+		}
+
+		multicastPort := discoveryPort()
+		serverErrChan, err := startServer(ctx, srv)
+		if err != nil {
+			return err
+		}
+		discoverySvc, err := startAnnouncing(ctx, multicastPort, 0, cli.QuietOutput(), srv.GetRegistryService())
+		if err != nil {
+			return err
+		}
+
+		if !cli.QuietOutput() {
+			what := "a transfer"
+			if receiveexpect > 0 {
+				what = fmt.Sprintf("%d file(s)", receiveexpect)
+			}
+			if receivefrom != "" {
+				what += " from " + receivefrom
+			}
+			cli.PrintInfo("Receiving as %s on port %d, saving to %s", Cfg.Alias, Cfg.Port, Cfg.DownloadDir)
+			cli.PrintSuccess("Waiting for %s...", what)
+		}
+
+		serveErr := <-serverErrChan
+		discoverySvc.Stop()
+		if serveErr != nil {
+			return fmt.Errorf("server failed: %w", serveErr)
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		for _, p := range paths {
+			fmt.Println(p)
+		}
+
+		var result error
+		switch {
+		case done.Load() && receiveexpect > 0 && count > receiveexpect:
+			result = &exitError{receiveExitTooMany, fmt.Errorf("expected %d file(s), received %d", receiveexpect, count)}
+		case done.Load():
+		case timedOut.Load():
+			result = &exitError{receiveExitTimeout, fmt.Errorf("timed out after %s with %d file(s) received", receivetimeout, count)}
+		default:
+			result = &exitError{receiveExitInterrupted, errors.New("interrupted before the transfer completed")}
+		}
+
+		if receivereport != "" {
+			if err := receiveReport(reports, started, result).WriteFile(receivereport); err != nil {
+				if result == nil {
+					return err
+				}
+				cli.PrintError("%v", err)
+			}
+		}
+		return result
+	},
+}
+
+// receiveReport combines the reports of the sessions received into one,
+// covering the whole run when nothing arrived.
+func receiveReport(reports []*report.Report, started time.Time, err error) *report.Report {
+	var r *report.Report
+	if len(reports) > 0 {
+		r = report.Merge("receive", reports)
+	} else {
+		r = report.New("receive", "")
+		r.StartedAt = started
+		r.Finish(time.Now())
+	}
+	if err != nil {
+		r.Error = err.Error()
+	}
+	return r
+}
+
+func init() {
+	rootCmd.AddCommand(receiveCmd)
+
+	receiveCmd.Flags().IntVar(&receiveexpect, "expect", 0, "Number of files to wait for (0 = the first completed transfer)")
+	receiveCmd.Flags().StringVar(&receivefrom, "from", "", "Only accept transfers from the device with this alias, or this fingerprint (auto-accepted)")
+	receiveCmd.Flags().DurationVar(&receivetimeout, "timeout", 0, "Give up after this long, e.g. 10m (0 = wait forever)")
+	receiveCmd.Flags().StringVar(&receivedir, "dir", "", "Download directory (default: from config)")
+	receiveCmd.Flags().IntVar(&receiveport, "port", 0, "Port to listen on (0 = any free port; default: from config)")
+	receiveCmd.Flags().BoolVar(&receiveuseHTTP, "http", false, "Use HTTP instead of HTTPS")
+	receiveCmd.Flags().StringVar(&receivepin, "pin", "", "PIN required from the sender")
+	receiveCmd.Flags().StringVar(&receivealias, "alias", "", "Device alias (default: from config)")
+	receiveCmd.Flags().BoolVar(&receiveautoAccept, "auto-accept", false, "Accept transfers without prompting")
+	receiveCmd.Flags().StringVar(&receivereport, "report", "", "Write a JSON summary of the transfer to this file")
+
+	receiveCmd.SetHelpFunc(func(cmd *cobra.Command, args []string) {
+		if h := help.GetCommandHelp("receive"); h != nil {
+			help.ShowCommandHelp(*h)
+		}
+	})
+}
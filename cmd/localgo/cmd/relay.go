@@ -0,0 +1,144 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/bethropolis/localgo/pkg/cli"
+	"github.com/bethropolis/localgo/pkg/config"
+	"github.com/bethropolis/localgo/pkg/help"
+	"github.com/bethropolis/localgo/pkg/httputil"
+	"github.com/bethropolis/localgo/pkg/model"
+	"github.com/bethropolis/localgo/pkg/relay"
+	"github.com/bethropolis/localgo/pkg/send"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var (
+	relayport  int
+	relayallow []string
+)
+
+var relayCmd = &cobra.Command{
+	Use:          "relay",
+	Short:        "Run a relay that brokers transfers between devices on different networks",
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		for i, fp := range relayallow {
+			relayallow[i] = strings.ToLower(strings.TrimSpace(fp))
+			if !config.ValidFingerprint(relayallow[i]) {
+				return fmt.Errorf("invalid --allow fingerprint %q: use the full 64-digit fingerprint", fp)
+			}
+		}
+		cert, err := Cfg.TLSCertificate()
+		if err != nil {
+			return fmt.Errorf("failed to load TLS key pair: %w", err)
+		}
+		ln, err := net.Listen("tcp", fmt.Sprintf(":%d", relayport))
+		if err != nil {
+			return fmt.Errorf("failed to bind port: %w", err)
+		}
+
+		cli.PrintHeader("Starting LocalGo relay")
+		cli.PrintInfo("Port: %d", ln.Addr().(*net.TCPAddr).Port)
+		cli.PrintInfo("Fingerprint: %s", relay.CertificateFingerprint(cert))
+		if len(relayallow) == 0 {
+			cli.PrintWarning("No --allow fingerprints: any device can register and send through this relay")
+		} else {
+			cli.PrintInfo("Allowed devices: %d", len(relayallow))
+		}
+		cli.PrintWarning("Press Ctrl+C to stop")
+
+		ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+		defer stop()
+		srv := relay.NewServer(relayallow, zap.S().Named("relay"))
+		if err := srv.Serve(ctx, ln, cert); err != nil {
+			return fmt.Errorf("relay failed: %w", err)
+		}
+		cli.PrintInfo("Relay stopped")
+		return nil
+	},
+}
+
+// newRelayClient returns a client of the relay in the config.
+func newRelayClient() (*relay.Client, error) {
+	if Cfg.Relay == "" {
+		return nil, fmt.Errorf("no relay: set relay in the config (see localgo relay)")
+	}
+	u, err := config.ParseRelay(Cfg.Relay)
+	if err != nil {
+		return nil, err
+	}
+	cert, err := Cfg.TLSCertificate()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load TLS key pair: %w", err)
+	}
+	if Cfg.RelayFingerprint == "" {
+		cli.PrintWarning("relay_fingerprint is not set: the relay's identity is not checked")
+	}
+	return relay.NewClient(u, Cfg.RelayFingerprint, cert, httputil.Transport(), zap.S().Named("relay")), nil
+}
+
+// relayRecipient finds the device to send to through the relay in the
+// config, given by the relay pairing code or fingerprint passed to send,
+// and returns it with the option that routes the send there.
+func relayRecipient(ctx context.Context) (*model.Device, send.SendOption, error) {
+	client, err := newRelayClient()
+	if err != nil {
+		return nil, nil, err
+	}
+	var fingerprint string
+	if sendcode != "" {
+		fingerprint, err = client.LookupCode(ctx, sendcode)
+	} else {
+		fingerprint, err = client.Resolve(ctx, sendfingerprint)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	rt := client.Transport(fingerprint)
+
+	// The relay vouches for the fingerprint; the device's info supplies the
+	// rest, such as the protocol version.
+	device := &model.Device{
+		IP:          client.Host(),
+		Port:        config.DefaultPort,
+		Protocol:    model.ProtocolTypeHTTPS,
+		Fingerprint: fingerprint,
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://relay/api/localsend/v2/info", nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	resp, err := (&http.Client{Transport: rt}).Do(req)
+	if err != nil {
+		return nil, nil, fmt.Errorf("relay: %w", err)
+	}
+	defer resp.Body.Close()
+	var info model.InfoDto
+	if resp.StatusCode != http.StatusOK || json.NewDecoder(resp.Body).Decode(&info) != nil {
+		return nil, nil, fmt.Errorf("relay: the device did not answer through the relay (%s)", resp.Status)
+	}
+	device.Alias = info.Alias
+	device.Version = info.Version
+	return device, send.WithTransport(rt), nil
+}
+
+func init() {
+	relayCmd.Flags().IntVar(&relayport, "port", config.DefaultRelayPort, "Port to listen on")
+	relayCmd.Flags().StringSliceVar(&relayallow, "allow", nil, "Fingerprint of a device allowed to register and send (repeatable; default: any device)")
+	relayCmd.SetHelpFunc(func(cmd *cobra.Command, args []string) {
+		if h := help.GetCommandHelp("relay"); h != nil {
+			help.ShowCommandHelp(*h)
+		}
+	})
+	rootCmd.AddCommand(relayCmd)
+}
@@ -1,13 +1,18 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"time"
 
+	"github.com/bethropolis/localgo/pkg/capture"
 	"github.com/bethropolis/localgo/pkg/cli"
 	"github.com/bethropolis/localgo/pkg/clipboard"
 	"github.com/bethropolis/localgo/pkg/config"
+	"github.com/bethropolis/localgo/pkg/crypto"
 	"github.com/bethropolis/localgo/pkg/help"
+	"github.com/bethropolis/localgo/pkg/httputil"
 	"github.com/bethropolis/localgo/pkg/logging"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -18,6 +23,14 @@ var (
 	versionFlag bool
 	privateMode  bool
 	noColor     bool
+	noEmoji      bool
+	quietMode    bool
+	outputFormat string
+	headlessMode bool
+	logLevel     string
+	logFile      string
+	logTarget    string
+	debugCapture string
 )
 
 var (
@@ -47,29 +60,64 @@ var rootCmd = &cobra.Command{
 		if noColor || os.Getenv("NO_COLOR") != "" {
 			noColor = true
 		}
-
-		logger := logging.Init(Verbose, JSONOutput, noColor)
+		format, tmpl, formatErr := cli.ParseFormat(outputFormat)
+		if formatErr != nil {
+			return formatErr
+		}
+		if JSONOutput && format != cli.FormatJSON && cmd.Flags().Changed("format") {
+			return fmt.Errorf("cannot use both --json and --format %s", format)
+		}
+		JSONOutput = JSONOutput || format == cli.FormatJSON
+		cli.SetOutput(cli.OutputOptions{JSON: JSONOutput, Format: format, Template: tmpl, Quiet: quietMode, NoColor: noColor, NoEmoji: noEmoji})
 
 		ViperCfg = config.InitViper()
+		var cfgFileErr error
 		if cfgFile != "" {
 			ViperCfg.SetConfigFile(cfgFile)
-			if err := ViperCfg.ReadInConfig(); err != nil {
-				zap.S().Warnf("Failed to read config file: %v", err)
+			cfgFileErr = ViperCfg.ReadInConfig()
+		}
+
+		// Headless mode is known before logging starts, since it moves the
+		// log to stdout as JSON.
+		headless := headlessMode || ViperCfg.GetString("headless") == "true" || ViperCfg.GetString("headless") == "1"
+		logger, logErr := logging.Setup(logOptions(headless))
+		if headless {
+			cli.SetHeadless(true)
+			cli.SetPrintOutput(os.Stderr)
+		}
+		if logErr != nil {
+			zap.S().Warnf("Logging: %v", logErr)
+		}
+		if cfgFileErr != nil {
+			zap.S().Warnf("Failed to read config file: %v", cfgFileErr)
+		}
+		if debugCapture != "" {
+			if err := capture.Start(debugCapture); err != nil {
+				return fmt.Errorf("failed to open --debug-capture file: %w", err)
 			}
+			zap.S().Infof("Recording protocol exchanges to %s", debugCapture)
 		}
 
 		var err error
-		Cfg, err = config.LoadConfig(ViperCfg, logger)
+		Cfg, err = config.LoadConfig(ViperCfg, logger.Named("config"))
 		if err != nil {
 			return fmt.Errorf("failed to load configuration: %w", err)
 		}
 		if Cfg.SecurityContext == nil {
 			return fmt.Errorf("security context is missing after loading config")
 		}
+		if Cfg.HttpsEnabled {
+			warnCertificateExpiry()
+		}
 
 		if privateMode {
 			Cfg.Private = true
 		}
+		if headless {
+			Cfg.ApplyHeadless()
+		}
+
+		httputil.ConfigureTransport(transportOptions())
 
 		if Cfg.ClipboardWriteCmd != "" || Cfg.ClipboardReadCmd != "" {
 			clipboard.OverrideProvider(Cfg.ClipboardWriteCmd, Cfg.ClipboardReadCmd)
@@ -82,8 +130,41 @@ var rootCmd = &cobra.Command{
 	},
 }
 
+// warnCertificateExpiry warns when the device's TLS certificate has expired
+// or is about to, so it is renewed before anyone has to work out why.
+func warnCertificateExpiry() {
+	cert, err := deviceCertificate()
+	if err != nil {
+		return // reported where the certificate is used
+	}
+	status := crypto.ExpiryStatus(cert, time.Now())
+	if status == "" {
+		return
+	}
+	renew := "run `localgo info --regenerate-cert` to replace it"
+	if Cfg.Identity != nil || Cfg.CustomTLSCertPath != "" {
+		renew = "renew the certificate given with --tls-cert"
+	}
+	zap.S().Warnf("The TLS certificate %s (%s); %s", status, cert.NotAfter.Format(time.DateOnly), renew)
+}
+
+// exitError makes Execute exit with a specific status code instead of 1.
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string { return e.err.Error() }
+func (e *exitError) Unwrap() error { return e.err }
+
 func Execute() {
-	if err := rootCmd.Execute(); err != nil {
+	err := rootCmd.Execute()
+	capture.Stop()
+	if err != nil {
+		var ee *exitError
+		if errors.As(err, &ee) {
+			os.Exit(ee.code)
+		}
 		os.Exit(1)
 	}
 }
@@ -93,10 +174,68 @@ func init() {
 	rootCmd.PersistentFlags().BoolVarP(&privateMode, "private", "p", false, "Hide device identity (alias, model) during discovery and transfer")
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.config/localgo/config.yaml)")
 	rootCmd.PersistentFlags().BoolVar(&Verbose, "verbose", false, "Enable debug logging")
-	rootCmd.PersistentFlags().BoolVar(&JSONOutput, "json", false, "Enable JSON log output")
+	rootCmd.PersistentFlags().BoolVar(&JSONOutput, "json", false, "Print results as JSON (messages go to stderr, logs are JSON lines)")
+	rootCmd.PersistentFlags().StringVar(&outputFormat, "format", "table", "Result format: table, json, yaml, csv or template=TEMPLATE (a Go template run per item)")
+	rootCmd.PersistentFlags().BoolVarP(&quietMode, "quiet", "q", false, "Only print results, warnings and errors")
 	rootCmd.PersistentFlags().BoolVar(&noColor, "no-color", false, "Disable colored output")
+	rootCmd.PersistentFlags().BoolVar(&noEmoji, "no-emoji", false, "Use plain text instead of Nerd Font icons")
+	rootCmd.PersistentFlags().StringVar(&logLevel, "log-level", "", "Log level: debug, info, warn or error (default info)")
+	rootCmd.PersistentFlags().StringVar(&logFile, "log-file", "", "Log file, rotated by size (- = stderr; default ~/.local/state/localgo/app.log)")
+	rootCmd.PersistentFlags().StringVar(&logTarget, "log-target", "", "Where logs go: file, journald, syslog or syslog+udp://HOST[:PORT] (default file)")
+	rootCmd.PersistentFlags().BoolVar(&headlessMode, "headless", false, "Run without a user at the machine: no prompts, JSON logs on stdout")
+	rootCmd.PersistentFlags().StringVar(&debugCapture, "debug-capture", "", "Record multicast packets and HTTP exchanges with peers to this JSON lines file")
 
 	rootCmd.SetHelpFunc(func(cmd *cobra.Command, args []string) {
 		help.ShowMainUsage()
 	})
 }
+
+// transportOptions returns the options of the transport shared by requests
+// to peers, from the config.
+func transportOptions() httputil.TransportOptions {
+	opts := httputil.DefaultTransportOptions()
+	if Cfg.ConnectTimeout > 0 {
+		opts.DialTimeout = Cfg.ConnectTimeout
+	}
+	opts.MaxConnsPerHost = Cfg.MaxConnsPerHost
+	opts.SourceIP = Cfg.SourceIP
+	opts.Proxy = Cfg.Proxy
+	if cert, err := Cfg.TLSCertificate(); err == nil {
+		opts.ClientCertificate = &cert
+	}
+	return opts
+}
+
+// logOptions gathers the logging settings from flags, environment and config
+// file. They are read from ViperCfg directly because logging starts before
+// the rest of the configuration is loaded.
+func logOptions(headless bool) logging.Options {
+	opts := logging.Options{
+		Verbose:    Verbose,
+		JSON:       JSONOutput,
+		NoColor:    noColor,
+		Headless:   headless,
+		Level:      ViperCfg.GetString("log_level"),
+		Levels:     ViperCfg.GetString("log_levels"),
+		Target:     ViperCfg.GetString("log_target"),
+		File:       ViperCfg.GetString("log_file"),
+		MaxSizeMB:  logging.DefaultMaxSizeMB,
+		MaxBackups: logging.DefaultMaxBackups,
+	}
+	if logLevel != "" {
+		opts.Level = logLevel
+	}
+	if logFile != "" {
+		opts.File = logFile
+	}
+	if logTarget != "" {
+		opts.Target = logTarget
+	}
+	if ViperCfg.IsSet("log_max_size") {
+		opts.MaxSizeMB = ViperCfg.GetInt("log_max_size")
+	}
+	if ViperCfg.IsSet("log_max_backups") {
+		opts.MaxBackups = ViperCfg.GetInt("log_max_backups")
+	}
+	return opts
+}
@@ -18,17 +18,20 @@ import (
 )
 
 var (
-	scanrange      string
-	scantimeout    int
-	scanport       int
-	scanjsonOutput bool
-	scanquiet      bool
+	scanrange   string
+	scantimeout int
+	scanport    int
+	scanknown   bool
 )
 
 var scanCmd = &cobra.Command{
 	Use:   "scan",
 	Short: "Scan the network for LocalGo devices using HTTP",
 	RunE: func(cmd *cobra.Command, args []string) error {
+		filter, err := deviceFilter()
+		if err != nil {
+			return err
+		}
 
 		scanPort := Cfg.Port
 		if scanport > 0 {
@@ -43,7 +46,7 @@ var scanCmd = &cobra.Command{
 				return fmt.Errorf("invalid --range CIDR: %w", err)
 			}
 			ips = parsedIPs
-			if !scanquiet {
+			if !cli.QuietOutput() {
 				cli.PrintHeader(fmt.Sprintf("Scanning CIDR range %s on port %d (timeout: %ds)...", scanrange, scanPort, scantimeout))
 				cli.PrintInfo("Scanning %d IP addresses...", len(ips))
 				cli.PrintInfo("Protocols: HTTPS first, then HTTP fallback")
@@ -72,7 +75,7 @@ var scanCmd = &cobra.Command{
 				}
 			}
 
-			if !scanquiet {
+			if !cli.QuietOutput() {
 				cli.PrintHeader(fmt.Sprintf("Scanning network on port %d (timeout: %ds)...", scanPort, scantimeout))
 				cli.PrintInfo("Scanning %d IP addresses (derived from %d local interfaces)...", len(ips), len(localIPs))
 				cli.PrintInfo("Protocols: HTTPS first, then HTTP fallback")
@@ -80,7 +83,11 @@ var scanCmd = &cobra.Command{
 		}
 
 		// Initialize HTTP discovery
-		httpDiscoverer := discovery.NewHTTPDiscovery(nil, Cfg.ToRegisterDto(), nil, zap.S())
+		scanConfig := discovery.DefaultHTTPDiscoveryConfig()
+		if scanknown {
+			scanConfig.SkipSweep = func([]*model.Device) bool { return true }
+		}
+		httpDiscoverer := discovery.NewHTTPDiscovery(scanConfig, Cfg.ToRegisterDto(), nil, zap.S().Named("discovery"))
 
 		// Perform scan
 		scanCtx, cancel := context.WithTimeout(context.Background(), time.Duration(scantimeout)*time.Second)
@@ -89,7 +96,7 @@ var scanCmd = &cobra.Command{
 		var foundDevices []*model.Device
 		var scanErr error
 
-		if !scanquiet {
+		if !cli.QuietOutput() {
 			_ = spinner.New().
 				Title(fmt.Sprintf("Scanning %d IP addresses on port %d...", len(ips), scanPort)).
 				Action(func() {
@@ -100,7 +107,7 @@ var scanCmd = &cobra.Command{
 			foundDevices, scanErr = httpDiscoverer.ScanNetwork(scanCtx, ips, scanPort)
 		}
 
-		if scanErr != nil && !scanquiet {
+		if scanErr != nil && !cli.QuietOutput() {
 			zap.S().Warnf("Scan completed with warnings: %v", scanErr)
 			cli.PrintWarning("Scan completed with warnings: %v", scanErr)
 		}
@@ -116,12 +123,19 @@ var scanCmd = &cobra.Command{
 			return false
 		})
 
-		if !scanquiet && len(foundDevices) == 0 {
+		if !cli.QuietOutput() && len(foundDevices) == 0 {
 			zap.S().Warnf("No devices found during scan")
-			cli.PrintWarning("No devices found during scan. Check your firewall or network.")
+			cli.PrintWarning("No devices found during scan. Run `localgo doctor` to check your network and firewall.")
+		}
+		if n := len(foundDevices); !filter.Empty() {
+			foundDevices = filter.Apply(foundDevices)
+			if !cli.QuietOutput() && n > 0 && len(foundDevices) < n {
+				cli.PrintInfo("%d of %d device(s) match the filters", len(foundDevices), n)
+			}
 		}
 
-		return displayDevices(foundDevices, scanjsonOutput, scanquiet, "HTTP scan")
+		annotateDevices(foundDevices, annotateTimeout)
+		return displayDevices(foundDevices, "HTTP scan")
 	},
 }
 
@@ -130,8 +144,8 @@ func init() {
 	scanCmd.Flags().StringVar(&scanrange, "range", "", "CIDR range to scan (e.g. 192.168.1.0/24)")
 	scanCmd.Flags().IntVar(&scantimeout, "timeout", 15, "Scan timeout in seconds")
 	scanCmd.Flags().IntVar(&scanport, "port", 0, "Port to scan")
-	scanCmd.Flags().BoolVar(&scanjsonOutput, "json", false, "Output in JSON format")
-	scanCmd.Flags().BoolVar(&scanquiet, "quiet", false, "Quiet mode")
+	scanCmd.Flags().BoolVar(&scanknown, "neighbors-only", false, "Only probe hosts in the neighbor table (ARP cache), not the whole range")
+	addFilterFlags(scanCmd)
 
 	scanCmd.SetHelpFunc(func(cmd *cobra.Command, args []string) {
 		if h := help.GetCommandHelp("scan"); h != nil {
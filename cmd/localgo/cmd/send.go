@@ -2,29 +2,38 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net"
 	"os"
+	"os/signal"
 	"path/filepath"
-	"strconv"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/bethropolis/localgo/pkg/cli"
 	"github.com/bethropolis/localgo/pkg/clipboard"
 	"github.com/bethropolis/localgo/pkg/discovery"
 	"github.com/bethropolis/localgo/pkg/help"
+	"github.com/bethropolis/localgo/pkg/httputil"
 	"github.com/bethropolis/localgo/pkg/model"
+	"github.com/bethropolis/localgo/pkg/pairing"
 	"github.com/bethropolis/localgo/pkg/network"
+	"github.com/bethropolis/localgo/pkg/queue"
+	"github.com/bethropolis/localgo/pkg/report"
 	"github.com/bethropolis/localgo/pkg/send"
 	"github.com/charmbracelet/huh/spinner"
 	"github.com/spf13/cobra"
 	"go.uber.org/zap"
+	"golang.org/x/term"
 )
 
 var (
 	sendfiles       []string
+	sendexcludes    []string
+	sendas          string
 	sendip          string
 	sendto          string
 	sendport        int
@@ -34,19 +43,120 @@ var (
 	sendmulticastiface string
 	sendclipboard   bool
 	sendstdin       bool
+	sendprogress    string
+	sendfailfast    bool
+	sendfingerprint string
+	sendtofingerprint string
+	sendsize        int64
+	sendreport      string
+	sendstream      *send.Stream // binary data streamed from stdin with "send -"
+	sendat          string
+	sendevery       time.Duration
+	sendskipdups    bool
+	sendcompress    bool
+	sendpreview     bool
+	sendwake        bool
+	sendsourceip    string
+	sendinterface   string
+	sendrelay       bool
+	sendcode        string
 )
 
 var sendCmd = &cobra.Command{
-	Use:          "send",
+	Use:          "send [-]",
 	Short:        "Send a file to another LocalGo device",
 	SilenceUsage: true,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		files := sendfiles
+		if err := applyProgressFormat(sendprogress); err != nil {
+			return err
+		}
+
 		var sendOpts []send.SendOption
 
+		// "-" as the argument or a --file value streams binary data from stdin.
+		var files []string
+		streamStdin := false
+		for _, arg := range args {
+			if arg != "-" {
+				return fmt.Errorf("unexpected argument %q: use --file to select files", arg)
+			}
+			streamStdin = true
+		}
+		for _, file := range sendfiles {
+			if file == "-" {
+				streamStdin = true
+				continue
+			}
+			files = append(files, file)
+		}
+
 		if sendclipboard && sendstdin {
 			return fmt.Errorf("cannot use both --clipboard and --stdin")
 		}
+		if streamStdin && (sendclipboard || sendstdin || len(files) > 0) {
+			return fmt.Errorf("streaming from stdin with '-' cannot be combined with other files, --clipboard, or --stdin")
+		}
+		if sendsize != 0 && !streamStdin {
+			return fmt.Errorf("--size can only be used when streaming from stdin with '-'")
+		}
+
+		// A scheduled send is handed to the running server's queue.
+		if sendat != "" || sendevery != 0 {
+			if streamStdin || sendclipboard || sendstdin || sendas != "" || sendreport != "" {
+				return fmt.Errorf("--at and --every only work with --file, not with '-', --clipboard, --stdin, --as or --report")
+			}
+			if sendcompress {
+				return fmt.Errorf("--compress does not apply to queued sends: set compress in the server's config instead")
+			}
+			if sendpreview {
+				return fmt.Errorf("--preview does not apply to queued sends: set send_previews in the server's config instead")
+			}
+			if sendwake {
+				return fmt.Errorf("--wake does not apply to queued sends")
+			}
+			if sendsourceip != "" || sendinterface != "" {
+				return fmt.Errorf("--source-ip and --interface do not apply to queued sends")
+			}
+			if sendrelay || sendcode != "" {
+				return fmt.Errorf("--relay and --code do not apply to queued sends")
+			}
+			return enqueueSend(Cfg.Port, files, sendip, sendtofingerprint, sendat, sendevery, queue.Job{
+				Excludes:       sendexcludes,
+				SkipDuplicates: sendskipdups,
+				To:             sendto,
+				Fingerprint:    sendfingerprint,
+				Port:           sendport,
+			})
+		}
+
+		if streamStdin {
+			name := sendas
+			if name == "" {
+				name = "stdin"
+			}
+			if strings.ContainsAny(name, `/\`) || name == "." || name == ".." {
+				return fmt.Errorf("invalid --as name: %s", name)
+			}
+
+			size := sendsize
+			if size < 0 {
+				return fmt.Errorf("invalid --size: %d", size)
+			}
+			if size == 0 {
+				// The receiver needs the size up front; without --size the
+				// stream is spooled to a temporary file first.
+				size = send.SizeUnknown
+			}
+			stream, err := send.NewStream(name, size, cmd.InOrStdin())
+			if errors.Is(err, send.ErrEmptyStream) {
+				return fmt.Errorf("standard input is empty")
+			} else if err != nil {
+				return err
+			}
+			defer stream.Close()
+			sendstream = stream
+			sendOpts = append(sendOpts, stream.Option())
+		}
 
 		if sendstdin {
 			textBytes, err := io.ReadAll(cmd.InOrStdin())
@@ -81,224 +191,583 @@ var sendCmd = &cobra.Command{
 			return fmt.Errorf("no file specified: use --file flag, --clipboard, or select from the file browser")
 		}
 
+		if len(files) > 0 {
+			expanded, names, err := send.ExpandGlobsWithNames(files, sendexcludes)
+			if err != nil {
+				return err
+			}
+			if len(expanded) == 0 && len(sendOpts) == 0 {
+				return fmt.Errorf("no files left to send after applying --exclude")
+			}
+			files = expanded
+			if len(names) > 0 {
+				sendOpts = append(sendOpts, send.WithFileNames(names))
+			}
+		}
+
 		for _, file := range files {
 			if _, err := os.Stat(file); os.IsNotExist(err) && len(sendOpts) == 0 {
 				return fmt.Errorf("file not found: %s", file)
 			}
 		}
 
-		// Direct send via --ip: skip discovery entirely
-		if sendip != "" {
-			host, portStr, err := net.SplitHostPort(sendip)
-			if err != nil {
-				// No port specified; treat whole string as host
-				host = sendip
-				portStr = ""
-			}
-			parsedIP := net.ParseIP(host)
-			if parsedIP == nil {
-				// Not a raw IP — try hostname resolution (mDNS, DNS, etc.)
-				ips, err := net.LookupIP(host)
-				if err != nil || len(ips) == 0 {
-					return fmt.Errorf("invalid IP address or unresolvable hostname: %s", host)
-				}
-				parsedIP = ips[0].To4()
-				if parsedIP == nil {
-					// Use first result even if it's IPv6; the caller handles it
-					parsedIP = ips[0]
-				}
-			}
+		if len(sendexcludes) > 0 {
+			sendOpts = append(sendOpts, send.WithExcludes(sendexcludes...))
+		}
 
-			port := sendport
-			if portStr != "" {
-				p, err := strconv.Atoi(portStr)
-				if err != nil {
-					return fmt.Errorf("invalid port in --ip: %w", err)
-				}
-				port = p
+		if sendas != "" && !streamStdin {
+			if len(files) != 1 || sendclipboard || sendstdin {
+				return fmt.Errorf("--as can only be used when sending a single file")
 			}
-			if port == 0 {
-				port = Cfg.Port
+			if info, err := os.Stat(files[0]); err == nil && info.IsDir() {
+				return fmt.Errorf("--as cannot be used with a directory: %s", files[0])
 			}
-
-			device := &model.Device{
-				Alias: host,
-				IP:    parsedIP.String(),
-				Port:  port,
+			if strings.ContainsAny(sendas, `/\`) || sendas == "." || sendas == ".." {
+				return fmt.Errorf("invalid --as name: %s", sendas)
 			}
+			sendOpts = append(sendOpts, send.WithRemoteName(sendas))
+		}
 
-			if sendalias != "" {
-				Cfg.Alias = sendalias
+		var result send.SendResult
+		sendOpts = append(sendOpts, send.WithResult(&result))
+		if sendfailfast {
+			sendOpts = append(sendOpts, send.WithFailFast())
+		}
+		if sendfingerprint != "" && sendtofingerprint != "" {
+			return fmt.Errorf("cannot use both --fingerprint and --to-fingerprint")
+		}
+		if sendtofingerprint != "" {
+			if len(sendtofingerprint) < 4 {
+				return fmt.Errorf("--to-fingerprint needs at least 4 characters")
 			}
-			if sendconcurrency > 0 {
-				Cfg.Concurrency = sendconcurrency
+			sendfingerprint = sendtofingerprint
+		}
+		if sendfingerprint != "" {
+			sendOpts = append(sendOpts, send.WithRecipientFingerprint(sendfingerprint))
+		}
+		if sendskipdups {
+			ledger, err := openSendLedger()
+			if err != nil {
+				return err
 			}
-
-			totalFiles := len(files) + len(sendOpts)
-			cli.PrintHeader(fmt.Sprintf("Sending %d file(s)", totalFiles))
-			for _, file := range files {
-				fileInfo, err := os.Stat(file)
-				if err == nil {
-					cli.PrintInfo("- %s (%s)", filepath.Base(file), cli.FormatBytes(fileInfo.Size()))
+			sendOpts = append(sendOpts, send.WithLedger(ledger))
+			defer func() {
+				if err := ledger.Save(); err != nil {
+					cli.PrintWarning("Failed to save the list of sent files: %v", err)
 				}
+			}()
+		}
+
+		// A relay pairing code is resolved by the relay; a direct one holds
+		// the recipient's address.
+		if sendcode != "" {
+			if sendip != "" || sendto != "" || sendfingerprint != "" || sendwake {
+				return fmt.Errorf("--code identifies the recipient: it cannot be combined with --ip, --to, --to-fingerprint, --fingerprint or --wake")
 			}
-			if sendclipboard {
-				cli.PrintInfo("- clipboard (in-memory)")
+			if pairing.IsRelayCode(sendcode) {
+				sendrelay = true
+			} else if _, err := pairing.Decode(sendcode); err != nil {
+				return fmt.Errorf("invalid --code %q: check it for typos", sendcode)
+			} else if sendrelay {
+				return fmt.Errorf("--code %s is not a relay code: use it without --relay", sendcode)
 			}
-			if sendstdin {
-				cli.PrintInfo("- stdin (in-memory)")
+		}
+		if sendrelay && (sendip != "" || sendto != "" || sendwake) {
+			return fmt.Errorf("--relay reaches the recipient by --to-fingerprint alone: it cannot be combined with --ip, --to or --wake")
+		}
+		if sendrelay && sendcode == "" && sendfingerprint == "" {
+			return fmt.Errorf("--relay needs the recipient: use --to-fingerprint or a relay --code")
+		}
+
+		if err := applySendSource(); err != nil {
+			return err
+		}
+
+		if sendwake {
+			if err := wakeRecipient(); err != nil {
+				return err
 			}
-			cli.PrintInfo("To: %s:%d", host, port)
+		}
+
+		// Through the relay: the recipient is on another network
+		if sendrelay {
+			applySendOverrides()
+
+			ctx, cancel := sendContext()
+			defer cancel()
+			device, relayOpt, err := relayRecipient(ctx)
+			if err != nil {
+				return err
+			}
+			sendOpts = append(sendOpts, relayOpt)
+
+			printSendSummary(files)
+			cli.PrintInfo("To: %s (through the relay %s)", device.Alias, device.IP)
 			fromAlias := Cfg.Alias
 			if Cfg.Private {
 				fromAlias = "Anonymous"
 			}
 			cli.PrintInfo("From: %s", fromAlias)
 
-			ctx, cancel := context.WithTimeout(context.Background(), time.Duration(sendtimeout)*time.Second)
+			started := time.Now()
+			err = send.SendToDevice(ctx, Cfg, device, files, zap.S().Named("send"), sendOpts...)
+			return finishSend(&result, device.Alias, started, err)
+		}
+
+		// Direct pairing code: the address is in the code
+		if sendcode != "" {
+			applySendOverrides()
+
+			ctx, cancel := sendContext()
 			defer cancel()
+			device, err := codeRecipient(ctx, sendcode)
+			if err != nil {
+				return err
+			}
 
-			if err := send.SendToDevice(ctx, Cfg, device, files, zap.S(), sendOpts...); err != nil {
-				return fmt.Errorf("failed to send files: %w", err)
+			printSendSummary(files)
+			cli.PrintInfo("To: %s (%s:%d)", device.Alias, device.IP, device.Port)
+			fromAlias := Cfg.Alias
+			if Cfg.Private {
+				fromAlias = "Anonymous"
 			}
+			cli.PrintInfo("From: %s", fromAlias)
 
-			cli.PrintSuccess("Files sent successfully!")
-			return nil
+			started := time.Now()
+			err = send.SendToDevice(ctx, Cfg, device, files, zap.S().Named("send"), sendOpts...)
+			return finishSend(&result, device.Alias, started, err)
 		}
 
-		target := sendto
-		var selectedDevice *model.Device
+		// Direct send via --ip: skip discovery entirely
+		if sendip != "" {
+			device, err := parseDeviceAddress(sendip, sendport)
+			if err != nil {
+				return err
+			}
+			host, port := device.Alias, device.Port
 
-		if target == "" {
-			sendConfig := discovery.DefaultServiceConfig()
-			sendConfig.MulticastConfig.InterfaceName = Cfg.MulticastInterface
+			applySendOverrides()
 
-			var devices []*model.Device
-			var discErr error
+			printSendSummary(files)
+			cli.PrintInfo("To: %s:%d", host, port)
+			fromAlias := Cfg.Alias
+			if Cfg.Private {
+				fromAlias = "Anonymous"
+			}
+			cli.PrintInfo("From: %s", fromAlias)
 
-			_ = spinner.New().
-				Title("Looking for devices on your network (multicast)...").
-				Action(func() {
-					devices, discErr = discovery.DiscoverDevices(
-						context.Background(),
-						sendConfig,
-						Cfg,
-						Cfg.HttpsEnabled,
-					)
-				}).
-				Run()
+			ctx, cancel := sendContext()
+			defer cancel()
 
-			if discErr != nil {
-				return fmt.Errorf("discovery failed: %w", discErr)
-			}
-
-			// Unicast fallback: if multicast finds nothing, automatically scan subnets
-			if len(devices) == 0 {
-				localIPs, ipErr := network.GetLocalIPAddresses()
-				if ipErr == nil && len(localIPs) > 0 {
-					// Prioritize the subnet connected to the default gateway
-					if gwIP, err := network.PrimaryLANIP(); err == nil {
-						for i, ip := range localIPs {
-							if ip.Equal(gwIP) && i > 0 {
-								localIPs[0], localIPs[i] = localIPs[i], localIPs[0]
-								break
-							}
-						}
-					}
+			started := time.Now()
+			err = send.SendToDevice(ctx, Cfg, device, files, zap.S().Named("send"), sendOpts...)
+			return finishSend(&result, host, started, err)
+		}
 
-					_ = spinner.New().
-						Title("No devices via multicast. Scanning local subnets...").
-						Action(func() {
-							registerDto := Cfg.ToRegisterDto()
-							httpFallback := discovery.NewHTTPDiscovery(nil, registerDto, nil, nil)
-
-							var ips []net.IP
-							for _, ip := range localIPs {
-								subnetIPs, err := network.GetUsableSubnetIPsFromIP(ip)
-								if err == nil {
-									ips = append(ips, subnetIPs...)
-								}
-							}
-							ips = append(ips, net.ParseIP("127.0.0.1"))
-
-							scanCtx, cancelScan := context.WithTimeout(context.Background(), 10*time.Second)
-							defer cancelScan()
-
-							devices, _ = httpFallback.ScanNetwork(scanCtx, ips, Cfg.Port)
-						}).
-						Run()
-				}
-			}
+		if sendmulticastiface != "" {
+			Cfg.MulticastInterface = sendmulticastiface
+		}
 
-			if len(devices) == 0 {
-				return fmt.Errorf("no devices found on the network via multicast or subnet scan")
-			}
+		target := sendto
+		var selectedDevice *model.Device
 
-			selected := cli.PickDevice(devices, Cfg.Private)
-			if selected == nil {
-				return fmt.Errorf("no device selected")
+		if target == "" && sendtofingerprint == "" {
+			selected, err := pickRecipient()
+			if err != nil {
+				return err
 			}
 			target = selected.Alias
 			sendport = selected.Port
 			selectedDevice = selected
 		}
 
-		if sendalias != "" {
-			Cfg.Alias = sendalias
-		}
-		if sendconcurrency > 0 {
-			Cfg.Concurrency = sendconcurrency
-		}
-		if sendmulticastiface != "" {
-			Cfg.MulticastInterface = sendmulticastiface
-		}
+		applySendOverrides()
 
-		cli.PrintHeader(fmt.Sprintf("Sending %d files", len(files)))
-		for _, file := range files {
-			fileInfo, err := os.Stat(file)
-			if err == nil {
-				cli.PrintInfo("- %s (%s)", filepath.Base(file), cli.FormatBytes(fileInfo.Size()))
-			}
-		}
+		printSendSummary(files)
 		fromAlias := Cfg.Alias
 		if Cfg.Private {
 			fromAlias = "Anonymous"
 		}
 
-		ctx, cancel := context.WithTimeout(context.Background(), time.Duration(sendtimeout)*time.Second)
+		ctx, cancel := sendContext()
 		defer cancel()
 
 		var err error
+		started := time.Now()
+		peer := target
 		if selectedDevice != nil {
 			cli.PrintInfo("To: %s (%s:%d)", selectedDevice.Alias, selectedDevice.IP, selectedDevice.Port)
 			cli.PrintInfo("From: %s", fromAlias)
-			err = send.SendToDevice(ctx, Cfg, selectedDevice, files, zap.S(), sendOpts...)
+			err = send.SendToDevice(ctx, Cfg, selectedDevice, files, zap.S().Named("send"), sendOpts...)
 		} else {
-			cli.PrintInfo("To: %s", target)
+			if target != "" {
+				cli.PrintInfo("To: %s", target)
+			} else {
+				cli.PrintInfo("To: fingerprint %s", sendtofingerprint)
+				peer = "fingerprint " + sendtofingerprint
+			}
 			cli.PrintInfo("From: %s", fromAlias)
-			err = send.SendFiles(ctx, Cfg, files, target, sendport, zap.S(), sendOpts...)
+			err = send.SendFiles(ctx, Cfg, files, target, sendport, zap.S().Named("send"), sendOpts...)
+
+			var ambiguous *send.AmbiguousRecipientError
+			if errors.As(err, &ambiguous) {
+				device, pickErr := disambiguateRecipient(ambiguous)
+				if pickErr != nil {
+					return pickErr
+				}
+				retryCtx, retryCancel := sendContext()
+				defer retryCancel()
+				cli.PrintInfo("To: %s (%s:%d)", device.Alias, device.IP, device.Port)
+				peer = device.Alias
+				err = send.SendToDevice(retryCtx, Cfg, device, files, zap.S().Named("send"), sendOpts...)
+			}
 		}
+		return finishSend(&result, peer, started, err)
+	},
+}
+
+// sendContext bounds a send by --timeout and ends it on Ctrl+C or SIGTERM,
+// so that the recipient is told when a session is abandoned.
+func sendContext() (context.Context, context.CancelFunc) {
+	sigCtx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	ctx, cancel := context.WithTimeout(sigCtx, time.Duration(sendtimeout)*time.Second)
+	return ctx, func() {
+		cancel()
+		stop()
+	}
+}
+
+// finishSend reports the outcome of a send to peer that began at started.
+// When not every file was sent it lists each file with its status, then
+// prints a summary, writes the --report file and returns the error, if any.
+func finishSend(result *send.SendResult, peer string, started time.Time, err error) error {
+	if err != nil {
+		cli.EmitEvent(cli.ProgressEvent{Event: cli.EventError, Direction: "send", Error: err.Error(), Reason: send.Outcome(err)})
+	}
+	summary := sendReport(result, peer, started, err)
+
+	sent := result.Count(send.FileSent)
+	if len(result.Files) > 0 && sent < len(result.Files) {
+		cli.PrintHeader(fmt.Sprintf("Sent %d of %d file(s)", sent, len(result.Files)))
+		for _, f := range result.Files {
+			switch f.Status {
+			case send.FileSent:
+				cli.PrintSuccess("%s", f.Name)
+			case send.FileFailed:
+				cli.PrintError("%s: %v", f.Name, f.Err)
+			case send.FileRejected:
+				cli.PrintWarning("%s: declined by receiver", f.Name)
+			case send.FileSkipped:
+				cli.PrintWarning("%s: skipped", f.Name)
+			case send.FileDuplicate:
+				cli.PrintInfo("%s: already delivered", f.Name)
+			}
+		}
+	}
+	if len(result.Files) > 0 {
+		printTransferSummary(summary)
+	}
+	if sendreport != "" {
+		if reportErr := summary.WriteFile(sendreport); reportErr != nil {
+			if err == nil {
+				return reportErr
+			}
+			cli.PrintError("%v", reportErr)
+		}
+	}
+
+	switch send.Outcome(err) {
+	case "declined":
+		cli.PrintWarning("Recipient declined")
+	case "busy":
+		cli.PrintWarning("Recipient busy: it is receiving another transfer")
+	case "cancelled":
+		cli.PrintWarning("Session cancelled by recipient")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to send files: %w", err)
+	}
+	if dups := result.Count(send.FileDuplicate); dups == len(result.Files) && dups > 0 {
+		cli.PrintInfo("Nothing to send: the receiver already has every file")
+		return nil
+	}
+	if sent == 0 && len(result.Files) > 0 {
+		cli.PrintWarning("Receiver declined all files")
+		return nil
+	}
+	cli.PrintSuccess("Files sent successfully!")
+	return nil
+}
+
+// sendReport builds the report of a send from its per-file results.
+func sendReport(result *send.SendResult, peer string, started time.Time, err error) *report.Report {
+	r := report.New("send", peer)
+	r.StartedAt = started
+	for _, f := range result.Files {
+		rf := report.File{Name: f.Name, Path: f.Path, Size: f.Size, Status: string(f.Status)} // send statuses match report's
+		if f.Err != nil {
+			rf.Error = f.Err.Error()
+		}
+		r.Add(rf)
+	}
+	if err != nil {
+		r.Error = err.Error()
+		r.Reason = send.Outcome(err)
+	}
+	r.Finish(time.Now())
+	return r
+}
+
+// disambiguateRecipient lets the user choose between devices sharing an
+// alias. Without a terminal it lists the candidates and returns an error
+// asking for --fingerprint instead.
+func disambiguateRecipient(ambiguous *send.AmbiguousRecipientError) (*model.Device, error) {
+	if ambiguous.Alias != "" {
+		cli.PrintWarning("Multiple devices are named '%s':", ambiguous.Alias)
+	} else {
+		cli.PrintWarning("Multiple devices match fingerprint %s:", ambiguous.Fingerprint)
+	}
+	for _, d := range ambiguous.Candidates {
+		cli.PrintInfo("- %s:%d  fingerprint %s", d.IP, d.Port, shortFingerprint(d.Fingerprint))
+	}
+
+	if term.IsTerminal(int(os.Stdin.Fd())) {
+		if selected := cli.PickDevice(ambiguous.Candidates, Cfg.Private); selected != nil {
+			return selected, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: use a longer fingerprint from the list above", ambiguous)
+}
+
+func shortFingerprint(fp string) string {
+	if fp == "" {
+		return "(none)"
+	}
+	if len(fp) > 16 {
+		return fp[:16]
+	}
+	return fp
+}
+
+// applySendOverrides applies the flags that override the sender's settings.
+func applySendOverrides() {
+	if sendalias != "" {
+		Cfg.Alias = sendalias
+	}
+	if sendconcurrency > 0 {
+		Cfg.Concurrency = sendconcurrency
+	}
+	if sendcompress {
+		Cfg.Compress = true
+	}
+	if sendpreview {
+		Cfg.SendPreviews = true
+	}
+}
+
+// applySendSource makes the send connect from the address given with
+// --source-ip, or the IPv4 address of --interface, for hosts where the
+// default route takes the wrong interface, such as through a VPN. Discovery
+// listens and announces on that interface too, unless --iface names another.
+func applySendSource() error {
+	if sendsourceip == "" && sendinterface == "" {
+		return nil
+	}
+	if sendsourceip != "" && sendinterface != "" {
+		return fmt.Errorf("cannot use both --source-ip and --interface")
+	}
+	var ip net.IP
+	var iface string
+	if sendinterface != "" {
+		ipnet, err := network.GetInterfaceIPNet(sendinterface)
+		if err != nil {
+			return fmt.Errorf("--interface: %w", err)
+		}
+		ip, iface = ipnet.IP, sendinterface
+	} else {
+		if ip = net.ParseIP(sendsourceip); ip == nil {
+			return fmt.Errorf("invalid --source-ip: %s", sendsourceip)
+		}
+		owner, err := network.InterfaceByIP(ip)
+		if err != nil {
+			return fmt.Errorf("--source-ip %s is not an address of this machine", ip)
+		}
+		iface = owner.Name
+	}
+	Cfg.SourceIP = ip
+	if sendmulticastiface == "" {
+		Cfg.MulticastInterface = iface
+	}
+	httputil.ConfigureTransport(transportOptions())
+	return nil
+}
+
+// pickRecipient runs a quick multicast discovery (falling back to a subnet
+// scan) and lets the user choose the target device from the results.
+func pickRecipient() (*model.Device, error) {
+	sendConfig := discovery.DefaultServiceConfig()
+	sendConfig.MulticastConfig.InterfaceName = Cfg.MulticastInterface
+
+	var devices []*model.Device
+	var discErr error
+
+	_ = spinner.New().
+		Title("Looking for devices on your network (multicast)...").
+		Action(func() {
+			devices, discErr = discovery.DiscoverDevices(
+				context.Background(),
+				sendConfig,
+				Cfg,
+				Cfg.HttpsEnabled,
+			)
+		}).
+		Run()
+
+	if discErr != nil {
+		return nil, fmt.Errorf("discovery failed: %w", discErr)
+	}
+
+	// Unicast fallback: if multicast finds nothing, automatically scan subnets
+	if len(devices) == 0 {
+		localIPs, ipErr := network.GetLocalIPAddresses()
+		if Cfg.SourceIP != nil {
+			localIPs = []net.IP{Cfg.SourceIP}
+		}
+		if ipErr == nil && len(localIPs) > 0 {
+			// Prioritize the subnet connected to the default gateway
+			if gwIP, err := network.PrimaryLANIP(); err == nil {
+				for i, ip := range localIPs {
+					if ip.Equal(gwIP) && i > 0 {
+						localIPs[0], localIPs[i] = localIPs[i], localIPs[0]
+						break
+					}
+				}
+			}
+
+			_ = spinner.New().
+				Title("No devices via multicast. Scanning local subnets...").
+				Action(func() {
+					registerDto := Cfg.ToRegisterDto()
+					httpFallback := discovery.NewHTTPDiscovery(nil, registerDto, nil, nil)
+
+					var ips []net.IP
+					for _, ip := range localIPs {
+						subnetIPs, err := network.GetUsableSubnetIPsFromIP(ip)
+						if err == nil {
+							ips = append(ips, subnetIPs...)
+						}
+					}
+					ips = append(ips, net.ParseIP("127.0.0.1"))
+
+					scanCtx, cancelScan := context.WithTimeout(context.Background(), 10*time.Second)
+					defer cancelScan()
+
+					devices, _ = httpFallback.ScanNetwork(scanCtx, ips, Cfg.Port)
+				}).
+				Run()
+		}
+	}
+
+	if len(devices) == 0 {
+		return nil, fmt.Errorf("no devices found on the network via multicast or subnet scan")
+	}
+
+	selected := cli.PickDevice(devices, Cfg.Private)
+	if selected == nil {
+		return nil, fmt.Errorf("no device selected (use --to or --ip to choose a recipient non-interactively)")
+	}
+	return selected, nil
+}
+
+// printSendSummary prints the file count and total size of a send before
+// the transfer starts, listing the first few entries by name.
+func printSendSummary(files []string) {
+	const maxListed = 10
+
+	count, total, err := send.Summarize(files, sendexcludes)
+	if err != nil {
+		count = len(files)
+	}
+	if sendclipboard {
+		count++
+	}
+	if sendstdin {
+		count++
+	}
+	if sendstream != nil {
+		count++
+		total += sendstream.Size()
+	}
+
+	cli.PrintHeader(fmt.Sprintf("Sending %d file(s), %s total", count, cli.FormatBytes(total)))
+	for i, file := range files {
+		if i == maxListed {
+			cli.PrintInfo("- ... and %d more", len(files)-maxListed)
+			break
+		}
+		fileInfo, err := os.Stat(file)
 		if err != nil {
-			return fmt.Errorf("failed to send files: %w", err)
+			continue
+		}
+		if fileInfo.IsDir() {
+			cli.PrintInfo("- %s/ (directory)", filepath.Base(file))
+		} else {
+			cli.PrintInfo("- %s (%s)", filepath.Base(file), cli.FormatBytes(fileInfo.Size()))
 		}
+	}
+	if sendclipboard {
+		cli.PrintInfo("- clipboard (in-memory)")
+	}
+	if sendstdin {
+		cli.PrintInfo("- stdin (in-memory)")
+	}
+	if sendstream != nil {
+		cli.PrintInfo("- %s (%s, streamed from stdin)", sendstream.Name(), cli.FormatBytes(sendstream.Size()))
+	}
+}
 
-		cli.PrintSuccess("Files sent successfully!")
+// applyProgressFormat validates a --progress value and configures the cli
+// package accordingly.
+func applyProgressFormat(format string) error {
+	switch f := cli.ProgressFormat(format); f {
+	case cli.ProgressBar, cli.ProgressJSON:
+		cli.SetProgressFormat(f)
 		return nil
-	},
+	default:
+		return fmt.Errorf("invalid --progress value %q: use bar or json", format)
+	}
 }
 
 func init() {
 	rootCmd.AddCommand(sendCmd)
-	sendCmd.Flags().StringSliceVar(&sendfiles, "file", []string{}, "File or directory to send")
-	sendCmd.Flags().StringVar(&sendip, "ip", "", "Target device IP (with optional :port, skips discovery)")
+	sendCmd.Flags().StringSliceVar(&sendfiles, "file", []string{}, "File, directory, or glob pattern to send (supports **)")
+	sendCmd.Flags().StringSliceVar(&sendexcludes, "exclude", []string{}, "Glob pattern of files to skip (can be repeated)")
+	sendCmd.Flags().Int64Var(&sendsize, "size", 0, "Size in bytes of data streamed with '-' (omit to buffer stdin to a temp file)")
+	sendCmd.Flags().StringVar(&sendas, "as", "", "File name to announce to the recipient (single file or '-' stream only)")
+	sendCmd.Flags().StringVar(&sendip, "ip", "", "Target device IP or host name, e.g. mylaptop.local (with optional :port, skips discovery)")
 	sendCmd.Flags().StringVar(&sendto, "to", "", "Target device alias (omit to pick interactively)")
+	sendCmd.Flags().StringVar(&sendtofingerprint, "to-fingerprint", "", "Target device by certificate fingerprint prefix (alias not needed)")
+	sendCmd.Flags().StringVar(&sendfingerprint, "fingerprint", "", "Fingerprint (or prefix) of the target, to choose between devices sharing an alias")
 	sendCmd.Flags().IntVar(&sendport, "port", 0, "Target device port")
 	sendCmd.Flags().IntVar(&sendtimeout, "timeout", 30, "Send timeout in seconds")
 	sendCmd.Flags().StringVar(&sendalias, "alias", "", "Sender alias")
 	sendCmd.Flags().IntVar(&sendconcurrency, "concurrency", 0, "Max parallel uploads (0 = use default)")
 	sendCmd.Flags().StringVar(&sendmulticastiface, "iface", "", "Multicast network interface name")
+	sendCmd.Flags().StringVar(&sendsourceip, "source-ip", "", "Local address to send from, when the default route takes the wrong interface (e.g. a VPN)")
+	sendCmd.Flags().StringVar(&sendinterface, "interface", "", "Network interface to send from: its IPv4 address is the source, and discovery uses it")
 	sendCmd.Flags().BoolVarP(&sendclipboard, "clipboard", "c", false, "Send current system clipboard text directly")
 	sendCmd.Flags().BoolVar(&sendstdin, "stdin", false, "Send text read from standard input (stdin)")
+	sendCmd.Flags().BoolVar(&sendfailfast, "fail-fast", false, "Stop starting new uploads after the first failure")
+	sendCmd.Flags().StringVar(&sendprogress, "progress", "bar", "Progress output: bar or json (NDJSON events on stdout)")
+	sendCmd.Flags().StringVar(&sendreport, "report", "", "Write a JSON summary of the transfer to this file")
+	sendCmd.Flags().StringVar(&sendat, "at", "", "Queue the send on the running server for this time (HH:MM, \"YYYY-MM-DD HH:MM\" or RFC 3339)")
+	sendCmd.Flags().BoolVar(&sendcompress, "compress", false, "Compress text-like files for receivers that accept it (see compress_types)")
+	sendCmd.Flags().BoolVar(&sendpreview, "preview", false, "Offer receivers a small thumbnail of each image (see send_previews)")
+	sendCmd.Flags().BoolVar(&sendskipdups, "skip-duplicates", false, "Skip files already delivered unchanged to this device by an earlier --skip-duplicates send")
+	sendCmd.Flags().StringVar(&sendcode, "code", "", "Pairing code of the recipient, from localgo pair on it (skips discovery)")
+	sendCmd.Flags().BoolVar(&sendrelay, "relay", false, "Send through the relay in the config to the device given by --to-fingerprint, on another network")
+	sendCmd.Flags().BoolVar(&sendwake, "wake", false, "Wake the recipient with Wake-on-LAN first and wait for it (needs its MAC in favorites)")
+	sendCmd.Flags().DurationVar(&sendevery, "every", 0, "Queue the send on the running server to repeat at this interval (e.g. 24h)")
 
 	sendCmd.RegisterFlagCompletionFunc("to", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 		cache := discovery.NewPeerCache(nil)
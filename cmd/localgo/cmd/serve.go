@@ -5,15 +5,20 @@ import (
 	"fmt"
 	"os"
 	"os/signal"
+	"slices"
+	"strings"
+	"sync/atomic"
 	"syscall"
 	"time"
 
+	"github.com/bethropolis/localgo/pkg/config"
+	"github.com/bethropolis/localgo/pkg/crypto"
 	"github.com/bethropolis/localgo/pkg/discovery"
 	"github.com/bethropolis/localgo/pkg/help"
 	"github.com/bethropolis/localgo/pkg/cli"
-	"github.com/bethropolis/localgo/pkg/model"
 	"github.com/bethropolis/localgo/pkg/network"
 	"github.com/bethropolis/localgo/pkg/server"
+	"github.com/bethropolis/localgo/pkg/server/services"
 	"github.com/spf13/cobra"
 	"go.uber.org/zap"
 )
@@ -24,15 +29,32 @@ var (
 	servepin         string
 	servealias       string
 	servedir         string
-	servequiet       bool
 	servedaemon      bool
 	serveinterval    int
 	serveautoAccept  bool
 	servenoClipboard bool
+	servestrictProtocol bool
+	serveprintMessages  bool
 	servehistory     string
+	serveaccessLog   string
+	serveaccessLogFormat string
+	serveadminSocket string
+	servecontrolGRPC string
+	servedbus        bool
+	servemqttBroker  string
+	serverelay       string
 	serveexecHook    string
-	serveopen        bool
+	servescan        string
+	serveencryptTo   string
+	serveopen        string
+	servemanifest    string
 	servemulticastiface string
+	serveprogress    string
+	servequickSave   string
+	serveonce        bool
+	serveidleTimeout time.Duration
+	servedrainTimeout time.Duration
+	servesessionWait  time.Duration
 )
 
 var serveCmd = &cobra.Command{
@@ -40,6 +62,10 @@ var serveCmd = &cobra.Command{
 	Short: "Start the LocalGo server to receive files",
 	RunE: func(cmd *cobra.Command, args []string) error {
 
+		if err := applyProgressFormat(serveprogress); err != nil {
+			return err
+		}
+
 		// Daemon mode: fork into background
 		if servedaemon && os.Getenv("LOCALGO_DAEMON_CHILD") != "1" {
 			return daemonize()
@@ -55,7 +81,10 @@ var serveCmd = &cobra.Command{
 		}
 
 		// Apply overrides
-		if serveport > 0 {
+		if cmd.Flags().Changed("port") {
+			if serveport < 0 || serveport > 65535 {
+				return fmt.Errorf("invalid port %d", serveport)
+			}
 			Cfg.Port = serveport
 		}
 		if serveuseHTTP {
@@ -81,22 +110,97 @@ var serveCmd = &cobra.Command{
 		if servenoClipboard {
 			Cfg.NoClipboard = true
 		}
+		if servestrictProtocol {
+			Cfg.StrictProtocol = true
+		}
+		if serveprintMessages {
+			Cfg.PrintMessages = true
+		}
 		if servehistory != "" {
 			Cfg.HistoryFile = servehistory
 		}
+		if serveaccessLog != "" {
+			Cfg.AccessLog = serveaccessLog
+		}
+		if serveaccessLogFormat != "" {
+			format, err := config.ParseAccessLogFormat(serveaccessLogFormat)
+			if err != nil {
+				return err
+			}
+			Cfg.AccessLogFormat = format
+		}
+		if serveadminSocket != "" {
+			Cfg.AdminSocket = serveadminSocket
+		}
+		if servecontrolGRPC != "" {
+			Cfg.ControlGRPC = servecontrolGRPC
+		}
+		if servedbus {
+			Cfg.DBus = true
+		}
+		if servemqttBroker != "" {
+			Cfg.MQTTBroker = servemqttBroker
+		}
+		if serverelay != "" {
+			if _, err := config.ParseRelay(serverelay); err != nil {
+				return fmt.Errorf("invalid --relay: %w", err)
+			}
+			Cfg.Relay = strings.TrimSpace(serverelay)
+		}
 		if serveexecHook != "" {
 			Cfg.ExecHook = serveexecHook
 		}
-		if serveopen {
-			Cfg.OpenDir = true
+		if servescan != "" {
+			Cfg.ScanCmd = servescan
+		}
+		if serveencryptTo != "" {
+			if _, err := crypto.ParseRecipient(serveencryptTo); err != nil {
+				return err
+			}
+			Cfg.EncryptTo = strings.TrimSpace(serveencryptTo)
+		}
+		if serveopen != "" {
+			mode, err := config.ParseOpenMode(serveopen)
+			if err != nil {
+				return err
+			}
+			Cfg.OpenMode = mode
 		}
-		if servequiet {
+		if servemanifest != "" {
+			format, err := config.ParseManifest(servemanifest)
+			if err != nil {
+				return err
+			}
+			Cfg.Manifest = format
+		}
+		if cli.QuietOutput() {
 			Cfg.Quiet = true
 		}
 		if servemulticastiface != "" {
 			Cfg.MulticastInterface = servemulticastiface
 		}
 
+		var quickSaveDur time.Duration
+		if servequickSave != "" {
+			d, err := parseQuickSave(servequickSave)
+			if err != nil {
+				return err
+			}
+			quickSaveDur = d
+		}
+		if cmd.Flags().Changed("drain-timeout") {
+			Cfg.DrainTimeout = servedrainTimeout
+		}
+		if cmd.Flags().Changed("session-wait") {
+			if servesessionWait < 0 || servesessionWait > config.MaxSessionWait {
+				return fmt.Errorf("--session-wait must be between 0 and %s", config.MaxSessionWait)
+			}
+			Cfg.SessionWait = servesessionWait
+		}
+		if err := checkHeadlessPolicy(servequickSave != ""); err != nil {
+			return err
+		}
+
 		// Create download directory if it doesn't exist
 		if err := os.MkdirAll(Cfg.DownloadDir, 0755); err != nil {
 			return fmt.Errorf("failed to create download directory: %w", err)
@@ -116,83 +220,87 @@ var serveCmd = &cobra.Command{
 		zap.S().Infof("Alias: %s", displayAlias)
 		zap.S().Infof("Protocol: %s", protocol)
 
-		if !servequiet {
+		if !cli.QuietOutput() {
 			cli.PrintHeader("Starting LocalGo server")
 			cli.PrintInfo("Alias: %s", displayAlias)
 			cli.PrintInfo("Protocol: %s", protocol)
-			cli.PrintInfo("Port: %d", Cfg.Port)
+			if Cfg.Port == 0 {
+				cli.PrintInfo("Port: random (announced once bound)")
+			} else {
+				cli.PrintInfo("Port: %d", Cfg.Port)
+			}
 			cli.PrintInfo("Download Directory: %s", Cfg.DownloadDir)
 			if Cfg.PIN != "" {
 				cli.PrintInfo("PIN Protection: Enabled")
 			}
+			if Cfg.Relay != "" {
+				cli.PrintInfo("Relay: %s", Cfg.Relay)
+			}
 			cli.PrintInfo("Fingerprint: %s", Cfg.SecurityContext.CertificateHash[:16]+"...")
+			if servequickSave != "" {
+				if quickSaveDur > 0 {
+					cli.PrintInfo("Quick Save: On for %s", quickSaveDur)
+				} else {
+					cli.PrintInfo("Quick Save: On")
+				}
+			}
 		}
 
 		// Context for graceful shutdown
 		ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 		defer stop()
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
 
 		// Start server first to determine the actual port
-		srv := server.NewServer(Cfg, zap.S())
-
-		serverErrChan := make(chan error, 1)
-		serverReadyChan := make(chan struct{}, 1)
-		go func() {
-			serverErrChan <- srv.Start(ctx, serverReadyChan)
-		}()
-
-		// Wait for server to be ready (server.Start waits for port bind)
-		select {
-		case err := <-serverErrChan:
-			return fmt.Errorf("server failed: %w", err)
-		case <-serverReadyChan:
+		srv := server.NewServer(Cfg, zap.S().Named("server"))
+		if servequickSave != "" {
+			srv.GetReceiveService().EnableQuickSave(quickSaveDur)
 		}
 
-		// Initialize discovery service AFTER server is ready (Cfg.Port may have
-		// changed if the configured port was busy)
-		discoverySvcConfig := discovery.DefaultServiceConfig()
-		discoverySvcConfig.MulticastConfig.Port = Cfg.Port
-		discoverySvcConfig.MulticastConfig.MulticastAddr = fmt.Sprintf("%s:%d", Cfg.MulticastGroup, Cfg.Port)
-		discoverySvcConfig.MulticastConfig.InterfaceName = Cfg.MulticastInterface
-
-		if serveinterval > 0 {
-			discoverySvcConfig.AnnounceInterval = time.Duration(serveinterval) * time.Second
+		var received, idleExpired atomic.Bool
+		if !Cfg.Quiet {
+			srv.GetReceiveService().AddSummaryHandler(printTransferSummary)
 		}
-
-		multicast := discovery.NewMulticastDiscovery(discoverySvcConfig.MulticastConfig, Cfg.ToMulticastDto(false), zap.S())
-
-		// Create HTTPDiscoverer for backchannel (HTTP response to multicast)
-		httpDiscoverer := discovery.NewHTTPDiscovery(nil, Cfg.ToRegisterDto(), nil, zap.S())
-		multicast.SetHTTPDiscoverer(httpDiscoverer)
-
-		peerCache := discovery.NewPeerCache(zap.S())
-		multicast.SetPeerCache(peerCache)
-
-		discoverySvc := discovery.NewService(discoverySvcConfig, multicast, zap.S())
-		discoverySvc.SetPeerCache(peerCache)
-
-		discoverySvc.AddDeviceHandler(func(device *model.Device) {
-			if !servequiet {
-				alias := device.Alias
-				if Cfg.Private {
-					alias = cli.AnonymizedAlias(device)
-				}
-				zap.S().Infof("Device discovered: %s (%s)", alias, device.IP)
-				cli.PrintSuccess("Device discovered: %s (%s)", alias, device.IP)
+		srv.GetReceiveService().AddCompletionHandler(func(sessionID string) {
+			received.Store(true)
+			if serveonce {
+				zap.S().Infof("Transfer complete, exiting (--once)")
+				cancel()
 			}
 		})
+		if serveidleTimeout > 0 {
+			go watchIdle(ctx, srv.GetReceiveService(), serveidleTimeout, func() {
+				zap.S().Infof("No activity for %s, exiting (--idle-timeout)", serveidleTimeout)
+				idleExpired.Store(true)
+				cancel()
+			})
+		}
+
+		if err := inheritSystemdListener(srv); err != nil {
+			return err
+		}
+
+		multicastPort := discoveryPort()
+		serverErrChan, err := startServer(ctx, srv)
+		if err != nil {
+			return err
+		}
 
-		// Start discovery
-		err := discoverySvc.Start(ctx, Cfg.ToMulticastDto(false))
+		discoverySvc, err := startAnnouncing(ctx, multicastPort, time.Duration(serveinterval)*time.Second, cli.QuietOutput(), srv.GetRegistryService())
 		if err != nil {
-			return fmt.Errorf("discovery service failed: %w", err)
+			return err
 		}
+		notifySystemd(ctx, srv)
 
-		if !servequiet {
+		if !cli.QuietOutput() {
 			zap.S().Infof("Server ready! Waiting for files...")
 			cli.PrintSuccess("Server ready! Waiting for files...")
 
 			localIPs, err := network.GetLocalIPAddresses()
+			// A link-local address is only usable with the zone of the
+			// other device's own interface, so it is not listed.
+			localIPs = slices.DeleteFunc(localIPs, network.IsLinkLocalIPv6)
 			if err == nil && len(localIPs) > 0 {
 				cli.PrintHeader("\nListening Addresses:")
 				for _, ip := range localIPs {
@@ -202,7 +310,9 @@ var serveCmd = &cobra.Command{
 					}
 					cli.PrintInfo("  %s://%s:%d", scheme, ip.String(), Cfg.Port)
 				}
-				fmt.Println()
+				if !cli.JSONProgressEnabled() {
+					fmt.Println()
+				}
 			}
 
 			cli.PrintWarning("Press Ctrl+C to stop")
@@ -214,32 +324,189 @@ var serveCmd = &cobra.Command{
 		}
 
 		discoverySvc.Stop()
-		if servequiet {
+		if cli.QuietOutput() {
 			zap.S().Infof("Server stopped")
 		} else {
 			zap.S().Infof("Server stopped")
 			cli.PrintInfo("Server stopped")
 		}
+		if idleExpired.Load() && !received.Load() {
+			return fmt.Errorf("nothing received within %s", serveidleTimeout)
+		}
 		return nil
 	},
 }
 
+// startServer starts srv and waits until its port is bound. The returned
+// channel yields the server's result once it stops.
+func startServer(ctx context.Context, srv *server.Server) (<-chan error, error) {
+	serverErrChan := make(chan error, 1)
+	serverReadyChan := make(chan struct{}, 1)
+	go func() {
+		serverErrChan <- srv.Start(ctx, serverReadyChan)
+	}()
+
+	// Wait for server to be ready (server.Start waits for port bind)
+	select {
+	case err := <-serverErrChan:
+		return nil, fmt.Errorf("server failed: %w", err)
+	case <-serverReadyChan:
+	}
+	return serverErrChan, nil
+}
+
+// discoveryPort returns the UDP port for multicast discovery. It follows the
+// configured server port, except that a random server port (0) keeps the
+// well-known port so peers can still hear the announcements. Call it before
+// startServer, which replaces Cfg.Port with the bound port.
+func discoveryPort() int {
+	if Cfg.Port == 0 {
+		return config.DefaultPort
+	}
+	return Cfg.Port
+}
+
+// startAnnouncing starts multicast discovery on multicastPort so other
+// devices can find this server, publishing each newly seen device to events.
+// It must run after startServer so the announcements carry the port the
+// server actually bound.
+func startAnnouncing(ctx context.Context, multicastPort int, interval time.Duration, quiet bool, registry *services.RegistryService) (*discovery.Service, error) {
+	discoverySvcConfig := discovery.DefaultServiceConfig()
+	discoverySvcConfig.MulticastConfig.Port = multicastPort
+	discoverySvcConfig.MulticastConfig.MulticastAddr = fmt.Sprintf("%s:%d", Cfg.MulticastGroup, multicastPort)
+	discoverySvcConfig.MulticastConfig.InterfaceName = Cfg.MulticastInterface
+
+	if interval > 0 {
+		discoverySvcConfig.AnnounceInterval = interval
+	}
+
+	logger := zap.S().Named("discovery")
+	multicast := discovery.NewMulticastDiscovery(discoverySvcConfig.MulticastConfig, Cfg.ToMulticastDto(false), logger)
+
+	// Create HTTPDiscoverer for backchannel (HTTP response to multicast)
+	httpDiscoverer := discovery.NewHTTPDiscovery(nil, Cfg.ToRegisterDto(), nil, logger)
+	multicast.SetHTTPDiscoverer(httpDiscoverer)
+
+	peerCache := discovery.NewPeerCache(logger)
+	multicast.SetPeerCache(peerCache)
+
+	discoverySvc := discovery.NewService(discoverySvcConfig, multicast, logger)
+	discoverySvc.SetPeerCache(peerCache)
+
+	// A subscription keeps a device that drops out and comes back in
+	// order in the registry.
+	deviceEvents, _ := discoverySvc.Subscribe(ctx)
+	go func() {
+		for ev := range deviceEvents {
+			alias := ev.Device.Alias
+			if Cfg.Private {
+				alias = cli.AnonymizedAlias(ev.Device)
+			}
+			switch ev.Type {
+			case discovery.DeviceFound:
+				// The registry publishes the discovery event and lets
+				// `localgo devices` list what this server has seen.
+				registry.RegisterDevice(ev.Device)
+				if !quiet {
+					zap.S().Infof("Device discovered: %s (%s)", alias, ev.Device.IP)
+					cli.PrintSuccess("Device discovered: %s (%s)", alias, ev.Device.IP)
+				}
+			case discovery.DeviceLost:
+				registry.RemoveDevice(ev.Device)
+				if !quiet {
+					zap.S().Infof("Device lost: %s (%s)", alias, ev.Device.IP)
+					cli.PrintInfo("Device lost: %s (%s)", alias, ev.Device.IP)
+				}
+			}
+		}
+	}()
+
+	// Discovery rebinds and announces again by itself when the network
+	// changes; say where the server can be reached now.
+	discoverySvc.AddNetworkHandler(func(change network.Change) {
+		var addrs []string
+		for _, ip := range change.Current {
+			if !network.IsLinkLocalIPv6(ip) {
+				addrs = append(addrs, ip.String())
+			}
+		}
+		if len(addrs) == 0 {
+			zap.S().Infof("Network changed: no network addresses left")
+			if !quiet {
+				cli.PrintWarning("Network changed: no network addresses left")
+			}
+			return
+		}
+		zap.S().Infof("Network changed: announced again on %s", strings.Join(addrs, ", "))
+		if !quiet {
+			cli.PrintInfo("Network changed: announced again on %s", strings.Join(addrs, ", "))
+		}
+	})
+
+	// Start discovery
+	if err := discoverySvc.Start(ctx, Cfg.ToMulticastDto(false)); err != nil {
+		return nil, fmt.Errorf("discovery service failed: %w", err)
+	}
+	return discoverySvc, nil
+}
+
+// watchIdle calls onIdle once the receive service has been idle for timeout.
+// Time spent in an active session does not count as idle.
+func watchIdle(ctx context.Context, svc *services.ReceiveService, timeout time.Duration, onIdle func()) {
+	interval := time.Second
+	if timeout < interval {
+		interval = timeout
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if svc.IdleFor() >= timeout {
+				onIdle()
+				return
+			}
+		}
+	}
+}
+
 func init() {
 	rootCmd.AddCommand(serveCmd)
-	serveCmd.Flags().IntVar(&serveport, "port", 0, "Port to run the server on (default: from config)")
+	serveCmd.Flags().IntVar(&serveport, "port", 0, "Port to run the server on (0 = any free port; default: from config)")
 	serveCmd.Flags().BoolVar(&serveuseHTTP, "http", false, "Use HTTP instead of HTTPS")
 	serveCmd.Flags().StringVar(&servepin, "pin", "", "PIN for authentication")
 	serveCmd.Flags().StringVar(&servealias, "alias", "", "Device alias (default: from config)")
 	serveCmd.Flags().StringVar(&servedir, "dir", "", "Download directory (default: from config)")
-	serveCmd.Flags().BoolVar(&servequiet, "quiet", false, "Quiet mode - minimal output")
 	serveCmd.Flags().BoolVarP(&servedaemon, "daemon", "d", false, "Run server as a background daemon")
 	serveCmd.Flags().IntVar(&serveinterval, "interval", 30, "Discovery announcement interval in seconds")
 	serveCmd.Flags().BoolVar(&serveautoAccept, "auto-accept", false, "Auto-accept incoming files without prompting")
 	serveCmd.Flags().BoolVar(&servenoClipboard, "no-clipboard", false, "Save incoming text as a file instead of copying to clipboard")
+	serveCmd.Flags().BoolVar(&servestrictProtocol, "strict-protocol", false, "Answer API errors exactly as the LocalSend protocol documents")
+	serveCmd.Flags().BoolVar(&serveprintMessages, "print-messages", false, "Print incoming text messages and offer to copy them instead of saving or copying them")
 	serveCmd.Flags().StringVar(&servehistory, "history", "", "Path to transfer history JSONL file (default: ~/.local/share/localgo/history.jsonl)")
+	serveCmd.Flags().StringVar(&serveaccessLog, "access-log", "", "Write an HTTP access log to this file (- = stderr)")
+	serveCmd.Flags().StringVar(&serveaccessLogFormat, "access-log-format", "", "Access log format: common or json (default: common)")
+	serveCmd.Flags().StringVar(&serveadminSocket, "admin-socket", "", "Also serve the admin API on this unix socket, usable only by you")
+	serveCmd.Flags().StringVar(&servecontrolGRPC, "control-grpc", "", "Serve the gRPC control interface on unix:PATH or a loopback HOST:PORT")
+	serveCmd.Flags().BoolVar(&servedbus, "dbus", false, "Publish the D-Bus interface on the session bus for desktop integration (Linux)")
+	serveCmd.Flags().StringVar(&servemqttBroker, "mqtt-broker", "", "Publish events to this MQTT broker, e.g. tcp://nas.local:1883")
+	serveCmd.Flags().StringVar(&serverelay, "relay", "", "Also register with this relay, e.g. https://relay.example.com, to receive from other networks")
 	serveCmd.Flags().StringVar(&serveexecHook, "exec", "", "Shell command to run after each received file")
-	serveCmd.Flags().BoolVar(&serveopen, "open", false, "Open download directory after transfer completes")
+	serveCmd.Flags().StringVar(&serveencryptTo, "encrypt-to", "", "Encrypt received files at rest to this recipient key (see localgo keygen)")
+	serveCmd.Flags().StringVar(&servescan, "scan", "", "Shell command that checks each received file before it is kept, e.g. clamdscan; failures are quarantined")
+	serveCmd.Flags().StringVar(&serveopen, "open", "", "Open received content: dir (download directory, default), file, or folder; executables are never opened")
+	serveCmd.Flags().Lookup("open").NoOptDefVal = config.OpenModeDir
+	serveCmd.Flags().StringVar(&servemanifest, "manifest", "", "Write a manifest of each session's files to the download directory: json (sizes, hashes, sender, times) or sha256")
+	serveCmd.Flags().Lookup("manifest").NoOptDefVal = config.ManifestJSON
 	serveCmd.Flags().StringVar(&servemulticastiface, "iface", "", "Multicast network interface name")
+	serveCmd.Flags().StringVar(&servequickSave, "quick-save", "", "Auto-accept all transfers: on, or a duration like 10m")
+	serveCmd.Flags().BoolVar(&serveonce, "once", false, "Exit after the first completed transfer")
+	serveCmd.Flags().DurationVar(&serveidleTimeout, "idle-timeout", 0, "Exit after this long without receiving anything (e.g. 10m)")
+	serveCmd.Flags().DurationVar(&servedrainTimeout, "drain-timeout", 0, "On shutdown, wait this long for active transfers to finish (default: 0, or 8s with --headless)")
+	serveCmd.Flags().DurationVar(&servesessionWait, "session-wait", 0, "Hold a transfer that arrives during another for up to this long, e.g. 30s (max 1m; default: 0, refuse it)")
+	serveCmd.Flags().StringVar(&serveprogress, "progress", "bar", "Progress output: bar or json (NDJSON events on stdout)")
 
 	serveCmd.SetHelpFunc(func(cmd *cobra.Command, args []string) {
 		if h := help.GetCommandHelp("serve"); h != nil {
@@ -7,6 +7,7 @@ import (
 	"os"
 	"os/signal"
 	"path/filepath"
+	"slices"
 	"strings"
 	"syscall"
 	"time"
@@ -33,7 +34,6 @@ var (
 	sharenoClipboard bool
 	sharehistory     string
 	shareexecHook    string
-	sharequiet       bool
 	sharezip         bool
 	shareconcurrency int
 	sharemulticastiface string
@@ -87,7 +87,7 @@ var shareCmd = &cobra.Command{
 		if shareexecHook != "" {
 			Cfg.ExecHook = shareexecHook
 		}
-		if sharequiet {
+		if cli.QuietOutput() {
 			Cfg.Quiet = true
 		}
 		if shareconcurrency > 0 {
@@ -107,7 +107,7 @@ var shareCmd = &cobra.Command{
 			displayAlias = "Anonymous"
 		}
 
-		if !sharequiet {
+		if !cli.QuietOutput() {
 			cli.PrintHeader("Starting LocalGo Web Share")
 			cli.PrintInfo("Alias: %s", displayAlias)
 			cli.PrintInfo("Protocol: %s", protocol)
@@ -171,7 +171,7 @@ var shareCmd = &cobra.Command{
 
 			filesMap[id] = fileDto
 			pathsMap[id] = file
-			if !sharequiet {
+			if !cli.QuietOutput() {
 				cli.PrintInfo("Sharing: %s (%s)", displayName, cli.FormatBytes(fileInfo.Size()))
 			}
 		}
@@ -183,7 +183,7 @@ var shareCmd = &cobra.Command{
 		}()
 
 		// Create server
-		srv := server.NewServer(Cfg, zap.S())
+		srv := server.NewServer(Cfg, zap.S().Named("server"))
 		sendService := srv.GetSendService()
 
 		// Register files in session
@@ -216,14 +216,15 @@ var shareCmd = &cobra.Command{
 		discoverySvcConfig.MulticastConfig.InterfaceName = Cfg.MulticastInterface
 		multicastDto := Cfg.ToMulticastDto(true)
 
-		multicast := discovery.NewMulticastDiscovery(discoverySvcConfig.MulticastConfig, multicastDto, zap.S())
-		httpDiscoverer := discovery.NewHTTPDiscovery(nil, Cfg.ToRegisterDto(), nil, zap.S())
+		logger := zap.S().Named("discovery")
+		multicast := discovery.NewMulticastDiscovery(discoverySvcConfig.MulticastConfig, multicastDto, logger)
+		httpDiscoverer := discovery.NewHTTPDiscovery(nil, Cfg.ToRegisterDto(), nil, logger)
 		multicast.SetHTTPDiscoverer(httpDiscoverer)
 
-		peerCache := discovery.NewPeerCache(zap.S())
+		peerCache := discovery.NewPeerCache(logger)
 		multicast.SetPeerCache(peerCache)
 
-		discoverySvc := discovery.NewService(discoverySvcConfig, multicast, zap.S())
+		discoverySvc := discovery.NewService(discoverySvcConfig, multicast, logger)
 		discoverySvc.SetPeerCache(peerCache)
 
 		// Start discovery AFTER server is ready
@@ -232,11 +233,14 @@ var shareCmd = &cobra.Command{
 			return fmt.Errorf("discovery service failed: %w", err)
 		}
 
-		if !sharequiet {
+		if !cli.QuietOutput() {
 			cli.PrintSuccess("Server ready! Waiting for connections...")
 
 			// Retrieve active network interfaces to display direct URLs
 			localIPs, err := network.GetLocalIPAddresses()
+			// A link-local address is only usable with the zone of the
+			// other device's own interface, so it is not listed.
+			localIPs = slices.DeleteFunc(localIPs, network.IsLinkLocalIPv6)
 			if err == nil && len(localIPs) > 0 {
 				cli.PrintHeader("\nAccess URLs:")
 				for _, ip := range localIPs {
@@ -258,7 +262,7 @@ var shareCmd = &cobra.Command{
 		}
 
 		discoverySvc.Stop()
-		if !sharequiet {
+		if !cli.QuietOutput() {
 			cli.PrintInfo("Web share stopped")
 		}
 		return nil
@@ -277,7 +281,6 @@ func init() {
 	shareCmd.Flags().BoolVar(&sharenoClipboard, "no-clipboard", false, "Save text as file")
 	shareCmd.Flags().StringVar(&sharehistory, "history", "", "Path to history file")
 	shareCmd.Flags().StringVar(&shareexecHook, "exec", "", "Shell command to run")
-	shareCmd.Flags().BoolVar(&sharequiet, "quiet", false, "Quiet mode")
 	shareCmd.Flags().BoolVar(&sharezip, "zip", false, "Zip directories before sharing")
 	shareCmd.Flags().IntVar(&shareconcurrency, "concurrency", 0, "Max parallel uploads (0 = use default)")
 	shareCmd.Flags().StringVar(&sharemulticastiface, "iface", "", "Multicast network interface name")
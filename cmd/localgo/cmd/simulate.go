@@ -0,0 +1,127 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/bethropolis/localgo/pkg/cli"
+	"github.com/bethropolis/localgo/pkg/help"
+	"github.com/bethropolis/localgo/pkg/simulate"
+	"github.com/charmbracelet/huh/spinner"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var (
+	simulatecount          int
+	simulateprefix         string
+	simulateport           int
+	simulateuseHTTP        bool
+	simulatedir            string
+	simulateinterval       int
+	simulatemulticastiface string
+)
+
+var simulateCmd = &cobra.Command{
+	Use:          "simulate",
+	Short:        "Run fake devices for testing discovery and transfers",
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if simulatecount < 1 || simulatecount > simulate.MaxCount {
+			return fmt.Errorf("--count must be between 1 and %d", simulate.MaxCount)
+		}
+		iface := Cfg.MulticastInterface
+		if simulatemulticastiface != "" {
+			iface = simulatemulticastiface
+		}
+		opts := simulate.Options{
+			Count:            simulatecount,
+			Prefix:           simulateprefix,
+			Port:             simulateport,
+			HTTPS:            !simulateuseHTTP,
+			MulticastGroup:   Cfg.MulticastGroup,
+			MulticastPort:    discoveryPort(),
+			Interface:        iface,
+			AnnounceInterval: time.Duration(simulateinterval) * time.Second,
+			Dir:              simulatedir,
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+		defer stop()
+
+		var sim *simulate.Simulation
+		var err error
+		start := func() { sim, err = simulate.Start(ctx, opts, zap.S().Named("simulate")) }
+		if cli.JSONOutput() || cli.Headless() {
+			start()
+		} else {
+			_ = spinner.New().Title(fmt.Sprintf("Starting %d device(s)...", simulatecount)).Action(start).Run()
+		}
+		if err != nil {
+			return err
+		}
+
+		if cli.JSONOutput() {
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			if err := encoder.Encode(sim.Devices); err != nil {
+				sim.Stop()
+				return err
+			}
+		} else {
+			printSimulatedDevices(sim.Devices)
+			cli.PrintWarning("Press Ctrl+C to stop")
+		}
+
+		<-ctx.Done()
+		if err := sim.Stop(); err != nil {
+			return fmt.Errorf("failed to stop cleanly: %w", err)
+		}
+		if !cli.JSONOutput() {
+			cli.PrintInfo("Simulation stopped")
+		}
+		return nil
+	},
+}
+
+// printSimulatedDevices lists the running devices and where their received
+// files go.
+func printSimulatedDevices(devices []*simulate.Device) {
+	cli.PrintHeader(fmt.Sprintf("Simulating %d device(s)", len(devices)))
+	fmt.Printf("  %s  %s  %s  %s\n", padRight("ALIAS", 20), padRight("TYPE", 24), padRight("PORT", 14), "FINGERPRINT")
+	for _, d := range devices {
+		fmt.Printf("  %s  %s  %s  %s\n",
+			padRight(cli.TruncateString(d.Alias, 20), 20),
+			padRight(fmt.Sprintf("%s (%s)", d.DeviceType, d.DeviceModel), 24),
+			padRight(fmt.Sprintf("%d %s", d.Port, d.Protocol), 14),
+			shortFingerprint(d.Fingerprint))
+	}
+	fmt.Println()
+	if simulatedir != "" {
+		cli.PrintInfo("Received files are saved under %s", simulatedir)
+	} else {
+		cli.PrintInfo("Received files are discarded when the simulation stops")
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(simulateCmd)
+	simulateCmd.Flags().IntVar(&simulatecount, "count", 5, "Number of devices to run")
+	simulateCmd.Flags().StringVar(&simulateprefix, "prefix", "", "Name the devices \"<prefix> 1\", \"<prefix> 2\", ... (default: random aliases)")
+	simulateCmd.Flags().IntVar(&simulateport, "port", 0, "Port of the first device, the rest follow (0 = any free ports)")
+	simulateCmd.Flags().BoolVar(&simulateuseHTTP, "http", false, "Use HTTP instead of HTTPS")
+	simulateCmd.Flags().StringVar(&simulatedir, "dir", "", "Keep received files here, one folder per device (default: a temporary folder)")
+	simulateCmd.Flags().IntVar(&simulateinterval, "interval", 30, "Discovery announcement interval in seconds")
+	simulateCmd.Flags().StringVar(&simulatemulticastiface, "iface", "", "Multicast network interface name")
+
+	simulateCmd.SetHelpFunc(func(cmd *cobra.Command, args []string) {
+		if h := help.GetCommandHelp("simulate"); h != nil {
+			help.ShowCommandHelp(*h)
+		}
+	})
+}
@@ -0,0 +1,147 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/bethropolis/localgo/pkg/cli"
+	"github.com/bethropolis/localgo/pkg/help"
+	"github.com/bethropolis/localgo/pkg/model"
+	"github.com/bethropolis/localgo/pkg/report"
+	"github.com/spf13/cobra"
+)
+
+var (
+	statusport  int
+	statuswatch bool
+)
+
+var statusCmd = &cobra.Command{
+	Use:          "status",
+	Short:        "Show the running server's transfers",
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		port := statusport
+		if port == 0 {
+			port = Cfg.Port
+		}
+		if statuswatch && cli.JSONOutput() {
+			return fmt.Errorf("cannot use both --watch and --json")
+		}
+
+		var status model.ServerStatusDto
+		if err := adminRequest(http.MethodGet, port, "/v1/status", nil, &status); err != nil {
+			return err
+		}
+		if cli.JSONOutput() {
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(status)
+		}
+		if !statuswatch {
+			printServerStatus(&status)
+			return nil
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+		defer stop()
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+		for {
+			fmt.Print("\033[H\033[2J")
+			printServerStatus(&status)
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-ticker.C:
+			}
+			status = model.ServerStatusDto{}
+			if err := adminRequest(http.MethodGet, port, "/v1/status", nil, &status); err != nil {
+				return err
+			}
+		}
+	},
+}
+
+// printServerStatus prints the active sessions with a line per file, the
+// shared files and the recently ended transfers.
+func printServerStatus(status *model.ServerStatusDto) {
+	printQuickSaveStatus(&status.QuickSave)
+	fmt.Println()
+
+	if len(status.Sessions) == 0 && status.Sharing == nil {
+		cli.PrintInfo("No active transfers")
+	}
+	for _, s := range status.Sessions {
+		received := 0
+		for _, f := range s.Files {
+			if f.Status == report.StatusReceived {
+				received++
+			}
+		}
+		cli.PrintHeader(fmt.Sprintf("Receiving from %s (%s), started %s", s.Sender, s.SenderIP, formatAge(time.Since(s.StartedAt))))
+		fmt.Printf("  %d of %d file(s), %s of %s (%s)\n", received, len(s.Files), cli.FormatBytes(s.Bytes), cli.FormatBytes(s.Size), percent(s.Bytes, s.Size))
+		for _, f := range s.Files {
+			line := fmt.Sprintf("%s  %s", padRight(cli.TruncateString(f.Name, 40), 40), f.Status)
+			switch f.Status {
+			case report.StatusReceived:
+				cli.PrintSuccess("%s, %s", line, cli.FormatBytes(f.Size))
+			case report.StatusFailed:
+				cli.PrintError("%s", line)
+			case "receiving":
+				cli.PrintInfo("%s, %s of %s (%s)", line, cli.FormatBytes(f.Bytes), cli.FormatBytes(f.Size), percent(f.Bytes, f.Size))
+			default:
+				fmt.Printf("  %s, %s\n", line, cli.FormatBytes(f.Size))
+			}
+		}
+		fmt.Println()
+	}
+
+	if status.Sharing != nil {
+		cli.PrintHeader(fmt.Sprintf("Sharing %d file(s) for download", len(status.Sharing.Files)))
+		for _, f := range status.Sharing.Files {
+			fmt.Printf("  %s  %s\n", padRight(cli.TruncateString(f.Name, 40), 40), cli.FormatBytes(f.Size))
+		}
+		fmt.Println()
+	}
+
+	if len(status.Recent) > 0 {
+		cli.PrintHeader("Recent transfers")
+		for _, r := range status.Recent {
+			line := fmt.Sprintf("%s  %d of %d file(s) from %s, %s in %s",
+				r.FinishedAt.Local().Format("15:04:05"), r.Transferred, len(r.Files), r.Peer,
+				cli.FormatBytes(r.Bytes), cli.FormatDuration(r.Duration()))
+			if r.Failed > 0 {
+				cli.PrintWarning("%s, %d failed", line, r.Failed)
+			} else {
+				cli.PrintSuccess("%s", line)
+			}
+		}
+	}
+}
+
+// percent formats part of total as a percentage.
+func percent(part, total int64) string {
+	if total <= 0 {
+		return "100%"
+	}
+	return fmt.Sprintf("%d%%", part*100/total)
+}
+
+func init() {
+	rootCmd.AddCommand(statusCmd)
+	statusCmd.Flags().IntVar(&statusport, "port", 0, "Port of the running server (default: from config)")
+	statusCmd.Flags().BoolVar(&statuswatch, "watch", false, "Refresh every second until interrupted")
+
+	statusCmd.SetHelpFunc(func(cmd *cobra.Command, args []string) {
+		if h := help.GetCommandHelp("status"); h != nil {
+			help.ShowCommandHelp(*h)
+		}
+	})
+}
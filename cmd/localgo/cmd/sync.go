@@ -0,0 +1,162 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"syscall"
+	"time"
+
+	"github.com/bethropolis/localgo/pkg/cli"
+	"github.com/bethropolis/localgo/pkg/help"
+	"github.com/bethropolis/localgo/pkg/model"
+	"github.com/bethropolis/localgo/pkg/ping"
+	"github.com/bethropolis/localgo/pkg/send"
+	"github.com/spf13/cobra"
+)
+
+var (
+	syncto            string
+	syncip            string
+	synctofingerprint string
+	syncfingerprint   string
+	syncport          int
+	syncexcludes      []string
+	syncdryrun        bool
+)
+
+var syncCmd = &cobra.Command{
+	Use:          "sync <dir>",
+	Short:        "Send the new and changed files of a directory",
+	SilenceUsage: true,
+	Args:         cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dir, err := filepath.Abs(args[0])
+		if err != nil {
+			return err
+		}
+		info, err := os.Stat(dir)
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			return fmt.Errorf("not a directory: %s", dir)
+		}
+		if syncfingerprint != "" && synctofingerprint != "" {
+			return fmt.Errorf("cannot use both --fingerprint and --to-fingerprint")
+		}
+		if synctofingerprint != "" {
+			if len(synctofingerprint) < 4 {
+				return fmt.Errorf("--to-fingerprint needs at least 4 characters")
+			}
+			syncfingerprint = synctofingerprint
+		}
+		if syncip != "" && syncto != "" {
+			return fmt.Errorf("cannot use both --ip and --to")
+		}
+
+		device, err := findDevice(syncto, syncip, syncfingerprint, syncport, !cli.Headless())
+		if err != nil {
+			return err
+		}
+		if device.Fingerprint == "" {
+			// Key the ledger by certificate, as the send itself will.
+			r := ping.Probe(context.Background(), device.IP, device.Port, model.ProtocolTypeHTTPS, 1, 5*time.Second)
+			if r.Reachable() {
+				device.Fingerprint, device.Protocol = r.CertFingerprint, model.ProtocolTypeHTTPS
+			}
+		}
+
+		ledger, err := openSendLedger()
+		if err != nil {
+			return err
+		}
+		target := send.LedgerTarget(device)
+		pending, err := ledger.Pending(target, []string{dir}, syncexcludes)
+		if err != nil {
+			return err
+		}
+		total, _, err := send.Summarize([]string{dir}, syncexcludes)
+		if err != nil {
+			return err
+		}
+		if len(pending) == 0 {
+			cli.PrintSuccess("%s is up to date on %s (%d file(s))", filepath.Base(dir), device.Alias, total)
+			return nil
+		}
+		if syncdryrun {
+			printSyncPlan(pending, total, device.Alias)
+			return nil
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+		defer stop()
+
+		cli.PrintInfo("Syncing %s to %s: %d of %d file(s) new or changed", dir, device.Alias, len(pending), total)
+		var result send.SendResult
+		started := time.Now()
+		err = sendTransfer(ctx, device, []string{dir},
+			send.WithExcludes(syncexcludes...), send.WithLedger(ledger), send.WithResult(&result))
+		if saveErr := ledger.Save(); saveErr != nil {
+			cli.PrintWarning("Failed to save the list of sent files: %v", saveErr)
+		}
+		if len(result.Files) > 0 {
+			printTransferSummary(sendReport(&result, device.Alias, started, err))
+		}
+		var partial *send.PartialFailureError
+		if errors.As(err, &partial) {
+			for _, f := range result.Files {
+				if f.Status == send.FileFailed {
+					cli.PrintError("%s: %v", f.Name, f.Err)
+				}
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("sync failed: %w", err)
+		}
+		if rejected := result.Count(send.FileRejected); rejected > 0 {
+			cli.PrintWarning("Receiver declined %d file(s); they will be offered again next time", rejected)
+			return nil
+		}
+		cli.PrintSuccess("%s is up to date on %s", filepath.Base(dir), device.Alias)
+		return nil
+	},
+}
+
+// printSyncPlan lists the files a sync would send.
+func printSyncPlan(pending map[string]string, total int, peer string) {
+	names := make([]string, 0, len(pending))
+	var size int64
+	for path, name := range pending {
+		names = append(names, name)
+		if info, err := os.Stat(path); err == nil {
+			size += info.Size()
+		}
+	}
+	slices.Sort(names)
+	cli.PrintHeader(fmt.Sprintf("Would send %d of %d file(s) (%s) to %s", len(names), total, cli.FormatBytes(size), peer))
+	for _, name := range names {
+		fmt.Println("  " + name)
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(syncCmd)
+	syncCmd.Flags().StringVar(&syncto, "to", "", "Target device alias (omit to pick interactively)")
+	syncCmd.Flags().StringVar(&syncip, "ip", "", "Target device IP (with optional :port, skips discovery)")
+	syncCmd.Flags().StringVar(&synctofingerprint, "to-fingerprint", "", "Target device by certificate fingerprint prefix")
+	syncCmd.Flags().StringVar(&syncfingerprint, "fingerprint", "", "Fingerprint (or prefix) of the target, to choose between devices sharing an alias")
+	syncCmd.Flags().IntVar(&syncport, "port", 0, "Target device port")
+	syncCmd.Flags().StringSliceVar(&syncexcludes, "exclude", []string{}, "Glob pattern of files to skip (can be repeated)")
+	syncCmd.Flags().BoolVar(&syncdryrun, "dry-run", false, "List the files that would be sent without sending them")
+
+	syncCmd.SetHelpFunc(func(cmd *cobra.Command, args []string) {
+		if h := help.GetCommandHelp("sync"); h != nil {
+			help.ShowCommandHelp(*h)
+		}
+	})
+}
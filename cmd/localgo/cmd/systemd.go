@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net"
+
+	"github.com/bethropolis/localgo/pkg/server"
+	"github.com/bethropolis/localgo/pkg/systemd"
+	"go.uber.org/zap"
+)
+
+// inheritSystemdListener hands srv the socket passed by systemd socket
+// activation, if any. Only the first socket is used; it must be TCP.
+func inheritSystemdListener(srv *server.Server) error {
+	listeners, err := systemd.Listeners()
+	if err != nil {
+		return err
+	}
+	if len(listeners) == 0 {
+		return nil
+	}
+	for _, extra := range listeners[1:] {
+		zap.S().Warnf("Ignoring extra socket-activated listener on %s", extra.Addr())
+		extra.Close()
+	}
+	ln := listeners[0]
+	if _, ok := ln.Addr().(*net.TCPAddr); !ok {
+		ln.Close()
+		return fmt.Errorf("socket-activated listener %s is not a TCP socket", ln.Addr())
+	}
+	srv.SetListener(ln)
+	return nil
+}
+
+// notifySystemd tells systemd the server is ready, keeps its watchdog fed
+// while the server stays responsive, and reports STOPPING once ctx is done.
+// It does nothing when not running under a Type=notify unit.
+func notifySystemd(ctx context.Context, srv *server.Server) {
+	sent, err := systemd.Notify(fmt.Sprintf("%s\nSTATUS=Listening on port %d", systemd.Ready, Cfg.Port))
+	if err != nil {
+		zap.S().Warnf("Failed to notify systemd: %v", err)
+		return
+	}
+	if !sent {
+		return
+	}
+	zap.S().Debugf("Notified systemd of readiness")
+
+	// Taking the session lock proves the receive path isn't deadlocked.
+	go systemd.RunWatchdog(ctx, func() bool {
+		srv.GetReceiveService().ActiveSessions()
+		return true
+	})
+	go func() {
+		<-ctx.Done()
+		_, _ = systemd.Notify(systemd.Stopping)
+	}()
+}
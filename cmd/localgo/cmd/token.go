@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/bethropolis/localgo/pkg/cli"
+	"github.com/bethropolis/localgo/pkg/config"
+	"github.com/bethropolis/localgo/pkg/help"
+	"github.com/spf13/cobra"
+)
+
+var tokenCmd = &cobra.Command{
+	Use:          "token",
+	Short:        "Show the token that authorizes use of the admin API",
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		token, err := config.LoadOrCreateAdminToken(Cfg.AdminTokenPath)
+		if err != nil {
+			return err
+		}
+		fmt.Println(token)
+		return nil
+	},
+}
+
+var tokenRotateCmd = &cobra.Command{
+	Use:          "rotate",
+	Short:        "Replace the admin API token",
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if _, err := config.RotateAdminToken(Cfg.AdminTokenPath); err != nil {
+			return err
+		}
+		cli.PrintSuccess("Admin token replaced in %s; the old one no longer works", Cfg.AdminTokenPath)
+		return nil
+	},
+}
+
+var tokenPathCmd = &cobra.Command{
+	Use:   "path",
+	Short: "Show where the admin API token is kept",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		fmt.Println(Cfg.AdminTokenPath)
+	},
+}
+
+func init() {
+	tokenCmd.AddCommand(tokenRotateCmd)
+	tokenCmd.AddCommand(tokenPathCmd)
+	tokenCmd.SetHelpFunc(func(cmd *cobra.Command, args []string) {
+		if h := help.GetCommandHelp("token"); h != nil {
+			help.ShowCommandHelp(*h)
+		}
+	})
+	rootCmd.AddCommand(tokenCmd)
+}
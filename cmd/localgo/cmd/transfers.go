@@ -0,0 +1,26 @@
+package cmd
+
+import (
+	"context"
+
+	"github.com/bethropolis/localgo/pkg/model"
+	"github.com/bethropolis/localgo/pkg/send"
+	"github.com/bethropolis/localgo/pkg/transfer"
+	"go.uber.org/zap"
+)
+
+// transfers runs the sends of the commands that send repeatedly, such as
+// watch and sync. A server keeps its own manager; see server.Transfers.
+var transfers = transfer.NewManager(0)
+
+// sendTransfer sends paths to device as a transfer of the process and waits
+// for it to end.
+func sendTransfer(ctx context.Context, device *model.Device, paths []string, opts ...send.SendOption) error {
+	h := transfers.Start(ctx, transfer.Send, device.Alias, func(ctx context.Context, progress func(transfer.Progress)) error {
+		opts := append(opts, send.WithProgress(func(sent, total int64) {
+			progress(transfer.Progress{Bytes: sent, Total: total})
+		}))
+		return send.SendToDevice(ctx, Cfg, device, paths, zap.S().Named("send"), opts...)
+	})
+	return h.Wait()
+}
@@ -1,11 +1,26 @@
 package cmd
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/acarl005/stripansi"
 	"github.com/bethropolis/localgo/pkg/cli"
+	"github.com/bethropolis/localgo/pkg/discovery"
 	"github.com/bethropolis/localgo/pkg/model"
+	"github.com/bethropolis/localgo/pkg/network"
+	"github.com/bethropolis/localgo/pkg/ping"
+	"github.com/bethropolis/localgo/pkg/report"
+	"github.com/bethropolis/localgo/pkg/send"
+	"github.com/charmbracelet/huh/spinner"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
 )
 
 // padRight pads a string with spaces on the right up to the specified length,
@@ -34,24 +49,170 @@ func anonymizeDeviceSlice(devices []*model.Device) []*model.Device {
 			Download:    d.Download,
 			LastSeen:    d.LastSeen,
 			Available:   d.Available,
+			LatencyMs:   d.LatencyMs,
 		}
 	}
 	return out
 }
 
-func displayDevices(devices []*model.Device, jsonOutput bool, quiet bool, method string) error {
+// Filter flags shared by discover and scan.
+var (
+	filtertype        string
+	filteralias       string
+	filterfingerprint string
+)
+
+func addFilterFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&filtertype, "type", "", "Only list these device types (e.g. laptop,mobile)")
+	cmd.Flags().StringVar(&filteralias, "alias-pattern", "", "Only list devices whose alias matches this pattern (e.g. 'Pixel*')")
+	cmd.Flags().StringVar(&filterfingerprint, "fingerprint", "", "Only list devices whose fingerprint starts with this prefix")
+}
+
+func deviceFilter() (*discovery.Filter, error) {
+	return discovery.NewFilter(filtertype, filteralias, filterfingerprint)
+}
+
+// annotateTimeout bounds how long scan and discover spend on hostnames and
+// latencies after finding devices.
+const annotateTimeout = 2 * time.Second
+
+// annotateDevices looks up the hostname of each device, and times with an
+// info request those no probe has timed yet, such as devices found by
+// multicast. It gives up on what is not known within timeout.
+func annotateDevices(devices []*model.Device, timeout time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	var wg sync.WaitGroup
+	for _, d := range devices {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if d.LatencyMs == 0 && d.Protocol != "" {
+				if r := ping.Probe(ctx, d.IP, d.Port, d.Protocol, 1, timeout); r.Reachable() {
+					d.LatencyMs = r.MinMs
+				}
+			}
+			d.Hostname = network.LookupHostname(ctx, d.IP)
+		}()
+	}
+	wg.Wait()
+}
+
+func displayDevices(devices []*model.Device, method string) error {
 	if Cfg != nil && Cfg.Private {
 		devices = anonymizeDeviceSlice(devices)
 	}
-	format := cli.FormatTable
-	if jsonOutput {
-		format = cli.FormatJSON
-	} else if quiet {
-		format = cli.FormatQuiet
-	}
-
-	writer := cli.NewOutputWriter(format)
+	writer := cli.NewOutputWriter(cli.Format())
 	defer writer.Flush()
 
 	return writer.WriteDevices(devices, method)
 }
+
+// checkHeadlessPolicy refuses to start a headless receiver that would have to
+// prompt: with nobody to answer, every transfer would hang until it timed out.
+func checkHeadlessPolicy(quickSave bool) error {
+	if !Cfg.Headless || Cfg.AutoAccept || quickSave || len(Cfg.AcceptRules) > 0 {
+		return nil
+	}
+	return errors.New("--headless needs an accept policy: use --auto-accept, --quick-save or accept_rules in the config file")
+}
+
+// printTransferSummary prints one line summing up a finished transfer:
+// files, bytes, duration, average speed and failures.
+func printTransferSummary(r *report.Report) {
+	verb, prep := "Sent", "to"
+	if r.Direction == "receive" {
+		verb, prep = "Received", "from"
+	}
+	line := fmt.Sprintf("%s %d of %d file(s)", verb, r.Transferred, len(r.Files))
+	if r.Peer != "" {
+		line += fmt.Sprintf(" %s %s", prep, r.Peer)
+	}
+	line += fmt.Sprintf(", %s in %s", cli.FormatBytes(r.Bytes), cli.FormatDuration(r.Duration()))
+	if r.BytesPerSecond > 0 {
+		line += fmt.Sprintf(" (%s/s)", cli.FormatBytes(int64(r.BytesPerSecond)))
+	}
+	if r.Failed > 0 {
+		cli.PrintWarning("%s, %d failed", line, r.Failed)
+		return
+	}
+	cli.PrintInfo("%s", line)
+}
+
+// parseDeviceAddress turns an --ip value, an IP address or hostname with an
+// optional :port, into a device to contact directly. Names such as
+// mylaptop.local are resolved with mDNS, and an IPv6 link-local address keeps
+// its zone, as in [fe80::1%eth0]:53317. Without a port in addr, port is used,
+// or else the configured port.
+func parseDeviceAddress(addr string, port int) (*model.Device, error) {
+	host, portStr, err := net.SplitHostPort(addr)
+	if err != nil {
+		// No port specified; treat whole string as host
+		host = addr
+		portStr = ""
+	}
+	literal, zone, _ := strings.Cut(host, "%")
+	parsedIP := net.ParseIP(literal)
+	if parsedIP == nil {
+		zone = ""
+		// Not a raw IP: resolve the name, IPv4 addresses first. IPv6 is
+		// used if there is nothing else; the caller handles it.
+		ips, err := network.ResolveHost(context.Background(), host)
+		if err != nil || len(ips) == 0 {
+			return nil, fmt.Errorf("invalid IP address or unresolvable hostname: %s", host)
+		}
+		parsedIP = ips[0]
+	}
+
+	if portStr != "" {
+		p, err := strconv.Atoi(portStr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid port in --ip: %w", err)
+		}
+		port = p
+	}
+	if port == 0 {
+		port = Cfg.Port
+	}
+
+	return &model.Device{
+		Alias: host,
+		IP:    (&net.IPAddr{IP: parsedIP, Zone: zone}).String(),
+		Port:  port,
+	}, nil
+}
+
+// openSendLedger opens the ledger of delivered files, with the shared hash
+// cache.
+func openSendLedger() (*send.Ledger, error) {
+	return send.OpenLedger(send.DefaultLedgerFile(), send.NewHashCache(send.DefaultHashCacheFile(), zap.S().Named("send")))
+}
+
+// findDevice finds the device a command targets: the ip address as given,
+// the device named to (optionally narrowed by fingerprint), or one picked
+// interactively. With spin set, a spinner shows while searching.
+func findDevice(to, ip, fingerprint string, port int, spin bool) (*model.Device, error) {
+	if ip != "" {
+		return parseDeviceAddress(ip, port)
+	}
+	if to == "" && fingerprint == "" {
+		return pickRecipient()
+	}
+
+	var device *model.Device
+	var err error
+	find := func() {
+		device, err = send.FindRecipient(context.Background(), Cfg, to, fingerprint, port, zap.S().Named("send"))
+	}
+	if spin && !cli.Headless() {
+		_ = spinner.New().Title("Looking for the device...").Action(find).Run()
+	} else {
+		find()
+	}
+
+	var ambiguous *send.AmbiguousRecipientError
+	if errors.As(err, &ambiguous) {
+		return disambiguateRecipient(ambiguous)
+	}
+	return device, err
+}
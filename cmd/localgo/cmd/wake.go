@@ -0,0 +1,135 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/bethropolis/localgo/pkg/cli"
+	"github.com/bethropolis/localgo/pkg/discovery"
+	"github.com/bethropolis/localgo/pkg/model"
+	"github.com/bethropolis/localgo/pkg/ping"
+	"github.com/bethropolis/localgo/pkg/wol"
+)
+
+const (
+	// wakeTimeout is how long send --wake waits for a woken device to answer.
+	wakeTimeout = 2 * time.Minute
+	// wakeResend is how often the magic packet is sent again meanwhile, in
+	// case the sleeping network card missed it.
+	wakeResend = 15 * time.Second
+)
+
+// wakeRecipient wakes the recipient named by the send flags with
+// Wake-on-LAN, unless it is already up, and waits until it answers its info
+// endpoint. The device's address comes from --ip or the peer cache, and its
+// MAC address from its favorites entry.
+func wakeRecipient() error {
+	device, mac, err := findWakeTarget()
+	if err != nil {
+		return err
+	}
+	name := device.Alias
+	if name == "" || name == device.IP {
+		name = net.JoinHostPort(device.IP, fmt.Sprint(device.Port))
+	}
+	if deviceAnswers(context.Background(), device) {
+		cli.PrintInfo("%s is awake", name)
+		return nil
+	}
+
+	ip := net.ParseIP(device.IP)
+	if err := wol.Wake(mac, ip); err != nil {
+		return err
+	}
+	cli.PrintInfo("Waking %s (%s)...", name, mac)
+
+	ctx, cancel := context.WithTimeout(context.Background(), wakeTimeout)
+	defer cancel()
+	started := time.Now()
+	resent := started
+	for !deviceAnswers(ctx, device) {
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%s did not wake up within %s: check that Wake-on-LAN is enabled on it", name, wakeTimeout)
+		case <-time.After(time.Second):
+		}
+		if time.Since(resent) >= wakeResend {
+			_ = wol.Wake(mac, ip)
+			resent = time.Now()
+		}
+	}
+	cli.PrintSuccess("%s woke up after %s", name, time.Since(started).Round(time.Second))
+	return nil
+}
+
+// findWakeTarget returns the recipient to wake and its MAC address. A
+// sleeping device cannot be discovered, so it must have been seen before,
+// or be given with --ip.
+func findWakeTarget() (*model.Device, net.HardwareAddr, error) {
+	peers := discovery.NewPeerCache(nil).GetPeers()
+	if sendip != "" {
+		device, err := parseDeviceAddress(sendip, sendport)
+		if err != nil {
+			return nil, nil, err
+		}
+		fingerprint := sendfingerprint
+		for _, peer := range peers {
+			if peer.IP == device.IP && (fingerprint == "" || strings.HasPrefix(strings.ToLower(peer.Fingerprint), strings.ToLower(fingerprint))) {
+				fingerprint = peer.Fingerprint
+				device.Protocol = peer.Protocol
+				break
+			}
+		}
+		mac := Cfg.FavoriteMAC(fingerprint)
+		if mac == nil {
+			return nil, nil, fmt.Errorf("no MAC address stored for %s: add one to its favorites entry (see localgo devices for its fingerprint)", sendip)
+		}
+		return device, mac, nil
+	}
+
+	if sendto == "" && sendfingerprint == "" {
+		return nil, nil, fmt.Errorf("--wake needs --to, --to-fingerprint or --ip: a sleeping device cannot be picked from discovery")
+	}
+	var matches []*model.Device
+	for _, peer := range peers {
+		if sendto != "" && peer.Alias != sendto {
+			continue
+		}
+		if sendfingerprint != "" && !strings.HasPrefix(strings.ToLower(peer.Fingerprint), strings.ToLower(sendfingerprint)) {
+			continue
+		}
+		if Cfg.FavoriteMAC(peer.Fingerprint) != nil {
+			matches = append(matches, peer)
+		}
+	}
+	recipient := sendto
+	if recipient == "" {
+		recipient = "fingerprint " + sendfingerprint
+	}
+	if len(matches) == 0 {
+		return nil, nil, fmt.Errorf("no favorite with a MAC address matches %s among the devices seen before: add its mac to favorites, or use --ip", recipient)
+	}
+	if len(matches) > 1 {
+		return nil, nil, fmt.Errorf("%d favorites match %s: use --fingerprint to choose one", len(matches), recipient)
+	}
+	device := matches[0]
+	if sendport != 0 {
+		device.Port = sendport
+	}
+	return device, Cfg.FavoriteMAC(device.Fingerprint), nil
+}
+
+// deviceAnswers reports whether device answers its info endpoint.
+func deviceAnswers(ctx context.Context, device *model.Device) bool {
+	protocol := device.Protocol
+	if protocol == "" {
+		protocol = model.ProtocolTypeHTTP
+		if Cfg.HttpsEnabled {
+			protocol = model.ProtocolTypeHTTPS
+		}
+	}
+	return ping.Probe(ctx, device.IP, device.Port, protocol, 1, 2*time.Second).Reachable()
+}
@@ -0,0 +1,190 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/bethropolis/localgo/pkg/cli"
+	"github.com/bethropolis/localgo/pkg/help"
+	"github.com/bethropolis/localgo/pkg/model"
+	"github.com/bethropolis/localgo/pkg/send"
+	"github.com/bethropolis/localgo/pkg/storage"
+	"github.com/bethropolis/localgo/pkg/watch"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var (
+	watchto            string
+	watchip            string
+	watchtofingerprint string
+	watchfingerprint   string
+	watchport          int
+	watchexcludes      []string
+	watchsettle        time.Duration
+	watchmoveto        string
+	watchdelete        bool
+	watchskipexisting  bool
+)
+
+var watchCmd = &cobra.Command{
+	Use:          "watch <dir>",
+	Short:        "Send files as they are dropped into a directory",
+	SilenceUsage: true,
+	Args:         cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dir, err := filepath.Abs(args[0])
+		if err != nil {
+			return err
+		}
+		if watchfingerprint != "" && watchtofingerprint != "" {
+			return fmt.Errorf("cannot use both --fingerprint and --to-fingerprint")
+		}
+		if watchtofingerprint != "" {
+			if len(watchtofingerprint) < 4 {
+				return fmt.Errorf("--to-fingerprint needs at least 4 characters")
+			}
+			watchfingerprint = watchtofingerprint
+		}
+		if watchip != "" && watchto != "" {
+			return fmt.Errorf("cannot use both --ip and --to")
+		}
+		if watchip == "" && watchto == "" && watchfingerprint == "" {
+			return fmt.Errorf("no recipient: use --to, --to-fingerprint or --ip")
+		}
+		if watchdelete && watchmoveto != "" {
+			return fmt.Errorf("cannot use both --delete and --move-to")
+		}
+		if watchmoveto != "" {
+			if watchmoveto, err = filepath.Abs(watchmoveto); err != nil {
+				return err
+			}
+			if watchmoveto == dir {
+				return fmt.Errorf("--move-to must not be the watched directory")
+			}
+			if err := storage.EnsureDirExists(watchmoveto); err != nil {
+				return fmt.Errorf("failed to create %s: %w", watchmoveto, err)
+			}
+		}
+
+		logger := zap.S().Named("watch")
+		watcher, err := watch.New(dir, watch.Options{
+			Settle:       watchsettle,
+			Excludes:     watchexcludes,
+			SkipExisting: watchskipexisting,
+		}, logger)
+		if err != nil {
+			return err
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+		defer stop()
+
+		cli.PrintInfo("Watching %s, sending new files to %s (Ctrl+C to stop)", dir, watchRecipientName())
+
+		sender := &watchSender{}
+		return watcher.Run(ctx, sender.send)
+	},
+}
+
+// watchSender sends the files the watcher reports, remembering the
+// recipient between batches.
+type watchSender struct {
+	device *model.Device
+}
+
+// send sends one batch of settled files, then moves or deletes those that
+// arrived. It returns the files to try again: all of them when the
+// recipient could not be reached, else those whose upload failed.
+func (s *watchSender) send(ctx context.Context, paths []string) []string {
+	if s.device == nil {
+		device, err := findDevice(watchto, watchip, watchfingerprint, watchport, false)
+		if err != nil {
+			cli.PrintError("Cannot reach %s, will retry: %v", watchRecipientName(), err)
+			return paths
+		}
+		s.device = device
+	}
+
+	var result send.SendResult
+	started := time.Now()
+	err := sendTransfer(ctx, s.device, paths, send.WithResult(&result))
+	if ctx.Err() != nil {
+		return nil
+	}
+	var partial *send.PartialFailureError
+	if err != nil && !errors.As(err, &partial) {
+		// Look the device up again next time, in case its address changed.
+		s.device = nil
+		cli.PrintError("Sending %d file(s) failed, will retry: %v", len(paths), err)
+		return paths
+	}
+	printTransferSummary(sendReport(&result, s.device.Alias, started, err))
+
+	var retry []string
+	for _, f := range result.Files {
+		switch f.Status {
+		case send.FileSent:
+			afterSent(f.Path)
+		case send.FileRejected:
+			cli.PrintWarning("%s: declined by receiver", f.Name)
+		default:
+			cli.PrintError("%s: %v, will retry", f.Name, f.Err)
+			retry = append(retry, f.Path)
+		}
+	}
+	return retry
+}
+
+// afterSent moves or deletes a file that has been sent, as asked.
+func afterSent(path string) {
+	switch {
+	case watchdelete:
+		if err := os.Remove(path); err != nil {
+			cli.PrintError("Failed to delete %s: %v", path, err)
+		}
+	case watchmoveto != "":
+		dest := storage.ResolveDuplicateFilename(watchmoveto, filepath.Base(path))
+		if err := os.Rename(path, dest); err != nil {
+			cli.PrintError("Failed to move %s: %v", path, err)
+		}
+	}
+}
+
+// watchRecipientName describes the recipient given on the command line.
+func watchRecipientName() string {
+	switch {
+	case watchip != "":
+		return watchip
+	case watchto != "":
+		return watchto
+	default:
+		return "fingerprint " + watchfingerprint
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(watchCmd)
+	watchCmd.Flags().StringVar(&watchto, "to", "", "Target device alias")
+	watchCmd.Flags().StringVar(&watchip, "ip", "", "Target device IP (with optional :port, skips discovery)")
+	watchCmd.Flags().StringVar(&watchtofingerprint, "to-fingerprint", "", "Target device by certificate fingerprint prefix")
+	watchCmd.Flags().StringVar(&watchfingerprint, "fingerprint", "", "Fingerprint (or prefix) of the target, to choose between devices sharing an alias")
+	watchCmd.Flags().IntVar(&watchport, "port", 0, "Target device port")
+	watchCmd.Flags().StringSliceVar(&watchexcludes, "exclude", []string{}, "Glob pattern of file names to ignore (can be repeated)")
+	watchCmd.Flags().DurationVar(&watchsettle, "settle", watch.DefaultSettle, "How long a file must stay unchanged before it is sent")
+	watchCmd.Flags().StringVar(&watchmoveto, "move-to", "", "Move files here once sent")
+	watchCmd.Flags().BoolVar(&watchdelete, "delete", false, "Delete files once sent")
+	watchCmd.Flags().BoolVar(&watchskipexisting, "skip-existing", false, "Only send files added after the watch starts")
+
+	watchCmd.SetHelpFunc(func(cmd *cobra.Command, args []string) {
+		if h := help.GetCommandHelp("watch"); h != nil {
+			help.ShowCommandHelp(*h)
+		}
+	})
+}
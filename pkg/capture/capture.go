@@ -0,0 +1,267 @@
+// Package capture records the LocalSend protocol as LocalGo speaks it, for
+// debugging interoperability with other implementations offline: every
+// multicast packet sent and received, and every HTTP exchange with a peer,
+// with its headers and JSON bodies. File contents are never recorded.
+//
+// Records are JSON lines in a file given to Start. Until then, and after
+// Stop, nothing is recorded and the hooks cost nothing.
+package capture
+
+import (
+	"bytes"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Kinds of record.
+const (
+	KindMulticast = "multicast"
+	KindHTTP      = "http"
+)
+
+// Directions of a record: a packet or request LocalGo received, or one it
+// sent.
+const (
+	In  = "in"
+	Out = "out"
+)
+
+// MaxBody is how much of a JSON body is recorded; a longer one is cut and
+// marked truncated.
+const MaxBody = 64 << 10
+
+// Record is one line of a capture.
+type Record struct {
+	Time      time.Time `json:"time"`
+	Kind      string    `json:"kind"`
+	Direction string    `json:"direction"`
+	Peer      string    `json:"peer,omitempty"` // address of the other side
+
+	// Multicast packets. A packet that is not valid JSON is recorded as a
+	// string in Data instead of Packet.
+	Packet json.RawMessage `json:"packet,omitempty"`
+	Data   string          `json:"data,omitempty"`
+
+	// HTTP exchanges.
+	Method          string      `json:"method,omitempty"`
+	URL             string      `json:"url,omitempty"`
+	Proto           string      `json:"proto,omitempty"`
+	RequestHeaders  http.Header `json:"requestHeaders,omitempty"`
+	RequestBody     *Body       `json:"requestBody,omitempty"`
+	Status          int         `json:"status,omitempty"`
+	ResponseHeaders http.Header `json:"responseHeaders,omitempty"`
+	ResponseBody    *Body       `json:"responseBody,omitempty"`
+	DurationMs      float64     `json:"durationMs,omitempty"`
+	Error           string      `json:"error,omitempty"`
+}
+
+// Body is a recorded message body: the JSON itself, or for anything else,
+// such as file contents, only its type and size.
+type Body struct {
+	ContentType string          `json:"contentType,omitempty"`
+	Size        int64           `json:"size"` // -1 if unknown
+	JSON        json.RawMessage `json:"json,omitempty"`
+	Truncated   bool            `json:"truncated,omitempty"`
+}
+
+var (
+	mu   sync.Mutex
+	file *os.File
+)
+
+// Start records to path, replacing what it held, until Stop is called.
+func Start(path string) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if file != nil {
+		file.Close()
+	}
+	file = f
+	return nil
+}
+
+// Stop stops recording and closes the capture file.
+func Stop() error {
+	mu.Lock()
+	defer mu.Unlock()
+	if file == nil {
+		return nil
+	}
+	err := file.Close()
+	file = nil
+	return err
+}
+
+// Enabled reports whether a capture is being recorded.
+func Enabled() bool {
+	mu.Lock()
+	defer mu.Unlock()
+	return file != nil
+}
+
+// write appends rec to the capture. Each record is written whole, so a
+// capture cut short by a crash is readable up to its last line.
+func write(rec Record) {
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if file != nil {
+		_, _ = file.Write(append(line, '\n'))
+	}
+}
+
+// Packet records a multicast packet sent to or received from peer.
+func Packet(direction string, peer net.Addr, data []byte) {
+	if !Enabled() {
+		return
+	}
+	rec := Record{Time: time.Now(), Kind: KindMulticast, Direction: direction}
+	if peer != nil {
+		rec.Peer = peer.String()
+	}
+	if json.Valid(data) {
+		rec.Packet = compact(data)
+	} else {
+		rec.Data = string(data)
+	}
+	write(rec)
+}
+
+// isJSON reports whether a body of contentType is recorded.
+func isJSON(contentType string) bool {
+	ct, _, _ := strings.Cut(contentType, ";")
+	ct = strings.TrimSpace(strings.ToLower(ct))
+	return ct == "application/json" || strings.HasSuffix(ct, "+json")
+}
+
+// newBody describes a body of contentType and size with the first bytes of
+// it read, data, if it is JSON.
+func newBody(contentType string, size int64, data []byte, truncated bool) *Body {
+	b := &Body{ContentType: contentType, Size: size}
+	if !isJSON(contentType) || len(data) == 0 {
+		return b
+	}
+	if truncated {
+		data = data[:min(len(data), MaxBody)]
+	}
+	if truncated || !json.Valid(data) {
+		// Kept as a JSON string, so the record stays valid.
+		s, _ := json.Marshal(string(data))
+		b.JSON, b.Truncated = s, truncated
+		return b
+	}
+	b.JSON = compact(data)
+	return b
+}
+
+func compact(data []byte) json.RawMessage {
+	var buf bytes.Buffer
+	if json.Compact(&buf, data) != nil {
+		return json.RawMessage(data)
+	}
+	return buf.Bytes()
+}
+
+// redactResponse returns data, the response to a request for path, without
+// the credentials some responses carry: the upload tokens of prepare-upload
+// and of a v1 send-request, and the session ID of prepare-download, which
+// is all a download needs. Such a response that cannot be read is left out.
+func redactResponse(path string, data []byte) []byte {
+	upload := strings.HasSuffix(path, "/prepare-upload")
+	sendV1 := strings.HasSuffix(path, "/v1/send-request")
+	download := strings.HasSuffix(path, "/prepare-download")
+	if !upload && !sendV1 && !download {
+		return data
+	}
+	var m map[string]json.RawMessage
+	if json.Unmarshal(data, &m) != nil {
+		return nil
+	}
+	switch {
+	case upload:
+		if files, ok := m["files"]; ok {
+			var tokens map[string]json.RawMessage
+			if json.Unmarshal(files, &tokens) != nil {
+				return nil
+			}
+			redactValues(tokens)
+			m["files"], _ = json.Marshal(tokens)
+		}
+	case sendV1:
+		redactValues(m)
+	case download:
+		if _, ok := m["sessionId"]; ok {
+			m["sessionId"] = redacted
+		}
+	}
+	out, err := json.Marshal(m)
+	if err != nil {
+		return nil
+	}
+	return out
+}
+
+var redacted = json.RawMessage(`"REDACTED"`)
+
+// redactValues replaces every value of m.
+func redactValues(m map[string]json.RawMessage) {
+	for k := range m {
+		m[k] = redacted
+	}
+}
+
+// secretParams are the query parameters and headers left out of records.
+var (
+	secretParams  = []string{"pin", "token"}
+	secretHeaders = []string{"Authorization", "Cookie", "Set-Cookie", "Proxy-Authorization"}
+)
+
+// redactURL returns u with its PIN and upload token replaced.
+func redactURL(u *url.URL) string {
+	q := u.Query()
+	changed := false
+	params := secretParams
+	if strings.HasSuffix(u.Path, "/download") {
+		// A download needs only the session ID.
+		params = append(params[:len(params):len(params)], "sessionId")
+	}
+	for _, p := range params {
+		if q.Has(p) {
+			q.Set(p, "REDACTED")
+			changed = true
+		}
+	}
+	if !changed {
+		return u.String()
+	}
+	r := *u
+	r.RawQuery = q.Encode()
+	return r.String()
+}
+
+// redactHeader returns a copy of h without credentials.
+func redactHeader(h http.Header) http.Header {
+	if len(h) == 0 {
+		return nil
+	}
+	c := h.Clone()
+	for _, k := range secretHeaders {
+		if c.Get(k) != "" {
+			c.Set(k, "REDACTED")
+		}
+	}
+	return c
+}
@@ -0,0 +1,126 @@
+package capture
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func readRecords(t *testing.T, path string) []Record {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var recs []Record
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		var r Record
+		if err := json.Unmarshal(sc.Bytes(), &r); err != nil {
+			t.Fatalf("invalid record %q: %v", sc.Text(), err)
+		}
+		recs = append(recs, r)
+	}
+	return recs
+}
+
+func TestCapture(t *testing.T) {
+	if Transport(http.DefaultTransport) != http.DefaultTransport {
+		t.Fatal("Transport wrapped without a capture")
+	}
+
+	path := filepath.Join(t.TempDir(), "capture.jsonl")
+	if err := Start(path); err != nil {
+		t.Fatal(err)
+	}
+	defer Stop()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/localsend/v2/prepare-upload", func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"sessionId": "s1", "files": {"f1": "tok-a", "f2": "tok-b"}}`))
+	})
+	mux.HandleFunc("/api/localsend/v2/upload", func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+	})
+	srv := httptest.NewServer(Handler(mux))
+	defer srv.Close()
+	client := &http.Client{Transport: Transport(http.DefaultTransport)}
+
+	resp, err := client.Post(srv.URL+"/api/localsend/v2/prepare-upload?pin=1234", "application/json", strings.NewReader(`{"info": {"alias": "Phone"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	resp, err = client.Post(srv.URL+"/api/localsend/v2/upload?token=secret", "application/octet-stream", strings.NewReader("file contents"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	Packet(In, &net.UDPAddr{IP: net.ParseIP("192.168.1.5"), Port: 53317}, []byte(`{"alias": "Phone"}`))
+	Packet(In, &net.UDPAddr{IP: net.ParseIP("192.168.1.6"), Port: 53317}, []byte("junk"))
+	Stop()
+
+	recs := readRecords(t, path)
+	if len(recs) != 6 {
+		t.Fatalf("got %d records, want 6: %+v", len(recs), recs)
+	}
+	var serverPrepare, clientUpload *Record
+	for i := range recs {
+		r := &recs[i]
+		if strings.Contains(r.URL, "1234") || strings.Contains(r.URL, "secret") {
+			t.Errorf("credentials recorded in %s", r.URL)
+		}
+		if r.ResponseBody != nil && strings.Contains(string(r.ResponseBody.JSON), "tok-") {
+			t.Errorf("upload tokens recorded in %s", r.ResponseBody.JSON)
+		}
+		switch {
+		case r.Direction == In && strings.Contains(r.URL, "prepare-upload"):
+			serverPrepare = r
+		case r.Direction == Out && strings.Contains(r.URL, "/upload"):
+			clientUpload = r
+		}
+	}
+	if serverPrepare == nil || string(serverPrepare.RequestBody.JSON) != `{"info":{"alias":"Phone"}}` ||
+		string(serverPrepare.ResponseBody.JSON) != `{"files":{"f1":"REDACTED","f2":"REDACTED"},"sessionId":"s1"}` || serverPrepare.Status != 200 {
+		t.Errorf("prepare-upload recorded as %+v", serverPrepare)
+	}
+	if clientUpload == nil || clientUpload.RequestBody.JSON != nil || clientUpload.RequestBody.Size != int64(len("file contents")) {
+		t.Errorf("upload recorded as %+v", clientUpload)
+	}
+	if string(recs[4].Packet) != `{"alias":"Phone"}` || recs[5].Data != "junk" || recs[5].Kind != KindMulticast {
+		t.Errorf("packets recorded as %+v, %+v", recs[4], recs[5])
+	}
+}
+
+func TestRedactResponse(t *testing.T) {
+	tests := []struct {
+		path, data, want string
+	}{
+		{"/api/localsend/v1/send-request", `{"f1":"tok"}`, `{"f1":"REDACTED"}`},
+		{"/api/localsend/v2/prepare-download", `{"info":{"alias":"PC"},"sessionId":"s1","files":{}}`, `{"files":{},"info":{"alias":"PC"},"sessionId":"REDACTED"}`},
+		{"/api/localsend/v2/prepare-upload", `{"message":"Rejected"}`, `{"message":"Rejected"}`},
+		{"/api/localsend/v2/prepare-upload", `{"sessionId":"s1","files":{"f1":"tok`, ``},
+		{"/api/localsend/v2/info", `{"alias":"PC"}`, `{"alias":"PC"}`},
+	}
+	for _, tt := range tests {
+		if got := string(redactResponse(tt.path, []byte(tt.data))); got != tt.want {
+			t.Errorf("redactResponse(%s, %s) = %s, want %s", tt.path, tt.data, got, tt.want)
+		}
+	}
+	u, _ := url.Parse("http://peer/api/localsend/v2/download?sessionId=s1&fileId=f1")
+	if got := redactURL(u); strings.Contains(got, "s1") {
+		t.Errorf("download session ID recorded in %s", got)
+	}
+}
@@ -0,0 +1,201 @@
+package capture
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Transport returns rt recording each exchange through it, or rt itself if
+// no capture is being recorded.
+func Transport(rt http.RoundTripper) http.RoundTripper {
+	if !Enabled() {
+		return rt
+	}
+	return &transport{base: rt}
+}
+
+type transport struct {
+	base http.RoundTripper
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	rec := Record{
+		Time:           start,
+		Kind:           KindHTTP,
+		Direction:      Out,
+		Peer:           req.URL.Host,
+		Method:         req.Method,
+		URL:            redactURL(req.URL),
+		RequestHeaders: redactHeader(req.Header),
+	}
+	contentType := req.Header.Get("Content-Type")
+	if req.Body != nil && req.Body != http.NoBody && isJSON(contentType) {
+		// The request must not be changed, so its copy gets the body back.
+		data, body := peek(req.Body)
+		req = req.Clone(req.Context())
+		req.Body = body
+		rec.RequestBody = newBody(contentType, req.ContentLength, data, len(data) > MaxBody)
+	} else if req.Body != nil && req.Body != http.NoBody {
+		rec.RequestBody = newBody(contentType, req.ContentLength, nil, false)
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		rec.Error = err.Error()
+		rec.DurationMs = since(start)
+		write(rec)
+		return nil, err
+	}
+	rec.Proto = resp.Proto
+	rec.Status = resp.StatusCode
+	rec.ResponseHeaders = redactHeader(resp.Header)
+	contentType = resp.Header.Get("Content-Type")
+	if !isJSON(contentType) {
+		rec.ResponseBody = newBody(contentType, resp.ContentLength, nil, false)
+		rec.DurationMs = since(start)
+		write(rec)
+		return resp, nil
+	}
+	// Written once the caller has read the body.
+	resp.Body = &recordingBody{ReadCloser: resp.Body, done: func(data []byte, truncated bool) {
+		rec.ResponseBody = newBody(contentType, resp.ContentLength, redactResponse(req.URL.Path, data), truncated)
+		rec.DurationMs = since(start)
+		write(rec)
+	}}
+	return resp, nil
+}
+
+// peek reads up to one byte more than MaxBody of body, and returns what it
+// read with a body that reads it all again.
+func peek(body io.ReadCloser) ([]byte, io.ReadCloser) {
+	data, _ := io.ReadAll(io.LimitReader(body, MaxBody+1))
+	return data, struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(data), body), body}
+}
+
+// recordingBody keeps the first MaxBody bytes read from a body, and passes
+// them to done when the body is closed.
+type recordingBody struct {
+	io.ReadCloser
+	buf       bytes.Buffer
+	truncated bool
+	once      sync.Once
+	done      func(data []byte, truncated bool)
+}
+
+func (b *recordingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	keep := min(n, MaxBody-b.buf.Len())
+	b.buf.Write(p[:keep])
+	b.truncated = b.truncated || keep < n
+	return n, err
+}
+
+func (b *recordingBody) Close() error {
+	b.once.Do(func() { b.done(b.buf.Bytes(), b.truncated) })
+	return b.ReadCloser.Close()
+}
+
+// Handler returns next recording each request it serves, or next itself
+// if no capture is being recorded. It has the shape of a
+// mux.MiddlewareFunc.
+func Handler(next http.Handler) http.Handler {
+	if !Enabled() {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := Record{
+			Time:           start,
+			Kind:           KindHTTP,
+			Direction:      In,
+			Peer:           r.RemoteAddr,
+			Method:         r.Method,
+			URL:            redactURL(r.URL),
+			Proto:          r.Proto,
+			RequestHeaders: redactHeader(r.Header),
+		}
+		contentType := r.Header.Get("Content-Type")
+		if r.Body != nil && r.Body != http.NoBody {
+			var data []byte
+			if isJSON(contentType) {
+				data, r.Body = peek(r.Body)
+			}
+			rec.RequestBody = newBody(contentType, r.ContentLength, data, len(data) > MaxBody)
+		}
+
+		cw := &captureWriter{ResponseWriter: w}
+		next.ServeHTTP(cw, r)
+
+		rec.Status = cw.statusCode()
+		rec.ResponseHeaders = redactHeader(w.Header())
+		contentType = w.Header().Get("Content-Type")
+		rec.ResponseBody = newBody(contentType, cw.size, redactResponse(r.URL.Path, cw.buf.Bytes()), cw.truncated)
+		rec.DurationMs = since(start)
+		write(rec)
+	})
+}
+
+// captureWriter keeps the status, size and, for JSON, the first MaxBody
+// bytes of a response.
+type captureWriter struct {
+	http.ResponseWriter
+	status    int
+	size      int64
+	buf       bytes.Buffer
+	truncated bool
+}
+
+func (w *captureWriter) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *captureWriter) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(p)
+	w.size += int64(n)
+	if isJSON(w.Header().Get("Content-Type")) {
+		keep := min(n, MaxBody-w.buf.Len())
+		w.buf.Write(p[:keep])
+		w.truncated = w.truncated || keep < n
+	}
+	return n, err
+}
+
+// ReadFrom keeps the underlying writer's ReadFrom, and so sendfile, in use
+// for file contents, which are not recorded.
+func (w *captureWriter) ReadFrom(src io.Reader) (int64, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := io.Copy(w.ResponseWriter, src)
+	w.size += n
+	return n, err
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (w *captureWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *captureWriter) statusCode() int {
+	if w.status == 0 {
+		return http.StatusOK
+	}
+	return w.status
+}
+
+func since(start time.Time) float64 {
+	return float64(time.Since(start).Microseconds()) / 1000
+}
@@ -0,0 +1,147 @@
+package cli
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// ProgressFormat selects how transfer progress is reported.
+type ProgressFormat string
+
+const (
+	ProgressBar  ProgressFormat = "bar"
+	ProgressJSON ProgressFormat = "json"
+)
+
+// Progress event names emitted in ProgressJSON mode.
+const (
+	EventSessionStarted   = "session_started"
+	EventFileProgress     = "file_progress"
+	EventFileCompleted    = "file_completed"
+	EventFileFailed       = "file_failed"
+	EventFileRejected     = "file_rejected"
+	EventSessionCompleted = "session_completed"
+	EventSessionCancelled = "session_cancelled"
+	EventError            = "error"
+)
+
+// progressInterval bounds how often file_progress events are emitted per file.
+const progressInterval = 250 * time.Millisecond
+
+var (
+	progressFormat           = ProgressBar
+	eventOut       io.Writer = os.Stdout
+	eventMu        sync.Mutex
+)
+
+// SetProgressFormat switches between progress bars and NDJSON events. In
+// ProgressJSON mode events are written to stdout and the Print* helpers move
+// to stderr so the stream stays machine-readable.
+func SetProgressFormat(f ProgressFormat) {
+	progressFormat = f
+	if f == ProgressJSON || output.JSON {
+		printOut = os.Stderr
+	} else {
+		printOut = os.Stdout
+	}
+}
+
+// JSONProgressEnabled reports whether progress is emitted as NDJSON events.
+func JSONProgressEnabled() bool {
+	return progressFormat == ProgressJSON
+}
+
+// ProgressEvent is a single line of the NDJSON progress stream.
+type ProgressEvent struct {
+	Event     string `json:"event"`
+	Time      string `json:"time"`
+	Direction string `json:"direction,omitempty"` // "send" or "receive"
+	SessionID string `json:"sessionId,omitempty"`
+	File      string `json:"file,omitempty"`
+	Files     int    `json:"files,omitempty"`
+	Bytes     int64  `json:"bytes,omitempty"`
+	Total     int64  `json:"total,omitempty"`
+	Error     string `json:"error,omitempty"`
+	Reason    string `json:"reason,omitempty"` // how the receiver ended a send: "declined", "busy" or "cancelled"
+}
+
+// EmitEvent writes e as one JSON line. It is a no-op unless JSON progress is
+// enabled, so callers can emit unconditionally.
+func EmitEvent(e ProgressEvent) {
+	if !JSONProgressEnabled() {
+		return
+	}
+	e.Time = time.Now().UTC().Format(time.RFC3339Nano)
+	data, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+	eventMu.Lock()
+	defer eventMu.Unlock()
+	eventOut.Write(append(data, '\n'))
+}
+
+// Progress tracks per-file transfer progress for one session.
+type Progress interface {
+	AddBar(name string, size int64) func(int64)
+	ForceComplete()
+	Wait()
+}
+
+// NewSessionProgress returns progress bars, or an NDJSON event emitter when
+// JSON progress is enabled, in which case a session_started event is sent.
+func NewSessionProgress(direction, sessionID string, files int, total int64) Progress {
+	if !JSONProgressEnabled() {
+		return NewMultiProgress(int64(files))
+	}
+	EmitEvent(ProgressEvent{
+		Event:     EventSessionStarted,
+		Direction: direction,
+		SessionID: sessionID,
+		Files:     files,
+		Total:     total,
+	})
+	return &jsonProgress{direction: direction, sessionID: sessionID}
+}
+
+// jsonProgress reports file progress as NDJSON events.
+type jsonProgress struct {
+	direction string
+	sessionID string
+}
+
+func (jp *jsonProgress) AddBar(name string, size int64) func(int64) {
+	var last time.Time
+	done := false
+	return func(current int64) {
+		if done {
+			return
+		}
+		e := ProgressEvent{
+			Direction: jp.direction,
+			SessionID: jp.sessionID,
+			File:      name,
+			Bytes:     current,
+			Total:     size,
+		}
+		if current >= size {
+			done = true
+			e.Event = EventFileCompleted
+			EmitEvent(e)
+			return
+		}
+		if time.Since(last) < progressInterval {
+			return
+		}
+		last = time.Now()
+		e.Event = EventFileProgress
+		EmitEvent(e)
+	}
+}
+
+func (jp *jsonProgress) ForceComplete() {}
+
+func (jp *jsonProgress) Wait() {}
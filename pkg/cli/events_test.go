@@ -0,0 +1,56 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestJSONProgress_EmitsEvents(t *testing.T) {
+	var buf bytes.Buffer
+	prev := eventOut
+	eventOut = &buf
+	SetProgressFormat(ProgressJSON)
+	defer func() {
+		SetProgressFormat(ProgressBar)
+		eventOut = prev
+	}()
+
+	p := NewSessionProgress("send", "session-1", 1, 10)
+	track := p.AddBar("a.txt", 10)
+	track(4)
+	track(10)
+	track(10)
+	EmitEvent(ProgressEvent{Event: EventSessionCompleted, Direction: "send", SessionID: "session-1"})
+
+	var events []string
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var e ProgressEvent
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			t.Fatalf("invalid JSON line %q: %v", line, err)
+		}
+		if e.SessionID != "session-1" || e.Time == "" {
+			t.Errorf("unexpected event fields: %+v", e)
+		}
+		events = append(events, e.Event)
+	}
+
+	want := []string{EventSessionStarted, EventFileProgress, EventFileCompleted, EventSessionCompleted}
+	if strings.Join(events, ",") != strings.Join(want, ",") {
+		t.Errorf("events = %v, want %v", events, want)
+	}
+}
+
+func TestEmitEvent_NoopInBarMode(t *testing.T) {
+	var buf bytes.Buffer
+	prev := eventOut
+	eventOut = &buf
+	defer func() { eventOut = prev }()
+
+	SetProgressFormat(ProgressBar)
+	EmitEvent(ProgressEvent{Event: EventError})
+	if buf.Len() != 0 {
+		t.Errorf("expected no output in bar mode, got %q", buf.String())
+	}
+}
@@ -2,7 +2,8 @@ package cli
 
 // Nerd Font v3 icons (Requires a Nerd Font patched terminal font, e.g. JetBrainsMono NF, FiraCode NF).
 // These glyphs are selected from standard NFv3 codepoints (FontAwesome 6) for maximum compatibility.
-const (
+// --no-emoji replaces them with plain text; see usePlainIcons.
+var (
 	IconCheck      = "\uf00c" // nf-fa-check (✓)
 	IconCross      = "\uf00d" // nf-fa-times (✗)
 	IconInfo       = "\uf129" // nf-fa-info (ℹ)
@@ -33,4 +34,25 @@ const (
 	IconArrowR     = "\uf178" // nf-fa-long_arrow_right
 	IconArrowUp    = "\uf062" // nf-fa-arrow_up
 	IconArrowDn    = "\uf063" // nf-fa-arrow_down
-)
\ No newline at end of file
+)
+
+// usePlainIcons replaces the icons with ASCII for terminals without a Nerd
+// Font. Status icons become short markers and purely decorative ones become
+// empty; see Label.
+func usePlainIcons() {
+	IconCheck, IconCross, IconInfo, IconWarning, IconSpinner = "[ok]", "[x]", "[i]", "[!]", "[*]"
+	IconFile, IconDownload, IconUpload = "-", "v", "^"
+	IconFolder, IconFolderOpen, IconServer, IconNetwork, IconDevice = "", "", "", "", ""
+	IconBell, IconLock, IconRocket, IconStop, IconGear = "", "", "", "", ""
+	IconHash, IconChain, IconStar, IconHeart, IconBolt, IconSearch = "#", "", "*", "", "", ""
+	IconPlus, IconMinus, IconArrow, IconArrowR, IconArrowUp, IconArrowDn = "+", "-", "->", "->", "^", "v"
+}
+
+// Label puts icon in front of text, or returns text alone if the icon is
+// empty.
+func Label(icon, text string) string {
+	if icon == "" {
+		return text
+	}
+	return icon + " " + text
+}
@@ -0,0 +1,43 @@
+package cli
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+)
+
+// RenderImage draws img width cells wide with half-block characters, two
+// pixels to a cell, for showing image previews in the terminal. It returns ""
+// when colors are off.
+func RenderImage(img image.Image, width int) string {
+	b := img.Bounds()
+	if lipgloss.ColorProfile() == termenv.Ascii || b.Empty() {
+		return ""
+	}
+	width = min(width, b.Dx())
+	rows := max(1, b.Dy()*width/b.Dx()/2)
+
+	var sb strings.Builder
+	for row := 0; row < rows; row++ {
+		if row > 0 {
+			sb.WriteByte('\n')
+		}
+		for col := 0; col < width; col++ {
+			x := b.Min.X + col*b.Dx()/width
+			top := img.At(x, b.Min.Y+2*row*b.Dy()/(2*rows))
+			bottom := img.At(x, b.Min.Y+(2*row+1)*b.Dy()/(2*rows))
+			sb.WriteString(lipgloss.NewStyle().Foreground(hexColor(top)).Background(hexColor(bottom)).Render("▀"))
+		}
+	}
+	return sb.String()
+}
+
+// hexColor converts c to a lipgloss color, ignoring transparency.
+func hexColor(c color.Color) lipgloss.Color {
+	r, g, b, _ := c.RGBA()
+	return lipgloss.Color(fmt.Sprintf("#%02x%02x%02x", r>>8, g>>8, b>>8))
+}
@@ -18,9 +18,9 @@ func SetNotificationCmd(cmd string) {
 }
 
 // Notify sends a native desktop notification. Icon is empty (system default).
-// No-op in container environments.
+// No-op in headless mode and container environments.
 func Notify(title, body string) {
-	if IsContainer() {
+	if Headless() {
 		return
 	}
 	if notificationCmd != "" {
@@ -34,6 +34,21 @@ func Notify(title, body string) {
 	beeep.Notify(title, body, "")
 }
 
+// headless is set by SetHeadless.
+var headless bool
+
+// SetHeadless marks the process as running without a user at the machine:
+// prompts are rejected and nothing is opened or notified, as in a container.
+func SetHeadless(on bool) {
+	headless = on
+}
+
+// Headless reports whether there is no user to interact with, either because
+// headless mode is on or because LocalGo runs in a container.
+func Headless() bool {
+	return headless || IsContainer()
+}
+
 // IsContainer returns true if LocalGo is running inside a Docker/Podman container.
 func IsContainer() bool {
 	if _, err := os.Stat("/.dockerenv"); err == nil {
@@ -1,16 +1,22 @@
 package cli
 
 import (
+	"encoding/csv"
 	"encoding/json"
 	"fmt"
 	"os"
+	"slices"
+	"strconv"
 	"strings"
 	"text/tabwriter"
+	"text/template"
 	"time"
 
 	"github.com/bethropolis/localgo/pkg/model"
 	"github.com/charmbracelet/huh"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+	"gopkg.in/yaml.v3"
 )
 
 // OutputFormat represents the output format type
@@ -20,20 +26,187 @@ const (
 	FormatJSON  OutputFormat = "json"
 	FormatTable OutputFormat = "table"
 	FormatQuiet OutputFormat = "quiet"
+	FormatCSV   OutputFormat = "csv"
+	FormatYAML  OutputFormat = "yaml"
+	// FormatTemplate executes a Go template once per result.
+	FormatTemplate OutputFormat = "template"
 )
 
+// OutputOptions are the global output flags. They apply to every command.
+type OutputOptions struct {
+	JSON     bool               // results as JSON on stdout; messages go to stderr
+	Format   OutputFormat       // table, json, yaml, csv or template; empty means table, or json with JSON
+	Template *template.Template // the template for FormatTemplate
+	Quiet    bool               // only results, warnings and errors
+	NoColor  bool               // no colors or text styles
+	NoEmoji  bool               // plain text instead of Nerd Font icons
+}
+
+// output is set by SetOutput.
+var output OutputOptions
+
+// ParseFormat parses a --format value: "table", "json", "yaml", "csv", or
+// "template=" followed by a Go template such as '{{.Alias}} {{.IP}}'.
+func ParseFormat(s string) (OutputFormat, *template.Template, error) {
+	if text, ok := strings.CutPrefix(s, "template="); ok {
+		if text == "" {
+			return "", nil, fmt.Errorf("empty template in --format %q", s)
+		}
+		tmpl, err := template.New("format").Parse(text)
+		if err != nil {
+			return "", nil, fmt.Errorf("invalid template: %w", err)
+		}
+		return FormatTemplate, tmpl, nil
+	}
+	switch f := OutputFormat(s); f {
+	case FormatTable, FormatJSON, FormatYAML, FormatCSV:
+		return f, nil, nil
+	case FormatTemplate:
+		return "", nil, fmt.Errorf("--format template needs a template, e.g. --format 'template={{.Alias}} {{.IP}}'")
+	}
+	return "", nil, fmt.Errorf("unknown format %q: use table, json, yaml, csv or template=TEMPLATE", s)
+}
+
+// SetOutput applies the global output flags. Call it once, before a command
+// prints anything.
+func SetOutput(o OutputOptions) {
+	if o.Format == FormatJSON {
+		o.JSON = true
+	} else if o.JSON {
+		o.Format = FormatJSON
+	}
+	output = o
+	if MachineOutput() {
+		printOut = os.Stderr
+	}
+	if o.NoColor {
+		lipgloss.SetColorProfile(termenv.Ascii)
+	}
+	if o.NoEmoji {
+		usePlainIcons()
+	}
+}
+
+// JSONOutput reports whether results should be printed as JSON.
+func JSONOutput() bool {
+	return output.JSON
+}
+
+// MachineOutput reports whether results are printed for programs to read
+// (JSON, YAML, CSV or a template), so stdout must carry nothing else.
+func MachineOutput() bool {
+	switch output.Format {
+	case FormatJSON, FormatYAML, FormatCSV, FormatTemplate:
+		return true
+	}
+	return false
+}
+
+// QuietOutput reports whether non-essential output is suppressed.
+func QuietOutput() bool {
+	return output.Quiet
+}
+
+// Format returns the format for command results chosen by the global flags.
+func Format() OutputFormat {
+	switch {
+	case MachineOutput():
+		return output.Format
+	case output.Quiet:
+		return FormatQuiet
+	default:
+		return FormatTable
+	}
+}
+
 // OutputWriter handles different output formats
 type OutputWriter struct {
-	format OutputFormat
-	writer *tabwriter.Writer
+	format   OutputFormat
+	template *template.Template
+	writer   *tabwriter.Writer
 }
 
-// NewOutputWriter creates a new output writer
+// NewOutputWriter creates a new output writer. FormatTemplate uses the
+// template given to SetOutput.
 func NewOutputWriter(format OutputFormat) *OutputWriter {
 	return &OutputWriter{
-		format: format,
-		writer: tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0),
+		format:   format,
+		template: output.Template,
+		writer:   tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0),
+	}
+}
+
+// Format returns the writer's output format.
+func (ow *OutputWriter) Format() OutputFormat {
+	return ow.format
+}
+
+// WriteJSON outputs data as indented JSON.
+func (ow *OutputWriter) WriteJSON(data any) error {
+	return ow.writeJSON(data)
+}
+
+// WriteYAML outputs data as YAML with the same keys, in the same order, as
+// its JSON encoding.
+func (ow *OutputWriter) WriteYAML(data any) error {
+	b, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	// JSON is valid YAML, and decoding it into a node keeps the key order.
+	var node yaml.Node
+	if err := yaml.Unmarshal(b, &node); err != nil {
+		return err
+	}
+	plainStyle(&node)
+	encoder := yaml.NewEncoder(os.Stdout)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&node); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
+
+// plainStyle drops the JSON flow and quoting styles from a decoded node so it
+// is written as block YAML. The encoder still quotes strings that need it.
+func plainStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		plainStyle(child)
+	}
+}
+
+// WriteCSV outputs a header line and one line per row as CSV.
+func (ow *OutputWriter) WriteCSV(header []string, rows [][]string) error {
+	w := csv.NewWriter(os.Stdout)
+	if err := w.Write(header); err != nil {
+		return err
 	}
+	if err := w.WriteAll(rows); err != nil {
+		return err
+	}
+	return w.Error()
+}
+
+// WriteTemplate executes the template with each item in turn, ending every
+// result with a newline.
+func (ow *OutputWriter) WriteTemplate(items ...any) error {
+	if ow.template == nil {
+		return fmt.Errorf("no output template set")
+	}
+	for _, item := range items {
+		var b strings.Builder
+		if err := ow.template.Execute(&b, item); err != nil {
+			return err
+		}
+		if !strings.HasSuffix(b.String(), "\n") {
+			b.WriteString("\n")
+		}
+		if _, err := os.Stdout.WriteString(b.String()); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 // WriteDevices outputs a list of devices in the specified format
@@ -41,8 +214,28 @@ func (ow *OutputWriter) WriteDevices(devices []*model.Device, method string) err
 	switch ow.format {
 	case FormatJSON:
 		return ow.writeDevicesJSON(devices)
+	case FormatYAML:
+		return ow.WriteYAML(devicesResult(devices))
 	case FormatQuiet:
 		return ow.writeDevicesQuiet(devices)
+	case FormatCSV:
+		// Found devices also have what the probe measured; latencyMs is
+		// empty for a device that was not timed.
+		rows := make([][]string, len(devices))
+		for i, device := range devices {
+			latency := ""
+			if device.LatencyMs > 0 {
+				latency = strconv.FormatFloat(device.LatencyMs, 'f', -1, 64)
+			}
+			rows[i] = append(DeviceCSVRow(device), latency, device.Hostname)
+		}
+		return ow.WriteCSV(append(slices.Clone(DeviceCSVHeader), "latencyMs", "hostname"), rows)
+	case FormatTemplate:
+		items := make([]any, len(devices))
+		for i, device := range devices {
+			items[i] = device
+		}
+		return ow.WriteTemplate(items...)
 	default:
 		return ow.writeDevicesTable(devices, method)
 	}
@@ -53,11 +246,25 @@ func (ow *OutputWriter) WriteDeviceInfo(info DeviceInfo) error {
 	switch ow.format {
 	case FormatJSON:
 		return ow.writeJSON(info)
+	case FormatYAML:
+		return ow.WriteYAML(info)
 	default:
 		return ow.writeDeviceInfoTable(info)
 	}
 }
 
+// WriteRemoteDeviceInfo outputs what another device reports about itself
+func (ow *OutputWriter) WriteRemoteDeviceInfo(info RemoteDeviceInfo) error {
+	switch ow.format {
+	case FormatJSON:
+		return ow.writeJSON(info)
+	case FormatYAML:
+		return ow.WriteYAML(info)
+	default:
+		return ow.writeRemoteDeviceInfoTable(info)
+	}
+}
+
 // WriteMessage outputs a simple message
 func (ow *OutputWriter) WriteMessage(message string) {
 	if ow.format != FormatQuiet {
@@ -99,13 +306,45 @@ func (ow *OutputWriter) Flush() error {
 	return nil
 }
 
+// DeviceCSVHeader names the columns of DeviceCSVRow.
+var DeviceCSVHeader = []string{"alias", "ip", "port", "protocol", "deviceType", "deviceModel", "fingerprint", "version", "download", "lastSeen"}
+
+// DeviceCSVRow returns the CSV columns for a device. lastSeen is RFC 3339,
+// or empty if the device has not been seen.
+func DeviceCSVRow(device *model.Device) []string {
+	lastSeen, deviceModel := "", ""
+	if t := device.GetLastSeen(); !t.IsZero() {
+		lastSeen = t.Format(time.RFC3339)
+	}
+	if device.DeviceModel != nil {
+		deviceModel = *device.DeviceModel
+	}
+	return []string{
+		device.Alias,
+		device.IP,
+		strconv.Itoa(device.Port),
+		string(device.Protocol),
+		string(device.DeviceType),
+		deviceModel,
+		device.Fingerprint,
+		device.Version,
+		strconv.FormatBool(device.Download),
+		lastSeen,
+	}
+}
+
 // writeDevicesJSON outputs devices in JSON format
 func (ow *OutputWriter) writeDevicesJSON(devices []*model.Device) error {
-	return ow.writeJSON(map[string]interface{}{
+	return ow.writeJSON(devicesResult(devices))
+}
+
+// devicesResult is the JSON and YAML document for a device list.
+func devicesResult(devices []*model.Device) map[string]interface{} {
+	return map[string]interface{}{
 		"devices":   devices,
 		"count":     len(devices),
 		"timestamp": time.Now().Format(time.RFC3339),
-	})
+	}
 }
 
 // writeDevicesTable outputs devices in table format
@@ -124,25 +363,63 @@ func (ow *OutputWriter) writeDevicesTable(devices []*model.Device, method string
 
 	fmt.Printf("Found %d device(s) via %s:\n\n", len(devices), method)
 
-	// Write header
-	fmt.Fprintf(ow.writer, "ALIAS\tIP ADDRESS\tPROTOCOL\tPORT\tDEVICE TYPE\tFINGERPRINT\n")
-	fmt.Fprintf(ow.writer, "-----\t----------\t--------\t----\t-----------\t-----------\n")
+	// Hostnames and latencies are only known after a scan or discover.
+	var hostnames, latencies bool
+	for _, device := range devices {
+		hostnames = hostnames || device.Hostname != ""
+		latencies = latencies || device.LatencyMs > 0
+	}
+	header := []string{"ALIAS", "IP ADDRESS"}
+	if hostnames {
+		header = append(header, "HOSTNAME")
+	}
+	header = append(header, "PROTOCOL", "PORT")
+	if latencies {
+		header = append(header, "LATENCY")
+	}
+	header = append(header, "DEVICE TYPE", "FINGERPRINT")
+	rule := make([]string, len(header))
+	for i, h := range header {
+		rule[i] = strings.Repeat("-", len(h))
+	}
+	fmt.Fprintln(ow.writer, strings.Join(header, "\t"))
+	fmt.Fprintln(ow.writer, strings.Join(rule, "\t"))
 
-	// Write devices
 	for _, device := range devices {
-		fmt.Fprintf(ow.writer, "%s\t%s\t%s\t%d\t%s\t%s...\n",
-			TruncateString(Sanitize(device.Alias), 20),
-			device.IP,
-			strings.ToUpper(string(device.Protocol)),
-			device.Port,
-			string(device.DeviceType),
-			shortFingerprint(device.Fingerprint),
-		)
+		row := []string{TruncateString(Sanitize(device.Alias), 20), device.IP}
+		if hostnames {
+			row = append(row, orDash(TruncateString(Sanitize(device.Hostname), 30)))
+		}
+		row = append(row, strings.ToUpper(string(device.Protocol)), strconv.Itoa(device.Port))
+		if latencies {
+			row = append(row, formatLatency(device.LatencyMs))
+		}
+		row = append(row, string(device.DeviceType), shortFingerprint(device.Fingerprint)+"...")
+		fmt.Fprintln(ow.writer, strings.Join(row, "\t"))
 	}
 
 	return ow.writer.Flush()
 }
 
+// formatLatency formats a latency in milliseconds for the device table.
+func formatLatency(ms float64) string {
+	switch {
+	case ms <= 0:
+		return "-"
+	case ms < 10:
+		return fmt.Sprintf("%.1f ms", ms)
+	default:
+		return fmt.Sprintf("%.0f ms", ms)
+	}
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
 // writeDevicesQuiet outputs devices in quiet format (tab-separated)
 func (ow *OutputWriter) writeDevicesQuiet(devices []*model.Device) error {
 	for _, device := range devices {
@@ -168,19 +445,35 @@ type DeviceInfo struct {
 	DownloadDir   string `json:"downloadDir"`
 	HasPin        bool   `json:"hasPin"`
 	MulticastAddr string `json:"multicastAddr"`
+	CertExpires   string `json:"certExpires,omitempty"` // date the TLS certificate expires
+	CertWarning   string `json:"certWarning,omitempty"` // e.g. that it expires soon
+	// PreviousFingerprints are those the device was known by before its
+	// certificate was regenerated, oldest first.
+	PreviousFingerprints []string `json:"previousFingerprints,omitempty"`
+}
+
+// RemoteDeviceInfo represents what another device reports about itself
+type RemoteDeviceInfo struct {
+	Alias       string `json:"alias"`
+	Version     string `json:"version"`
+	DeviceModel string `json:"deviceModel"`
+	DeviceType  string `json:"deviceType"`
+	Fingerprint string `json:"fingerprint"`
+	Address     string `json:"address"`
+	Protocol    string `json:"protocol"`
+	Download    bool   `json:"download"` // serves files for download (share)
+	Verified    bool   `json:"verified"` // TLS certificate matches the fingerprint
+}
+
+// infoField is one labelled line of an info card.
+type infoField struct {
+	label string
+	value string
 }
 
 // writeDeviceInfoTable outputs device info in a stylized card layout
 func (ow *OutputWriter) writeDeviceInfoTable(info DeviceInfo) error {
-	titleStyle := HeaderStyle.Padding(0, 1).MarginBottom(1)
-	borderStyle := lipgloss.NewStyle().Border(lipgloss.RoundedBorder()).BorderForeground(lipgloss.Color("62")).Padding(1, 2)
-	labelStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("242")).Width(18).Bold(true)
-	valueStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("255"))
-
-	fields := []struct {
-		label string
-		value string
-	}{
+	fields := []infoField{
 		{"Alias", info.Alias},
 		{"Protocol", "LocalSend v" + info.Version},
 		{"Device Model", info.DeviceModel},
@@ -197,15 +490,58 @@ func (ow *OutputWriter) writeDeviceInfoTable(info DeviceInfo) error {
 		{"Multicast", info.MulticastAddr},
 		{"Fingerprint", info.Fingerprint},
 	}
+	if info.CertExpires != "" {
+		expires := info.CertExpires
+		if info.CertWarning != "" {
+			expires += " (" + info.CertWarning + ")"
+		}
+		fields = append(fields, infoField{"Cert Expires", expires})
+	}
+	for _, fp := range info.PreviousFingerprints {
+		fields = append(fields, infoField{"Old Fingerprint", fp})
+	}
+	writeInfoCard("LocalGo Device Information", fields)
+	return nil
+}
+
+// writeRemoteDeviceInfoTable outputs remote device info in the same card layout
+func (ow *OutputWriter) writeRemoteDeviceInfoTable(info RemoteDeviceInfo) error {
+	download := "Not available"
+	if info.Download {
+		download = "Available"
+	}
+	fingerprint := info.Fingerprint
+	if info.Verified {
+		fingerprint += " (verified)"
+	}
+	fields := []infoField{
+		{"Alias", info.Alias},
+		{"Protocol", "LocalSend v" + info.Version},
+		{"Device Model", info.DeviceModel},
+		{"Device Type", info.DeviceType},
+		{"Address", info.Address},
+		{"Transport", strings.ToUpper(info.Protocol)},
+		{"Download", download},
+		{"Fingerprint", fingerprint},
+	}
+	writeInfoCard("Remote Device Information", fields)
+	return nil
+}
+
+// writeInfoCard prints a title and a bordered card of labelled fields.
+func writeInfoCard(title string, fields []infoField) {
+	titleStyle := HeaderStyle.Padding(0, 1).MarginBottom(1)
+	borderStyle := lipgloss.NewStyle().Border(lipgloss.RoundedBorder()).BorderForeground(lipgloss.Color("62")).Padding(1, 2)
+	labelStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("242")).Width(18).Bold(true)
+	valueStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("255"))
 
 	var content strings.Builder
 	for _, f := range fields {
 		content.WriteString(fmt.Sprintf("%s %s\n", labelStyle.Render(f.label+":"), valueStyle.Render(f.value)))
 	}
 
-	fmt.Println(titleStyle.Render(IconDevice + "  LocalGo Device Information"))
+	fmt.Println(titleStyle.Render(Label(IconDevice, " "+title)))
 	fmt.Println(borderStyle.Render(content.String()))
-	return nil
 }
 
 // writeJSON outputs data in JSON format
@@ -218,31 +554,48 @@ func (ow *OutputWriter) writeJSON(data interface{}) error {
 // PickDevice presents an interactive TUI to select a device. Returns the selected device or nil if canceled.
 // When private is true, device aliases are anonymized in the selection list.
 func PickDevice(devices []*model.Device, private bool) *model.Device {
-	if IsContainer() {
+	if Headless() {
 		return nil
 	}
 	if len(devices) == 0 {
 		return nil
 	}
 
+	// Sort a copy by alias so the numbering is stable between runs.
+	sorted := slices.Clone(devices)
+	slices.SortFunc(sorted, func(a, b *model.Device) int {
+		if c := strings.Compare(strings.ToLower(a.Alias), strings.ToLower(b.Alias)); c != 0 {
+			return c
+		}
+		return strings.Compare(a.IP, b.IP)
+	})
+
+	// Devices sharing an alias are told apart by their fingerprint.
+	aliasCount := make(map[string]int, len(sorted))
+	for _, d := range sorted {
+		aliasCount[d.Alias]++
+	}
+
 	var selected *model.Device
-	options := make([]huh.Option[*model.Device], len(devices))
-	for i, d := range devices {
+	options := make([]huh.Option[*model.Device], len(sorted))
+	for i, d := range sorted {
 		displayName := Sanitize(d.Alias)
 		if private {
 			displayName = AnonymizedAlias(d)
 		}
 		protocol := strings.ToUpper(string(d.Protocol))
-		options[i] = huh.NewOption(
-			fmt.Sprintf("%s  %s:%d  [%s]", displayName, d.IP, d.Port, protocol),
-			d,
-		)
+		label := fmt.Sprintf("%2d. %s  %s:%d  [%s]", i+1, displayName, d.IP, d.Port, protocol)
+		if aliasCount[d.Alias] > 1 && len(d.Fingerprint) >= 8 {
+			label += "  " + d.Fingerprint[:8]
+		}
+		options[i] = huh.NewOption(label, d)
 	}
 
 	form := huh.NewForm(
 		huh.NewGroup(
 			huh.NewSelect[*model.Device]().
-				Title("Select recipient:").
+				Title(fmt.Sprintf("Select recipient (%d found):", len(sorted))).
+				Description("↑/↓ to move, / to filter, enter to select").
 				Options(options...).
 				Value(&selected).
 				WithHeight(10),
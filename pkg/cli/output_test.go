@@ -1,8 +1,14 @@
 package cli
 
 import (
+	"bytes"
+	"io"
+	"os"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/bethropolis/localgo/pkg/model"
 )
 
 func TestFormatBytes(t *testing.T) {
@@ -82,3 +88,170 @@ func TestOutputWriterWriteMessage(t *testing.T) {
 	ow := NewOutputWriter(FormatQuiet)
 	ow.WriteMessage("test message")
 }
+
+func TestSetOutput_Quiet(t *testing.T) {
+	var buf bytes.Buffer
+	prevOut, prevOutput := printOut, output
+	defer func() { printOut, output = prevOut, prevOutput }()
+
+	SetOutput(OutputOptions{Quiet: true})
+	printOut = &buf
+	PrintInfo("info")
+	PrintSuccess("success")
+	PrintHeader("header")
+	PrintWarning("warning")
+	PrintError("error")
+
+	got := buf.String()
+	for _, hidden := range []string{"info", "success", "header"} {
+		if strings.Contains(got, hidden) {
+			t.Errorf("quiet output contains %q: %q", hidden, got)
+		}
+	}
+	for _, shown := range []string{"warning", "error"} {
+		if !strings.Contains(got, shown) {
+			t.Errorf("quiet output is missing %q: %q", shown, got)
+		}
+	}
+	if Format() != FormatQuiet {
+		t.Errorf("Format() = %s, want %s", Format(), FormatQuiet)
+	}
+}
+
+func TestLabel(t *testing.T) {
+	if got := Label("[ok]", "done"); got != "[ok] done" {
+		t.Errorf("Label with icon = %q", got)
+	}
+	if got := Label("", "done"); got != "done" {
+		t.Errorf("Label without icon = %q", got)
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    OutputFormat
+		wantErr bool
+	}{
+		{"table", FormatTable, false},
+		{"json", FormatJSON, false},
+		{"csv", FormatCSV, false},
+		{"template={{.Alias}} {{.IP}}", FormatTemplate, false},
+		{"template", "", true},
+		{"template=", "", true},
+		{"template={{.Alias", "", true},
+		{"xml", "", true},
+	}
+	for _, tt := range tests {
+		got, tmpl, err := ParseFormat(tt.input)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseFormat(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseFormat(%q) = %s, want %s", tt.input, got, tt.want)
+		}
+		if (tmpl != nil) != (got == FormatTemplate) {
+			t.Errorf("ParseFormat(%q) template = %v", tt.input, tmpl)
+		}
+	}
+}
+
+// captureStdout returns what fn writes to os.Stdout.
+func captureStdout(t *testing.T, fn func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	prev := os.Stdout
+	os.Stdout = w
+	fn()
+	os.Stdout = prev
+	w.Close()
+	out, _ := io.ReadAll(r)
+	return string(out)
+}
+
+func TestOutputWriter_CSVAndTemplate(t *testing.T) {
+	prevOut, prevOutput := printOut, output
+	defer func() { printOut, output = prevOut, prevOutput }()
+
+	deviceModel := "Pixel"
+	devices := []*model.Device{
+		{Alias: "Phone, \"A\"", IP: "192.168.1.5", Port: 53317, Protocol: model.ProtocolTypeHTTPS, DeviceModel: &deviceModel, Fingerprint: "abc", LatencyMs: 2.5, Hostname: "phone.local"},
+		{Alias: "Laptop", IP: "192.168.1.6", Port: 53318, Protocol: model.ProtocolTypeHTTP},
+	}
+
+	SetOutput(OutputOptions{Format: FormatCSV})
+	if !MachineOutput() || Format() != FormatCSV {
+		t.Fatalf("Format() = %s, want %s", Format(), FormatCSV)
+	}
+	got := captureStdout(t, func() {
+		if err := NewOutputWriter(Format()).WriteDevices(devices, "test"); err != nil {
+			t.Errorf("WriteDevices: %v", err)
+		}
+	})
+	want := "alias,ip,port,protocol,deviceType,deviceModel,fingerprint,version,download,lastSeen,latencyMs,hostname\n" +
+		"\"Phone, \"\"A\"\"\",192.168.1.5,53317,https,,Pixel,abc,,false,,2.5,phone.local\n" +
+		"Laptop,192.168.1.6,53318,http,,,,,false,,,\n"
+	if got != want {
+		t.Errorf("CSV output = %q, want %q", got, want)
+	}
+
+	_, tmpl, err := ParseFormat("template={{.Alias}} {{.IP}}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	SetOutput(OutputOptions{Format: FormatTemplate, Template: tmpl})
+	got = captureStdout(t, func() {
+		if err := NewOutputWriter(Format()).WriteDevices(devices, "test"); err != nil {
+			t.Errorf("WriteDevices: %v", err)
+		}
+	})
+	if want := "Phone, \"A\" 192.168.1.5\nLaptop 192.168.1.6\n"; got != want {
+		t.Errorf("template output = %q, want %q", got, want)
+	}
+}
+
+func TestOutputWriter_WriteYAML(t *testing.T) {
+	info := DeviceInfo{Alias: "Desk", Version: "2.0", Port: 53317, HasPin: true}
+	got := captureStdout(t, func() {
+		if err := NewOutputWriter(FormatYAML).WriteDeviceInfo(info); err != nil {
+			t.Errorf("WriteDeviceInfo: %v", err)
+		}
+	})
+	// Keys follow the JSON names and order; strings that look like numbers
+	// stay quoted.
+	for _, line := range []string{"alias: Desk\n", "version: \"2.0\"\n", "port: 53317\n", "hasPin: true\n"} {
+		if !strings.Contains(got, line) {
+			t.Errorf("YAML output is missing %q:\n%s", line, got)
+		}
+	}
+	if strings.Index(got, "alias:") > strings.Index(got, "port:") {
+		t.Errorf("YAML keys are not in JSON order:\n%s", got)
+	}
+}
+
+func TestOutputWriter_DevicesTableColumns(t *testing.T) {
+	devices := []*model.Device{
+		{Alias: "Phone", IP: "192.168.1.5", Port: 53317, Protocol: model.ProtocolTypeHTTPS, LatencyMs: 2.54, Hostname: "phone.local"},
+		{Alias: "Laptop", IP: "192.168.1.6", Port: 53317, Protocol: model.ProtocolTypeHTTP, LatencyMs: 31.2},
+	}
+	got := captureStdout(t, func() {
+		NewOutputWriter(FormatTable).WriteDevices(devices, "test")
+	})
+	for _, want := range []string{"HOSTNAME", "LATENCY", "phone.local", "2.5 ms", "31 ms"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("table is missing %q:\n%s", want, got)
+		}
+	}
+
+	// Columns nothing filled in are left out.
+	got = captureStdout(t, func() {
+		NewOutputWriter(FormatTable).WriteDevices([]*model.Device{{Alias: "Phone", IP: "192.168.1.5"}}, "test")
+	})
+	if strings.Contains(got, "HOSTNAME") || strings.Contains(got, "LATENCY") {
+		t.Errorf("table has empty columns:\n%s", got)
+	}
+}
@@ -1,23 +1,55 @@
 package cli
 
-import "fmt"
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// printOut is where the Print* helpers write; see SetProgressFormat.
+var printOut io.Writer = os.Stdout
+
+// SetPrintOutput redirects the Print* helpers, e.g. to stderr when stdout
+// carries output meant for scripts.
+func SetPrintOutput(w io.Writer) {
+	printOut = w
+}
+
+// PrintSuccess, PrintInfo and PrintHeader print nothing with --quiet;
+// warnings and errors are always printed.
 
 func PrintSuccess(format string, a ...any) {
-	fmt.Println(SuccessStyle.Render(IconCheck + " " + fmt.Sprintf(format, a...)))
+	if output.Quiet {
+		return
+	}
+	fmt.Fprintln(printOut, SuccessStyle.Render(Label(IconCheck, fmt.Sprintf(format, a...))))
 }
 
 func PrintError(format string, a ...any) {
-	fmt.Println(ErrorStyle.Render(IconCross + " " + fmt.Sprintf(format, a...)))
+	fmt.Fprintln(printOut, ErrorStyle.Render(Label(IconCross, fmt.Sprintf(format, a...))))
 }
 
 func PrintWarning(format string, a ...any) {
-	fmt.Println(WarningStyle.Render(IconWarning + " " + fmt.Sprintf(format, a...)))
+	fmt.Fprintln(printOut, WarningStyle.Render(Label(IconWarning, fmt.Sprintf(format, a...))))
 }
 
 func PrintInfo(format string, a ...any) {
-	fmt.Println(InfoStyle.Render(IconInfo + " " + fmt.Sprintf(format, a...)))
+	if output.Quiet {
+		return
+	}
+	fmt.Fprintln(printOut, InfoStyle.Render(Label(IconInfo, fmt.Sprintf(format, a...))))
+}
+
+// PrintMessage prints a text message received from alias. Like warnings it
+// is printed even with --quiet, since it is the content itself.
+func PrintMessage(alias, text string) {
+	fmt.Fprintln(printOut, HeaderStyle.Render(fmt.Sprintf("Message from %s", alias)))
+	fmt.Fprintln(printOut, Sanitize(text))
 }
 
 func PrintHeader(text string) {
-	fmt.Println(HeaderStyle.Render(text))
+	if output.Quiet {
+		return
+	}
+	fmt.Fprintln(printOut, HeaderStyle.Render(text))
 }
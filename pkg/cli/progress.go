@@ -55,11 +55,14 @@ func (mp *MultiProgress) AddBar(name string, size int64) func(int64) {
 	}
 }
 
+// ForceComplete ends every bar still running, such as those of failed or
+// skipped uploads, so Wait can return. SetTotal can't be used for this: it
+// is ignored once a bar has trigger-complete enabled.
 func (mp *MultiProgress) ForceComplete() {
 	mp.mu.Lock()
 	defer mp.mu.Unlock()
 	for _, bar := range mp.bars {
-		bar.SetTotal(0, true)
+		bar.Abort(false)
 	}
 }
 
@@ -6,6 +6,7 @@
 package clipboard
 
 import (
+	"bytes"
 	"fmt"
 	"os/exec"
 	"strings"
@@ -20,8 +21,16 @@ type clipProvider struct {
 	args     []string
 	readCmd  string
 	readArgs []string
+
+	// imageCmd prints the clipboard image, converted by decodeImage if set.
+	imageCmd    string
+	imageArgs   []string
+	decodeImage func([]byte) []byte
 }
 
+// pngSignature starts every PNG file.
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
 func init() {
 	provider = detect()
 }
@@ -60,6 +69,36 @@ func Read() (string, error) {
 	return strings.ReplaceAll(string(out), "\r\n", "\n"), nil
 }
 
+// ReadImage retrieves an image from the system clipboard as PNG data. It
+// returns nil, without an error, when the clipboard holds no image.
+// Returns an error when no tool that can read images is available.
+func ReadImage() ([]byte, error) {
+	if provider == nil || provider.imageCmd == "" {
+		return nil, fmt.Errorf("clipboard image read unavailable: no supported tool found (install wl-paste or xclip)")
+	}
+	cmd := exec.Command(provider.imageCmd, provider.imageArgs...) //nolint:gosec
+	out, err := cmd.Output()
+	if err != nil {
+		// The tools fail when the clipboard has no image in PNG form.
+		if len(out) == 0 {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("clipboard image read failed (%s): %w", provider.imageCmd, err)
+	}
+	if provider.decodeImage != nil {
+		out = provider.decodeImage(out)
+	}
+	if !bytes.HasPrefix(out, pngSignature) {
+		return nil, nil
+	}
+	return out, nil
+}
+
+// ImageAvailable reports whether images can be read from the clipboard.
+func ImageAvailable() bool {
+	return provider != nil && provider.imageCmd != ""
+}
+
 // Available reports whether a clipboard tool was found on this system.
 func Available() bool {
 	return provider != nil
@@ -93,6 +132,9 @@ func OverrideProvider(writeCmd, readCmd string) {
 		p.readCmd = provider.readCmd
 		p.readArgs = provider.readArgs
 	}
+	if provider != nil {
+		p.imageCmd, p.imageArgs, p.decodeImage = provider.imageCmd, provider.imageArgs, provider.decodeImage
+	}
 	if p.cmd != "" || p.readCmd != "" {
 		provider = p
 	}
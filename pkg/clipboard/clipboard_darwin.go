@@ -2,14 +2,21 @@
 
 package clipboard
 
-import "os/exec"
+import (
+	"bytes"
+	"encoding/hex"
+	"os/exec"
+)
 
 // detect probes for pbcopy, which ships with every macOS installation.
 func detect() *clipProvider {
 	if lookPath("pbcopy") && lookPath("pbpaste") {
 		return &clipProvider{
-			cmd:     "pbcopy",
-			readCmd: "pbpaste",
+			cmd:         "pbcopy",
+			readCmd:     "pbpaste",
+			imageCmd:    "osascript",
+			imageArgs:   []string{"-e", "the clipboard as «class PNGf»"},
+			decodeImage: decodeAppleScriptData,
 		}
 	}
 	return nil
@@ -19,3 +26,19 @@ func lookPath(name string) bool {
 	_, err := exec.LookPath(name)
 	return err == nil
 }
+
+// decodeAppleScriptData turns osascript's «data PNGf89504E47...» into the
+// bytes it stands for.
+func decodeAppleScriptData(out []byte) []byte {
+	out = bytes.TrimSpace(out)
+	start := bytes.Index(out, []byte("«data PNGf"))
+	end := bytes.LastIndex(out, []byte("»"))
+	if start < 0 || end < start {
+		return nil
+	}
+	data, err := hex.DecodeString(string(out[start+len("«data PNGf") : end]))
+	if err != nil {
+		return nil
+	}
+	return data
+}
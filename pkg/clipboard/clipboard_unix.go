@@ -13,20 +13,24 @@ func detect() *clipProvider {
 	// Wayland — only if WAYLAND_DISPLAY is actually set
 	if os.Getenv("WAYLAND_DISPLAY") != "" && lookPath("wl-copy") && lookPath("wl-paste") {
 		return &clipProvider{
-			cmd:     "wl-copy",
-			readCmd: "wl-paste",
+			cmd:       "wl-copy",
+			readCmd:   "wl-paste",
+			imageCmd:  "wl-paste",
+			imageArgs: []string{"--no-newline", "--type", "image/png"},
 		}
 	}
 	// X11 via xclip
 	if lookPath("xclip") {
 		return &clipProvider{
-			cmd:      "xclip",
-			args:     []string{"-selection", "clipboard"},
-			readCmd:  "xclip",
-			readArgs: []string{"-selection", "clipboard", "-o"},
+			cmd:       "xclip",
+			args:      []string{"-selection", "clipboard"},
+			readCmd:   "xclip",
+			readArgs:  []string{"-selection", "clipboard", "-o"},
+			imageCmd:  "xclip",
+			imageArgs: []string{"-selection", "clipboard", "-t", "image/png", "-o"},
 		}
 	}
-	// X11 via xsel
+	// X11 via xsel, which cannot read images
 	if lookPath("xsel") {
 		return &clipProvider{
 			cmd:      "xsel",
@@ -14,6 +14,11 @@ func detect() *clipProvider {
 		args:     []string{"-NoProfile", "-Command", "$input | Set-Clipboard"},
 			readCmd:  "powershell",
 			readArgs: []string{"-NoProfile", "-Command", "Get-Clipboard"},
+			imageCmd: "powershell",
+			imageArgs: []string{"-NoProfile", "-STA", "-Command",
+				"Add-Type -AssemblyName System.Windows.Forms; $i = [Windows.Forms.Clipboard]::GetImage(); " +
+					"if ($i) { $m = New-Object IO.MemoryStream; $i.Save($m, [Drawing.Imaging.ImageFormat]::Png); " +
+					"$o = [Console]::OpenStandardOutput(); $o.Write($m.ToArray(), 0, $m.Length); $o.Flush() }"},
 		}
 	}
 	if lookPath("clip") {
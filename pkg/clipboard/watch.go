@@ -0,0 +1,125 @@
+package clipboard
+
+import (
+	"context"
+	"crypto/sha256"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// DefaultPollInterval is how often a Watcher reads the clipboard.
+	DefaultPollInterval = time.Second
+	// DefaultMinGap is the least time between two changes a Watcher reports.
+	DefaultMinGap = 2 * time.Second
+)
+
+// Content is what the clipboard held: text or a PNG image.
+type Content struct {
+	Text  string
+	Image []byte
+}
+
+// Size returns the size of the content in bytes.
+func (c Content) Size() int {
+	if c.Image != nil {
+		return len(c.Image)
+	}
+	return len(c.Text)
+}
+
+// WatchOptions configures a Watcher.
+type WatchOptions struct {
+	Images       bool          // report images as well as text
+	MaxSize      int64         // skip content larger than this many bytes; 0 for no limit
+	PollInterval time.Duration // DefaultPollInterval if 0
+	MinGap       time.Duration // DefaultMinGap if 0; changes in between are coalesced
+
+	readText  func() (string, error) // Read if nil
+	readImage func() ([]byte, error) // ReadImage if nil
+}
+
+// Watcher polls the clipboard and reports new content.
+type Watcher struct {
+	opts   WatchOptions
+	logger *zap.SugaredLogger
+}
+
+// NewWatcher returns a clipboard watcher.
+func NewWatcher(opts WatchOptions, logger *zap.SugaredLogger) *Watcher {
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = DefaultPollInterval
+	}
+	if opts.MinGap <= 0 {
+		opts.MinGap = DefaultMinGap
+	}
+	if opts.readText == nil {
+		opts.readText = Read
+	}
+	if opts.readImage == nil {
+		opts.readImage = ReadImage
+	}
+	if logger == nil {
+		logger = zap.NewNop().Sugar()
+	}
+	return &Watcher{opts: opts, logger: logger}
+}
+
+// Run polls the clipboard until ctx is done and calls changed with content
+// that differs from what was last seen. Whatever the clipboard holds when Run
+// starts is not reported. changed is not called more often than MinGap; the
+// latest content is reported once the gap has passed. Empty text and content
+// over MaxSize are skipped.
+func (w *Watcher) Run(ctx context.Context, changed func(Content)) {
+	_, last := w.read()
+	var lastReported time.Time
+	var waiting *Content // seen during the gap, not yet reported
+
+	ticker := time.NewTicker(w.opts.PollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		content, sum := w.read()
+		if content != nil && sum != last {
+			last = sum
+			switch {
+			case content.Image == nil && content.Text == "":
+				waiting = nil
+			case w.opts.MaxSize > 0 && int64(content.Size()) > w.opts.MaxSize:
+				w.logger.Warnf("Skipping clipboard content of %d bytes, over the %d byte limit", content.Size(), w.opts.MaxSize)
+				waiting = nil
+			default:
+				waiting = content
+			}
+		}
+		if waiting != nil && time.Since(lastReported) >= w.opts.MinGap {
+			changed(*waiting)
+			waiting = nil
+			lastReported = time.Now()
+		}
+	}
+}
+
+// read returns the clipboard content and its checksum, or nil if it could
+// not be read. An image is preferred over text when images are watched.
+func (w *Watcher) read() (*Content, [sha256.Size]byte) {
+	if w.opts.Images {
+		img, err := w.opts.readImage()
+		if err != nil {
+			w.logger.Debugf("Reading the clipboard image failed: %v", err)
+		} else if img != nil {
+			return &Content{Image: img}, sha256.Sum256(append([]byte("image:"), img...))
+		}
+	}
+	text, err := w.opts.readText()
+	if err != nil {
+		w.logger.Debugf("Reading the clipboard failed: %v", err)
+		return nil, [sha256.Size]byte{}
+	}
+	return &Content{Text: text}, sha256.Sum256([]byte("text:" + text))
+}
@@ -0,0 +1,89 @@
+package clipboard
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClipboard is a clipboard the test sets.
+type fakeClipboard struct {
+	mu    sync.Mutex
+	text  string
+	image []byte
+}
+
+func (f *fakeClipboard) set(text string, image []byte) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.text, f.image = text, image
+}
+
+func (f *fakeClipboard) readText() (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.text, nil
+}
+
+func (f *fakeClipboard) readImage() ([]byte, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.image, nil
+}
+
+func TestWatcher(t *testing.T) {
+	clip := &fakeClipboard{text: "already there"}
+	w := NewWatcher(WatchOptions{
+		Images:       true,
+		MaxSize:      10,
+		PollInterval: 5 * time.Millisecond,
+		MinGap:       100 * time.Millisecond,
+		readText:     clip.readText,
+		readImage:    clip.readImage,
+	}, nil)
+
+	var mu sync.Mutex
+	var got []Content
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		w.Run(ctx, func(c Content) {
+			mu.Lock()
+			got = append(got, c)
+			mu.Unlock()
+		})
+	}()
+
+	step := func(text string, image []byte) {
+		clip.set(text, image)
+		time.Sleep(30 * time.Millisecond)
+	}
+	time.Sleep(20 * time.Millisecond) // let Run see the starting content
+	step("first", nil)
+	step(strings.Repeat("x", 11), nil) // over the size limit
+	step("second", nil)                // within the gap after "first"
+	step("third", nil)                 // replaces "second"
+	time.Sleep(100 * time.Millisecond)
+	step("third", []byte("\x89PNG"))
+	time.Sleep(100 * time.Millisecond)
+	cancel()
+	<-done
+
+	mu.Lock()
+	defer mu.Unlock()
+	var seen []string
+	for _, c := range got {
+		if c.Image != nil {
+			seen = append(seen, "image")
+		} else {
+			seen = append(seen, c.Text)
+		}
+	}
+	want := []string{"first", "third", "image"}
+	if strings.Join(seen, ",") != strings.Join(want, ",") {
+		t.Errorf("reported %v, want %v", seen, want)
+	}
+}
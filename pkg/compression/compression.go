@@ -0,0 +1,177 @@
+// Package compression decides which files are worth compressing in transit
+// and provides the HTTP content codings LocalGo speaks.
+//
+// A receiver advertises the codings it decodes with an Accept-Encoding
+// header on its prepare-upload response (RFC 7694); a sender compresses an
+// upload only when the receiver advertised a coding and the file matches its
+// Policy. Receivers that do not advertise, such as the LocalSend apps, get
+// files as they are.
+package compression
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Gzip is the only coding LocalGo compresses with. zstd would compress
+// faster but needs a dependency outside the standard library.
+const Gzip = "gzip"
+
+// DefaultMinSize is the smallest file compressed by default; below it the
+// saving does not pay for the work.
+const DefaultMinSize = 16 * 1024
+
+// DefaultTypes are the file types compressed by default: text and the
+// structured formats stored as text.
+var DefaultTypes = []string{
+	"text/*",
+	"application/json",
+	"application/xml",
+	"application/javascript",
+	"image/svg+xml",
+	".log", ".csv", ".tsv", ".md", ".json", ".xml", ".yaml", ".yml", ".sql", ".svg",
+}
+
+// ErrUnsupported is returned for a content coding LocalGo cannot decode.
+var ErrUnsupported = errors.New("unsupported content encoding")
+
+// ErrCorrupt is returned when compressed data cannot be decoded.
+var ErrCorrupt = errors.New("corrupt compressed data")
+
+// Policy decides which files are compressed.
+type Policy struct {
+	MinSize int64
+	// Types are MIME types, where "text/*" matches a whole family, and file
+	// extensions such as ".log". A file matching any of them qualifies.
+	Types []string
+}
+
+// Compressible reports whether a file with this name, MIME type and size
+// should be compressed.
+func (p Policy) Compressible(name, mimeType string, size int64) bool {
+	if size < p.MinSize {
+		return false
+	}
+	mimeType, _, _ = strings.Cut(mimeType, ";")
+	mimeType = strings.ToLower(strings.TrimSpace(mimeType))
+	ext := strings.ToLower(filepath.Ext(name))
+	for _, t := range p.Types {
+		t = strings.ToLower(strings.TrimSpace(t))
+		switch {
+		case t == "":
+		case strings.HasPrefix(t, "."):
+			if ext == t {
+				return true
+			}
+		case strings.HasSuffix(t, "/*"):
+			if strings.HasPrefix(mimeType, strings.TrimSuffix(t, "*")) {
+				return true
+			}
+		case mimeType == t:
+			return true
+		}
+	}
+	return false
+}
+
+// Negotiate returns the coding to use for a peer that sent acceptEncoding,
+// or "" if it accepts none LocalGo supports.
+func Negotiate(acceptEncoding string) string {
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		coding, params, _ := strings.Cut(part, ";")
+		coding = strings.ToLower(strings.TrimSpace(coding))
+		if coding != Gzip && coding != "*" {
+			continue
+		}
+		if q, ok := strings.CutPrefix(strings.ReplaceAll(params, " ", ""), "q="); ok {
+			if v, err := strconv.ParseFloat(q, 64); err == nil && v == 0 {
+				continue
+			}
+		}
+		return Gzip
+	}
+	return ""
+}
+
+// Identity reports whether coding leaves data as it is: it is empty or
+// "identity".
+func Identity(coding string) bool {
+	coding = strings.TrimSpace(coding)
+	return coding == "" || strings.EqualFold(coding, "identity")
+}
+
+// Supported reports whether coding can be decoded. The identity coding is
+// always supported.
+func Supported(coding string) bool {
+	return Identity(coding) || strings.EqualFold(strings.TrimSpace(coding), Gzip)
+}
+
+// NewWriter returns a writer that compresses to w with coding. Close flushes
+// it; it does not close w.
+func NewWriter(w io.Writer, coding string) (io.WriteCloser, error) {
+	if !strings.EqualFold(coding, Gzip) {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupported, coding)
+	}
+	// Favour speed: on a local network the link is rarely the bottleneck
+	// by much, and slower levels gain little on text.
+	return gzip.NewWriterLevel(w, gzip.BestSpeed)
+}
+
+// NewReader returns a reader that decodes r, compressed with coding. The
+// identity coding, or none, returns r as it is. Data that fails to decode
+// is reported with ErrCorrupt.
+func NewReader(r io.Reader, coding string) (io.ReadCloser, error) {
+	switch {
+	case Identity(coding):
+		return io.NopCloser(r), nil
+	case strings.EqualFold(strings.TrimSpace(coding), Gzip):
+		return &gzipReader{src: r}, nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupported, coding)
+	}
+}
+
+// gzipReader decodes gzip, reading the header on the first Read so that
+// creating it never blocks on the network.
+type gzipReader struct {
+	src io.Reader
+	zr  *gzip.Reader
+}
+
+func (g *gzipReader) Read(p []byte) (int, error) {
+	if g.zr == nil {
+		zr, err := gzip.NewReader(g.src)
+		if err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return 0, corrupt(err)
+		}
+		g.zr = zr
+	}
+	n, err := g.zr.Read(p)
+	return n, corrupt(err)
+}
+
+func (g *gzipReader) Close() error {
+	if g.zr == nil {
+		return nil
+	}
+	return g.zr.Close()
+}
+
+// corrupt marks decoding errors with ErrCorrupt, leaving errors from the
+// underlying reader as they are.
+func corrupt(err error) error {
+	var flateErr flate.CorruptInputError
+	if errors.Is(err, gzip.ErrHeader) || errors.Is(err, gzip.ErrChecksum) || errors.As(err, &flateErr) {
+		return fmt.Errorf("%w: %v", ErrCorrupt, err)
+	}
+	return err
+}
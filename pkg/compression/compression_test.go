@@ -0,0 +1,90 @@
+package compression
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestPolicy_Compressible(t *testing.T) {
+	p := Policy{MinSize: 100, Types: []string{"text/*", "application/json", ".log"}}
+	tests := []struct {
+		name, mimeType string
+		size           int64
+		want           bool
+	}{
+		{"notes.txt", "text/plain; charset=utf-8", 1000, true},
+		{"data.json", "application/json", 1000, true},
+		{"server.log", "application/octet-stream", 1000, true},
+		{"SERVER.LOG", "", 1000, true},
+		{"photo.jpg", "image/jpeg", 1000, false},
+		{"textfile", "textual/thing", 1000, false},
+		{"notes.txt", "text/plain", 99, false},
+	}
+	for _, tt := range tests {
+		if got := p.Compressible(tt.name, tt.mimeType, tt.size); got != tt.want {
+			t.Errorf("Compressible(%q, %q, %d) = %v, want %v", tt.name, tt.mimeType, tt.size, got, tt.want)
+		}
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	tests := map[string]string{
+		"":                   "",
+		"gzip":               "gzip",
+		"br, gzip;q=0.8":     "gzip",
+		"GZIP":               "gzip",
+		"*":                  "gzip",
+		"gzip;q=0, identity": "",
+		"br, deflate":        "",
+	}
+	for header, want := range tests {
+		if got := Negotiate(header); got != want {
+			t.Errorf("Negotiate(%q) = %q, want %q", header, got, want)
+		}
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	content := strings.Repeat("hello compression\n", 1000)
+	var buf bytes.Buffer
+	zw, err := NewWriter(&buf, Gzip)
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.WriteString(zw, content)
+	zw.Close()
+	if buf.Len() >= len(content) {
+		t.Errorf("compressed %d bytes to %d", len(content), buf.Len())
+	}
+	compressed := buf.Bytes()
+
+	zr, err := NewReader(bytes.NewReader(compressed), "gzip")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := io.ReadAll(zr); err != nil || string(got) != content {
+		t.Errorf("round trip: err %v, %d bytes", err, len(got))
+	}
+
+	corrupt := bytes.Clone(compressed)
+	corrupt[len(corrupt)/2] ^= 0xff
+	zr, _ = NewReader(bytes.NewReader(corrupt), "gzip")
+	if _, err := io.ReadAll(zr); !errors.Is(err, ErrCorrupt) {
+		t.Errorf("corrupt data: got %v, want ErrCorrupt", err)
+	}
+	zr, _ = NewReader(strings.NewReader("not gzip at all"), "gzip")
+	if _, err := io.ReadAll(zr); !errors.Is(err, ErrCorrupt) {
+		t.Errorf("not gzip: got %v, want ErrCorrupt", err)
+	}
+
+	if _, err := NewReader(strings.NewReader(content), "br"); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("br: got %v, want ErrUnsupported", err)
+	}
+	zr, _ = NewReader(strings.NewReader(content), "identity")
+	if got, _ := io.ReadAll(zr); string(got) != content {
+		t.Error("identity changed the data")
+	}
+}
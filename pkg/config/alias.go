@@ -0,0 +1,60 @@
+package config
+
+import (
+	mathrand "math/rand/v2"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// AliasFile is the file in the security directory that keeps the generated
+// alias, so the device keeps its name along with its identity.
+const AliasFile = "alias"
+
+// Word lists for generated aliases, the same ones the LocalSend app uses.
+var (
+	aliasAdjectives = []string{
+		"Adorable", "Beautiful", "Big", "Bright", "Clean", "Clever", "Cool", "Cute",
+		"Cunning", "Determined", "Energetic", "Efficient", "Fantastic", "Fast", "Fine", "Fresh",
+		"Good", "Gorgeous", "Great", "Handsome", "Hot", "Kind", "Lovely", "Mystic",
+		"Neat", "Nice", "Patient", "Pretty", "Powerful", "Rich", "Secret", "Smart",
+		"Solid", "Special", "Strategic", "Strong", "Tidy", "Wise",
+	}
+	aliasFruits = []string{
+		"Apple", "Avocado", "Banana", "Blackberry", "Blueberry", "Broccoli", "Carrot", "Cherry",
+		"Coconut", "Grape", "Lemon", "Lettuce", "Mango", "Melon", "Mushroom", "Onion",
+		"Orange", "Papaya", "Peach", "Pear", "Pineapple", "Potato", "Pumpkin", "Raspberry",
+		"Strawberry", "Tomato",
+	}
+)
+
+// GenerateAlias returns a random "Adjective Fruit" alias such as "Clever Mango".
+func GenerateAlias() string {
+	return aliasAdjectives[mathrand.IntN(len(aliasAdjectives))] + " " + aliasFruits[mathrand.IntN(len(aliasFruits))]
+}
+
+// loadOrCreateAlias returns the alias saved in dir, generating and saving one
+// on first use. If it cannot be saved, the generated alias is used for this
+// run only.
+func loadOrCreateAlias(dir string, logger *zap.SugaredLogger) string {
+	path := filepath.Join(dir, AliasFile)
+	if data, err := os.ReadFile(path); err == nil {
+		if alias := strings.TrimSpace(string(data)); alias != "" {
+			return alias
+		}
+	}
+
+	alias := GenerateAlias()
+	err := os.MkdirAll(dir, 0700)
+	if err == nil {
+		err = os.WriteFile(path, []byte(alias+"\n"), 0600)
+	}
+	if err != nil {
+		logger.Warnf("Could not save generated alias to %s: %v", path, err)
+	} else {
+		logger.Infof("No alias configured, using %q", alias)
+	}
+	return alias
+}
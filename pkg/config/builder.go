@@ -0,0 +1,276 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/bethropolis/localgo/pkg/compression"
+	"github.com/bethropolis/localgo/pkg/crypto"
+	"github.com/bethropolis/localgo/pkg/model"
+	"go.uber.org/zap"
+)
+
+// Builder constructs a Config in code, for applications embedding LocalGo
+// that do not read LOCALSEND_* variables or a config file. It starts from
+// the same defaults as LoadConfig:
+//
+//	cfg, err := config.New().
+//		Alias("Kiosk").
+//		DownloadDir("/srv/inbox").
+//		AutoAccept(true).
+//		Build()
+type Builder struct {
+	cfg         Config
+	securityDir string
+	identity    crypto.IdentityProvider
+	logger      *zap.SugaredLogger
+}
+
+// New returns a builder holding the default configuration.
+func New() *Builder {
+	deviceModel := "GoDevice"
+	return &Builder{
+		cfg: Config{
+			Alias:           GenerateAlias(),
+			Port:            DefaultPort,
+			HttpsEnabled:    true,
+			MulticastGroup:  DefaultMulticastGroup,
+			DeviceModel:     &deviceModel,
+			DeviceType:      model.DeviceTypeDesktop,
+			DownloadDir:     defaultDownloadDir(),
+			RateLimit:       DefaultRateLimit,
+			QueueWorkers:    DefaultQueueWorkers,
+			CopyBufferSize:  DefaultCopyBufferSize,
+			CompressMinSize: compression.DefaultMinSize,
+			CompressTypes:   compression.DefaultTypes,
+			MQTTTopic:       DefaultMQTTTopic,
+			AccessLogFormat: AccessLogCommon,
+		},
+		logger: zap.NewNop().Sugar(),
+	}
+}
+
+// Alias sets the device name shown to others.
+func (b *Builder) Alias(alias string) *Builder {
+	b.cfg.Alias = alias
+	return b
+}
+
+// Port sets the server port; 0 picks any free port.
+func (b *Builder) Port(port int) *Builder {
+	b.cfg.Port = port
+	return b
+}
+
+// HTTPS sets whether the server uses HTTPS, as LocalSend does by default.
+func (b *Builder) HTTPS(enabled bool) *Builder {
+	b.cfg.HttpsEnabled = enabled
+	return b
+}
+
+// Multicast sets the multicast group discovery uses and the interface it
+// is joined on; "" joins on every interface.
+func (b *Builder) Multicast(group, iface string) *Builder {
+	b.cfg.MulticastGroup = group
+	b.cfg.MulticastInterface = iface
+	return b
+}
+
+// Device sets the device type and model shown to others.
+func (b *Builder) Device(deviceType model.DeviceType, deviceModel string) *Builder {
+	b.cfg.DeviceType = deviceType
+	b.cfg.DeviceModel = &deviceModel
+	return b
+}
+
+// DownloadDir sets the directory received files are saved to.
+func (b *Builder) DownloadDir(dir string) *Builder {
+	b.cfg.DownloadDir = dir
+	return b
+}
+
+// AutoAccept sets whether transfers are accepted without prompting.
+func (b *Builder) AutoAccept(accept bool) *Builder {
+	b.cfg.AutoAccept = accept
+	return b
+}
+
+// PIN sets the PIN senders must give; "" requires none.
+func (b *Builder) PIN(pin string) *Builder {
+	b.cfg.PIN = pin
+	return b
+}
+
+// MaxBodySize sets the largest file accepted, in bytes; 0 means no limit.
+func (b *Builder) MaxBodySize(size int64) *Builder {
+	b.cfg.MaxBodySize = size
+	return b
+}
+
+// RateLimit sets the API requests allowed per second per IP; 0 means no
+// limit.
+func (b *Builder) RateLimit(perSecond int) *Builder {
+	b.cfg.RateLimit = perSecond
+	return b
+}
+
+// QueueWorkers sets how many queued sends run at the same time.
+func (b *Builder) QueueWorkers(n int) *Builder {
+	b.cfg.QueueWorkers = n
+	return b
+}
+
+// Headless selects the profile for running without a user at the machine;
+// see Config.ApplyHeadless.
+func (b *Builder) Headless() *Builder {
+	b.cfg.ApplyHeadless()
+	return b
+}
+
+// SecurityDir keeps the TLS identity and admin token in dir, loading the
+// identity stored there or creating one. Without it, Build generates an
+// identity that lasts only as long as the process.
+func (b *Builder) SecurityDir(dir string) *Builder {
+	b.securityDir = dir
+	return b
+}
+
+// SecurityContext sets the TLS identity, in place of one from SecurityDir
+// or a generated one.
+func (b *Builder) SecurityContext(sc *crypto.StoredSecurityContext) *Builder {
+	b.cfg.SecurityContext = sc
+	return b
+}
+
+// Identity makes p the source of the TLS identity, as Config.UseIdentity
+// does, in place of a stored or generated one.
+func (b *Builder) Identity(p crypto.IdentityProvider) *Builder {
+	b.identity = p
+	return b
+}
+
+// Logger sets where Build logs, such as when it generates an identity.
+func (b *Builder) Logger(logger *zap.SugaredLogger) *Builder {
+	if logger != nil {
+		b.logger = logger
+	}
+	return b
+}
+
+// Apply calls fn with the configuration being built, for the fields without
+// a setter of their own.
+func (b *Builder) Apply(fn func(*Config)) *Builder {
+	fn(&b.cfg)
+	return b
+}
+
+// Build validates the configuration and returns it, with a TLS identity
+// loaded or generated if no Identity or SecurityContext was set. The error lists every problem found,
+// as Config.Validate does. Each call returns a new Config.
+func (b *Builder) Build() (*Config, error) {
+	cfg := b.cfg
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	if b.securityDir != "" {
+		cfg.SecurityPath = filepath.Join(b.securityDir, DefaultSecurityFile)
+		cfg.AdminTokenPath = filepath.Join(b.securityDir, AdminTokenFile)
+	}
+	if b.identity != nil {
+		if err := cfg.UseIdentity(b.identity); err != nil {
+			return nil, err
+		}
+	} else if cfg.SecurityContext == nil {
+		var err error
+		if cfg.SecurityPath != "" {
+			cfg.SecurityContext, err = crypto.LoadOrCreateSecurityContext(cfg.SecurityPath, cfg.Alias, b.logger)
+		} else {
+			cfg.SecurityContext, err = crypto.GenerateSecurityContext(cfg.Alias, b.logger)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if cfg.RandomFingerprint == "" {
+		cfg.RandomFingerprint = generateRandomID(64)
+	}
+	return &cfg, nil
+}
+
+// Validate checks c and returns every problem found, joined into one error,
+// or nil. It checks values the way LoadConfig and `localgo config set` do,
+// so a Config built in code is held to the same rules as one loaded from
+// the environment.
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(field string, err error) {
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", field, err))
+		}
+	}
+	atLeast := func(field string, n, min int64) {
+		if n < min {
+			check(field, fmt.Errorf("%d must be at least %d", n, min))
+		}
+	}
+	oneOf := func(field, value string, parse func(string) (string, error)) {
+		if parsed, err := parse(value); err != nil {
+			check(field, err)
+		} else if value != parsed {
+			check(field, fmt.Errorf("%q is not in canonical form, use %q", value, parsed))
+		}
+	}
+
+	check("alias", nonEmpty(c.Alias))
+	if c.Port < 0 || c.Port > 65535 {
+		check("port", fmt.Errorf("%d is out of range 0-65535", c.Port))
+	}
+	check("multicast_group", multicastAddr(c.MulticastGroup))
+	check("device_type", deviceType(string(c.DeviceType)))
+	check("download_dir", CheckWritableDir(c.DownloadDir))
+
+	atLeast("max_body_size", c.MaxBodySize, 0)
+	atLeast("rate_limit", int64(c.RateLimit), 0)
+	atLeast("concurrency", int64(c.Concurrency), 0)
+	atLeast("queue_workers", int64(c.QueueWorkers), 1)
+	atLeast("max_conns_per_host", int64(c.MaxConnsPerHost), 0)
+	atLeast("max_session_uploads", int64(c.MaxSessionUploads), 0)
+	atLeast("compress_min_size", c.CompressMinSize, 0)
+	if c.CopyBufferSize < minCopyBufferSize || c.CopyBufferSize > maxCopyBufferSize {
+		check("copy_buffer_size", fmt.Errorf("%d is out of range %d-%d bytes", c.CopyBufferSize, minCopyBufferSize, maxCopyBufferSize))
+	}
+	for _, d := range []struct {
+		field string
+		value time.Duration
+	}{
+		{"connect_timeout", c.ConnectTimeout},
+		{"drain_timeout", c.DrainTimeout},
+		{"session_wait", c.SessionWait},
+	} {
+		if d.value < 0 {
+			check(d.field, fmt.Errorf("%s must not be negative", d.value))
+		}
+	}
+	if c.SessionWait > MaxSessionWait {
+		check("session_wait", fmt.Errorf("%s is longer than %s", c.SessionWait, MaxSessionWait))
+	}
+
+	check("proxy", proxySetting(c.Proxy))
+	check("relay", relaySetting(c.Relay))
+	check("relay_fingerprint", fingerprintSetting(c.RelayFingerprint))
+	check("mqtt_broker", mqttBroker(c.MQTTBroker))
+	check("mqtt_topic", mqttTopic(c.MQTTTopic))
+	check("control_grpc", controlGRPC(c.ControlGRPC))
+	check("encrypt_to", recipientKey(c.EncryptTo))
+	oneOf("open", c.OpenMode, ParseOpenMode)
+	oneOf("manifest", c.Manifest, ParseManifest)
+	oneOf("access_log_format", c.AccessLogFormat, ParseAccessLogFormat)
+
+	if (c.CustomTLSCertPath == "") != (c.CustomTLSKeyPath == "") {
+		errs = append(errs, errors.New("tls_cert and tls_key must be set together"))
+	}
+	return errors.Join(errs...)
+}
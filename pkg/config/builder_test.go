@@ -0,0 +1,96 @@
+package config
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bethropolis/localgo/pkg/crypto"
+	"github.com/bethropolis/localgo/pkg/model"
+)
+
+func TestBuilder_Build(t *testing.T) {
+	securityDir := t.TempDir()
+	cfg, err := New().
+		Alias("Kiosk").
+		Port(0).
+		Device(model.DeviceTypeServer, "Rack").
+		DownloadDir(t.TempDir()).
+		AutoAccept(true).
+		SecurityDir(securityDir).
+		Apply(func(c *Config) { c.Manifest = ManifestJSON }).
+		Build()
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	if cfg.Alias != "Kiosk" || cfg.Port != 0 || cfg.DeviceType != model.DeviceTypeServer || *cfg.DeviceModel != "Rack" || !cfg.AutoAccept || cfg.Manifest != ManifestJSON {
+		t.Errorf("setters not applied: %+v", cfg)
+	}
+	if !cfg.HttpsEnabled || cfg.MulticastGroup != DefaultMulticastGroup || cfg.QueueWorkers != DefaultQueueWorkers {
+		t.Errorf("defaults not kept: %+v", cfg)
+	}
+	if cfg.SecurityContext == nil || cfg.GetFingerprint() == "" {
+		t.Fatal("no TLS identity")
+	}
+	if cfg.AdminTokenPath != filepath.Join(securityDir, AdminTokenFile) {
+		t.Errorf("AdminTokenPath = %q", cfg.AdminTokenPath)
+	}
+
+	// The identity stored in the security directory is reused.
+	again, err := New().DownloadDir(t.TempDir()).SecurityDir(securityDir).Build()
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	if again.GetFingerprint() != cfg.GetFingerprint() {
+		t.Error("identity not loaded from the security directory")
+	}
+}
+
+func TestConfig_Validate_ReportsEveryProblem(t *testing.T) {
+	_, err := New().
+		Alias(" ").
+		Port(70000).
+		Multicast("10.0.0.1", "").
+		DownloadDir(t.TempDir()).
+		QueueWorkers(0).
+		Apply(func(c *Config) { c.OpenMode = "sideways" }).
+		Build()
+	if err == nil {
+		t.Fatal("Build accepted an invalid config")
+	}
+	for _, field := range []string{"alias", "port", "multicast_group", "queue_workers", "open"} {
+		if !strings.Contains(err.Error(), field+":") {
+			t.Errorf("error does not mention %s:\n%v", field, err)
+		}
+	}
+	if n := strings.Count(err.Error(), "\n") + 1; n != 5 {
+		t.Errorf("%d problems reported, want 5:\n%v", n, err)
+	}
+
+	if err := (&Config{}).Validate(); err == nil {
+		t.Error("zero Config passed validation")
+	}
+}
+
+func TestBuilder_Identity(t *testing.T) {
+	managed, err := crypto.GenerateSecurityContext("managed", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Only the provider is passed, as for a key that cannot be exported.
+	provider := struct{ crypto.IdentityProvider }{managed}
+
+	cfg, err := New().DownloadDir(t.TempDir()).Identity(provider).Build()
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	if cfg.GetFingerprint() != managed.CertificateHash {
+		t.Errorf("fingerprint %s, want the managed certificate's", cfg.GetFingerprint())
+	}
+	if _, err := cfg.TLSCertificate(); err != nil {
+		t.Errorf("TLSCertificate: %v", err)
+	}
+	if cfg.SecurityContext.PrivateKey != "" {
+		t.Error("private key copied out of the provider")
+	}
+}
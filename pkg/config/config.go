@@ -2,14 +2,20 @@ package config
 
 import (
 	"crypto/rand"
+	"crypto/tls"
 	"fmt"
+	"net"
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
+	"time"
 
 	mathrand "math/rand/v2"
 
+	"github.com/bethropolis/localgo/pkg/compression"
 	"github.com/bethropolis/localgo/pkg/crypto"
+	"github.com/bethropolis/localgo/pkg/httputil"
 	"github.com/bethropolis/localgo/pkg/model"
 	"github.com/spf13/viper"
 	"go.uber.org/zap"
@@ -21,8 +27,91 @@ const (
 	ProtocolVersion       = "2.0"
 	DefaultSecurityDir    = ".localgo_security"
 	DefaultSecurityFile   = "context.json"
+	DefaultRateLimit      = 20 // control requests per second per IP
+	DefaultQueueWorkers   = 1  // queued sends run at the same time
+
+	// DefaultCopyBufferSize is how much of a received file is buffered
+	// before it is written to disk.
+	DefaultCopyBufferSize = 1024 * 1024
+	minCopyBufferSize     = 4 * 1024
+	maxCopyBufferSize     = 64 * 1024 * 1024
+
+	// DefaultHeadlessDrainTimeout leaves time to shut down within the 10s
+	// Docker allows between SIGTERM and SIGKILL.
+	DefaultHeadlessDrainTimeout = 8 * time.Second
+
+	// MaxSessionWait bounds SessionWait. A waiting prepare-upload also gives
+	// up when its request deadline nears, however long the accept prompt
+	// took of it, and is then refused as blocked by another session.
+	MaxSessionWait = time.Minute
+
+	// DefaultMQTTTopic prefixes the topics events are published to.
+	DefaultMQTTTopic = "localgo"
 )
 
+// Values for Config.OpenMode.
+const (
+	OpenModeDir    = "dir"    // download directory, once the session ends
+	OpenModeFile   = "file"   // each received file
+	OpenModeFolder = "folder" // folder containing each received file
+)
+
+// ParseOpenMode validates an open mode from a flag or config value. "true"
+// and "1" are accepted as OpenModeDir; "", "false" and "0" disable opening.
+func ParseOpenMode(s string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "false", "0":
+		return "", nil
+	case "true", "1", OpenModeDir:
+		return OpenModeDir, nil
+	case OpenModeFile:
+		return OpenModeFile, nil
+	case OpenModeFolder:
+		return OpenModeFolder, nil
+	default:
+		return "", fmt.Errorf("invalid open mode %q: use dir, file, or folder", s)
+	}
+}
+
+// Values for Config.Manifest.
+const (
+	ManifestJSON   = "json"   // files, sizes, hashes, sender and times as JSON
+	ManifestSHA256 = "sha256" // hashes in the format of sha256sum, for sha256sum -c
+)
+
+// ParseManifest validates a manifest format; "", "false" and "0" turn
+// manifests off.
+func ParseManifest(s string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "false", "0":
+		return "", nil
+	case ManifestJSON:
+		return ManifestJSON, nil
+	case ManifestSHA256:
+		return ManifestSHA256, nil
+	default:
+		return "", fmt.Errorf("invalid manifest format %q: use json or sha256", s)
+	}
+}
+
+// Values for Config.AccessLogFormat.
+const (
+	AccessLogCommon = "common" // Common Log Format, one line per request
+	AccessLogJSON   = "json"   // one JSON object per request
+)
+
+// ParseAccessLogFormat validates an access log format; "" means AccessLogCommon.
+func ParseAccessLogFormat(s string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", AccessLogCommon:
+		return AccessLogCommon, nil
+	case AccessLogJSON:
+		return AccessLogJSON, nil
+	default:
+		return "", fmt.Errorf("invalid access log format %q: use common or json", s)
+	}
+}
+
 type Config struct {
 	Alias             string                        `json:"alias"`
 	Port              int                           `json:"port"`
@@ -31,20 +120,65 @@ type Config struct {
 	DeviceModel       *string                       `json:"deviceModel"`
 	DeviceType        model.DeviceType              `json:"deviceType"`
 	SecurityContext   *crypto.StoredSecurityContext `json:"-"`
+	Identity          crypto.IdentityProvider       `json:"-"` // supplies the TLS certificate in place of SecurityContext; see UseIdentity
+	AdminTokenPath    string                        `json:"-"` // file holding the bearer token the admin API requires
+	AdminSocket       string                        `json:"-"` // unix socket the admin API is also served on ("" = off)
+	ControlGRPC       string                        `json:"-"` // unix:PATH or loopback HOST:PORT of the gRPC control interface ("" = off)
+	DBus              bool                          `json:"-"` // publish the D-Bus interface on the session bus (Linux)
+	MQTTBroker        string                        `json:"-"` // URL of the broker events are published to ("" = off)
+	MQTTTopic         string                        `json:"-"` // prefix of the topics events are published to
+	MQTTUsername      string                        `json:"-"`
+	MQTTPassword      string                        `json:"-"`
+	MQTTCAFile        string                        `json:"-"` // CA certificate the broker's is checked against, for TLS
 	SecurityPath      string                        `json:"-"`
 	PIN               string                        `json:"-"`
 	DownloadDir       string                        `json:"-"`
 	AutoAccept        bool                          `json:"-"`
 	RandomFingerprint string                        `json:"-"`
-	MaxBodySize       int64                         `json:"-"`
+	MaxBodySize       int64                         `json:"-"` // largest accepted file in bytes (0 = unlimited)
+	RateLimit         int                           `json:"-"` // control requests per second per IP (0 = unlimited)
 	NoClipboard       bool                          `json:"-"` // skip clipboard; save text as a file instead
+	StrictProtocol    bool                          `json:"-"` // answer API errors exactly as the LocalSend protocol documents
+	PrintMessages     bool                          `json:"-"` // print incoming text messages instead of saving or copying them
+	SendPreviews      bool                          `json:"-"` // offer image thumbnails to receivers when sending
 	HistoryFile       string                        `json:"-"` // path to transfer history jsonl file
+	SessionFile       string                        `json:"-"` // path to saved receive sessions; "off" disables
+	AccessLog         string                        `json:"-"` // path to HTTP access log ("-" = stderr, "" = off)
+	AccessLogFormat   string                        `json:"-"` // AccessLogCommon or AccessLogJSON
 	Quiet             bool                          `json:"-"` // quiet mode - minimal output
 	ExecHook          string                        `json:"-"` // shell command to run after receiving file
-	OpenDir           bool                          `json:"-"` // open download directory after transfer
+	ScanCmd           string                        `json:"-"` // shell command that checks each received file before it is kept
+	QuarantineDir     string                        `json:"-"` // where files failing ScanCmd are moved ("" = .quarantine in DownloadDir)
+	EncryptTo         string                        `json:"-"` // recipient key received files are encrypted to at rest ("" = off)
+	Dedupe            bool                          `json:"-"` // hard-link received files identical to ones already received
+	OpenMode          string                        `json:"-"` // what to open after receiving: "", "dir", "file" or "folder"
+	Manifest          string                        `json:"-"` // manifest written for each receive session: "", ManifestJSON or ManifestSHA256
 	Concurrency       int                           `json:"-"` // max parallel uploads (0 = use default)
+	QueueWorkers      int                           `json:"-"` // queued sends run at the same time by serve and receive
+	CopyBufferSize    int64                         `json:"-"` // bytes of a received file buffered before writing to disk
+	Fsync             bool                          `json:"-"` // flush each received file to disk before reporting it done
+	ConnectTimeout    time.Duration                 `json:"-"` // how long connecting to a peer may take (0 = default)
+	MaxConnsPerHost   int                           `json:"-"` // connections open to one peer at a time (0 = unlimited)
+	Proxy             string                        `json:"-"` // proxy for requests to peers: "" = from the environment, "none", or a URL
+	Relay             string                        `json:"-"` // URL of the relay the server registers with and send --relay goes through ("" = none)
+	RelayFingerprint  string                        `json:"-"` // fingerprint the relay's certificate must have ("" = not checked)
+	MaxSessionUploads int                           `json:"-"` // files of one receive session uploaded at a time (0 = unlimited)
+	Compress          bool                          `json:"-"` // compress uploads and downloads of matching files for peers that accept it
+	CompressMinSize   int64                         `json:"-"` // smallest file compressed
+	CompressTypes     []string                      `json:"-"` // MIME types ("text/*") and extensions (".log") compressed
 	MulticastInterface string                        `json:"-"` // multicast network interface name
+	SourceIP          net.IP                        `json:"-"` // local address sends connect and scan from (nil = any)
 	Private           bool                          `json:"-"` // anonymize device identities
+	Headless          bool                          `json:"-"` // no user at the machine; see ApplyHeadless
+	DrainTimeout      time.Duration                 `json:"-"` // how long shutdown waits for active transfers
+	SessionWait       time.Duration                 `json:"-"` // how long a transfer waits for another session to end (0 = refuse it)
+
+	TrustedFingerprints []string                    `json:"-"` // sender fingerprints treated as trusted by accept rules
+	AcceptRules         []AcceptRule                `json:"-"` // ordered rules deciding how incoming transfers are handled
+	Favorites           []string                    `json:"-"` // fingerprints of devices listed first by `localgo devices`
+	FavoriteMACs        map[string]net.HardwareAddr `json:"-"` // MAC addresses of favorites, by fingerprint, for send --wake
+	AllowedSenders      []string                    `json:"-"` // if set, only these senders may start a transfer: aliases, or fingerprint prefixes the sender must prove
+	TargetRoots         []string                    `json:"-"` // folders in DownloadDir senders may name as a target path
 
 	Shell             string `json:"-"` // shell command prefix for exec hooks (default: "sh -c" or "cmd /c")
 	ClipboardWriteCmd string `json:"-"` // custom clipboard write command
@@ -55,22 +189,66 @@ type Config struct {
 	customFingerprint string `json:"-"` // fingerprint computed from custom TLS cert
 }
 
+// ApplyHeadless switches to the headless profile for running without a user
+// at the machine, such as in a container: quiet output, no clipboard, nothing
+// opened, and active transfers drained on shutdown.
+func (c *Config) ApplyHeadless() {
+	c.Headless = true
+	c.Quiet = true
+	c.NoClipboard = true
+	c.PrintMessages = false
+	c.OpenMode = ""
+	if c.DrainTimeout == 0 {
+		c.DrainTimeout = DefaultHeadlessDrainTimeout
+	}
+}
+
 // SetCustomFingerprint overrides the advertised fingerprint with one computed
 // from a user-supplied TLS certificate.
 func (c *Config) SetCustomFingerprint(fp string) {
 	c.customFingerprint = fp
 }
 
+// TLSCertificate returns the device's TLS certificate: the one of Identity
+// if set, else the custom one if tls_cert and tls_key are set, else the one
+// of the security context. A Config is thus an IdentityProvider itself.
+func (c *Config) TLSCertificate() (tls.Certificate, error) {
+	switch {
+	case c.Identity != nil:
+		return c.Identity.TLSCertificate()
+	case c.CustomTLSCertPath != "" && c.CustomTLSKeyPath != "":
+		return crypto.PEMFiles(c.CustomTLSCertPath, c.CustomTLSKeyPath).TLSCertificate()
+	}
+	return c.SecurityContext.TLSCertificate()
+}
+
+// UseIdentity makes p the source of the device's TLS certificate, such as
+// a certificate managed by a company CA or a key held in an HSM. It loads
+// the certificate once to replace SecurityContext with its description, so
+// the fingerprint advertised is that of p's certificate.
+func (c *Config) UseIdentity(p crypto.IdentityProvider) error {
+	sc, err := crypto.DescribeIdentity(p)
+	if err != nil {
+		return fmt.Errorf("failed to load identity: %w", err)
+	}
+	c.Identity = p
+	c.SecurityContext = sc
+	return nil
+}
+
 // getSecurityDir determines the best location for the security directory
-func getSecurityDir(v *viper.Viper) string {
+func getSecurityDir(v *viper.Viper, logger *zap.SugaredLogger) string {
 	if envDir := v.GetString("security_dir"); envDir != "" {
-		zap.S().Infof("Using security directory: %s", envDir)
+		logger.Infof("Using security directory: %s", envDir)
 		return envDir
 	}
+	if dir := os.Getenv(ConfigDirEnv); dir != "" {
+		return filepath.Join(dir, ".security")
+	}
 
 	configDir, err := os.UserConfigDir()
 	if err != nil {
-		zap.S().Warnf("Could not determine config directory: %v; falling back to current directory", err)
+		logger.Warnf("Could not determine config directory: %v; falling back to current directory", err)
 		return DefaultSecurityDir
 	}
 
@@ -108,15 +286,15 @@ func LoadConfig(v *viper.Viper, logger *zap.SugaredLogger) (*Config, error) {
 	if v == nil {
 		v = InitViper()
 	}
+	// Use the new security directory resolution
+	securityDirPath := getSecurityDir(v, logger)
+	securityFilePath := filepath.Join(securityDirPath, DefaultSecurityFile)
+
 	alias := v.GetString("alias")
 	if alias == "" {
-		alias = generateDefaultAlias()
+		alias = loadOrCreateAlias(securityDirPath, logger)
 	}
 
-	// Use the new security directory resolution
-	securityDirPath := getSecurityDir(v)
-	securityFilePath := filepath.Join(securityDirPath, DefaultSecurityFile)
-
 	portStr := v.GetString("port")
 	port := DefaultPort
 	if p, err := strconv.Atoi(portStr); err == nil {
@@ -130,11 +308,7 @@ func LoadConfig(v *viper.Viper, logger *zap.SugaredLogger) (*Config, error) {
 
 	downloadDir := v.GetString("download_dir")
 	if downloadDir == "" {
-		home, err := os.UserHomeDir()
-		if err != nil {
-			home = "."
-		}
-		downloadDir = filepath.Join(home, "Downloads", "localgo")
+		downloadDir = defaultDownloadDir()
 	}
 
 	maxBodySizeStr := v.GetString("max_body_size")
@@ -143,7 +317,103 @@ func LoadConfig(v *viper.Viper, logger *zap.SugaredLogger) (*Config, error) {
 		if size, err := strconv.ParseInt(maxBodySizeStr, 10, 64); err == nil {
 			maxBodySize = size
 		} else {
-			zap.S().Warnf("Invalid LOCALSEND_MAX_BODY_SIZE value: %s, using default", maxBodySizeStr)
+			logger.Warnf("Invalid LOCALSEND_MAX_BODY_SIZE value: %s, using default", maxBodySizeStr)
+		}
+	}
+
+	rateLimit := DefaultRateLimit
+	if rateLimitStr := v.GetString("rate_limit"); rateLimitStr != "" {
+		if n, err := strconv.Atoi(rateLimitStr); err == nil && n >= 0 {
+			rateLimit = n
+		} else {
+			logger.Warnf("Invalid LOCALSEND_RATE_LIMIT value: %s, using default", rateLimitStr)
+		}
+	}
+
+	queueWorkers := DefaultQueueWorkers
+	if queueWorkersStr := v.GetString("queue_workers"); queueWorkersStr != "" {
+		if n, err := strconv.Atoi(queueWorkersStr); err == nil && n >= 1 {
+			queueWorkers = n
+		} else {
+			logger.Warnf("Invalid LOCALSEND_QUEUE_WORKERS value: %s, using default", queueWorkersStr)
+		}
+	}
+
+	copyBufferSize := int64(DefaultCopyBufferSize)
+	if copyBufferSizeStr := v.GetString("copy_buffer_size"); copyBufferSizeStr != "" {
+		if size, err := ParseSize(copyBufferSizeStr); err == nil && size >= minCopyBufferSize && size <= maxCopyBufferSize {
+			copyBufferSize = size
+		} else {
+			logger.Warnf("Invalid LOCALSEND_COPY_BUFFER_SIZE value: %s, using default", copyBufferSizeStr)
+		}
+	}
+
+	var connectTimeout time.Duration
+	if s := v.GetString("connect_timeout"); s != "" {
+		if d, err := time.ParseDuration(s); err == nil && d >= 0 {
+			connectTimeout = d
+		} else {
+			logger.Warnf("Invalid LOCALSEND_CONNECT_TIMEOUT value: %s, using default", s)
+		}
+	}
+
+	maxConnsPerHost := 0
+	if maxConnsStr := v.GetString("max_conns_per_host"); maxConnsStr != "" {
+		if n, err := strconv.Atoi(maxConnsStr); err == nil && n >= 0 {
+			maxConnsPerHost = n
+		} else {
+			logger.Warnf("Invalid LOCALSEND_MAX_CONNS_PER_HOST value: %s, using default", maxConnsStr)
+		}
+	}
+
+	proxy := strings.TrimSpace(v.GetString("proxy"))
+	if proxy != "" && proxy != httputil.ProxyNone {
+		if _, err := httputil.ParseProxy(proxy); err != nil {
+			logger.Warnf("Invalid LOCALSEND_PROXY value: %v, using the proxy environment variables", err)
+			proxy = ""
+		}
+	}
+
+	relayURL := strings.TrimSpace(v.GetString("relay"))
+	if relayURL != "" {
+		if _, err := ParseRelay(relayURL); err != nil {
+			logger.Warnf("Invalid LOCALSEND_RELAY value: %v, not using a relay", err)
+			relayURL = ""
+		}
+	}
+	relayFingerprint := strings.ToLower(strings.TrimSpace(v.GetString("relay_fingerprint")))
+	if relayFingerprint != "" && !ValidFingerprint(relayFingerprint) {
+		// Trusting any relay instead would defeat the point.
+		return nil, fmt.Errorf("relay_fingerprint: %q is not a SHA-256 certificate fingerprint", relayFingerprint)
+	}
+
+	maxSessionUploads := 0
+	if s := v.GetString("max_session_uploads"); s != "" {
+		if n, err := strconv.Atoi(s); err == nil && n >= 0 {
+			maxSessionUploads = n
+		} else {
+			logger.Warnf("Invalid LOCALSEND_MAX_SESSION_UPLOADS value: %s, not limiting parallel uploads", s)
+		}
+	}
+
+	compressMinSize := int64(compression.DefaultMinSize)
+	if s := v.GetString("compress_min_size"); s != "" {
+		if size, err := ParseSize(s); err == nil {
+			compressMinSize = size
+		} else {
+			logger.Warnf("Invalid LOCALSEND_COMPRESS_MIN_SIZE value: %s, using default", s)
+		}
+	}
+	compressTypes := compression.DefaultTypes
+	if v.IsSet("compress_types") {
+		// A list in the config file, or comma-separated from the environment.
+		compressTypes = nil
+		for _, entry := range v.GetStringSlice("compress_types") {
+			for _, t := range strings.Split(entry, ",") {
+				if t = strings.TrimSpace(t); t != "" {
+					compressTypes = append(compressTypes, t)
+				}
+			}
 		}
 	}
 
@@ -153,23 +423,9 @@ func LoadConfig(v *viper.Viper, logger *zap.SugaredLogger) (*Config, error) {
 	forceHTTP := v.GetString("force_http") == "true" || v.GetString("force_http") == "1"
 	HttpsEnabled := !forceHTTP
 
-	securityContext, err := crypto.LoadSecurityContext(securityFilePath, logger)
+	securityContext, err := crypto.LoadOrCreateSecurityContext(securityFilePath, alias, logger)
 	if err != nil {
-		if os.IsNotExist(err) {
-			zap.S().Infof("Security context not found at %s, generating new one...", securityFilePath)
-			securityContext, err = crypto.GenerateSecurityContext(alias, logger)
-			if err != nil {
-				return nil, fmt.Errorf("failed to generate security context: %w", err)
-			}
-			if err := os.MkdirAll(securityDirPath, 0700); err != nil {
-				zap.S().Warnf("Could not create security directory '%s': %v", securityDirPath, err)
-			}
-			if err := crypto.SaveSecurityContext(securityContext, securityFilePath, logger); err != nil {
-				zap.S().Warnf("failed to save newly generated security context to '%s': %v", securityFilePath, err)
-			}
-		} else {
-			return nil, fmt.Errorf("failed to load security context from '%s': %w", securityFilePath, err)
-		}
+		return nil, err
 	}
 
 	deviceModel := "GoDevice"
@@ -187,11 +443,27 @@ func LoadConfig(v *viper.Viper, logger *zap.SugaredLogger) (*Config, error) {
 
 	autoAccept := v.GetString("auto_accept") == "true" || v.GetString("auto_accept") == "1"
 	noClipboard := v.GetString("no_clipboard") == "true" || v.GetString("no_clipboard") == "1"
+	strictProtocol := v.GetString("strict_protocol") == "true" || v.GetString("strict_protocol") == "1"
+	dbus := v.GetString("dbus") == "true" || v.GetString("dbus") == "1"
+	printMessages := v.GetString("print_messages") == "true" || v.GetString("print_messages") == "1"
+	sendPreviews := v.GetString("send_previews") == "true" || v.GetString("send_previews") == "1"
 	quiet := v.GetString("quiet") == "true" || v.GetString("quiet") == "1"
+	fsync := v.GetString("fsync") == "true" || v.GetString("fsync") == "1"
+	dedupe := v.GetString("dedupe") == "true" || v.GetString("dedupe") == "1"
+	compress := v.GetString("compress") == "true" || v.GetString("compress") == "1"
 
 	historyFile := v.GetString("history")
 
 	execHook := v.GetString("exec")
+	scanCmd := v.GetString("scan")
+	quarantineDir := v.GetString("quarantine_dir")
+	encryptTo := strings.TrimSpace(v.GetString("encrypt_to"))
+	if encryptTo != "" {
+		// Falling back to storing files in the clear would defeat the point.
+		if _, err := crypto.ParseRecipient(encryptTo); err != nil {
+			return nil, fmt.Errorf("encrypt_to: %w", err)
+		}
+	}
 
 	concurrency := v.GetInt("concurrency")
 
@@ -202,12 +474,76 @@ func LoadConfig(v *viper.Viper, logger *zap.SugaredLogger) (*Config, error) {
 	customTLSKeyPath := v.GetString("tls_key")
 	notificationCmd := v.GetString("notification_cmd")
 
+	mqttTopic := strings.TrimSpace(v.GetString("mqtt_topic"))
+	if mqttTopic == "" {
+		mqttTopic = DefaultMQTTTopic
+	} else if !MQTTTopicValid(mqttTopic) {
+		logger.Warnf("Invalid LOCALSEND_MQTT_TOPIC value: %q, using %s", mqttTopic, DefaultMQTTTopic)
+		mqttTopic = DefaultMQTTTopic
+	}
+
+	openMode, err := ParseOpenMode(v.GetString("open"))
+	if err != nil {
+		logger.Warnf("Invalid LOCALSEND_OPEN value: %v, not opening received files", err)
+	}
+
+	var drainTimeout time.Duration
+	if s := v.GetString("drain_timeout"); s != "" {
+		if d, err := time.ParseDuration(s); err == nil && d >= 0 {
+			drainTimeout = d
+		} else {
+			logger.Warnf("Invalid LOCALSEND_DRAIN_TIMEOUT value: %s, not waiting for transfers on shutdown", s)
+		}
+	}
+
+	var sessionWait time.Duration
+	if s := v.GetString("session_wait"); s != "" {
+		if d, err := time.ParseDuration(s); err == nil && d >= 0 {
+			sessionWait = min(d, MaxSessionWait)
+		} else {
+			logger.Warnf("Invalid LOCALSEND_SESSION_WAIT value: %s, refusing transfers while another is active", s)
+		}
+	}
+
+	accessLogFormat, err := ParseAccessLogFormat(v.GetString("access_log_format"))
+	if err != nil {
+		logger.Warnf("Invalid LOCALSEND_ACCESS_LOG_FORMAT value: %v, using common", err)
+		accessLogFormat = AccessLogCommon
+	}
+
+	manifest, err := ParseManifest(v.GetString("manifest"))
+	if err != nil {
+		logger.Warnf("Invalid LOCALSEND_MANIFEST value: %v, not writing manifests", err)
+	}
+
+	trustedFingerprints, acceptRules, err := loadAcceptRules(v)
+	if err != nil {
+		return nil, err
+	}
+	favorites, favoriteMACs, err := loadFavorites(v)
+	if err != nil {
+		return nil, err
+	}
+	targetRoots, err := loadTargetRoots(v)
+	if err != nil {
+		return nil, err
+	}
+
 	cfg := &Config{
 		Alias:             alias,
 		Port:              port,
 		MulticastGroup:    multicastGroup,
 		HttpsEnabled:      HttpsEnabled,
 		SecurityContext:   securityContext,
+		AdminTokenPath:    filepath.Join(securityDirPath, AdminTokenFile),
+		AdminSocket:       v.GetString("admin_socket"),
+		ControlGRPC:       v.GetString("control_grpc"),
+		DBus:              dbus,
+		MQTTBroker:        strings.TrimSpace(v.GetString("mqtt_broker")),
+		MQTTTopic:         mqttTopic,
+		MQTTUsername:      v.GetString("mqtt_username"),
+		MQTTPassword:      v.GetString("mqtt_password"),
+		MQTTCAFile:        v.GetString("mqtt_ca_file"),
 		SecurityPath:      securityFilePath,
 		DeviceModel:       &deviceModel,
 		DeviceType:        deviceType,
@@ -215,11 +551,34 @@ func LoadConfig(v *viper.Viper, logger *zap.SugaredLogger) (*Config, error) {
 		AutoAccept:        autoAccept,
 		RandomFingerprint: generateRandomID(64),
 		MaxBodySize:       maxBodySize,
+		RateLimit:         rateLimit,
 		NoClipboard:       noClipboard,
+		StrictProtocol:    strictProtocol,
+		PrintMessages:     printMessages,
+		SendPreviews:      sendPreviews,
 		HistoryFile:       historyFile,
+		SessionFile:       v.GetString("session_file"),
+		AccessLog:         v.GetString("access_log"),
+		AccessLogFormat:   accessLogFormat,
 		Quiet:             quiet,
 		ExecHook:          execHook,
+		ScanCmd:           scanCmd,
+		QuarantineDir:     quarantineDir,
+		EncryptTo:         encryptTo,
+		Dedupe:            dedupe,
 		Concurrency:       concurrency,
+		QueueWorkers:      queueWorkers,
+		CopyBufferSize:    copyBufferSize,
+		Fsync:             fsync,
+		ConnectTimeout:    connectTimeout,
+		MaxSessionUploads: maxSessionUploads,
+		MaxConnsPerHost:   maxConnsPerHost,
+		Proxy:             proxy,
+		Relay:             relayURL,
+		RelayFingerprint:  relayFingerprint,
+		Compress:          compress,
+		CompressMinSize:   compressMinSize,
+		CompressTypes:     compressTypes,
 		MulticastInterface: multicastInterface,
 		Shell:             shell,
 		ClipboardWriteCmd: clipboardWriteCmd,
@@ -227,18 +586,34 @@ func LoadConfig(v *viper.Viper, logger *zap.SugaredLogger) (*Config, error) {
 		CustomTLSCertPath: customTLSCertPath,
 		CustomTLSKeyPath:  customTLSKeyPath,
 		NotificationCmd:   notificationCmd,
+		OpenMode:          openMode,
+		Manifest:          manifest,
+		Headless:          v.GetString("headless") == "true" || v.GetString("headless") == "1",
+		DrainTimeout:      drainTimeout,
+		SessionWait:       sessionWait,
+		TrustedFingerprints: trustedFingerprints,
+		AcceptRules:         acceptRules,
+		Favorites:           favorites,
+		FavoriteMACs:        favoriteMACs,
+		TargetRoots:         targetRoots,
 	}
 
 	return cfg, nil
 }
 
-func generateDefaultAlias() string {
-	hostname, err := os.Hostname()
-	if err != nil || hostname == "" {
-		zap.S().Infow("Could not get hostname, generating random alias suffix.")
-		hostname = "LocalGo"
+// defaultDownloadDir is where received files go unless configured
+// otherwise.
+func defaultDownloadDir() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		home = "."
 	}
-	return hostname
+	return filepath.Join(home, "Downloads", "localgo")
+}
+
+// CompressionPolicy returns the files to compress when Compress is set.
+func (c *Config) CompressionPolicy() compression.Policy {
+	return compression.Policy{MinSize: c.CompressMinSize, Types: c.CompressTypes}
 }
 
 func generateRandomID(length int) string {
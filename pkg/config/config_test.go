@@ -3,6 +3,8 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
 	"time"
 
@@ -202,7 +204,7 @@ func TestGetSecurityDir_EnvOverride(t *testing.T) {
 		v.SetEnvPrefix("LOCALSEND")
 		v.AutomaticEnv()
 		return v
-	}())
+	}(), testLogger)
 	if dir != tmpDir {
 		t.Errorf("Expected security dir '%s', got '%s'", tmpDir, dir)
 	}
@@ -273,3 +275,187 @@ func TestConfig_Constants(t *testing.T) {
 
 var _ = time.Now      // silence unused import
 var _ = filepath.Join // silence unused import
+
+func TestApplyHeadless(t *testing.T) {
+	cfg := &Config{OpenMode: "dir"}
+	cfg.ApplyHeadless()
+	if !cfg.Headless || !cfg.Quiet || !cfg.NoClipboard {
+		t.Errorf("Expected Headless, Quiet and NoClipboard, got %+v", cfg)
+	}
+	if cfg.OpenMode != "" {
+		t.Errorf("Expected OpenMode cleared, got %q", cfg.OpenMode)
+	}
+	if cfg.DrainTimeout != DefaultHeadlessDrainTimeout {
+		t.Errorf("Expected drain timeout %v, got %v", DefaultHeadlessDrainTimeout, cfg.DrainTimeout)
+	}
+
+	cfg = &Config{DrainTimeout: time.Minute}
+	cfg.ApplyHeadless()
+	if cfg.DrainTimeout != time.Minute {
+		t.Errorf("Expected configured drain timeout kept, got %v", cfg.DrainTimeout)
+	}
+}
+
+func TestConfigDirEnv(t *testing.T) {
+	origEnv := saveEnv()
+	defer restoreEnv(origEnv)
+	clearEnv()
+
+	dir := t.TempDir()
+	t.Setenv(ConfigDirEnv, dir)
+
+	if paths := ConfigPaths(); len(paths) != 1 || paths[0] != dir {
+		t.Errorf("Expected config paths [%s], got %v", dir, paths)
+	}
+	if got, want := DefaultConfigFile(), filepath.Join(dir, "config.yaml"); got != want {
+		t.Errorf("Expected config file %s, got %s", want, got)
+	}
+
+	v := viper.New()
+	v.SetEnvPrefix("LOCALSEND")
+	v.AutomaticEnv()
+	if got, want := getSecurityDir(v, testLogger), filepath.Join(dir, ".security"); got != want {
+		t.Errorf("Expected security dir %s, got %s", want, got)
+	}
+}
+
+func TestLoadConfig_GeneratedAliasPersists(t *testing.T) {
+	origEnv := saveEnv()
+	defer restoreEnv(origEnv)
+
+	clearEnv()
+
+	tmpDir := t.TempDir()
+	os.Setenv("LOCALSEND_SECURITY_DIR", tmpDir)
+
+	load := func() *Config {
+		v := viper.New()
+		v.SetEnvPrefix("LOCALSEND")
+		v.AutomaticEnv()
+		cfg, err := LoadConfig(v, testLogger)
+		if err != nil {
+			t.Fatalf("LoadConfig failed: %v", err)
+		}
+		return cfg
+	}
+
+	first := load()
+	words := strings.Fields(first.Alias)
+	if len(words) != 2 || !slices.Contains(aliasAdjectives, words[0]) || !slices.Contains(aliasFruits, words[1]) {
+		t.Errorf("expected an \"Adjective Fruit\" alias, got %q", first.Alias)
+	}
+	if second := load(); second.Alias != first.Alias {
+		t.Errorf("alias changed between runs: %q then %q", first.Alias, second.Alias)
+	}
+
+	data, err := os.ReadFile(filepath.Join(tmpDir, AliasFile))
+	if err != nil || strings.TrimSpace(string(data)) != first.Alias {
+		t.Errorf("expected the alias to be saved in %s, got %q (%v)", AliasFile, data, err)
+	}
+}
+
+func TestAdminToken(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".security", AdminTokenFile)
+	token, err := LoadOrCreateAdminToken(path)
+	if err != nil {
+		t.Fatalf("LoadOrCreateAdminToken: %v", err)
+	}
+	if len(token) != 64 {
+		t.Errorf("token %q, want 64 hex digits", token)
+	}
+	if info, err := os.Stat(path); err != nil {
+		t.Errorf("token file not saved: %v", err)
+	} else if info.Mode().Perm() != 0600 {
+		t.Errorf("token file mode = %v, want 0600", info.Mode().Perm())
+	}
+	if again, _ := LoadOrCreateAdminToken(path); again != token {
+		t.Errorf("second load = %q, want the stored %q", again, token)
+	}
+
+	rotated, err := RotateAdminToken(path)
+	if err != nil {
+		t.Fatalf("RotateAdminToken: %v", err)
+	}
+	if read, _ := ReadAdminToken(path); rotated == token || read != rotated {
+		t.Errorf("after rotation read %q, rotated %q, old %q", read, rotated, token)
+	}
+}
+
+func TestParseControlGRPC(t *testing.T) {
+	for _, tc := range []struct {
+		in, network, addr string
+		ok                bool
+	}{
+		{"unix:/run/user/1000/localgo/control.sock", "unix", "/run/user/1000/localgo/control.sock", true},
+		{"127.0.0.1:53318", "tcp", "127.0.0.1:53318", true},
+		{"[::1]:53318", "tcp", "[::1]:53318", true},
+		{"localhost:53318", "tcp", "localhost:53318", true},
+		{"unix:", "", "", false},
+		{"0.0.0.0:53318", "", "", false},
+		{"192.168.1.2:53318", "", "", false},
+		{":53318", "", "", false},
+		{"/run/control.sock", "", "", false},
+	} {
+		network, addr, err := ParseControlGRPC(tc.in)
+		if (err == nil) != tc.ok || network != tc.network || addr != tc.addr {
+			t.Errorf("ParseControlGRPC(%q) = %q, %q, %v", tc.in, network, addr, err)
+		}
+	}
+}
+
+func TestParseMQTTBroker(t *testing.T) {
+	for _, tc := range []struct {
+		in string
+		ok bool
+	}{
+		{"tcp://nas.local:1883", true},
+		{"mqtts://user@broker.example.com", true},
+		{"wss://broker.example.com/mqtt", true},
+		{"http://nas.local:1883", false},
+		{"nas.local:1883", false},
+		{"tcp://", false},
+	} {
+		if _, err := ParseMQTTBroker(tc.in); (err == nil) != tc.ok {
+			t.Errorf("ParseMQTTBroker(%q) = %v", tc.in, err)
+		}
+	}
+	for topic, ok := range map[string]bool{
+		"localgo":     true,
+		"home/nas/lg": true,
+		"home/+/lg":   false,
+		"home/#":      false,
+		"home/":       false,
+		"":            false,
+	} {
+		if MQTTTopicValid(topic) != ok {
+			t.Errorf("MQTTTopicValid(%q) = %v", topic, !ok)
+		}
+	}
+}
+
+func TestParseRelay(t *testing.T) {
+	for _, tc := range []struct {
+		in, want string
+	}{
+		{"https://relay.example.com", "https://relay.example.com:53318"},
+		{"https://relay.example.com:443/ignored?x=1", "https://relay.example.com:443"},
+		{"https://[2001:db8::1]", "https://[2001:db8::1]:53318"},
+		{"http://relay.example.com", ""},
+		{"relay.example.com", ""},
+		{"https://", ""},
+	} {
+		u, err := ParseRelay(tc.in)
+		if tc.want == "" {
+			if err == nil {
+				t.Errorf("ParseRelay(%q) = %v, want an error", tc.in, u)
+			}
+			continue
+		}
+		if err != nil || u.String() != tc.want {
+			t.Errorf("ParseRelay(%q) = %v, %v, want %s", tc.in, u, err, tc.want)
+		}
+	}
+	if !ValidFingerprint(strings.Repeat("ab", 32)) || ValidFingerprint("abcd") || ValidFingerprint(strings.Repeat("zz", 32)) {
+		t.Error("ValidFingerprint accepts or rejects the wrong fingerprints")
+	}
+}
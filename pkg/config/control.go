@@ -0,0 +1,28 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// ParseControlGRPC parses the control_grpc setting: "unix:PATH" for a unix
+// socket, or HOST:PORT on a loopback address. The control interface is
+// never served to the network.
+func ParseControlGRPC(s string) (network, addr string, err error) {
+	s = strings.TrimSpace(s)
+	if path, ok := strings.CutPrefix(s, "unix:"); ok {
+		if path == "" {
+			return "", "", fmt.Errorf("%q has no socket path", s)
+		}
+		return "unix", path, nil
+	}
+	host, _, err := net.SplitHostPort(s)
+	if err != nil {
+		return "", "", fmt.Errorf("%q is neither unix:PATH nor HOST:PORT", s)
+	}
+	if ip := net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+		return "", "", fmt.Errorf("%q is not a loopback address", s)
+	}
+	return "tcp", s, nil
+}
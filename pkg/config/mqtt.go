@@ -0,0 +1,31 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// ParseMQTTBroker parses the mqtt_broker setting: a URL such as
+// tcp://nas:1883, or ssl://nas:8883 and wss://nas/mqtt for TLS.
+func ParseMQTTBroker(s string) (*url.URL, error) {
+	u, err := url.Parse(strings.TrimSpace(s))
+	if err != nil {
+		return nil, fmt.Errorf("%q is not a URL", s)
+	}
+	switch u.Scheme {
+	case "tcp", "mqtt", "ssl", "tls", "mqtts", "ws", "wss":
+	default:
+		return nil, fmt.Errorf("%q: use tcp://, ssl://, ws:// or wss://", s)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("%q has no host", s)
+	}
+	return u, nil
+}
+
+// MQTTTopicValid reports whether topic can prefix the topics events are
+// published to: MQTT wildcards are not allowed in them.
+func MQTTTopicValid(topic string) bool {
+	return topic != "" && !strings.ContainsAny(topic, "+#\x00") && !strings.HasSuffix(topic, "/")
+}
@@ -0,0 +1,39 @@
+package config
+
+import (
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// DefaultRelayPort is where `localgo relay` listens unless told otherwise,
+// next to the port of the LocalSend API.
+const DefaultRelayPort = DefaultPort + 1
+
+// ParseRelay parses the relay setting: the https:// URL of a server running
+// `localgo relay`. A URL without a port uses DefaultRelayPort.
+func ParseRelay(s string) (*url.URL, error) {
+	u, err := url.Parse(strings.TrimSpace(s))
+	if err != nil {
+		return nil, fmt.Errorf("%q is not a URL", s)
+	}
+	if u.Scheme != "https" {
+		return nil, fmt.Errorf("%q: use https://HOST[:PORT]", s)
+	}
+	if u.Hostname() == "" {
+		return nil, fmt.Errorf("%q has no host", s)
+	}
+	if u.Port() == "" {
+		u.Host = fmt.Sprintf("%s:%d", u.Host, DefaultRelayPort)
+	}
+	u.Path, u.RawPath, u.RawQuery, u.Fragment = "", "", "", ""
+	return u, nil
+}
+
+// ValidFingerprint reports whether fp is a full certificate fingerprint: 64
+// hex digits, the SHA-256 hash of the certificate.
+func ValidFingerprint(fp string) bool {
+	b, err := hex.DecodeString(fp)
+	return err == nil && len(b) == 32
+}
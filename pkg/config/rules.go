@@ -0,0 +1,360 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// minRuleFingerprintLen is the shortest fingerprint prefix accepted in
+// trusted_fingerprints and accept rules.
+const minRuleFingerprintLen = 8
+
+// AcceptAction is what an accept rule does with a matching transfer.
+type AcceptAction string
+
+const (
+	AcceptActionAccept AcceptAction = "accept" // accept without prompting
+	AcceptActionPrompt AcceptAction = "prompt" // ask interactively
+	AcceptActionReject AcceptAction = "reject" // refuse with 403
+	AcceptActionSkip   AcceptAction = "skip"   // leave matching files out
+)
+
+// AcceptRule decides how an incoming transfer is handled. Every condition
+// that is set must match; rules are evaluated in order and the first match
+// wins. Transfers matching no rule fall back to AutoAccept.
+//
+// Skip rules are matched against each file on its own, before the other
+// rules: a matching file is left out of the transfer and gets no upload
+// token, and the other rules decide on the files that remain.
+type AcceptRule struct {
+	Fingerprints []string     // sender fingerprint starts with one of these
+	Trusted      *bool        // sender is (or is not) verified and in TrustedFingerprints
+	MinSize      int64        // total transfer size is at least this many bytes (0 = any)
+	MaxSize      int64        // total transfer size is at most this many bytes (0 = any)
+	Executable   *bool        // transfer does (or does not) contain an executable
+	Action       AcceptAction // what to do with a matching transfer
+	PIN          *bool        // overrides whether the configured PIN is required
+}
+
+// TransferFacts describes an incoming transfer for rule matching.
+//
+// Senders report their fingerprint themselves, and anyone can copy one seen
+// on the network. Unless Verified is set, Fingerprint is therefore never
+// trusted and never matches a rule that accepts a transfer or waives the PIN;
+// it still matches rules that reject, prompt or skip.
+type TransferFacts struct {
+	Fingerprint string
+	Verified    bool // the sender proved it holds the certificate with Fingerprint
+	TotalSize   int64
+	Executable  bool
+}
+
+// rawAcceptRule is the config file form of an AcceptRule.
+type rawAcceptRule struct {
+	Fingerprints []string `mapstructure:"fingerprints"`
+	Trusted      *bool    `mapstructure:"trusted"`
+	MinSize      string   `mapstructure:"min_size"`
+	MaxSize      string   `mapstructure:"max_size"`
+	Executable   *bool    `mapstructure:"executable"`
+	Action       string   `mapstructure:"action"`
+	PIN          *bool    `mapstructure:"pin"`
+}
+
+// loadAcceptRules reads trusted_fingerprints and accept_rules from v.
+func loadAcceptRules(v *viper.Viper) ([]string, []AcceptRule, error) {
+	trusted := v.GetStringSlice("trusted_fingerprints")
+	for _, fp := range trusted {
+		if len(fp) < minRuleFingerprintLen {
+			return nil, nil, fmt.Errorf("trusted fingerprint %q is too short: use at least %d characters", fp, minRuleFingerprintLen)
+		}
+	}
+
+	var raw []rawAcceptRule
+	if err := v.UnmarshalKey("accept_rules", &raw); err != nil {
+		return nil, nil, fmt.Errorf("invalid accept_rules: %w", err)
+	}
+
+	rules := make([]AcceptRule, 0, len(raw))
+	for i, r := range raw {
+		rule := AcceptRule{
+			Fingerprints: r.Fingerprints,
+			Trusted:      r.Trusted,
+			Executable:   r.Executable,
+			PIN:          r.PIN,
+		}
+		for _, fp := range r.Fingerprints {
+			if len(fp) < minRuleFingerprintLen {
+				return nil, nil, fmt.Errorf("accept rule %d: fingerprint %q is too short: use at least %d characters", i+1, fp, minRuleFingerprintLen)
+			}
+		}
+		if r.MinSize != "" {
+			size, err := ParseSize(r.MinSize)
+			if err != nil {
+				return nil, nil, fmt.Errorf("accept rule %d: %w", i+1, err)
+			}
+			rule.MinSize = size
+		}
+		if r.MaxSize != "" {
+			size, err := ParseSize(r.MaxSize)
+			if err != nil {
+				return nil, nil, fmt.Errorf("accept rule %d: %w", i+1, err)
+			}
+			rule.MaxSize = size
+		}
+		switch action := AcceptAction(strings.ToLower(r.Action)); action {
+		case AcceptActionAccept, AcceptActionPrompt, AcceptActionReject, AcceptActionSkip:
+			rule.Action = action
+		default:
+			return nil, nil, fmt.Errorf("accept rule %d: invalid action %q: use accept, prompt, reject, or skip", i+1, r.Action)
+		}
+		rules = append(rules, rule)
+	}
+	return trusted, rules, nil
+}
+
+// ParseSize parses a byte size such as "512", "100KB" or "1.5GB". Units are
+// binary (1KB = 1024 bytes), matching how sizes are displayed.
+func ParseSize(s string) (int64, error) {
+	str := strings.ToUpper(strings.TrimSpace(s))
+	units := []struct {
+		suffix string
+		mult   float64
+	}{
+		{"TB", 1 << 40}, {"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10}, {"B", 1},
+	}
+	mult := 1.0
+	for _, u := range units {
+		if strings.HasSuffix(str, u.suffix) {
+			str = strings.TrimSpace(strings.TrimSuffix(str, u.suffix))
+			mult = u.mult
+			break
+		}
+	}
+	n, err := strconv.ParseFloat(str, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return int64(n * mult), nil
+}
+
+// loadFavorites reads favorites from v. An entry is a fingerprint, or a
+// mapping with the fingerprint and the device's MAC address for waking it.
+func loadFavorites(v *viper.Viper) ([]string, map[string]net.HardwareAddr, error) {
+	entries, ok := v.Get("favorites").([]any)
+	if !ok {
+		entries = nil
+		for _, fp := range v.GetStringSlice("favorites") {
+			entries = append(entries, fp)
+		}
+	}
+	var favorites []string
+	macs := make(map[string]net.HardwareAddr)
+	for i, entry := range entries {
+		var fp, mac string
+		switch e := entry.(type) {
+		case string:
+			fp = e
+		case map[string]any:
+			fp, _ = e["fingerprint"].(string)
+			mac, _ = e["mac"].(string)
+		default:
+			return nil, nil, fmt.Errorf("favorite %d: want a fingerprint, or fingerprint and mac", i+1)
+		}
+		if len(fp) < minRuleFingerprintLen {
+			return nil, nil, fmt.Errorf("favorite fingerprint %q is too short: use at least %d characters", fp, minRuleFingerprintLen)
+		}
+		favorites = append(favorites, fp)
+		if mac == "" {
+			continue
+		}
+		addr, err := net.ParseMAC(mac)
+		if err != nil || len(addr) != 6 {
+			return nil, nil, fmt.Errorf("favorite %s: invalid MAC address %q", fp, mac)
+		}
+		macs[fp] = addr
+	}
+	return favorites, macs, nil
+}
+
+// loadTargetRoots reads target_roots from v: folders inside the download
+// directory that senders may name as a transfer's target path, or "." for
+// any of them. Entries are cleaned and use forward slashes.
+func loadTargetRoots(v *viper.Viper) ([]string, error) {
+	var roots []string
+	for _, entry := range v.GetStringSlice("target_roots") {
+		root := path.Clean(filepath.ToSlash(strings.TrimSpace(entry)))
+		if path.IsAbs(root) || filepath.VolumeName(entry) != "" || root == ".." || strings.HasPrefix(root, "../") {
+			return nil, fmt.Errorf("target root %q must be a folder inside the download directory", entry)
+		}
+		roots = append(roots, root)
+	}
+	return roots, nil
+}
+
+// TargetDir returns the folder, relative to the download directory, for a
+// transfer whose sender asked for target path p, and whether p is allowed:
+// it must stay inside the download directory and within a TargetRoots entry.
+// Backslashes, as sent by Windows devices, separate folders too.
+func (c *Config) TargetDir(p string) (string, bool) {
+	p = strings.ReplaceAll(p, `\`, "/")
+	if p == "" || path.IsAbs(p) || filepath.VolumeName(p) != "" {
+		return "", false
+	}
+	dir := path.Clean(p)
+	if dir == ".." || strings.HasPrefix(dir, "../") {
+		return "", false
+	}
+	for _, root := range c.TargetRoots {
+		if root == "." || dir == root || strings.HasPrefix(dir, root+"/") {
+			return dir, true
+		}
+	}
+	return "", false
+}
+
+// IsFavorite reports whether fingerprint matches an entry in Favorites.
+func (c *Config) IsFavorite(fingerprint string) bool {
+	return matchesFingerprint(fingerprint, c.Favorites)
+}
+
+// FavoriteMAC returns the MAC address stored with the favorite matching
+// fingerprint, which may itself be a prefix as given with --fingerprint, or
+// nil if no single favorite matches.
+func (c *Config) FavoriteMAC(fingerprint string) net.HardwareAddr {
+	if fingerprint == "" {
+		return nil
+	}
+	var found net.HardwareAddr
+	for fp, mac := range c.FavoriteMACs {
+		if !matchesFingerprint(fingerprint, []string{fp}) && !matchesFingerprint(fp, []string{fingerprint}) {
+			continue
+		}
+		if found != nil && found.String() != mac.String() {
+			return nil
+		}
+		found = mac
+	}
+	return found
+}
+
+// IsTrusted reports whether fingerprint matches an entry in TrustedFingerprints.
+// Whether the fingerprint can be believed is up to the caller; see
+// TransferFacts.
+func (c *Config) IsTrusted(fingerprint string) bool {
+	return matchesFingerprint(fingerprint, c.TrustedFingerprints)
+}
+
+// FingerprintEntries lists the settings with an entry naming fingerprint,
+// such as "trusted_fingerprints" or "accept_rules (rule 2)", so entries left
+// behind when a device's fingerprint changes can be pointed out.
+func (c *Config) FingerprintEntries(fingerprint string) []string {
+	var entries []string
+	names := func(prefixes []string) bool {
+		for _, p := range prefixes {
+			if IsFingerprintPrefix(p) && matchesFingerprint(fingerprint, []string{p}) {
+				return true
+			}
+		}
+		return false
+	}
+	if names(c.TrustedFingerprints) {
+		entries = append(entries, "trusted_fingerprints")
+	}
+	for i, rule := range c.AcceptRules {
+		if names(rule.Fingerprints) {
+			entries = append(entries, fmt.Sprintf("accept_rules (rule %d)", i+1))
+		}
+	}
+	if names(c.Favorites) {
+		entries = append(entries, "favorites")
+	}
+	return entries
+}
+
+// MatchAcceptRule returns the first accept rule other than a skip rule
+// matching the transfer, or nil if none does.
+func (c *Config) MatchAcceptRule(t TransferFacts) *AcceptRule {
+	for i := range c.AcceptRules {
+		rule := &c.AcceptRules[i]
+		if rule.Action != AcceptActionSkip && c.ruleMatches(rule, t) {
+			return rule
+		}
+	}
+	return nil
+}
+
+// SkipsFile reports whether a skip rule matches a file, described by t as a
+// transfer of that file alone.
+func (c *Config) SkipsFile(t TransferFacts) bool {
+	for i := range c.AcceptRules {
+		rule := &c.AcceptRules[i]
+		if rule.Action == AcceptActionSkip && c.ruleMatches(rule, t) {
+			return true
+		}
+	}
+	return false
+}
+
+// ruleMatches reports whether every condition set on rule holds for t.
+func (c *Config) ruleMatches(rule *AcceptRule, t TransferFacts) bool {
+	if len(rule.Fingerprints) > 0 {
+		if !matchesFingerprint(t.Fingerprint, rule.Fingerprints) || (rule.grants() && !t.Verified) {
+			return false
+		}
+	}
+	if rule.Trusted != nil && *rule.Trusted != (t.Verified && c.IsTrusted(t.Fingerprint)) {
+		return false
+	}
+	if rule.MinSize > 0 && t.TotalSize < rule.MinSize {
+		return false
+	}
+	if rule.MaxSize > 0 && t.TotalSize > rule.MaxSize {
+		return false
+	}
+	if rule.Executable != nil && *rule.Executable != t.Executable {
+		return false
+	}
+	return true
+}
+
+// IsFingerprintPrefix reports whether s can be taken for a fingerprint or a
+// prefix of one in rules: at least 8 hex digits.
+func IsFingerprintPrefix(s string) bool {
+	if len(s) < minRuleFingerprintLen {
+		return false
+	}
+	for _, c := range strings.ToLower(s) {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// grants reports whether the rule lets a transfer through without the
+// user's or the PIN's say-so, so it must only match a verified sender.
+func (rule *AcceptRule) grants() bool {
+	waivesPIN := rule.PIN != nil && !*rule.PIN
+	return rule.Action == AcceptActionAccept || (rule.Action == AcceptActionPrompt && waivesPIN)
+}
+
+// matchesFingerprint reports whether fingerprint starts with any of the
+// given prefixes, ignoring case.
+func matchesFingerprint(fingerprint string, prefixes []string) bool {
+	if fingerprint == "" {
+		return false
+	}
+	fingerprint = strings.ToLower(fingerprint)
+	for _, p := range prefixes {
+		if strings.HasPrefix(fingerprint, strings.ToLower(p)) {
+			return true
+		}
+	}
+	return false
+}
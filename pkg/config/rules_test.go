@@ -0,0 +1,255 @@
+package config
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    int64
+		wantErr bool
+	}{
+		{"512", 512, false},
+		{"10B", 10, false},
+		{"100KB", 100 << 10, false},
+		{"50 MB", 50 << 20, false},
+		{"1.5gb", 3 << 29, false},
+		{"2TB", 2 << 40, false},
+		{"", 0, true},
+		{"-1MB", 0, true},
+		{"lots", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseSize(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseSize(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseSize(%q) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+}
+
+func loadRulesFromYAML(t *testing.T, yaml string) ([]string, []AcceptRule, error) {
+	t.Helper()
+	v := viper.New()
+	v.SetConfigType("yaml")
+	if err := v.ReadConfig(strings.NewReader(yaml)); err != nil {
+		t.Fatalf("failed to read yaml: %v", err)
+	}
+	return loadAcceptRules(v)
+}
+
+func TestLoadAcceptRules(t *testing.T) {
+	trusted, rules, err := loadRulesFromYAML(t, `
+trusted_fingerprints:
+  - 3F9A1C2B7D
+accept_rules:
+  - executable: true
+    action: prompt
+  - trusted: true
+    action: accept
+    pin: false
+  - max_size: 50MB
+    action: Accept
+`)
+	if err != nil {
+		t.Fatalf("loadAcceptRules failed: %v", err)
+	}
+	if len(trusted) != 1 || len(rules) != 3 {
+		t.Fatalf("expected 1 trusted fingerprint and 3 rules, got %d and %d", len(trusted), len(rules))
+	}
+	if rules[0].Executable == nil || !*rules[0].Executable || rules[0].Action != AcceptActionPrompt {
+		t.Errorf("unexpected first rule: %+v", rules[0])
+	}
+	if rules[1].PIN == nil || *rules[1].PIN {
+		t.Errorf("expected second rule to disable the PIN: %+v", rules[1])
+	}
+	if rules[2].MaxSize != 50<<20 || rules[2].Action != AcceptActionAccept {
+		t.Errorf("unexpected third rule: %+v", rules[2])
+	}
+}
+
+func TestLoadAcceptRules_Invalid(t *testing.T) {
+	tests := map[string]string{
+		"unknown action": "accept_rules:\n  - action: maybe\n",
+		"missing action": "accept_rules:\n  - max_size: 1MB\n",
+		"bad size":       "accept_rules:\n  - max_size: huge\n    action: accept\n",
+		"bad min size":   "accept_rules:\n  - min_size: huge\n    action: skip\n",
+		"short trusted":  "trusted_fingerprints: [abc]\n",
+		"short rule fp":  "accept_rules:\n  - fingerprints: [abc]\n    action: accept\n",
+	}
+	for name, yaml := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, _, err := loadRulesFromYAML(t, yaml); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+func TestMatchAcceptRule(t *testing.T) {
+	yes, no := true, false
+	cfg := &Config{
+		TrustedFingerprints: []string{"aaaaaaaa"},
+		AcceptRules: []AcceptRule{
+			{Executable: &yes, Action: AcceptActionPrompt},
+			{Trusted: &yes, Action: AcceptActionAccept, PIN: &no},
+			{Fingerprints: []string{"bbbbbbbb"}, Action: AcceptActionReject},
+			{MaxSize: 1 << 20, Action: AcceptActionAccept},
+			{Fingerprints: []string{"dddddddd"}, Action: AcceptActionAccept},
+		},
+	}
+
+	tests := []struct {
+		name  string
+		facts TransferFacts
+		want  int // index of the matching rule, -1 for none
+	}{
+		{"executable from trusted device", TransferFacts{Fingerprint: "AAAAAAAA1234", Executable: true}, 0},
+		{"trusted device", TransferFacts{Fingerprint: "aaaaaaaa1234", Verified: true, TotalSize: 1 << 30}, 1},
+		{"unverified claim of a trusted fingerprint", TransferFacts{Fingerprint: "aaaaaaaa1234", TotalSize: 1 << 30}, -1},
+		{"blocked device, even unverified", TransferFacts{Fingerprint: "bbbbbbbb1234", TotalSize: 10}, 2},
+		{"small transfer", TransferFacts{Fingerprint: "cccccccc", TotalSize: 1 << 20}, 3},
+		{"large transfer", TransferFacts{Fingerprint: "cccccccc", TotalSize: 1<<20 + 1}, -1},
+		{"no fingerprint is never trusted", TransferFacts{TotalSize: 1 << 30}, -1},
+		{"named device", TransferFacts{Fingerprint: "dddddddd1234", Verified: true, TotalSize: 1 << 30}, 4},
+		{"unverified claim of a named device", TransferFacts{Fingerprint: "dddddddd1234", TotalSize: 1 << 30}, -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := cfg.MatchAcceptRule(tt.facts)
+			switch {
+			case tt.want < 0 && got != nil:
+				t.Errorf("expected no match, got %+v", *got)
+			case tt.want >= 0 && got != &cfg.AcceptRules[tt.want]:
+				t.Errorf("expected rule %d, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestSkipsFile(t *testing.T) {
+	cfg := &Config{
+		AcceptRules: []AcceptRule{
+			{MinSize: 1 << 30, Action: AcceptActionSkip},
+			{Action: AcceptActionAccept},
+		},
+	}
+	if !cfg.SkipsFile(TransferFacts{TotalSize: 2 << 30}) {
+		t.Error("expected a large file to be skipped")
+	}
+	if cfg.SkipsFile(TransferFacts{TotalSize: 1<<30 - 1}) {
+		t.Error("expected a small file to be kept")
+	}
+	if got := cfg.MatchAcceptRule(TransferFacts{TotalSize: 2 << 30}); got != &cfg.AcceptRules[1] {
+		t.Errorf("expected skip rules to be ignored for the transfer, got %v", got)
+	}
+}
+
+func TestTargetDir(t *testing.T) {
+	v := viper.New()
+	v.Set("target_roots", []string{"Photos", "Projects/incoming/"})
+	roots, err := loadTargetRoots(v)
+	if err != nil {
+		t.Fatalf("loadTargetRoots failed: %v", err)
+	}
+	cfg := &Config{TargetRoots: roots}
+
+	tests := []struct {
+		target string
+		want   string
+		ok     bool
+	}{
+		{"Photos", "Photos", true},
+		{"Photos/2024/trip", "Photos/2024/trip", true},
+		{`Projects\incoming\app`, "Projects/incoming/app", true},
+		{"Projects", "", false},
+		{"Photoshop", "", false},
+		{"Photos/../Documents", "", false},
+		{"../Photos", "", false},
+		{"/etc", "", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := cfg.TargetDir(tt.target)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("TargetDir(%q) = %q, %v; want %q, %v", tt.target, got, ok, tt.want, tt.ok)
+		}
+	}
+
+	for _, bad := range []string{"../outside", "/srv/share"} {
+		v.Set("target_roots", []string{bad})
+		if _, err := loadTargetRoots(v); err == nil {
+			t.Errorf("expected target root %q to be refused", bad)
+		}
+	}
+}
+
+func TestFingerprintEntries(t *testing.T) {
+	cfg := &Config{
+		TrustedFingerprints: []string{"aaaaaaaa", "3f9a1c2b"},
+		AcceptRules: []AcceptRule{
+			{MaxSize: 10, Action: AcceptActionAccept},
+			{Fingerprints: []string{"3F9A1C2B7D4E"}, Action: AcceptActionAccept},
+		},
+		Favorites: []string{"8c1d2e3f4a5b6c7d"},
+	}
+	got := cfg.FingerprintEntries("3f9a1c2b7d4e8f60")
+	if want := []string{"trusted_fingerprints", "accept_rules (rule 2)"}; !slices.Equal(got, want) {
+		t.Errorf("FingerprintEntries = %v, want %v", got, want)
+	}
+	if got := cfg.FingerprintEntries("0000000000000000"); len(got) != 0 {
+		t.Errorf("FingerprintEntries for an unknown fingerprint = %v", got)
+	}
+}
+
+func TestLoadFavorites(t *testing.T) {
+	v := viper.New()
+	v.SetConfigType("yaml")
+	err := v.ReadConfig(strings.NewReader(`
+favorites:
+  - 3f9a1c2b7d4e8f60
+  - fingerprint: 8c1d2e3f4a5b6c7d
+    mac: A4-BB-6D-12-34-56
+`))
+	if err != nil {
+		t.Fatalf("ReadConfig failed: %v", err)
+	}
+	favorites, macs, err := loadFavorites(v)
+	if err != nil {
+		t.Fatalf("loadFavorites failed: %v", err)
+	}
+	if strings.Join(favorites, ",") != "3f9a1c2b7d4e8f60,8c1d2e3f4a5b6c7d" {
+		t.Errorf("favorites = %v", favorites)
+	}
+	cfg := &Config{Favorites: favorites, FavoriteMACs: macs}
+	if mac := cfg.FavoriteMAC("8C1D2E3F4A5B6C7D0011"); mac.String() != "a4:bb:6d:12:34:56" {
+		t.Errorf("FavoriteMAC = %v, want a4:bb:6d:12:34:56", mac)
+	}
+	if mac := cfg.FavoriteMAC("8c1d2e"); mac.String() != "a4:bb:6d:12:34:56" {
+		t.Errorf("FavoriteMAC for a prefix = %v, want a4:bb:6d:12:34:56", mac)
+	}
+	if mac := cfg.FavoriteMAC(""); mac != nil {
+		t.Errorf("FavoriteMAC for no fingerprint = %v", mac)
+	}
+	if mac := cfg.FavoriteMAC("3f9a1c2b7d4e8f60"); mac != nil {
+		t.Errorf("FavoriteMAC for a favorite without a MAC = %v", mac)
+	}
+
+	v = viper.New()
+	v.Set("favorites", []any{map[string]any{"fingerprint": "8c1d2e3f4a5b6c7d", "mac": "not-a-mac"}})
+	if _, _, err := loadFavorites(v); err == nil {
+		t.Error("expected an error for an invalid MAC address")
+	}
+	v.Set("favorites", "3f9a1c2b7d4e8f60 8c1d2e3f4a5b6c7d")
+	if favorites, _, err := loadFavorites(v); err != nil || len(favorites) != 2 {
+		t.Errorf("favorites from a string = %v, %v", favorites, err)
+	}
+}
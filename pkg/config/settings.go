@@ -0,0 +1,431 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bethropolis/localgo/pkg/crypto"
+	"github.com/bethropolis/localgo/pkg/httputil"
+	"github.com/bethropolis/localgo/pkg/logging"
+	"github.com/bethropolis/localgo/pkg/model"
+	"github.com/spf13/viper"
+	"go.uber.org/zap/zapcore"
+)
+
+// Kinds of setting values, as stored in config.yaml.
+const (
+	KindString   = "string"
+	KindInt      = "int"
+	KindBool     = "bool"
+	KindDuration = "duration"
+	KindList     = "list" // edited in the file; see `localgo config edit`
+)
+
+// Setting describes a key that can be stored in config.yaml. Each key can
+// also be set with the LOCALSEND_<KEY> environment variable.
+type Setting struct {
+	Key         string
+	Kind        string
+	Description string
+
+	check     func(string) error // extra validation after the kind is parsed
+	effective func(*Config) any  // value in a loaded Config; nil if it has none
+}
+
+var settings = []Setting{
+	{Key: "alias", Kind: KindString, Description: "Device name shown to others", check: nonEmpty,
+		effective: func(c *Config) any { return c.Alias }},
+	{Key: "port", Kind: KindInt, Description: "Server port (0 = any free port)", check: intRange(0, 65535),
+		effective: func(c *Config) any { return c.Port }},
+	{Key: "multicast_group", Kind: KindString, Description: "Multicast address for discovery", check: multicastAddr,
+		effective: func(c *Config) any { return c.MulticastGroup }},
+	{Key: "multicast_interface", Kind: KindString, Description: "Network interface for multicast",
+		effective: func(c *Config) any { return c.MulticastInterface }},
+	{Key: "download_dir", Kind: KindString, Description: "Directory for received files", check: CheckWritableDir,
+		effective: func(c *Config) any { return c.DownloadDir }},
+	{Key: "security_dir", Kind: KindString, Description: "Directory for the TLS identity",
+		effective: func(c *Config) any { return filepath.Dir(c.SecurityPath) }},
+	{Key: "device_model", Kind: KindString, Description: "Device model shown to others",
+		effective: func(c *Config) any { return *c.DeviceModel }},
+	{Key: "device_type", Kind: KindString, Description: "mobile, desktop, web, headless or server", check: deviceType,
+		effective: func(c *Config) any { return c.DeviceType }},
+	{Key: "auto_accept", Kind: KindBool, Description: "Accept transfers without prompting",
+		effective: func(c *Config) any { return c.AutoAccept }},
+	{Key: "no_clipboard", Kind: KindBool, Description: "Save incoming text as a file",
+		effective: func(c *Config) any { return c.NoClipboard }},
+	{Key: "strict_protocol", Kind: KindBool, Description: "Answer API errors exactly as the LocalSend protocol documents",
+		effective: func(c *Config) any { return c.StrictProtocol }},
+	{Key: "print_messages", Kind: KindBool, Description: "Print incoming text messages instead of saving or copying them",
+		effective: func(c *Config) any { return c.PrintMessages }},
+	{Key: "quiet", Kind: KindBool, Description: "Minimal output",
+		effective: func(c *Config) any { return c.Quiet }},
+	{Key: "force_http", Kind: KindBool, Description: "Use HTTP instead of HTTPS",
+		effective: func(c *Config) any { return !c.HttpsEnabled }},
+	{Key: "headless", Kind: KindBool, Description: "Run without a user at the machine",
+		effective: func(c *Config) any { return c.Headless }},
+	{Key: "max_body_size", Kind: KindInt, Description: "Largest file accepted, in bytes (0 = no limit)", check: intRange(0, -1),
+		effective: func(c *Config) any { return c.MaxBodySize }},
+	{Key: "rate_limit", Kind: KindInt, Description: "API requests per second per IP (0 = no limit)", check: intRange(0, -1),
+		effective: func(c *Config) any { return c.RateLimit }},
+	{Key: "concurrency", Kind: KindInt, Description: "Parallel uploads when sending", check: intRange(1, -1),
+		effective: func(c *Config) any { return c.Concurrency }},
+	{Key: "queue_workers", Kind: KindInt, Description: "Queued sends run at the same time", check: intRange(1, -1),
+		effective: func(c *Config) any { return c.QueueWorkers }},
+	{Key: "copy_buffer_size", Kind: KindString, Description: "Received data buffered before writing to disk, e.g. 1MB", check: sizeRange(minCopyBufferSize, maxCopyBufferSize),
+		effective: func(c *Config) any { return c.CopyBufferSize }},
+	{Key: "fsync", Kind: KindBool, Description: "Flush received files to disk before reporting them done",
+		effective: func(c *Config) any { return c.Fsync }},
+	{Key: "connect_timeout", Kind: KindDuration, Description: "How long connecting to a device may take",
+		effective: func(c *Config) any { return c.ConnectTimeout }},
+	{Key: "send_previews", Kind: KindBool, Description: "Offer image thumbnails to receivers when sending",
+		effective: func(c *Config) any { return c.SendPreviews }},
+	{Key: "compress", Kind: KindBool, Description: "Compress text-like files for devices that accept it",
+		effective: func(c *Config) any { return c.Compress }},
+	{Key: "compress_min_size", Kind: KindString, Description: "Smallest file compressed, e.g. 16KB", check: sizeRange(0, math.MaxInt64),
+		effective: func(c *Config) any { return c.CompressMinSize }},
+	{Key: "compress_types", Kind: KindString, Description: "Comma-separated MIME types (text/*) and extensions (.log) to compress",
+		effective: func(c *Config) any { return strings.Join(c.CompressTypes, ",") }},
+	{Key: "max_conns_per_host", Kind: KindInt, Description: "Connections open to one device at a time (0 = no limit)", check: intRange(0, -1),
+		effective: func(c *Config) any { return c.MaxConnsPerHost }},
+	{Key: "proxy", Kind: KindString, Description: "Proxy for requests to devices, e.g. socks5://localhost:1080 (none = direct; default: HTTP_PROXY and NO_PROXY)", check: proxySetting,
+		effective: func(c *Config) any { return redactedProxy(c.Proxy) }},
+	{Key: "relay", Kind: KindString, Description: "Relay for devices on other networks, e.g. https://relay.example.com (see localgo relay)", check: relaySetting,
+		effective: func(c *Config) any { return c.Relay }},
+	{Key: "relay_fingerprint", Kind: KindString, Description: "Fingerprint the relay's certificate must have", check: fingerprintSetting,
+		effective: func(c *Config) any { return c.RelayFingerprint }},
+	{Key: "max_session_uploads", Kind: KindInt, Description: "Files of one incoming transfer uploaded at a time (0 = no limit)", check: intRange(0, -1),
+		effective: func(c *Config) any { return c.MaxSessionUploads }},
+	{Key: "history", Kind: KindString, Description: "Transfer history file (off to disable)",
+		effective: func(c *Config) any { return c.HistoryFile }},
+	{Key: "session_file", Kind: KindString, Description: "Saved receive sessions (off to disable)",
+		effective: func(c *Config) any { return c.SessionFile }},
+	{Key: "admin_socket", Kind: KindString, Description: "Unix socket the admin API is also served on",
+		effective: func(c *Config) any { return c.AdminSocket }},
+	{Key: "control_grpc", Kind: KindString, Description: "unix:PATH or loopback HOST:PORT to serve the gRPC control interface on", check: controlGRPC,
+		effective: func(c *Config) any { return c.ControlGRPC }},
+	{Key: "dbus", Kind: KindBool, Description: "Publish the D-Bus interface for desktop integration (Linux)",
+		effective: func(c *Config) any { return c.DBus }},
+	{Key: "mqtt_broker", Kind: KindString, Description: "MQTT broker events are published to, e.g. tcp://nas:1883 or ssl://nas:8883", check: mqttBroker,
+		effective: func(c *Config) any { return c.MQTTBroker }},
+	{Key: "mqtt_topic", Kind: KindString, Description: "Prefix of the MQTT topics events are published to", check: mqttTopic,
+		effective: func(c *Config) any { return c.MQTTTopic }},
+	{Key: "mqtt_username", Kind: KindString, Description: "MQTT user name",
+		effective: func(c *Config) any { return c.MQTTUsername }},
+	{Key: "mqtt_password", Kind: KindString, Description: "MQTT password",
+		effective: func(c *Config) any { return masked(c.MQTTPassword) }},
+	{Key: "mqtt_ca_file", Kind: KindString, Description: "CA certificate to check a TLS broker's against (default: system roots)",
+		effective: func(c *Config) any { return c.MQTTCAFile }},
+	{Key: "exec", Kind: KindString, Description: "Command run after each received file",
+		effective: func(c *Config) any { return c.ExecHook }},
+	{Key: "scan", Kind: KindString, Description: "Command that checks each received file before it is kept",
+		effective: func(c *Config) any { return c.ScanCmd }},
+	{Key: "quarantine_dir", Kind: KindString, Description: "Where files failing the scan are moved",
+		effective: func(c *Config) any { return c.QuarantineDir }},
+	{Key: "dedupe", Kind: KindBool, Description: "Hard-link received files identical to ones already received",
+		effective: func(c *Config) any { return c.Dedupe }},
+	{Key: "encrypt_to", Kind: KindString, Description: "Recipient key received files are encrypted to at rest", check: recipientKey,
+		effective: func(c *Config) any { return c.EncryptTo }},
+	{Key: "shell", Kind: KindString, Description: "Shell used to run exec hooks",
+		effective: func(c *Config) any { return c.Shell }},
+	{Key: "open", Kind: KindString, Description: "Open received files: dir, file or folder", check: func(s string) error { _, err := ParseOpenMode(s); return err },
+		effective: func(c *Config) any { return c.OpenMode }},
+	{Key: "manifest", Kind: KindString, Description: "Manifest written for each receive session: json or sha256", check: func(s string) error { _, err := ParseManifest(s); return err },
+		effective: func(c *Config) any { return c.Manifest }},
+	{Key: "drain_timeout", Kind: KindDuration, Description: "How long shutdown waits for transfers",
+		effective: func(c *Config) any { return c.DrainTimeout }},
+	{Key: "session_wait", Kind: KindDuration, Description: "How long a transfer waits for another session to end",
+		effective: func(c *Config) any { return c.SessionWait }},
+	{Key: "access_log", Kind: KindString, Description: "HTTP access log file (- = stderr)",
+		effective: func(c *Config) any { return c.AccessLog }},
+	{Key: "access_log_format", Kind: KindString, Description: "common or json", check: func(s string) error { _, err := ParseAccessLogFormat(s); return err },
+		effective: func(c *Config) any { return c.AccessLogFormat }},
+	{Key: "tls_cert", Kind: KindString, Description: "Custom TLS certificate file",
+		effective: func(c *Config) any { return c.CustomTLSCertPath }},
+	{Key: "tls_key", Kind: KindString, Description: "Custom TLS private key file",
+		effective: func(c *Config) any { return c.CustomTLSKeyPath }},
+	{Key: "clipboard_write_cmd", Kind: KindString, Description: "Command that writes the clipboard",
+		effective: func(c *Config) any { return c.ClipboardWriteCmd }},
+	{Key: "clipboard_read_cmd", Kind: KindString, Description: "Command that reads the clipboard",
+		effective: func(c *Config) any { return c.ClipboardReadCmd }},
+	{Key: "notification_cmd", Kind: KindString, Description: "Command that shows notifications",
+		effective: func(c *Config) any { return c.NotificationCmd }},
+	{Key: "log_level", Kind: KindString, Description: "debug, info, warn or error", check: logLevel},
+	{Key: "log_levels", Kind: KindString, Description: "Per-logger levels, e.g. discovery=debug", check: func(s string) error { _, err := logging.ParseLevels(s); return err }},
+	{Key: "log_target", Kind: KindString, Description: "file, journald, syslog or syslog+udp://HOST[:PORT]", check: func(s string) error { _, err := logging.ParseTarget(s); return err }},
+	{Key: "log_file", Kind: KindString, Description: "Log file (- = stderr)"},
+	{Key: "log_max_size", Kind: KindInt, Description: "Log size in MB before rotating (0 = never)", check: intRange(0, -1)},
+	{Key: "log_max_backups", Kind: KindInt, Description: "Rotated log files to keep", check: intRange(0, -1)},
+	{Key: "trusted_fingerprints", Kind: KindList, Description: "Fingerprints of trusted devices",
+		effective: func(c *Config) any { return c.TrustedFingerprints }},
+	{Key: "favorites", Kind: KindList, Description: "Fingerprints of devices listed first",
+		effective: func(c *Config) any { return c.Favorites }},
+	{Key: "target_roots", Kind: KindList, Description: "Folders in the download directory senders may target",
+		effective: func(c *Config) any { return c.TargetRoots }},
+	{Key: "accept_rules", Kind: KindList, Description: "Rules deciding incoming transfers",
+		effective: func(c *Config) any { return fmt.Sprintf("%d rule(s)", len(c.AcceptRules)) }},
+}
+
+// Settings returns every key that can be stored in config.yaml.
+func Settings() []Setting {
+	return settings
+}
+
+// LookupSetting returns the setting for key.
+func LookupSetting(key string) (Setting, bool) {
+	for _, s := range settings {
+		if s.Key == key {
+			return s, true
+		}
+	}
+	return Setting{}, false
+}
+
+// EnvVar returns the environment variable that overrides the setting.
+func (s Setting) EnvVar() string {
+	return "LOCALSEND_" + strings.ToUpper(s.Key)
+}
+
+// Parse validates value and converts it to the type written to the config
+// file.
+func (s Setting) Parse(value string) (any, error) {
+	var parsed any
+	switch s.Kind {
+	case KindInt:
+		n, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s must be an integer, got %q", s.Key, value)
+		}
+		parsed = n
+	case KindBool:
+		b, err := strconv.ParseBool(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("%s must be true or false, got %q", s.Key, value)
+		}
+		parsed = b
+	case KindDuration:
+		d, err := time.ParseDuration(strings.TrimSpace(value))
+		if err != nil || d < 0 {
+			return nil, fmt.Errorf("%s must be a duration such as 30s or 5m, got %q", s.Key, value)
+		}
+		parsed = value
+	case KindList:
+		return nil, fmt.Errorf("%s is a list: edit it in the config file with `localgo config edit`", s.Key)
+	default:
+		parsed = value
+	}
+	if s.check != nil {
+		if err := s.check(value); err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", s.Key, err)
+		}
+	}
+	return parsed, nil
+}
+
+// Effective returns the value of the setting in c, which already includes
+// defaults, environment variables and global flags. ok is false for
+// settings that are not part of Config.
+func (s Setting) Effective(c *Config) (value any, ok bool) {
+	if s.effective == nil || c == nil {
+		return nil, false
+	}
+	return s.effective(c), true
+}
+
+// ValidateFile checks every key stored in v, as read from a config file,
+// and returns one error per problem found.
+func ValidateFile(v *viper.Viper) []error {
+	var errs []error
+	for _, key := range v.AllKeys() {
+		top := strings.SplitN(key, ".", 2)[0]
+		s, ok := LookupSetting(top)
+		if !ok {
+			errs = append(errs, fmt.Errorf("unknown key %q", key))
+			continue
+		}
+		if s.Kind == KindList {
+			continue
+		}
+		if _, err := s.Parse(v.GetString(key)); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if _, _, err := loadAcceptRules(v); err != nil {
+		errs = append(errs, err)
+	}
+	if _, err := loadTargetRoots(v); err != nil {
+		errs = append(errs, err)
+	}
+	return errs
+}
+
+func nonEmpty(s string) error {
+	if strings.TrimSpace(s) == "" {
+		return errors.New("must not be empty")
+	}
+	return nil
+}
+
+// intRange returns a check that the value lies within [min, max]; a max
+// below zero means no upper bound.
+func intRange(min, max int64) func(string) error {
+	return func(s string) error {
+		n, _ := strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+		if n < min || (max >= 0 && n > max) {
+			if max < 0 {
+				return fmt.Errorf("%d must be at least %d", n, min)
+			}
+			return fmt.Errorf("%d is out of range %d-%d", n, min, max)
+		}
+		return nil
+	}
+}
+
+// sizeRange returns a check that the value is a size, as ParseSize reads
+// it, within [min, max] bytes.
+func sizeRange(min, max int64) func(string) error {
+	return func(s string) error {
+		n, err := ParseSize(s)
+		if err != nil {
+			return err
+		}
+		if n < min || n > max {
+			return fmt.Errorf("%s is out of range %d-%d bytes", s, min, max)
+		}
+		return nil
+	}
+}
+
+func multicastAddr(s string) error {
+	ip := net.ParseIP(strings.TrimSpace(s))
+	if ip == nil || ip.To4() == nil || !ip.IsMulticast() {
+		return fmt.Errorf("%q is not an IPv4 multicast address", s)
+	}
+	return nil
+}
+
+func mqttBroker(s string) error {
+	if strings.TrimSpace(s) == "" {
+		return nil
+	}
+	_, err := ParseMQTTBroker(s)
+	return err
+}
+
+func proxySetting(s string) error {
+	if s := strings.TrimSpace(s); s == "" || s == httputil.ProxyNone {
+		return nil
+	}
+	_, err := httputil.ParseProxy(s)
+	return err
+}
+
+func relaySetting(s string) error {
+	if strings.TrimSpace(s) == "" {
+		return nil
+	}
+	_, err := ParseRelay(s)
+	return err
+}
+
+func fingerprintSetting(s string) error {
+	if s := strings.TrimSpace(s); s != "" && !ValidFingerprint(strings.ToLower(s)) {
+		return fmt.Errorf("%q is not a SHA-256 certificate fingerprint (64 hex digits)", s)
+	}
+	return nil
+}
+
+// redactedProxy hides the password of a proxy URL in `localgo config list`.
+func redactedProxy(s string) string {
+	if u, err := httputil.ParseProxy(s); err == nil {
+		return u.Redacted()
+	}
+	return s
+}
+
+func mqttTopic(s string) error {
+	if s := strings.TrimSpace(s); s != "" && !MQTTTopicValid(s) {
+		return fmt.Errorf("%q must not contain + or # or end with /", s)
+	}
+	return nil
+}
+
+// masked hides a secret in `localgo config list`, showing only whether it
+// is set.
+func masked(s string) string {
+	if s == "" {
+		return ""
+	}
+	return "********"
+}
+
+func controlGRPC(s string) error {
+	if strings.TrimSpace(s) == "" {
+		return nil
+	}
+	_, _, err := ParseControlGRPC(s)
+	return err
+}
+
+func recipientKey(s string) error {
+	if strings.TrimSpace(s) == "" {
+		return nil
+	}
+	_, err := crypto.ParseRecipient(s)
+	return err
+}
+
+func deviceType(s string) error {
+	switch model.DeviceType(s) {
+	case model.DeviceTypeMobile, model.DeviceTypeDesktop, model.DeviceTypeWeb, model.DeviceTypeHeadless, model.DeviceTypeServer:
+		return nil
+	}
+	return fmt.Errorf("%q is not a device type: use mobile, desktop, web, headless or server", s)
+}
+
+func logLevel(s string) error {
+	_, err := zapcore.ParseLevel(s)
+	return err
+}
+
+// CheckWritableDir checks that files can be created in dir. A directory that
+// does not exist yet is fine if it can be created under its nearest
+// existing parent.
+func CheckWritableDir(dir string) error {
+	if strings.TrimSpace(dir) == "" {
+		return errors.New("must not be empty")
+	}
+	dir = filepath.Clean(os.ExpandEnv(dir))
+	existing := dir
+	for {
+		info, err := os.Stat(existing)
+		if err == nil {
+			if !info.IsDir() {
+				return fmt.Errorf("%s is not a directory", existing)
+			}
+			break
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		parent := filepath.Dir(existing)
+		if parent == existing {
+			return fmt.Errorf("%s has no existing parent directory", dir)
+		}
+		existing = parent
+	}
+
+	f, err := os.CreateTemp(existing, ".localgo-write-test-*")
+	if err != nil {
+		return fmt.Errorf("%s is not writable", existing)
+	}
+	f.Close()
+	os.Remove(f.Name())
+	return nil
+}
@@ -0,0 +1,109 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestSettingParse(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "file")
+	if err := os.WriteFile(file, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		key, value string
+		want       any // nil means an error is expected
+	}{
+		{"port", "8080", int64(8080)},
+		{"port", "0", int64(0)},
+		{"port", "65536", nil},
+		{"port", "http", nil},
+		{"auto_accept", "1", true},
+		{"auto_accept", "maybe", nil},
+		{"drain_timeout", "30s", "30s"},
+		{"drain_timeout", "soon", nil},
+		{"concurrency", "0", nil},
+		{"copy_buffer_size", "2MB", "2MB"},
+		{"copy_buffer_size", "1KB", nil},
+		{"copy_buffer_size", "lots", nil},
+		{"max_conns_per_host", "8", int64(8)},
+		{"compress_min_size", "16KB", "16KB"},
+		{"compress_min_size", "big", nil},
+		{"max_conns_per_host", "-1", nil},
+		{"proxy", "socks5://localhost:1080", "socks5://localhost:1080"},
+		{"proxy", "none", "none"},
+		{"proxy", "ftp://proxy:21", nil},
+		{"relay", "https://relay.example.com", "https://relay.example.com"},
+		{"relay", "http://relay.example.com", nil},
+		{"relay_fingerprint", strings.Repeat("ab", 32), strings.Repeat("ab", 32)},
+		{"relay_fingerprint", "ab12", nil},
+		{"multicast_group", "224.0.0.167", "224.0.0.167"},
+		{"multicast_group", "192.168.1.1", nil},
+		{"device_type", "server", "server"},
+		{"device_type", "toaster", nil},
+		{"log_level", "warn", "warn"},
+		{"log_level", "loud", nil},
+		{"log_target", "journald", "journald"},
+		{"log_target", "stdout", nil},
+		{"open", "folder", "folder"},
+		{"download_dir", filepath.Join(dir, "new", "sub"), filepath.Join(dir, "new", "sub")},
+		{"download_dir", file, nil},
+		{"accept_rules", "[]", nil},
+	}
+	for _, tt := range tests {
+		s, ok := LookupSetting(tt.key)
+		if !ok {
+			t.Fatalf("LookupSetting(%q) found nothing", tt.key)
+		}
+		got, err := s.Parse(tt.value)
+		if tt.want == nil {
+			if err == nil {
+				t.Errorf("Parse(%s=%q) = %v, want an error", tt.key, tt.value, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("Parse(%s=%q) = %v, %v; want %v", tt.key, tt.value, got, err, tt.want)
+		}
+	}
+
+	if _, ok := LookupSetting("colour"); ok {
+		t.Error("expected unknown keys not to be found")
+	}
+}
+
+func TestValidateFile(t *testing.T) {
+	v := viper.New()
+	v.SetConfigType("yaml")
+	err := v.ReadConfig(strings.NewReader(`
+port: 70000
+colour: red
+alias: Laptop
+accept_rules:
+  - action: maybe
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	errs := ValidateFile(v)
+	var msgs []string
+	for _, e := range errs {
+		msgs = append(msgs, e.Error())
+	}
+	joined := strings.Join(msgs, "\n")
+	for _, want := range []string{"invalid port", `unknown key "colour"`, "invalid action"} {
+		if !strings.Contains(joined, want) {
+			t.Errorf("expected an error mentioning %q, got:\n%s", want, joined)
+		}
+	}
+	if len(errs) != 3 {
+		t.Errorf("expected 3 errors, got %d:\n%s", len(errs), joined)
+	}
+}
@@ -0,0 +1,79 @@
+package config
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// AdminTokenFile is the file in the security directory that keeps the
+// bearer token the admin API requires. Only its owner can read it, so other
+// users on the machine cannot control the server.
+const AdminTokenFile = "admin-token"
+
+// ReadAdminToken returns the admin token stored at path.
+func ReadAdminToken(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	token := strings.TrimSpace(string(data))
+	if token == "" {
+		return "", fmt.Errorf("admin token file %s is empty", path)
+	}
+	return token, nil
+}
+
+// CheckAdminToken reports whether auth, the value of an Authorization
+// header, carries the admin token stored at path. The file is read every
+// time, so a rotated token takes effect at once.
+func CheckAdminToken(path, auth string) bool {
+	want, err := ReadAdminToken(path)
+	got, ok := strings.CutPrefix(auth, "Bearer ")
+	return err == nil && ok && subtle.ConstantTimeCompare([]byte(got), []byte(want)) == 1
+}
+
+// LoadOrCreateAdminToken returns the admin token stored at path, creating
+// one on first use.
+func LoadOrCreateAdminToken(path string) (string, error) {
+	token, err := ReadAdminToken(path)
+	if err == nil || !errors.Is(err, os.ErrNotExist) {
+		return token, err
+	}
+	return RotateAdminToken(path)
+}
+
+// RotateAdminToken replaces the admin token stored at path with a new one
+// and returns it. A running server accepts only the new token from then on.
+func RotateAdminToken(path string) (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate admin token: %w", err)
+	}
+	token := hex.EncodeToString(buf)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return "", fmt.Errorf("failed to save admin token: %w", err)
+	}
+	// Written aside and renamed, so the server never reads half a token.
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+AdminTokenFile+"-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to save admin token: %w", err)
+	}
+	_, err = tmp.WriteString(token + "\n")
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return "", fmt.Errorf("failed to save admin token: %w", err)
+	}
+	return token, nil
+}
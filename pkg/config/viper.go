@@ -1,19 +1,43 @@
 package config
 
 import (
+	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/spf13/viper"
 )
 
+// ConfigDirEnv names the environment variable that relocates the config
+// directory: config.yaml is read from it and, unless LOCALSEND_SECURITY_DIR
+// is set, the security context is kept in its .security subdirectory.
+const ConfigDirEnv = "LOCALSEND_CONFIG_DIR"
+
+// ConfigPaths returns the directories searched for config.yaml, in order.
+// The first is where a new config file is created.
+func ConfigPaths() []string {
+	if dir := os.Getenv(ConfigDirEnv); dir != "" {
+		return []string{dir}
+	}
+	return []string{"$HOME/.config/localgo/", "$HOME/.local/etc/localgo/"}
+}
+
+// DefaultConfigFile returns the path a new config.yaml is written to.
+func DefaultConfigFile() string {
+	return filepath.Join(os.ExpandEnv(ConfigPaths()[0]), "config.yaml")
+}
+
 func InitViper() *viper.Viper {
 	v := viper.New()
 
 	v.SetConfigName("config")
 	v.SetConfigType("yaml")
-	v.AddConfigPath("$HOME/.config/localgo/")
-	v.AddConfigPath("$HOME/.local/etc/localgo/")
-	v.AddConfigPath(".")
+	for _, dir := range ConfigPaths() {
+		v.AddConfigPath(dir)
+	}
+	if os.Getenv(ConfigDirEnv) == "" {
+		v.AddConfigPath(".")
+	}
 
 	v.SetEnvPrefix("LOCALSEND")
 	v.SetEnvKeyReplacer(strings.NewReplacer("-", "_"))
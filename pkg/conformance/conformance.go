@@ -0,0 +1,415 @@
+// Package conformance checks that a LocalSend implementation speaks the v2
+// HTTP API as the protocol documents it: the info and register endpoints,
+// the status codes for malformed and unauthorized requests, and, if asked,
+// a real transfer. The conformance command runs it against a device on the
+// network; tests can run it against a server of their own with
+// conformancetest.
+package conformance
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/bethropolis/localgo/pkg/capture"
+	"github.com/bethropolis/localgo/pkg/model"
+)
+
+// Result statuses.
+const (
+	StatusOK   = "ok"
+	StatusWarn = "warn"
+	StatusFail = "fail"
+	StatusSkip = "skip"
+)
+
+// Result is the outcome of one check.
+type Result struct {
+	Check   string `json:"check"`
+	Status  string `json:"status"`
+	Message string `json:"message"`
+	Spec    string `json:"spec,omitempty"` // what the protocol requires, for violations
+}
+
+// Options describes how to check a target.
+type Options struct {
+	Self          model.InfoDto // the device the checks present themselves as
+	PIN           string        // sent with the test transfer
+	Transfer      bool          // also send a small text file; the target may ask its user first
+	Timeout       time.Duration // per request; default 5s
+	PromptTimeout time.Duration // how long the test transfer waits to be accepted; default 1m
+	Client        *http.Client  // default: one that accepts any certificate
+}
+
+// apiPath is where the v2 endpoints live.
+const apiPath = "/api/localsend/v2"
+
+// The test transfer's file.
+const (
+	transferFileID   = "conformance"
+	transferFileName = "localgo-conformance.txt"
+	transferContent  = "LocalGo conformance check\n"
+)
+
+// Run performs the checks against the device at baseURL, such as
+// https://192.168.1.5:53317, and returns the results in order. If the info
+// endpoint cannot be reached, that is the only result.
+func Run(ctx context.Context, baseURL string, opts Options) []Result {
+	if opts.Timeout <= 0 {
+		opts.Timeout = 5 * time.Second
+	}
+	if opts.PromptTimeout <= 0 {
+		opts.PromptTimeout = time.Minute
+	}
+	client := opts.Client
+	if client == nil {
+		tr := &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}
+		defer tr.CloseIdleConnections()
+		client = &http.Client{Transport: capture.Transport(tr)}
+	}
+	r := &runner{ctx: ctx, base: strings.TrimSuffix(baseURL, "/"), opts: opts, client: client}
+
+	info := r.checkInfo()
+	if info == nil {
+		return r.results
+	}
+	r.checkRegister(info)
+	r.checkErrors()
+	r.checkTransfer()
+	return r.results
+}
+
+// Failed reports whether any result is a failure.
+func Failed(results []Result) bool {
+	for _, res := range results {
+		if res.Status == StatusFail {
+			return true
+		}
+	}
+	return false
+}
+
+type runner struct {
+	ctx     context.Context
+	base    string
+	opts    Options
+	client  *http.Client
+	results []Result
+}
+
+func (r *runner) pass(check, format string, a ...any) {
+	r.results = append(r.results, Result{Check: check, Status: StatusOK, Message: fmt.Sprintf(format, a...)})
+}
+
+func (r *runner) warn(check, spec, format string, a ...any) {
+	r.results = append(r.results, Result{Check: check, Status: StatusWarn, Message: fmt.Sprintf(format, a...), Spec: spec})
+}
+
+func (r *runner) fail(check, spec, format string, a ...any) {
+	r.results = append(r.results, Result{Check: check, Status: StatusFail, Message: fmt.Sprintf(format, a...), Spec: spec})
+}
+
+func (r *runner) skip(check, format string, a ...any) {
+	r.results = append(r.results, Result{Check: check, Status: StatusSkip, Message: fmt.Sprintf(format, a...)})
+}
+
+// response is a response read whole.
+type response struct {
+	status int
+	header http.Header
+	body   []byte
+	tls    *tls.ConnectionState
+}
+
+// maxBody bounds the responses read; none the checks expect comes close.
+const maxBody = 1 << 20
+
+// do sends a request to endpoint, such as "/info", and reads the response.
+func (r *runner) do(method, endpoint string, query url.Values, contentType string, body []byte, timeout time.Duration) (*response, error) {
+	ctx, cancel := context.WithTimeout(r.ctx, timeout)
+	defer cancel()
+	u := r.base + apiPath + endpoint
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if body == nil {
+		req.Body = http.NoBody
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxBody))
+	if err != nil {
+		return nil, err
+	}
+	return &response{status: resp.StatusCode, header: resp.Header, body: data, tls: resp.TLS}, nil
+}
+
+func (r *runner) post(endpoint string, query url.Values, contentType string, body []byte) (*response, error) {
+	return r.do(http.MethodPost, endpoint, query, contentType, body, r.opts.Timeout)
+}
+
+// isJSON reports whether resp says its body is JSON.
+func (resp *response) isJSON() bool {
+	ct, _, _ := mime.ParseMediaType(resp.header.Get("Content-Type"))
+	return ct == "application/json"
+}
+
+// deviceTypes are the device types the protocol defines.
+var deviceTypes = []model.DeviceType{
+	model.DeviceTypeMobile,
+	model.DeviceTypeDesktop,
+	model.DeviceTypeWeb,
+	model.DeviceTypeHeadless,
+	model.DeviceTypeServer,
+}
+
+// checkInfo checks GET /info and returns what the target reported, or nil
+// if it did not answer with an info object.
+func (r *runner) checkInfo() *model.InfoDto {
+	const spec = "GET /info answers 200 with a JSON object of alias, version, deviceModel, deviceType and fingerprint"
+	query := url.Values{"fingerprint": {r.opts.Self.Fingerprint}}
+	resp, err := r.do(http.MethodGet, "/info", query, "", nil, r.opts.Timeout)
+	if err != nil {
+		r.fail("info", spec, "not reachable: %v", err)
+		return nil
+	}
+	if resp.status != http.StatusOK {
+		r.fail("info", spec, "answered %d", resp.status)
+		return nil
+	}
+	var fields map[string]json.RawMessage
+	var info model.InfoDto
+	if err := json.Unmarshal(resp.body, &fields); err != nil {
+		r.fail("info", spec, "body is not a JSON object: %v", err)
+		return nil
+	}
+	if err := json.Unmarshal(resp.body, &info); err != nil {
+		r.fail("info", spec, "body is not an info object: %v", err)
+		return nil
+	}
+	if !resp.isJSON() {
+		r.warn("info", "JSON responses have Content-Type application/json", "answered with Content-Type %q", resp.header.Get("Content-Type"))
+	} else {
+		r.pass("info", "%s answers as %q", r.base, info.Alias)
+	}
+
+	var missing []string
+	for _, name := range []string{"alias", "version", "fingerprint"} {
+		var s string
+		if json.Unmarshal(fields[name], &s) != nil || s == "" {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		r.fail("info.fields", spec, "missing or empty: %s", strings.Join(missing, ", "))
+	} else {
+		r.pass("info.fields", "alias, version and fingerprint present")
+	}
+
+	if major, _, _ := strings.Cut(info.Version, "."); major != "2" {
+		r.fail("info.version", "v2 devices report a protocol version 2.x", "reports protocol version %q", info.Version)
+	} else {
+		r.pass("info.version", "protocol version %s", info.Version)
+	}
+
+	if info.DeviceType != "" && !slices.Contains(deviceTypes, info.DeviceType) {
+		r.warn("info.device-type", "deviceType is mobile, desktop, web, headless, server or null", "unknown device type %q", info.DeviceType)
+	} else if _, ok := fields["deviceType"]; !ok {
+		r.warn("info.device-type", "deviceType is mobile, desktop, web, headless, server or null", "deviceType missing")
+	} else {
+		r.pass("info.device-type", "device type %s", orNull(string(info.DeviceType)))
+	}
+
+	r.checkFingerprint(resp, info.Fingerprint)
+	return &info
+}
+
+// checkFingerprint checks that a target served over HTTPS presents the
+// certificate its fingerprint is the hash of.
+func (r *runner) checkFingerprint(resp *response, fingerprint string) {
+	if resp.tls == nil || len(resp.tls.PeerCertificates) == 0 {
+		r.skip("info.fingerprint", "served over HTTP, where the fingerprint is not tied to a certificate")
+		return
+	}
+	sum := sha256.Sum256(resp.tls.PeerCertificates[0].Raw)
+	cert := hex.EncodeToString(sum[:])
+	if !strings.EqualFold(cert, fingerprint) {
+		r.fail("info.fingerprint", "over HTTPS the fingerprint is the SHA-256 hash of the TLS certificate",
+			"reports %s, certificate is %s", fingerprint, cert)
+		return
+	}
+	r.pass("info.fingerprint", "matches the TLS certificate")
+}
+
+// checkRegister checks POST /register, which answers with the same info.
+func (r *runner) checkRegister(info *model.InfoDto) {
+	const spec = "POST /register answers 200 with the device's info"
+	self := r.opts.Self
+	body, _ := json.Marshal(model.RegisterDto{
+		Alias:       self.Alias,
+		Version:     self.Version,
+		DeviceModel: self.DeviceModel,
+		DeviceType:  self.DeviceType,
+		Fingerprint: self.Fingerprint,
+		Port:        self.Port,
+		Protocol:    self.Protocol,
+		Download:    self.Download,
+	})
+	resp, err := r.post("/register", nil, "application/json", body)
+	switch {
+	case err != nil:
+		r.fail("register", spec, "request failed: %v", err)
+		return
+	case resp.status != http.StatusOK:
+		r.fail("register", spec, "answered %d", resp.status)
+		return
+	}
+	var got model.InfoDto
+	if err := json.Unmarshal(resp.body, &got); err != nil {
+		r.fail("register", spec, "body is not an info object: %v", err)
+		return
+	}
+	if got.Fingerprint != info.Fingerprint {
+		r.fail("register", spec, "answered as fingerprint %q, info reports %q", got.Fingerprint, info.Fingerprint)
+		return
+	}
+	r.pass("register", "answered as %q", got.Alias)
+}
+
+// checkErrors checks the status codes for requests the target must turn
+// down.
+func (r *runner) checkErrors() {
+	resp, err := r.post("/prepare-upload", nil, "application/json", []byte(`{"info": {"alias": `))
+	r.expect("prepare-upload.invalid-body", "prepare-upload answers 400 to an invalid body", resp, err, http.StatusBadRequest)
+
+	resp, err = r.post("/upload", nil, "application/octet-stream", []byte("x"))
+	r.expect("upload.missing-params", "upload answers 400 without sessionId, fileId and token", resp, err, http.StatusBadRequest)
+
+	query := url.Values{"sessionId": {"conformance-no-such-session"}, "fileId": {transferFileID}, "token": {"invalid"}}
+	resp, err = r.post("/upload", query, "application/octet-stream", []byte("x"))
+	r.expect("upload.unknown-session", "upload answers 403 (or 409) for a session that does not exist", resp, err,
+		http.StatusForbidden, http.StatusConflict)
+}
+
+// expect records whether resp has one of the want statuses. Another 4xx is
+// a warning, as the target did turn the request down; success or a server
+// error is a failure.
+func (r *runner) expect(check, spec string, resp *response, err error, want ...int) {
+	switch {
+	case err != nil:
+		r.fail(check, spec, "request failed: %v", err)
+	case slices.Contains(want, resp.status):
+		r.pass(check, "answered %d", resp.status)
+	case resp.status >= 400 && resp.status < 500:
+		r.warn(check, spec, "answered %d", resp.status)
+	default:
+		r.fail(check, spec, "answered %d", resp.status)
+	}
+}
+
+// checkTransfer sends a small text file through prepare-upload, upload and
+// cancel.
+func (r *runner) checkTransfer() {
+	if !r.opts.Transfer {
+		r.skip("transfer", "not run; it sends a test file the target may ask its user to accept")
+		return
+	}
+	const spec = "prepare-upload answers 200 with a sessionId and a token per accepted file"
+	sum := sha256.Sum256([]byte(transferContent))
+	hash := hex.EncodeToString(sum[:])
+	body, _ := json.Marshal(model.PrepareUploadRequestDto{
+		Info: r.opts.Self,
+		Files: map[string]model.FileDto{transferFileID: {
+			ID:       transferFileID,
+			FileName: transferFileName,
+			Size:     int64(len(transferContent)),
+			FileType: "text/plain",
+			SHA256:   &hash,
+		}},
+	})
+	var query url.Values
+	if r.opts.PIN != "" {
+		query = url.Values{"pin": {r.opts.PIN}}
+	}
+	resp, err := r.do(http.MethodPost, "/prepare-upload", query, "application/json", body, r.opts.PromptTimeout)
+	switch {
+	case err != nil:
+		r.fail("transfer.prepare", spec, "request failed: %v", err)
+		return
+	case resp.status == http.StatusUnauthorized && r.opts.PIN == "":
+		r.skip("transfer.prepare", "the target requires a PIN")
+		return
+	case resp.status == http.StatusUnauthorized:
+		r.skip("transfer.prepare", "the target did not accept the PIN")
+		return
+	case resp.status == http.StatusForbidden:
+		r.skip("transfer.prepare", "the target rejected the transfer")
+		return
+	case resp.status == http.StatusConflict:
+		r.skip("transfer.prepare", "the target is busy with another transfer")
+		return
+	case resp.status == http.StatusNoContent:
+		r.warn("transfer.prepare", spec, "answered 204: the target wanted none of the files")
+		return
+	case resp.status != http.StatusOK:
+		r.fail("transfer.prepare", spec, "answered %d", resp.status)
+		return
+	}
+	var prepared model.PrepareUploadResponseDto
+	if err := json.Unmarshal(resp.body, &prepared); err != nil {
+		r.fail("transfer.prepare", spec, "body is not a prepare-upload response: %v", err)
+		return
+	}
+	token := prepared.Files[transferFileID]
+	if prepared.SessionID == "" || token == "" {
+		r.fail("transfer.prepare", spec, "sessionId or the file's token missing: %s", resp.body)
+		return
+	}
+	r.pass("transfer.prepare", "session %s", prepared.SessionID)
+
+	query = url.Values{"sessionId": {prepared.SessionID}, "fileId": {transferFileID}, "token": {token + "-wrong"}}
+	resp, err = r.post("/upload", query, "text/plain", []byte(transferContent))
+	r.expect("transfer.wrong-token", "upload answers 403 to a wrong token", resp, err, http.StatusForbidden)
+
+	query.Set("token", token)
+	resp, err = r.post("/upload", query, "text/plain", []byte(transferContent))
+	switch {
+	case err != nil:
+		r.fail("transfer.upload", "upload answers 200 once the file is received", "request failed: %v", err)
+	case resp.status != http.StatusOK:
+		r.fail("transfer.upload", "upload answers 200 once the file is received", "answered %d", resp.status)
+	default:
+		r.pass("transfer.upload", "%d bytes received", len(transferContent))
+	}
+
+	resp, err = r.post("/cancel", url.Values{"sessionId": {prepared.SessionID}}, "", nil)
+	r.expect("transfer.cancel", "cancel answers 200", resp, err, http.StatusOK)
+}
+
+func orNull(s string) string {
+	if s == "" {
+		return "null"
+	}
+	return s
+}
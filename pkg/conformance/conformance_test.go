@@ -0,0 +1,58 @@
+package conformance
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestRun_Violations runs the checks against a server that gets the info
+// wrong and accepts anything.
+func TestRun_Violations(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case apiPath + "/info", apiPath + "/register":
+			w.Write([]byte(`{"alias": "Broken", "version": "1.0", "deviceType": "toaster", "fingerprint": "abc"}`))
+		default:
+			w.WriteHeader(http.StatusOK)
+		}
+	}))
+	defer srv.Close()
+
+	results := Run(context.Background(), srv.URL, Options{Client: srv.Client()})
+	if !Failed(results) {
+		t.Fatal("violations not reported as failures")
+	}
+	want := map[string]string{
+		"info":                        StatusWarn, // no Content-Type
+		"info.fields":                 StatusOK,
+		"info.version":                StatusFail,
+		"info.device-type":            StatusWarn,
+		"info.fingerprint":            StatusFail,
+		"register":                    StatusOK,
+		"prepare-upload.invalid-body": StatusFail,
+		"upload.missing-params":       StatusFail,
+		"upload.unknown-session":      StatusFail,
+		"transfer":                    StatusSkip,
+	}
+	for _, res := range results {
+		if res.Status != want[res.Check] {
+			t.Errorf("%s = %s (%s), want %s", res.Check, res.Status, res.Message, want[res.Check])
+		}
+		delete(want, res.Check)
+	}
+	for check := range want {
+		t.Errorf("%s not checked", check)
+	}
+}
+
+func TestRun_Unreachable(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	srv.Close()
+
+	results := Run(context.Background(), srv.URL, Options{})
+	if len(results) != 1 || results[0].Check != "info" || results[0].Status != StatusFail {
+		t.Errorf("results = %+v, want only a failed info check", results)
+	}
+}
@@ -0,0 +1,42 @@
+// Package conformancetest runs the LocalSend conformance checks from a Go
+// test, so an implementation can keep itself to the protocol in CI.
+package conformancetest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bethropolis/localgo/pkg/conformance"
+	"github.com/bethropolis/localgo/pkg/model"
+)
+
+// DefaultSelf is the device the checks present themselves as when the
+// options do not name one.
+var DefaultSelf = model.InfoDto{
+	Alias:       "Conformance Test",
+	Version:     "2.1",
+	DeviceType:  model.DeviceTypeHeadless,
+	Fingerprint: "conformance-test",
+	Protocol:    model.ProtocolTypeHTTP,
+}
+
+// Run checks the device at baseURL and reports each failed check as a test
+// error. Warnings and skipped checks are logged.
+func Run(t testing.TB, baseURL string, opts conformance.Options) []conformance.Result {
+	t.Helper()
+	if opts.Self.Fingerprint == "" {
+		opts.Self = DefaultSelf
+	}
+	results := conformance.Run(context.Background(), baseURL, opts)
+	for _, res := range results {
+		switch res.Status {
+		case conformance.StatusFail:
+			t.Errorf("%s: %s (%s)", res.Check, res.Message, res.Spec)
+		case conformance.StatusWarn:
+			t.Logf("warning: %s: %s (%s)", res.Check, res.Message, res.Spec)
+		case conformance.StatusSkip:
+			t.Logf("skipped: %s: %s", res.Check, res.Message)
+		}
+	}
+	return results
+}
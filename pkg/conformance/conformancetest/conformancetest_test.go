@@ -0,0 +1,70 @@
+package conformancetest
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bethropolis/localgo/pkg/config"
+	"github.com/bethropolis/localgo/pkg/conformance"
+	"github.com/bethropolis/localgo/pkg/crypto"
+	"github.com/bethropolis/localgo/pkg/history"
+	"github.com/bethropolis/localgo/pkg/server"
+	"go.uber.org/zap"
+)
+
+// TestLocalGo keeps LocalGo's own server conformant, with the test transfer.
+func TestLocalGo(t *testing.T) {
+	for _, https := range []bool{true, false} {
+		t.Run(fmt.Sprintf("https=%v", https), func(t *testing.T) {
+			security, err := crypto.GenerateSecurityContext("Test", zap.NewNop().Sugar())
+			if err != nil {
+				t.Fatalf("generate security context: %v", err)
+			}
+			cfg := &config.Config{
+				Alias:             "Test",
+				HttpsEnabled:      https,
+				AutoAccept:        true,
+				HistoryFile:       history.DisabledSentinel,
+				SessionFile:       history.DisabledSentinel,
+				DownloadDir:       t.TempDir(),
+				SecurityContext:   security,
+				RandomFingerprint: "random-fingerprint",
+			}
+			srv := server.NewServer(cfg, zap.NewNop().Sugar())
+			ctx, cancel := context.WithCancel(context.Background())
+			ready := make(chan struct{}, 1)
+			errCh := make(chan error, 1)
+			go func() { errCh <- srv.Start(ctx, ready) }()
+			defer func() {
+				cancel()
+				<-errCh
+			}()
+			select {
+			case <-ready:
+			case err := <-errCh:
+				t.Fatalf("server failed to start: %v", err)
+			case <-time.After(5 * time.Second):
+				t.Fatal("server did not become ready")
+			}
+
+			scheme := "http"
+			if https {
+				scheme = "https"
+			}
+			results := Run(t, fmt.Sprintf("%s://127.0.0.1:%d", scheme, cfg.Port), conformance.Options{Transfer: true})
+			for _, res := range results {
+				if res.Status == conformance.StatusSkip && res.Check != "info.fingerprint" {
+					t.Errorf("%s skipped: %s", res.Check, res.Message)
+				}
+			}
+			data, err := os.ReadFile(filepath.Join(cfg.DownloadDir, "localgo-conformance.txt"))
+			if err != nil || len(data) == 0 {
+				t.Errorf("test file not received: %v", err)
+			}
+		})
+	}
+}
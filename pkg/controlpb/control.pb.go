@@ -0,0 +1,1704 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        (unknown)
+// source: localgo/control/v1/control.proto
+
+package controlpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type GetStatusRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetStatusRequest) Reset() {
+	*x = GetStatusRequest{}
+	mi := &file_localgo_control_v1_control_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetStatusRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetStatusRequest) ProtoMessage() {}
+
+func (x *GetStatusRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_localgo_control_v1_control_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetStatusRequest.ProtoReflect.Descriptor instead.
+func (*GetStatusRequest) Descriptor() ([]byte, []int) {
+	return file_localgo_control_v1_control_proto_rawDescGZIP(), []int{0}
+}
+
+type GetStatusResponse struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Sessions       []*Session             `protobuf:"bytes,1,rep,name=sessions,proto3" json:"sessions,omitempty"`
+	Recent         []*Report              `protobuf:"bytes,2,rep,name=recent,proto3" json:"recent,omitempty"` // most recent first
+	QuickSave      bool                   `protobuf:"varint,3,opt,name=quick_save,json=quickSave,proto3" json:"quick_save,omitempty"`
+	QuickSaveUntil *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=quick_save_until,json=quickSaveUntil,proto3" json:"quick_save_until,omitempty"` // unset when on until turned off
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *GetStatusResponse) Reset() {
+	*x = GetStatusResponse{}
+	mi := &file_localgo_control_v1_control_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetStatusResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetStatusResponse) ProtoMessage() {}
+
+func (x *GetStatusResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_localgo_control_v1_control_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetStatusResponse.ProtoReflect.Descriptor instead.
+func (*GetStatusResponse) Descriptor() ([]byte, []int) {
+	return file_localgo_control_v1_control_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *GetStatusResponse) GetSessions() []*Session {
+	if x != nil {
+		return x.Sessions
+	}
+	return nil
+}
+
+func (x *GetStatusResponse) GetRecent() []*Report {
+	if x != nil {
+		return x.Recent
+	}
+	return nil
+}
+
+func (x *GetStatusResponse) GetQuickSave() bool {
+	if x != nil {
+		return x.QuickSave
+	}
+	return false
+}
+
+func (x *GetStatusResponse) GetQuickSaveUntil() *timestamppb.Timestamp {
+	if x != nil {
+		return x.QuickSaveUntil
+	}
+	return nil
+}
+
+// Session is an active receive session.
+type Session struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	SessionId     string                 `protobuf:"bytes,1,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
+	Sender        string                 `protobuf:"bytes,2,opt,name=sender,proto3" json:"sender,omitempty"`
+	SenderIp      string                 `protobuf:"bytes,3,opt,name=sender_ip,json=senderIp,proto3" json:"sender_ip,omitempty"`
+	StartedAt     *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=started_at,json=startedAt,proto3" json:"started_at,omitempty"`
+	Size          int64                  `protobuf:"varint,5,opt,name=size,proto3" json:"size,omitempty"`   // of all files
+	Bytes         int64                  `protobuf:"varint,6,opt,name=bytes,proto3" json:"bytes,omitempty"` // received so far, of all files
+	Files         []*FileStatus          `protobuf:"bytes,7,rep,name=files,proto3" json:"files,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Session) Reset() {
+	*x = Session{}
+	mi := &file_localgo_control_v1_control_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Session) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Session) ProtoMessage() {}
+
+func (x *Session) ProtoReflect() protoreflect.Message {
+	mi := &file_localgo_control_v1_control_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Session.ProtoReflect.Descriptor instead.
+func (*Session) Descriptor() ([]byte, []int) {
+	return file_localgo_control_v1_control_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Session) GetSessionId() string {
+	if x != nil {
+		return x.SessionId
+	}
+	return ""
+}
+
+func (x *Session) GetSender() string {
+	if x != nil {
+		return x.Sender
+	}
+	return ""
+}
+
+func (x *Session) GetSenderIp() string {
+	if x != nil {
+		return x.SenderIp
+	}
+	return ""
+}
+
+func (x *Session) GetStartedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.StartedAt
+	}
+	return nil
+}
+
+func (x *Session) GetSize() int64 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+func (x *Session) GetBytes() int64 {
+	if x != nil {
+		return x.Bytes
+	}
+	return 0
+}
+
+func (x *Session) GetFiles() []*FileStatus {
+	if x != nil {
+		return x.Files
+	}
+	return nil
+}
+
+// FileStatus is the progress of one file.
+type FileStatus struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Size          int64                  `protobuf:"varint,2,opt,name=size,proto3" json:"size,omitempty"`
+	Bytes         int64                  `protobuf:"varint,3,opt,name=bytes,proto3" json:"bytes,omitempty"`  // received so far
+	Status        string                 `protobuf:"bytes,4,opt,name=status,proto3" json:"status,omitempty"` // pending, receiving, received or failed
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FileStatus) Reset() {
+	*x = FileStatus{}
+	mi := &file_localgo_control_v1_control_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FileStatus) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FileStatus) ProtoMessage() {}
+
+func (x *FileStatus) ProtoReflect() protoreflect.Message {
+	mi := &file_localgo_control_v1_control_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FileStatus.ProtoReflect.Descriptor instead.
+func (*FileStatus) Descriptor() ([]byte, []int) {
+	return file_localgo_control_v1_control_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *FileStatus) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *FileStatus) GetSize() int64 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+func (x *FileStatus) GetBytes() int64 {
+	if x != nil {
+		return x.Bytes
+	}
+	return 0
+}
+
+func (x *FileStatus) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+// Report summarizes a transfer that ended.
+type Report struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	SessionId     string                 `protobuf:"bytes,1,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
+	Peer          string                 `protobuf:"bytes,2,opt,name=peer,proto3" json:"peer,omitempty"`
+	StartedAt     *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=started_at,json=startedAt,proto3" json:"started_at,omitempty"`
+	FinishedAt    *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=finished_at,json=finishedAt,proto3" json:"finished_at,omitempty"`
+	Transferred   int32                  `protobuf:"varint,5,opt,name=transferred,proto3" json:"transferred,omitempty"`
+	Failed        int32                  `protobuf:"varint,6,opt,name=failed,proto3" json:"failed,omitempty"`
+	Skipped       int32                  `protobuf:"varint,7,opt,name=skipped,proto3" json:"skipped,omitempty"`
+	Bytes         int64                  `protobuf:"varint,8,opt,name=bytes,proto3" json:"bytes,omitempty"`
+	Reason        string                 `protobuf:"bytes,9,opt,name=reason,proto3" json:"reason,omitempty"` // declined, busy or cancelled, when not completed
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Report) Reset() {
+	*x = Report{}
+	mi := &file_localgo_control_v1_control_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Report) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Report) ProtoMessage() {}
+
+func (x *Report) ProtoReflect() protoreflect.Message {
+	mi := &file_localgo_control_v1_control_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Report.ProtoReflect.Descriptor instead.
+func (*Report) Descriptor() ([]byte, []int) {
+	return file_localgo_control_v1_control_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *Report) GetSessionId() string {
+	if x != nil {
+		return x.SessionId
+	}
+	return ""
+}
+
+func (x *Report) GetPeer() string {
+	if x != nil {
+		return x.Peer
+	}
+	return ""
+}
+
+func (x *Report) GetStartedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.StartedAt
+	}
+	return nil
+}
+
+func (x *Report) GetFinishedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.FinishedAt
+	}
+	return nil
+}
+
+func (x *Report) GetTransferred() int32 {
+	if x != nil {
+		return x.Transferred
+	}
+	return 0
+}
+
+func (x *Report) GetFailed() int32 {
+	if x != nil {
+		return x.Failed
+	}
+	return 0
+}
+
+func (x *Report) GetSkipped() int32 {
+	if x != nil {
+		return x.Skipped
+	}
+	return 0
+}
+
+func (x *Report) GetBytes() int64 {
+	if x != nil {
+		return x.Bytes
+	}
+	return 0
+}
+
+func (x *Report) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+type CancelSessionRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	SessionId     string                 `protobuf:"bytes,1,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CancelSessionRequest) Reset() {
+	*x = CancelSessionRequest{}
+	mi := &file_localgo_control_v1_control_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CancelSessionRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CancelSessionRequest) ProtoMessage() {}
+
+func (x *CancelSessionRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_localgo_control_v1_control_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CancelSessionRequest.ProtoReflect.Descriptor instead.
+func (*CancelSessionRequest) Descriptor() ([]byte, []int) {
+	return file_localgo_control_v1_control_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *CancelSessionRequest) GetSessionId() string {
+	if x != nil {
+		return x.SessionId
+	}
+	return ""
+}
+
+type CancelSessionResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CancelSessionResponse) Reset() {
+	*x = CancelSessionResponse{}
+	mi := &file_localgo_control_v1_control_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CancelSessionResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CancelSessionResponse) ProtoMessage() {}
+
+func (x *CancelSessionResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_localgo_control_v1_control_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CancelSessionResponse.ProtoReflect.Descriptor instead.
+func (*CancelSessionResponse) Descriptor() ([]byte, []int) {
+	return file_localgo_control_v1_control_proto_rawDescGZIP(), []int{6}
+}
+
+type ListDevicesRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListDevicesRequest) Reset() {
+	*x = ListDevicesRequest{}
+	mi := &file_localgo_control_v1_control_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListDevicesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListDevicesRequest) ProtoMessage() {}
+
+func (x *ListDevicesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_localgo_control_v1_control_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListDevicesRequest.ProtoReflect.Descriptor instead.
+func (*ListDevicesRequest) Descriptor() ([]byte, []int) {
+	return file_localgo_control_v1_control_proto_rawDescGZIP(), []int{7}
+}
+
+type ListDevicesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Devices       []*Device              `protobuf:"bytes,1,rep,name=devices,proto3" json:"devices,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListDevicesResponse) Reset() {
+	*x = ListDevicesResponse{}
+	mi := &file_localgo_control_v1_control_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListDevicesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListDevicesResponse) ProtoMessage() {}
+
+func (x *ListDevicesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_localgo_control_v1_control_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListDevicesResponse.ProtoReflect.Descriptor instead.
+func (*ListDevicesResponse) Descriptor() ([]byte, []int) {
+	return file_localgo_control_v1_control_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *ListDevicesResponse) GetDevices() []*Device {
+	if x != nil {
+		return x.Devices
+	}
+	return nil
+}
+
+type Device struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Alias         string                 `protobuf:"bytes,1,opt,name=alias,proto3" json:"alias,omitempty"`
+	Fingerprint   string                 `protobuf:"bytes,2,opt,name=fingerprint,proto3" json:"fingerprint,omitempty"`
+	Ip            string                 `protobuf:"bytes,3,opt,name=ip,proto3" json:"ip,omitempty"`
+	Port          int32                  `protobuf:"varint,4,opt,name=port,proto3" json:"port,omitempty"`
+	Protocol      string                 `protobuf:"bytes,5,opt,name=protocol,proto3" json:"protocol,omitempty"` // http or https
+	DeviceModel   string                 `protobuf:"bytes,6,opt,name=device_model,json=deviceModel,proto3" json:"device_model,omitempty"`
+	DeviceType    string                 `protobuf:"bytes,7,opt,name=device_type,json=deviceType,proto3" json:"device_type,omitempty"` // mobile, desktop, web, headless or server
+	LastSeen      *timestamppb.Timestamp `protobuf:"bytes,8,opt,name=last_seen,json=lastSeen,proto3" json:"last_seen,omitempty"`
+	Available     bool                   `protobuf:"varint,9,opt,name=available,proto3" json:"available,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Device) Reset() {
+	*x = Device{}
+	mi := &file_localgo_control_v1_control_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Device) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Device) ProtoMessage() {}
+
+func (x *Device) ProtoReflect() protoreflect.Message {
+	mi := &file_localgo_control_v1_control_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Device.ProtoReflect.Descriptor instead.
+func (*Device) Descriptor() ([]byte, []int) {
+	return file_localgo_control_v1_control_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *Device) GetAlias() string {
+	if x != nil {
+		return x.Alias
+	}
+	return ""
+}
+
+func (x *Device) GetFingerprint() string {
+	if x != nil {
+		return x.Fingerprint
+	}
+	return ""
+}
+
+func (x *Device) GetIp() string {
+	if x != nil {
+		return x.Ip
+	}
+	return ""
+}
+
+func (x *Device) GetPort() int32 {
+	if x != nil {
+		return x.Port
+	}
+	return 0
+}
+
+func (x *Device) GetProtocol() string {
+	if x != nil {
+		return x.Protocol
+	}
+	return ""
+}
+
+func (x *Device) GetDeviceModel() string {
+	if x != nil {
+		return x.DeviceModel
+	}
+	return ""
+}
+
+func (x *Device) GetDeviceType() string {
+	if x != nil {
+		return x.DeviceType
+	}
+	return ""
+}
+
+func (x *Device) GetLastSeen() *timestamppb.Timestamp {
+	if x != nil {
+		return x.LastSeen
+	}
+	return nil
+}
+
+func (x *Device) GetAvailable() bool {
+	if x != nil {
+		return x.Available
+	}
+	return false
+}
+
+type SendRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Files []string               `protobuf:"bytes,1,rep,name=files,proto3" json:"files,omitempty"` // absolute paths
+	// The recipient: an alias, a fingerprint or both, or an IP address.
+	To             string   `protobuf:"bytes,2,opt,name=to,proto3" json:"to,omitempty"`
+	Fingerprint    string   `protobuf:"bytes,3,opt,name=fingerprint,proto3" json:"fingerprint,omitempty"`
+	Ip             string   `protobuf:"bytes,4,opt,name=ip,proto3" json:"ip,omitempty"`
+	Port           int32    `protobuf:"varint,5,opt,name=port,proto3" json:"port,omitempty"`
+	Priority       int32    `protobuf:"varint,6,opt,name=priority,proto3" json:"priority,omitempty"` // higher runs first
+	MaxAttempts    int32    `protobuf:"varint,7,opt,name=max_attempts,json=maxAttempts,proto3" json:"max_attempts,omitempty"`
+	Excludes       []string `protobuf:"bytes,8,rep,name=excludes,proto3" json:"excludes,omitempty"`                                    // glob patterns skipped inside directories
+	SkipDuplicates bool     `protobuf:"varint,9,opt,name=skip_duplicates,json=skipDuplicates,proto3" json:"skip_duplicates,omitempty"` // skip files the recipient already received
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *SendRequest) Reset() {
+	*x = SendRequest{}
+	mi := &file_localgo_control_v1_control_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SendRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SendRequest) ProtoMessage() {}
+
+func (x *SendRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_localgo_control_v1_control_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SendRequest.ProtoReflect.Descriptor instead.
+func (*SendRequest) Descriptor() ([]byte, []int) {
+	return file_localgo_control_v1_control_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *SendRequest) GetFiles() []string {
+	if x != nil {
+		return x.Files
+	}
+	return nil
+}
+
+func (x *SendRequest) GetTo() string {
+	if x != nil {
+		return x.To
+	}
+	return ""
+}
+
+func (x *SendRequest) GetFingerprint() string {
+	if x != nil {
+		return x.Fingerprint
+	}
+	return ""
+}
+
+func (x *SendRequest) GetIp() string {
+	if x != nil {
+		return x.Ip
+	}
+	return ""
+}
+
+func (x *SendRequest) GetPort() int32 {
+	if x != nil {
+		return x.Port
+	}
+	return 0
+}
+
+func (x *SendRequest) GetPriority() int32 {
+	if x != nil {
+		return x.Priority
+	}
+	return 0
+}
+
+func (x *SendRequest) GetMaxAttempts() int32 {
+	if x != nil {
+		return x.MaxAttempts
+	}
+	return 0
+}
+
+func (x *SendRequest) GetExcludes() []string {
+	if x != nil {
+		return x.Excludes
+	}
+	return nil
+}
+
+func (x *SendRequest) GetSkipDuplicates() bool {
+	if x != nil {
+		return x.SkipDuplicates
+	}
+	return false
+}
+
+// SendJob is a job in the send queue.
+type SendJob struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Files         []string               `protobuf:"bytes,2,rep,name=files,proto3" json:"files,omitempty"`
+	Recipient     string                 `protobuf:"bytes,3,opt,name=recipient,proto3" json:"recipient,omitempty"`
+	Priority      int32                  `protobuf:"varint,4,opt,name=priority,proto3" json:"priority,omitempty"`
+	Status        string                 `protobuf:"bytes,5,opt,name=status,proto3" json:"status,omitempty"` // queued, sending, sent or failed
+	Attempts      int32                  `protobuf:"varint,6,opt,name=attempts,proto3" json:"attempts,omitempty"`
+	MaxAttempts   int32                  `protobuf:"varint,7,opt,name=max_attempts,json=maxAttempts,proto3" json:"max_attempts,omitempty"`
+	Error         string                 `protobuf:"bytes,8,opt,name=error,proto3" json:"error,omitempty"` // why the last attempt failed
+	CreatedAt     *timestamppb.Timestamp `protobuf:"bytes,9,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SendJob) Reset() {
+	*x = SendJob{}
+	mi := &file_localgo_control_v1_control_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SendJob) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SendJob) ProtoMessage() {}
+
+func (x *SendJob) ProtoReflect() protoreflect.Message {
+	mi := &file_localgo_control_v1_control_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SendJob.ProtoReflect.Descriptor instead.
+func (*SendJob) Descriptor() ([]byte, []int) {
+	return file_localgo_control_v1_control_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *SendJob) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *SendJob) GetFiles() []string {
+	if x != nil {
+		return x.Files
+	}
+	return nil
+}
+
+func (x *SendJob) GetRecipient() string {
+	if x != nil {
+		return x.Recipient
+	}
+	return ""
+}
+
+func (x *SendJob) GetPriority() int32 {
+	if x != nil {
+		return x.Priority
+	}
+	return 0
+}
+
+func (x *SendJob) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *SendJob) GetAttempts() int32 {
+	if x != nil {
+		return x.Attempts
+	}
+	return 0
+}
+
+func (x *SendJob) GetMaxAttempts() int32 {
+	if x != nil {
+		return x.MaxAttempts
+	}
+	return 0
+}
+
+func (x *SendJob) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *SendJob) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+type ListSendJobsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListSendJobsRequest) Reset() {
+	*x = ListSendJobsRequest{}
+	mi := &file_localgo_control_v1_control_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListSendJobsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListSendJobsRequest) ProtoMessage() {}
+
+func (x *ListSendJobsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_localgo_control_v1_control_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListSendJobsRequest.ProtoReflect.Descriptor instead.
+func (*ListSendJobsRequest) Descriptor() ([]byte, []int) {
+	return file_localgo_control_v1_control_proto_rawDescGZIP(), []int{12}
+}
+
+type ListSendJobsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Jobs          []*SendJob             `protobuf:"bytes,1,rep,name=jobs,proto3" json:"jobs,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListSendJobsResponse) Reset() {
+	*x = ListSendJobsResponse{}
+	mi := &file_localgo_control_v1_control_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListSendJobsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListSendJobsResponse) ProtoMessage() {}
+
+func (x *ListSendJobsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_localgo_control_v1_control_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListSendJobsResponse.ProtoReflect.Descriptor instead.
+func (*ListSendJobsResponse) Descriptor() ([]byte, []int) {
+	return file_localgo_control_v1_control_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *ListSendJobsResponse) GetJobs() []*SendJob {
+	if x != nil {
+		return x.Jobs
+	}
+	return nil
+}
+
+// TransferRequest is an incoming transfer waiting to be accepted or
+// declined.
+type TransferRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Sender        *Device                `protobuf:"bytes,2,opt,name=sender,proto3" json:"sender,omitempty"`
+	Files         []*OfferedFile         `protobuf:"bytes,3,rep,name=files,proto3" json:"files,omitempty"`
+	ReceivedAt    *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=received_at,json=receivedAt,proto3" json:"received_at,omitempty"`
+	ExpiresAt     *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at,omitempty"` // declined if not answered by then
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TransferRequest) Reset() {
+	*x = TransferRequest{}
+	mi := &file_localgo_control_v1_control_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TransferRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TransferRequest) ProtoMessage() {}
+
+func (x *TransferRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_localgo_control_v1_control_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TransferRequest.ProtoReflect.Descriptor instead.
+func (*TransferRequest) Descriptor() ([]byte, []int) {
+	return file_localgo_control_v1_control_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *TransferRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *TransferRequest) GetSender() *Device {
+	if x != nil {
+		return x.Sender
+	}
+	return nil
+}
+
+func (x *TransferRequest) GetFiles() []*OfferedFile {
+	if x != nil {
+		return x.Files
+	}
+	return nil
+}
+
+func (x *TransferRequest) GetReceivedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ReceivedAt
+	}
+	return nil
+}
+
+func (x *TransferRequest) GetExpiresAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ExpiresAt
+	}
+	return nil
+}
+
+type OfferedFile struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Name          string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Size          int64                  `protobuf:"varint,3,opt,name=size,proto3" json:"size,omitempty"`
+	Type          string                 `protobuf:"bytes,4,opt,name=type,proto3" json:"type,omitempty"`
+	Preview       string                 `protobuf:"bytes,5,opt,name=preview,proto3" json:"preview,omitempty"` // text of a message, or an image thumbnail as a data URL
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *OfferedFile) Reset() {
+	*x = OfferedFile{}
+	mi := &file_localgo_control_v1_control_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *OfferedFile) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*OfferedFile) ProtoMessage() {}
+
+func (x *OfferedFile) ProtoReflect() protoreflect.Message {
+	mi := &file_localgo_control_v1_control_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use OfferedFile.ProtoReflect.Descriptor instead.
+func (*OfferedFile) Descriptor() ([]byte, []int) {
+	return file_localgo_control_v1_control_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *OfferedFile) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *OfferedFile) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *OfferedFile) GetSize() int64 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+func (x *OfferedFile) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *OfferedFile) GetPreview() string {
+	if x != nil {
+		return x.Preview
+	}
+	return ""
+}
+
+type ListTransferRequestsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListTransferRequestsRequest) Reset() {
+	*x = ListTransferRequestsRequest{}
+	mi := &file_localgo_control_v1_control_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListTransferRequestsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListTransferRequestsRequest) ProtoMessage() {}
+
+func (x *ListTransferRequestsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_localgo_control_v1_control_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListTransferRequestsRequest.ProtoReflect.Descriptor instead.
+func (*ListTransferRequestsRequest) Descriptor() ([]byte, []int) {
+	return file_localgo_control_v1_control_proto_rawDescGZIP(), []int{16}
+}
+
+type ListTransferRequestsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Requests      []*TransferRequest     `protobuf:"bytes,1,rep,name=requests,proto3" json:"requests,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListTransferRequestsResponse) Reset() {
+	*x = ListTransferRequestsResponse{}
+	mi := &file_localgo_control_v1_control_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListTransferRequestsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListTransferRequestsResponse) ProtoMessage() {}
+
+func (x *ListTransferRequestsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_localgo_control_v1_control_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListTransferRequestsResponse.ProtoReflect.Descriptor instead.
+func (*ListTransferRequestsResponse) Descriptor() ([]byte, []int) {
+	return file_localgo_control_v1_control_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *ListTransferRequestsResponse) GetRequests() []*TransferRequest {
+	if x != nil {
+		return x.Requests
+	}
+	return nil
+}
+
+type AnswerTransferRequestRequest struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	Id     string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Accept bool                   `protobuf:"varint,2,opt,name=accept,proto3" json:"accept,omitempty"`
+	// The files to accept; all of them when empty.
+	FileIds       []string `protobuf:"bytes,3,rep,name=file_ids,json=fileIds,proto3" json:"file_ids,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AnswerTransferRequestRequest) Reset() {
+	*x = AnswerTransferRequestRequest{}
+	mi := &file_localgo_control_v1_control_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AnswerTransferRequestRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AnswerTransferRequestRequest) ProtoMessage() {}
+
+func (x *AnswerTransferRequestRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_localgo_control_v1_control_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AnswerTransferRequestRequest.ProtoReflect.Descriptor instead.
+func (*AnswerTransferRequestRequest) Descriptor() ([]byte, []int) {
+	return file_localgo_control_v1_control_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *AnswerTransferRequestRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *AnswerTransferRequestRequest) GetAccept() bool {
+	if x != nil {
+		return x.Accept
+	}
+	return false
+}
+
+func (x *AnswerTransferRequestRequest) GetFileIds() []string {
+	if x != nil {
+		return x.FileIds
+	}
+	return nil
+}
+
+type AnswerTransferRequestResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AnswerTransferRequestResponse) Reset() {
+	*x = AnswerTransferRequestResponse{}
+	mi := &file_localgo_control_v1_control_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AnswerTransferRequestResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AnswerTransferRequestResponse) ProtoMessage() {}
+
+func (x *AnswerTransferRequestResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_localgo_control_v1_control_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AnswerTransferRequestResponse.ProtoReflect.Descriptor instead.
+func (*AnswerTransferRequestResponse) Descriptor() ([]byte, []int) {
+	return file_localgo_control_v1_control_proto_rawDescGZIP(), []int{19}
+}
+
+type WatchEventsRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Answer incoming transfers: while the stream is open, transfers that
+	// would be prompted for in the terminal are held as transfer requests,
+	// announced by transfer_requested events, until answered.
+	AnswerTransfers bool `protobuf:"varint,1,opt,name=answer_transfers,json=answerTransfers,proto3" json:"answer_transfers,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *WatchEventsRequest) Reset() {
+	*x = WatchEventsRequest{}
+	mi := &file_localgo_control_v1_control_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchEventsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchEventsRequest) ProtoMessage() {}
+
+func (x *WatchEventsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_localgo_control_v1_control_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchEventsRequest.ProtoReflect.Descriptor instead.
+func (*WatchEventsRequest) Descriptor() ([]byte, []int) {
+	return file_localgo_control_v1_control_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *WatchEventsRequest) GetAnswerTransfers() bool {
+	if x != nil {
+		return x.AnswerTransfers
+	}
+	return false
+}
+
+// Event is server activity. The types are those of the activity stream at
+// /api/localgo/events, and transfer_requested.
+type Event struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	Id              uint64                 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Type            string                 `protobuf:"bytes,2,opt,name=type,proto3" json:"type,omitempty"`
+	Time            *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=time,proto3" json:"time,omitempty"`
+	SessionId       string                 `protobuf:"bytes,4,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
+	File            string                 `protobuf:"bytes,5,opt,name=file,proto3" json:"file,omitempty"`
+	Path            string                 `protobuf:"bytes,6,opt,name=path,proto3" json:"path,omitempty"`
+	Files           int32                  `protobuf:"varint,7,opt,name=files,proto3" json:"files,omitempty"`
+	Bytes           int64                  `protobuf:"varint,8,opt,name=bytes,proto3" json:"bytes,omitempty"`
+	Total           int64                  `protobuf:"varint,9,opt,name=total,proto3" json:"total,omitempty"`
+	Device          *Device                `protobuf:"bytes,10,opt,name=device,proto3" json:"device,omitempty"`
+	TransferRequest *TransferRequest       `protobuf:"bytes,11,opt,name=transfer_request,json=transferRequest,proto3" json:"transfer_request,omitempty"` // for transfer_requested
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *Event) Reset() {
+	*x = Event{}
+	mi := &file_localgo_control_v1_control_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Event) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Event) ProtoMessage() {}
+
+func (x *Event) ProtoReflect() protoreflect.Message {
+	mi := &file_localgo_control_v1_control_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Event.ProtoReflect.Descriptor instead.
+func (*Event) Descriptor() ([]byte, []int) {
+	return file_localgo_control_v1_control_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *Event) GetId() uint64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *Event) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *Event) GetTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Time
+	}
+	return nil
+}
+
+func (x *Event) GetSessionId() string {
+	if x != nil {
+		return x.SessionId
+	}
+	return ""
+}
+
+func (x *Event) GetFile() string {
+	if x != nil {
+		return x.File
+	}
+	return ""
+}
+
+func (x *Event) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+func (x *Event) GetFiles() int32 {
+	if x != nil {
+		return x.Files
+	}
+	return 0
+}
+
+func (x *Event) GetBytes() int64 {
+	if x != nil {
+		return x.Bytes
+	}
+	return 0
+}
+
+func (x *Event) GetTotal() int64 {
+	if x != nil {
+		return x.Total
+	}
+	return 0
+}
+
+func (x *Event) GetDevice() *Device {
+	if x != nil {
+		return x.Device
+	}
+	return nil
+}
+
+func (x *Event) GetTransferRequest() *TransferRequest {
+	if x != nil {
+		return x.TransferRequest
+	}
+	return nil
+}
+
+var File_localgo_control_v1_control_proto protoreflect.FileDescriptor
+
+const file_localgo_control_v1_control_proto_rawDesc = "" +
+	"\n" +
+	" localgo/control/v1/control.proto\x12\x12localgo.control.v1\x1a\x1fgoogle/protobuf/timestamp.proto\"\x12\n" +
+	"\x10GetStatusRequest\"\xe5\x01\n" +
+	"\x11GetStatusResponse\x127\n" +
+	"\bsessions\x18\x01 \x03(\v2\x1b.localgo.control.v1.SessionR\bsessions\x122\n" +
+	"\x06recent\x18\x02 \x03(\v2\x1a.localgo.control.v1.ReportR\x06recent\x12\x1d\n" +
+	"\n" +
+	"quick_save\x18\x03 \x01(\bR\tquickSave\x12D\n" +
+	"\x10quick_save_until\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\x0equickSaveUntil\"\xf8\x01\n" +
+	"\aSession\x12\x1d\n" +
+	"\n" +
+	"session_id\x18\x01 \x01(\tR\tsessionId\x12\x16\n" +
+	"\x06sender\x18\x02 \x01(\tR\x06sender\x12\x1b\n" +
+	"\tsender_ip\x18\x03 \x01(\tR\bsenderIp\x129\n" +
+	"\n" +
+	"started_at\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\tstartedAt\x12\x12\n" +
+	"\x04size\x18\x05 \x01(\x03R\x04size\x12\x14\n" +
+	"\x05bytes\x18\x06 \x01(\x03R\x05bytes\x124\n" +
+	"\x05files\x18\a \x03(\v2\x1e.localgo.control.v1.FileStatusR\x05files\"b\n" +
+	"\n" +
+	"FileStatus\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x12\n" +
+	"\x04size\x18\x02 \x01(\x03R\x04size\x12\x14\n" +
+	"\x05bytes\x18\x03 \x01(\x03R\x05bytes\x12\x16\n" +
+	"\x06status\x18\x04 \x01(\tR\x06status\"\xb5\x02\n" +
+	"\x06Report\x12\x1d\n" +
+	"\n" +
+	"session_id\x18\x01 \x01(\tR\tsessionId\x12\x12\n" +
+	"\x04peer\x18\x02 \x01(\tR\x04peer\x129\n" +
+	"\n" +
+	"started_at\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\tstartedAt\x12;\n" +
+	"\vfinished_at\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\n" +
+	"finishedAt\x12 \n" +
+	"\vtransferred\x18\x05 \x01(\x05R\vtransferred\x12\x16\n" +
+	"\x06failed\x18\x06 \x01(\x05R\x06failed\x12\x18\n" +
+	"\askipped\x18\a \x01(\x05R\askipped\x12\x14\n" +
+	"\x05bytes\x18\b \x01(\x03R\x05bytes\x12\x16\n" +
+	"\x06reason\x18\t \x01(\tR\x06reason\"5\n" +
+	"\x14CancelSessionRequest\x12\x1d\n" +
+	"\n" +
+	"session_id\x18\x01 \x01(\tR\tsessionId\"\x17\n" +
+	"\x15CancelSessionResponse\"\x14\n" +
+	"\x12ListDevicesRequest\"K\n" +
+	"\x13ListDevicesResponse\x124\n" +
+	"\adevices\x18\x01 \x03(\v2\x1a.localgo.control.v1.DeviceR\adevices\"\x9b\x02\n" +
+	"\x06Device\x12\x14\n" +
+	"\x05alias\x18\x01 \x01(\tR\x05alias\x12 \n" +
+	"\vfingerprint\x18\x02 \x01(\tR\vfingerprint\x12\x0e\n" +
+	"\x02ip\x18\x03 \x01(\tR\x02ip\x12\x12\n" +
+	"\x04port\x18\x04 \x01(\x05R\x04port\x12\x1a\n" +
+	"\bprotocol\x18\x05 \x01(\tR\bprotocol\x12!\n" +
+	"\fdevice_model\x18\x06 \x01(\tR\vdeviceModel\x12\x1f\n" +
+	"\vdevice_type\x18\a \x01(\tR\n" +
+	"deviceType\x127\n" +
+	"\tlast_seen\x18\b \x01(\v2\x1a.google.protobuf.TimestampR\blastSeen\x12\x1c\n" +
+	"\tavailable\x18\t \x01(\bR\tavailable\"\xfd\x01\n" +
+	"\vSendRequest\x12\x14\n" +
+	"\x05files\x18\x01 \x03(\tR\x05files\x12\x0e\n" +
+	"\x02to\x18\x02 \x01(\tR\x02to\x12 \n" +
+	"\vfingerprint\x18\x03 \x01(\tR\vfingerprint\x12\x0e\n" +
+	"\x02ip\x18\x04 \x01(\tR\x02ip\x12\x12\n" +
+	"\x04port\x18\x05 \x01(\x05R\x04port\x12\x1a\n" +
+	"\bpriority\x18\x06 \x01(\x05R\bpriority\x12!\n" +
+	"\fmax_attempts\x18\a \x01(\x05R\vmaxAttempts\x12\x1a\n" +
+	"\bexcludes\x18\b \x03(\tR\bexcludes\x12'\n" +
+	"\x0fskip_duplicates\x18\t \x01(\bR\x0eskipDuplicates\"\x91\x02\n" +
+	"\aSendJob\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\x12\x14\n" +
+	"\x05files\x18\x02 \x03(\tR\x05files\x12\x1c\n" +
+	"\trecipient\x18\x03 \x01(\tR\trecipient\x12\x1a\n" +
+	"\bpriority\x18\x04 \x01(\x05R\bpriority\x12\x16\n" +
+	"\x06status\x18\x05 \x01(\tR\x06status\x12\x1a\n" +
+	"\battempts\x18\x06 \x01(\x05R\battempts\x12!\n" +
+	"\fmax_attempts\x18\a \x01(\x05R\vmaxAttempts\x12\x14\n" +
+	"\x05error\x18\b \x01(\tR\x05error\x129\n" +
+	"\n" +
+	"created_at\x18\t \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\"\x15\n" +
+	"\x13ListSendJobsRequest\"G\n" +
+	"\x14ListSendJobsResponse\x12/\n" +
+	"\x04jobs\x18\x01 \x03(\v2\x1b.localgo.control.v1.SendJobR\x04jobs\"\x84\x02\n" +
+	"\x0fTransferRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x122\n" +
+	"\x06sender\x18\x02 \x01(\v2\x1a.localgo.control.v1.DeviceR\x06sender\x125\n" +
+	"\x05files\x18\x03 \x03(\v2\x1f.localgo.control.v1.OfferedFileR\x05files\x12;\n" +
+	"\vreceived_at\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\n" +
+	"receivedAt\x129\n" +
+	"\n" +
+	"expires_at\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\texpiresAt\"s\n" +
+	"\vOfferedFile\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12\x12\n" +
+	"\x04size\x18\x03 \x01(\x03R\x04size\x12\x12\n" +
+	"\x04type\x18\x04 \x01(\tR\x04type\x12\x18\n" +
+	"\apreview\x18\x05 \x01(\tR\apreview\"\x1d\n" +
+	"\x1bListTransferRequestsRequest\"_\n" +
+	"\x1cListTransferRequestsResponse\x12?\n" +
+	"\brequests\x18\x01 \x03(\v2#.localgo.control.v1.TransferRequestR\brequests\"a\n" +
+	"\x1cAnswerTransferRequestRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x16\n" +
+	"\x06accept\x18\x02 \x01(\bR\x06accept\x12\x19\n" +
+	"\bfile_ids\x18\x03 \x03(\tR\afileIds\"\x1f\n" +
+	"\x1dAnswerTransferRequestResponse\"?\n" +
+	"\x12WatchEventsRequest\x12)\n" +
+	"\x10answer_transfers\x18\x01 \x01(\bR\x0fanswerTransfers\"\xe8\x02\n" +
+	"\x05Event\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x04R\x02id\x12\x12\n" +
+	"\x04type\x18\x02 \x01(\tR\x04type\x12.\n" +
+	"\x04time\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\x04time\x12\x1d\n" +
+	"\n" +
+	"session_id\x18\x04 \x01(\tR\tsessionId\x12\x12\n" +
+	"\x04file\x18\x05 \x01(\tR\x04file\x12\x12\n" +
+	"\x04path\x18\x06 \x01(\tR\x04path\x12\x14\n" +
+	"\x05files\x18\a \x01(\x05R\x05files\x12\x14\n" +
+	"\x05bytes\x18\b \x01(\x03R\x05bytes\x12\x14\n" +
+	"\x05total\x18\t \x01(\x03R\x05total\x122\n" +
+	"\x06device\x18\n" +
+	" \x01(\v2\x1a.localgo.control.v1.DeviceR\x06device\x12N\n" +
+	"\x10transfer_request\x18\v \x01(\v2#.localgo.control.v1.TransferRequestR\x0ftransferRequest2\x9f\x06\n" +
+	"\aControl\x12X\n" +
+	"\tGetStatus\x12$.localgo.control.v1.GetStatusRequest\x1a%.localgo.control.v1.GetStatusResponse\x12d\n" +
+	"\rCancelSession\x12(.localgo.control.v1.CancelSessionRequest\x1a).localgo.control.v1.CancelSessionResponse\x12^\n" +
+	"\vListDevices\x12&.localgo.control.v1.ListDevicesRequest\x1a'.localgo.control.v1.ListDevicesResponse\x12D\n" +
+	"\x04Send\x12\x1f.localgo.control.v1.SendRequest\x1a\x1b.localgo.control.v1.SendJob\x12a\n" +
+	"\fListSendJobs\x12'.localgo.control.v1.ListSendJobsRequest\x1a(.localgo.control.v1.ListSendJobsResponse\x12y\n" +
+	"\x14ListTransferRequests\x12/.localgo.control.v1.ListTransferRequestsRequest\x1a0.localgo.control.v1.ListTransferRequestsResponse\x12|\n" +
+	"\x15AnswerTransferRequest\x120.localgo.control.v1.AnswerTransferRequestRequest\x1a1.localgo.control.v1.AnswerTransferRequestResponse\x12R\n" +
+	"\vWatchEvents\x12&.localgo.control.v1.WatchEventsRequest\x1a\x19.localgo.control.v1.Event0\x01B.Z,github.com/bethropolis/localgo/pkg/controlpbb\x06proto3"
+
+var (
+	file_localgo_control_v1_control_proto_rawDescOnce sync.Once
+	file_localgo_control_v1_control_proto_rawDescData []byte
+)
+
+func file_localgo_control_v1_control_proto_rawDescGZIP() []byte {
+	file_localgo_control_v1_control_proto_rawDescOnce.Do(func() {
+		file_localgo_control_v1_control_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_localgo_control_v1_control_proto_rawDesc), len(file_localgo_control_v1_control_proto_rawDesc)))
+	})
+	return file_localgo_control_v1_control_proto_rawDescData
+}
+
+var file_localgo_control_v1_control_proto_msgTypes = make([]protoimpl.MessageInfo, 22)
+var file_localgo_control_v1_control_proto_goTypes = []any{
+	(*GetStatusRequest)(nil),              // 0: localgo.control.v1.GetStatusRequest
+	(*GetStatusResponse)(nil),             // 1: localgo.control.v1.GetStatusResponse
+	(*Session)(nil),                       // 2: localgo.control.v1.Session
+	(*FileStatus)(nil),                    // 3: localgo.control.v1.FileStatus
+	(*Report)(nil),                        // 4: localgo.control.v1.Report
+	(*CancelSessionRequest)(nil),          // 5: localgo.control.v1.CancelSessionRequest
+	(*CancelSessionResponse)(nil),         // 6: localgo.control.v1.CancelSessionResponse
+	(*ListDevicesRequest)(nil),            // 7: localgo.control.v1.ListDevicesRequest
+	(*ListDevicesResponse)(nil),           // 8: localgo.control.v1.ListDevicesResponse
+	(*Device)(nil),                        // 9: localgo.control.v1.Device
+	(*SendRequest)(nil),                   // 10: localgo.control.v1.SendRequest
+	(*SendJob)(nil),                       // 11: localgo.control.v1.SendJob
+	(*ListSendJobsRequest)(nil),           // 12: localgo.control.v1.ListSendJobsRequest
+	(*ListSendJobsResponse)(nil),          // 13: localgo.control.v1.ListSendJobsResponse
+	(*TransferRequest)(nil),               // 14: localgo.control.v1.TransferRequest
+	(*OfferedFile)(nil),                   // 15: localgo.control.v1.OfferedFile
+	(*ListTransferRequestsRequest)(nil),   // 16: localgo.control.v1.ListTransferRequestsRequest
+	(*ListTransferRequestsResponse)(nil),  // 17: localgo.control.v1.ListTransferRequestsResponse
+	(*AnswerTransferRequestRequest)(nil),  // 18: localgo.control.v1.AnswerTransferRequestRequest
+	(*AnswerTransferRequestResponse)(nil), // 19: localgo.control.v1.AnswerTransferRequestResponse
+	(*WatchEventsRequest)(nil),            // 20: localgo.control.v1.WatchEventsRequest
+	(*Event)(nil),                         // 21: localgo.control.v1.Event
+	(*timestamppb.Timestamp)(nil),         // 22: google.protobuf.Timestamp
+}
+var file_localgo_control_v1_control_proto_depIdxs = []int32{
+	2,  // 0: localgo.control.v1.GetStatusResponse.sessions:type_name -> localgo.control.v1.Session
+	4,  // 1: localgo.control.v1.GetStatusResponse.recent:type_name -> localgo.control.v1.Report
+	22, // 2: localgo.control.v1.GetStatusResponse.quick_save_until:type_name -> google.protobuf.Timestamp
+	22, // 3: localgo.control.v1.Session.started_at:type_name -> google.protobuf.Timestamp
+	3,  // 4: localgo.control.v1.Session.files:type_name -> localgo.control.v1.FileStatus
+	22, // 5: localgo.control.v1.Report.started_at:type_name -> google.protobuf.Timestamp
+	22, // 6: localgo.control.v1.Report.finished_at:type_name -> google.protobuf.Timestamp
+	9,  // 7: localgo.control.v1.ListDevicesResponse.devices:type_name -> localgo.control.v1.Device
+	22, // 8: localgo.control.v1.Device.last_seen:type_name -> google.protobuf.Timestamp
+	22, // 9: localgo.control.v1.SendJob.created_at:type_name -> google.protobuf.Timestamp
+	11, // 10: localgo.control.v1.ListSendJobsResponse.jobs:type_name -> localgo.control.v1.SendJob
+	9,  // 11: localgo.control.v1.TransferRequest.sender:type_name -> localgo.control.v1.Device
+	15, // 12: localgo.control.v1.TransferRequest.files:type_name -> localgo.control.v1.OfferedFile
+	22, // 13: localgo.control.v1.TransferRequest.received_at:type_name -> google.protobuf.Timestamp
+	22, // 14: localgo.control.v1.TransferRequest.expires_at:type_name -> google.protobuf.Timestamp
+	14, // 15: localgo.control.v1.ListTransferRequestsResponse.requests:type_name -> localgo.control.v1.TransferRequest
+	22, // 16: localgo.control.v1.Event.time:type_name -> google.protobuf.Timestamp
+	9,  // 17: localgo.control.v1.Event.device:type_name -> localgo.control.v1.Device
+	14, // 18: localgo.control.v1.Event.transfer_request:type_name -> localgo.control.v1.TransferRequest
+	0,  // 19: localgo.control.v1.Control.GetStatus:input_type -> localgo.control.v1.GetStatusRequest
+	5,  // 20: localgo.control.v1.Control.CancelSession:input_type -> localgo.control.v1.CancelSessionRequest
+	7,  // 21: localgo.control.v1.Control.ListDevices:input_type -> localgo.control.v1.ListDevicesRequest
+	10, // 22: localgo.control.v1.Control.Send:input_type -> localgo.control.v1.SendRequest
+	12, // 23: localgo.control.v1.Control.ListSendJobs:input_type -> localgo.control.v1.ListSendJobsRequest
+	16, // 24: localgo.control.v1.Control.ListTransferRequests:input_type -> localgo.control.v1.ListTransferRequestsRequest
+	18, // 25: localgo.control.v1.Control.AnswerTransferRequest:input_type -> localgo.control.v1.AnswerTransferRequestRequest
+	20, // 26: localgo.control.v1.Control.WatchEvents:input_type -> localgo.control.v1.WatchEventsRequest
+	1,  // 27: localgo.control.v1.Control.GetStatus:output_type -> localgo.control.v1.GetStatusResponse
+	6,  // 28: localgo.control.v1.Control.CancelSession:output_type -> localgo.control.v1.CancelSessionResponse
+	8,  // 29: localgo.control.v1.Control.ListDevices:output_type -> localgo.control.v1.ListDevicesResponse
+	11, // 30: localgo.control.v1.Control.Send:output_type -> localgo.control.v1.SendJob
+	13, // 31: localgo.control.v1.Control.ListSendJobs:output_type -> localgo.control.v1.ListSendJobsResponse
+	17, // 32: localgo.control.v1.Control.ListTransferRequests:output_type -> localgo.control.v1.ListTransferRequestsResponse
+	19, // 33: localgo.control.v1.Control.AnswerTransferRequest:output_type -> localgo.control.v1.AnswerTransferRequestResponse
+	21, // 34: localgo.control.v1.Control.WatchEvents:output_type -> localgo.control.v1.Event
+	27, // [27:35] is the sub-list for method output_type
+	19, // [19:27] is the sub-list for method input_type
+	19, // [19:19] is the sub-list for extension type_name
+	19, // [19:19] is the sub-list for extension extendee
+	0,  // [0:19] is the sub-list for field type_name
+}
+
+func init() { file_localgo_control_v1_control_proto_init() }
+func file_localgo_control_v1_control_proto_init() {
+	if File_localgo_control_v1_control_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_localgo_control_v1_control_proto_rawDesc), len(file_localgo_control_v1_control_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   22,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_localgo_control_v1_control_proto_goTypes,
+		DependencyIndexes: file_localgo_control_v1_control_proto_depIdxs,
+		MessageInfos:      file_localgo_control_v1_control_proto_msgTypes,
+	}.Build()
+	File_localgo_control_v1_control_proto = out.File
+	file_localgo_control_v1_control_proto_goTypes = nil
+	file_localgo_control_v1_control_proto_depIdxs = nil
+}
@@ -0,0 +1,435 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: localgo/control/v1/control.proto
+
+package controlpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	Control_GetStatus_FullMethodName             = "/localgo.control.v1.Control/GetStatus"
+	Control_CancelSession_FullMethodName         = "/localgo.control.v1.Control/CancelSession"
+	Control_ListDevices_FullMethodName           = "/localgo.control.v1.Control/ListDevices"
+	Control_Send_FullMethodName                  = "/localgo.control.v1.Control/Send"
+	Control_ListSendJobs_FullMethodName          = "/localgo.control.v1.Control/ListSendJobs"
+	Control_ListTransferRequests_FullMethodName  = "/localgo.control.v1.Control/ListTransferRequests"
+	Control_AnswerTransferRequest_FullMethodName = "/localgo.control.v1.Control/AnswerTransferRequest"
+	Control_WatchEvents_FullMethodName           = "/localgo.control.v1.Control/WatchEvents"
+)
+
+// ControlClient is the client API for Control service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// Control is the control interface of a running LocalGo server, for GUIs
+// and other integrations. It mirrors the admin REST API under /api/localgo
+// and adds answering incoming transfers. Generate clients for other
+// languages from this file; the Go code is in pkg/controlpb (make proto).
+//
+// The interface is served only where the server is told to, with the
+// control_grpc setting: a unix socket usable only by the user running the
+// server, or a loopback address. Over a loopback address every call needs
+// the admin token (localgo token) in the "authorization" metadata, as
+// "Bearer <token>".
+type ControlClient interface {
+	// GetStatus reports the active receive sessions with per-file progress,
+	// and the transfers that ended last.
+	GetStatus(ctx context.Context, in *GetStatusRequest, opts ...grpc.CallOption) (*GetStatusResponse, error)
+	// CancelSession ends an active receive session, as the sender cancelling
+	// it would.
+	CancelSession(ctx context.Context, in *CancelSessionRequest, opts ...grpc.CallOption) (*CancelSessionResponse, error)
+	// ListDevices lists the devices the server has seen since it started.
+	ListDevices(ctx context.Context, in *ListDevicesRequest, opts ...grpc.CallOption) (*ListDevicesResponse, error)
+	// Send queues files to be sent to a device, as localgo queue add does.
+	Send(ctx context.Context, in *SendRequest, opts ...grpc.CallOption) (*SendJob, error)
+	// ListSendJobs lists the send queue.
+	ListSendJobs(ctx context.Context, in *ListSendJobsRequest, opts ...grpc.CallOption) (*ListSendJobsResponse, error)
+	// ListTransferRequests lists incoming transfers waiting for an answer.
+	ListTransferRequests(ctx context.Context, in *ListTransferRequestsRequest, opts ...grpc.CallOption) (*ListTransferRequestsResponse, error)
+	// AnswerTransferRequest accepts or declines an incoming transfer.
+	AnswerTransferRequest(ctx context.Context, in *AnswerTransferRequestRequest, opts ...grpc.CallOption) (*AnswerTransferRequestResponse, error)
+	// WatchEvents streams server activity as it happens. Events are not
+	// replayed, and a client that falls too far behind misses some.
+	WatchEvents(ctx context.Context, in *WatchEventsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Event], error)
+}
+
+type controlClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewControlClient(cc grpc.ClientConnInterface) ControlClient {
+	return &controlClient{cc}
+}
+
+func (c *controlClient) GetStatus(ctx context.Context, in *GetStatusRequest, opts ...grpc.CallOption) (*GetStatusResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetStatusResponse)
+	err := c.cc.Invoke(ctx, Control_GetStatus_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *controlClient) CancelSession(ctx context.Context, in *CancelSessionRequest, opts ...grpc.CallOption) (*CancelSessionResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CancelSessionResponse)
+	err := c.cc.Invoke(ctx, Control_CancelSession_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *controlClient) ListDevices(ctx context.Context, in *ListDevicesRequest, opts ...grpc.CallOption) (*ListDevicesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListDevicesResponse)
+	err := c.cc.Invoke(ctx, Control_ListDevices_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *controlClient) Send(ctx context.Context, in *SendRequest, opts ...grpc.CallOption) (*SendJob, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SendJob)
+	err := c.cc.Invoke(ctx, Control_Send_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *controlClient) ListSendJobs(ctx context.Context, in *ListSendJobsRequest, opts ...grpc.CallOption) (*ListSendJobsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListSendJobsResponse)
+	err := c.cc.Invoke(ctx, Control_ListSendJobs_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *controlClient) ListTransferRequests(ctx context.Context, in *ListTransferRequestsRequest, opts ...grpc.CallOption) (*ListTransferRequestsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListTransferRequestsResponse)
+	err := c.cc.Invoke(ctx, Control_ListTransferRequests_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *controlClient) AnswerTransferRequest(ctx context.Context, in *AnswerTransferRequestRequest, opts ...grpc.CallOption) (*AnswerTransferRequestResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AnswerTransferRequestResponse)
+	err := c.cc.Invoke(ctx, Control_AnswerTransferRequest_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *controlClient) WatchEvents(ctx context.Context, in *WatchEventsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Event], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Control_ServiceDesc.Streams[0], Control_WatchEvents_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[WatchEventsRequest, Event]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Control_WatchEventsClient = grpc.ServerStreamingClient[Event]
+
+// ControlServer is the server API for Control service.
+// All implementations must embed UnimplementedControlServer
+// for forward compatibility.
+//
+// Control is the control interface of a running LocalGo server, for GUIs
+// and other integrations. It mirrors the admin REST API under /api/localgo
+// and adds answering incoming transfers. Generate clients for other
+// languages from this file; the Go code is in pkg/controlpb (make proto).
+//
+// The interface is served only where the server is told to, with the
+// control_grpc setting: a unix socket usable only by the user running the
+// server, or a loopback address. Over a loopback address every call needs
+// the admin token (localgo token) in the "authorization" metadata, as
+// "Bearer <token>".
+type ControlServer interface {
+	// GetStatus reports the active receive sessions with per-file progress,
+	// and the transfers that ended last.
+	GetStatus(context.Context, *GetStatusRequest) (*GetStatusResponse, error)
+	// CancelSession ends an active receive session, as the sender cancelling
+	// it would.
+	CancelSession(context.Context, *CancelSessionRequest) (*CancelSessionResponse, error)
+	// ListDevices lists the devices the server has seen since it started.
+	ListDevices(context.Context, *ListDevicesRequest) (*ListDevicesResponse, error)
+	// Send queues files to be sent to a device, as localgo queue add does.
+	Send(context.Context, *SendRequest) (*SendJob, error)
+	// ListSendJobs lists the send queue.
+	ListSendJobs(context.Context, *ListSendJobsRequest) (*ListSendJobsResponse, error)
+	// ListTransferRequests lists incoming transfers waiting for an answer.
+	ListTransferRequests(context.Context, *ListTransferRequestsRequest) (*ListTransferRequestsResponse, error)
+	// AnswerTransferRequest accepts or declines an incoming transfer.
+	AnswerTransferRequest(context.Context, *AnswerTransferRequestRequest) (*AnswerTransferRequestResponse, error)
+	// WatchEvents streams server activity as it happens. Events are not
+	// replayed, and a client that falls too far behind misses some.
+	WatchEvents(*WatchEventsRequest, grpc.ServerStreamingServer[Event]) error
+	mustEmbedUnimplementedControlServer()
+}
+
+// UnimplementedControlServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedControlServer struct{}
+
+func (UnimplementedControlServer) GetStatus(context.Context, *GetStatusRequest) (*GetStatusResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetStatus not implemented")
+}
+func (UnimplementedControlServer) CancelSession(context.Context, *CancelSessionRequest) (*CancelSessionResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CancelSession not implemented")
+}
+func (UnimplementedControlServer) ListDevices(context.Context, *ListDevicesRequest) (*ListDevicesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListDevices not implemented")
+}
+func (UnimplementedControlServer) Send(context.Context, *SendRequest) (*SendJob, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Send not implemented")
+}
+func (UnimplementedControlServer) ListSendJobs(context.Context, *ListSendJobsRequest) (*ListSendJobsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListSendJobs not implemented")
+}
+func (UnimplementedControlServer) ListTransferRequests(context.Context, *ListTransferRequestsRequest) (*ListTransferRequestsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListTransferRequests not implemented")
+}
+func (UnimplementedControlServer) AnswerTransferRequest(context.Context, *AnswerTransferRequestRequest) (*AnswerTransferRequestResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AnswerTransferRequest not implemented")
+}
+func (UnimplementedControlServer) WatchEvents(*WatchEventsRequest, grpc.ServerStreamingServer[Event]) error {
+	return status.Errorf(codes.Unimplemented, "method WatchEvents not implemented")
+}
+func (UnimplementedControlServer) mustEmbedUnimplementedControlServer() {}
+func (UnimplementedControlServer) testEmbeddedByValue()                 {}
+
+// UnsafeControlServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to ControlServer will
+// result in compilation errors.
+type UnsafeControlServer interface {
+	mustEmbedUnimplementedControlServer()
+}
+
+func RegisterControlServer(s grpc.ServiceRegistrar, srv ControlServer) {
+	// If the following call pancis, it indicates UnimplementedControlServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Control_ServiceDesc, srv)
+}
+
+func _Control_GetStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetStatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ControlServer).GetStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Control_GetStatus_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ControlServer).GetStatus(ctx, req.(*GetStatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Control_CancelSession_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CancelSessionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ControlServer).CancelSession(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Control_CancelSession_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ControlServer).CancelSession(ctx, req.(*CancelSessionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Control_ListDevices_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListDevicesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ControlServer).ListDevices(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Control_ListDevices_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ControlServer).ListDevices(ctx, req.(*ListDevicesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Control_Send_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SendRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ControlServer).Send(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Control_Send_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ControlServer).Send(ctx, req.(*SendRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Control_ListSendJobs_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListSendJobsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ControlServer).ListSendJobs(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Control_ListSendJobs_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ControlServer).ListSendJobs(ctx, req.(*ListSendJobsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Control_ListTransferRequests_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListTransferRequestsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ControlServer).ListTransferRequests(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Control_ListTransferRequests_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ControlServer).ListTransferRequests(ctx, req.(*ListTransferRequestsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Control_AnswerTransferRequest_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AnswerTransferRequestRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ControlServer).AnswerTransferRequest(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Control_AnswerTransferRequest_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ControlServer).AnswerTransferRequest(ctx, req.(*AnswerTransferRequestRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Control_WatchEvents_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchEventsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ControlServer).WatchEvents(m, &grpc.GenericServerStream[WatchEventsRequest, Event]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Control_WatchEventsServer = grpc.ServerStreamingServer[Event]
+
+// Control_ServiceDesc is the grpc.ServiceDesc for Control service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Control_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "localgo.control.v1.Control",
+	HandlerType: (*ControlServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetStatus",
+			Handler:    _Control_GetStatus_Handler,
+		},
+		{
+			MethodName: "CancelSession",
+			Handler:    _Control_CancelSession_Handler,
+		},
+		{
+			MethodName: "ListDevices",
+			Handler:    _Control_ListDevices_Handler,
+		},
+		{
+			MethodName: "Send",
+			Handler:    _Control_Send_Handler,
+		},
+		{
+			MethodName: "ListSendJobs",
+			Handler:    _Control_ListSendJobs_Handler,
+		},
+		{
+			MethodName: "ListTransferRequests",
+			Handler:    _Control_ListTransferRequests_Handler,
+		},
+		{
+			MethodName: "AnswerTransferRequest",
+			Handler:    _Control_AnswerTransferRequest_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchEvents",
+			Handler:       _Control_WatchEvents_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "localgo/control/v1/control.proto",
+}
@@ -0,0 +1,285 @@
+package crypto
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Files encrypted at rest are sealed to a recipient's X25519 public key. A
+// fresh key is agreed with an ephemeral X25519 key for every file, and the
+// content is sealed with AES-256-GCM in chunks, so any length can be
+// streamed and truncation is detected. Only the holder of the identity,
+// the matching private key, can decrypt them.
+const (
+	// RecipientPrefix starts the text form of a recipient (public) key.
+	RecipientPrefix = "localgo-recipient:"
+	// IdentityPrefix starts the text form of an identity (private) key.
+	IdentityPrefix = "localgo-identity:"
+	// EncryptedExt is added to the names of files encrypted at rest.
+	EncryptedExt = ".lgenc"
+)
+
+// encryptedMagic starts every file encrypted at rest.
+const encryptedMagic = "localgo-encrypted/v1\n"
+
+// encryptedChunkSize is the plaintext sealed in each chunk.
+const encryptedChunkSize = 64 * 1024
+
+var (
+	// ErrNotEncrypted is returned when decrypting data that was not
+	// encrypted at rest by LocalGo.
+	ErrNotEncrypted = errors.New("not a LocalGo encrypted file")
+	// ErrDecrypt is returned when encrypted data was sealed to another
+	// identity, or has been altered or cut short.
+	ErrDecrypt = errors.New("cannot decrypt: wrong identity, or the file is damaged")
+)
+
+// Identity is the private key files encrypted at rest are decrypted with.
+type Identity struct {
+	key *ecdh.PrivateKey
+}
+
+// Recipient is the public key files are encrypted at rest to.
+type Recipient struct {
+	key *ecdh.PublicKey
+}
+
+// GenerateIdentity creates a new identity.
+func GenerateIdentity() (*Identity, error) {
+	key, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate identity: %w", err)
+	}
+	return &Identity{key: key}, nil
+}
+
+// ParseIdentity reads an identity in the form written by Identity.String.
+func ParseIdentity(s string) (*Identity, error) {
+	raw, err := decodeKey(s, IdentityPrefix)
+	if err != nil {
+		return nil, fmt.Errorf("invalid identity: %w", err)
+	}
+	key, err := ecdh.X25519().NewPrivateKey(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid identity: %w", err)
+	}
+	return &Identity{key: key}, nil
+}
+
+// ParseRecipient reads a recipient in the form written by Recipient.String.
+func ParseRecipient(s string) (*Recipient, error) {
+	raw, err := decodeKey(s, RecipientPrefix)
+	if err != nil {
+		return nil, fmt.Errorf("invalid recipient: %w", err)
+	}
+	key, err := ecdh.X25519().NewPublicKey(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid recipient: %w", err)
+	}
+	return &Recipient{key: key}, nil
+}
+
+func decodeKey(s, prefix string) ([]byte, error) {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, prefix) {
+		return nil, fmt.Errorf("does not start with %q", prefix)
+	}
+	return base64.RawURLEncoding.DecodeString(strings.TrimPrefix(s, prefix))
+}
+
+func (id *Identity) String() string {
+	return IdentityPrefix + base64.RawURLEncoding.EncodeToString(id.key.Bytes())
+}
+
+// Recipient returns the recipient that files are encrypted to for id.
+func (id *Identity) Recipient() *Recipient {
+	return &Recipient{key: id.key.PublicKey()}
+}
+
+func (r *Recipient) String() string {
+	return RecipientPrefix + base64.RawURLEncoding.EncodeToString(r.key.Bytes())
+}
+
+// fileKey derives the key a file is sealed with from the shared secret of
+// the ephemeral and recipient keys.
+func fileKey(shared, ephemeral, recipient []byte) (cipher.AEAD, error) {
+	key, err := hkdf.Key(sha256.New, shared, append(bytes.Clone(ephemeral), recipient...), strings.TrimSpace(encryptedMagic), 32)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// chunkNonce is the nonce of chunk n: its number, and whether it is the last.
+func chunkNonce(n uint64, last bool) []byte {
+	nonce := make([]byte, 12)
+	binary.BigEndian.PutUint64(nonce[3:11], n)
+	if last {
+		nonce[11] = 1
+	}
+	return nonce
+}
+
+// NewEncryptWriter returns a writer that encrypts what is written to it for
+// r and writes it to w. Close must be called to write the last chunk; it
+// does not close w.
+func NewEncryptWriter(w io.Writer, r *Recipient) (io.WriteCloser, error) {
+	ephemeral, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate file key: %w", err)
+	}
+	shared, err := ephemeral.ECDH(r.key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to agree file key: %w", err)
+	}
+	aead, err := fileKey(shared, ephemeral.PublicKey().Bytes(), r.key.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive file key: %w", err)
+	}
+	if _, err := io.WriteString(w, encryptedMagic); err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(ephemeral.PublicKey().Bytes()); err != nil {
+		return nil, err
+	}
+	return &encryptWriter{w: w, aead: aead, buf: make([]byte, 0, encryptedChunkSize)}, nil
+}
+
+type encryptWriter struct {
+	w      io.Writer
+	aead   cipher.AEAD
+	buf    []byte
+	sealed []byte
+	n      uint64
+	closed bool
+}
+
+func (e *encryptWriter) Write(p []byte) (int, error) {
+	if e.closed {
+		return 0, errors.New("write to closed encrypt writer")
+	}
+	written := 0
+	for len(p) > 0 {
+		// A full chunk is sealed only once more follows, since the last
+		// chunk is sealed differently.
+		if len(e.buf) == encryptedChunkSize {
+			if err := e.seal(false); err != nil {
+				return written, err
+			}
+		}
+		n := copy(e.buf[len(e.buf):cap(e.buf)], p)
+		e.buf = e.buf[:len(e.buf)+n]
+		p = p[n:]
+		written += n
+	}
+	return written, nil
+}
+
+func (e *encryptWriter) seal(last bool) error {
+	e.sealed = e.aead.Seal(e.sealed[:0], chunkNonce(e.n, last), e.buf, nil)
+	e.n++
+	e.buf = e.buf[:0]
+	_, err := e.w.Write(e.sealed)
+	return err
+}
+
+// Close seals and writes the last chunk.
+func (e *encryptWriter) Close() error {
+	if e.closed {
+		return nil
+	}
+	e.closed = true
+	return e.seal(true)
+}
+
+// NewDecryptReader returns a reader of the plaintext of src, which was
+// encrypted for id. Reading returns ErrDecrypt if src was sealed to another
+// identity or has been altered or cut short.
+func NewDecryptReader(src io.Reader, id *Identity) (io.Reader, error) {
+	br := bufio.NewReaderSize(src, encryptedChunkSize+64)
+	header := make([]byte, len(encryptedMagic)+32)
+	if _, err := io.ReadFull(br, header); err != nil || string(header[:len(encryptedMagic)]) != encryptedMagic {
+		return nil, ErrNotEncrypted
+	}
+	ephemeral, err := ecdh.X25519().NewPublicKey(header[len(encryptedMagic):])
+	if err != nil {
+		return nil, ErrNotEncrypted
+	}
+	shared, err := id.key.ECDH(ephemeral)
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	aead, err := fileKey(shared, ephemeral.Bytes(), id.key.PublicKey().Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive file key: %w", err)
+	}
+	return &decryptReader{r: br, aead: aead, sealed: make([]byte, encryptedChunkSize+aead.Overhead())}, nil
+}
+
+type decryptReader struct {
+	r      *bufio.Reader
+	aead   cipher.AEAD
+	sealed []byte
+	plain  []byte
+	n      uint64
+	done   bool
+	err    error
+}
+
+func (d *decryptReader) Read(p []byte) (int, error) {
+	for len(d.plain) == 0 {
+		if d.err != nil {
+			return 0, d.err
+		}
+		if d.done {
+			return 0, io.EOF
+		}
+		d.err = d.open()
+	}
+	n := copy(p, d.plain)
+	d.plain = d.plain[n:]
+	return n, nil
+}
+
+// open reads and opens the next chunk. A chunk followed by the end of the
+// data must be the last one.
+func (d *decryptReader) open() error {
+	n, err := io.ReadFull(d.r, d.sealed)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		if err == io.EOF {
+			return ErrDecrypt
+		}
+		return err
+	}
+	last := n < len(d.sealed)
+	if !last {
+		if _, err := d.r.Peek(1); err == io.EOF {
+			last = true
+		} else if err != nil {
+			return err
+		}
+	}
+	plain, err := d.aead.Open(d.sealed[:0], chunkNonce(d.n, last), d.sealed[:n], nil)
+	if err != nil {
+		return ErrDecrypt
+	}
+	d.n++
+	d.plain, d.done = plain, last
+	return nil
+}
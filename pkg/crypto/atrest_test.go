@@ -0,0 +1,111 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"io"
+	"testing"
+)
+
+func encryptAll(t *testing.T, r *Recipient, plain []byte) []byte {
+	t.Helper()
+	var sealed bytes.Buffer
+	w, err := NewEncryptWriter(&sealed, r)
+	if err != nil {
+		t.Fatalf("NewEncryptWriter: %v", err)
+	}
+	// Odd-sized writes cross chunk boundaries.
+	for p := plain; len(p) > 0; {
+		n := min(len(p), 1000)
+		if _, err := w.Write(p[:n]); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+		p = p[n:]
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	return sealed.Bytes()
+}
+
+func decryptAll(id *Identity, sealed []byte) ([]byte, error) {
+	r, err := NewDecryptReader(bytes.NewReader(sealed), id)
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(r)
+}
+
+func TestEncryptAtRest_RoundTrip(t *testing.T) {
+	id, err := GenerateIdentity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, size := range []int{0, 1, encryptedChunkSize - 1, encryptedChunkSize, encryptedChunkSize + 1, 3*encryptedChunkSize + 17} {
+		plain := make([]byte, size)
+		rand.Read(plain)
+		sealed := encryptAll(t, id.Recipient(), plain)
+		if size > 16 && bytes.Contains(sealed, plain[:16]) {
+			t.Errorf("size %d: plaintext appears in the encrypted data", size)
+		}
+		got, err := decryptAll(id, sealed)
+		if err != nil {
+			t.Fatalf("size %d: decrypt: %v", size, err)
+		}
+		if !bytes.Equal(got, plain) {
+			t.Errorf("size %d: decrypted %d bytes that differ from the plaintext", size, len(got))
+		}
+	}
+}
+
+func TestEncryptAtRest_Rejects(t *testing.T) {
+	id, _ := GenerateIdentity()
+	other, _ := GenerateIdentity()
+	plain := make([]byte, 2*encryptedChunkSize+100)
+	sealed := encryptAll(t, id.Recipient(), plain)
+	chunk := encryptedChunkSize + 16
+	header := len(encryptedMagic) + 32
+
+	tampered := bytes.Clone(sealed)
+	tampered[header+10] ^= 1
+	cases := map[string]struct {
+		id     *Identity
+		sealed []byte
+	}{
+		"other identity":         {other, sealed},
+		"altered":                {id, tampered},
+		"truncated mid-chunk":    {id, sealed[:len(sealed)-5]},
+		"last chunk removed":     {id, sealed[:header+2*chunk]},
+		"truncated after header": {id, sealed[:header]},
+	}
+	for name, c := range cases {
+		if _, err := decryptAll(c.id, c.sealed); !errors.Is(err, ErrDecrypt) {
+			t.Errorf("%s: err = %v, want ErrDecrypt", name, err)
+		}
+	}
+
+	if _, err := NewDecryptReader(bytes.NewReader([]byte("plain text")), id); !errors.Is(err, ErrNotEncrypted) {
+		t.Errorf("plain data: err = %v, want ErrNotEncrypted", err)
+	}
+}
+
+func TestEncryptAtRest_KeyText(t *testing.T) {
+	id, _ := GenerateIdentity()
+	parsed, err := ParseIdentity(id.String())
+	if err != nil {
+		t.Fatalf("ParseIdentity: %v", err)
+	}
+	r, err := ParseRecipient(" " + id.Recipient().String() + "\n")
+	if err != nil {
+		t.Fatalf("ParseRecipient: %v", err)
+	}
+	if r.String() != parsed.Recipient().String() {
+		t.Error("parsed identity and recipient do not match")
+	}
+	for _, bad := range []string{"", id.String(), RecipientPrefix + "not base64!", RecipientPrefix + "AAAA"} {
+		if _, err := ParseRecipient(bad); err == nil {
+			t.Errorf("ParseRecipient(%q) succeeded", bad)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package crypto
+
+import (
+	"crypto"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// IdentityProvider supplies the TLS certificate a device is known by. The
+// device's fingerprint is the SHA-256 hash of the certificate, so peers
+// that have paired with it recognize it as long as the certificate stays
+// the same.
+//
+// The private key need not be held in memory: a provider backed by an OS
+// keyring, an HSM or a PKCS#11 token returns a certificate whose
+// PrivateKey is a crypto.Signer that signs there, as SignerIdentity does.
+type IdentityProvider interface {
+	TLSCertificate() (tls.Certificate, error)
+}
+
+// TLSCertificate returns the certificate and key of the stored context,
+// which makes a StoredSecurityContext an IdentityProvider.
+func (ctx *StoredSecurityContext) TLSCertificate() (tls.Certificate, error) {
+	return tls.X509KeyPair([]byte(ctx.Certificate), []byte(ctx.PrivateKey))
+}
+
+// LoadOrCreateSecurityContext loads the context stored at path, or
+// generates a self-signed one for alias and saves it there if there is none
+// yet. This is the identity LocalGo uses unless told otherwise.
+func LoadOrCreateSecurityContext(path, alias string, logger *zap.SugaredLogger) (*StoredSecurityContext, error) {
+	ctx, err := LoadSecurityContext(path, logger)
+	if err == nil {
+		return ctx, nil
+	}
+	if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to load security context from '%s': %w", path, err)
+	}
+	if logger == nil {
+		logger = zap.NewNop().Sugar()
+	}
+	logger.Infof("Security context not found at %s, generating new one...", path)
+	ctx, err = GenerateSecurityContext(alias, logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate security context: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		logger.Warnf("Could not create security directory '%s': %v", filepath.Dir(path), err)
+	}
+	if err := SaveSecurityContext(ctx, path, logger); err != nil {
+		logger.Warnf("failed to save newly generated security context to '%s': %v", path, err)
+	}
+	return ctx, nil
+}
+
+// PEMFiles provides the certificate and key in PEM files, such as ones
+// issued by a company CA. They are read each time the certificate is asked
+// for, so a renewed certificate is picked up on the next start.
+func PEMFiles(certPath, keyPath string) IdentityProvider {
+	return pemFiles{cert: certPath, key: keyPath}
+}
+
+type pemFiles struct {
+	cert, key string
+}
+
+func (p pemFiles) TLSCertificate() (tls.Certificate, error) {
+	return tls.LoadX509KeyPair(p.cert, p.key)
+}
+
+// SignerIdentity provides chain, leaf first, with a key that is used only
+// through key, such as a handle to a key in an HSM. key must match the
+// public key of the leaf.
+func SignerIdentity(chain []*x509.Certificate, key crypto.Signer) (IdentityProvider, error) {
+	if len(chain) == 0 {
+		return nil, errors.New("no certificate given")
+	}
+	if !publicKeysEqual(chain[0].PublicKey, key.Public()) {
+		return nil, errors.New("the key does not belong to the certificate")
+	}
+	cert := tls.Certificate{PrivateKey: key, Leaf: chain[0]}
+	for _, c := range chain {
+		cert.Certificate = append(cert.Certificate, c.Raw)
+	}
+	return staticIdentity(cert), nil
+}
+
+type staticIdentity tls.Certificate
+
+func (s staticIdentity) TLSCertificate() (tls.Certificate, error) {
+	return tls.Certificate(s), nil
+}
+
+// publicKeysEqual compares public keys of the types crypto/x509 parses,
+// all of which have an Equal method.
+func publicKeysEqual(a, b crypto.PublicKey) bool {
+	eq, ok := a.(interface{ Equal(crypto.PublicKey) bool })
+	return ok && eq.Equal(b)
+}
+
+// DescribeIdentity loads the certificate of p and returns it as a stored
+// context, for code that reads the certificate or its fingerprint there.
+// The private key is left out: it may not be exportable.
+func DescribeIdentity(p IdentityProvider) (*StoredSecurityContext, error) {
+	cert, err := p.TLSCertificate()
+	if err != nil {
+		return nil, err
+	}
+	if len(cert.Certificate) == 0 {
+		return nil, errors.New("the identity has no certificate")
+	}
+	var pemChain strings.Builder
+	for _, der := range cert.Certificate {
+		pemChain.WriteString(encodeCertificateToPem(der))
+	}
+	return &StoredSecurityContext{
+		Certificate:     pemChain.String(),
+		CertificateHash: calculateCertificateHash(cert.Certificate[0]),
+	}, nil
+}
@@ -0,0 +1,79 @@
+package crypto
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// selfSigned returns a certificate for key, standing in for one issued for
+// a key kept in an HSM.
+func selfSigned(t *testing.T, key *ecdsa.PrivateKey) *x509.Certificate {
+	t.Helper()
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "managed"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert
+}
+
+func TestSignerIdentity(t *testing.T) {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	cert := selfSigned(t, key)
+
+	p, err := SignerIdentity([]*x509.Certificate{cert}, key)
+	if err != nil {
+		t.Fatalf("SignerIdentity: %v", err)
+	}
+	tlsCert, err := p.TLSCertificate()
+	if err != nil || tlsCert.PrivateKey != key || len(tlsCert.Certificate) != 1 {
+		t.Fatalf("TLSCertificate = %+v, %v", tlsCert, err)
+	}
+
+	sc, err := DescribeIdentity(p)
+	if err != nil {
+		t.Fatalf("DescribeIdentity: %v", err)
+	}
+	if sc.CertificateHash != calculateCertificateHash(cert.Raw) || sc.PrivateKey != "" {
+		t.Errorf("described as %+v", sc)
+	}
+
+	other, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if _, err := SignerIdentity([]*x509.Certificate{cert}, other); err == nil {
+		t.Error("SignerIdentity accepted a key that does not match the certificate")
+	}
+}
+
+func TestLoadOrCreateSecurityContext(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sub", "context.json")
+	first, err := LoadOrCreateSecurityContext(path, "device", testLogger)
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	second, err := LoadOrCreateSecurityContext(path, "device", testLogger)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if first.CertificateHash != second.CertificateHash {
+		t.Error("a second identity was generated instead of loading the first")
+	}
+	if _, err := second.TLSCertificate(); err != nil {
+		t.Errorf("stored context unusable: %v", err)
+	}
+}
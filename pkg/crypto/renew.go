@@ -0,0 +1,127 @@
+package crypto
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// ExpiryWarning is how long before its certificate expires a device starts
+// being warned to regenerate it.
+const ExpiryWarning = 30 * 24 * time.Hour
+
+// FingerprintHistoryFile is the name of the file, next to the security
+// context, that lists the fingerprints RegenerateSecurityContext replaced.
+const FingerprintHistoryFile = "fingerprint_history.json"
+
+// RetiredFingerprint is a fingerprint the device was once known by.
+type RetiredFingerprint struct {
+	Fingerprint string    `json:"fingerprint"`
+	Alias       string    `json:"alias,omitempty"` // the certificate's common name
+	NotAfter    time.Time `json:"notAfter,omitzero"`
+	RetiredAt   time.Time `json:"retiredAt"`
+}
+
+// Leaf parses the certificate of the context, the first one in its PEM.
+func (ctx *StoredSecurityContext) Leaf() (*x509.Certificate, error) {
+	block, _ := pem.Decode([]byte(ctx.Certificate))
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, errors.New("no PEM certificate found")
+	}
+	return x509.ParseCertificate(block.Bytes)
+}
+
+// ExpiryStatus tells whether cert needs renewing at now: it is "expired",
+// "expires soon" within ExpiryWarning of its expiry, or "" otherwise.
+func ExpiryStatus(cert *x509.Certificate, now time.Time) string {
+	switch {
+	case now.After(cert.NotAfter):
+		return "expired"
+	case cert.NotAfter.Sub(now) < ExpiryWarning:
+		return "expires soon"
+	}
+	return ""
+}
+
+// FingerprintHistoryPath returns where the fingerprint history of the
+// context stored at contextPath is kept.
+func FingerprintHistoryPath(contextPath string) string {
+	return filepath.Join(filepath.Dir(contextPath), FingerprintHistoryFile)
+}
+
+// FingerprintHistory returns the fingerprints replaced in the context stored
+// at contextPath, oldest first. There are none until it is regenerated.
+func FingerprintHistory(contextPath string) ([]RetiredFingerprint, error) {
+	data, err := os.ReadFile(FingerprintHistoryPath(contextPath))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fingerprint history: %w", err)
+	}
+	var history []RetiredFingerprint
+	if err := json.Unmarshal(data, &history); err != nil {
+		return nil, fmt.Errorf("failed to decode fingerprint history: %w", err)
+	}
+	return history, nil
+}
+
+// RegenerateSecurityContext replaces the context stored at path with a new
+// self-signed one for alias, such as when the certificate nears its expiry
+// or the alias has changed. The old fingerprint is added to the history
+// first, so paired devices can still be told which identity the new one
+// replaces. Peers that trust the old fingerprint must be updated to trust
+// the new one.
+func RegenerateSecurityContext(path, alias string, logger *zap.SugaredLogger) (*StoredSecurityContext, error) {
+	old, err := LoadSecurityContext(path, logger)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	ctx, err := GenerateSecurityContext(alias, logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate security context: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create security directory: %w", err)
+	}
+	if old != nil {
+		if err := retireFingerprint(path, old, time.Now()); err != nil {
+			return nil, err
+		}
+	}
+	if err := SaveSecurityContext(ctx, path, logger); err != nil {
+		return nil, err
+	}
+	return ctx, nil
+}
+
+// retireFingerprint appends the fingerprint of old to the history of the
+// context stored at contextPath.
+func retireFingerprint(contextPath string, old *StoredSecurityContext, now time.Time) error {
+	history, err := FingerprintHistory(contextPath)
+	if err != nil {
+		return err
+	}
+	retired := RetiredFingerprint{Fingerprint: old.CertificateHash, RetiredAt: now.UTC().Truncate(time.Second)}
+	if cert, err := old.Leaf(); err == nil {
+		retired.Alias = cert.Subject.CommonName
+		retired.NotAfter = cert.NotAfter.UTC()
+	}
+	history = append(history, retired)
+
+	data, err := json.MarshalIndent(history, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(FingerprintHistoryPath(contextPath), append(data, '\n'), 0600); err != nil {
+		return fmt.Errorf("failed to save fingerprint history: %w", err)
+	}
+	return nil
+}
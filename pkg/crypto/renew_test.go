@@ -0,0 +1,81 @@
+package crypto
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRegenerateSecurityContext(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "context.json")
+	first, err := LoadOrCreateSecurityContext(path, "Old Name", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	second, err := RegenerateSecurityContext(path, "New Name", nil)
+	if err != nil {
+		t.Fatalf("RegenerateSecurityContext: %v", err)
+	}
+	if second.CertificateHash == first.CertificateHash {
+		t.Error("regenerating kept the old fingerprint")
+	}
+	stored, err := LoadSecurityContext(path, nil)
+	if err != nil || stored.CertificateHash != second.CertificateHash {
+		t.Errorf("stored context = %v, %v; want the regenerated one", stored, err)
+	}
+	if cert, err := second.Leaf(); err != nil || cert.Subject.CommonName != "New Name" {
+		t.Errorf("Leaf() = %v, %v; want a certificate for the new alias", cert, err)
+	}
+
+	if _, err := RegenerateSecurityContext(path, "New Name", nil); err != nil {
+		t.Fatal(err)
+	}
+	history, err := FingerprintHistory(path)
+	if err != nil {
+		t.Fatalf("FingerprintHistory: %v", err)
+	}
+	if len(history) != 2 {
+		t.Fatalf("history has %d entries, want 2: %+v", len(history), history)
+	}
+	if history[0].Fingerprint != first.CertificateHash || history[0].Alias != "Old Name" {
+		t.Errorf("history[0] = %+v, want the first identity", history[0])
+	}
+	if history[1].Fingerprint != second.CertificateHash || history[0].NotAfter.IsZero() || history[1].RetiredAt.IsZero() {
+		t.Errorf("history[1] = %+v, want the second identity with its dates", history[1])
+	}
+}
+
+func TestRegenerateSecurityContext_NoneStored(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "security", "context.json")
+	if _, err := RegenerateSecurityContext(path, "Desk", nil); err != nil {
+		t.Fatalf("RegenerateSecurityContext: %v", err)
+	}
+	if history, err := FingerprintHistory(path); err != nil || len(history) != 0 {
+		t.Errorf("FingerprintHistory() = %v, %v; want no entries", history, err)
+	}
+}
+
+func TestExpiryStatus(t *testing.T) {
+	ctx, err := GenerateSecurityContext("Desk", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := ctx.Leaf()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		name string
+		now  time.Time
+		want string
+	}{
+		{"fresh", time.Now(), ""},
+		{"expiring", cert.NotAfter.Add(-ExpiryWarning / 2), "expires soon"},
+		{"expired", cert.NotAfter.Add(time.Hour), "expired"},
+	} {
+		if got := ExpiryStatus(cert, tt.now); got != tt.want {
+			t.Errorf("%s: ExpiryStatus() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
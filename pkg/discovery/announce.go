@@ -8,6 +8,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/bethropolis/localgo/pkg/capture"
 	"github.com/bethropolis/localgo/pkg/model"
 )
 
@@ -52,6 +53,7 @@ func (md *MulticastDiscovery) SendDiscoveryAnnouncement() error {
 	if err != nil {
 		return fmt.Errorf("failed to send multicast announcement: %w", err)
 	}
+	capture.Packet(capture.Out, addr, data)
 
 	md.logger.Debugf("Sent multicast announcement as %s (fingerprint: %s) to %s",
 		md.dto.Alias, getShortFingerprint(md.dto.Fingerprint), md.config.MulticastAddr)
@@ -101,13 +103,16 @@ func (md *MulticastDiscovery) SendDiscoveryResponse(targetAddr *net.UDPAddr, tar
 	if err != nil {
 		return fmt.Errorf("failed to send discovery response: %w", err)
 	}
+	capture.Packet(capture.Out, respAddr, data)
 
 	md.logger.Debugf("Sent discovery response via multicast to %s", md.config.MulticastAddr)
 	return nil
 }
 
 func (md *MulticastDiscovery) listenLoop(ctx context.Context, conn net.PacketConn) {
-	buffer := make([]byte, 2048)
+	// One byte more than allowed, to tell an oversized packet from one
+	// that just fits.
+	buffer := make([]byte, MaxPacketSize+1)
 
 	for {
 		select {
@@ -135,16 +140,35 @@ func (md *MulticastDiscovery) listenLoop(ctx context.Context, conn net.PacketCon
 			continue
 		}
 
+		capture.Packet(capture.In, addr, buffer[:n])
 		if err := md.handlePacket(buffer[:n], addr); err != nil {
-			md.logger.Warnf("Failed to handle multicast packet: %v", err)
+			md.dropPacket(err, addr)
 		}
 	}
 }
 
+// dropPacket counts a packet handlePacket rejected. Drops are logged at
+// debug level, with a warning of the totals at most every
+// dropReportInterval.
+func (md *MulticastDiscovery) dropPacket(err error, addr net.Addr) {
+	md.logger.Debugf("Dropped multicast packet from %v: %v", addr, err)
+	if md.drops.count(err) {
+		d := md.drops.snapshot()
+		md.logger.Warnf("Dropped %d multicast packet(s) so far (%d oversized, %d malformed, %d invalid), last from %v: %v",
+			d.Total(), d.Oversized, d.Malformed, d.Invalid, addr, err)
+	}
+}
+
+// Dropped returns how many multicast packets have been ignored as too
+// large, malformed or invalid.
+func (md *MulticastDiscovery) Dropped() DroppedPackets {
+	return md.drops.snapshot()
+}
+
 func (md *MulticastDiscovery) handlePacket(data []byte, addr net.Addr) error {
-	var dto model.MulticastDto
-	if err := json.Unmarshal(data, &dto); err != nil {
-		return fmt.Errorf("failed to unmarshal packet: %w", err)
+	dto, err := decodeMulticastDto(data)
+	if err != nil {
+		return err
 	}
 
 	if dto.Fingerprint == md.dto.Fingerprint {
@@ -157,17 +181,42 @@ func (md *MulticastDiscovery) handlePacket(data []byte, addr net.Addr) error {
 	}
 
 	device := model.FromMulticastDto(dto, udpAddr.IP)
-
-	md.logger.Debugf("Discovered raw device via multicast: %s (%s) at %s:%d",
-		device.Alias, getShortFingerprint(device.Fingerprint), device.IP, device.Port)
-
 	md.updateDevice(device)
 
-	if dto.Announce {
+	if !dto.Announce {
+		md.logger.Debugf("Discovery response via multicast: %s (%s) at %s:%d",
+			device.Alias, getShortFingerprint(device.Fingerprint), device.IP, device.Port)
+		return nil
+	}
+	// Repeated and excess announcements update the device but are neither
+	// answered nor logged.
+	if !md.responses.allow(device.Fingerprint+"@"+device.IP, time.Now()) {
+		md.suppressed.Add(1)
+		return nil
+	}
+	md.logger.Debugf("Announcement via multicast: %s (%s) at %s:%d",
+		device.Alias, getShortFingerprint(device.Fingerprint), device.IP, device.Port)
+	// Answered in the background, so a slow peer does not hold up the
+	// packets behind its announcement; not once Stop has closed the
+	// listener and is waiting for responses.
+	md.connMu.Lock()
+	defer md.connMu.Unlock()
+	if md.conn == nil {
+		return nil
+	}
+	md.responding.Add(1)
+	go func() {
+		defer md.responding.Done()
 		if err := md.SendDiscoveryResponse(udpAddr, device); err != nil {
 			md.logger.Warnf("Failed to send discovery response: %v", err)
 		}
-	}
-
+	}()
 	return nil
 }
+
+// SuppressedResponses returns how many announcements have gone unanswered
+// because the peer was answered recently or too many peers announced at
+// once.
+func (md *MulticastDiscovery) SuppressedResponses() uint64 {
+	return md.suppressed.Load()
+}
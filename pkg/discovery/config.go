@@ -9,6 +9,12 @@ type MulticastConfig struct {
 	InterfaceName   string
 	AnnounceTimeout time.Duration
 	ListenTimeout   time.Duration
+
+	// Each peer's announcements are answered at most once per
+	// ResponseWindow, and all of them at most MaxResponsesPerSecond times
+	// a second. Zero turns a limit off.
+	ResponseWindow        time.Duration
+	MaxResponsesPerSecond int
 }
 
 // DefaultMulticastConfig returns a default configuration
@@ -18,5 +24,8 @@ func DefaultMulticastConfig() *MulticastConfig {
 		Port:            53317,
 		AnnounceTimeout: 2 * time.Second,
 		ListenTimeout:   5 * time.Second,
+
+		ResponseWindow:        5 * time.Second,
+		MaxResponsesPerSecond: 10,
 	}
 }
@@ -0,0 +1,77 @@
+package discovery
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/bethropolis/localgo/pkg/model"
+)
+
+// Filter selects found devices by type, alias and fingerprint. The zero
+// Filter matches every device.
+type Filter struct {
+	Types             []model.DeviceType // any of these; empty for all
+	AliasPattern      string             // shell pattern such as "Pixel*", case-insensitive
+	FingerprintPrefix string             // case-insensitive
+}
+
+// NewFilter returns the filter for a comma-separated list of device types,
+// an alias pattern and a fingerprint prefix, any of which may be empty.
+// "laptop" is accepted for desktop, the type LocalSend gives laptops.
+func NewFilter(types, aliasPattern, fingerprintPrefix string) (*Filter, error) {
+	f := &Filter{
+		AliasPattern:      strings.ToLower(aliasPattern),
+		FingerprintPrefix: strings.ToLower(fingerprintPrefix),
+	}
+	for _, t := range strings.Split(types, ",") {
+		t = strings.ToLower(strings.TrimSpace(t))
+		switch model.DeviceType(t) {
+		case "":
+			continue
+		case "laptop":
+			t = string(model.DeviceTypeDesktop)
+		case model.DeviceTypeMobile, model.DeviceTypeDesktop, model.DeviceTypeWeb, model.DeviceTypeHeadless, model.DeviceTypeServer:
+		default:
+			return nil, fmt.Errorf("%q is not a device type: use mobile, desktop, laptop, web, headless or server", t)
+		}
+		f.Types = append(f.Types, model.DeviceType(t))
+	}
+	if _, err := path.Match(f.AliasPattern, ""); err != nil {
+		return nil, fmt.Errorf("invalid alias pattern %q: %w", aliasPattern, err)
+	}
+	return f, nil
+}
+
+// Empty reports whether f matches every device.
+func (f *Filter) Empty() bool {
+	return len(f.Types) == 0 && f.AliasPattern == "" && f.FingerprintPrefix == ""
+}
+
+// Match reports whether device passes f.
+func (f *Filter) Match(device *model.Device) bool {
+	if len(f.Types) > 0 && !slices.Contains(f.Types, device.DeviceType) {
+		return false
+	}
+	if f.AliasPattern != "" {
+		if ok, _ := path.Match(f.AliasPattern, strings.ToLower(device.Alias)); !ok {
+			return false
+		}
+	}
+	return strings.HasPrefix(strings.ToLower(device.Fingerprint), f.FingerprintPrefix)
+}
+
+// Apply returns the devices that pass f, in their original order.
+func (f *Filter) Apply(devices []*model.Device) []*model.Device {
+	if f.Empty() {
+		return devices
+	}
+	var out []*model.Device
+	for _, d := range devices {
+		if f.Match(d) {
+			out = append(out, d)
+		}
+	}
+	return out
+}
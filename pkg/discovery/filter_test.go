@@ -0,0 +1,53 @@
+package discovery
+
+import (
+	"testing"
+
+	"github.com/bethropolis/localgo/pkg/model"
+)
+
+func TestFilter(t *testing.T) {
+	devices := []*model.Device{
+		{Alias: "Pixel 8", DeviceType: model.DeviceTypeMobile, Fingerprint: "ab12"},
+		{Alias: "pixelbook", DeviceType: model.DeviceTypeDesktop, Fingerprint: "AB34"},
+		{Alias: "Office NAS", DeviceType: model.DeviceTypeServer, Fingerprint: "cd56"},
+	}
+	tests := []struct {
+		types, alias, fingerprint string
+		want                      []string
+	}{
+		{"", "", "", []string{"Pixel 8", "pixelbook", "Office NAS"}},
+		{"laptop, mobile", "", "", []string{"Pixel 8", "pixelbook"}},
+		{"", "Pixel*", "", []string{"Pixel 8", "pixelbook"}},
+		{"", "", "AB", []string{"Pixel 8", "pixelbook"}},
+		{"mobile", "", "ab3", nil},
+		{"server", "*nas", "cd", []string{"Office NAS"}},
+	}
+	for _, tt := range tests {
+		f, err := NewFilter(tt.types, tt.alias, tt.fingerprint)
+		if err != nil {
+			t.Fatalf("NewFilter(%q, %q, %q) = %v", tt.types, tt.alias, tt.fingerprint, err)
+		}
+		var got []string
+		for _, d := range f.Apply(devices) {
+			got = append(got, d.Alias)
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("filter %q %q %q kept %q, want %q", tt.types, tt.alias, tt.fingerprint, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("filter %q %q %q kept %q, want %q", tt.types, tt.alias, tt.fingerprint, got, tt.want)
+				break
+			}
+		}
+	}
+
+	if _, err := NewFilter("tablet", "", ""); err == nil {
+		t.Error("NewFilter accepted an unknown device type")
+	}
+	if _, err := NewFilter("", "[", ""); err == nil {
+		t.Error("NewFilter accepted a malformed pattern")
+	}
+}
@@ -4,16 +4,17 @@ package discovery
 import (
 	"bytes"
 	"context"
-	"crypto/tls"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net"
 	"net/http"
-	"strconv"
+	"net/http/httptrace"
 	"sync"
 	"time"
 
+	"github.com/bethropolis/localgo/pkg/capture"
+	"github.com/bethropolis/localgo/pkg/httputil"
 	"github.com/bethropolis/localgo/pkg/model"
 	"github.com/bethropolis/localgo/pkg/network"
 	"go.uber.org/zap"
@@ -21,11 +22,21 @@ import (
 
 type HTTPDiscoveryConfig struct {
 	RequestTimeout time.Duration
+	DialTimeout    time.Duration // how long to wait for an address to accept a connection
+
+	// SkipSweep, if set, is given the devices found among the hosts in the
+	// neighbor table, which ScanNetwork probes first. If it returns true,
+	// the other addresses are not probed.
+	SkipSweep func(found []*model.Device) bool
 }
 
+// neighborTable lists the hosts ScanNetwork probes first.
+var neighborTable = network.Neighbors
+
 func DefaultHTTPDiscoveryConfig() *HTTPDiscoveryConfig {
 	return &HTTPDiscoveryConfig{
 		RequestTimeout: 2 * time.Second,
+		DialTimeout:    500 * time.Millisecond,
 	}
 }
 
@@ -34,6 +45,7 @@ type HTTPDiscovery struct {
 	dto           model.RegisterDto
 	client        *http.Client
 	deviceHandler func(*model.Device)
+	registry      *Registry
 	logger        *zap.SugaredLogger
 }
 
@@ -45,21 +57,11 @@ func NewHTTPDiscovery(config *HTTPDiscoveryConfig, dto model.RegisterDto, handle
 		logger = zap.NewNop().Sugar()
 	}
 
+	// Scans share one transport, so devices found in one scan are reached
+	// over the same connections by the next.
 	client := &http.Client{
-		Timeout: config.RequestTimeout,
-		Transport: &http.Transport{
-			DialContext: (&net.Dialer{
-				Timeout:   500 * time.Millisecond,
-				KeepAlive: 30 * time.Second,
-			}).DialContext,
-			TLSClientConfig: &tls.Config{
-				InsecureSkipVerify: true,
-			},
-			MaxIdleConns:          100,
-			IdleConnTimeout:       90 * time.Second,
-			TLSHandshakeTimeout:   1 * time.Second,
-			ExpectContinueTimeout: 1 * time.Second,
-		},
+		Timeout:   config.RequestTimeout,
+		Transport: capture.Transport(httputil.Transport()),
 	}
 
 	return &HTTPDiscovery{
@@ -80,6 +82,12 @@ func (hd *HTTPDiscovery) FetchDeviceInfo(ctx context.Context, ip net.IP, port in
 }
 
 func (hd *HTTPDiscovery) RegisterWithDevice(ctx context.Context, ip net.IP, port int, scheme string) (*model.Device, error) {
+	return hd.registerWithAddr(ctx, net.IPAddr{IP: ip}, port, scheme)
+}
+
+// registerWithAddr is RegisterWithDevice for an address that may carry a
+// zone, as IPv6 link-local ones do. The zone is kept in the device's IP.
+func (hd *HTTPDiscovery) registerWithAddr(ctx context.Context, addr net.IPAddr, port int, scheme string) (*model.Device, error) {
 	jsonData, err := json.Marshal(hd.dto)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal request body: %w", err)
@@ -88,8 +96,17 @@ func (hd *HTTPDiscovery) RegisterWithDevice(ctx context.Context, ip net.IP, port
 	if scheme == "" {
 		scheme = "http"
 	}
-	url := fmt.Sprintf("%s://%s/api/localsend/v2/register", scheme, net.JoinHostPort(ip.String(), strconv.Itoa(port)))
+	url := fmt.Sprintf("%s://%s/api/localsend/v2/register", scheme, network.URLHost(addr.String(), port))
 
+	if hd.config.DialTimeout > 0 {
+		ctx = httputil.WithDialTimeout(ctx, hd.config.DialTimeout)
+	}
+	// The latency is timed from having a connection, so a TLS handshake
+	// does not count against HTTPS devices.
+	var gotConn time.Time
+	ctx = httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		GotConn: func(httptrace.GotConnInfo) { gotConn = time.Now() },
+	})
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
@@ -111,6 +128,7 @@ func (hd *HTTPDiscovery) RegisterWithDevice(ctx context.Context, ip net.IP, port
 	if err != nil {
 		return nil, fmt.Errorf("failed to read response body: %w", err)
 	}
+	latency := time.Since(gotConn)
 
 	var infoDto model.InfoDto
 	if err := json.Unmarshal(body, &infoDto); err != nil {
@@ -118,7 +136,7 @@ func (hd *HTTPDiscovery) RegisterWithDevice(ctx context.Context, ip net.IP, port
 	}
 
 	return &model.Device{
-		IP:          ip.String(),
+		IP:          addr.String(),
 		Version:     infoDto.Version,
 		Protocol:    model.ProtocolType(scheme),
 		Port:        port,
@@ -129,37 +147,87 @@ func (hd *HTTPDiscovery) RegisterWithDevice(ctx context.Context, ip net.IP, port
 		Download:    infoDto.Download,
 		LastSeen:    time.Now(),
 		Available:   true,
+		LatencyMs:   float64(latency.Microseconds()) / 1000,
 	}, nil
 }
 
+// ScanNetwork probes each of ips for a device on port. A local IPv6
+// link-local address stands for its link: the neighbors that answer a ping
+// on it are probed in its place.
+//
+// Hosts in the operating system's neighbor table are probed first, as they
+// are likely up, and the rest of ips are swept after them; most devices are
+// found in the first round, which config.SkipSweep can make the only one.
 func (hd *HTTPDiscovery) ScanNetwork(ctx context.Context, ips []net.IP, port int) ([]*model.Device, error) {
+	known := make(map[string]bool)
+	if table, err := neighborTable(); err != nil {
+		hd.logger.Debugf("Cannot read the neighbor table: %v", err)
+	} else {
+		for _, ip := range table {
+			known[ip.String()] = true
+		}
+	}
+
+	var first, rest []net.IPAddr
+	for _, ip := range ips {
+		if !network.IsLinkLocalIPv6(ip) {
+			if known[ip.String()] {
+				first = append(first, net.IPAddr{IP: ip})
+			} else {
+				rest = append(rest, net.IPAddr{IP: ip})
+			}
+			continue
+		}
+		neighbors, err := network.LinkLocalNeighbors(ctx, ip)
+		if err != nil {
+			hd.logger.Debugf("Skipping the link of %s: %v", ip, err)
+		}
+		first = append(first, neighbors...) // they just answered a ping
+	}
+
+	hd.logger.Debugf("Scanning %d IPs on port %d, %d of them known neighbors first", len(first)+len(rest), port, len(first))
+	devices := hd.probe(ctx, first, port)
+	if hd.config.SkipSweep != nil && hd.config.SkipSweep(devices) {
+		hd.logger.Debugf("Found %d device(s) among the known neighbors, skipping the sweep", len(devices))
+		return devices, nil
+	}
+	if ctx.Err() == nil {
+		devices = append(devices, hd.probe(ctx, rest, port)...)
+	}
+	return devices, nil
+}
+
+// probe registers with each of addrs in parallel, returning the devices
+// that answer.
+func (hd *HTTPDiscovery) probe(ctx context.Context, addrs []net.IPAddr, port int) []*model.Device {
 	var devices []*model.Device
 	var wg sync.WaitGroup
-	deviceChan := make(chan *model.Device, len(ips))
+	deviceChan := make(chan *model.Device, len(addrs))
 
 	// Semaphore limits parallel pinging to prevent socket exhaustion
 	sem := make(chan struct{}, 100)
 
-	hd.logger.Debugf("Scanning %d IPs on port %d", len(ips), port)
-
-	for _, ip := range ips {
+	for _, addr := range addrs {
 		wg.Add(1)
-		go func(ip net.IP) {
+		go func(addr net.IPAddr) {
 			defer wg.Done()
 
 			sem <- struct{}{}
 			defer func() { <-sem }()
 
-			device, err := hd.RegisterWithDevice(ctx, ip, port, "https")
+			device, err := hd.registerWithAddr(ctx, addr, port, "https")
 			if err != nil {
-				device, err = hd.RegisterWithDevice(ctx, ip, port, "http")
+				device, err = hd.registerWithAddr(ctx, addr, port, "http")
 				if err != nil {
 					return
 				}
 			}
 
+			if hd.registry != nil {
+				hd.registry.Seen(device)
+			}
 			deviceChan <- device
-		}(ip)
+		}(addr)
 	}
 
 	wg.Wait()
@@ -168,8 +236,13 @@ func (hd *HTTPDiscovery) ScanNetwork(ctx context.Context, ips []net.IP, port int
 	for device := range deviceChan {
 		devices = append(devices, device)
 	}
+	return devices
+}
 
-	return devices, nil
+// SetRegistry makes scans record the devices that answer in r, such as a
+// Service's registry. Call it before scanning.
+func (hd *HTTPDiscovery) SetRegistry(r *Registry) {
+	hd.registry = r
 }
 
 func (hd *HTTPDiscovery) ScanLocalNetwork(ctx context.Context, port int) ([]*model.Device, error) {
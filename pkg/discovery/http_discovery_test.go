@@ -0,0 +1,102 @@
+package discovery
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/bethropolis/localgo/pkg/model"
+	"github.com/bethropolis/localgo/pkg/network"
+)
+
+// TestRegisterWithAddr_LinkLocal registers with a device listening on an
+// IPv6 link-local address of this machine, which is only reachable with
+// its zone.
+func TestRegisterWithAddr_LinkLocal(t *testing.T) {
+	var local net.IP
+	ips, _ := network.GetLocalIPAddresses()
+	for _, ip := range ips {
+		if network.IsLinkLocalIPv6(ip) {
+			local = ip
+			break
+		}
+	}
+	if local == nil {
+		t.Skip("no IPv6 link-local address")
+	}
+	var zone string
+	ifaces, _ := net.Interfaces()
+	for _, iface := range ifaces {
+		addrs, _ := iface.Addrs()
+		for _, a := range addrs {
+			if ipnet, ok := a.(*net.IPNet); ok && ipnet.IP.Equal(local) {
+				zone = iface.Name
+			}
+		}
+	}
+	addr := net.IPAddr{IP: local, Zone: zone}
+
+	ln, err := net.Listen("tcp", net.JoinHostPort(addr.String(), "0"))
+	if err != nil {
+		t.Skipf("cannot listen on %s: %v", addr.String(), err)
+	}
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(model.InfoDto{Alias: "Neighbor", Fingerprint: "abc"})
+	})}
+	go srv.Serve(ln)
+	defer srv.Close()
+
+	hd := NewHTTPDiscovery(nil, model.RegisterDto{Alias: "Test"}, nil, nil)
+	device, err := hd.registerWithAddr(context.Background(), addr, ln.Addr().(*net.TCPAddr).Port, "http")
+	if err != nil {
+		t.Fatalf("registerWithAddr: %v", err)
+	}
+	if device.IP != addr.String() || device.Alias != "Neighbor" {
+		t.Errorf("got device %s %q, want %s \"Neighbor\"", device.IP, device.Alias, addr.String())
+	}
+}
+
+// TestScanNetwork_NeighborsFirst probes the hosts in the neighbor table
+// first, and only them when SkipSweep says so.
+func TestScanNetwork_NeighborsFirst(t *testing.T) {
+	serve := func(addr, alias string) net.Listener {
+		ln, err := net.Listen("tcp", addr)
+		if err != nil {
+			t.Skipf("cannot listen on %s: %v", addr, err)
+		}
+		srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			json.NewEncoder(w).Encode(model.InfoDto{Alias: alias})
+		})}
+		go srv.Serve(ln)
+		t.Cleanup(func() { srv.Close() })
+		return ln
+	}
+	port := serve("127.0.0.1:0", "Swept").Addr().(*net.TCPAddr).Port
+	serve(net.JoinHostPort("127.0.0.2", strconv.Itoa(port)), "Neighbor")
+
+	orig := neighborTable
+	neighborTable = func() ([]net.IP, error) { return []net.IP{net.ParseIP("127.0.0.2")}, nil }
+	defer func() { neighborTable = orig }()
+
+	ips := []net.IP{net.ParseIP("127.0.0.1"), net.ParseIP("127.0.0.2")}
+	cfg := DefaultHTTPDiscoveryConfig()
+	var seen []string
+	cfg.SkipSweep = func(found []*model.Device) bool {
+		for _, d := range found {
+			seen = append(seen, d.Alias)
+		}
+		return true
+	}
+	devices, _ := NewHTTPDiscovery(cfg, model.RegisterDto{Alias: "Test"}, nil, nil).ScanNetwork(context.Background(), ips, port)
+	if len(devices) != 1 || devices[0].Alias != "Neighbor" || len(seen) != 1 {
+		t.Errorf("with SkipSweep: found %v (SkipSweep saw %v), want only Neighbor", devices, seen)
+	}
+
+	devices, _ = NewHTTPDiscovery(nil, model.RegisterDto{Alias: "Test"}, nil, nil).ScanNetwork(context.Background(), ips, port)
+	if len(devices) != 2 || devices[0].Alias != "Neighbor" {
+		t.Errorf("without SkipSweep: found %v, want Neighbor then Swept", devices)
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"sync/atomic"
 
 	"github.com/bethropolis/localgo/pkg/model"
+	"github.com/bethropolis/localgo/pkg/network"
 	"go.uber.org/zap"
 )
 
@@ -16,13 +17,14 @@ import (
 type MulticastDiscovery struct {
 	config         *MulticastConfig
 	dto            model.MulticastDto
-	devices        map[string]*model.Device
-	devicesMutex   sync.RWMutex
-	handlers       []func(*model.Device)
-	handlersMu     sync.RWMutex
-	conns          []net.PacketConn
-	connsMu        sync.Mutex
+	registry       atomic.Pointer[Registry]
+	conn           net.PacketConn // shared by every interface
+	connMu         sync.Mutex
 	closed         atomic.Bool
+	drops          dropCounters
+	responses      *responseLimiter
+	suppressed     atomic.Uint64
+	responding     sync.WaitGroup // responses being sent
 	httpDiscoverer *HTTPDiscovery
 	peerCache      *PeerCache
 	logger         *zap.SugaredLogger
@@ -37,19 +39,27 @@ func NewMulticastDiscovery(config *MulticastConfig, dto model.MulticastDto, logg
 		logger = zap.NewNop().Sugar()
 	}
 
-	return &MulticastDiscovery{
-		config:  config,
-		dto:     dto,
-		devices: make(map[string]*model.Device),
-		logger:  logger,
+	md := &MulticastDiscovery{
+		config:    config,
+		dto:       dto,
+		responses: newResponseLimiter(config.ResponseWindow, config.MaxResponsesPerSecond),
+		logger:    logger,
 	}
+	md.registry.Store(NewRegistry())
+	return md
 }
 
-// AddDeviceHandler adds a handler function that will be called when a device is discovered
+// AddDeviceHandler adds a handler function that will be called when a new
+// device is discovered. It is registered with the current registry, so call
+// it after SetRegistry.
 func (md *MulticastDiscovery) AddDeviceHandler(handler func(*model.Device)) {
-	md.handlersMu.Lock()
-	defer md.handlersMu.Unlock()
-	md.handlers = append(md.handlers, handler)
+	md.registry.Load().addFoundHandler(handler)
+}
+
+// SetRegistry makes md record the devices it finds in r, in place of a
+// registry of its own. NewService calls it to share the service's registry.
+func (md *MulticastDiscovery) SetRegistry(r *Registry) {
+	md.registry.Store(r)
 }
 
 // StartListening starts listening for multicast announcements on all suitable interfaces.
@@ -57,12 +67,11 @@ func (md *MulticastDiscovery) AddDeviceHandler(handler func(*model.Device)) {
 func (md *MulticastDiscovery) StartListening(ctx context.Context) error {
 	md.closed.Store(false)
 
-	md.connsMu.Lock()
-	if len(md.conns) > 0 {
-		md.connsMu.Unlock()
+	md.connMu.Lock()
+	defer md.connMu.Unlock()
+	if md.conn != nil {
 		return fmt.Errorf("already listening")
 	}
-	md.connsMu.Unlock()
 
 	addr, err := net.ResolveUDPAddr("udp4", md.config.MulticastAddr)
 	if err != nil {
@@ -99,74 +108,43 @@ func (md *MulticastDiscovery) StartListening(ctx context.Context) error {
 		return fmt.Errorf("no suitable multicast interface found")
 	}
 
-	for _, iface := range targetIfaces {
-		conn, err := net.ListenMulticastUDP("udp4", &iface, addr)
-		if err != nil {
-			md.logger.Warnf("Failed to listen on interface %s: %v", iface.Name, err)
-			continue
-		}
-		conn.SetReadBuffer(2048)
-
-		md.connsMu.Lock()
-		md.conns = append(md.conns, conn)
-		md.connsMu.Unlock()
-
-		go md.listenLoop(ctx, conn)
-
-		md.logger.Debugf("Multicast discovery listening on %s (interface: %s)", md.config.MulticastAddr, iface.Name)
+	// One socket joined on every interface, which other processes on this
+	// host can listen next to.
+	conn, joined, err := network.ListenMulticast(ctx, addr, targetIfaces)
+	if err != nil {
+		return fmt.Errorf("failed to listen on any multicast interface: %w", err)
 	}
+	md.conn = conn
+	go md.listenLoop(ctx, conn)
 
-	md.connsMu.Lock()
-	listening := len(md.conns)
-	md.connsMu.Unlock()
-
-	if listening == 0 {
-		return fmt.Errorf("failed to listen on any multicast interface")
+	for _, iface := range joined {
+		md.logger.Debugf("Multicast discovery listening on %s (interface: %s)", md.config.MulticastAddr, iface.Name)
 	}
-
 	return nil
 }
 
-// Stop stops the multicast discovery and closes all listeners.
+// Stop stops the multicast discovery and closes all listeners, once the
+// responses being sent have gone out.
 func (md *MulticastDiscovery) Stop() {
 	md.closed.Store(true)
-	md.connsMu.Lock()
-	for _, conn := range md.conns {
-		conn.Close()
+	md.connMu.Lock()
+	if md.conn != nil {
+		md.conn.Close()
+		md.conn = nil
 	}
-	md.conns = nil
-	md.connsMu.Unlock()
+	md.connMu.Unlock()
+	md.responding.Wait()
 }
 
 func (md *MulticastDiscovery) updateDevice(device *model.Device) {
-	md.devicesMutex.Lock()
-	md.devices[device.Fingerprint] = device
-	md.devicesMutex.Unlock()
-
 	if md.peerCache != nil {
 		md.peerCache.Save(device)
 	}
-
-	md.handlersMu.RLock()
-	handlers := make([]func(*model.Device), len(md.handlers))
-	copy(handlers, md.handlers)
-	md.handlersMu.RUnlock()
-
-	for _, handler := range handlers {
-		go handler(device)
-	}
+	md.registry.Load().Seen(device)
 }
 
 func (md *MulticastDiscovery) GetDevices() []*model.Device {
-	md.devicesMutex.RLock()
-	defer md.devicesMutex.RUnlock()
-
-	devices := make([]*model.Device, 0, len(md.devices))
-	for _, device := range md.devices {
-		devices = append(devices, device)
-	}
-
-	return devices
+	return md.registry.Load().Devices(0)
 }
 
 func (md *MulticastDiscovery) SetDto(dto model.MulticastDto) {
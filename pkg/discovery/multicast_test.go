@@ -109,6 +109,47 @@ func TestMulticastDiscovery_ReceiveAnnouncement(t *testing.T) {
 	}
 }
 
+// Two listeners on one host, like serve and discover, share the port and
+// both hear an announcement.
+func TestMulticastDiscovery_SharedPort(t *testing.T) {
+	config := DefaultMulticastConfig()
+	config.MulticastAddr = testMulticastAddr
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var found []chan *model.Device
+	for _, fp := range []string{"serve-fp", "discover-fp"} {
+		md := NewMulticastDiscovery(config, model.MulticastDto{Alias: fp, Fingerprint: fp, Port: 53318}, testLoggerMulticast)
+		ch := make(chan *model.Device, 4)
+		md.AddDeviceHandler(func(d *model.Device) {
+			if d.Fingerprint == "sender-fp" {
+				ch <- d
+			}
+		})
+		if err := md.StartListening(ctx); err != nil {
+			if len(found) == 0 {
+				t.Skipf("multicast socket unavailable (CI/sandbox environment): %v", err)
+			}
+			t.Fatalf("second listener on the same port: %v", err)
+		}
+		defer md.Stop()
+		found = append(found, ch)
+	}
+
+	sender := NewMulticastDiscovery(config, model.MulticastDto{Alias: "Sender", Fingerprint: "sender-fp", Port: 53318}, testLoggerMulticast)
+	if err := sender.SendDiscoveryAnnouncement(); err != nil {
+		t.Skipf("sender unable to announce (CI/sandbox environment): %v", err)
+	}
+	for i, ch := range found {
+		select {
+		case <-ch:
+		case <-time.After(2 * time.Second):
+			t.Errorf("listener %d did not receive the announcement", i+1)
+		}
+	}
+}
+
 func TestMulticastDiscovery_IgnoreSelf(t *testing.T) {
 	config := DefaultMulticastConfig()
 	config.MulticastAddr = testMulticastAddr
@@ -129,12 +170,8 @@ func TestMulticastDiscovery_IgnoreSelf(t *testing.T) {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	md.StartListening(ctx)
-	md.connsMu.Lock()
-	connCount := len(md.conns)
-	md.connsMu.Unlock()
-	if connCount == 0 {
-		t.Skipf("multicast socket unavailable (CI/sandbox environment)")
+	if err := md.StartListening(ctx); err != nil {
+		t.Skipf("multicast socket unavailable (CI/sandbox environment): %v", err)
 	}
 	defer md.Stop()
 
@@ -0,0 +1,104 @@
+package discovery
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+
+	"github.com/bethropolis/localgo/pkg/model"
+	"github.com/bethropolis/localgo/pkg/validate"
+)
+
+// MaxPacketSize bounds incoming multicast packets. A LocalSend announcement
+// is a few hundred bytes.
+const MaxPacketSize = 4096
+
+// Why a packet was dropped.
+var (
+	errOversized = errors.New("packet too large")
+	errMalformed = errors.New("malformed packet")
+	errInvalid   = errors.New("invalid announcement")
+)
+
+// dropReportInterval is how often at most dropped packets are logged as a
+// warning, so a noisy or hostile peer cannot flood the log.
+const dropReportInterval = time.Minute
+
+// DroppedPackets counts the multicast packets discovery has ignored since it
+// was created.
+type DroppedPackets struct {
+	Oversized uint64 `json:"oversized"` // larger than MaxPacketSize
+	Malformed uint64 `json:"malformed"` // not a JSON object
+	Invalid   uint64 `json:"invalid"`   // missing or out-of-range fields
+}
+
+// Total returns the number of dropped packets.
+func (d DroppedPackets) Total() uint64 {
+	return d.Oversized + d.Malformed + d.Invalid
+}
+
+// dropCounters is the live form of DroppedPackets.
+type dropCounters struct {
+	oversized, malformed, invalid atomic.Uint64
+	lastReport                    atomic.Int64 // unix nanoseconds
+}
+
+func (c *dropCounters) snapshot() DroppedPackets {
+	return DroppedPackets{
+		Oversized: c.oversized.Load(),
+		Malformed: c.malformed.Load(),
+		Invalid:   c.invalid.Load(),
+	}
+}
+
+// count records a packet dropped for err, and reports whether the totals
+// are due to be logged.
+func (c *dropCounters) count(err error) bool {
+	switch {
+	case errors.Is(err, errOversized):
+		c.oversized.Add(1)
+	case errors.Is(err, errMalformed):
+		c.malformed.Add(1)
+	default:
+		c.invalid.Add(1)
+	}
+	now := time.Now().UnixNano()
+	last := c.lastReport.Load()
+	return now-last >= int64(dropReportInterval) && c.lastReport.CompareAndSwap(last, now)
+}
+
+// decodeMulticastDto parses and checks a multicast packet. Fields it does
+// not know are ignored, so newer protocol versions can add some; a missing
+// port or protocol, as from LocalSend v1, is the protocol's default.
+func decodeMulticastDto(data []byte) (model.MulticastDto, error) {
+	var dto model.MulticastDto
+	if len(data) > MaxPacketSize {
+		return dto, fmt.Errorf("%w: %d bytes", errOversized, len(data))
+	}
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 || data[0] != '{' || !utf8.Valid(data) {
+		return dto, errMalformed
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if err := dec.Decode(&dto); err != nil {
+		return dto, fmt.Errorf("%w: %v", errMalformed, err)
+	}
+	if dec.More() {
+		return dto, fmt.Errorf("%w: data after the object", errMalformed)
+	}
+
+	if err := validate.Multicast(&dto); err != nil {
+		return dto, fmt.Errorf("%w: %v", errInvalid, err)
+	}
+	if dto.Protocol == "" {
+		dto.Protocol = model.ProtocolTypeHTTPS
+	}
+	if dto.Port == 0 {
+		dto.Port = model.DefaultPort
+	}
+	return dto, nil
+}
@@ -0,0 +1,75 @@
+package discovery
+
+import (
+	"errors"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/bethropolis/localgo/pkg/model"
+	"github.com/bethropolis/localgo/pkg/validate"
+)
+
+func TestDecodeMulticastDto(t *testing.T) {
+	dto, err := decodeMulticastDto([]byte(`{"alias":"Phone","fingerprint":"fp","port":53320,"protocol":"http","announce":true,"futureField":[1,2]}`))
+	if err != nil {
+		t.Fatalf("valid packet rejected: %v", err)
+	}
+	if dto.Alias != "Phone" || dto.Port != 53320 || dto.Protocol != model.ProtocolTypeHTTP || !dto.Announce {
+		t.Errorf("decoded %+v", dto)
+	}
+
+	// LocalSend v1 announcements have no port or protocol.
+	dto, err = decodeMulticastDto([]byte(`{"alias":"Old","fingerprint":"fp","announcement":true}`))
+	if err != nil {
+		t.Fatalf("v1 packet rejected: %v", err)
+	}
+	if dto.Port != model.DefaultPort || dto.Protocol != model.ProtocolTypeHTTPS {
+		t.Errorf("v1 packet decoded to port %d, protocol %q", dto.Port, dto.Protocol)
+	}
+
+	tests := []struct {
+		packet string
+		want   error
+	}{
+		{`{"alias":"` + strings.Repeat("a", MaxPacketSize) + `","fingerprint":"fp"}`, errOversized},
+		{``, errMalformed},
+		{`[1,2,3]`, errMalformed},
+		{`{"alias":"Phone","fingerprint":"fp"`, errMalformed},
+		{`{"alias":"Phone","fingerprint":"fp"}{}`, errMalformed},
+		{`{"alias":"Phone","fingerprint":"fp","port":"53317"}`, errMalformed},
+		{"{\"alias\":\"\xff\",\"fingerprint\":\"fp\"}", errMalformed},
+		{`{"alias":"Phone"}`, errInvalid},
+		{`{"fingerprint":"fp"}`, errInvalid},
+		{`{"alias":"Phone","fingerprint":"fp","port":70000}`, errInvalid},
+		{`{"alias":"Phone","fingerprint":"fp","protocol":"ftp"}`, errInvalid},
+		{`{"alias":"` + strings.Repeat("a", validate.MaxAliasLength+1) + `","fingerprint":"fp"}`, errInvalid},
+	}
+	for _, tt := range tests {
+		if _, err := decodeMulticastDto([]byte(tt.packet)); !errors.Is(err, tt.want) {
+			t.Errorf("decodeMulticastDto(%.40q) = %v, want %v", tt.packet, err, tt.want)
+		}
+	}
+}
+
+func TestMulticastDiscovery_DroppedPackets(t *testing.T) {
+	md := NewMulticastDiscovery(nil, model.MulticastDto{Fingerprint: "my-fp"}, testLoggerMulticast)
+	addr := &net.UDPAddr{IP: net.ParseIP("192.168.1.100"), Port: 53317}
+
+	for _, packet := range []string{"junk", "junk", `{"alias":"x"}`} {
+		if err := md.handlePacket([]byte(packet), addr); err == nil {
+			t.Fatalf("handlePacket(%q) succeeded", packet)
+		} else {
+			md.dropPacket(err, addr)
+		}
+	}
+	if d := md.Dropped(); d.Malformed != 2 || d.Invalid != 1 || d.Total() != 3 {
+		t.Errorf("Dropped() = %+v", d)
+	}
+
+	// Only the first drop of a burst is due to be logged.
+	var c dropCounters
+	if !c.count(errMalformed) || c.count(errMalformed) {
+		t.Error("drops are not logged once per interval")
+	}
+}
@@ -2,17 +2,17 @@ package discovery
 
 import (
 	"context"
-	"crypto/tls"
 	"encoding/json"
-	"net"
 	"net/http"
 	"os"
 	"path/filepath"
-	"strconv"
 	"sync"
 	"time"
 
+	"github.com/bethropolis/localgo/pkg/capture"
+	"github.com/bethropolis/localgo/pkg/httputil"
 	"github.com/bethropolis/localgo/pkg/model"
+	"github.com/bethropolis/localgo/pkg/network"
 	"go.uber.org/zap"
 )
 
@@ -172,13 +172,9 @@ func ProbeCached(ctx context.Context, cache *PeerCache, onFound func(*model.Devi
 	}
 
 	client := &http.Client{
-		Timeout: 2 * time.Second,
+		Timeout:   2 * time.Second,
+		Transport: capture.Transport(httputil.Transport()),
 	}
-	tr := &http.Transport{
-		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-	}
-	client.Transport = tr
-	defer tr.CloseIdleConnections()
 
 	var wg sync.WaitGroup
 	for _, device := range peers {
@@ -191,7 +187,7 @@ func ProbeCached(ctx context.Context, cache *PeerCache, onFound func(*model.Devi
 				scheme = "https"
 			}
 
-			url := scheme + "://" + net.JoinHostPort(d.IP, strconv.Itoa(d.Port)) + "/api/localsend/v2/info"
+			url := scheme + "://" + network.URLHost(d.IP, d.Port) + "/api/localsend/v2/info"
 			req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 			if err != nil {
 				return
@@ -0,0 +1,110 @@
+package discovery
+
+import (
+	"sync"
+	"time"
+
+	"github.com/bethropolis/localgo/pkg/model"
+)
+
+// Registry holds the devices discovery has found and the observers waiting
+// on them, under one lock. A Service shares its registry with its
+// discoverers, so every sighting is recorded in one place and each found or
+// lost device is reported once, in the order the changes happened.
+type Registry struct {
+	mu            sync.RWMutex
+	devices       map[string]*model.Device
+	foundHandlers []func(*model.Device)
+	lostHandlers  []func(*model.Device)
+	subscribers   map[*subscriber]struct{}
+}
+
+// NewRegistry returns an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{
+		devices:     make(map[string]*model.Device),
+		subscribers: make(map[*subscriber]struct{}),
+	}
+}
+
+// Seen records a sighting of device and reports whether it is new. A new
+// device is reported to the found handlers and subscribers; a known one
+// only has its last seen time updated.
+func (r *Registry) Seen(device *model.Device) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if existing, ok := r.devices[device.Fingerprint]; ok {
+		existing.UpdateLastSeen()
+		return false
+	}
+	r.devices[device.Fingerprint] = device
+	for _, handler := range r.foundHandlers {
+		go handler(device)
+	}
+	r.publish(DeviceEvent{Type: DeviceFound, Device: device})
+	return true
+}
+
+// Prune forgets the devices not seen for timeout, reports each to the lost
+// handlers and subscribers, and returns them.
+func (r *Registry) Prune(timeout time.Duration) []*model.Device {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var lost []*model.Device
+	for fingerprint, device := range r.devices {
+		if !device.IsStale(timeout) {
+			continue
+		}
+		delete(r.devices, fingerprint)
+		lost = append(lost, device)
+		for _, handler := range r.lostHandlers {
+			go handler(device)
+		}
+		r.publish(DeviceEvent{Type: DeviceLost, Device: device})
+	}
+	return lost
+}
+
+// Devices returns the devices seen within maxAge, or all of them if maxAge
+// is 0.
+func (r *Registry) Devices(maxAge time.Duration) []*model.Device {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	devices := make([]*model.Device, 0, len(r.devices))
+	for _, device := range r.devices {
+		if maxAge == 0 || !device.IsStale(maxAge) {
+			devices = append(devices, device)
+		}
+	}
+	return devices
+}
+
+// Device returns the device with the given fingerprint if it was seen
+// within maxAge, or at all if maxAge is 0.
+func (r *Registry) Device(fingerprint string, maxAge time.Duration) *model.Device {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	device, ok := r.devices[fingerprint]
+	if !ok || (maxAge != 0 && device.IsStale(maxAge)) {
+		return nil
+	}
+	return device
+}
+
+// addFoundHandler and addLostHandler back the deprecated callback methods
+// of Service and MulticastDiscovery. Handlers run in their own goroutines.
+func (r *Registry) addFoundHandler(handler func(*model.Device)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.foundHandlers = append(r.foundHandlers, handler)
+}
+
+func (r *Registry) addLostHandler(handler func(*model.Device)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.lostHandlers = append(r.lostHandlers, handler)
+}
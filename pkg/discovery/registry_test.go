@@ -0,0 +1,68 @@
+package discovery
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bethropolis/localgo/pkg/model"
+	"github.com/stretchr/testify/assert"
+)
+
+// The multicast discoverer records into the service's registry, so a
+// device it hears is reported once through the service.
+func TestService_SharesRegistryWithMulticast(t *testing.T) {
+	md := NewMulticastDiscovery(nil, model.MulticastDto{Fingerprint: "my-fp"}, testLoggerMulticast)
+	service := NewService(nil, md, testLoggerService)
+	events, unsubscribe := service.Subscribe(context.Background())
+	defer unsubscribe()
+
+	data, _ := json.Marshal(model.MulticastDto{Alias: "Peer", Fingerprint: "peer-fp"})
+	addr := &net.UDPAddr{IP: net.ParseIP("192.168.1.100"), Port: 53317}
+	for range 3 {
+		if err := md.handlePacket(data, addr); err != nil {
+			t.Fatalf("handlePacket: %v", err)
+		}
+	}
+
+	select {
+	case ev := <-events:
+		assert.Equal(t, DeviceFound, ev.Type)
+		assert.Equal(t, "Peer", ev.Device.Alias)
+	case <-time.After(time.Second):
+		t.Fatal("device not reported by the service")
+	}
+	select {
+	case ev := <-events:
+		t.Errorf("%s %s reported again", ev.Type, ev.Device.Alias)
+	case <-time.After(50 * time.Millisecond):
+	}
+	assert.Same(t, service.GetDevice("peer-fp"), md.GetDevices()[0])
+}
+
+// Sightings, handler registration and subscriptions from many goroutines
+// at once, for the race detector.
+func TestRegistry_Concurrent(t *testing.T) {
+	r := NewRegistry()
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			r.Subscribe(ctx, 0)
+			r.addFoundHandler(func(*model.Device) {})
+			for j := range 50 {
+				r.Seen(&model.Device{Fingerprint: string(rune('a'+i)) + string(rune('a'+j%5)), LastSeen: time.Now()})
+				r.Devices(time.Minute)
+			}
+			r.Prune(time.Minute)
+		}()
+	}
+	wg.Wait()
+	assert.Len(t, r.Devices(0), 8*5)
+}
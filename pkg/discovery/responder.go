@@ -0,0 +1,67 @@
+package discovery
+
+import (
+	"sync"
+	"time"
+)
+
+// responseLimiter decides which announcements get a response. Each peer is
+// answered at most once per window, which suppresses the duplicates of an
+// announcement sent several times in a row, and all peers together at most
+// perSecond times a second, so a room full of devices announcing at once
+// does not set off a storm of responses.
+type responseLimiter struct {
+	window    time.Duration
+	perSecond float64
+
+	mu        sync.Mutex
+	answered  map[string]time.Time // peer -> last response
+	tokens    float64
+	last      time.Time
+	lastSweep time.Time
+}
+
+// newResponseLimiter returns a limiter; a zero window or perSecond turns that
+// limit off.
+func newResponseLimiter(window time.Duration, perSecond int) *responseLimiter {
+	return &responseLimiter{
+		window:    window,
+		perSecond: float64(perSecond),
+		answered:  make(map[string]time.Time),
+		tokens:    float64(perSecond),
+	}
+}
+
+// allow reports whether peer may be answered now, and if so counts the
+// response.
+func (l *responseLimiter) allow(peer string, now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Sub(l.lastSweep) > l.window {
+		for k, t := range l.answered {
+			if now.Sub(t) >= l.window {
+				delete(l.answered, k)
+			}
+		}
+		l.lastSweep = now
+	}
+	if t, ok := l.answered[peer]; ok && now.Sub(t) < l.window {
+		return false
+	}
+
+	if l.perSecond > 0 {
+		if !l.last.IsZero() {
+			l.tokens = min(l.perSecond, l.tokens+now.Sub(l.last).Seconds()*l.perSecond)
+		}
+		l.last = now
+		if l.tokens < 1 {
+			return false
+		}
+		l.tokens--
+	}
+	if l.window > 0 {
+		l.answered[peer] = now
+	}
+	return true
+}
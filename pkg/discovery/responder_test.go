@@ -0,0 +1,44 @@
+package discovery
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestResponseLimiter(t *testing.T) {
+	l := newResponseLimiter(5*time.Second, 10)
+	now := time.Unix(1000, 0)
+
+	if !l.allow("a", now) {
+		t.Fatal("first announcement not answered")
+	}
+	if l.allow("a", now.Add(time.Second)) {
+		t.Error("repeated announcement answered within the window")
+	}
+	if !l.allow("a", now.Add(5*time.Second)) {
+		t.Error("announcement after the window not answered")
+	}
+
+	// A classroom announcing at once: the burst left is answered, the
+	// rest wait for the bucket to refill.
+	answered := 0
+	for i := range 40 {
+		if l.allow(fmt.Sprintf("peer%d", i), now.Add(5*time.Second)) {
+			answered++
+		}
+	}
+	if answered != 9 {
+		t.Errorf("%d of 40 simultaneous peers answered, want 9", answered)
+	}
+	if !l.allow("peer39", now.Add(6*time.Second)) {
+		t.Error("peer not answered once the rate allows")
+	}
+
+	unlimited := newResponseLimiter(0, 0)
+	for range 3 {
+		if !unlimited.allow("a", now) {
+			t.Fatal("limiter without limits suppressed a response")
+		}
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/bethropolis/localgo/pkg/model"
+	"github.com/bethropolis/localgo/pkg/network"
 	"go.uber.org/zap"
 )
 
@@ -15,10 +16,10 @@ import (
 type Service struct {
 	config        *ServiceConfig
 	multicast     MulticastDiscoverer
-	devices       map[string]*model.Device
-	devicesMutex  sync.RWMutex
-	handlers      []func(*model.Device)
-	handlersMutex sync.RWMutex
+	registry      *Registry
+	handlersMutex sync.RWMutex // guards netHandlers
+	netHandlers   []func(network.Change)
+	rebindMutex   sync.Mutex // keeps a network change from rebinding after Stop
 	announceTimer *time.Timer
 	peerCache     *PeerCache
 	stopCh        chan struct{}
@@ -30,8 +31,9 @@ type Service struct {
 type ServiceConfig struct {
 	MulticastConfig    *MulticastConfig
 	AnnounceInterval   time.Duration
-	DeviceTimeout      time.Duration
+	DeviceTimeout      time.Duration // devices not seen for this long are lost
 	EnableAnnouncement bool
+	WatchNetwork       bool // rebind and announce again when the local addresses change
 }
 
 // DefaultServiceConfig returns a default configuration for the discovery service
@@ -41,6 +43,7 @@ func DefaultServiceConfig() *ServiceConfig {
 		AnnounceInterval:   30 * time.Second,
 		DeviceTimeout:      2 * time.Minute,
 		EnableAnnouncement: true,
+		WatchNetwork:       true,
 	}
 }
 
@@ -55,14 +58,17 @@ func NewService(config *ServiceConfig, multicast MulticastDiscoverer, logger *za
 
 	s := &Service{
 		config:    config,
-		devices:   make(map[string]*model.Device),
+		registry:  NewRegistry(),
 		multicast: multicast,
 		stopCh:    make(chan struct{}),
 		logger:    logger,
 	}
 
-	// ALWAYS ensure multicast propagates raw events upward to the Service state
-	if s.multicast != nil {
+	// The multicast discoverer records into the service's registry where
+	// it can; otherwise its sightings are passed on.
+	if mc, ok := s.multicast.(interface{ SetRegistry(*Registry) }); ok {
+		mc.SetRegistry(s.registry)
+	} else if s.multicast != nil {
 		s.multicast.AddDeviceHandler(func(device *model.Device) {
 			s.updateDevice(device)
 		})
@@ -90,11 +96,18 @@ func (s *Service) Start(ctx context.Context, dto model.MulticastDto) error {
 	if s.config.EnableAnnouncement {
 		s.startAnnouncementLoop(ctx)
 	}
+	if s.config.DeviceTimeout > 0 {
+		go s.pruneLoop(ctx)
+	}
 
 	if err := s.multicast.SendDiscoveryAnnouncement(); err != nil {
 		s.logger.Errorf("Failed to send initial discovery announcement: %v", err)
 	}
 
+	if s.config.WatchNetwork {
+		go s.watchNetwork(ctx, network.WatchAddresses)
+	}
+
 	// Probe cached peers in the background
 	if s.peerCache != nil {
 		probeCtx, cancelProbe := context.WithTimeout(ctx, 10*time.Second)
@@ -116,7 +129,9 @@ func (s *Service) Stop() {
 		close(s.stopCh)
 	})
 	if s.multicast != nil {
+		s.rebindMutex.Lock()
 		s.multicast.Stop()
+		s.rebindMutex.Unlock()
 	}
 
 	if s.announceTimer != nil {
@@ -160,65 +175,131 @@ func (s *Service) Discover(ctx context.Context, dto model.MulticastDto) ([]*mode
 
 // GetDevices returns all currently known devices
 func (s *Service) GetDevices() []*model.Device {
-	s.devicesMutex.RLock()
-	defer s.devicesMutex.RUnlock()
-
-	devices := make([]*model.Device, 0, len(s.devices))
-	for _, device := range s.devices {
-		if !device.IsStale(s.config.DeviceTimeout) {
-			devices = append(devices, device)
-		}
-	}
-
-	return devices
+	return s.registry.Devices(s.config.DeviceTimeout)
 }
 
 // GetDevice returns a specific device by ID
 func (s *Service) GetDevice(id string) *model.Device {
-	s.devicesMutex.RLock()
-	defer s.devicesMutex.RUnlock()
-
-	if device, ok := s.devices[id]; ok {
-		if !device.IsStale(s.config.DeviceTimeout) {
-			return device
-		}
-	}
+	return s.registry.Device(id, s.config.DeviceTimeout)
+}
 
-	return nil
+// Registry returns the registry the service and its discoverers record
+// devices in. Pass it to HTTPDiscovery.SetRegistry to have scans reported
+// to the service's subscribers too.
+func (s *Service) Registry() *Registry {
+	return s.registry
 }
 
-// AddDeviceHandler adds a handler for device discovery events
+// AddDeviceHandler adds a handler for device discovery events. Each call
+// runs in its own goroutine, so calls may arrive out of order.
+//
+// Deprecated: Use Subscribe, which delivers events in order and can be
+// ended.
 func (s *Service) AddDeviceHandler(handler func(*model.Device)) {
+	s.registry.addFoundHandler(handler)
+}
+
+// AddDeviceLostHandler adds a handler called once a device has not been seen
+// for ServiceConfig.DeviceTimeout and has been forgotten. Should it appear
+// again, the device handlers are called for it as for a new device.
+//
+// Deprecated: Use Subscribe and watch for DeviceLost events.
+func (s *Service) AddDeviceLostHandler(handler func(*model.Device)) {
+	s.registry.addLostHandler(handler)
+}
+
+// AddNetworkHandler adds a handler called after the service has rebound to
+// a changed network, see ServiceConfig.WatchNetwork.
+func (s *Service) AddNetworkHandler(handler func(network.Change)) {
 	s.handlersMutex.Lock()
 	defer s.handlersMutex.Unlock()
-	s.handlers = append(s.handlers, handler)
+	s.netHandlers = append(s.netHandlers, handler)
 }
 
-// updateDevice updates the device list with a newly discovered device
-func (s *Service) updateDevice(device *model.Device) {
-	s.devicesMutex.Lock()
-	defer s.devicesMutex.Unlock()
-
-	existingDevice, exists := s.devices[device.Fingerprint]
-	if exists {
-		// Update last seen timestamp
-		existingDevice.UpdateLastSeen()
-	} else {
-		// Add new device
-		s.devices[device.Fingerprint] = device
-
-		// Notify Service-level handlers about the new device ONLY ONCE (debounces the bursts)
+// watchNetwork rebinds the multicast listener and announces this device
+// again each time the local addresses change, such as when switching Wi-Fi
+// networks or docking, so devices on the new network see it without waiting
+// for the next periodic announcement. watchAddresses is
+// network.WatchAddresses, replaced in tests.
+func (s *Service) watchNetwork(ctx context.Context, watchAddresses func(context.Context) <-chan network.Change) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-s.stopCh:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	for change := range watchAddresses(ctx) {
+		s.logger.Infof("Network changed (added %v, removed %v): rebinding multicast discovery", change.Added, change.Removed)
+		if !s.rebind(ctx) {
+			return
+		}
+		if err := s.multicast.SendDiscoveryAnnouncement(); err != nil {
+			s.logger.Warnf("Failed to announce on the changed network: %v", err)
+		}
+
 		s.handlersMutex.RLock()
-		handlers := make([]func(*model.Device), len(s.handlers))
-		copy(handlers, s.handlers)
+		handlers := make([]func(network.Change), len(s.netHandlers))
+		copy(handlers, s.netHandlers)
 		s.handlersMutex.RUnlock()
-
 		for _, handler := range handlers {
-			go handler(device)
+			go handler(change)
+		}
+	}
+}
+
+// rebind restarts the multicast listener on the interfaces there are now.
+// It reports false if the service has been stopped meanwhile.
+func (s *Service) rebind(ctx context.Context) bool {
+	s.rebindMutex.Lock()
+	defer s.rebindMutex.Unlock()
+	select {
+	case <-s.stopCh:
+		return false
+	default:
+	}
+	s.multicast.Stop()
+	if err := s.multicast.StartListening(ctx); err != nil {
+		// No interface may be up between two networks; the next
+		// change tries again.
+		s.logger.Warnf("Failed to rebind multicast discovery: %v", err)
+	}
+	return true
+}
+
+// updateDevice records a sighting of device, reporting it if it is new.
+func (s *Service) updateDevice(device *model.Device) {
+	s.registry.Seen(device)
+}
+
+// pruneLoop forgets stale devices until the service stops, checking often
+// enough that a device is reported lost soon after it times out.
+func (s *Service) pruneLoop(ctx context.Context) {
+	ticker := time.NewTicker(max(s.config.DeviceTimeout/4, time.Second))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.stopCh:
+			return
+		case <-ticker.C:
+			s.pruneStale()
 		}
 	}
 }
 
+// pruneStale forgets the devices that have timed out; the registry reports
+// each as lost.
+func (s *Service) pruneStale() {
+	for _, device := range s.registry.Prune(s.config.DeviceTimeout) {
+		s.logger.Debugf("Device lost: %s (%s)", device.Alias, device.IP)
+	}
+}
+
 // startAnnouncementLoop starts a periodic announcement loop
 func (s *Service) startAnnouncementLoop(ctx context.Context) {
 	s.announceTimer = time.NewTimer(s.config.AnnounceInterval)
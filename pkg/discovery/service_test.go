@@ -2,10 +2,14 @@ package discovery
 
 import (
 	"context"
+	"fmt"
+	"net"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/bethropolis/localgo/pkg/model"
+	"github.com/bethropolis/localgo/pkg/network"
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/zap"
 )
@@ -64,3 +68,145 @@ func TestService_Start(t *testing.T) {
 	assert.True(t, multicast.startListeningCalled)
 	assert.True(t, multicast.sendAnnouncementCalled)
 }
+
+// countingMulticast counts the calls the service makes, for tests that run
+// it from several goroutines.
+type countingMulticast struct {
+	listens       atomic.Int32
+	announcements atomic.Int32
+	stops         atomic.Int32
+}
+
+func (m *countingMulticast) AddDeviceHandler(handler func(*model.Device)) {}
+func (m *countingMulticast) StartListening(ctx context.Context) error {
+	m.listens.Add(1)
+	return nil
+}
+func (m *countingMulticast) SendDiscoveryAnnouncement() error {
+	m.announcements.Add(1)
+	return nil
+}
+func (m *countingMulticast) Stop()                         { m.stops.Add(1) }
+func (m *countingMulticast) SetDto(dto model.MulticastDto) {}
+
+func TestService_WatchNetwork_RebindsAndAnnounces(t *testing.T) {
+	multicast := &countingMulticast{}
+	service := NewService(nil, multicast, testLoggerService)
+	changes := make(chan network.Change)
+	handled := make(chan network.Change, 1)
+	service.AddNetworkHandler(func(c network.Change) { handled <- c })
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		service.watchNetwork(context.Background(), func(context.Context) <-chan network.Change { return changes })
+	}()
+
+	change := network.Change{Added: []net.IP{net.ParseIP("10.1.2.3")}}
+	changes <- change
+	select {
+	case got := <-handled:
+		assert.Equal(t, change.Added, got.Added)
+	case <-time.After(time.Second):
+		t.Fatal("network handler not called")
+	}
+	assert.Equal(t, int32(1), multicast.stops.Load())
+	assert.Equal(t, int32(1), multicast.listens.Load())
+	assert.Equal(t, int32(1), multicast.announcements.Load())
+
+	// After Stop a change no longer rebinds, and the watch ends.
+	service.Stop()
+	select {
+	case changes <- change:
+	case <-time.After(time.Second):
+	}
+	close(changes)
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("watchNetwork did not return after Stop")
+	}
+	assert.Equal(t, int32(1), multicast.listens.Load())
+}
+
+func TestService_PruneStale_ReportsLostDevices(t *testing.T) {
+	cfg := DefaultServiceConfig()
+	cfg.DeviceTimeout = time.Minute
+	service := NewService(cfg, &MockMulticastDiscovery{}, testLoggerService)
+
+	lost := make(chan *model.Device, 2)
+	service.AddDeviceLostHandler(func(d *model.Device) { lost <- d })
+
+	gone := &model.Device{Alias: "Gone", Fingerprint: "fp-gone", LastSeen: time.Now().Add(-2 * time.Minute)}
+	here := &model.Device{Alias: "Here", Fingerprint: "fp-here", LastSeen: time.Now()}
+	service.updateDevice(gone)
+	service.updateDevice(here)
+
+	service.pruneStale()
+	select {
+	case d := <-lost:
+		assert.Equal(t, "Gone", d.Alias)
+	case <-time.After(time.Second):
+		t.Fatal("no device lost")
+	}
+	assert.Nil(t, service.GetDevice("fp-gone"))
+	assert.NotNil(t, service.GetDevice("fp-here"))
+
+	service.pruneStale()
+	select {
+	case d := <-lost:
+		t.Errorf("%s reported lost twice", d.Alias)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestService_Subscribe(t *testing.T) {
+	cfg := DefaultServiceConfig()
+	cfg.DeviceTimeout = time.Minute
+	service := NewService(cfg, &MockMulticastDiscovery{}, testLoggerService)
+
+	known := &model.Device{Alias: "Known", Fingerprint: "fp-known", LastSeen: time.Now()}
+	service.updateDevice(known)
+
+	events, unsubscribe := service.Subscribe(context.Background())
+	// More changes than the channel holds, before anything is read.
+	var want []string
+	for i := range deviceEventBuffer * 2 {
+		d := &model.Device{Alias: fmt.Sprintf("D%d", i), Fingerprint: fmt.Sprintf("fp-%d", i), LastSeen: time.Now()}
+		service.updateDevice(d)
+		want = append(want, "found "+d.Alias)
+	}
+	gone := &model.Device{Alias: "Gone", Fingerprint: "fp-gone", LastSeen: time.Now().Add(-2 * time.Minute)}
+	service.updateDevice(gone)
+	service.pruneStale()
+	want = append([]string{"found Known"}, append(want, "found Gone", "lost Gone")...)
+
+	for _, w := range want {
+		select {
+		case ev := <-events:
+			assert.Equal(t, w, string(ev.Type)+" "+ev.Device.Alias)
+		case <-time.After(time.Second):
+			t.Fatalf("no event, want %s", w)
+		}
+	}
+
+	unsubscribe()
+	unsubscribe()
+	service.updateDevice(&model.Device{Alias: "Late", Fingerprint: "fp-late", LastSeen: time.Now()})
+	for ev := range events {
+		t.Errorf("event %s %s after unsubscribing", ev.Type, ev.Device.Alias)
+	}
+}
+
+func TestService_Subscribe_EndsWithContext(t *testing.T) {
+	service := NewService(nil, &MockMulticastDiscovery{}, testLoggerService)
+	ctx, cancel := context.WithCancel(context.Background())
+	events, _ := service.Subscribe(ctx)
+	cancel()
+	select {
+	case _, ok := <-events:
+		assert.False(t, ok)
+	case <-time.After(time.Second):
+		t.Fatal("channel not closed when the context ended")
+	}
+}
@@ -0,0 +1,129 @@
+package discovery
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/bethropolis/localgo/pkg/model"
+)
+
+// DeviceEventType tells what happened to a device.
+type DeviceEventType string
+
+const (
+	DeviceFound DeviceEventType = "found"
+	DeviceLost  DeviceEventType = "lost" // not seen for ServiceConfig.DeviceTimeout
+)
+
+// DeviceEvent is a change to the devices the service knows.
+type DeviceEvent struct {
+	Type   DeviceEventType
+	Device *model.Device
+}
+
+// deviceEventBuffer is the channel capacity of each subscription.
+const deviceEventBuffer = 16
+
+// subscriber queues the events of one subscription and feeds them to its
+// channel in order, so the discovery loop never waits for a slow reader.
+type subscriber struct {
+	ch   chan DeviceEvent
+	wake chan struct{} // signalled when the queue grows
+	done chan struct{} // closed on unsubscribe
+
+	mu    sync.Mutex
+	queue []DeviceEvent
+}
+
+// Subscribe returns a channel of device events and a function that ends the
+// subscription. The devices already known are delivered first, as found
+// events, followed by each later change in the order it happened. Events are
+// queued rather than dropped for a slow reader. The channel is closed once
+// the subscription ends, by calling the function or by ctx ending.
+func (s *Service) Subscribe(ctx context.Context) (<-chan DeviceEvent, func()) {
+	return s.registry.Subscribe(ctx, s.config.DeviceTimeout)
+}
+
+// Subscribe is Service.Subscribe for the devices of r. The devices already
+// known are those seen within maxAge, or all of them if maxAge is 0.
+func (r *Registry) Subscribe(ctx context.Context, maxAge time.Duration) (<-chan DeviceEvent, func()) {
+	sub := &subscriber{
+		ch:   make(chan DeviceEvent, deviceEventBuffer),
+		wake: make(chan struct{}, 1),
+		done: make(chan struct{}),
+	}
+
+	// Registering under the same lock as the known devices keeps a change
+	// from slipping in between.
+	r.mu.Lock()
+	for _, device := range r.devices {
+		if maxAge == 0 || !device.IsStale(maxAge) {
+			sub.push(DeviceEvent{Type: DeviceFound, Device: device})
+		}
+	}
+	r.subscribers[sub] = struct{}{}
+	r.mu.Unlock()
+
+	go sub.run()
+
+	var once sync.Once
+	end := func() {
+		once.Do(func() {
+			r.mu.Lock()
+			delete(r.subscribers, sub)
+			r.mu.Unlock()
+			close(sub.done)
+		})
+	}
+	stop := context.AfterFunc(ctx, end)
+	return sub.ch, func() {
+		stop()
+		end()
+	}
+}
+
+// publish queues ev for every subscriber. Callers hold r.mu, so events
+// reach subscribers in the order the devices changed.
+func (r *Registry) publish(ev DeviceEvent) {
+	for sub := range r.subscribers {
+		sub.push(ev)
+	}
+}
+
+func (sub *subscriber) push(ev DeviceEvent) {
+	sub.mu.Lock()
+	sub.queue = append(sub.queue, ev)
+	sub.mu.Unlock()
+	select {
+	case sub.wake <- struct{}{}:
+	default:
+	}
+}
+
+// run delivers the queued events until the subscription ends.
+func (sub *subscriber) run() {
+	defer close(sub.ch)
+	for {
+		sub.mu.Lock()
+		if len(sub.queue) == 0 {
+			sub.mu.Unlock()
+			select {
+			case <-sub.wake:
+				continue
+			case <-sub.done:
+				return
+			}
+		}
+		ev := sub.queue[0]
+		sub.queue[0] = DeviceEvent{}
+		sub.queue = sub.queue[1:]
+		sub.mu.Unlock()
+
+		select {
+		case sub.ch <- ev:
+		case <-sub.done:
+			return
+		}
+	}
+}
@@ -0,0 +1,81 @@
+// Package doctor diagnoses the usual reasons devices cannot find or reach
+// each other: multicast that never arrives, a port taken by another program,
+// a firewall dropping connections, a broken TLS identity or a download
+// directory that cannot be written.
+package doctor
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/bethropolis/localgo/pkg/crypto"
+)
+
+// Finding statuses.
+const (
+	StatusOK   = "ok"
+	StatusWarn = "warn"
+	StatusFail = "fail"
+)
+
+// Finding is the result of one check.
+type Finding struct {
+	Check   string `json:"check"`
+	Status  string `json:"status"`
+	Message string `json:"message"`
+	Hint    string `json:"hint,omitempty"` // what to do about it
+}
+
+// Options describes the setup to check.
+type Options struct {
+	Port           int    // server port; 0 means any free port
+	MulticastGroup string // e.g. 224.0.0.167
+	MulticastPort  int    // UDP port for discovery
+	Interface      string // check only this interface, if set
+	HTTPS          bool
+	Alias          string // compared with the name in a generated certificate
+	Security       *crypto.StoredSecurityContext
+	SecurityPath   string                  // where Security is stored, for hints
+	Identity       crypto.IdentityProvider // replaces Security and CertFile if set
+	CertFile       string                  // custom certificate, replaces Security if set
+	KeyFile        string
+	DownloadDir    string
+	Timeout        time.Duration // per network probe
+}
+
+// Run performs every check and returns the findings in order.
+func Run(ctx context.Context, opts Options) []Finding {
+	if opts.Timeout <= 0 {
+		opts.Timeout = time.Second
+	}
+	ifaces, findings := checkInterfaces(opts.Interface)
+	findings = append(findings, checkMulticast(ctx, ifaces, opts)...)
+	findings = append(findings, checkPort(ctx, opts)...)
+	findings = append(findings, checkReachability(ctx, ifaces, opts)...)
+	findings = append(findings, checkCertificate(opts, time.Now())...)
+	findings = append(findings, checkDownloadDir(opts.DownloadDir)...)
+	return findings
+}
+
+// Failed reports whether any finding is a failure.
+func Failed(findings []Finding) bool {
+	for _, f := range findings {
+		if f.Status == StatusFail {
+			return true
+		}
+	}
+	return false
+}
+
+func pass(check, format string, a ...any) Finding {
+	return Finding{Check: check, Status: StatusOK, Message: fmt.Sprintf(format, a...)}
+}
+
+func warn(check, hint, format string, a ...any) Finding {
+	return Finding{Check: check, Status: StatusWarn, Message: fmt.Sprintf(format, a...), Hint: hint}
+}
+
+func fail(check, hint, format string, a ...any) Finding {
+	return Finding{Check: check, Status: StatusFail, Message: fmt.Sprintf(format, a...), Hint: hint}
+}
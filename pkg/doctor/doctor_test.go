@@ -0,0 +1,99 @@
+package doctor
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bethropolis/localgo/pkg/crypto"
+	"go.uber.org/zap"
+)
+
+func statuses(findings []Finding) []string {
+	var s []string
+	for _, f := range findings {
+		s = append(s, f.Status)
+	}
+	return s
+}
+
+func TestCheckCertificate(t *testing.T) {
+	sec, err := crypto.GenerateSecurityContext("Doctor", zap.NewNop().Sugar())
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := Options{HTTPS: true, Security: sec, SecurityPath: "/tmp/security.json"}
+	now := time.Now()
+
+	if got := checkCertificate(opts, now); len(got) != 1 || got[0].Status != StatusOK {
+		t.Errorf("fresh identity: got %+v, want one ok finding", got)
+	}
+	if got := checkCertificate(opts, now.AddDate(20, 0, 0)); got[0].Status != StatusFail {
+		t.Errorf("expired certificate: got %v, want fail", statuses(got))
+	}
+
+	bad := *sec
+	bad.CertificateHash = "0000"
+	opts.Security = &bad
+	if got := checkCertificate(opts, now); len(got) != 2 || got[1].Status != StatusWarn {
+		t.Errorf("wrong fingerprint: got %v, want ok then warn", statuses(got))
+	}
+
+	other, err := crypto.GenerateSecurityContext("Other", zap.NewNop().Sugar())
+	if err != nil {
+		t.Fatal(err)
+	}
+	bad.PrivateKey = other.PrivateKey
+	if got := checkCertificate(opts, now); got[0].Status != StatusFail {
+		t.Errorf("mismatched key: got %v, want fail", statuses(got))
+	}
+
+	opts.Security = sec
+	opts.Alias = "Renamed"
+	if got := checkCertificate(opts, now); len(got) != 2 || got[1].Status != StatusWarn {
+		t.Errorf("alias changed: got %v, want ok then warn", statuses(got))
+	}
+
+	if got := checkCertificate(Options{}, now); got[0].Status != StatusOK {
+		t.Errorf("HTTP only: got %v, want ok", statuses(got))
+	}
+}
+
+func TestCheckDownloadDir(t *testing.T) {
+	dir := t.TempDir()
+	got := checkDownloadDir(filepath.Join(dir, "not", "yet"))
+	if len(got) != 2 || got[0].Status != StatusOK || got[1].Status == StatusFail {
+		t.Errorf("missing but creatable directory: got %+v", got)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "not")); !os.IsNotExist(err) {
+		t.Error("the check should not create the download directory")
+	}
+
+	file := filepath.Join(dir, "file")
+	if err := os.WriteFile(file, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if got := checkDownloadDir(file); len(got) != 1 || got[0].Status != StatusFail {
+		t.Errorf("file as download directory: got %+v, want one failure", got)
+	}
+}
+
+func TestCheckPort(t *testing.T) {
+	ln, err := net.Listen("tcp", ":0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := ln.Addr().(*net.TCPAddr).Port
+	opts := Options{Port: port, Timeout: 200 * time.Millisecond}
+
+	if got := checkPort(context.Background(), opts); got[0].Status != StatusFail {
+		t.Errorf("port held by another program: got %+v, want fail", got)
+	}
+	ln.Close()
+	if got := checkPort(context.Background(), opts); got[0].Status != StatusOK {
+		t.Errorf("free port: got %+v, want ok", got)
+	}
+}
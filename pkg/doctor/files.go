@@ -0,0 +1,107 @@
+package doctor
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/bethropolis/localgo/pkg/cli"
+	"github.com/bethropolis/localgo/pkg/config"
+	"github.com/bethropolis/localgo/pkg/crypto"
+	"github.com/bethropolis/localgo/pkg/storage"
+)
+
+// lowDiskSpace is the free space below which checkDownloadDir warns.
+const lowDiskSpace = 1 << 30
+
+// checkCertificate checks that the TLS certificate and key belong together
+// and are valid at now, and that the stored fingerprint matches. A generated
+// certificate should also carry the current alias.
+func checkCertificate(opts Options, now time.Time) []Finding {
+	const check = "certificate"
+	if !opts.HTTPS {
+		return []Finding{pass(check, "HTTPS is off, no certificate is used")}
+	}
+
+	var pair tls.Certificate
+	var err error
+	regenerate := fmt.Sprintf("Run `localgo info --regenerate-cert` to replace the identity in %s (other devices will see a new fingerprint).", opts.SecurityPath)
+	if opts.Identity != nil {
+		regenerate = "Check the identity provider the certificate comes from."
+		pair, err = opts.Identity.TLSCertificate()
+	} else if opts.CertFile != "" {
+		regenerate = "Check --tls-cert and --tls-key, or unset them to use the generated identity."
+		pair, err = tls.LoadX509KeyPair(opts.CertFile, opts.KeyFile)
+	} else if opts.Security == nil {
+		return []Finding{fail(check, regenerate, "No TLS identity is loaded")}
+	} else {
+		pair, err = tls.X509KeyPair([]byte(opts.Security.Certificate), []byte(opts.Security.PrivateKey))
+	}
+	if err != nil {
+		return []Finding{fail(check, regenerate, "The TLS certificate and key are unusable: %v", err)}
+	}
+	cert, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		return []Finding{fail(check, regenerate, "The TLS certificate cannot be parsed: %v", err)}
+	}
+
+	var findings []Finding
+	switch {
+	case now.Before(cert.NotBefore):
+		findings = append(findings, fail(check, "Check the system clock.", "The TLS certificate is not valid until %s", cert.NotBefore.Format(time.DateOnly)))
+	case now.After(cert.NotAfter):
+		findings = append(findings, fail(check, regenerate, "The TLS certificate expired on %s", cert.NotAfter.Format(time.DateOnly)))
+	case cert.NotAfter.Sub(now) < crypto.ExpiryWarning:
+		findings = append(findings, warn(check, regenerate, "The TLS certificate expires on %s", cert.NotAfter.Format(time.DateOnly)))
+	default:
+		findings = append(findings, pass(check, "The TLS certificate is valid until %s", cert.NotAfter.Format(time.DateOnly)))
+	}
+
+	generated := opts.Identity == nil && opts.CertFile == "" && opts.Security != nil
+	if generated && opts.Alias != "" && cert.Subject.CommonName != opts.Alias {
+		findings = append(findings, warn(check, regenerate, "The TLS certificate was issued for %q, not the alias %q", cert.Subject.CommonName, opts.Alias))
+	}
+	if opts.CertFile == "" && opts.Security != nil {
+		sum := sha256.Sum256(cert.Raw)
+		if hex.EncodeToString(sum[:]) != opts.Security.CertificateHash {
+			findings = append(findings, warn(check, regenerate, "The stored fingerprint does not match the certificate"))
+		}
+	}
+	return findings
+}
+
+// checkDownloadDir checks that received files can be written to dir and
+// that its volume has room for them.
+func checkDownloadDir(dir string) []Finding {
+	const check = "download-dir"
+	if err := config.CheckWritableDir(dir); err != nil {
+		return []Finding{fail(check, "Fix its permissions, or choose another directory with --dir or LOCALSEND_DOWNLOAD_DIR.",
+			"Download directory %s is not usable: %v", dir, err)}
+	}
+	findings := []Finding{pass(check, "Download directory %s is writable", dir)}
+
+	// Measure the nearest existing directory; CheckFreeSpace would create dir.
+	existing := filepath.Clean(os.ExpandEnv(dir))
+	for {
+		if _, err := os.Stat(existing); err == nil || filepath.Dir(existing) == existing {
+			break
+		}
+		existing = filepath.Dir(existing)
+	}
+	free, err := storage.CheckFreeSpace(existing)
+	switch {
+	case err == nil && free < lowDiskSpace:
+		findings = append(findings, warn(check, "Free up space; larger transfers will be refused.",
+			"Only %s free for downloads", cli.FormatBytes(int64(free))))
+	case err == nil:
+		findings = append(findings, pass(check, "%s free for downloads", cli.FormatBytes(int64(free))))
+	default:
+		findings = append(findings, warn(check, "", "Cannot determine free disk space: %v", err))
+	}
+	return findings
+}
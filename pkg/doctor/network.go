@@ -0,0 +1,228 @@
+package doctor
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// ifaceAddr is an interface discovery runs on, with its first IPv4 address.
+type ifaceAddr struct {
+	iface net.Interface
+	ip    net.IP
+}
+
+// firewallHint explains how to open port on common firewalls.
+func firewallHint(port int, proto string) string {
+	return fmt.Sprintf("A firewall is likely dropping %s port %d. Allow it, e.g. `sudo ufw allow %d/%s` or `sudo firewall-cmd --add-port=%d/%s --permanent`; on Windows, allow localgo through Windows Defender Firewall.",
+		proto, port, port, proto, port, proto)
+}
+
+// checkInterfaces lists the interfaces discovery runs on: those that are up,
+// support multicast and have an IPv4 address. If name is set, only that
+// interface is considered.
+func checkInterfaces(name string) ([]ifaceAddr, []Finding) {
+	const check = "interfaces"
+	all, err := net.Interfaces()
+	if err != nil {
+		return nil, []Finding{fail(check, "", "Cannot list network interfaces: %v", err)}
+	}
+
+	var found []ifaceAddr
+	var findings []Finding
+	for _, iface := range all {
+		if name != "" && iface.Name != name {
+			continue
+		}
+		if name == "" && (iface.Flags&net.FlagLoopback != 0 || iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagMulticast == 0) {
+			continue
+		}
+		ip := firstIPv4(iface)
+		switch {
+		case iface.Flags&net.FlagUp == 0:
+			findings = append(findings, fail(check, "Bring the interface up or pick another with --interface.", "%s is down", iface.Name))
+		case iface.Flags&net.FlagMulticast == 0:
+			findings = append(findings, fail(check, "Pick another interface with --interface.", "%s does not support multicast", iface.Name))
+		case ip == nil:
+			findings = append(findings, fail(check, "Connect it to a network, or pick another interface with --interface.", "%s has no IPv4 address", iface.Name))
+		default:
+			found = append(found, ifaceAddr{iface: iface, ip: ip})
+			findings = append(findings, pass(check, "%s is up with address %s", iface.Name, ip))
+		}
+	}
+
+	if name != "" && len(findings) == 0 {
+		findings = append(findings, fail(check, "Run `ip link` (or `ipconfig` on Windows) to list interfaces, then fix LOCALSEND_MULTICAST_INTERFACE.", "Interface %q does not exist", name))
+	} else if len(findings) == 0 {
+		findings = append(findings, fail(check, "Connect to the same network as the other device.", "No network interface with an IPv4 address is up"))
+	}
+	return found, findings
+}
+
+func firstIPv4(iface net.Interface) net.IP {
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return nil
+	}
+	for _, a := range addrs {
+		if ipnet, ok := a.(*net.IPNet); ok && ipnet.IP.To4() != nil {
+			return ipnet.IP.To4()
+		}
+	}
+	return nil
+}
+
+// checkMulticast sends a probe to the discovery group from each interface and
+// checks that it is heard on the same interface, the path announcements from
+// other devices take once they reach this machine.
+func checkMulticast(ctx context.Context, ifaces []ifaceAddr, opts Options) []Finding {
+	const check = "multicast"
+	group, err := net.ResolveUDPAddr("udp4", net.JoinHostPort(opts.MulticastGroup, strconv.Itoa(opts.MulticastPort)))
+	if err != nil || !group.IP.IsMulticast() {
+		return []Finding{fail(check, "Set multicast_group to LocalSend's 224.0.0.167.", "%q is not a multicast address", opts.MulticastGroup)}
+	}
+
+	var findings []Finding
+	for _, ia := range ifaces {
+		findings = append(findings, probeMulticast(ctx, ia, group, opts.Timeout))
+	}
+	return findings
+}
+
+func probeMulticast(ctx context.Context, ia ifaceAddr, group *net.UDPAddr, timeout time.Duration) Finding {
+	const check = "multicast"
+	listener, err := net.ListenMulticastUDP("udp4", &ia.iface, group)
+	if err != nil {
+		return fail(check, "Another program may hold the port exclusively, or the interface cannot join multicast groups.",
+			"Cannot join %s on %s: %v", group, ia.iface.Name, err)
+	}
+	defer listener.Close()
+
+	nonce := make([]byte, 8)
+	rand.Read(nonce)
+	// Not an announcement, so servers that hear the probe drop it.
+	probe := []byte("localgo-doctor " + hex.EncodeToString(nonce))
+
+	sender, err := net.DialUDP("udp4", &net.UDPAddr{IP: ia.ip}, group)
+	if err != nil {
+		return fail(check, "Check that the interface has a route for 224.0.0.0/4.", "Cannot send multicast on %s: %v", ia.iface.Name, err)
+	}
+	defer sender.Close()
+	if _, err := sender.Write(probe); err != nil {
+		return fail(check, "Check that the interface has a route for 224.0.0.0/4.", "Cannot send multicast on %s: %v", ia.iface.Name, err)
+	}
+
+	deadline := time.Now().Add(timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	listener.SetReadDeadline(deadline)
+	buf := make([]byte, 2048)
+	for {
+		n, _, err := listener.ReadFromUDP(buf)
+		if err != nil {
+			break
+		}
+		if bytes.Equal(buf[:n], probe) {
+			return pass(check, "Multicast send and receive work on %s", ia.iface.Name)
+		}
+	}
+	return warn(check, firewallHint(group.Port, "udp")+" Until then, use `localgo scan` or `localgo send --ip`.",
+		"Multicast sent on %s was not received back", ia.iface.Name)
+}
+
+// checkPort checks that the server port is free, or taken by a LocalGo
+// server.
+func checkPort(ctx context.Context, opts Options) []Finding {
+	const check = "port"
+	if opts.Port == 0 {
+		return []Finding{pass(check, "Port 0: the server picks a free port when it starts")}
+	}
+	ln, err := net.Listen("tcp", fmt.Sprintf(":%d", opts.Port))
+	if err == nil {
+		ln.Close()
+		return []Finding{pass(check, "TCP port %d is free", opts.Port)}
+	}
+	if serverAnswers(ctx, opts) {
+		return []Finding{pass(check, "TCP port %d is used by a running LocalGo server", opts.Port)}
+	}
+	return []Finding{fail(check, "Stop the program using it, or choose another port with --port or LOCALSEND_PORT.",
+		"TCP port %d is in use by another program", opts.Port)}
+}
+
+// serverAnswers reports whether a LocalSend server answers on the port.
+func serverAnswers(ctx context.Context, opts Options) bool {
+	scheme := "http"
+	if opts.HTTPS {
+		scheme = "https"
+	}
+	url := fmt.Sprintf("%s://127.0.0.1:%d/api/localsend/v2/info", scheme, opts.Port)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return false
+	}
+	tr := &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}
+	client := &http.Client{Timeout: opts.Timeout, Transport: tr}
+	resp, err := client.Do(req)
+	if err != nil {
+		return false
+	}
+	resp.Body.Close()
+	return resp.StatusCode == http.StatusOK
+}
+
+// checkReachability connects to the server port on each interface address
+// from the address of another interface, so the connection passes through
+// the firewall's input rules rather than the loopback shortcut. If nothing
+// listens on the port, a temporary listener stands in for the server.
+func checkReachability(ctx context.Context, ifaces []ifaceAddr, opts Options) []Finding {
+	const check = "firewall"
+	if opts.Port == 0 || len(ifaces) == 0 {
+		return nil
+	}
+	ln, err := net.Listen("tcp", fmt.Sprintf(":%d", opts.Port))
+	if err == nil {
+		defer ln.Close()
+		go func() {
+			for {
+				conn, err := ln.Accept()
+				if err != nil {
+					return
+				}
+				conn.Close()
+			}
+		}()
+	} else if !serverAnswers(ctx, opts) {
+		return nil // reported by checkPort
+	}
+
+	var findings []Finding
+	for i, ia := range ifaces {
+		source := net.IPv4(127, 0, 0, 1)
+		if len(ifaces) > 1 {
+			source = ifaces[(i+1)%len(ifaces)].ip
+		}
+		dialer := net.Dialer{Timeout: opts.Timeout, LocalAddr: &net.TCPAddr{IP: source}}
+		conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(ia.ip.String(), strconv.Itoa(opts.Port)))
+		if err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				err = errors.New("timed out")
+			}
+			findings = append(findings, fail(check, firewallHint(opts.Port, "tcp"),
+				"TCP port %d on %s (%s) is not reachable from %s: %v", opts.Port, ia.ip, ia.iface.Name, source, err))
+			continue
+		}
+		conn.Close()
+		findings = append(findings, pass(check, "TCP port %d on %s (%s) is reachable from %s", opts.Port, ia.ip, ia.iface.Name, source))
+	}
+	return findings
+}
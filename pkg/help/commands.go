@@ -17,9 +17,26 @@ func GetCommandHelp(commandName string) *CommandHelp {
 				"localgo serve --exec 'notify-send \"Got: %f\"'",
 				"localgo serve --daemon",
 				"localgo serve -d",
+				"localgo serve --open=file",
+				"localgo serve --quick-save 10m",
+				"localgo serve --port 0",
+				"localgo serve --access-log ~/localgo-access.log --access-log-format json",
+				"localgo serve --once --idle-timeout 10m --auto-accept",
+				"localgo serve --auto-accept --progress json",
+				"localgo serve --strict-protocol",
+				"localgo serve --print-messages",
+				"localgo serve --session-wait 30s",
+				"localgo serve --manifest=sha256",
+				"localgo serve --scan 'clamdscan --no-summary \"$LOCALGO_FILE\"'",
+				"localgo serve --encrypt-to localgo-recipient:…",
+				"localgo serve --admin-socket $XDG_RUNTIME_DIR/localgo/admin.sock",
+				"localgo serve --control-grpc unix:$XDG_RUNTIME_DIR/localgo/control.sock",
+				"localgo serve --dbus",
+				"localgo serve --mqtt-broker tcp://nas.local:1883",
+				"localgo serve --relay https://relay.example.com",
 			},
 			Flags: []FlagHelp{
-				{Name: "--port", Type: "int", Default: "from config", Description: "Port to run the server on"},
+				{Name: "--port", Type: "int", Default: "from config", Description: "Port to run the server on (0 = any free port)"},
 				{Name: "--http", Type: "bool", Default: "false", Description: "Use HTTP instead of HTTPS"},
 				{Name: "--pin", Type: "string", Default: "", Description: "PIN for authentication"},
 				{Name: "--alias", Type: "string", Default: "from config", Description: "Device alias"},
@@ -27,13 +44,56 @@ func GetCommandHelp(commandName string) *CommandHelp {
 				{Name: "--daemon, -d", Type: "bool", Default: "false", Description: "Run server as a background daemon"},
 				{Name: "--interval", Type: "int", Default: "30", Description: "Discovery announcement interval in seconds"},
 				{Name: "--auto-accept", Type: "bool", Default: "false", Description: "Auto-accept incoming files without prompting"},
+				{Name: "--quick-save", Type: "string", Default: "", Description: "Auto-accept all transfers: on, or a duration like 10m"},
 				{Name: "--no-clipboard", Type: "bool", Default: "false", Description: "Save incoming text as a file instead of copying to clipboard"},
-				{Name: "--open", Type: "bool", Default: "false", Description: "Open download directory after transfer completes"},
+				{Name: "--strict-protocol", Type: "bool", Default: "false", Description: "Answer API errors exactly as the LocalSend protocol documents"},
+				{Name: "--print-messages", Type: "bool", Default: "false", Description: "Print incoming text messages and offer to copy them instead of saving or copying them"},
+				{Name: "--open", Type: "string", Default: "", Description: "Open received content: dir (default), file, or folder; file opens only media and documents"},
+				{Name: "--manifest", Type: "string", Default: "", Description: "Write a manifest of each session's files: json (default) or sha256"},
+				{Name: "--once", Type: "bool", Default: "false", Description: "Exit after the first completed transfer"},
+				{Name: "--idle-timeout", Type: "duration", Default: "0", Description: "Exit after this long without receiving anything (fails if nothing arrived)"},
+				{Name: "--drain-timeout", Type: "duration", Default: "0", Description: "On shutdown, wait this long for active transfers (8s with --headless)"},
+				{Name: "--session-wait", Type: "duration", Default: "0", Description: "Hold a transfer that arrives during another for up to this long (max 1m)"},
 				{Name: "--quiet", Type: "bool", Default: "false", Description: "Quiet mode - minimal output"},
 				{Name: "--verbose", Type: "bool", Default: "false", Description: "Verbose mode - detailed output"},
 				{Name: "--history", Type: "string", Default: "~/.local/share/localgo/history.jsonl", Description: "Path to transfer history JSONL file"},
+				{Name: "--access-log", Type: "string", Default: "", Description: "Write an HTTP access log to this file (- = stderr)"},
+				{Name: "--access-log-format", Type: "string", Default: "common", Description: "Access log format: common or json"},
+				{Name: "--admin-socket", Type: "string", Default: "", Description: "Also serve the admin API on this unix socket, usable only by you"},
+				{Name: "--control-grpc", Type: "string", Default: "", Description: "Serve the gRPC control interface on unix:PATH or a loopback HOST:PORT"},
+				{Name: "--dbus", Type: "bool", Default: "false", Description: "Publish the D-Bus interface on the session bus for desktop integration (Linux)"},
+				{Name: "--mqtt-broker", Type: "string", Default: "", Description: "Publish events to this MQTT broker, e.g. tcp://nas.local:1883"},
+				{Name: "--relay", Type: "string", Default: "from config", Description: "Also register with this relay to receive from devices on other networks"},
 				{Name: "--exec", Type: "string", Default: "", Description: "Shell command to execute after each received file (use %f, %n, %s, %a, %i)"},
+				{Name: "--encrypt-to", Type: "string", Default: "", Description: "Encrypt received files at rest to this recipient key (see localgo keygen)"},
+				{Name: "--scan", Type: "string", Default: "", Description: "Shell command that checks each received file before it is kept; files it fails are quarantined"},
 				{Name: "--iface", Type: "string", Default: "", Description: "Multicast network interface name"},
+				{Name: "--progress", Type: "string", Default: "bar", Description: "Progress output: bar or json (NDJSON events on stdout)"},
+			},
+		},
+		"receive": {
+			Name:        "receive",
+			Description: "Wait for an expected transfer, print the saved paths, and exit",
+			Usage:       "localgo receive [OPTIONS]",
+			Examples: []string{
+				"localgo receive",
+				"localgo receive --expect 3 --from MyPhone --auto-accept",
+				"localgo receive --expect 3 --from 3f9a1c2b7d4e8f60",
+				"localgo receive --from MyPhone --timeout 10m --dir ./incoming",
+				"localgo receive --expect 1 --auto-accept --quiet | xargs -I{} cp {} /backup/",
+			},
+			Flags: []FlagHelp{
+				{Name: "--expect", Type: "int", Default: "0", Description: "Number of files to wait for (0 = the first completed transfer)"},
+				{Name: "--from", Type: "string", Default: "", Description: "Only accept transfers from the device with this alias, or this fingerprint (auto-accepted)"},
+				{Name: "--timeout", Type: "duration", Default: "0", Description: "Give up after this long, e.g. 10m (0 = wait forever)"},
+				{Name: "--dir", Type: "string", Default: "from config", Description: "Download directory"},
+				{Name: "--port", Type: "int", Default: "from config", Description: "Port to listen on (0 = any free port)"},
+				{Name: "--http", Type: "bool", Default: "false", Description: "Use HTTP instead of HTTPS"},
+				{Name: "--pin", Type: "string", Default: "", Description: "PIN required from the sender"},
+				{Name: "--alias", Type: "string", Default: "from config", Description: "Device alias"},
+				{Name: "--auto-accept", Type: "bool", Default: "false", Description: "Accept transfers without prompting"},
+				{Name: "--quiet", Type: "bool", Default: "false", Description: "Only print the received paths"},
+				{Name: "--report", Type: "string", Default: "", Description: "Write a JSON summary of the transfer to this file"},
 			},
 		},
 		"share": {
@@ -73,9 +133,13 @@ func GetCommandHelp(commandName string) *CommandHelp {
 				"localgo discover --timeout 10",
 				"localgo discover --json",
 				"localgo discover --quiet",
+				"localgo discover --type laptop,mobile",
 			},
 			Flags: []FlagHelp{
 				{Name: "--timeout", Type: "int", Default: "10", Description: "Discovery timeout in seconds"},
+				{Name: "--type", Type: "string", Default: "", Description: "Only list these device types (e.g. laptop,mobile)"},
+				{Name: "--alias-pattern", Type: "string", Default: "", Description: "Only list devices whose alias matches this pattern (e.g. 'Pixel*')"},
+				{Name: "--fingerprint", Type: "string", Default: "", Description: "Only list devices whose fingerprint starts with this prefix"},
 				{Name: "--json", Type: "bool", Default: "false", Description: "Output in JSON format"},
 				{Name: "--quiet", Type: "bool", Default: "false", Description: "Quiet mode - only show results"},
 			},
@@ -90,33 +154,113 @@ func GetCommandHelp(commandName string) *CommandHelp {
 				"localgo scan --json",
 				"localgo scan --quiet",
 				"localgo scan --range 192.168.1.0/24",
+				"localgo scan --neighbors-only",
+				"localgo scan --alias-pattern 'Pixel*'",
 			},
 			Flags: []FlagHelp{
 				{Name: "--range", Type: "string", Default: "", Description: "CIDR range to scan (e.g. 192.168.1.0/24)"},
 				{Name: "--timeout", Type: "int", Default: "15", Description: "Scan timeout in seconds"},
 				{Name: "--port", Type: "int", Default: "from config", Description: "Port to scan"},
+				{Name: "--neighbors-only", Type: "bool", Default: "false", Description: "Only probe hosts in the neighbor table (ARP cache), not the whole range"},
+				{Name: "--type", Type: "string", Default: "", Description: "Only list these device types (e.g. laptop,mobile)"},
+				{Name: "--alias-pattern", Type: "string", Default: "", Description: "Only list devices whose alias matches this pattern (e.g. 'Pixel*')"},
+				{Name: "--fingerprint", Type: "string", Default: "", Description: "Only list devices whose fingerprint starts with this prefix"},
 				{Name: "--json", Type: "bool", Default: "false", Description: "Output in JSON format"},
 				{Name: "--quiet", Type: "bool", Default: "false", Description: "Quiet mode - only show results"},
 			},
 		},
+		"doctor": {
+			Name:        "doctor",
+			Description: "Check multicast, ports, firewall, certificate and download directory, and suggest fixes",
+			Usage:       "localgo doctor [OPTIONS]",
+			Examples: []string{
+				"localgo doctor",
+				"localgo doctor --interface wlan0",
+				"localgo doctor --json",
+			},
+			Flags: []FlagHelp{
+				{Name: "--timeout", Type: "duration", Default: "1s", Description: "How long each network probe waits"},
+				{Name: "--port", Type: "int", Default: "from config", Description: "Server port to check"},
+				{Name: "--interface", Type: "string", Default: "from config", Description: "Check only this network interface"},
+				{Name: "--json", Type: "bool", Default: "false", Description: "Output findings in JSON format"},
+			},
+		},
+		"conformance": {
+			Name:        "conformance",
+			Description: "Check that a LocalSend implementation answers the v2 API as the protocol documents it, and report each violation",
+			Usage:       "localgo conformance --target <device> [OPTIONS]",
+			Examples: []string{
+				"localgo conformance --target 192.168.1.5",
+				"localgo conformance --target 192.168.1.5:53317 --http",
+				"localgo conformance --target phone.local --transfer --pin 1234",
+				"localgo conformance --target 192.168.1.5 --json",
+			},
+			Flags: []FlagHelp{
+				{Name: "--target", Type: "string", Default: "", Description: "Device to check: IP address or hostname, with an optional :port"},
+				{Name: "--http", Type: "bool", Default: "false", Description: "Use HTTP instead of HTTPS"},
+				{Name: "--transfer", Type: "bool", Default: "false", Description: "Also send a small test file, which the device may ask its user to accept"},
+				{Name: "--pin", Type: "string", Default: "", Description: "PIN for the test transfer"},
+				{Name: "--timeout", Type: "duration", Default: "5s", Description: "How long each request waits"},
+				{Name: "--json", Type: "bool", Default: "false", Description: "Output results in JSON format"},
+			},
+		},
+		"simulate": {
+			Name:        "simulate",
+			Description: "Run fake LocalSend devices in this process, each with its own alias and fingerprint, for testing discovery, scripts and load without extra hardware",
+			Usage:       "localgo simulate [OPTIONS]",
+			Examples: []string{
+				"localgo simulate",
+				"localgo simulate --count 20 --prefix Test",
+				"localgo simulate --count 3 --http --dir ./received",
+				"localgo simulate --json",
+			},
+			Flags: []FlagHelp{
+				{Name: "--count", Type: "int", Default: "5", Description: "Number of devices to run (at most 100)"},
+				{Name: "--prefix", Type: "string", Default: "", Description: "Name the devices \"<prefix> 1\", \"<prefix> 2\", ... (default: random aliases)"},
+				{Name: "--port", Type: "int", Default: "0", Description: "Port of the first device, the rest follow (0 = any free ports)"},
+				{Name: "--http", Type: "bool", Default: "false", Description: "Use HTTP instead of HTTPS"},
+				{Name: "--dir", Type: "string", Default: "", Description: "Keep received files here, one folder per device (default: a temporary folder)"},
+				{Name: "--interval", Type: "int", Default: "30", Description: "Discovery announcement interval in seconds"},
+				{Name: "--iface", Type: "string", Default: "", Description: "Multicast network interface name"},
+				{Name: "--json", Type: "bool", Default: "false", Description: "List the devices in JSON format"},
+			},
+		},
 		"send": {
 			Name:        "send",
 			Description: "Send a file or clipboard text to another LocalGo device",
-			Usage:       "localgo send [OPTIONS]",
+			Usage:       "localgo send [-] [OPTIONS]",
 			Examples: []string{
 				"localgo send --file document.pdf --to MyPhone",
+				"localgo send --file 'photos/**/*.jpg' --exclude '*.raw' --to MyPhone",
+				"localgo send --file report_final_v3.pdf --as report.pdf --to MyPhone",
+				"localgo send --file backup.tar --to-fingerprint ab12cd34",
 				"localgo send --ip 192.168.1.42 --file document.pdf",
 				"localgo send --ip 192.168.1.42:53317 --file document.pdf",
+				"localgo send --ip mylaptop.local --file document.pdf",
 				"localgo send --clipboard --to MyPhone",
 				"localgo send -c --to MyPhone",
 				"localgo send --stdin --to MyPhone < list.txt",
 				"echo 'message' | localgo send --stdin --to MyPhone",
+				"cat backup.tar | localgo send - --as backup.tar --to NAS --size 1048576",
+				"localgo send --file ~/backups --to NAS --at 02:00 --every 24h",
+				"localgo send --file ~/photos --to NAS --skip-duplicates",
+				"localgo send --file ~/logs --to NAS --compress",
+				"localgo send --file ~/photos --to Laptop --preview",
+				"localgo send --file ~/videos --to Desktop --wake",
+				"localgo send --file report.pdf --to Laptop --interface wlan0",
+				"localgo send --file report.pdf --relay --to-fingerprint ab12cd34",
+				"localgo send --file report.pdf --code 1G2H-3J4K-5M6N-7P8Q",
 				"localgo send (starts interactive clipboard or file picker if empty)",
 			},
 			Flags: []FlagHelp{
-				{Name: "--file", Type: "string", Default: "", Description: "File or directory to send (optional, can be specified multiple times)"},
-				{Name: "--ip", Type: "string", Default: "", Description: "Target device IP (with optional :port, skips discovery)"},
+				{Name: "--file", Type: "string", Default: "", Description: "File, directory, or glob pattern to send (supports **, can be specified multiple times)"},
+				{Name: "--exclude", Type: "string", Default: "", Description: "Glob pattern of files to skip (can be specified multiple times)"},
+				{Name: "--as", Type: "string", Default: "", Description: "File name to announce to the recipient (single file or '-' stream only)"},
+				{Name: "--size", Type: "int", Default: "", Description: "Size in bytes of data streamed with '-' (omit to buffer stdin to a temp file)"},
+				{Name: "--ip", Type: "string", Default: "", Description: "Target device IP or host name, e.g. mylaptop.local (with optional :port, skips discovery)"},
 				{Name: "--to", Type: "string", Default: "", Description: "Target device alias (omit to pick interactively)"},
+				{Name: "--to-fingerprint", Type: "string", Default: "", Description: "Target device by certificate fingerprint prefix (alias not needed)"},
+				{Name: "--fingerprint", Type: "string", Default: "", Description: "Fingerprint (or prefix) of the target, to choose between devices sharing an alias"},
 				{Name: "--clipboard, -c", Type: "bool", Default: "false", Description: "Send current system clipboard text directly"},
 				{Name: "--stdin", Type: "bool", Default: "false", Description: "Send text read from standard input (stdin)"},
 				{Name: "--port", Type: "int", Default: "auto-detect", Description: "Target device port"},
@@ -124,6 +268,59 @@ func GetCommandHelp(commandName string) *CommandHelp {
 				{Name: "--alias", Type: "string", Default: "from config", Description: "Sender alias"},
 				{Name: "--concurrency", Type: "int", Default: "0", Description: "Max parallel uploads (0 = use default)"},
 				{Name: "--iface", Type: "string", Default: "", Description: "Multicast network interface name"},
+				{Name: "--source-ip", Type: "string", Default: "", Description: "Local address to send from, when the default route takes the wrong interface (e.g. a VPN)"},
+				{Name: "--interface", Type: "string", Default: "", Description: "Network interface to send from: its IPv4 address is the source, and discovery uses it"},
+				{Name: "--fail-fast", Type: "bool", Default: "false", Description: "Stop starting new uploads after the first failure"},
+				{Name: "--progress", Type: "string", Default: "bar", Description: "Progress output: bar or json (NDJSON events on stdout)"},
+				{Name: "--report", Type: "string", Default: "", Description: "Write a JSON summary of the transfer to this file"},
+				{Name: "--at", Type: "string", Default: "", Description: "Queue the send on the running server for this time (HH:MM, \"YYYY-MM-DD HH:MM\" or RFC 3339)"},
+				{Name: "--every", Type: "duration", Default: "", Description: "Queue the send on the running server to repeat at this interval (e.g. 24h, at least 1m); not kept across restarts"},
+				{Name: "--skip-duplicates", Type: "bool", Default: "false", Description: "Skip files already delivered unchanged to this device by an earlier --skip-duplicates send"},
+				{Name: "--compress", Type: "bool", Default: "false", Description: "Compress text-like files for receivers that accept it (see compress_types)"},
+				{Name: "--preview", Type: "bool", Default: "false", Description: "Offer receivers a small thumbnail of each image (see send_previews)"},
+				{Name: "--wake", Type: "bool", Default: "false", Description: "Wake the recipient with Wake-on-LAN first and wait for it (needs its MAC in favorites)"},
+				{Name: "--relay", Type: "bool", Default: "false", Description: "Send through the configured relay to the device given by --to-fingerprint, on another network"},
+				{Name: "--code", Type: "string", Default: "", Description: "Pairing code of the recipient, from localgo pair on it (skips discovery)"},
+			},
+		},
+		"ping": {
+			Name:        "ping",
+			Description: "Check that a device is reachable, measure its latency over HTTPS and HTTP, and verify its fingerprint",
+			Usage:       "localgo ping [OPTIONS]",
+			Examples: []string{
+				"localgo ping --to MyPhone",
+				"localgo ping --ip 192.168.1.42",
+				"localgo ping --ip 192.168.1.42:53317 --fingerprint ab12cd34",
+				"localgo ping --ip mylaptop.local",
+				"localgo ping --to NAS --count 10 --json",
+			},
+			Flags: []FlagHelp{
+				{Name: "--to", Type: "string", Default: "", Description: "Alias of the device to ping (omit to pick interactively)"},
+				{Name: "--ip", Type: "string", Default: "", Description: "Device IP or host name, e.g. mylaptop.local (with optional :port, skips discovery)"},
+				{Name: "--fingerprint", Type: "string", Default: "", Description: "Expected fingerprint (or prefix); also chooses between devices sharing an alias"},
+				{Name: "--port", Type: "int", Default: "from discovery", Description: "Device port (falls back to the configured port)"},
+				{Name: "--count", Type: "int", Default: "3", Description: "Requests per protocol"},
+				{Name: "--timeout", Type: "int", Default: "3", Description: "Timeout per request in seconds"},
+				{Name: "--json", Type: "bool", Default: "false", Description: "Output in JSON format"},
+			},
+		},
+		"bench": {
+			Name:        "bench",
+			Description: "Measure transfer speed by streaming generated data to another LocalGo device, which discards it",
+			Usage:       "localgo bench [OPTIONS]",
+			Examples: []string{
+				"localgo bench --to MyLaptop",
+				"localgo bench --to NAS --size 500MB",
+				"localgo bench --ip 192.168.1.42 --size 1GB --json",
+			},
+			Flags: []FlagHelp{
+				{Name: "--to", Type: "string", Default: "", Description: "Alias of the device to test (omit to pick interactively)"},
+				{Name: "--ip", Type: "string", Default: "", Description: "Device IP (with optional :port, skips discovery)"},
+				{Name: "--fingerprint", Type: "string", Default: "", Description: "Choose between devices sharing an alias by fingerprint prefix"},
+				{Name: "--port", Type: "int", Default: "from discovery", Description: "Device port (falls back to the configured port)"},
+				{Name: "--size", Type: "string", Default: "100MB", Description: "Amount of data to send, e.g. 500MB or 1GB (at most 10GB)"},
+				{Name: "--timeout", Type: "int", Default: "600", Description: "Give up after this many seconds (0 = no limit)"},
+				{Name: "--json", Type: "bool", Default: "false", Description: "Output the result in JSON format"},
 			},
 		},
 		"history": {
@@ -133,6 +330,7 @@ func GetCommandHelp(commandName string) *CommandHelp {
 			Examples: []string{
 				"localgo history",
 				"localgo history --limit 20",
+				"localgo history --format csv > history.csv",
 				"localgo history --clear",
 			},
 			Flags: []FlagHelp{
@@ -142,28 +340,223 @@ func GetCommandHelp(commandName string) *CommandHelp {
 		},
 		"devices": {
 			Name:        "devices",
-			Description: "List recently discovered devices on the network",
+			Description: "List known devices with their status, favorites and trust, from the running server or the peer cache",
 			Usage:       "localgo devices [OPTIONS]",
 			Examples: []string{
 				"localgo devices",
-				"localgo devices --probe",
+				"localgo devices --cache --probe",
 				"localgo devices --json",
+				"localgo devices --format 'template={{.Alias}} {{.IP}}'",
 			},
 			Flags: []FlagHelp{
-				{Name: "--probe, -p", Type: "bool", Default: "false", Description: "Probe cached devices to verify if they are currently online"},
+				{Name: "--port", Type: "int", Default: "from config", Description: "Port of the running server"},
+				{Name: "--cache", Type: "bool", Default: "false", Description: "Read the peer cache even if a server is running"},
+				{Name: "--probe", Type: "bool", Default: "false", Description: "Probe cached devices to verify if they are currently online (implies --cache)"},
 				{Name: "--json", Type: "bool", Default: "false", Description: "Output in JSON format"},
 			},
 		},
 		"info": {
 			Name:        "info",
-			Description: "Show device information and configuration",
+			Description: "Show device information and configuration, or what another device reports about itself",
 			Usage:       "localgo info [OPTIONS]",
 			Examples: []string{
 				"localgo info",
 				"localgo info --json",
+				"localgo info --format yaml",
+				"localgo info --remote MyPhone",
+				"localgo info --remote 192.168.1.42:53317 --json",
+				"localgo info --regenerate-cert",
 			},
 			Flags: []FlagHelp{
 				{Name: "--json", Type: "bool", Default: "false", Description: "Output in JSON format"},
+				{Name: "--remote", Type: "string", Default: "", Description: "Show another device's information instead (alias or IP with optional :port)"},
+				{Name: "--port", Type: "int", Default: "from discovery", Description: "Port of the remote device (falls back to the configured port)"},
+				{Name: "--timeout", Type: "int", Default: "5", Description: "Timeout for the remote request in seconds"},
+				{Name: "--regenerate-cert", Type: "bool", Default: "false", Description: "Replace the TLS certificate with a new one for the current alias (changes the fingerprint)"},
+			},
+		},
+		"quick-save": {
+			Name:        "quick-save",
+			Description: "Toggle quick save (auto-accept everything) on the running server",
+			Usage:       "localgo quick-save [on [DURATION] | off | status] [OPTIONS]",
+			Examples: []string{
+				"localgo quick-save on 10m",
+				"localgo quick-save on",
+				"localgo quick-save off",
+				"localgo quick-save",
+			},
+			Flags: []FlagHelp{
+				{Name: "--port", Type: "int", Default: "from config", Description: "Port of the running server"},
+			},
+		},
+		"status": {
+			Name:        "status",
+			Description: "Show the running server's active transfers with per-file progress, shared files and recent transfers",
+			Usage:       "localgo status [OPTIONS]",
+			Examples: []string{
+				"localgo status",
+				"localgo status --watch",
+				"localgo status --json",
+			},
+			Flags: []FlagHelp{
+				{Name: "--port", Type: "int", Default: "from config", Description: "Port of the running server"},
+				{Name: "--watch", Type: "bool", Default: "false", Description: "Refresh every second until interrupted"},
+				{Name: "--json", Type: "bool", Default: "false", Description: "Output in JSON format"},
+			},
+		},
+		"queue": {
+			Name:        "queue",
+			Description: "Queue sends on the running server, which sends them in the background in priority order and retries those that fail. Jobs are kept in memory only: waiting and scheduled ones are dropped when the server stops",
+			Usage:       "localgo queue [add|list|remove|retry|priority] [OPTIONS]",
+			Examples: []string{
+				"localgo queue add --file ./photos --to \"My Phone\"",
+				"localgo queue add --file report.pdf --ip 192.168.1.5 --priority 10",
+				"localgo queue add --file ~/backups --to NAS --at 02:00 --every 24h",
+				"localgo queue add --file ~/photos --to NAS --every 1h --skip-duplicates",
+				"localgo queue",
+				"localgo queue priority 3 5",
+				"localgo queue retry",
+				"localgo queue remove 2 4",
+			},
+			Flags: []FlagHelp{
+				{Name: "--port", Type: "int", Default: "from config", Description: "Port of the running server"},
+				{Name: "--file", Type: "string", Default: "", Description: "add: file, directory, or glob pattern to send (repeatable)"},
+				{Name: "--exclude", Type: "string", Default: "", Description: "add: glob pattern of files to skip (repeatable)"},
+				{Name: "--to", Type: "string", Default: "", Description: "add: target device alias"},
+				{Name: "--ip", Type: "string", Default: "", Description: "add: target device IP (with optional :port)"},
+				{Name: "--to-fingerprint", Type: "string", Default: "", Description: "add: target device by certificate fingerprint prefix"},
+				{Name: "--fingerprint", Type: "string", Default: "", Description: "add: fingerprint prefix to choose between devices sharing an alias"},
+				{Name: "--priority", Type: "int", Default: "0", Description: "add: higher runs first"},
+				{Name: "--attempts", Type: "int", Default: "3", Description: "add: tries before the job is marked failed"},
+				{Name: "--at", Type: "string", Default: "", Description: "add: send at this time (HH:MM, \"YYYY-MM-DD HH:MM\" or RFC 3339)"},
+				{Name: "--every", Type: "duration", Default: "", Description: "add: send again at this interval (e.g. 24h, at least 1m) until removed or the server stops"},
+				{Name: "--skip-duplicates", Type: "bool", Default: "false", Description: "add: skip files already delivered unchanged to this device"},
+			},
+		},
+		"clipboard-sync": {
+			Name:        "clipboard-sync",
+			Description: "Watch the clipboard and send new text and images to a trusted device, for one-way clipboard sync to a phone",
+			Usage:       "localgo clipboard-sync [OPTIONS]",
+			Examples: []string{
+				"localgo clipboard-sync --to MyPhone",
+				"localgo clipboard-sync --ip 192.168.1.42 --images=false",
+				"localgo clipboard-sync --to MyPhone --max-size 2MB --min-gap 5s",
+			},
+			Flags: []FlagHelp{
+				{Name: "--to", Type: "string", Default: "", Description: "Target device alias"},
+				{Name: "--ip", Type: "string", Default: "", Description: "Target device IP (with optional :port, skips discovery)"},
+				{Name: "--to-fingerprint", Type: "string", Default: "", Description: "Target device by certificate fingerprint prefix"},
+				{Name: "--fingerprint", Type: "string", Default: "", Description: "Fingerprint (or prefix) of the target, to choose between devices sharing an alias"},
+				{Name: "--port", Type: "int", Default: "auto-detect", Description: "Target device port"},
+				{Name: "--images", Type: "bool", Default: "true", Description: "Send copied images as well as text"},
+				{Name: "--max-size", Type: "string", Default: "10MB", Description: "Skip clipboard content larger than this"},
+				{Name: "--min-gap", Type: "duration", Default: "2s", Description: "Least time between two sends; the latest change is sent after it"},
+				{Name: "--interval", Type: "duration", Default: "1s", Description: "How often to check the clipboard"},
+			},
+		},
+		"watch": {
+			Name:        "watch",
+			Description: "Watch a directory and send each file dropped into it once it has finished being written, optionally moving or deleting it afterwards",
+			Usage:       "localgo watch <dir> [OPTIONS]",
+			Examples: []string{
+				"localgo watch ./outbox --to NAS",
+				"localgo watch ./outbox --to NAS --move-to ./outbox/sent",
+				"localgo watch ~/Scans --ip 192.168.1.5 --delete --exclude '*.log'",
+			},
+			Flags: []FlagHelp{
+				{Name: "--to", Type: "string", Default: "", Description: "Target device alias"},
+				{Name: "--ip", Type: "string", Default: "", Description: "Target device IP (with optional :port, skips discovery)"},
+				{Name: "--to-fingerprint", Type: "string", Default: "", Description: "Target device by certificate fingerprint prefix"},
+				{Name: "--fingerprint", Type: "string", Default: "", Description: "Fingerprint (or prefix) of the target, to choose between devices sharing an alias"},
+				{Name: "--port", Type: "int", Default: "auto-detect", Description: "Target device port"},
+				{Name: "--exclude", Type: "string", Default: "", Description: "Glob pattern of file names to ignore (repeatable)"},
+				{Name: "--settle", Type: "duration", Default: "2s", Description: "How long a file must stay unchanged before it is sent"},
+				{Name: "--move-to", Type: "string", Default: "", Description: "Move files here once sent"},
+				{Name: "--delete", Type: "bool", Default: "false", Description: "Delete files once sent"},
+				{Name: "--skip-existing", Type: "bool", Default: "false", Description: "Only send files added after the watch starts"},
+			},
+		},
+		"sync": {
+			Name:        "sync",
+			Description: "Send the files of a directory that the device has not received yet, keeping the folder structure; run it again to send only what is new or changed",
+			Usage:       "localgo sync <dir> [OPTIONS]",
+			Examples: []string{
+				"localgo sync ./photos --to Tablet",
+				"localgo sync ./photos --to Tablet --dry-run",
+				"localgo sync ~/Music --ip 192.168.1.5 --exclude '*.m3u'",
+			},
+			Flags: []FlagHelp{
+				{Name: "--to", Type: "string", Default: "", Description: "Target device alias (omit to pick interactively)"},
+				{Name: "--ip", Type: "string", Default: "", Description: "Target device IP (with optional :port, skips discovery)"},
+				{Name: "--to-fingerprint", Type: "string", Default: "", Description: "Target device by certificate fingerprint prefix"},
+				{Name: "--fingerprint", Type: "string", Default: "", Description: "Fingerprint (or prefix) of the target, to choose between devices sharing an alias"},
+				{Name: "--port", Type: "int", Default: "auto-detect", Description: "Target device port"},
+				{Name: "--exclude", Type: "string", Default: "", Description: "Glob pattern of files to skip (repeatable)"},
+				{Name: "--dry-run", Type: "bool", Default: "false", Description: "List the files that would be sent without sending them"},
+			},
+		},
+		"pair": {
+			Name:        "pair",
+			Description: "Show a pairing code other devices can send to this one with, when they cannot discover it, such as from another subnet. The code holds this device's address and the start of its fingerprint, which the sender checks",
+			Usage:       "localgo pair [OPTIONS]",
+			Examples: []string{
+				"localgo pair",
+				"localgo pair --ip 10.8.0.2",
+				"localgo pair --relay",
+			},
+			Flags: []FlagHelp{
+				{Name: "--ip", Type: "string", Default: "LAN address", Description: "Address to put in the code, such as a VPN address"},
+				{Name: "--port", Type: "int", Default: "from config", Description: "Port to put in the code"},
+				{Name: "--relay", Type: "bool", Default: "false", Description: "Get a short code from the relay in the config instead, valid for 10 minutes"},
+			},
+		},
+		"relay": {
+			Name:        "relay",
+			Description: "Run a relay that brokers transfers between devices on different networks. Devices register with it by their certificate fingerprint and senders reach them through it",
+			Usage:       "localgo relay [OPTIONS]",
+			Examples: []string{
+				"localgo relay",
+				"localgo relay --port 443 --allow <fingerprint> --allow <fingerprint>",
+			},
+			Flags: []FlagHelp{
+				{Name: "--port", Type: "int", Default: "53318", Description: "Port to listen on"},
+				{Name: "--allow", Type: "string", Default: "", Description: "Fingerprint of a device allowed to register and send (repeatable; default: any device)"},
+			},
+		},
+		"keygen": {
+			Name:        "keygen",
+			Description: "Create a key pair for encrypting received files at rest. The identity (private key) decrypts them; its recipient (public key) is what the receiver encrypts to",
+			Usage:       "localgo keygen [OPTIONS]",
+			Examples: []string{
+				"localgo keygen -o ~/localgo-identity.txt",
+				"localgo config set encrypt_to $(localgo keygen -o ~/localgo-identity.txt)",
+			},
+			Flags: []FlagHelp{
+				{Name: "--output, -o", Type: "string", Default: "stdout", Description: "Write the identity to this file, which must not exist; the recipient is printed"},
+			},
+		},
+		"token": {
+			Name:        "token",
+			Description: "Show the token that authorizes use of the running server's admin API, or replace it. Commands such as status and queue read it themselves; scripts send it as a bearer token",
+			Usage:       "localgo token [rotate | path]",
+			Examples: []string{
+				"localgo token",
+				"localgo token rotate",
+				"curl -sk -H \"Authorization: Bearer $(localgo token)\" https://127.0.0.1:53317/api/localgo/v1/status",
+			},
+			Flags: []FlagHelp{},
+		},
+		"decrypt": {
+			Name:        "decrypt",
+			Description: "Decrypt files received with encryption at rest",
+			Usage:       "localgo decrypt --identity FILE FILE...",
+			Examples: []string{
+				"localgo decrypt -i ~/localgo-identity.txt ~/Downloads/localgo/report.pdf.lgenc",
+				"localgo decrypt -i ~/localgo-identity.txt -o - notes.txt.lgenc",
+			},
+			Flags: []FlagHelp{
+				{Name: "--identity, -i", Type: "string", Default: "", Description: "Identity file written by localgo keygen (required)"},
+				{Name: "--output, -o", Type: "string", Default: "next to the file", Description: "Write the plaintext here (- = stdout); only with a single file"},
 			},
 		},
 		"stop": {
@@ -194,9 +587,12 @@ func GetCommandHelp(commandName string) *CommandHelp {
 				"localgo config get port",
 				"localgo config set alias MyDevice",
 				"localgo config list",
+				"localgo config edit",
 				"localgo config path",
 			},
-			Flags: []FlagHelp{},
+			Flags: []FlagHelp{
+				{Name: "--file", Type: "bool", Default: "false", Description: "get/list: only values stored in the config file, ignoring overrides"},
+			},
 		},
 		"version": {
 			Name:        "version",
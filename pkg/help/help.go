@@ -40,14 +40,31 @@ func ShowMainUsage() {
 		name, desc string
 	}{
 		{"serve", "Start the LocalGo server to receive files"},
+		{"receive", "Wait for an expected transfer, print the saved paths, and exit"},
 		{"share", "Share files so other devices can download them"},
 		{"send", "Send a file or clipboard text to another device"},
+		{"ping", "Check that a device is reachable and measure its latency"},
+		{"bench", "Measure transfer speed to another LocalGo device"},
 		{"discover", "Discover devices using multicast"},
 		{"scan", "Scan network for devices using HTTP"},
-		{"devices", "List recently discovered devices"},
+		{"doctor", "Diagnose network and setup problems"},
+		{"simulate", "Run fake devices for testing discovery and transfers"},
+		{"conformance", "Check a device against the LocalSend v2 protocol"},
+		{"devices", "List known devices and whether they are online"},
 		{"history", "Show file transfer history log"},
+		{"quick-save", "Toggle quick save on the running server"},
+		{"status", "Show the running server's transfers"},
+		{"queue", "Queue sends on the running server"},
+		{"watch", "Send files as they are dropped into a directory"},
+		{"sync", "Send the new and changed files of a directory"},
+		{"clipboard-sync", "Send clipboard changes to a trusted device"},
+		{"pair", "Show a pairing code to send to this device with"},
+		{"relay", "Run a relay for transfers between networks"},
+		{"keygen", "Create a key pair for encrypting received files at rest"},
+		{"decrypt", "Decrypt files received with encryption at rest"},
+		{"token", "Show or rotate the admin API token (rotate/path)"},
 		{"stop", "Stop the running LocalGo daemon"},
-		{"config", "Manage LocalGo configuration (get/set/list/path)"},
+		{"config", "Manage LocalGo configuration (get/set/list/edit/path)"},
 		{"info", "Show device information"},
 		{"completion", "Generate shell completion scripts"},
 		{"help", "Show help information"},
@@ -73,8 +90,17 @@ func ShowMainUsage() {
 		{"-h, --help", "Show help"},
 		{"-v, --version", "Show version"},
 		{"--verbose", "Enable debug logging"},
-		{"--json", "Enable JSON log output"},
+		{"--json", "Print results as JSON"},
+		{"--format", "Results as table, json, yaml, csv or template=TPL"},
+		{"-q, --quiet", "Only print results, warnings and errors"},
+		{"--no-color", "Disable colored output"},
+		{"--no-emoji", "Plain text instead of Nerd Font icons"},
+		{"--log-level", "Log level: debug, info, warn or error"},
+		{"--log-file", "Log file path (- = stderr)"},
+		{"--log-target", "Where logs go: file, journald or syslog"},
+		{"--debug-capture", "Record protocol exchanges to a JSON lines file"},
 		{"--private, -p", "Hide device identity during discovery/transfer"},
+		{"--headless", "No prompts; JSON logs on stdout (for containers)"},
 		{"--config", "Config file path"},
 	}
 
@@ -127,10 +153,13 @@ func ShowMainUsage() {
 		{"LOCALSEND_NO_CLIPBOARD", "Save incoming text as file instead of clipboard (true/1)"},
 		{"LOCALSEND_QUIET", "Quiet mode - minimal output (true/1)"},
 		{"LOCALSEND_HISTORY", "Path to transfer history JSONL file"},
+		{"LOCALSEND_SESSION_FILE", "Path to saved receive sessions (off to disable)"},
 		{"LOCALSEND_EXEC", "Shell command to execute after each received file"},
 		{"LOCALSEND_MULTICAST_GROUP", "Multicast group address"},
 		{"LOCALSEND_SECURITY_DIR", "Security directory path"},
 		{"LOCALSEND_LOG_LEVEL", "Log verbosity (debug/info/warn/error)"},
+		{"LOCALSEND_LOG_LEVELS", "Per-component levels (e.g. discovery=debug,server=warn)"},
+		{"LOCALSEND_LOG_FILE", "Log file path, rotated by size (- = stderr)"},
 	}
 
 	for _, env := range envVars {
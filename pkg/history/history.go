@@ -13,10 +13,11 @@ import (
 
 // Status values for a history entry.
 const (
-	StatusReceived   = "received"
-	StatusClipboard  = "clipboard"
-	StatusFailed     = "failed"
-	DisabledSentinel = "off"
+	StatusReceived    = "received"
+	StatusClipboard   = "clipboard"
+	StatusFailed      = "failed"
+	StatusQuarantined = "quarantined" // failed the scan and moved aside
+	DisabledSentinel  = "off"
 )
 
 // Entry represents a single file transfer event.
@@ -29,13 +30,16 @@ type Entry struct {
 	FileSize    int64     `json:"file_size"`
 	FileType    string    `json:"file_type"`
 	Status      string    `json:"status"`
+	SHA256      string    `json:"sha256,omitempty"` // content hash of a received file, when recorded for dedupe
 }
 
 // Logger writes transfer history entries to an append-only JSONL file.
 type Logger struct {
-	mu   sync.Mutex
-	file *os.File
-	enc  *json.Encoder
+	mu     sync.Mutex
+	path   string
+	file   *os.File
+	enc    *json.Encoder
+	hashes map[string]string // received files by SHA256; loaded by the first Find
 }
 
 // NewLogger opens (or creates) the JSONL history file at path.
@@ -49,7 +53,7 @@ func NewLogger(path string) (*Logger, error) {
 		return nil, fmt.Errorf("history: open file: %w", err)
 	}
 	enc := json.NewEncoder(f)
-	return &Logger{file: f, enc: enc}, nil
+	return &Logger{path: path, file: f, enc: enc}, nil
 }
 
 // Log appends one entry to the JSONL file. It is safe for concurrent use.
@@ -62,9 +66,56 @@ func (l *Logger) Log(e Entry) error {
 	if err := l.enc.Encode(e); err != nil {
 		return fmt.Errorf("history: encode entry: %w", err)
 	}
+	if l.hashes != nil {
+		l.index(e)
+	}
 	return nil
 }
 
+// Find returns the path of a received file recorded with the given SHA-256
+// and size that is still there, or "" if there is none. The file may have
+// been changed since; callers comparing content must check it.
+func (l *Logger) Find(sha256 string, size int64) string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.hashes == nil {
+		l.load()
+	}
+	path, ok := l.hashes[sha256]
+	if !ok {
+		return ""
+	}
+	if info, err := os.Stat(path); err != nil || !info.Mode().IsRegular() || info.Size() != size {
+		return ""
+	}
+	return path
+}
+
+// load indexes the received files recorded so far. Callers hold mu.
+func (l *Logger) load() {
+	l.hashes = make(map[string]string)
+	f, err := os.Open(l.path)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	dec := json.NewDecoder(f)
+	for {
+		var e Entry
+		if err := dec.Decode(&e); err != nil {
+			// A torn last line, as left by a crash, ends the index.
+			return
+		}
+		l.index(e)
+	}
+}
+
+func (l *Logger) index(e Entry) {
+	if e.Status == StatusReceived && e.SHA256 != "" && e.FilePath != "" {
+		l.hashes[e.SHA256] = e.FilePath
+	}
+}
+
 // Close closes the underlying file.
 func (l *Logger) Close() error {
 	l.mu.Lock()
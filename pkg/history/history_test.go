@@ -53,6 +53,49 @@ func TestHistoryLogger(t *testing.T) {
 	}
 }
 
+func TestLoggerFind(t *testing.T) {
+	tempDir := t.TempDir()
+	logPath := filepath.Join(tempDir, "history.jsonl")
+	kept := filepath.Join(tempDir, "kept.txt")
+	gone := filepath.Join(tempDir, "gone.txt")
+	if err := os.WriteFile(kept, []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	logger, err := history.NewLogger(logPath)
+	if err != nil {
+		t.Fatalf("failed to create logger: %v", err)
+	}
+	logger.Log(history.Entry{FileName: "kept.txt", FilePath: kept, FileSize: 5, Status: history.StatusReceived, SHA256: "aaaa"})
+	logger.Log(history.Entry{FileName: "gone.txt", FilePath: gone, FileSize: 5, Status: history.StatusReceived, SHA256: "bbbb"})
+	logger.Close()
+
+	// A new logger indexes what an earlier one recorded.
+	logger, err = history.NewLogger(logPath)
+	if err != nil {
+		t.Fatalf("failed to reopen logger: %v", err)
+	}
+	defer logger.Close()
+	if got := logger.Find("aaaa", 5); got != kept {
+		t.Errorf("Find(aaaa) = %q, want %q", got, kept)
+	}
+	if got := logger.Find("aaaa", 6); got != "" {
+		t.Errorf("Find with another size = %q, want none", got)
+	}
+	if got := logger.Find("bbbb", 5); got != "" {
+		t.Errorf("Find of a removed file = %q, want none", got)
+	}
+
+	// Entries logged after the index is loaded are found too.
+	if err := os.WriteFile(gone, []byte("again"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	logger.Log(history.Entry{FileName: "gone.txt", FilePath: gone, FileSize: 5, Status: history.StatusReceived, SHA256: "cccc"})
+	if got := logger.Find("cccc", 5); got != gone {
+		t.Errorf("Find(cccc) = %q, want %q", got, gone)
+	}
+}
+
 func TestDefaultPath(t *testing.T) {
 	// filepath.FromSlash converts forward slashes to the OS path separator.
 	// This makes the expected strings correct on both Unix (no-op) and
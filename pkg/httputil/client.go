@@ -0,0 +1,163 @@
+package httputil
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TransportOptions configures the transport shared by requests to peers.
+type TransportOptions struct {
+	DialTimeout         time.Duration // connecting, unless a request sets its own with WithDialTimeout
+	TLSHandshakeTimeout time.Duration
+	IdleConnTimeout     time.Duration // how long an unused connection is kept open
+	MaxIdleConnsPerHost int
+	MaxConnsPerHost     int    // 0 = no limit
+	SourceIP            net.IP // local address to connect from; nil lets the system choose
+	Proxy               string // "" = from the environment, ProxyNone, or the proxy's URL (see ParseProxy)
+	// ClientCertificate is presented to peers that ask for one, so a
+	// receiver can verify which device is sending; nil presents none.
+	ClientCertificate *tls.Certificate
+}
+
+// DefaultTransportOptions returns the options used until ConfigureTransport
+// is called.
+func DefaultTransportOptions() TransportOptions {
+	return TransportOptions{
+		DialTimeout:         5 * time.Second,
+		TLSHandshakeTimeout: 5 * time.Second,
+		IdleConnTimeout:     90 * time.Second,
+		MaxIdleConnsPerHost: 16,
+	}
+}
+
+var (
+	transportMu sync.Mutex
+	transport   *http.Transport
+	pinned      map[string]*http.Transport
+)
+
+type dialTimeoutKey struct{}
+
+// WithDialTimeout returns a context whose requests through the shared
+// transport give up connecting after d, without limiting the rest of the
+// request. Scans use it to move on quickly from addresses nobody answers.
+func WithDialTimeout(ctx context.Context, d time.Duration) context.Context {
+	return context.WithValue(ctx, dialTimeoutKey{}, d)
+}
+
+// ConfigureTransport replaces the shared transport with one built from
+// opts. Call it once at startup, before any requests are made; connections
+// of the previous transport are closed once idle.
+func ConfigureTransport(opts TransportOptions) {
+	transportMu.Lock()
+	defer transportMu.Unlock()
+	closeIdleLocked()
+	transport = newTransport(opts)
+	pinned = nil
+}
+
+// Transport returns the transport shared by requests to peers, so that
+// scans and the several requests of a send reuse their connections. Over
+// HTTPS it speaks HTTP/2 to peers that offer it, multiplexing parallel
+// uploads over a single connection. Peer
+// certificates are self-signed and are not verified; use PinnedTransport
+// to check a known fingerprint.
+func Transport() *http.Transport {
+	transportMu.Lock()
+	defer transportMu.Unlock()
+	return sharedLocked()
+}
+
+// PinnedTransport returns a transport with the shared settings that only
+// completes TLS handshakes with the certificate whose SHA-256 fingerprint is
+// fingerprint. It is kept per fingerprint, so sends to the same device
+// reuse its connections.
+func PinnedTransport(fingerprint string) *http.Transport {
+	fingerprint = strings.ToLower(fingerprint)
+	transportMu.Lock()
+	defer transportMu.Unlock()
+	if tr, ok := pinned[fingerprint]; ok {
+		return tr
+	}
+	tr := sharedLocked().Clone()
+	tr.TLSClientConfig.VerifyConnection = func(state tls.ConnectionState) error {
+		if len(state.PeerCertificates) == 0 {
+			return fmt.Errorf("no peer certificates presented")
+		}
+		hash := sha256.Sum256(state.PeerCertificates[0].Raw)
+		actual := hex.EncodeToString(hash[:])
+		if actual != fingerprint {
+			return fmt.Errorf("TLS certificate fingerprint mismatch: expected %s, got %s", fingerprint, actual)
+		}
+		return nil
+	}
+	if pinned == nil {
+		pinned = make(map[string]*http.Transport)
+	}
+	pinned[fingerprint] = tr
+	return tr
+}
+
+// CloseIdleConnections closes the idle connections of the shared and
+// pinned transports.
+func CloseIdleConnections() {
+	transportMu.Lock()
+	defer transportMu.Unlock()
+	closeIdleLocked()
+}
+
+func sharedLocked() *http.Transport {
+	if transport == nil {
+		transport = newTransport(DefaultTransportOptions())
+	}
+	return transport
+}
+
+func closeIdleLocked() {
+	if transport != nil {
+		transport.CloseIdleConnections()
+	}
+	for _, tr := range pinned {
+		tr.CloseIdleConnections()
+	}
+}
+
+func newTransport(opts TransportOptions) *http.Transport {
+	dialer := &net.Dialer{Timeout: opts.DialTimeout, KeepAlive: 30 * time.Second}
+	if opts.SourceIP != nil {
+		dialer.LocalAddr = &net.TCPAddr{IP: opts.SourceIP}
+	}
+	tlsConfig := &tls.Config{InsecureSkipVerify: true}
+	if cert := opts.ClientCertificate; cert != nil {
+		tlsConfig.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return cert, nil
+		}
+	}
+	return &http.Transport{
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			if d, ok := ctx.Value(dialTimeoutKey{}).(time.Duration); ok && d > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, d)
+				defer cancel()
+			}
+			return dialer.DialContext(ctx, network, addr)
+		},
+		Proxy:                 proxyFunc(opts.Proxy),
+		TLSClientConfig:       tlsConfig,
+		ForceAttemptHTTP2:     true, // kept off by a custom dialer otherwise
+		MaxIdleConns:          100,
+		MaxIdleConnsPerHost:   opts.MaxIdleConnsPerHost,
+		MaxConnsPerHost:       opts.MaxConnsPerHost,
+		IdleConnTimeout:       opts.IdleConnTimeout,
+		TLSHandshakeTimeout:   opts.TLSHandshakeTimeout,
+		ExpectContinueTimeout: 1 * time.Second,
+	}
+}
@@ -0,0 +1,129 @@
+package httputil
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httptrace"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTransport_ReusesConnections(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "ok")
+	}))
+	defer srv.Close()
+	ConfigureTransport(DefaultTransportOptions())
+	defer CloseIdleConnections()
+
+	get := func(client *http.Client) (reused bool, err error) {
+		trace := &httptrace.ClientTrace{GotConn: func(info httptrace.GotConnInfo) { reused = info.Reused }}
+		req, _ := http.NewRequestWithContext(httptrace.WithClientTrace(context.Background(), trace), http.MethodGet, srv.URL, nil)
+		resp, err := client.Do(req)
+		if err != nil {
+			return false, err
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		return reused, nil
+	}
+
+	// Separate clients, as separate scans and sends make, share connections.
+	if _, err := get(&http.Client{Transport: Transport()}); err != nil {
+		t.Fatalf("first request: %v", err)
+	}
+	reused, err := get(&http.Client{Timeout: time.Second, Transport: Transport()})
+	if err != nil {
+		t.Fatalf("second request: %v", err)
+	}
+	if !reused {
+		t.Error("second request opened a new connection")
+	}
+
+	sum := sha256.Sum256(srv.Certificate().Raw)
+	fingerprint := strings.ToUpper(hex.EncodeToString(sum[:]))
+	if PinnedTransport(fingerprint) != PinnedTransport(strings.ToLower(fingerprint)) {
+		t.Error("PinnedTransport returned a new transport for the same fingerprint")
+	}
+	if _, err := get(&http.Client{Transport: PinnedTransport(fingerprint)}); err != nil {
+		t.Errorf("pinned to the server's certificate: %v", err)
+	}
+	if reused, err := get(&http.Client{Transport: PinnedTransport(fingerprint)}); err != nil || !reused {
+		t.Errorf("second pinned request: reused=%v err=%v", reused, err)
+	}
+	_, err = get(&http.Client{Transport: PinnedTransport(strings.Repeat("0", 64))})
+	if err == nil || !strings.Contains(err.Error(), "fingerprint mismatch") {
+		t.Errorf("pinned to another certificate: got %v, want a fingerprint mismatch", err)
+	}
+}
+
+func TestWithDialTimeout(t *testing.T) {
+	opts := DefaultTransportOptions()
+	opts.DialTimeout = time.Minute
+	ConfigureTransport(opts)
+	defer ConfigureTransport(DefaultTransportOptions())
+
+	var dialCtx context.Context
+	tr := Transport()
+	dial := tr.DialContext
+	tr.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := dial(ctx, network, addr)
+		dialCtx = ctx
+		return conn, err
+	}
+	defer func() { tr.DialContext = dial }()
+
+	// A blackholed address: the dial hangs until a timeout stops it.
+	ctx := WithDialTimeout(context.Background(), 50*time.Millisecond)
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "http://10.255.255.1:9/", nil)
+	started := time.Now()
+	_, err := (&http.Client{Transport: tr}).Do(req)
+	if err == nil {
+		t.Fatal("request to an unreachable address succeeded")
+	}
+	if elapsed := time.Since(started); elapsed > 5*time.Second {
+		t.Errorf("dial took %v, want it cut short by WithDialTimeout", elapsed)
+	}
+	if dialCtx == nil {
+		t.Skip("no dial attempted (no route to the test address)")
+	}
+	if _, ok := dialCtx.Value(dialTimeoutKey{}).(time.Duration); !ok {
+		t.Error("the dial did not see the timeout set on the request")
+	}
+}
+
+func TestTransport_SourceIP(t *testing.T) {
+	remote := make(chan string, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, _, _ := net.SplitHostPort(r.RemoteAddr)
+		remote <- host
+	}))
+	defer srv.Close()
+
+	// Linux answers on all of 127.0.0.0/8; elsewhere binding may fail.
+	source := net.ParseIP("127.0.0.2")
+	if ln, err := net.Listen("tcp", "127.0.0.2:0"); err != nil {
+		t.Skipf("127.0.0.2 is not usable here: %v", err)
+	} else {
+		ln.Close()
+	}
+	opts := DefaultTransportOptions()
+	opts.SourceIP = source
+	ConfigureTransport(opts)
+	defer ConfigureTransport(DefaultTransportOptions())
+
+	resp, err := (&http.Client{Transport: Transport()}).Get(srv.URL)
+	if err != nil {
+		t.Fatalf("request: %v", err)
+	}
+	resp.Body.Close()
+	if got := <-remote; got != source.String() {
+		t.Errorf("request came from %s, want %s", got, source)
+	}
+}
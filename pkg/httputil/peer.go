@@ -0,0 +1,40 @@
+package httputil
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+)
+
+type verifiedKey struct{}
+
+// VerifiedFingerprint returns the SHA-256 fingerprint of the certificate the
+// sender of r proved it holds: its TLS client certificate, or one recorded
+// with WithVerifiedFingerprint. It returns "" for a sender that presented
+// none. The fingerprint a sender reports in a request body is not verified
+// and must not be trusted on its own.
+func VerifiedFingerprint(r *http.Request) string {
+	if fp, ok := r.Context().Value(verifiedKey{}).(string); ok {
+		return fp
+	}
+	if r.TLS == nil || len(r.TLS.PeerCertificates) == 0 {
+		return ""
+	}
+	hash := sha256.Sum256(r.TLS.PeerCertificates[0].Raw)
+	return hex.EncodeToString(hash[:])
+}
+
+// WithVerifiedFingerprint returns r with fp recorded as the fingerprint of
+// its sender's certificate, for a sender verified by other means than the
+// TLS connection, such as by the relay that forwarded the request.
+func WithVerifiedFingerprint(r *http.Request, fp string) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), verifiedKey{}, strings.ToLower(fp)))
+}
+
+// IsVerifiedSender reports whether the sender of r proved it holds the
+// certificate with the fingerprint it reports, fingerprint.
+func IsVerifiedSender(r *http.Request, fingerprint string) bool {
+	return fingerprint != "" && strings.EqualFold(VerifiedFingerprint(r), fingerprint)
+}
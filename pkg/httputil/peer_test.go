@@ -0,0 +1,57 @@
+package httputil
+
+import (
+	"crypto/tls"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bethropolis/localgo/pkg/crypto"
+)
+
+func TestVerifiedFingerprint(t *testing.T) {
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, VerifiedFingerprint(r))
+	}))
+	srv.TLS = &tls.Config{ClientAuth: tls.RequestClientCert}
+	srv.StartTLS()
+	defer srv.Close()
+
+	sc, err := crypto.GenerateSecurityContext("Sender", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := sc.TLSCertificate()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	get := func(opts TransportOptions) string {
+		t.Helper()
+		resp, err := (&http.Client{Transport: newTransport(opts)}).Get(srv.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return string(body)
+	}
+
+	if got := get(DefaultTransportOptions()); got != "" {
+		t.Errorf("without a client certificate: VerifiedFingerprint = %q, want none", got)
+	}
+	opts := DefaultTransportOptions()
+	opts.ClientCertificate = &cert
+	if got := get(opts); got != sc.CertificateHash {
+		t.Errorf("with a client certificate: VerifiedFingerprint = %q, want %q", got, sc.CertificateHash)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if !IsVerifiedSender(WithVerifiedFingerprint(req, "ABCD1234"), "abcd1234") {
+		t.Error("a fingerprint recorded with WithVerifiedFingerprint is not verified")
+	}
+	if IsVerifiedSender(req, "") {
+		t.Error("an empty fingerprint is verified")
+	}
+}
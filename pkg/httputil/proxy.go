@@ -0,0 +1,58 @@
+package httputil
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/http/httpproxy"
+)
+
+// ProxyNone is the Proxy option that connects to peers directly, whatever
+// the environment says.
+const ProxyNone = "none"
+
+// ParseProxy parses the URL of a proxy: http://, https://, socks5:// or
+// socks5h:// (resolving names on the proxy) with a host, such as
+// socks5://localhost:1080. It may carry a user and password.
+func ParseProxy(s string) (*url.URL, error) {
+	u, err := url.Parse(strings.TrimSpace(s))
+	if err != nil {
+		return nil, fmt.Errorf("%q is not a URL", s)
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("%q: use http://, https://, socks5:// or socks5h://", s)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("%q has no host", s)
+	}
+	return u, nil
+}
+
+// proxyFunc returns the Proxy of a transport for the Proxy option: "" takes
+// the proxy from HTTP_PROXY, HTTPS_PROXY and NO_PROXY, ProxyNone connects
+// directly, and a URL sends every request through that proxy, except those
+// NO_PROXY exempts. Requests to loopback addresses never use a proxy. An
+// invalid URL falls back to the environment; config loading reports it.
+func proxyFunc(proxy string) func(*http.Request) (*url.URL, error) {
+	switch proxy {
+	case "":
+		return http.ProxyFromEnvironment
+	case ProxyNone:
+		return nil
+	}
+	u, err := ParseProxy(proxy)
+	if err != nil {
+		return http.ProxyFromEnvironment
+	}
+	cfg := httpproxy.FromEnvironment()
+	cfg.HTTPProxy = u.String()
+	cfg.HTTPSProxy = u.String()
+	fn := cfg.ProxyFunc()
+	return func(req *http.Request) (*url.URL, error) {
+		return fn(req.URL)
+	}
+}
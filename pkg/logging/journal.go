@@ -0,0 +1,104 @@
+//go:build linux
+
+package logging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// journalSocket is where journald takes entries in its native protocol.
+var journalSocket = "/run/systemd/journal/socket"
+
+// newJournalCore returns a core that sends entries to journald as native
+// fields: MESSAGE, PRIORITY and SYSLOG_IDENTIFIER, the logger name as
+// LOCALGO_LOGGER, the caller as CODE_FILE, CODE_LINE and CODE_FUNC, and each
+// field as LOCALGO_ followed by its upper-cased key, so journalctl can
+// filter on them.
+func newJournalCore() (zapcore.Core, error) {
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: journalSocket, Net: "unixgram"})
+	if err != nil {
+		return nil, fmt.Errorf("journald is not running: %w", err)
+	}
+	return &sinkCore{
+		LevelEnabler: zapcore.DebugLevel,
+		write: func(ent zapcore.Entry, fields []zapcore.Field) error {
+			_, err := conn.Write(journalEntry(ent, fields))
+			return err
+		},
+	}, nil
+}
+
+// journalEntry encodes an entry in the journal export format, see
+// systemd.journal-fields(7) and the native protocol documentation.
+func journalEntry(ent zapcore.Entry, fields []zapcore.Field) []byte {
+	var b bytes.Buffer
+	journalField(&b, "MESSAGE", ent.Message)
+	journalField(&b, "PRIORITY", strconv.Itoa(syslogSeverity(ent.Level)))
+	journalField(&b, "SYSLOG_IDENTIFIER", syslogTag)
+	if ent.LoggerName != "" {
+		journalField(&b, "LOCALGO_LOGGER", ent.LoggerName)
+	}
+	if ent.Caller.Defined {
+		journalField(&b, "CODE_FILE", ent.Caller.File)
+		journalField(&b, "CODE_LINE", strconv.Itoa(ent.Caller.Line))
+		journalField(&b, "CODE_FUNC", ent.Caller.Function)
+	}
+	if ent.Stack != "" {
+		journalField(&b, "LOCALGO_STACK", ent.Stack)
+	}
+	enc := zapcore.NewMapObjectEncoder()
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+	for k, v := range enc.Fields {
+		s, ok := v.(string)
+		if !ok {
+			data, err := json.Marshal(v)
+			if err != nil {
+				data = []byte(fmt.Sprint(v))
+			}
+			s = string(data)
+		}
+		journalField(&b, journalFieldName(k), s)
+	}
+	return b.Bytes()
+}
+
+// journalField appends NAME=value, or for a value spanning lines the name,
+// the little-endian length and the raw value.
+func journalField(b *bytes.Buffer, name, value string) {
+	b.WriteString(name)
+	if !strings.Contains(value, "\n") {
+		b.WriteByte('=')
+		b.WriteString(value)
+		b.WriteByte('\n')
+		return
+	}
+	b.WriteByte('\n')
+	_ = binary.Write(b, binary.LittleEndian, uint64(len(value)))
+	b.WriteString(value)
+	b.WriteByte('\n')
+}
+
+// journalFieldName turns a field key into a journal field name, which may
+// only hold upper-case letters, digits and underscores, at most 64 of them.
+func journalFieldName(key string) string {
+	name := []byte("LOCALGO_" + strings.ToUpper(key))
+	for i, c := range name {
+		if (c < 'A' || c > 'Z') && (c < '0' || c > '9') {
+			name[i] = '_'
+		}
+	}
+	if len(name) > 64 {
+		name = name[:64]
+	}
+	return string(name)
+}
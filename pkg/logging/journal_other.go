@@ -0,0 +1,13 @@
+//go:build !linux
+
+package logging
+
+import (
+	"errors"
+
+	"go.uber.org/zap/zapcore"
+)
+
+func newJournalCore() (zapcore.Core, error) {
+	return nil, errors.New("journald is only available on Linux")
+}
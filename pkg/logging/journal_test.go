@@ -0,0 +1,67 @@
+//go:build linux
+
+package logging
+
+import (
+	"encoding/binary"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSetup_JournaldTarget(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "journal.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		t.Skipf("unixgram sockets unavailable: %v", err)
+	}
+	defer conn.Close()
+	defer func(old string) { journalSocket = old }(journalSocket)
+	journalSocket = socket
+
+	logger, err := Setup(Options{Target: TargetJournald})
+	if err != nil {
+		t.Fatalf("Setup failed: %v", err)
+	}
+	logger.Named("server.handlers").Errorw("Upload failed", "session-id", "abc", "size", 12, "detail", "line one\nline two")
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	buf := make([]byte, 4096)
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatalf("no journal entry received: %v", err)
+	}
+	entry := string(buf[:n])
+	for _, want := range []string{
+		"MESSAGE=Upload failed\n",
+		"PRIORITY=3\n",
+		"SYSLOG_IDENTIFIER=localgo\n",
+		"LOCALGO_LOGGER=server.handlers\n",
+		"LOCALGO_SESSION_ID=abc\n",
+		"LOCALGO_SIZE=12\n",
+	} {
+		if !strings.Contains(entry, want) {
+			t.Errorf("journal entry %q does not contain %q", entry, want)
+		}
+	}
+	var size [8]byte
+	binary.LittleEndian.PutUint64(size[:], uint64(len("line one\nline two")))
+	if !strings.Contains(entry, "LOCALGO_DETAIL\n"+string(size[:])+"line one\nline two\n") {
+		t.Errorf("journal entry %q does not hold the multi-line field in binary form", entry)
+	}
+}
+
+func TestJournalFieldName(t *testing.T) {
+	for key, want := range map[string]string{
+		"session":               "LOCALGO_SESSION",
+		"file.name":             "LOCALGO_FILE_NAME",
+		"2fa":                   "LOCALGO_2FA",
+		strings.Repeat("k", 80): "LOCALGO_" + strings.Repeat("K", 56),
+	} {
+		if got := journalFieldName(key); got != want {
+			t.Errorf("journalFieldName(%q) = %q, want %q", key, got, want)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package logging
+
+import (
+	"fmt"
+	"strings"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// ParseLevels parses per-logger level overrides of the form
+// "discovery=debug,server.handlers=warn". A name also covers its children:
+// "server" applies to "server.handlers" unless that has its own entry.
+func ParseLevels(s string) (map[string]zapcore.Level, error) {
+	levels := make(map[string]zapcore.Level)
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		name, lvl, ok := strings.Cut(part, "=")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid log level override %q: want name=level", part)
+		}
+		level, err := zapcore.ParseLevel(strings.TrimSpace(lvl))
+		if err != nil {
+			return nil, fmt.Errorf("invalid log level override %q: %w", part, err)
+		}
+		levels[name] = level
+	}
+	return levels, nil
+}
+
+// levelCore filters entries by the level configured for their logger name,
+// falling back to base. The wrapped core must accept every level.
+type levelCore struct {
+	zapcore.Core
+	base   zapcore.Level
+	levels map[string]zapcore.Level
+	min    zapcore.Level
+}
+
+func newLevelCore(core zapcore.Core, base zapcore.Level, levels map[string]zapcore.Level) zapcore.Core {
+	min := base
+	for _, l := range levels {
+		if l < min {
+			min = l
+		}
+	}
+	return &levelCore{Core: core, base: base, levels: levels, min: min}
+}
+
+// levelFor returns the level of the longest configured prefix of name.
+func (c *levelCore) levelFor(name string) zapcore.Level {
+	for name != "" {
+		if l, ok := c.levels[name]; ok {
+			return l
+		}
+		i := strings.LastIndexByte(name, '.')
+		if i < 0 {
+			break
+		}
+		name = name[:i]
+	}
+	return c.base
+}
+
+func (c *levelCore) Enabled(l zapcore.Level) bool {
+	return l >= c.min
+}
+
+func (c *levelCore) With(fields []zapcore.Field) zapcore.Core {
+	return &levelCore{Core: c.Core.With(fields), base: c.base, levels: c.levels, min: c.min}
+}
+
+func (c *levelCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if ent.Level < c.levelFor(ent.LoggerName) {
+		return ce
+	}
+	return c.Core.Check(ent, ce)
+}
@@ -1,6 +1,8 @@
 package logging
 
 import (
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
 
@@ -41,60 +43,86 @@ func colourLevelEncoder(l zapcore.Level, enc zapcore.PrimitiveArrayEncoder) {
 	}
 }
 
-func timeEncoder(t zapcore.TimeEncoder) zapcore.TimeEncoder {
-	return t
+// Defaults for the rotating log file.
+const (
+	DefaultMaxSizeMB  = 10
+	DefaultMaxBackups = 3
+)
+
+// Options configures the global logger.
+type Options struct {
+	Verbose  bool   // debug level, and echo the log to stdout in colour
+	JSON     bool   // newline-delimited JSON instead of human-readable text
+	NoColor  bool   // no ANSI colour in the stdout echo
+	Headless bool   // JSON on stdout, and no log file unless File is set
+	Level    string // base level: debug, info, warn or error (default info)
+	Levels   string // per-logger overrides, see ParseLevels
+
+	// Target is where the log goes, see ParseTarget. With journald or
+	// syslog the log file is only written if File is set.
+	Target string
+
+	// File is the log file; "" means app.log in the state directory and
+	// "-" means stderr. It is rotated at MaxSizeMB megabytes (0 = never),
+	// keeping MaxBackups old files.
+	File       string
+	MaxSizeMB  int
+	MaxBackups int
 }
 
-// Init initialises the global zap logger.
-//
-//   - verbose: enable debug-level output
-//   - jsonFmt: output newline-delimited JSON instead of human-readable text
-//   - noColor: disable ANSI color escape sequences in log output
-func Init(verbose, jsonFmt, noColor bool) *zap.SugaredLogger {
+// Setup initialises the global zap logger from opts. Invalid levels are
+// reported after falling back to the defaults, so logging always works.
+func Setup(opts Options) (*zap.SugaredLogger, error) {
+	var errs []error
+
 	level := zapcore.InfoLevel
-	if verbose {
+	if opts.Level != "" {
+		l, err := zapcore.ParseLevel(opts.Level)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid log level %q: %w", opts.Level, err))
+		} else {
+			level = l
+		}
+	}
+	if opts.Verbose {
 		level = zapcore.DebugLevel
 	}
-
-	stateDir := ""
-	if xdgState := os.Getenv("XDG_STATE_HOME"); xdgState != "" {
-		stateDir = filepath.Join(xdgState, "localgo")
-	} else if home, err := os.UserHomeDir(); err == nil {
-		stateDir = filepath.Join(home, ".local", "state", "localgo")
+	levels, err := ParseLevels(opts.Levels)
+	if err != nil {
+		errs = append(errs, err)
+		levels = nil
 	}
 
-	var fileWs zapcore.WriteSyncer
-	if stateDir != "" {
-		os.MkdirAll(stateDir, 0700)
-		logPath := filepath.Join(stateDir, "app.log")
-		if f, err := os.OpenFile(logPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600); err == nil {
-			fileWs = zapcore.Lock(f)
-		}
+	var cores []zapcore.Core
+	if opts.Headless {
+		cores = append(cores, zapcore.NewCore(jsonEncoder(), zapcore.Lock(os.Stdout), zapcore.DebugLevel))
 	}
-	if fileWs == nil {
-		fileWs = zapcore.AddSync(os.Stderr)
+	sink, err := openTarget(opts.Target)
+	if err != nil {
+		// Fall back to the log file rather than lose the log.
+		errs = append(errs, err)
 	}
-
-	var fileEnc zapcore.Encoder
-	if jsonFmt {
-		encCfg := zap.NewProductionEncoderConfig()
-		encCfg.TimeKey = "time"
-		encCfg.EncodeTime = zapcore.ISO8601TimeEncoder
-		encCfg.EncodeLevel = zapcore.LowercaseLevelEncoder
-		fileEnc = zapcore.NewJSONEncoder(encCfg)
-	} else {
-		encCfg := zap.NewProductionEncoderConfig()
-		encCfg.EncodeTime = zapcore.ISO8601TimeEncoder
-		fileEnc = zapcore.NewConsoleEncoder(encCfg)
+	if sink != nil {
+		cores = append(cores, sink)
 	}
-
-	fileCore := zapcore.NewCore(fileEnc, fileWs, level)
-
-	var core zapcore.Core
-	if verbose {
+	if (!opts.Headless && sink == nil) || opts.File != "" {
+		ws, err := openLogFile(opts)
+		if err != nil {
+			errs = append(errs, err)
+			ws = zapcore.Lock(os.Stderr)
+		}
+		enc := jsonEncoder()
+		if !opts.JSON {
+			encCfg := zap.NewProductionEncoderConfig()
+			encCfg.EncodeTime = zapcore.ISO8601TimeEncoder
+			enc = zapcore.NewConsoleEncoder(encCfg)
+		}
+		cores = append(cores, zapcore.NewCore(enc, ws, zapcore.DebugLevel))
+	}
+	if opts.Verbose && !opts.Headless {
 		// Also log to stdout
 		levelEnc := zapcore.LevelEncoder(colourLevelEncoder)
-		if noColor {
+		if opts.NoColor {
 			levelEnc = zapcore.CapitalLevelEncoder
 		}
 		stdoutEncCfg := zapcore.EncoderConfig{
@@ -112,26 +140,99 @@ func Init(verbose, jsonFmt, noColor bool) *zap.SugaredLogger {
 			ConsoleSeparator: "  ",
 		}
 		stdoutEnc := zapcore.NewConsoleEncoder(stdoutEncCfg)
-		stdoutCore := zapcore.NewCore(stdoutEnc, zapcore.Lock(os.Stdout), level)
-		core = zapcore.NewTee(fileCore, stdoutCore)
-	} else {
-		core = fileCore
+		cores = append(cores, zapcore.NewCore(stdoutEnc, zapcore.Lock(os.Stdout), zapcore.DebugLevel))
 	}
+	core := newLevelCore(zapcore.NewTee(cores...), level, levels)
 
-	opts := []zap.Option{zap.AddCaller(), zap.AddCallerSkip(0)}
-	if verbose {
-		opts = append(opts, zap.AddStacktrace(zapcore.ErrorLevel))
-	} else {
-		opts = []zap.Option{} // Minimal options for non-verbose
+	var zapOpts []zap.Option
+	if opts.Verbose {
+		zapOpts = append(zapOpts, zap.AddCaller(), zap.AddStacktrace(zapcore.ErrorLevel))
 	}
 
-	logger := zap.New(core, opts...)
+	logger := zap.New(core, zapOpts...)
 
 	globalLogger = logger
 	globalSugar = logger.Sugar()
 	zap.ReplaceGlobals(logger)
 
-	return globalSugar
+	return globalSugar, errors.Join(errs...)
+}
+
+// Init initialises the global zap logger with the default log file.
+//
+//   - verbose: enable debug-level output
+//   - jsonFmt: output newline-delimited JSON instead of human-readable text
+//   - noColor: disable ANSI color escape sequences in log output
+func Init(verbose, jsonFmt, noColor bool) *zap.SugaredLogger {
+	logger, _ := Setup(Options{
+		Verbose:    verbose,
+		JSON:       jsonFmt,
+		NoColor:    noColor,
+		MaxSizeMB:  DefaultMaxSizeMB,
+		MaxBackups: DefaultMaxBackups,
+	})
+	return logger
+}
+
+func jsonEncoder() zapcore.Encoder {
+	encCfg := zap.NewProductionEncoderConfig()
+	encCfg.TimeKey = "time"
+	encCfg.EncodeTime = zapcore.ISO8601TimeEncoder
+	encCfg.EncodeLevel = zapcore.LowercaseLevelEncoder
+	return zapcore.NewJSONEncoder(encCfg)
+}
+
+// openTarget returns the core writing to the log target s, or nil for the
+// log file.
+func openTarget(s string) (zapcore.Core, error) {
+	target, err := ParseTarget(s)
+	if err != nil {
+		return nil, err
+	}
+	var core zapcore.Core
+	switch target.Kind {
+	case TargetJournald:
+		core, err = newJournalCore()
+	case TargetSyslog:
+		core, err = newSyslogCore(target)
+	default:
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open log target %s: %w", s, err)
+	}
+	return core, nil
+}
+
+// openLogFile opens the log file named by opts, rotating it by size.
+func openLogFile(opts Options) (zapcore.WriteSyncer, error) {
+	path := opts.File
+	switch path {
+	case "-":
+		return zapcore.Lock(os.Stderr), nil
+	case "":
+		path = DefaultLogFile()
+		if path == "" {
+			return zapcore.Lock(os.Stderr), nil
+		}
+	}
+	f, err := openRotatingFile(path, int64(opts.MaxSizeMB)*1024*1024, opts.MaxBackups)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open log file %s: %w", path, err)
+	}
+	return f, nil
+}
+
+// DefaultLogFile returns app.log in the XDG state directory, or "" if there
+// is no home directory.
+func DefaultLogFile() string {
+	if xdgState := os.Getenv("XDG_STATE_HOME"); xdgState != "" {
+		return filepath.Join(xdgState, "localgo", "app.log")
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		return filepath.Join(home, ".local", "state", "localgo", "app.log")
+	}
+	return ""
 }
 
 // NewQuiet returns a no-op logger that discards all output.
@@ -0,0 +1,137 @@
+package logging
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestParseLevels(t *testing.T) {
+	levels, err := ParseLevels(" discovery=debug, server.handlers=WARN ,")
+	if err != nil {
+		t.Fatalf("ParseLevels failed: %v", err)
+	}
+	if len(levels) != 2 || levels["discovery"] != zapcore.DebugLevel || levels["server.handlers"] != zapcore.WarnLevel {
+		t.Errorf("unexpected levels: %v", levels)
+	}
+
+	for _, bad := range []string{"discovery", "=debug", "discovery=loud"} {
+		if _, err := ParseLevels(bad); err == nil {
+			t.Errorf("ParseLevels(%q): expected an error", bad)
+		}
+	}
+}
+
+func TestLevelCore(t *testing.T) {
+	inner, logs := observer.New(zapcore.DebugLevel)
+	core := newLevelCore(inner, zapcore.InfoLevel, map[string]zapcore.Level{
+		"discovery":       zapcore.DebugLevel,
+		"server":          zapcore.WarnLevel,
+		"server.handlers": zapcore.InfoLevel,
+	})
+	logger := zap.New(core)
+
+	logger.Debug("root debug")
+	logger.Info("root info")
+	logger.Named("discovery").Debug("discovery debug")
+	logger.Named("discovery").Named("multicast").Debug("child debug")
+	logger.Named("server").Info("server info")
+	logger.Named("server").Warn("server warn")
+	logger.Named("server").Named("handlers").Info("handlers info")
+	logger.Named("server").With(zap.String("k", "v")).Info("server info with fields")
+
+	var got []string
+	for _, e := range logs.All() {
+		got = append(got, e.Message)
+	}
+	want := []string{"root info", "discovery debug", "child debug", "server warn", "handlers info"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("logged %v, want %v", got, want)
+	}
+}
+
+func TestRotatingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", "app.log")
+	f, err := openRotatingFile(path, 10, 2)
+	if err != nil {
+		t.Fatalf("openRotatingFile failed: %v", err)
+	}
+	defer f.f.Close()
+
+	for _, line := range []string{"first\n", "second\n", "third\n", "fourth\n"} {
+		if _, err := f.Write([]byte(line)); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+	}
+
+	for name, want := range map[string]string{
+		path:        "fourth\n",
+		path + ".1": "third\n",
+		path + ".2": "second\n",
+	} {
+		data, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatalf("ReadFile %s: %v", name, err)
+		}
+		if string(data) != want {
+			t.Errorf("%s = %q, want %q", filepath.Base(name), data, want)
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("expected at most 2 backups, found %s.3", filepath.Base(path))
+	}
+}
+
+func TestSetup_InvalidLevelFallsBack(t *testing.T) {
+	logger, err := Setup(Options{Level: "loud", File: filepath.Join(t.TempDir(), "app.log")})
+	if err == nil {
+		t.Error("expected an error for an invalid level")
+	}
+	if logger == nil {
+		t.Fatal("expected a usable logger despite the error")
+	}
+	if !logger.Desugar().Core().Enabled(zapcore.InfoLevel) || logger.Desugar().Core().Enabled(zapcore.DebugLevel) {
+		t.Error("expected the default info level")
+	}
+}
+
+func TestParseTarget(t *testing.T) {
+	for _, tc := range []struct {
+		in   string
+		want Target
+		ok   bool
+	}{
+		{"", Target{Kind: TargetFile}, true},
+		{"file", Target{Kind: TargetFile}, true},
+		{"journald", Target{Kind: TargetJournald}, true},
+		{"syslog", Target{Kind: TargetSyslog}, true},
+		{"syslog+udp://logs.lan", Target{Kind: TargetSyslog, Network: "udp", Addr: "logs.lan:514"}, true},
+		{"syslog+tcp://[::1]:6514", Target{Kind: TargetSyslog, Network: "tcp", Addr: "[::1]:6514"}, true},
+		{"stdout", Target{}, false},
+		{"udp://logs.lan:514", Target{}, false},
+		{"syslog+udp://", Target{}, false},
+		{"syslog+udp://logs.lan/path", Target{}, false},
+	} {
+		got, err := ParseTarget(tc.in)
+		if (err == nil) != tc.ok || got != tc.want {
+			t.Errorf("ParseTarget(%q) = %+v, %v", tc.in, got, err)
+		}
+	}
+}
+
+func TestSetup_BadTargetFallsBackToFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	logger, err := Setup(Options{Target: "syslog+udp://logs.lan/path", File: path})
+	if err == nil {
+		t.Error("expected an error for an invalid target")
+	}
+	logger.Info("still logged")
+	if data, _ := os.ReadFile(path); !strings.Contains(string(data), "still logged") {
+		t.Errorf("log file = %q, want the message", data)
+	}
+}
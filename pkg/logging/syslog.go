@@ -0,0 +1,48 @@
+//go:build !windows && !plan9
+
+package logging
+
+import (
+	"log/syslog"
+	"strings"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// newSyslogCore returns a core that sends entries to the syslog daemon named
+// by t, as "logger message {fields}"; the daemon adds the time and severity.
+func newSyslogCore(t Target) (zapcore.Core, error) {
+	w, err := syslog.Dial(t.Network, t.Addr, syslog.LOG_DAEMON|syslog.LOG_INFO, syslogTag)
+	if err != nil {
+		return nil, err
+	}
+	enc := zapcore.NewConsoleEncoder(zapcore.EncoderConfig{
+		NameKey:          "N",
+		MessageKey:       "M",
+		EncodeName:       zapcore.FullNameEncoder,
+		ConsoleSeparator: " ",
+	})
+	return &sinkCore{
+		LevelEnabler: zapcore.DebugLevel,
+		write: func(ent zapcore.Entry, fields []zapcore.Field) error {
+			buf, err := enc.EncodeEntry(ent, fields)
+			if err != nil {
+				return err
+			}
+			defer buf.Free()
+			msg := strings.TrimSuffix(buf.String(), "\n")
+			switch syslogSeverity(ent.Level) {
+			case 7:
+				return w.Debug(msg)
+			case 6:
+				return w.Info(msg)
+			case 4:
+				return w.Warning(msg)
+			case 3:
+				return w.Err(msg)
+			default:
+				return w.Crit(msg)
+			}
+		},
+	}, nil
+}
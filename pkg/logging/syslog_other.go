@@ -0,0 +1,13 @@
+//go:build windows || plan9
+
+package logging
+
+import (
+	"errors"
+
+	"go.uber.org/zap/zapcore"
+)
+
+func newSyslogCore(Target) (zapcore.Core, error) {
+	return nil, errors.New("syslog is not available on this platform")
+}
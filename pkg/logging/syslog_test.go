@@ -0,0 +1,38 @@
+//go:build !windows && !plan9
+
+package logging
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSetup_SyslogTarget(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("udp unavailable: %v", err)
+	}
+	defer conn.Close()
+
+	logger, err := Setup(Options{Target: "syslog+udp://" + conn.LocalAddr().String()})
+	if err != nil {
+		t.Fatalf("Setup failed: %v", err)
+	}
+	logger.Named("server").Warnw("Disk nearly full", "free", 42)
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	buf := make([]byte, 2048)
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatalf("no syslog message received: %v", err)
+	}
+	// <28> is facility daemon (3) * 8 + severity warning (4).
+	msg := string(buf[:n])
+	for _, want := range []string{"<28>", "localgo[", `server Disk nearly full {"free": 42}`} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("syslog message %q does not contain %q", msg, want)
+		}
+	}
+}
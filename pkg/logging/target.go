@@ -0,0 +1,97 @@
+package logging
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// Log targets, see ParseTarget.
+const (
+	TargetFile     = "file"     // the rotating log file
+	TargetJournald = "journald" // the systemd journal, with native fields (Linux)
+	TargetSyslog   = "syslog"   // a syslog daemon, local or remote
+)
+
+// syslogTag is the program name syslog and journald entries carry.
+const syslogTag = "localgo"
+
+// Target is where the log is written.
+type Target struct {
+	Kind string // TargetFile, TargetJournald or TargetSyslog
+
+	// Network and Addr name a remote syslog daemon, "udp" or "tcp" and
+	// HOST:PORT; both are empty for the local one.
+	Network string
+	Addr    string
+}
+
+// ParseTarget parses a log target: "file" (or ""), "journald", "syslog" for
+// the local syslog daemon, or syslog+udp://HOST[:PORT] or
+// syslog+tcp://HOST[:PORT] for a remote one (port 514 by default).
+func ParseTarget(s string) (Target, error) {
+	switch s {
+	case "", TargetFile:
+		return Target{Kind: TargetFile}, nil
+	case TargetJournald, TargetSyslog:
+		return Target{Kind: s}, nil
+	}
+	u, err := url.Parse(s)
+	if err != nil || (u.Scheme != "syslog+udp" && u.Scheme != "syslog+tcp") || u.Host == "" || (u.Path != "" && u.Path != "/") {
+		return Target{}, fmt.Errorf("unknown log target %q: want file, journald, syslog, syslog+udp://HOST[:PORT] or syslog+tcp://HOST[:PORT]", s)
+	}
+	addr := u.Host
+	if u.Port() == "" {
+		addr = net.JoinHostPort(u.Hostname(), "514")
+	}
+	return Target{Kind: TargetSyslog, Network: strings.TrimPrefix(u.Scheme, "syslog+"), Addr: addr}, nil
+}
+
+// sinkCore hands each entry, with its fields, to write. It backs the
+// journald and syslog targets, which format entries themselves.
+type sinkCore struct {
+	zapcore.LevelEnabler
+	fields []zapcore.Field
+	write  func(zapcore.Entry, []zapcore.Field) error
+}
+
+func (c *sinkCore) With(fields []zapcore.Field) zapcore.Core {
+	return &sinkCore{
+		LevelEnabler: c.LevelEnabler,
+		fields:       append(c.fields[:len(c.fields):len(c.fields)], fields...),
+		write:        c.write,
+	}
+}
+
+func (c *sinkCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *sinkCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	return c.write(ent, append(c.fields[:len(c.fields):len(c.fields)], fields...))
+}
+
+func (c *sinkCore) Sync() error { return nil }
+
+// syslogSeverity maps a zap level to a syslog severity, which journald uses
+// as PRIORITY too.
+func syslogSeverity(l zapcore.Level) int {
+	switch {
+	case l <= zapcore.DebugLevel:
+		return 7 // debug
+	case l == zapcore.InfoLevel:
+		return 6 // info
+	case l == zapcore.WarnLevel:
+		return 4 // warning
+	case l == zapcore.ErrorLevel:
+		return 3 // err
+	default:
+		return 2 // crit
+	}
+}
@@ -0,0 +1,126 @@
+package metadata
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif" // register GIF for image.Decode
+	"image/jpeg"
+	_ "image/png" // register PNG for image.Decode
+	"io"
+	"os"
+	"strings"
+)
+
+// PreviewMaxSide is the longest side, in pixels, of an image preview.
+const PreviewMaxSide = 128
+
+// maxPreviewSourcePixels bounds the images Preview decodes, so a huge image
+// is not loaded into memory just for a thumbnail.
+const maxPreviewSourcePixels = 50_000_000
+
+// previewPrefix starts every preview made by Preview.
+const previewPrefix = "data:image/jpeg;base64,"
+
+// ErrNotImagePreview is returned by DecodePreview for a preview that is not
+// an image, such as the text of a message.
+var ErrNotImagePreview = errors.New("preview is not an image")
+
+// Preview returns a small JPEG thumbnail of the image at path as a base64
+// data URL, for the preview field of a file offered to a receiver. Files that
+// are not JPEG, PNG or GIF images, and very large images, have no preview:
+// Preview returns "" and no error for them.
+func Preview(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("preview: open: %w", err)
+	}
+	defer f.Close()
+
+	cfg, _, err := image.DecodeConfig(f)
+	if err != nil || cfg.Width*cfg.Height > maxPreviewSourcePixels {
+		return "", nil
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return "", fmt.Errorf("preview: seek: %w", err)
+	}
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return "", fmt.Errorf("preview: decode: %w", err)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(previewPrefix)
+	enc := base64.NewEncoder(base64.StdEncoding, &buf)
+	if err := jpeg.Encode(enc, Thumbnail(img, PreviewMaxSide), &jpeg.Options{Quality: 60}); err != nil {
+		return "", fmt.Errorf("preview: encode: %w", err)
+	}
+	enc.Close()
+	return buf.String(), nil
+}
+
+// IsImagePreview reports whether preview holds an image rather than text.
+func IsImagePreview(preview string) bool {
+	return strings.HasPrefix(preview, "data:image/")
+}
+
+// DecodePreview decodes an image preview: a base64 data URL of a JPEG, PNG
+// or GIF image no larger than PreviewMaxSide on either side.
+func DecodePreview(preview string) (image.Image, error) {
+	header, data, ok := strings.Cut(preview, ",")
+	if !ok || !IsImagePreview(header) || !strings.HasSuffix(header, ";base64") {
+		return nil, ErrNotImagePreview
+	}
+	raw, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return nil, fmt.Errorf("preview: %w", err)
+	}
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(raw))
+	if err != nil {
+		return nil, fmt.Errorf("preview: %w", err)
+	}
+	if cfg.Width > PreviewMaxSide || cfg.Height > PreviewMaxSide {
+		return nil, fmt.Errorf("preview: %dx%d is larger than %dx%d", cfg.Width, cfg.Height, PreviewMaxSide, PreviewMaxSide)
+	}
+	img, _, err := image.Decode(bytes.NewReader(raw))
+	if err != nil {
+		return nil, fmt.Errorf("preview: %w", err)
+	}
+	return img, nil
+}
+
+// Thumbnail scales img down so that neither side is longer than maxSide,
+// keeping its aspect ratio. Each pixel is the average of the pixels it
+// covers. Images that already fit are returned as they are.
+func Thumbnail(img image.Image, maxSide int) image.Image {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w <= maxSide && h <= maxSide {
+		return img
+	}
+	tw, th := maxSide, max(1, h*maxSide/w)
+	if h > w {
+		tw, th = max(1, w*maxSide/h), maxSide
+	}
+
+	dst := image.NewRGBA64(image.Rect(0, 0, tw, th))
+	for y := 0; y < th; y++ {
+		y0, y1 := b.Min.Y+y*h/th, b.Min.Y+(y+1)*h/th
+		for x := 0; x < tw; x++ {
+			x0, x1 := b.Min.X+x*w/tw, b.Min.X+(x+1)*w/tw
+			var r, g, bl, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := img.At(sx, sy).RGBA()
+					r, g, bl, a = r+uint64(cr), g+uint64(cg), bl+uint64(cb), a+uint64(ca)
+					n++
+				}
+			}
+			dst.SetRGBA64(x, y, color.RGBA64{R: uint16(r / n), G: uint16(g / n), B: uint16(bl / n), A: uint16(a / n)})
+		}
+	}
+	return dst
+}
@@ -0,0 +1,82 @@
+package metadata
+
+import (
+	"encoding/base64"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writePNG(t *testing.T, path string, w, h int) {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.RGBA{R: 200, G: 40, B: 40, A: 255})
+		}
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	defer f.Close()
+	if err := png.Encode(f, img); err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+}
+
+func TestPreview_Image(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "photo.png")
+	writePNG(t, path, 640, 320)
+
+	preview, err := Preview(path)
+	if err != nil {
+		t.Fatalf("Preview: %v", err)
+	}
+	if !IsImagePreview(preview) {
+		t.Fatalf("expected an image data URL, got %.40q", preview)
+	}
+	img, err := DecodePreview(preview)
+	if err != nil {
+		t.Fatalf("DecodePreview: %v", err)
+	}
+	if b := img.Bounds(); b.Dx() != PreviewMaxSide || b.Dy() != PreviewMaxSide/2 {
+		t.Errorf("expected a %dx%d thumbnail, got %dx%d", PreviewMaxSide, PreviewMaxSide/2, b.Dx(), b.Dy())
+	}
+	r, g, _, _ := img.At(10, 10).RGBA()
+	if r>>8 < 150 || g>>8 > 90 {
+		t.Errorf("expected the thumbnail to keep the image's colour, got r=%d g=%d", r>>8, g>>8)
+	}
+}
+
+func TestPreview_NotAnImage(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notes.txt")
+	if err := os.WriteFile(path, []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	preview, err := Preview(path)
+	if err != nil || preview != "" {
+		t.Errorf("expected no preview and no error, got %q, %v", preview, err)
+	}
+}
+
+func TestDecodePreview_Rejects(t *testing.T) {
+	big := filepath.Join(t.TempDir(), "big.png")
+	writePNG(t, big, PreviewMaxSide+1, 10)
+	data, _ := os.ReadFile(big)
+
+	for name, preview := range map[string]string{
+		"text":      "see you at 6",
+		"bad data":  "data:image/png;base64,!!!",
+		"too large": "data:image/png;base64," + base64.StdEncoding.EncodeToString(data),
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := DecodePreview(preview); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package model
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Feature is a part of the LocalSend protocol that not every peer supports.
+type Feature string
+
+const (
+	// FeatureSessions: uploads and cancels name a session ID (v2). v1 peers
+	// take upload tokens alone.
+	FeatureSessions Feature = "sessions"
+	// FeaturePIN: a receiver can require a PIN on prepare-upload (v2).
+	FeaturePIN Feature = "pin"
+	// FeatureMetadata: files carry modification and access times (v2).
+	FeatureMetadata Feature = "metadata"
+	// FeatureDownload: the peer serves files through the download API
+	// (v2, when it advertises download).
+	FeatureDownload Feature = "download"
+)
+
+// ParseProtocolVersion parses a protocol version such as "2.1" or "1".
+func ParseProtocolVersion(version string) (major, minor int, ok bool) {
+	majorStr, minorStr, hasMinor := strings.Cut(strings.TrimSpace(version), ".")
+	major, err := strconv.Atoi(majorStr)
+	if err != nil || major < 0 {
+		return 0, 0, false
+	}
+	if hasMinor {
+		if minor, err = strconv.Atoi(minorStr); err != nil || minor < 0 {
+			return 0, 0, false
+		}
+	}
+	return major, minor, true
+}
+
+// Supports reports whether the device supports feature, going by the
+// protocol version it reported. A device that reported no version, or one
+// that cannot be parsed, is taken to speak v2.
+func (d *Device) Supports(feature Feature) bool {
+	d.mu.RLock()
+	version, download := d.Version, d.Download
+	d.mu.RUnlock()
+
+	major, _, ok := ParseProtocolVersion(version)
+	if !ok {
+		major = 2
+	}
+	switch feature {
+	case FeatureSessions, FeaturePIN, FeatureMetadata:
+		return major >= 2
+	case FeatureDownload:
+		return major >= 2 && download
+	default:
+		return false
+	}
+}
+
+// Capabilities returns the features the device supports.
+func (d *Device) Capabilities() []Feature {
+	var features []Feature
+	for _, f := range []Feature{FeatureSessions, FeaturePIN, FeatureMetadata, FeatureDownload} {
+		if d.Supports(f) {
+			features = append(features, f)
+		}
+	}
+	return features
+}
@@ -0,0 +1,48 @@
+package model_test
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/bethropolis/localgo/pkg/model"
+)
+
+func TestParseProtocolVersion(t *testing.T) {
+	for _, tc := range []struct {
+		version      string
+		major, minor int
+		ok           bool
+	}{
+		{"2.1", 2, 1, true},
+		{"1.0", 1, 0, true},
+		{"1", 1, 0, true},
+		{"", 0, 0, false},
+		{"v2", 0, 0, false},
+		{"2.x", 0, 0, false},
+	} {
+		major, minor, ok := model.ParseProtocolVersion(tc.version)
+		if major != tc.major || minor != tc.minor || ok != tc.ok {
+			t.Errorf("ParseProtocolVersion(%q) = %d, %d, %v; want %d, %d, %v", tc.version, major, minor, ok, tc.major, tc.minor, tc.ok)
+		}
+	}
+}
+
+func TestDeviceSupports(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		device *model.Device
+		want   []model.Feature
+	}{
+		{"v2 with download", &model.Device{Version: "2.1", Download: true}, []model.Feature{model.FeatureSessions, model.FeaturePIN, model.FeatureMetadata, model.FeatureDownload}},
+		{"v2", &model.Device{Version: "2.0"}, []model.Feature{model.FeatureSessions, model.FeaturePIN, model.FeatureMetadata}},
+		{"unknown version", &model.Device{}, []model.Feature{model.FeatureSessions, model.FeaturePIN, model.FeatureMetadata}},
+		{"v1", &model.Device{Version: "1.0", Download: true}, nil},
+	} {
+		if got := tc.device.Capabilities(); !slices.Equal(got, tc.want) {
+			t.Errorf("%s: Capabilities() = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+	if (&model.Device{}).Supports("no-such-feature") {
+		t.Error("an unknown feature is reported as supported")
+	}
+}
@@ -22,6 +22,10 @@ type Device struct {
 	Download    bool       `json:"download"` // Whether the device has download server running
 	LastSeen    time.Time  `json:"lastSeen"`
 	Available   bool       `json:"available"`
+
+	// Set by scan and discover.
+	Hostname  string  `json:"hostname,omitempty"`  // reverse DNS name of IP
+	LatencyMs float64 `json:"latencyMs,omitempty"` // how long the device took to answer a probe
 }
 
 // NewDevice creates a new Device instance
@@ -87,6 +91,28 @@ func (d *Device) IsStale(staleThreshold time.Duration) bool {
 	return time.Since(d.LastSeen) > staleThreshold
 }
 
+// Snapshot returns a copy of the device that can be read or encoded while
+// the original keeps being updated.
+func (d *Device) Snapshot() *Device {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return &Device{
+		IP:          d.IP,
+		Version:     d.Version,
+		Port:        d.Port,
+		Alias:       d.Alias,
+		Protocol:    d.Protocol,
+		Fingerprint: d.Fingerprint,
+		DeviceModel: d.DeviceModel,
+		DeviceType:  d.DeviceType,
+		Download:    d.Download,
+		LastSeen:    d.LastSeen,
+		Available:   d.Available,
+		Hostname:    d.Hostname,
+		LatencyMs:   d.LatencyMs,
+	}
+}
+
 // ToDebugString returns a string representation suitable for debugging
 func (d *Device) ToDebugString() string {
 	shortFingerprint := d.Fingerprint
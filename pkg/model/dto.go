@@ -1,6 +1,12 @@
 // Package model contains the data structures used throughout the LocalGo application
 package model
 
+import (
+	"time"
+
+	"github.com/bethropolis/localgo/pkg/report"
+)
+
 const DefaultPort = 53317
 
 // DeviceType defines the type of the device.
@@ -127,8 +133,9 @@ type MulticastDto struct {
 
 // PrepareUploadRequestDto is sent to prepare file uploads
 type PrepareUploadRequestDto struct {
-	Info  InfoDto            `json:"info"`
-	Files map[string]FileDto `json:"files"`
+	Info       InfoDto            `json:"info"`
+	Files      map[string]FileDto `json:"files"`
+	TargetPath string             `json:"targetPath,omitempty"` // folder in the receiver's download directory to place the files in
 }
 
 // FileDto contains information about a file being uploaded
@@ -154,6 +161,52 @@ type PrepareUploadResponseDto struct {
 	Files     map[string]string `json:"files"`
 }
 
+// SendRequestV1Dto is the body of a v1 /send-request, the v1 counterpart of
+// PrepareUploadRequestDto. It is answered with a map of file IDs to tokens.
+type SendRequestV1Dto struct {
+	Info  InfoV1Dto            `json:"info"`
+	Files map[string]FileV1Dto `json:"files"`
+}
+
+// InfoV1Dto describes the sender of a v1 /send-request.
+type InfoV1Dto struct {
+	Alias       string     `json:"alias"`
+	DeviceModel *string    `json:"deviceModel"` // nullable
+	DeviceType  DeviceType `json:"deviceType"`
+}
+
+// FileV1Dto describes a file in a v1 /send-request. FileType is one of the
+// LegacyFileType values rather than a MIME type.
+type FileV1Dto struct {
+	ID       string  `json:"id"`
+	FileName string  `json:"fileName"`
+	Size     int64   `json:"size"`
+	FileType string  `json:"fileType"`
+	Preview  *string `json:"preview,omitempty"` // nullable
+}
+
+// ToFileDto converts a v1 file to a FileDto, deriving its MIME type.
+func (f FileV1Dto) ToFileDto() FileDto {
+	return FileDto{
+		ID:       f.ID,
+		FileName: f.FileName,
+		Size:     f.Size,
+		FileType: MimeTypeForLegacy(f.FileType, f.FileName),
+		Preview:  f.Preview,
+	}
+}
+
+// ToFileV1Dto converts a FileDto to a v1 file, dropping what v1 lacks.
+func (f FileDto) ToFileV1Dto() FileV1Dto {
+	return FileV1Dto{
+		ID:       f.ID,
+		FileName: f.FileName,
+		Size:     f.Size,
+		FileType: LegacyFileType(f.FileType),
+		Preview:  f.Preview,
+	}
+}
+
 // ReceiveRequestResponseDto is returned for download preparations
 type ReceiveRequestResponseDto struct {
 	Info      InfoDto            `json:"info"` // Added Info field as per protocol spec
@@ -165,3 +218,45 @@ type ReceiveRequestResponseDto struct {
 type StatusDto struct {
 	Status string `json:"status"`
 }
+
+// ServerStatusDto reports what a running server is doing: the sessions it is
+// receiving, the files it shares, and the transfers that ended last.
+type ServerStatusDto struct {
+	Sessions  []SessionStatusDto `json:"sessions"`
+	Sharing   *ShareStatusDto    `json:"sharing,omitempty"`
+	Recent    []*report.Report   `json:"recent"` // most recent first
+	QuickSave QuickSaveDto       `json:"quickSave"`
+}
+
+// SessionStatusDto is an active receive session.
+type SessionStatusDto struct {
+	SessionID string          `json:"sessionId"`
+	Sender    string          `json:"sender"`
+	SenderIP  string          `json:"senderIp"`
+	StartedAt time.Time       `json:"startedAt"`
+	Restored  bool            `json:"restored,omitempty"` // recovered after a restart
+	Size      int64           `json:"size"`               // of all files
+	Bytes     int64           `json:"bytes"`              // received so far, of all files
+	Files     []FileStatusDto `json:"files"`
+}
+
+// ShareStatusDto lists the files a running server offers for download.
+type ShareStatusDto struct {
+	SessionID string          `json:"sessionId"`
+	Files     []FileStatusDto `json:"files"`
+}
+
+// FileStatusDto is the progress of one file.
+type FileStatusDto struct {
+	Name    string `json:"name"`
+	Size    int64  `json:"size"`
+	Bytes   int64  `json:"bytes"`             // transferred so far
+	Status  string `json:"status"`            // pending, receiving, received, failed or offered
+	Preview string `json:"preview,omitempty"` // image thumbnail offered by the sender, as a data URL
+}
+
+// QuickSaveDto reports the quick save state of a running server.
+type QuickSaveDto struct {
+	Enabled bool    `json:"enabled"`
+	Until   *string `json:"until,omitempty"` // RFC 3339; absent when on until disabled
+}
@@ -8,6 +8,7 @@ import (
 	"mime"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 )
 
@@ -97,6 +98,51 @@ func determineFileType(path string) string {
 	}
 }
 
+// The file types of the v1 protocol, which sends a category in place of a
+// MIME type.
+const (
+	LegacyFileTypeImage = "image"
+	LegacyFileTypeVideo = "video"
+	LegacyFileTypePDF   = "pdf"
+	LegacyFileTypeText  = "text"
+	LegacyFileTypeAPK   = "apk"
+	LegacyFileTypeOther = "other"
+)
+
+// LegacyFileType returns the v1 file type for a MIME type.
+func LegacyFileType(mimeType string) string {
+	mimeType, _, _ = strings.Cut(mimeType, ";")
+	mimeType = strings.ToLower(strings.TrimSpace(mimeType))
+	switch {
+	case strings.HasPrefix(mimeType, "image/"):
+		return LegacyFileTypeImage
+	case strings.HasPrefix(mimeType, "video/"):
+		return LegacyFileTypeVideo
+	case mimeType == "application/pdf":
+		return LegacyFileTypePDF
+	case mimeType == "text/plain":
+		return LegacyFileTypeText
+	case mimeType == "application/vnd.android.package-archive":
+		return LegacyFileTypeAPK
+	default:
+		return LegacyFileTypeOther
+	}
+}
+
+// MimeTypeForLegacy returns a MIME type for a file sent with a v1 file type,
+// going by the file name's extension where the category is too broad.
+func MimeTypeForLegacy(fileType, fileName string) string {
+	switch fileType {
+	case LegacyFileTypeText:
+		return "text/plain"
+	case LegacyFileTypePDF:
+		return "application/pdf"
+	case LegacyFileTypeAPK:
+		return "application/vnd.android.package-archive"
+	}
+	return determineFileType(fileName)
+}
+
 // calculateSHA256 calculates the SHA-256 hash of a file
 func calculateSHA256(path string) (string, error) {
 	f, err := os.Open(path)
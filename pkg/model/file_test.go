@@ -131,3 +131,28 @@ func TestDetermineFileType_Fallback(t *testing.T) {
 		}
 	}
 }
+
+func TestLegacyFileTypes(t *testing.T) {
+	for mimeType, want := range map[string]string{
+		"image/png":                 model.LegacyFileTypeImage,
+		"video/mp4":                 model.LegacyFileTypeVideo,
+		"application/pdf":           model.LegacyFileTypePDF,
+		"text/plain; charset=utf-8": model.LegacyFileTypeText,
+		"application/zip":           model.LegacyFileTypeOther,
+	} {
+		if got := model.LegacyFileType(mimeType); got != want {
+			t.Errorf("LegacyFileType(%q) = %q, want %q", mimeType, got, want)
+		}
+	}
+
+	for _, tc := range []struct{ fileType, name, want string }{
+		{model.LegacyFileTypeText, "message", "text/plain"},
+		{model.LegacyFileTypeImage, "photo.png", "image/png"},
+		{model.LegacyFileTypeAPK, "app", "application/vnd.android.package-archive"},
+		{model.LegacyFileTypeOther, "data", "application/octet-stream"},
+	} {
+		if got := model.MimeTypeForLegacy(tc.fileType, tc.name); got != tc.want {
+			t.Errorf("MimeTypeForLegacy(%q, %q) = %q, want %q", tc.fileType, tc.name, got, tc.want)
+		}
+	}
+}
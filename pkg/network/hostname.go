@@ -0,0 +1,110 @@
+package network
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// LookupHostname returns a name of the host at ip, or "" if none is found
+// before ctx ends. The DNS is asked first; an IPv4 host without a DNS name
+// is then asked for its own multicast DNS name, which is where the .local
+// names of most LAN devices live.
+func LookupHostname(ctx context.Context, ip string) string {
+	host, _, _ := strings.Cut(ip, "%") // a zone is no part of the name
+	if names, err := net.DefaultResolver.LookupAddr(ctx, host); err == nil && len(names) > 0 {
+		return strings.TrimSuffix(names[0], ".")
+	}
+	addr := net.ParseIP(host).To4()
+	if addr == nil {
+		return ""
+	}
+	name, err := lookupMDNSName(ctx, addr, &net.UDPAddr{IP: addr, Port: mdnsAddr.Port})
+	if err != nil {
+		return ""
+	}
+	return name
+}
+
+// lookupMDNSName asks server for the PTR record of ip with a one-shot
+// multicast DNS query. Sent straight to a host, it is answered by the host's
+// responder, if it runs one.
+func lookupMDNSName(ctx context.Context, ip net.IP, server *net.UDPAddr) (string, error) {
+	reverse := fmt.Sprintf("%d.%d.%d.%d.in-addr.arpa.", ip[3], ip[2], ip[1], ip[0])
+	qname, err := dnsmessage.NewName(reverse)
+	if err != nil {
+		return "", err
+	}
+	b := dnsmessage.NewBuilder(nil, dnsmessage.Header{})
+	if err := b.StartQuestions(); err != nil {
+		return "", err
+	}
+	if err := b.Question(dnsmessage.Question{Name: qname, Type: dnsmessage.TypePTR, Class: dnsmessage.ClassINET}); err != nil {
+		return "", err
+	}
+	query, err := b.Finish()
+	if err != nil {
+		return "", err
+	}
+
+	conn, err := net.ListenUDP("udp4", nil)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	deadline := time.Now().Add(mdnsTimeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	conn.SetDeadline(deadline)
+	stop := context.AfterFunc(ctx, func() { conn.SetDeadline(time.Now()) })
+	defer stop()
+
+	if _, err := conn.WriteToUDP(query, server); err != nil {
+		return "", err
+	}
+	buf := make([]byte, 9000)
+	for {
+		n, _, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			return "", fmt.Errorf("no mDNS name for %s: %w", ip, err)
+		}
+		if name := ptrAnswer(buf[:n], reverse); name != "" {
+			return name, nil
+		}
+	}
+}
+
+// ptrAnswer returns the name a response maps reverse to, without the
+// trailing dot.
+func ptrAnswer(msg []byte, reverse string) string {
+	var p dnsmessage.Parser
+	if h, err := p.Start(msg); err != nil || !h.Response {
+		return ""
+	}
+	if err := p.SkipAllQuestions(); err != nil {
+		return ""
+	}
+	for {
+		rh, err := p.AnswerHeader()
+		if errors.Is(err, dnsmessage.ErrSectionDone) || err != nil {
+			return ""
+		}
+		if rh.Type != dnsmessage.TypePTR || !strings.EqualFold(rh.Name.String(), reverse) {
+			if p.SkipAnswer() != nil {
+				return ""
+			}
+			continue
+		}
+		r, err := p.PTRResource()
+		if err != nil {
+			return ""
+		}
+		return strings.TrimSuffix(r.PTR.String(), ".")
+	}
+}
@@ -0,0 +1,46 @@
+package network
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+func TestLookupMDNSName(t *testing.T) {
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Skipf("udp unavailable: %v", err)
+	}
+	defer conn.Close()
+	go func() {
+		buf := make([]byte, 1500)
+		n, from, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			return
+		}
+		var p dnsmessage.Parser
+		if _, err := p.Start(buf[:n]); err != nil {
+			return
+		}
+		q, err := p.Question()
+		if err != nil || q.Type != dnsmessage.TypePTR {
+			return
+		}
+		b := dnsmessage.NewBuilder(nil, dnsmessage.Header{Response: true, Authoritative: true})
+		b.StartAnswers()
+		hdr := dnsmessage.ResourceHeader{Name: q.Name, Class: dnsmessage.ClassINET, TTL: 120}
+		b.PTRResource(hdr, dnsmessage.PTRResource{PTR: dnsmessage.MustNewName("mylaptop.local.")})
+		msg, _ := b.Finish()
+		conn.WriteToUDP(msg, from)
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	name, err := lookupMDNSName(ctx, net.IPv4(192, 168, 1, 42).To4(), conn.LocalAddr().(*net.UDPAddr))
+	if err != nil || name != "mylaptop.local" {
+		t.Errorf("lookupMDNSName = %q, %v, want mylaptop.local", name, err)
+	}
+}
@@ -28,9 +28,11 @@ func GetLocalIP() (string, error) {
 	return "", errors.New("no suitable local IP address found")
 }
 
-// GetLocalIPAddresses returns a list of local IP addresses for all non-loopback interfaces
+// GetLocalIPAddresses returns a list of local IP addresses for all non-loopback interfaces:
+// their IPv4 addresses, then their IPv6 link-local ones, which reach the link's
+// neighbors where there is no IPv4 (see LinkLocalNeighbors).
 func GetLocalIPAddresses() ([]net.IP, error) {
-	var ips []net.IP
+	var ips, linkLocal []net.IP
 	ifaces, err := net.Interfaces()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get network interfaces: %w", err)
@@ -53,11 +55,13 @@ func GetLocalIPAddresses() ([]net.IP, error) {
 				// Get the IPv4 address
 				if ip = ipnet.IP.To4(); ip != nil {
 					ips = append(ips, ip)
+				} else if IsLinkLocalIPv6(ipnet.IP) && (i.Flags&net.FlagMulticast) != 0 {
+					linkLocal = append(linkLocal, ipnet.IP)
 				}
 			}
 		}
 	}
-	return ips, nil
+	return append(ips, linkLocal...), nil
 }
 
 // GetInterfaceIPs returns all IP addresses for a specific network interface
@@ -169,6 +173,29 @@ func GetSubnetIPs(ip net.IP) []net.IP {
 	return ips
 }
 
+// InterfaceByIP returns the up interface that has the address ip.
+func InterfaceByIP(ip net.IP) (*net.Interface, error) {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get network interfaces: %w", err)
+	}
+	for i := range ifaces {
+		if ifaces[i].Flags&net.FlagUp == 0 {
+			continue
+		}
+		addrs, err := ifaces[i].Addrs()
+		if err != nil {
+			continue
+		}
+		for _, addr := range addrs {
+			if ipnet, ok := addr.(*net.IPNet); ok && ipnet.IP.Equal(ip) {
+				return &ifaces[i], nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("no interface has the address %s", ip)
+}
+
 // GetInterfaceIPNet returns the IPv4 network (IP + subnet mask) for the named interface.
 // Returns nil if the interface has no IPv4 address.
 func GetInterfaceIPNet(ifaceName string) (*net.IPNet, error) {
@@ -192,8 +219,13 @@ func GetInterfaceIPNet(ifaceName string) (*net.IPNet, error) {
 
 // GetUsableSubnetIPsFromIP returns all usable host IPs in the subnet of the
 // interface that owns the given IP, respecting its actual netmask. Falls back
-// to a flat /24 scan if the interface cannot be determined.
+// to a flat /24 scan if the interface cannot be determined. An IPv6 link-local
+// address is returned as is: its link cannot be swept, and the HTTP scanner
+// probes the neighbors that answer a ping on it instead.
 func GetUsableSubnetIPsFromIP(ip net.IP) ([]net.IP, error) {
+	if IsLinkLocalIPv6(ip) {
+		return []net.IP{ip}, nil
+	}
 	ipStr := ip.String()
 	ifaces, err := net.Interfaces()
 	if err != nil {
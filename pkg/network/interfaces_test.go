@@ -33,8 +33,8 @@ func TestGetLocalIPAddresses(t *testing.T) {
 		if ip.IsLoopback() {
 			t.Errorf("GetLocalIPAddresses returned loopback IP: %v", ip)
 		}
-		if ip.To4() == nil {
-			t.Errorf("GetLocalIPAddresses returned non-IPv4 address: %v", ip)
+		if ip.To4() == nil && !network.IsLinkLocalIPv6(ip) {
+			t.Errorf("GetLocalIPAddresses returned an IPv6 address that is not link-local: %v", ip)
 		}
 	}
 }
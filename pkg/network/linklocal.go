@@ -0,0 +1,120 @@
+package network
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv6"
+)
+
+// neighborWait bounds how long LinkLocalNeighbors collects answers.
+const neighborWait = time.Second
+
+// IsLinkLocalIPv6 reports whether ip is an IPv6 link-local address, one in
+// fe80::/10, which is only meaningful together with a zone naming its link.
+func IsLinkLocalIPv6(ip net.IP) bool {
+	return ip.To4() == nil && ip.IsLinkLocalUnicast()
+}
+
+// URLHost joins host and port for the host part of a URL. Unlike
+// net.JoinHostPort it escapes the zone of an IPv6 link-local address, as in
+// [fe80::1%25eth0]:53317, which URLs require.
+func URLHost(host string, port int) string {
+	return net.JoinHostPort(strings.Replace(host, "%", "%25", 1), strconv.Itoa(port))
+}
+
+// LinkLocalNeighbors returns the addresses of the neighbors on the link of
+// the local IPv6 link-local address ip, with the link's interface as zone.
+// Unlike an IPv4 subnet, a link cannot be swept address by address, so the
+// all-nodes group is pinged and whoever answers before the context's
+// deadline (or neighborWait, if sooner) is a neighbor. Hosts that ignore
+// multicast pings, as Windows does by default, are not found.
+func LinkLocalNeighbors(ctx context.Context, ip net.IP) ([]net.IPAddr, error) {
+	if !IsLinkLocalIPv6(ip) {
+		return nil, fmt.Errorf("%s is not an IPv6 link-local address", ip)
+	}
+	iface, err := InterfaceByIP(ip)
+	if err != nil {
+		return nil, err
+	}
+	local := &net.IPAddr{IP: ip, Zone: iface.Name}
+
+	// Unprivileged ICMP sockets need net.ipv4.ping_group_range to include
+	// the user's group; raw ones need root or CAP_NET_RAW.
+	raw := false
+	conn, err := icmp.ListenPacket("udp6", local.String())
+	if err != nil {
+		raw = true
+		if conn, err = icmp.ListenPacket("ip6:ipv6-icmp", local.String()); err != nil {
+			return nil, fmt.Errorf("cannot send ICMPv6 pings on %s: %w", iface.Name, err)
+		}
+	}
+	defer conn.Close()
+
+	deadline := time.Now().Add(neighborWait)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	conn.SetDeadline(deadline)
+	stop := context.AfterFunc(ctx, func() { conn.SetDeadline(time.Now()) })
+	defer stop()
+
+	id := os.Getpid() & 0xffff
+	req := icmp.Message{
+		Type: ipv6.ICMPTypeEchoRequest,
+		Body: &icmp.Echo{ID: id, Seq: 1, Data: []byte("localgo")},
+	}
+	b, err := req.Marshal(nil)
+	if err != nil {
+		return nil, err
+	}
+	var dst net.Addr = &net.UDPAddr{IP: net.IPv6linklocalallnodes, Zone: iface.Name}
+	if raw {
+		dst = &net.IPAddr{IP: net.IPv6linklocalallnodes, Zone: iface.Name}
+	}
+	if _, err := conn.WriteTo(b, dst); err != nil {
+		return nil, fmt.Errorf("ping all nodes on %s: %w", iface.Name, err)
+	}
+
+	var neighbors []net.IPAddr
+	seen := map[string]bool{ip.String(): true}
+	buf := make([]byte, 1500)
+	for {
+		n, from, err := conn.ReadFrom(buf)
+		if err != nil {
+			var ne net.Error
+			if errors.As(err, &ne) && ne.Timeout() {
+				return neighbors, nil
+			}
+			return neighbors, err
+		}
+		msg, err := icmp.ParseMessage(ipv6.ICMPTypeEchoReply.Protocol(), buf[:n])
+		if err != nil || msg.Type != ipv6.ICMPTypeEchoReply {
+			continue
+		}
+		// The kernel matches replies to unprivileged sockets itself; a raw
+		// socket sees every ICMPv6 message.
+		if echo, ok := msg.Body.(*icmp.Echo); !ok || (raw && echo.ID != id) {
+			continue
+		}
+		var from6 net.IP
+		switch a := from.(type) {
+		case *net.UDPAddr:
+			from6 = a.IP
+		case *net.IPAddr:
+			from6 = a.IP
+		}
+		if !IsLinkLocalIPv6(from6) || seen[from6.String()] {
+			continue
+		}
+		seen[from6.String()] = true
+		neighbors = append(neighbors, net.IPAddr{IP: from6, Zone: iface.Name})
+	}
+}
@@ -0,0 +1,81 @@
+package network_test
+
+import (
+	"context"
+	"net"
+	"net/url"
+	"testing"
+
+	"github.com/bethropolis/localgo/pkg/network"
+)
+
+func TestURLHost(t *testing.T) {
+	tests := []struct {
+		host string
+		want string
+	}{
+		{"192.168.1.10", "192.168.1.10:53317"},
+		{"fe80::1", "[fe80::1]:53317"},
+		{"fe80::1%eth0", "[fe80::1%25eth0]:53317"},
+		{"mylaptop.local", "mylaptop.local:53317"},
+	}
+	for _, tt := range tests {
+		got := network.URLHost(tt.host, 53317)
+		if got != tt.want {
+			t.Errorf("URLHost(%q) = %q, want %q", tt.host, got, tt.want)
+			continue
+		}
+		// The zone must survive a round trip through a URL.
+		u, err := url.Parse("http://" + got + "/")
+		if err != nil {
+			t.Errorf("URLHost(%q): %v", tt.host, err)
+		} else if u.Hostname() != tt.host {
+			t.Errorf("URLHost(%q) parses back to %q", tt.host, u.Hostname())
+		}
+	}
+}
+
+func TestGetUsableSubnetIPsFromIP_LinkLocal(t *testing.T) {
+	ip := net.ParseIP("fe80::1")
+	ips, err := network.GetUsableSubnetIPsFromIP(ip)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ips) != 1 || !ips[0].Equal(ip) {
+		t.Errorf("GetUsableSubnetIPsFromIP(%s) = %v, want the address itself", ip, ips)
+	}
+}
+
+func TestLinkLocalNeighbors_NotLinkLocal(t *testing.T) {
+	if _, err := network.LinkLocalNeighbors(context.Background(), net.ParseIP("192.168.1.10")); err == nil {
+		t.Error("expected an error for an IPv4 address")
+	}
+}
+
+// TestLinkLocalNeighbors_Link pings the all-nodes group on a real link, if
+// the machine has one, and checks what it gets back is usable.
+func TestLinkLocalNeighbors_Link(t *testing.T) {
+	var local net.IP
+	ips, _ := network.GetLocalIPAddresses()
+	for _, ip := range ips {
+		if network.IsLinkLocalIPv6(ip) {
+			local = ip
+			break
+		}
+	}
+	if local == nil {
+		t.Skip("no IPv6 link-local address")
+	}
+	neighbors, err := network.LinkLocalNeighbors(context.Background(), local)
+	if err != nil {
+		t.Skipf("cannot ping the link: %v", err)
+	}
+	for _, n := range neighbors {
+		if n.IP.Equal(local) {
+			t.Errorf("own address %s listed as a neighbor", local)
+		}
+		if !network.IsLinkLocalIPv6(n.IP) || n.Zone == "" {
+			t.Errorf("neighbor %s is not a zoned link-local address", n.String())
+		}
+	}
+}
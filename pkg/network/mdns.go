@@ -0,0 +1,174 @@
+package network
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// mdnsAddr is the multicast DNS group and port (RFC 6762).
+var mdnsAddr = &net.UDPAddr{IP: net.IPv4(224, 0, 0, 251), Port: 5353}
+
+// mdnsTimeout bounds how long a .local name is asked for before falling
+// back to the system resolver.
+const mdnsTimeout = 1500 * time.Millisecond
+
+// ResolveHost returns the addresses of host, IPv4 ones first. Names under
+// .local are asked for with multicast DNS, which the resolver built into
+// static builds does not do, and go to the system resolver if no device
+// answers; other names go to the system resolver alone.
+func ResolveHost(ctx context.Context, host string) ([]net.IP, error) {
+	if IsMDNSName(host) {
+		mctx, cancel := context.WithTimeout(ctx, mdnsTimeout)
+		ips, err := lookupMDNS(mctx, host, mdnsAddr)
+		cancel()
+		if err == nil {
+			return ips, nil
+		}
+	}
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	ips := make([]net.IP, 0, len(addrs))
+	for _, a := range addrs {
+		ips = append(ips, a.IP)
+	}
+	return ipv4First(ips), nil
+}
+
+// IsMDNSName reports whether host is a multicast DNS name, one ending in
+// .local.
+func IsMDNSName(host string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	return strings.HasSuffix(host, ".local") && len(host) > len(".local")
+}
+
+// lookupMDNS asks for the A and AAAA records of name with a one-shot
+// multicast DNS query sent to server, and returns the addresses in the first
+// answer that has any. Responders send one-shot answers straight back to
+// the query's source port.
+func lookupMDNS(ctx context.Context, name string, server *net.UDPAddr) ([]net.IP, error) {
+	fqdn := strings.TrimSuffix(name, ".") + "."
+	query, err := mdnsQuery(fqdn)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := net.ListenUDP("udp4", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	stop := context.AfterFunc(ctx, func() { conn.SetDeadline(time.Now()) })
+	defer stop()
+
+	if _, err := conn.WriteToUDP(query, server); err != nil {
+		return nil, err
+	}
+	buf := make([]byte, 9000)
+	for {
+		n, _, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			return nil, fmt.Errorf("no mDNS answer for %s: %w", name, err)
+		}
+		if ips := mdnsAnswer(buf[:n], fqdn); len(ips) > 0 {
+			return ipv4First(ips), nil
+		}
+	}
+}
+
+// mdnsQuery builds a query for the A and AAAA records of fqdn.
+func mdnsQuery(fqdn string) ([]byte, error) {
+	qname, err := dnsmessage.NewName(fqdn)
+	if err != nil {
+		return nil, fmt.Errorf("invalid host name %q: %w", fqdn, err)
+	}
+	b := dnsmessage.NewBuilder(nil, dnsmessage.Header{})
+	if err := b.StartQuestions(); err != nil {
+		return nil, err
+	}
+	for _, t := range []dnsmessage.Type{dnsmessage.TypeA, dnsmessage.TypeAAAA} {
+		if err := b.Question(dnsmessage.Question{Name: qname, Type: t, Class: dnsmessage.ClassINET}); err != nil {
+			return nil, err
+		}
+	}
+	return b.Finish()
+}
+
+// mdnsAnswer returns the addresses of fqdn in a response, from its answers
+// and the additional records responders put the other address family in.
+func mdnsAnswer(msg []byte, fqdn string) []net.IP {
+	var p dnsmessage.Parser
+	h, err := p.Start(msg)
+	if err != nil || !h.Response {
+		return nil
+	}
+	if err := p.SkipAllQuestions(); err != nil {
+		return nil
+	}
+	var ips []net.IP
+	section := func(next func() (dnsmessage.ResourceHeader, error), skip func() error) error {
+		for {
+			rh, err := next()
+			if errors.Is(err, dnsmessage.ErrSectionDone) {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if !strings.EqualFold(rh.Name.String(), fqdn) {
+				if err := skip(); err != nil {
+					return err
+				}
+				continue
+			}
+			switch rh.Type {
+			case dnsmessage.TypeA:
+				r, err := p.AResource()
+				if err != nil {
+					return err
+				}
+				ips = append(ips, net.IP(r.A[:]))
+			case dnsmessage.TypeAAAA:
+				r, err := p.AAAAResource()
+				if err != nil {
+					return err
+				}
+				ips = append(ips, net.IP(r.AAAA[:]))
+			default:
+				if err := skip(); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	if section(p.AnswerHeader, p.SkipAnswer) != nil || p.SkipAllAuthorities() != nil {
+		return ips
+	}
+	_ = section(p.AdditionalHeader, p.SkipAdditional)
+	return ips
+}
+
+// ipv4First orders IPv4 addresses before IPv6 ones, keeping their order.
+func ipv4First(ips []net.IP) []net.IP {
+	sorted := make([]net.IP, 0, len(ips))
+	for _, ip := range ips {
+		if ip.To4() != nil {
+			sorted = append(sorted, ip)
+		}
+	}
+	for _, ip := range ips {
+		if ip.To4() == nil {
+			sorted = append(sorted, ip)
+		}
+	}
+	return sorted
+}
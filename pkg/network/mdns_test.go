@@ -0,0 +1,92 @@
+package network
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// fakeResponder answers mDNS queries on a loopback port: first with a
+// response about another host, then with name's addresses.
+func fakeResponder(t *testing.T, name string, a [4]byte, aaaa [16]byte) *net.UDPAddr {
+	t.Helper()
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Skipf("udp unavailable: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	go func() {
+		buf := make([]byte, 1500)
+		n, from, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			return
+		}
+		var p dnsmessage.Parser
+		if _, err := p.Start(buf[:n]); err != nil {
+			return
+		}
+		questions, err := p.AllQuestions()
+		if err != nil || len(questions) != 2 {
+			return
+		}
+		for _, host := range []string{"other.local.", name} {
+			b := dnsmessage.NewBuilder(nil, dnsmessage.Header{Response: true, Authoritative: true})
+			b.StartAnswers()
+			hdr := dnsmessage.ResourceHeader{Name: dnsmessage.MustNewName(host), Class: dnsmessage.ClassINET, TTL: 120}
+			b.AResource(hdr, dnsmessage.AResource{A: a})
+			b.StartAdditionals()
+			b.AAAAResource(hdr, dnsmessage.AAAAResource{AAAA: aaaa})
+			msg, _ := b.Finish()
+			conn.WriteToUDP(msg, from)
+		}
+	}()
+	return conn.LocalAddr().(*net.UDPAddr)
+}
+
+func TestLookupMDNS(t *testing.T) {
+	a := [4]byte{192, 168, 1, 42}
+	aaaa := [16]byte{0xfe, 0x80, 15: 1}
+	server := fakeResponder(t, "mylaptop.local.", a, aaaa)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	ips, err := lookupMDNS(ctx, "MyLaptop.local", server)
+	if err != nil {
+		t.Fatalf("lookupMDNS failed: %v", err)
+	}
+	if len(ips) != 2 || ips[0].String() != "192.168.1.42" || ips[1].String() != "fe80::1" {
+		t.Errorf("lookupMDNS = %v, want [192.168.1.42 fe80::1]", ips)
+	}
+}
+
+func TestLookupMDNS_NoAnswer(t *testing.T) {
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Skipf("udp unavailable: %v", err)
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	if ips, err := lookupMDNS(ctx, "nobody.local", conn.LocalAddr().(*net.UDPAddr)); err == nil {
+		t.Errorf("lookupMDNS = %v, want an error", ips)
+	}
+}
+
+func TestIsMDNSName(t *testing.T) {
+	for host, want := range map[string]bool{
+		"mylaptop.local":  true,
+		"MyLaptop.LOCAL.": true,
+		".local":          false,
+		"local":           false,
+		"nas.lan":         false,
+		"192.168.1.5":     false,
+	} {
+		if got := IsMDNSName(host); got != want {
+			t.Errorf("IsMDNSName(%q) = %v, want %v", host, got, want)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package network
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"runtime"
+	"syscall"
+
+	"golang.org/x/net/ipv4"
+)
+
+// ListenMulticast opens one UDP socket on the port of group and joins group
+// on each of ifaces, returning the socket and the interfaces it joined on.
+//
+// The socket is opened so that it shares the port: several processes on one
+// host, such as `localgo serve` and `localgo discover`, or LocalGo and the
+// LocalSend app, can each listen for the group and each receive every
+// packet sent to it.
+func ListenMulticast(ctx context.Context, group *net.UDPAddr, ifaces []net.Interface) (net.PacketConn, []net.Interface, error) {
+	if group.IP.To4() == nil || !group.IP.IsMulticast() {
+		return nil, nil, fmt.Errorf("%s is not an IPv4 multicast address", group.IP)
+	}
+
+	// Bound to the group, the socket only receives packets sent to it.
+	// Windows cannot bind to a multicast address, so it takes the port on
+	// every address, as the standard library does.
+	bind := &net.UDPAddr{IP: group.IP, Port: group.Port}
+	if runtime.GOOS == "windows" {
+		bind.IP = net.IPv4zero
+	}
+	lc := net.ListenConfig{
+		Control: func(network, address string, c syscall.RawConn) error {
+			var err error
+			if cerr := c.Control(func(fd uintptr) { err = reusePort(fd) }); cerr != nil {
+				return cerr
+			}
+			return err
+		},
+	}
+	conn, err := lc.ListenPacket(ctx, "udp4", bind.String())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	p := ipv4.NewPacketConn(conn)
+	var joined []net.Interface
+	var errs []error
+	for _, iface := range ifaces {
+		if err := p.JoinGroup(&iface, &net.UDPAddr{IP: group.IP}); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", iface.Name, err))
+			continue
+		}
+		joined = append(joined, iface)
+	}
+	if len(joined) == 0 {
+		conn.Close()
+		return nil, nil, fmt.Errorf("failed to join %s on any interface: %w", group.IP, errors.Join(errs...))
+	}
+	// Other LocalGo processes on this host hear what this one sends.
+	_ = p.SetMulticastLoopback(true)
+	return conn, joined, nil
+}
@@ -0,0 +1,91 @@
+package network
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// Neighbors returns the IPv4 addresses in the operating system's neighbor
+// table (the ARP cache) that have a hardware address, which are hosts this
+// machine exchanged packets with recently. Scans probe them first, since
+// they are likely still up.
+func Neighbors() ([]net.IP, error) {
+	return neighbors()
+}
+
+// parseProcARP parses Linux's /proc/net/arp, keeping complete entries.
+func parseProcARP(r io.Reader) []net.IP {
+	var ips []net.IP
+	sc := bufio.NewScanner(r)
+	sc.Scan() // header
+	for sc.Scan() {
+		f := strings.Fields(sc.Text())
+		if len(f) < 4 {
+			continue
+		}
+		flags, err := strconv.ParseUint(f[2], 0, 32)
+		if err != nil || flags&0x2 == 0 { // ATF_COM: the hardware address is known
+			continue
+		}
+		if ip := neighborIP(f[0], f[3]); ip != nil {
+			ips = append(ips, ip)
+		}
+	}
+	return ips
+}
+
+// parseARPOutput parses the output of `arp -a`, in the BSD and macOS form
+// ("? (192.168.1.1) at 0:11:22:33:44:55 on en0 ...") or the Windows one
+// ("  192.168.1.1    00-11-22-33-44-55    dynamic").
+func parseARPOutput(out string) []net.IP {
+	var ips []net.IP
+	for _, line := range strings.Split(out, "\n") {
+		var addr string
+		for _, f := range strings.Fields(line) {
+			f = strings.Trim(f, "()")
+			if addr == "" {
+				if ip := net.ParseIP(f); ip != nil && ip.To4() != nil {
+					addr = f
+				}
+				continue
+			}
+			if ip := neighborIP(addr, f); ip != nil {
+				ips = append(ips, ip)
+				break
+			}
+		}
+	}
+	return ips
+}
+
+// neighborIP returns addr as an IP if it is a unicast IPv4 address and mac
+// a unicast hardware address, or nil.
+func neighborIP(addr, mac string) net.IP {
+	ip := net.ParseIP(addr).To4()
+	if ip == nil || ip.IsMulticast() || ip.Equal(net.IPv4bcast) || ip.IsUnspecified() {
+		return nil
+	}
+	parts := strings.FieldsFunc(mac, func(r rune) bool { return r == ':' || r == '-' })
+	if len(parts) != 6 {
+		return nil
+	}
+	var first uint64
+	zero := true
+	for i, p := range parts {
+		b, err := strconv.ParseUint(p, 16, 8)
+		if err != nil || len(p) > 2 {
+			return nil
+		}
+		if i == 0 {
+			first = b
+		}
+		zero = zero && b == 0
+	}
+	if zero || first&1 != 0 { // no address, or a multicast or broadcast one
+		return nil
+	}
+	return ip
+}
@@ -0,0 +1,16 @@
+package network
+
+import (
+	"net"
+	"os"
+)
+
+// neighbors reads the kernel's ARP table.
+func neighbors() ([]net.IP, error) {
+	f, err := os.Open("/proc/net/arp")
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return parseProcARP(f), nil
+}
@@ -0,0 +1,22 @@
+//go:build !linux
+
+package network
+
+import (
+	"context"
+	"net"
+	"os/exec"
+	"time"
+)
+
+// neighbors lists the ARP table with `arp -a`, which macOS, the BSDs and
+// Windows all have.
+func neighbors() ([]net.IP, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, "arp", "-a").Output()
+	if err != nil {
+		return nil, err
+	}
+	return parseARPOutput(string(out)), nil
+}
@@ -0,0 +1,54 @@
+package network
+
+import (
+	"net"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func ipStrings(ips []net.IP) []string {
+	var s []string
+	for _, ip := range ips {
+		s = append(s, ip.String())
+	}
+	return s
+}
+
+func TestParseProcARP(t *testing.T) {
+	table := `IP address       HW type     Flags       HW address            Mask     Device
+192.168.1.1      0x1         0x2         aa:bb:cc:dd:ee:01     *        eth0
+192.168.1.20     0x1         0x0         00:00:00:00:00:00     *        eth0
+192.168.1.30     0x1         0x6         aa:bb:cc:dd:ee:03     *        eth0
+10.0.0.7         0x1         0x2         02:fc:00:00:00:05     *        wg0
+`
+	got := ipStrings(parseProcARP(strings.NewReader(table)))
+	want := []string{"192.168.1.1", "192.168.1.30", "10.0.0.7"}
+	if !slices.Equal(got, want) {
+		t.Errorf("parseProcARP = %v, want %v", got, want)
+	}
+}
+
+func TestParseARPOutput(t *testing.T) {
+	macOS := `? (192.168.1.1) at 0:11:22:33:44:55 on en0 ifscope [ethernet]
+? (192.168.1.5) at (incomplete) on en0 ifscope [ethernet]
+nas.local (192.168.1.9) at a4:83:e7:1:2:3 on en0 ifscope [ethernet]
+? (224.0.0.251) at 1:0:5e:0:0:fb on en0 ifscope permanent [ethernet]
+? (192.168.1.255) at ff:ff:ff:ff:ff:ff on en0 ifscope [ethernet]
+`
+	if got, want := ipStrings(parseARPOutput(macOS)), []string{"192.168.1.1", "192.168.1.9"}; !slices.Equal(got, want) {
+		t.Errorf("parseARPOutput(macOS) = %v, want %v", got, want)
+	}
+
+	windows := `
+Interface: 192.168.1.10 --- 0x5
+  Internet Address      Physical Address      Type
+  192.168.1.1           00-11-22-33-44-55     dynamic
+  192.168.1.42          a4-83-e7-01-02-03     dynamic
+  192.168.1.255         ff-ff-ff-ff-ff-ff     static
+  224.0.0.22            01-00-5e-00-00-16     static
+`
+	if got, want := ipStrings(parseARPOutput(windows)), []string{"192.168.1.1", "192.168.1.42"}; !slices.Equal(got, want) {
+		t.Errorf("parseARPOutput(windows) = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,9 @@
+//go:build !(aix || darwin || dragonfly || freebsd || linux || netbsd || openbsd || windows)
+
+package network
+
+// reusePort does nothing where the port cannot be shared; a second process
+// then fails to listen.
+func reusePort(fd uintptr) error {
+	return nil
+}
@@ -0,0 +1,22 @@
+//go:build aix || darwin || dragonfly || freebsd || linux || netbsd || openbsd
+
+package network
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// reusePort lets other sockets bind the address fd is bound to. Both options
+// are set: Linux only shares a port between sockets that all set the same
+// one, and other programs may set either.
+func reusePort(fd uintptr) error {
+	if err := unix.SetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_REUSEADDR, 1); err != nil {
+		return os.NewSyscallError("setsockopt", err)
+	}
+	if err := unix.SetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_REUSEPORT, 1); err != nil {
+		return os.NewSyscallError("setsockopt", err)
+	}
+	return nil
+}
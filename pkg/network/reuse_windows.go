@@ -0,0 +1,11 @@
+package network
+
+import (
+	"os"
+	"syscall"
+)
+
+// reusePort lets other sockets bind the address fd is bound to.
+func reusePort(fd uintptr) error {
+	return os.NewSyscallError("setsockopt", syscall.SetsockoptInt(syscall.Handle(fd), syscall.SOL_SOCKET, syscall.SO_REUSEADDR, 1))
+}
@@ -0,0 +1,130 @@
+package network
+
+import (
+	"context"
+	"net"
+	"time"
+)
+
+// Change is how the local addresses changed, see WatchAddresses.
+type Change struct {
+	Added   []net.IP
+	Removed []net.IP
+	Current []net.IP // the addresses now, as GetLocalIPAddresses lists them
+}
+
+var (
+	// pollInterval is how often addresses are compared where the system
+	// does not report changes.
+	pollInterval = 5 * time.Second
+	// settleDelay lets a burst of changes, such as a new DHCP lease
+	// bringing up an address and its routes, settle before addresses are
+	// compared.
+	settleDelay = 2 * time.Second
+)
+
+// WatchAddresses sends a Change each time the local addresses change, such
+// as when switching Wi-Fi networks or docking, until ctx is done, when the
+// channel is closed. On Linux the kernel reports changes over netlink;
+// elsewhere, or if netlink is unavailable, the addresses are polled.
+func WatchAddresses(ctx context.Context) <-chan Change {
+	events, err := addressEvents(ctx)
+	if err != nil {
+		events = pollEvents(ctx, pollInterval)
+	}
+	return watch(ctx, events, GetLocalIPAddresses)
+}
+
+// watch compares the addresses listed by addrs after each event on events
+// and sends a Change when they differ.
+func watch(ctx context.Context, events <-chan struct{}, addrs func() ([]net.IP, error)) <-chan Change {
+	out := make(chan Change)
+	go func() {
+		defer close(out)
+		prev, _ := addrs()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case _, ok := <-events:
+				if !ok {
+					// The netlink socket failed; fall back to polling.
+					events = pollEvents(ctx, pollInterval)
+					continue
+				}
+			}
+
+			settle := time.NewTimer(settleDelay)
+		settling:
+			for {
+				select {
+				case <-ctx.Done():
+					settle.Stop()
+					return
+				case <-events:
+				case <-settle.C:
+					break settling
+				}
+			}
+
+			cur, err := addrs()
+			if err != nil {
+				continue
+			}
+			change := diffAddresses(prev, cur)
+			if len(change.Added) == 0 && len(change.Removed) == 0 {
+				continue
+			}
+			prev = cur
+			select {
+			case out <- change:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// pollEvents sends an event every interval until ctx is done.
+func pollEvents(ctx context.Context, interval time.Duration) <-chan struct{} {
+	events := make(chan struct{}, 1)
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				select {
+				case events <- struct{}{}:
+				default:
+				}
+			}
+		}
+	}()
+	return events
+}
+
+// diffAddresses returns the change from the addresses prev to cur.
+func diffAddresses(prev, cur []net.IP) Change {
+	had := make(map[string]bool, len(prev))
+	for _, ip := range prev {
+		had[ip.String()] = true
+	}
+	has := make(map[string]bool, len(cur))
+	change := Change{Current: cur}
+	for _, ip := range cur {
+		has[ip.String()] = true
+		if !had[ip.String()] {
+			change.Added = append(change.Added, ip)
+		}
+	}
+	for _, ip := range prev {
+		if !has[ip.String()] {
+			change.Removed = append(change.Removed, ip)
+		}
+	}
+	return change
+}
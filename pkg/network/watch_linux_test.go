@@ -0,0 +1,27 @@
+package network
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestAddressEvents_ClosesOnCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	events, err := addressEvents(ctx)
+	if err != nil {
+		t.Skipf("netlink unavailable: %v", err)
+	}
+	cancel()
+	deadline := time.After(time.Second)
+	for {
+		select {
+		case _, ok := <-events:
+			if !ok {
+				return
+			}
+		case <-deadline:
+			t.Fatal("events not closed after cancel")
+		}
+	}
+}
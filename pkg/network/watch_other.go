@@ -0,0 +1,14 @@
+//go:build !linux
+
+package network
+
+import (
+	"context"
+	"errors"
+)
+
+// addressEvents is only implemented on Linux; elsewhere addresses are
+// polled.
+func addressEvents(ctx context.Context) (<-chan struct{}, error) {
+	return nil, errors.New("address notifications are not supported on this platform")
+}
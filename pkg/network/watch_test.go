@@ -0,0 +1,76 @@
+package network
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestWatch_ReportsChanges(t *testing.T) {
+	defer func(d time.Duration) { settleDelay = d }(settleDelay)
+	settleDelay = 10 * time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	current := []net.IP{net.ParseIP("192.168.1.10")}
+	addrs := make(chan []net.IP, 1)
+	lookup := func() ([]net.IP, error) {
+		select {
+		case current = <-addrs:
+		default:
+		}
+		return current, nil
+	}
+	events := make(chan struct{}, 1)
+	changes := watch(ctx, events, lookup)
+
+	// An event without a change sends nothing.
+	events <- struct{}{}
+	select {
+	case c := <-changes:
+		t.Fatalf("unexpected change %+v", c)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	addrs <- []net.IP{net.ParseIP("10.0.0.5")}
+	events <- struct{}{}
+	select {
+	case c := <-changes:
+		if len(c.Added) != 1 || c.Added[0].String() != "10.0.0.5" {
+			t.Errorf("Added = %v, want [10.0.0.5]", c.Added)
+		}
+		if len(c.Removed) != 1 || c.Removed[0].String() != "192.168.1.10" {
+			t.Errorf("Removed = %v, want [192.168.1.10]", c.Removed)
+		}
+		if len(c.Current) != 1 || c.Current[0].String() != "10.0.0.5" {
+			t.Errorf("Current = %v, want [10.0.0.5]", c.Current)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no change reported")
+	}
+
+	cancel()
+	select {
+	case _, ok := <-changes:
+		if ok {
+			t.Error("expected the channel to close")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("channel not closed after cancel")
+	}
+}
+
+func TestDiffAddresses(t *testing.T) {
+	a, b, c := net.ParseIP("10.0.0.1"), net.ParseIP("10.0.0.2"), net.ParseIP("fe80::1")
+	change := diffAddresses([]net.IP{a, b}, []net.IP{b, c})
+	if len(change.Added) != 1 || !change.Added[0].Equal(c) {
+		t.Errorf("Added = %v, want [%s]", change.Added, c)
+	}
+	if len(change.Removed) != 1 || !change.Removed[0].Equal(a) {
+		t.Errorf("Removed = %v, want [%s]", change.Removed, a)
+	}
+	if change := diffAddresses([]net.IP{a}, []net.IP{net.ParseIP("10.0.0.1")}); len(change.Added)+len(change.Removed) != 0 {
+		t.Errorf("same addresses reported as changed: %+v", change)
+	}
+}
@@ -0,0 +1,179 @@
+// Package pairing turns the address of a device into a short code a person
+// can read out or type, for reaching a device multicast discovery cannot
+// find, such as one on another subnet.
+//
+// A direct code holds the device's IPv4 address, port and the start of its
+// certificate fingerprint, which the sender checks before sending. A relay
+// code is shorter: it is issued by a relay (see package relay), which
+// resolves it to the fingerprint of the device that asked for it.
+//
+// Codes use Crockford's base32, so they are case-insensitive, read the
+// letters I, L and O as the digits they resemble, and ignore dashes and
+// spaces.
+package pairing
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// alphabet is Crockford's base32 alphabet.
+const alphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// Code lengths, without separators.
+const (
+	DirectLength = 16 // 80 bits: IPv4 address, port and 32 bits of fingerprint
+	RelayLength  = 6  // 30 random bits
+)
+
+// FingerprintPrefixLength is how many hex digits of the fingerprint a
+// direct code holds.
+const FingerprintPrefixLength = 8
+
+// ErrInvalid is returned for a code that is neither a direct nor a relay
+// code, such as one with a typo.
+var ErrInvalid = errors.New("invalid pairing code")
+
+// Direct is what a direct code holds.
+type Direct struct {
+	IP          net.IP
+	Port        int
+	Fingerprint string // first FingerprintPrefixLength hex digits
+}
+
+// Encode returns the direct code of the device at ip:port with the
+// certificate fingerprint, grouped in fours like 1G2H-3J4K-5M6N-7P8Q.
+func Encode(ip net.IP, port int, fingerprint string) (string, error) {
+	ip4 := ip.To4()
+	if ip4 == nil {
+		return "", fmt.Errorf("pairing codes need an IPv4 address, not %s", ip)
+	}
+	if port < 1 || port > 65535 {
+		return "", fmt.Errorf("invalid port %d", port)
+	}
+	fp, err := hex.DecodeString(fingerprint[:min(len(fingerprint), FingerprintPrefixLength)])
+	if err != nil || len(fp) != FingerprintPrefixLength/2 {
+		return "", fmt.Errorf("invalid fingerprint %q", fingerprint)
+	}
+
+	var b [10]byte
+	copy(b[:4], ip4)
+	binary.BigEndian.PutUint16(b[4:6], uint16(port))
+	copy(b[6:], fp)
+	return group(encode(b[:])), nil
+}
+
+// Decode parses a direct code.
+func Decode(code string) (*Direct, error) {
+	s := Normalize(code)
+	if len(s) != DirectLength {
+		return nil, ErrInvalid
+	}
+	b, ok := decode(s)
+	if !ok {
+		return nil, ErrInvalid
+	}
+	d := &Direct{
+		IP:          net.IPv4(b[0], b[1], b[2], b[3]).To4(),
+		Port:        int(binary.BigEndian.Uint16(b[4:6])),
+		Fingerprint: hex.EncodeToString(b[6:]),
+	}
+	if d.Port == 0 || d.IP.IsUnspecified() {
+		return nil, ErrInvalid
+	}
+	return d, nil
+}
+
+// NewRelayCode returns a random relay code.
+func NewRelayCode() string {
+	var b [4]byte
+	rand.Read(b[:])
+	n := binary.BigEndian.Uint32(b[:])
+	code := make([]byte, RelayLength)
+	for i := range code {
+		code[i] = alphabet[n&31]
+		n >>= 5
+	}
+	return string(code)
+}
+
+// IsRelayCode reports whether code has the form of a relay code.
+func IsRelayCode(code string) bool {
+	s := Normalize(code)
+	if len(s) != RelayLength {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if strings.IndexByte(alphabet, s[i]) < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Normalize returns code in its canonical form: upper case, without
+// separators, with the letters read as digits replaced.
+func Normalize(code string) string {
+	var sb strings.Builder
+	for _, r := range strings.ToUpper(code) {
+		switch r {
+		case '-', ' ':
+			continue
+		case 'I', 'L':
+			r = '1'
+		case 'O':
+			r = '0'
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+// encode encodes b, whose length in bits is a multiple of 5.
+func encode(b []byte) string {
+	var sb strings.Builder
+	var acc uint64
+	bits := 0
+	for _, c := range b {
+		acc = acc<<8 | uint64(c)
+		bits += 8
+		for bits >= 5 {
+			bits -= 5
+			sb.WriteByte(alphabet[acc>>bits&31])
+		}
+	}
+	return sb.String()
+}
+
+func decode(s string) ([]byte, bool) {
+	out := make([]byte, 0, len(s)*5/8)
+	var acc uint64
+	bits := 0
+	for i := 0; i < len(s); i++ {
+		v := strings.IndexByte(alphabet, s[i])
+		if v < 0 {
+			return nil, false
+		}
+		acc = acc<<5 | uint64(v)
+		bits += 5
+		if bits >= 8 {
+			bits -= 8
+			out = append(out, byte(acc>>bits))
+		}
+	}
+	return out, true
+}
+
+func group(s string) string {
+	var parts []string
+	for len(s) > 4 {
+		parts = append(parts, s[:4])
+		s = s[4:]
+	}
+	return strings.Join(append(parts, s), "-")
+}
@@ -0,0 +1,51 @@
+package pairing
+
+import (
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestEncodeDecode(t *testing.T) {
+	fp := "3f2a9c01" + strings.Repeat("0", 56)
+	code, err := Encode(net.ParseIP("10.20.30.40"), 53317, fp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(code) != DirectLength+3 || strings.Count(code, "-") != 3 {
+		t.Errorf("Encode = %q, want four groups of four", code)
+	}
+
+	// Case, separators and look-alike letters do not matter.
+	typed := strings.ToLower(strings.ReplaceAll(code, "-", " "))
+	typed = strings.ReplaceAll(strings.ReplaceAll(typed, "1", "l"), "0", "o")
+	d, err := Decode(typed)
+	if err != nil {
+		t.Fatalf("Decode(%q) = %v", typed, err)
+	}
+	if !d.IP.Equal(net.ParseIP("10.20.30.40")) || d.Port != 53317 || d.Fingerprint != "3f2a9c01" {
+		t.Errorf("Decode = %+v", d)
+	}
+
+	if _, err := Encode(net.ParseIP("fe80::1"), 53317, fp); err == nil {
+		t.Error("Encode accepted an IPv6 address")
+	}
+	if _, err := Encode(net.ParseIP("10.0.0.1"), 53317, "xyz"); err == nil {
+		t.Error("Encode accepted an invalid fingerprint")
+	}
+	for _, bad := range []string{"", "ABCD-EFGH", code + "0", strings.Replace(code, code[:1], "U", 1)} {
+		if _, err := Decode(bad); err == nil {
+			t.Errorf("Decode(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestRelayCode(t *testing.T) {
+	code := NewRelayCode()
+	if !IsRelayCode(code) || Normalize(code) != code {
+		t.Errorf("NewRelayCode = %q, not a relay code", code)
+	}
+	if !IsRelayCode("7f3-k2a") || IsRelayCode("7F3K2") || IsRelayCode("7F3K2U") {
+		t.Error("IsRelayCode accepts or rejects the wrong codes")
+	}
+}
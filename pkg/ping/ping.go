@@ -0,0 +1,158 @@
+// Package ping measures how quickly a LocalSend device answers its info
+// endpoint over HTTP and HTTPS, and checks that it presents the TLS
+// certificate its fingerprint promises.
+package ping
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"strings"
+	"time"
+
+	"github.com/bethropolis/localgo/pkg/capture"
+	"github.com/bethropolis/localgo/pkg/model"
+	"github.com/bethropolis/localgo/pkg/network"
+)
+
+// Result is the outcome of pinging a device over one protocol. Times are in
+// milliseconds.
+type Result struct {
+	Protocol model.ProtocolType `json:"protocol"`
+	Sent     int                `json:"sent"`
+	Received int                `json:"received"`
+	Error    string             `json:"error,omitempty"` // last failure, if any
+
+	SetupMs float64 `json:"setupMs"` // connecting, including the TLS handshake
+	MinMs   float64 `json:"minMs"`
+	AvgMs   float64 `json:"avgMs"`
+	MaxMs   float64 `json:"maxMs"`
+
+	Info            *model.InfoDto `json:"info,omitempty"`
+	CertFingerprint string         `json:"certFingerprint,omitempty"` // SHA-256 of the TLS certificate
+}
+
+// Reachable reports whether the device answered at least once.
+func (r *Result) Reachable() bool {
+	return r.Received > 0
+}
+
+// Probe requests /api/localsend/v2/info count times from ip:port over
+// protocol, reusing one connection. The first request's connection setup is
+// reported separately so the round-trip times reflect the network alone.
+func Probe(ctx context.Context, ip string, port int, protocol model.ProtocolType, count int, timeout time.Duration) *Result {
+	r := &Result{Protocol: protocol}
+	tr := &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}
+	defer tr.CloseIdleConnections()
+	client := &http.Client{Timeout: timeout, Transport: capture.Transport(tr)}
+	url := fmt.Sprintf("%s://%s/api/localsend/v2/info", protocol, network.URLHost(ip, port))
+
+	var total time.Duration
+	for i := 0; i < count; i++ {
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+				return r
+			case <-time.After(200 * time.Millisecond):
+			}
+		}
+		r.Sent++
+		rtt, setup, err := r.request(ctx, client, url)
+		if err != nil {
+			r.Error = err.Error()
+			continue
+		}
+		if setup > 0 && r.SetupMs == 0 {
+			r.SetupMs = ms(setup)
+		}
+		r.Received++
+		total += rtt
+		if r.Received == 1 || ms(rtt) < r.MinMs {
+			r.MinMs = ms(rtt)
+		}
+		if ms(rtt) > r.MaxMs {
+			r.MaxMs = ms(rtt)
+		}
+	}
+	if r.Received > 0 {
+		r.AvgMs = ms(total / time.Duration(r.Received))
+		r.Error = ""
+	}
+	return r
+}
+
+// request performs one info request, returning the round trip from having
+// a connection to reading the response, and the time spent getting the
+// connection if a new one was made.
+func (r *Result) request(ctx context.Context, client *http.Client, url string) (rtt, setup time.Duration, err error) {
+	start := time.Now()
+	var gotConn time.Time
+	var reused bool
+	trace := &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			gotConn = time.Now()
+			reused = info.Reused
+		},
+	}
+	req, err := http.NewRequestWithContext(httptrace.WithClientTrace(ctx, trace), http.MethodGet, url, nil)
+	if err != nil {
+		return 0, 0, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if err != nil {
+		return 0, 0, err
+	}
+	rtt = time.Since(gotConn)
+	if resp.StatusCode != http.StatusOK {
+		return 0, 0, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	var info model.InfoDto
+	if err := json.Unmarshal(body, &info); err != nil {
+		return 0, 0, fmt.Errorf("not a LocalSend device: %w", err)
+	}
+	r.Info = &info
+	if resp.TLS != nil && len(resp.TLS.PeerCertificates) > 0 {
+		sum := sha256.Sum256(resp.TLS.PeerCertificates[0].Raw)
+		r.CertFingerprint = hex.EncodeToString(sum[:])
+	}
+	if !reused {
+		setup = gotConn.Sub(start)
+	}
+	return rtt, setup, nil
+}
+
+// VerifyFingerprint checks that the device is the one expected. Over HTTPS
+// the certificate must match the fingerprint the device reports; expected,
+// if set, must be a prefix of it (case-insensitive).
+func (r *Result) VerifyFingerprint(expected string) error {
+	if r.Info == nil {
+		return fmt.Errorf("no response to verify")
+	}
+	actual := r.Info.Fingerprint
+	if r.Protocol == model.ProtocolTypeHTTPS {
+		if !strings.EqualFold(r.CertFingerprint, r.Info.Fingerprint) {
+			return fmt.Errorf("TLS certificate %s does not match the reported fingerprint %s", r.CertFingerprint, r.Info.Fingerprint)
+		}
+		actual = r.CertFingerprint
+	}
+	if expected != "" && !strings.HasPrefix(strings.ToLower(actual), strings.ToLower(expected)) {
+		return fmt.Errorf("fingerprint mismatch: expected %s, got %s", expected, actual)
+	}
+	return nil
+}
+
+func ms(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
@@ -0,0 +1,91 @@
+package ping
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/bethropolis/localgo/pkg/model"
+)
+
+func infoServer(t *testing.T, tlsServer bool, fingerprint func(*httptest.Server) string) (*httptest.Server, string, int) {
+	t.Helper()
+	var srv *httptest.Server
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/localsend/v2/info" {
+			http.NotFound(w, r)
+			return
+		}
+		json.NewEncoder(w).Encode(model.InfoDto{Alias: "Phone", Version: "2.1", Fingerprint: fingerprint(srv)})
+	})
+	if tlsServer {
+		srv = httptest.NewTLSServer(handler)
+	} else {
+		srv = httptest.NewServer(handler)
+	}
+	t.Cleanup(srv.Close)
+	host, portStr, _ := net.SplitHostPort(srv.Listener.Addr().String())
+	port, _ := strconv.Atoi(portStr)
+	return srv, host, port
+}
+
+func certHash(srv *httptest.Server) string {
+	sum := sha256.Sum256(srv.Certificate().Raw)
+	return hex.EncodeToString(sum[:])
+}
+
+func TestProbeHTTPS(t *testing.T) {
+	srv, host, port := infoServer(t, true, certHash)
+	r := Probe(context.Background(), host, port, model.ProtocolTypeHTTPS, 3, 2*time.Second)
+
+	if r.Sent != 3 || r.Received != 3 || r.Error != "" {
+		t.Fatalf("sent %d, received %d, error %q; want 3 answers", r.Sent, r.Received, r.Error)
+	}
+	if r.Info == nil || r.Info.Alias != "Phone" {
+		t.Fatalf("info = %+v, want the device's info", r.Info)
+	}
+	if r.SetupMs <= 0 || r.MinMs <= 0 || r.MinMs > r.AvgMs || r.AvgMs > r.MaxMs {
+		t.Errorf("implausible times: setup %.3f, min/avg/max %.3f/%.3f/%.3f", r.SetupMs, r.MinMs, r.AvgMs, r.MaxMs)
+	}
+	if err := r.VerifyFingerprint(certHash(srv)[:8]); err != nil {
+		t.Errorf("VerifyFingerprint with a matching prefix: %v", err)
+	}
+	if err := r.VerifyFingerprint("ffff0000"); err == nil {
+		t.Error("expected a mismatch for the wrong fingerprint")
+	}
+}
+
+func TestProbeHTTPSCertificateMismatch(t *testing.T) {
+	_, host, port := infoServer(t, true, func(*httptest.Server) string { return "0123456789abcdef" })
+	r := Probe(context.Background(), host, port, model.ProtocolTypeHTTPS, 1, 2*time.Second)
+	if !r.Reachable() {
+		t.Fatalf("expected an answer, got error %q", r.Error)
+	}
+	if err := r.VerifyFingerprint(""); err == nil {
+		t.Error("expected the certificate not to match the reported fingerprint")
+	}
+}
+
+func TestProbeWrongProtocol(t *testing.T) {
+	_, host, port := infoServer(t, false, func(*httptest.Server) string { return "random-id" })
+
+	r := Probe(context.Background(), host, port, model.ProtocolTypeHTTP, 1, 2*time.Second)
+	if !r.Reachable() {
+		t.Fatalf("HTTP probe failed: %s", r.Error)
+	}
+	if err := r.VerifyFingerprint("random"); err != nil {
+		t.Errorf("HTTP devices are verified by their reported fingerprint: %v", err)
+	}
+
+	r = Probe(context.Background(), host, port, model.ProtocolTypeHTTPS, 2, 2*time.Second)
+	if r.Reachable() || r.Sent != 2 || r.Error == "" {
+		t.Errorf("HTTPS probe of an HTTP server: received %d of %d, error %q", r.Received, r.Sent, r.Error)
+	}
+}
@@ -0,0 +1,486 @@
+// Package queue runs sends in the background: jobs wait in priority order,
+// run one at a time or a few in parallel, and are retried when they fail.
+// A job can be held until a given time and repeated at an interval. Jobs can
+// be listed, removed, retried and reprioritized while the queue runs. The
+// queue lives in memory: jobs, scheduled ones included, end with it.
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Job statuses.
+const (
+	StatusQueued  = "queued"  // waiting, possibly for a retry
+	StatusSending = "sending" // being sent
+	StatusSent    = "sent"
+	StatusFailed  = "failed" // out of attempts
+)
+
+const (
+	// DefaultAttempts is how often a job is tried if it does not say.
+	DefaultAttempts = 3
+	// DefaultRetryDelay is the wait before the first retry; each further
+	// retry waits that much longer again.
+	DefaultRetryDelay = 30 * time.Second
+	// DefaultMinEvery is the shortest repeat interval Add accepts if the
+	// options do not say.
+	DefaultMinEvery = time.Minute
+	// maxFinished bounds how many sent and failed jobs are kept for listing.
+	maxFinished = 100
+)
+
+// ErrNotFound is returned for a job ID the queue does not have.
+var ErrNotFound = errors.New("no such job")
+
+// Job is a send waiting in, or done by, the queue. The recipient is given by
+// IP, or by alias and/or fingerprint prefix as with send --to.
+//
+// A job with At set waits until then; one with Every set is sent again each
+// interval after that and stays in the queue until removed.
+type Job struct {
+	ID             int       `json:"id"`
+	Files          []string  `json:"files"`                    // absolute paths
+	Excludes       []string  `json:"excludes,omitempty"`       // glob patterns skipped inside directories
+	SkipDuplicates bool      `json:"skipDuplicates,omitempty"` // skip files the recipient already received
+	To             string    `json:"to,omitempty"`
+	IP             string    `json:"ip,omitempty"`
+	Fingerprint    string    `json:"fingerprint,omitempty"`
+	Port           int       `json:"port,omitempty"`
+	Priority       int       `json:"priority"` // higher runs first
+	MaxAttempts    int       `json:"maxAttempts"`
+	Status         string    `json:"status"`
+	Attempts       int       `json:"attempts"`
+	Error          string    `json:"error,omitempty"` // why the last attempt failed
+	CreatedAt      time.Time `json:"createdAt"`
+	StartedAt      time.Time `json:"startedAt,omitzero"`   // of the last attempt
+	FinishedAt     time.Time `json:"finishedAt,omitzero"`  // when it was sent or gave up
+	NextAttempt    time.Time `json:"nextAttempt,omitzero"` // when a retry or scheduled run is due
+
+	At         time.Time     `json:"at,omitzero"`          // scheduled time of the next run
+	Every      time.Duration `json:"every,omitempty"`      // repeat interval, in nanoseconds
+	Runs       int           `json:"runs,omitempty"`       // finished runs of a repeating job
+	LastStatus string        `json:"lastStatus,omitempty"` // StatusSent or StatusFailed, of the last run
+
+	// Names are the names files matched by glob patterns are sent under,
+	// by path; other files keep their usual names.
+	Names map[string]string `json:"names,omitempty"`
+}
+
+// Recipient describes the job's recipient for messages.
+func (j *Job) Recipient() string {
+	switch {
+	case j.IP != "":
+		return j.IP
+	case j.To != "" && j.Fingerprint != "":
+		return fmt.Sprintf("%s (%s)", j.To, j.Fingerprint)
+	case j.To != "":
+		return j.To
+	default:
+		return "fingerprint " + j.Fingerprint
+	}
+}
+
+// schedule describes when a scheduled job runs, for log messages.
+func (j *Job) schedule() string {
+	var b strings.Builder
+	if j.At.After(j.CreatedAt) {
+		b.WriteString(" at " + j.At.Format("2006-01-02 15:04"))
+	}
+	if j.Every > 0 {
+		b.WriteString(" every " + j.Every.String())
+	}
+	return b.String()
+}
+
+// ParseAt parses a time to run a job: "15:04" is the next time the clock
+// shows that, today or tomorrow, and "2006-01-02 15:04" and RFC 3339 are
+// taken as given, in local time unless they say otherwise.
+func ParseAt(s string, now time.Time) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if t, err := time.ParseInLocation("15:04", s, now.Location()); err == nil {
+		at := time.Date(now.Year(), now.Month(), now.Day(), t.Hour(), t.Minute(), 0, 0, now.Location())
+		if !at.After(now) {
+			at = at.AddDate(0, 0, 1)
+		}
+		return at, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02 15:04", s, now.Location()); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q: use HH:MM, \"YYYY-MM-DD HH:MM\" or RFC 3339", s)
+}
+
+// SendFunc sends a job's files. It should give up when ctx is done.
+type SendFunc func(ctx context.Context, job Job) error
+
+// Options configures a Queue.
+type Options struct {
+	Workers    int           // jobs sent at the same time; at least 1
+	RetryDelay time.Duration // DefaultRetryDelay if 0
+	MinEvery   time.Duration // shortest repeat interval; DefaultMinEvery if 0
+}
+
+// Queue holds the jobs and runs them with SendFunc.
+type Queue struct {
+	opts   Options
+	send   SendFunc
+	logger *zap.SugaredLogger
+
+	mu      sync.Mutex
+	jobs    []*Job // in the order they were added
+	cancels map[int]context.CancelFunc
+	nextID  int
+	changed chan struct{} // closed and replaced whenever the jobs change
+}
+
+// New returns an empty queue. Jobs are only sent once Run is called.
+func New(opts Options, send SendFunc, logger *zap.SugaredLogger) *Queue {
+	if opts.Workers < 1 {
+		opts.Workers = 1
+	}
+	if opts.RetryDelay <= 0 {
+		opts.RetryDelay = DefaultRetryDelay
+	}
+	if opts.MinEvery <= 0 {
+		opts.MinEvery = DefaultMinEvery
+	}
+	if logger == nil {
+		logger = zap.NewNop().Sugar()
+	}
+	return &Queue{
+		opts:    opts,
+		send:    send,
+		logger:  logger,
+		cancels: make(map[int]context.CancelFunc),
+		nextID:  1,
+		changed: make(chan struct{}),
+	}
+}
+
+// notify wakes the workers. The caller holds q.mu.
+func (q *Queue) notify() {
+	close(q.changed)
+	q.changed = make(chan struct{})
+}
+
+// Add queues a job and returns it with its ID and status filled in.
+func (q *Queue) Add(job Job) (Job, error) {
+	if len(job.Files) == 0 {
+		return Job{}, errors.New("no files to send")
+	}
+	for _, f := range job.Files {
+		if !filepath.IsAbs(f) {
+			return Job{}, fmt.Errorf("file path is not absolute: %s", f)
+		}
+	}
+	if job.IP == "" && job.To == "" && job.Fingerprint == "" {
+		return Job{}, errors.New("no recipient given")
+	}
+	if job.MaxAttempts <= 0 {
+		job.MaxAttempts = DefaultAttempts
+	}
+	if job.Every < 0 {
+		return Job{}, errors.New("repeat interval must be positive")
+	}
+	if job.Every > 0 && job.Every < q.opts.MinEvery {
+		return Job{}, fmt.Errorf("repeat interval must be at least %s", q.opts.MinEvery)
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	job.ID = q.nextID
+	q.nextID++
+	job.Status = StatusQueued
+	job.Attempts = 0
+	job.Error = ""
+	job.CreatedAt = time.Now()
+	job.StartedAt, job.FinishedAt, job.NextAttempt = time.Time{}, time.Time{}, time.Time{}
+	job.Runs, job.LastStatus = 0, ""
+	if job.Every > 0 && job.At.IsZero() {
+		job.At = job.CreatedAt
+	}
+	if job.At.After(job.CreatedAt) {
+		job.NextAttempt = job.At
+	}
+	q.jobs = append(q.jobs, &job)
+	q.notify()
+	q.logger.Infof("Queued job %d: %d file(s) to %s%s", job.ID, len(job.Files), job.Recipient(), job.schedule())
+	return job, nil
+}
+
+// List returns copies of the jobs in the order they will be handled: those
+// being sent, then those waiting by priority, then finished ones.
+func (q *Queue) List() []Job {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	list := make([]Job, 0, len(q.jobs))
+	for _, j := range q.jobs {
+		list = append(list, *j)
+	}
+	rank := map[string]int{StatusSending: 0, StatusQueued: 1, StatusSent: 2, StatusFailed: 2}
+	slices.SortStableFunc(list, func(a, b Job) int {
+		if rank[a.Status] != rank[b.Status] {
+			return rank[a.Status] - rank[b.Status]
+		}
+		if a.Status == StatusQueued && a.Priority != b.Priority {
+			return b.Priority - a.Priority
+		}
+		return a.ID - b.ID
+	})
+	return list
+}
+
+// find returns the job with id. The caller holds q.mu.
+func (q *Queue) find(id int) (int, *Job) {
+	for i, j := range q.jobs {
+		if j.ID == id {
+			return i, j
+		}
+	}
+	return -1, nil
+}
+
+// Remove drops a job, stopping it if it is being sent.
+func (q *Queue) Remove(id int) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	i, job := q.find(id)
+	if job == nil {
+		return fmt.Errorf("job %d: %w", id, ErrNotFound)
+	}
+	if cancel := q.cancels[id]; cancel != nil {
+		cancel()
+		delete(q.cancels, id)
+	}
+	q.jobs = slices.Delete(q.jobs, i, i+1)
+	q.notify()
+	q.logger.Infof("Removed job %d", id)
+	return nil
+}
+
+// Retry queues a failed job again with a fresh set of attempts, or runs a
+// job waiting for a retry or its scheduled time right away. A repeating job
+// keeps its schedule.
+func (q *Queue) Retry(id int) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	_, job := q.find(id)
+	if job == nil {
+		return fmt.Errorf("job %d: %w", id, ErrNotFound)
+	}
+	switch job.Status {
+	case StatusFailed:
+		job.Attempts = 0
+		job.FinishedAt = time.Time{}
+	case StatusQueued:
+	default:
+		return fmt.Errorf("job %d is %s", id, job.Status)
+	}
+	job.Status = StatusQueued
+	job.NextAttempt = time.Time{}
+	q.notify()
+	q.logger.Infof("Retrying job %d", id)
+	return nil
+}
+
+// SetPriority changes the priority of a waiting job.
+func (q *Queue) SetPriority(id, priority int) (Job, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	_, job := q.find(id)
+	if job == nil {
+		return Job{}, fmt.Errorf("job %d: %w", id, ErrNotFound)
+	}
+	if job.Status != StatusQueued {
+		return Job{}, fmt.Errorf("job %d is %s", id, job.Status)
+	}
+	job.Priority = priority
+	q.notify()
+	return *job, nil
+}
+
+// Pending returns how many jobs are waiting or being sent.
+func (q *Queue) Pending() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	n := 0
+	for _, j := range q.jobs {
+		if j.Status == StatusQueued || j.Status == StatusSending {
+			n++
+		}
+	}
+	return n
+}
+
+// Run sends jobs with the configured number of workers until ctx is done.
+// Jobs being sent are stopped then.
+func (q *Queue) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for range q.opts.Workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			q.work(ctx)
+		}()
+	}
+	wg.Wait()
+}
+
+func (q *Queue) work(ctx context.Context) {
+	for {
+		job, jobCtx, ok := q.next(ctx)
+		if !ok {
+			return
+		}
+		q.logger.Infof("Sending job %d (attempt %d of %d): %d file(s) to %s", job.ID, job.Attempts, job.MaxAttempts, len(job.Files), job.Recipient())
+		err := q.send(jobCtx, job)
+		q.finish(job.ID, jobCtx, err)
+	}
+}
+
+// next waits for a job that is due and marks it as being sent. It returns
+// false once ctx is done.
+func (q *Queue) next(ctx context.Context) (Job, context.Context, bool) {
+	for {
+		q.mu.Lock()
+		now := time.Now()
+		var due *Job
+		var wake time.Time // earliest retry still to come
+		for _, j := range q.jobs {
+			if j.Status != StatusQueued {
+				continue
+			}
+			if j.NextAttempt.After(now) {
+				if wake.IsZero() || j.NextAttempt.Before(wake) {
+					wake = j.NextAttempt
+				}
+				continue
+			}
+			if due == nil || j.Priority > due.Priority {
+				due = j
+			}
+		}
+		if due != nil {
+			due.Status = StatusSending
+			due.Attempts++
+			due.StartedAt = now
+			jobCtx, cancel := context.WithCancel(ctx)
+			q.cancels[due.ID] = cancel
+			job := *due
+			q.mu.Unlock()
+			return job, jobCtx, true
+		}
+		changed := q.changed
+		q.mu.Unlock()
+
+		var timer *time.Timer
+		var retry <-chan time.Time
+		if !wake.IsZero() {
+			timer = time.NewTimer(time.Until(wake))
+			retry = timer.C
+		}
+		select {
+		case <-ctx.Done():
+		case <-changed:
+		case <-retry:
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+		if ctx.Err() != nil {
+			return Job{}, nil, false
+		}
+	}
+}
+
+// finish records the outcome of an attempt at job id.
+func (q *Queue) finish(id int, jobCtx context.Context, err error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	stopped := jobCtx.Err() != nil
+	if cancel := q.cancels[id]; cancel != nil {
+		cancel()
+		delete(q.cancels, id)
+	}
+	_, job := q.find(id)
+	if job == nil {
+		return // removed while being sent
+	}
+	if err != nil && stopped {
+		// The queue is stopping; leave the job for a later run.
+		job.Status = StatusQueued
+		job.Attempts--
+		return
+	}
+
+	now := time.Now()
+	switch {
+	case err == nil:
+		job.Status = StatusSent
+		job.Error = ""
+		job.FinishedAt = now
+		q.logger.Infof("Job %d sent to %s", id, job.Recipient())
+	case job.Attempts < job.MaxAttempts:
+		job.Status = StatusQueued
+		job.Error = err.Error()
+		job.NextAttempt = now.Add(q.opts.RetryDelay * time.Duration(job.Attempts))
+		q.logger.Warnf("Job %d failed, retrying at %s: %v", id, job.NextAttempt.Format("15:04:05"), err)
+	default:
+		job.Status = StatusFailed
+		job.Error = err.Error()
+		job.FinishedAt = now
+		q.logger.Errorf("Job %d failed after %d attempt(s): %v", id, job.Attempts, err)
+	}
+	if job.Every > 0 && job.Status != StatusQueued {
+		q.reschedule(job, now)
+	}
+	q.pruneFinished()
+	q.notify()
+}
+
+// reschedule queues a repeating job that has finished a run for its next
+// run, skipping runs whose time has passed. The caller holds q.mu.
+func (q *Queue) reschedule(job *Job, now time.Time) {
+	job.Runs++
+	job.LastStatus = job.Status
+	if !job.At.After(now) {
+		missed := now.Sub(job.At) / job.Every
+		job.At = job.At.Add((missed + 1) * job.Every)
+	}
+	job.Status = StatusQueued
+	job.Attempts = 0
+	job.NextAttempt = job.At
+	q.logger.Infof("Job %d runs again at %s", job.ID, job.At.Format("2006-01-02 15:04"))
+}
+
+// pruneFinished drops the oldest finished jobs beyond maxFinished. The
+// caller holds q.mu.
+func (q *Queue) pruneFinished() {
+	finished := 0
+	for _, j := range q.jobs {
+		if j.Status == StatusSent || j.Status == StatusFailed {
+			finished++
+		}
+	}
+	for i := 0; finished > maxFinished && i < len(q.jobs); {
+		if s := q.jobs[i].Status; s == StatusSent || s == StatusFailed {
+			q.jobs = slices.Delete(q.jobs, i, i+1)
+			finished--
+			continue
+		}
+		i++
+	}
+}
@@ -0,0 +1,219 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// waitFor polls until cond holds or fails the test after a second.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func status(q *Queue, id int) string {
+	for _, j := range q.List() {
+		if j.ID == id {
+			return j.Status
+		}
+	}
+	return ""
+}
+
+func TestQueue_PriorityOrder(t *testing.T) {
+	var mu sync.Mutex
+	var order []int
+	q := New(Options{}, func(ctx context.Context, job Job) error {
+		mu.Lock()
+		order = append(order, job.ID)
+		mu.Unlock()
+		return nil
+	}, nil)
+
+	low, _ := q.Add(Job{Files: []string{"/a"}, To: "Phone"})
+	high, _ := q.Add(Job{Files: []string{"/b"}, To: "Phone", Priority: 5})
+	mid, _ := q.Add(Job{Files: []string{"/c"}, To: "Phone"})
+	if _, err := q.SetPriority(mid.ID, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go q.Run(ctx)
+	waitFor(t, "all jobs", func() bool { return status(q, low.ID) == StatusSent })
+
+	mu.Lock()
+	defer mu.Unlock()
+	want := []int{high.ID, mid.ID, low.ID}
+	for i := range want {
+		if i >= len(order) || order[i] != want[i] {
+			t.Fatalf("sent in order %v, want %v", order, want)
+		}
+	}
+}
+
+func TestQueue_RetriesThenFails(t *testing.T) {
+	var mu sync.Mutex
+	calls := 0
+	q := New(Options{RetryDelay: time.Millisecond}, func(ctx context.Context, job Job) error {
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+		return errors.New("unreachable")
+	}, nil)
+
+	job, _ := q.Add(Job{Files: []string{"/a"}, IP: "192.168.1.5", MaxAttempts: 2})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go q.Run(ctx)
+	waitFor(t, "the job to fail", func() bool { return status(q, job.ID) == StatusFailed })
+
+	j := q.List()[0]
+	if j.Attempts != 2 || j.Error != "unreachable" {
+		t.Errorf("attempts = %d, error = %q; want 2 attempts with the send error", j.Attempts, j.Error)
+	}
+
+	if err := q.Retry(job.ID); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "the retried job to fail", func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return calls == 4 && status(q, job.ID) == StatusFailed
+	})
+}
+
+func TestQueue_RemoveStopsSend(t *testing.T) {
+	started := make(chan struct{})
+	stopped := make(chan struct{})
+	q := New(Options{}, func(ctx context.Context, job Job) error {
+		close(started)
+		<-ctx.Done()
+		close(stopped)
+		return ctx.Err()
+	}, nil)
+
+	job, _ := q.Add(Job{Files: []string{"/a"}, To: "Phone"})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go q.Run(ctx)
+	<-started
+
+	if err := q.Remove(job.ID); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("removing the job did not stop its send")
+	}
+	if n := len(q.List()); n != 0 {
+		t.Errorf("%d job(s) left after removing the only one", n)
+	}
+	if err := q.Remove(job.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("removing again: got %v, want ErrNotFound", err)
+	}
+}
+
+func TestQueue_AddValidates(t *testing.T) {
+	q := New(Options{}, nil, nil)
+	if _, err := q.Add(Job{To: "Phone"}); err == nil {
+		t.Error("a job without files was accepted")
+	}
+	if _, err := q.Add(Job{Files: []string{"/a"}}); err == nil {
+		t.Error("a job without a recipient was accepted")
+	}
+	if _, err := q.Add(Job{Files: []string{"a"}, To: "Phone"}); err == nil {
+		t.Error("a job with a relative path was accepted")
+	}
+	if _, err := q.Add(Job{Files: []string{"/a"}, To: "Phone", Every: time.Nanosecond}); err == nil {
+		t.Error("a job repeating more often than DefaultMinEvery was accepted")
+	}
+	job, err := q.Add(Job{Files: []string{"/a"}, To: "Phone"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.Status != StatusQueued || job.MaxAttempts != DefaultAttempts {
+		t.Errorf("status %s with %d attempts, want %s with %d", job.Status, job.MaxAttempts, StatusQueued, DefaultAttempts)
+	}
+}
+
+func TestQueue_Repeats(t *testing.T) {
+	var mu sync.Mutex
+	calls := 0
+	q := New(Options{MinEvery: time.Millisecond}, func(ctx context.Context, job Job) error {
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+		if calls == 2 {
+			return errors.New("unreachable")
+		}
+		return nil
+	}, nil)
+
+	at := time.Now().Add(50 * time.Millisecond)
+	job, err := q.Add(Job{Files: []string{"/a"}, To: "NAS", At: at, Every: 50 * time.Millisecond, MaxAttempts: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !job.NextAttempt.Equal(at) {
+		t.Fatalf("next attempt %v, want the scheduled time %v", job.NextAttempt, at)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go q.Run(ctx)
+
+	time.Sleep(20 * time.Millisecond)
+	mu.Lock()
+	early := calls
+	mu.Unlock()
+	if early != 0 {
+		t.Fatal("the job was sent before its scheduled time")
+	}
+
+	waitFor(t, "three runs", func() bool {
+		q.mu.Lock()
+		defer q.mu.Unlock()
+		return q.jobs[0].Runs >= 3
+	})
+	j := q.List()[0]
+	if j.Status != StatusQueued || !j.At.After(at) || !j.NextAttempt.Equal(j.At) {
+		t.Errorf("after three runs: status %s, at %v, next attempt %v; want it queued for a later run", j.Status, j.At, j.NextAttempt)
+	}
+}
+
+func TestParseAt(t *testing.T) {
+	now := time.Date(2026, 3, 14, 15, 30, 0, 0, time.Local)
+	tests := []struct {
+		in   string
+		want time.Time
+	}{
+		{"16:00", time.Date(2026, 3, 14, 16, 0, 0, 0, time.Local)},
+		{"02:00", time.Date(2026, 3, 15, 2, 0, 0, 0, time.Local)},
+		{"15:30", time.Date(2026, 3, 15, 15, 30, 0, 0, time.Local)},
+		{"2026-04-01 08:15", time.Date(2026, 4, 1, 8, 15, 0, 0, time.Local)},
+		{"2026-04-01T08:15:00Z", time.Date(2026, 4, 1, 8, 15, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		got, err := ParseAt(tt.in, now)
+		if err != nil {
+			t.Errorf("ParseAt(%q): %v", tt.in, err)
+			continue
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("ParseAt(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+	if _, err := ParseAt("tonight", now); err == nil {
+		t.Error("ParseAt accepted \"tonight\"")
+	}
+}
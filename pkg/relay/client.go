@@ -0,0 +1,280 @@
+package relay
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/bethropolis/localgo/pkg/pairing"
+	"go.uber.org/zap"
+	"golang.org/x/net/http2"
+)
+
+// Reconnection delays after a tunnel breaks or cannot be opened.
+const (
+	minRetryDelay = time.Second
+	maxRetryDelay = time.Minute
+)
+
+// ErrNotRegistered is returned by Client.Resolve when no registered device
+// matches.
+var ErrNotRegistered = errors.New("no device with that fingerprint is registered with the relay")
+
+// Client talks to a relay on behalf of this device.
+type Client struct {
+	url         *url.URL
+	fingerprint string // of the relay's certificate; "" accepts any
+	cert        tls.Certificate
+	base        *http.Transport
+	logger      *zap.SugaredLogger
+}
+
+// NewClient returns a client of the relay at relayURL (see
+// config.ParseRelay) that identifies itself with cert. If fingerprint is
+// set, only a relay with that certificate is trusted. Requests go through
+// base, usually httputil.Transport(), for its proxy and source address.
+func NewClient(relayURL *url.URL, fingerprint string, cert tls.Certificate, base *http.Transport, logger *zap.SugaredLogger) *Client {
+	if logger == nil {
+		logger = zap.NewNop().Sugar()
+	}
+	if base == nil {
+		base = http.DefaultTransport.(*http.Transport)
+	}
+	return &Client{url: relayURL, fingerprint: fingerprint, cert: cert, base: base, logger: logger}
+}
+
+// Host returns the host name of the relay.
+func (c *Client) Host() string {
+	return c.url.Hostname()
+}
+
+// tlsConfig returns the client side of the TLS handshake with the relay.
+func (c *Client) tlsConfig() *tls.Config {
+	cfg := &tls.Config{
+		Certificates:       []tls.Certificate{c.cert},
+		InsecureSkipVerify: true, // self-signed like any LocalGo certificate; see fingerprint
+		MinVersion:         tls.VersionTLS12,
+		NextProtos:         []string{"http/1.1"},
+	}
+	if c.fingerprint != "" {
+		cfg.VerifyConnection = verifyFingerprint(c.fingerprint)
+	}
+	return cfg
+}
+
+// transport returns a transport to the relay that presents the device
+// certificate.
+func (c *Client) transport() *http.Transport {
+	tr := c.base.Clone()
+	tr.TLSClientConfig = c.tlsConfig()
+	tr.ForceAttemptHTTP2 = false
+	return tr
+}
+
+// Peers lists the devices registered with the relay.
+func (c *Client) Peers(ctx context.Context) ([]Peer, error) {
+	var peers []Peer
+	if err := c.call(ctx, http.MethodGet, PeersPath, http.StatusOK, &peers); err != nil {
+		return nil, err
+	}
+	return peers, nil
+}
+
+// Resolve returns the full fingerprint of the registered device whose
+// fingerprint starts with prefix.
+func (c *Client) Resolve(ctx context.Context, prefix string) (string, error) {
+	peers, err := c.Peers(ctx)
+	if err != nil {
+		return "", err
+	}
+	prefix = strings.ToLower(prefix)
+	var matches []string
+	for _, p := range peers {
+		if strings.HasPrefix(p.Fingerprint, prefix) {
+			matches = append(matches, p.Fingerprint)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return "", ErrNotRegistered
+	case 1:
+		return matches[0], nil
+	default:
+		return "", fmt.Errorf("%d devices registered with the relay match fingerprint %s: use a longer prefix", len(matches), prefix)
+	}
+}
+
+// NewCode asks the relay for a pairing code that resolves to this device.
+func (c *Client) NewCode(ctx context.Context) (*Code, error) {
+	var code Code
+	if err := c.call(ctx, http.MethodPost, CodesPath, http.StatusCreated, &code); err != nil {
+		return nil, err
+	}
+	return &code, nil
+}
+
+// LookupCode returns the fingerprint of the device a pairing code was
+// issued to.
+func (c *Client) LookupCode(ctx context.Context, code string) (string, error) {
+	var resolved Code
+	if err := c.call(ctx, http.MethodGet, CodesPath+"/"+pairing.Normalize(code), http.StatusOK, &resolved); err != nil {
+		return "", err
+	}
+	return resolved.Fingerprint, nil
+}
+
+// call sends a request to the relay API and decodes its answer into v.
+func (c *Client) call(ctx context.Context, method, path string, status int, v any) error {
+	u := *c.url
+	u.Path = path
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := (&http.Client{Transport: c.transport()}).Do(req)
+	if err != nil {
+		return fmt.Errorf("relay: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != status {
+		return fmt.Errorf("relay: %s", responseError(resp))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("relay: invalid answer: %w", err)
+	}
+	return nil
+}
+
+// Transport returns a transport that sends every request to the device with
+// fingerprint through the relay, whatever host its URL names.
+func (c *Client) Transport(fingerprint string) http.RoundTripper {
+	prefix := *c.url
+	prefix.Path = PeersPath + "/" + strings.ToLower(fingerprint)
+	return &forwardTransport{base: c.transport(), prefix: &prefix}
+}
+
+type forwardTransport struct {
+	base   http.RoundTripper
+	prefix *url.URL
+}
+
+func (t *forwardTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	out := req.Clone(req.Context())
+	u := *t.prefix
+	u.Path += req.URL.Path
+	u.RawPath = ""
+	u.RawQuery = req.URL.RawQuery
+	out.URL = &u
+	out.Host = ""
+	return t.base.RoundTrip(out)
+}
+
+// Serve registers with the relay and serves h to requests the relay
+// forwards, until ctx ends. A broken or refused registration is retried
+// with growing delays.
+func (c *Client) Serve(ctx context.Context, h http.Handler) error {
+	delay := minRetryDelay
+	for {
+		registered, err := c.serveOnce(ctx, h)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if registered {
+			delay = minRetryDelay
+			c.logger.Warnf("Lost the relay %s, reconnecting", c.url.Host)
+		} else {
+			c.logger.Warnf("Failed to register with the relay %s, retrying in %s: %v", c.url.Host, delay, err)
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(delay):
+		}
+		if !registered {
+			delay = min(delay*2, maxRetryDelay)
+		}
+	}
+}
+
+// serveOnce registers and serves h until the tunnel breaks. It reports
+// whether the registration succeeded.
+func (c *Client) serveOnce(ctx context.Context, h http.Handler) (bool, error) {
+	conn, err := c.register(ctx)
+	if err != nil {
+		return false, err
+	}
+	c.logger.Infof("Registered with the relay %s", c.url.Host)
+
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+	(&http2.Server{}).ServeConn(conn, &http2.ServeConnOpts{
+		Context: ctx,
+		Handler: h,
+		BaseConfig: &http.Server{
+			ReadHeaderTimeout: 30 * time.Second,
+		},
+	})
+	conn.Close()
+	return true, nil
+}
+
+// register opens the tunnel: a TLS connection to the relay, upgraded by a
+// registration request.
+func (c *Client) register(ctx context.Context) (net.Conn, error) {
+	// The shared transport's dialer picks the source address.
+	dial := c.base.DialContext
+	if dial == nil {
+		dial = (&net.Dialer{Timeout: 10 * time.Second, KeepAlive: 30 * time.Second}).DialContext
+	}
+	plain, err := dial(ctx, "tcp", c.url.Host)
+	if err != nil {
+		return nil, err
+	}
+	raw := tls.Client(plain, c.tlsConfig())
+	if err := raw.HandshakeContext(ctx); err != nil {
+		plain.Close()
+		return nil, err
+	}
+
+	req, _ := http.NewRequest(http.MethodPost, c.url.JoinPath(RegisterPath).String(), nil)
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", upgradeProtocol)
+	raw.SetDeadline(time.Now().Add(15 * time.Second))
+	if err := req.Write(raw); err != nil {
+		raw.Close()
+		return nil, err
+	}
+	br := bufio.NewReader(raw)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		raw.Close()
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		msg := responseError(resp)
+		resp.Body.Close()
+		raw.Close()
+		return nil, errors.New(msg)
+	}
+	raw.SetDeadline(time.Time{})
+	return newTunnelConn(raw, br), nil
+}
+
+// responseError describes a failed response from the relay.
+func responseError(resp *http.Response) string {
+	var body struct {
+		Error string `json:"error"`
+	}
+	if json.NewDecoder(resp.Body).Decode(&body) == nil && body.Error != "" {
+		return fmt.Sprintf("%s (%d)", body.Error, resp.StatusCode)
+	}
+	return resp.Status
+}
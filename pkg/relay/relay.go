@@ -0,0 +1,125 @@
+// Package relay brokers LocalSend transfers between devices that are not on
+// the same network. A device registers with a relay server over a TLS
+// connection that the relay then sends requests back through, so it needs
+// no open port; senders reach it by fingerprint through the relay.
+//
+// Both sides present their LocalGo certificate as a TLS client certificate,
+// and the relay identifies them by its fingerprint, the same SHA-256 hash
+// LocalSend uses for device identity.
+package relay
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Paths of the relay API.
+const (
+	PeersPath    = "/relay/v1/peers"    // GET: registered devices; PeersPath/FP/...: forwarded to FP
+	RegisterPath = "/relay/v1/register" // POST with Upgrade: the connection becomes the device's tunnel
+	CodesPath    = "/relay/v1/codes"    // POST: a pairing code for the caller; CodesPath/CODE: who it is for
+)
+
+// CallerHeader carries the fingerprint of the device that sent a request
+// the relay forwards, as checked from its client certificate. The relay
+// replaces any value the sender set itself; devices believe it only on
+// requests that came through their tunnel.
+const CallerHeader = "X-Localgo-Relay-Caller"
+
+// CallerAddr is the remote address a device gives a request forwarded from
+// the device with fingerprint fp. Every request through the tunnel comes
+// from the relay's address, so code that tells senders apart by address
+// uses this instead.
+func CallerAddr(fp string) string {
+	return net.JoinHostPort("relay-"+fp, "0")
+}
+
+// CodeTTL is how long a pairing code issued by the relay stays valid.
+const CodeTTL = 10 * time.Minute
+
+// upgradeProtocol is named in the Upgrade header of a registration. Once
+// the relay answers 101, it speaks HTTP/2 over the connection as the client.
+const upgradeProtocol = "localgo-relay"
+
+// pingInterval is how often the relay checks a quiet tunnel is still up.
+const pingInterval = 30 * time.Second
+
+// Peer is a device registered with a relay.
+type Peer struct {
+	Fingerprint string    `json:"fingerprint"`
+	Since       time.Time `json:"since"`
+}
+
+// Code is a pairing code issued by a relay, which resolves to the
+// fingerprint of the device it was issued to until it expires.
+type Code struct {
+	Code        string    `json:"code"`
+	Fingerprint string    `json:"fingerprint"`
+	Expires     time.Time `json:"expires"`
+}
+
+// Fingerprint returns the fingerprint of the certificate a TLS peer
+// presented, or "" if it presented none.
+func Fingerprint(state *tls.ConnectionState) string {
+	if state == nil || len(state.PeerCertificates) == 0 {
+		return ""
+	}
+	hash := sha256.Sum256(state.PeerCertificates[0].Raw)
+	return hex.EncodeToString(hash[:])
+}
+
+// CertificateFingerprint returns the fingerprint of cert, by which the relay
+// knows the device presenting it.
+func CertificateFingerprint(cert tls.Certificate) string {
+	if len(cert.Certificate) == 0 {
+		return ""
+	}
+	hash := sha256.Sum256(cert.Certificate[0])
+	return hex.EncodeToString(hash[:])
+}
+
+// verifyFingerprint returns a tls.Config.VerifyConnection that accepts only
+// the certificate with fingerprint fp.
+func verifyFingerprint(fp string) func(tls.ConnectionState) error {
+	fp = strings.ToLower(fp)
+	return func(state tls.ConnectionState) error {
+		if actual := Fingerprint(&state); actual != fp {
+			return errors.New("relay certificate fingerprint mismatch: expected " + fp + ", got " + actual)
+		}
+		return nil
+	}
+}
+
+// tunnelConn is a tunnel connection once the upgrade is done. It returns
+// what was buffered while reading the upgrade first, hides the TLS state so
+// HTTP/2 treats the tunnel as cleartext, and closes done when it breaks.
+type tunnelConn struct {
+	net.Conn
+	r    *bufio.Reader
+	once sync.Once
+	done chan struct{}
+}
+
+func newTunnelConn(conn net.Conn, r *bufio.Reader) *tunnelConn {
+	return &tunnelConn{Conn: conn, r: r, done: make(chan struct{})}
+}
+
+func (c *tunnelConn) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	if err != nil {
+		c.once.Do(func() { close(c.done) })
+	}
+	return n, err
+}
+
+func (c *tunnelConn) Close() error {
+	c.once.Do(func() { close(c.done) })
+	return c.Conn.Close()
+}
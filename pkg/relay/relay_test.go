@@ -0,0 +1,103 @@
+package relay
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bethropolis/localgo/pkg/crypto"
+)
+
+func testCertificate(t *testing.T) tls.Certificate {
+	t.Helper()
+	sc, err := crypto.GenerateSecurityContext("test", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := tls.X509KeyPair([]byte(sc.Certificate), []byte(sc.PrivateKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert
+}
+
+func TestRelay(t *testing.T) {
+	relayCert, deviceCert, senderCert, strangerCert := testCertificate(t), testCertificate(t), testCertificate(t), testCertificate(t)
+	deviceFP := CertificateFingerprint(deviceCert)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	srv := NewServer([]string{deviceFP, CertificateFingerprint(senderCert)}, nil)
+	go srv.Serve(ctx, ln, relayCert)
+	u := &url.URL{Scheme: "https", Host: ln.Addr().String()}
+
+	// The device serves its API through the relay.
+	device := NewClient(u, CertificateFingerprint(relayCert), deviceCert, nil, nil)
+	go device.Serve(ctx, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, r.Method+" "+r.URL.RequestURI()+" from "+r.Header.Get(CallerHeader))
+	}))
+	deadline := time.Now().Add(10 * time.Second)
+	for len(srv.Peers()) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("the device did not register")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	sender := NewClient(u, CertificateFingerprint(relayCert), senderCert, nil, nil)
+	fp, err := sender.Resolve(ctx, strings.ToUpper(deviceFP[:8]))
+	if err != nil || fp != deviceFP {
+		t.Fatalf("Resolve = %q, %v, want %q", fp, err, deviceFP)
+	}
+	if _, err := sender.Resolve(ctx, "not-hex"); !errors.Is(err, ErrNotRegistered) {
+		t.Errorf("Resolve of an unknown fingerprint = %v, want ErrNotRegistered", err)
+	}
+
+	// The device learns who sent the request from the relay, not the sender.
+	req, _ := http.NewRequest(http.MethodGet, "https://device/api/localsend/v2/info?x=1", nil)
+	req.Header.Set(CallerHeader, deviceFP)
+	resp, err := (&http.Client{Transport: sender.Transport(fp)}).Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if want := "GET /api/localsend/v2/info?x=1 from " + CertificateFingerprint(senderCert); resp.StatusCode != http.StatusOK || string(body) != want {
+		t.Errorf("forwarded request = %s %q", resp.Status, body)
+	}
+
+	// A pairing code resolves to the device that asked for it.
+	code, err := device.NewCode(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fp, err := sender.LookupCode(ctx, strings.ToLower(code.Code)); err != nil || fp != deviceFP {
+		t.Errorf("LookupCode = %q, %v, want %q", fp, err, deviceFP)
+	}
+	if _, err := sender.LookupCode(ctx, "UUUUUU"); err == nil {
+		t.Error("LookupCode resolved an unknown code")
+	}
+
+	// A device that is not allowed is refused.
+	stranger := NewClient(u, CertificateFingerprint(relayCert), strangerCert, nil, nil)
+	if _, err := stranger.Peers(ctx); err == nil || !strings.Contains(err.Error(), "403") {
+		t.Errorf("Peers for a device that is not allowed = %v, want 403", err)
+	}
+
+	// So is a relay with another certificate.
+	impostor := NewClient(u, CertificateFingerprint(strangerCert), senderCert, nil, nil)
+	if _, err := impostor.Peers(ctx); err == nil {
+		t.Error("Peers accepted a relay with the wrong fingerprint")
+	}
+}
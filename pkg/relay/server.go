@@ -0,0 +1,273 @@
+package relay
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+	stdhttputil "net/http/httputil"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bethropolis/localgo/pkg/httputil"
+	"github.com/bethropolis/localgo/pkg/pairing"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+	"golang.org/x/net/http2"
+)
+
+// Server is a relay server. Devices register with it, and it forwards
+// requests for a registered device's LocalSend API through its tunnel.
+type Server struct {
+	allowed map[string]bool // fingerprints allowed to use the relay; empty allows any
+	logger  *zap.SugaredLogger
+
+	mu    sync.Mutex
+	peers map[string]*tunnel
+	codes map[string]Code
+}
+
+type tunnel struct {
+	conn  *tunnelConn
+	cc    *http2.ClientConn
+	proxy *stdhttputil.ReverseProxy
+	since time.Time
+}
+
+// NewServer creates a relay that only devices with the allowed fingerprints
+// may register with or send through. Without any, every device with a
+// certificate may.
+func NewServer(allowed []string, logger *zap.SugaredLogger) *Server {
+	if logger == nil {
+		logger = zap.NewNop().Sugar()
+	}
+	s := &Server{allowed: make(map[string]bool), logger: logger, peers: make(map[string]*tunnel), codes: make(map[string]Code)}
+	for _, fp := range allowed {
+		s.allowed[strings.ToLower(fp)] = true
+	}
+	return s
+}
+
+// TLSConfig returns the TLS configuration the relay is served with, using
+// cert. Clients must present a certificate, which identifies them. HTTP/2
+// is not offered, since registrations take over their connection.
+func (s *Server) TLSConfig(cert tls.Certificate) *tls.Config {
+	return &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientAuth:   tls.RequireAnyClientCert, // self-signed; identified by fingerprint
+		MinVersion:   tls.VersionTLS12,
+		NextProtos:   []string{"http/1.1"},
+	}
+}
+
+// Handler returns the relay API.
+func (s *Server) Handler() http.Handler {
+	r := mux.NewRouter()
+	r.Use(s.authenticate)
+	r.HandleFunc(PeersPath, s.peersHandler).Methods("GET")
+	r.HandleFunc(RegisterPath, s.registerHandler).Methods("POST")
+	r.PathPrefix(PeersPath + "/{fingerprint}/").HandlerFunc(s.forwardHandler)
+	r.HandleFunc(CodesPath, s.newCodeHandler).Methods("POST")
+	r.HandleFunc(CodesPath+"/{code}", s.codeHandler).Methods("GET")
+	return r
+}
+
+// Serve serves the relay on ln with cert until ctx ends, then closes every
+// tunnel.
+func (s *Server) Serve(ctx context.Context, ln net.Listener, cert tls.Certificate) error {
+	srv := &http.Server{
+		Handler:           s.Handler(),
+		TLSConfig:         s.TLSConfig(cert),
+		ReadHeaderTimeout: 30 * time.Second,
+		BaseContext:       func(net.Listener) context.Context { return ctx },
+	}
+	errc := make(chan error, 1)
+	go func() { errc <- srv.Serve(tls.NewListener(ln, srv.TLSConfig)) }()
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err := srv.Shutdown(shutdownCtx)
+	s.closeAll()
+	if errors.Is(err, context.DeadlineExceeded) {
+		return nil // transfers in progress are cut off
+	}
+	return err
+}
+
+// Peers returns the registered devices, oldest first.
+func (s *Server) Peers() []Peer {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	peers := make([]Peer, 0, len(s.peers))
+	for fp, t := range s.peers {
+		peers = append(peers, Peer{Fingerprint: fp, Since: t.since})
+	}
+	slices.SortFunc(peers, func(a, b Peer) int { return a.Since.Compare(b.Since) })
+	return peers
+}
+
+type callerKey struct{}
+
+// authenticate lets through requests from devices allowed to use the relay,
+// noting the fingerprint of the caller.
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fp := Fingerprint(r.TLS)
+		if fp == "" {
+			httputil.RespondError(w, http.StatusUnauthorized, "A client certificate is required")
+			return
+		}
+		if len(s.allowed) > 0 && !s.allowed[fp] {
+			s.logger.Warnf("Refused %s from %s: fingerprint %.16s... is not allowed", r.URL.Path, r.RemoteAddr, fp)
+			httputil.RespondError(w, http.StatusForbidden, "Fingerprint not allowed")
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), callerKey{}, fp)))
+	})
+}
+
+func (s *Server) peersHandler(w http.ResponseWriter, r *http.Request) {
+	httputil.RespondJSON(w, http.StatusOK, s.Peers())
+}
+
+// newCodeHandler issues a pairing code for the caller.
+func (s *Server) newCodeHandler(w http.ResponseWriter, r *http.Request) {
+	fp := r.Context().Value(callerKey{}).(string)
+	now := time.Now()
+	s.mu.Lock()
+	for c, code := range s.codes {
+		if now.After(code.Expires) {
+			delete(s.codes, c)
+		}
+	}
+	code := Code{Code: pairing.NewRelayCode(), Fingerprint: fp, Expires: now.Add(CodeTTL)}
+	for s.codes[code.Code].Code != "" {
+		code.Code = pairing.NewRelayCode()
+	}
+	s.codes[code.Code] = code
+	s.mu.Unlock()
+	s.logger.Infof("Issued a pairing code to %.16s...", fp)
+	httputil.RespondJSON(w, http.StatusCreated, code)
+}
+
+// codeHandler tells who a pairing code was issued to.
+func (s *Server) codeHandler(w http.ResponseWriter, r *http.Request) {
+	c := pairing.Normalize(mux.Vars(r)["code"])
+	s.mu.Lock()
+	code, ok := s.codes[c]
+	s.mu.Unlock()
+	if !ok || time.Now().After(code.Expires) {
+		httputil.RespondError(w, http.StatusNotFound, "Unknown or expired pairing code")
+		return
+	}
+	httputil.RespondJSON(w, http.StatusOK, code)
+}
+
+// registerHandler takes over the connection of a registration as the tunnel
+// to the calling device, replacing an earlier one.
+func (s *Server) registerHandler(w http.ResponseWriter, r *http.Request) {
+	if !strings.EqualFold(r.Header.Get("Upgrade"), upgradeProtocol) {
+		httputil.RespondError(w, http.StatusBadRequest, "Upgrade: "+upgradeProtocol+" required")
+		return
+	}
+	fp := r.Context().Value(callerKey{}).(string)
+	conn, brw, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		s.logger.Errorf("Failed to take over the connection of %s: %v", r.RemoteAddr, err)
+		return
+	}
+	conn.SetDeadline(time.Time{})
+	brw.WriteString("HTTP/1.1 101 Switching Protocols\r\nConnection: Upgrade\r\nUpgrade: " + upgradeProtocol + "\r\n\r\n")
+	if err := brw.Flush(); err != nil {
+		conn.Close()
+		return
+	}
+
+	tc := newTunnelConn(conn, brw.Reader)
+	tr, err := http2.ConfigureTransports(&http.Transport{})
+	if err != nil {
+		s.logger.Errorf("Failed to open the tunnel to %.16s...: %v", fp, err)
+		tc.Close()
+		return
+	}
+	tr.ReadIdleTimeout, tr.PingTimeout = pingInterval, 15*time.Second
+	cc, err := tr.NewClientConn(tc)
+	if err != nil {
+		s.logger.Errorf("Failed to open the tunnel to %.16s...: %v", fp, err)
+		tc.Close()
+		return
+	}
+	t := &tunnel{conn: tc, cc: cc, since: time.Now()}
+	t.proxy = &stdhttputil.ReverseProxy{
+		Rewrite: func(pr *stdhttputil.ProxyRequest) {
+			// The device serves its API at the root of the tunnel.
+			pr.Out.URL.Scheme = "https"
+			pr.Out.URL.Host = fp
+			_, rest, _ := strings.Cut(strings.TrimPrefix(pr.In.URL.Path, PeersPath+"/"), "/")
+			pr.Out.URL.Path = "/" + rest
+			pr.Out.URL.RawPath = ""
+			pr.Out.Host = ""
+			pr.Out.Header.Del(CallerHeader)
+			if caller, ok := pr.In.Context().Value(callerKey{}).(string); ok {
+				pr.Out.Header.Set(CallerHeader, caller)
+			}
+		},
+		Transport:     cc,
+		FlushInterval: -1,
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			s.logger.Debugf("Forwarding %s to %.16s... failed: %v", r.URL.Path, fp, err)
+			httputil.RespondError(w, http.StatusBadGateway, "Device unreachable through the relay")
+		},
+	}
+
+	s.mu.Lock()
+	old := s.peers[fp]
+	s.peers[fp] = t
+	s.mu.Unlock()
+	if old != nil {
+		old.conn.Close()
+	}
+	s.logger.Infof("Device %.16s... registered from %s", fp, r.RemoteAddr)
+
+	go func() {
+		<-tc.done
+		s.mu.Lock()
+		if s.peers[fp] == t {
+			delete(s.peers, fp)
+		}
+		s.mu.Unlock()
+		cc.Close()
+		s.logger.Infof("Device %.16s... disconnected", fp)
+	}()
+}
+
+// forwardHandler passes a request on to the device it names.
+func (s *Server) forwardHandler(w http.ResponseWriter, r *http.Request) {
+	fp := strings.ToLower(mux.Vars(r)["fingerprint"])
+	s.mu.Lock()
+	t := s.peers[fp]
+	s.mu.Unlock()
+	if t == nil {
+		httputil.RespondError(w, http.StatusNotFound, "Device not registered with the relay")
+		return
+	}
+	t.proxy.ServeHTTP(w, r)
+}
+
+// closeAll closes the tunnel of every registered device.
+func (s *Server) closeAll() {
+	s.mu.Lock()
+	peers := s.peers
+	s.peers = make(map[string]*tunnel)
+	s.mu.Unlock()
+	for _, t := range peers {
+		t.conn.Close()
+	}
+}
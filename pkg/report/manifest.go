@@ -0,0 +1,106 @@
+package report
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Manifest lists the files a receive session saved, with their hashes and
+// where they came from, so they can be verified or audited later.
+type Manifest struct {
+	SessionID         string         `json:"sessionId,omitempty"`
+	Sender            string         `json:"sender,omitempty"`
+	SenderFingerprint string         `json:"senderFingerprint,omitempty"`
+	StartedAt         time.Time      `json:"startedAt"`
+	FinishedAt        time.Time      `json:"finishedAt"`
+	Files             []ManifestFile `json:"files"`
+}
+
+// ManifestFile is a saved file listed in a manifest.
+type ManifestFile struct {
+	Name   string `json:"name"`
+	Path   string `json:"path"` // relative to the manifest's folder when inside it
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// NewManifest hashes the files r received for a manifest to be written to
+// dir. Files that were not saved to disk, such as text copied to the
+// clipboard, are left out.
+func NewManifest(r *Report, dir string) (*Manifest, error) {
+	m := &Manifest{
+		SessionID:         r.SessionID,
+		Sender:            r.Peer,
+		SenderFingerprint: r.PeerFingerprint,
+		StartedAt:         r.StartedAt,
+		FinishedAt:        r.FinishedAt,
+		Files:             []ManifestFile{},
+	}
+	for _, f := range r.Files {
+		if f.Status != StatusReceived || !filepath.IsAbs(f.Path) {
+			continue
+		}
+		sum, size, err := hashFile(f.Path)
+		if err != nil {
+			return nil, err
+		}
+		path := f.Path
+		if rel, err := filepath.Rel(dir, f.Path); err == nil && filepath.IsLocal(rel) {
+			path = rel
+		}
+		m.Files = append(m.Files, ManifestFile{Name: f.Name, Path: path, Size: size, SHA256: sum})
+	}
+	return m, nil
+}
+
+func hashFile(path string) (string, int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to hash %s: %w", path, err)
+	}
+	defer f.Close()
+	h := sha256.New()
+	n, err := io.Copy(h, f)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to hash %s: %w", path, err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), n, nil
+}
+
+// WriteJSON writes the manifest to path as indented JSON.
+func (m *Manifest) WriteJSON(path string) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	return nil
+}
+
+// WriteSHA256 writes the hashes of the manifest to path in the format of
+// sha256sum, so `sha256sum -c` run in the manifest's folder checks them.
+func (m *Manifest) WriteSHA256(path string) error {
+	var b strings.Builder
+	for _, f := range m.Files {
+		name := filepath.ToSlash(f.Path)
+		if strings.ContainsAny(name, "\\\n\r") {
+			// sha256sum escapes such names and marks the line with a backslash.
+			name = strings.NewReplacer("\\", "\\\\", "\n", "\\n", "\r", "\\r").Replace(name)
+			b.WriteString("\\")
+		}
+		fmt.Fprintf(&b, "%s  %s\n", f.SHA256, name)
+	}
+	if err := os.WriteFile(path, []byte(b.String()), 0644); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	return nil
+}
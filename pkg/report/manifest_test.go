@@ -0,0 +1,64 @@
+package report
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestManifest(t *testing.T) {
+	dir := t.TempDir()
+	sub := filepath.Join(dir, "photos")
+	if err := os.Mkdir(sub, 0755); err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(filepath.Join(dir, "a.txt"), []byte("hello"), 0644)
+	os.WriteFile(filepath.Join(sub, "b.txt"), []byte("world"), 0644)
+
+	r := New("receive", "Phone")
+	r.PeerFingerprint = "abc123"
+	r.Add(File{Name: "a.txt", Path: filepath.Join(dir, "a.txt"), Size: 5, Status: StatusReceived})
+	r.Add(File{Name: "b.txt", Path: filepath.Join(sub, "b.txt"), Size: 5, Status: StatusReceived})
+	r.Add(File{Name: "note.txt", Path: "<clipboard>", Size: 4, Status: StatusReceived})
+	r.Add(File{Name: "c.txt", Size: 7, Status: StatusFailed})
+	r.Finish(r.StartedAt)
+
+	m, err := NewManifest(r, dir)
+	if err != nil {
+		t.Fatalf("NewManifest: %v", err)
+	}
+	if m.Sender != "Phone" || m.SenderFingerprint != "abc123" {
+		t.Errorf("sender = %q (%q), want Phone (abc123)", m.Sender, m.SenderFingerprint)
+	}
+	want := []ManifestFile{
+		{Name: "a.txt", Path: "a.txt", Size: 5, SHA256: "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"},
+		{Name: "b.txt", Path: filepath.Join("photos", "b.txt"), Size: 5, SHA256: "486ea46224d1bb4fb680f34f7c9ad96a8f24ec88be73ea8e5a6c65260e9cb8a7"},
+	}
+	if len(m.Files) != len(want) {
+		t.Fatalf("files = %+v, want %+v", m.Files, want)
+	}
+	for i := range want {
+		if m.Files[i] != want[i] {
+			t.Errorf("file %d = %+v, want %+v", i, m.Files[i], want[i])
+		}
+	}
+
+	path := filepath.Join(dir, "manifest.sha256")
+	if err := m.WriteSHA256(path); err != nil {
+		t.Fatalf("WriteSHA256: %v", err)
+	}
+	data, _ := os.ReadFile(path)
+	wantSums := "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824  a.txt\n" +
+		"486ea46224d1bb4fb680f34f7c9ad96a8f24ec88be73ea8e5a6c65260e9cb8a7  photos/b.txt\n"
+	if string(data) != wantSums {
+		t.Errorf("sha256 manifest = %q, want %q", data, wantSums)
+	}
+}
+
+func TestManifest_MissingFile(t *testing.T) {
+	r := New("receive", "Phone")
+	r.Add(File{Name: "gone.txt", Path: filepath.Join(t.TempDir(), "gone.txt"), Size: 5, Status: StatusReceived})
+	if _, err := NewManifest(r, t.TempDir()); err == nil {
+		t.Error("NewManifest succeeded for a missing file")
+	}
+}
@@ -0,0 +1,133 @@
+// Package report summarizes finished send and receive sessions: which files
+// were transferred, how many bytes, how long it took and what failed.
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// File statuses.
+const (
+	StatusSent      = "sent"
+	StatusReceived  = "received"
+	StatusFailed    = "failed"
+	StatusRejected  = "rejected"  // declined by the receiver
+	StatusSkipped   = "skipped"   // not attempted
+	StatusDuplicate = "duplicate" // already delivered by an earlier send
+)
+
+// File is the outcome of a single file.
+type File struct {
+	Name   string `json:"name"`
+	Path   string `json:"path,omitempty"`
+	Size   int64  `json:"size"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Report summarizes one transfer. The totals are filled in by Finish.
+type Report struct {
+	Direction       string    `json:"direction"` // "send" or "receive"
+	SessionID       string    `json:"sessionId,omitempty"`
+	Peer            string    `json:"peer,omitempty"`
+	PeerFingerprint string    `json:"peerFingerprint,omitempty"`
+	StartedAt       time.Time `json:"startedAt"`
+	FinishedAt      time.Time `json:"finishedAt"`
+	Files           []File    `json:"files"`
+	Error           string    `json:"error,omitempty"`  // why the transfer as a whole failed
+	Reason          string    `json:"reason,omitempty"` // how the receiver ended a send, if it did
+
+	Transferred     int     `json:"transferred"`
+	Failed          int     `json:"failed"`
+	Skipped         int     `json:"skipped"` // rejected, skipped or duplicate
+	Bytes           int64   `json:"bytes"`   // size of the transferred files
+	DurationSeconds float64 `json:"durationSeconds"`
+	BytesPerSecond  float64 `json:"bytesPerSecond"`
+}
+
+// New starts a report for a transfer in direction with peer, timed from now.
+func New(direction, peer string) *Report {
+	return &Report{Direction: direction, Peer: peer, StartedAt: time.Now(), Files: []File{}}
+}
+
+// Add records the outcome of a file.
+func (r *Report) Add(f File) {
+	r.Files = append(r.Files, f)
+}
+
+// Finish stops the clock at t and computes the totals.
+func (r *Report) Finish(t time.Time) {
+	r.FinishedAt = t
+	r.Transferred, r.Failed, r.Skipped, r.Bytes = 0, 0, 0, 0
+	for _, f := range r.Files {
+		switch f.Status {
+		case StatusSent, StatusReceived:
+			r.Transferred++
+			r.Bytes += f.Size
+		case StatusFailed:
+			r.Failed++
+		default:
+			r.Skipped++
+		}
+	}
+	d := r.Duration()
+	r.DurationSeconds = d.Seconds()
+	r.BytesPerSecond = 0
+	if d > 0 {
+		r.BytesPerSecond = float64(r.Bytes) / d.Seconds()
+	}
+}
+
+// Duration returns how long the transfer took.
+func (r *Report) Duration() time.Duration {
+	if r.FinishedAt.Before(r.StartedAt) {
+		return 0
+	}
+	return r.FinishedAt.Sub(r.StartedAt)
+}
+
+// Merge combines finished reports of the same direction into one spanning
+// all of them, as when a receive takes several sessions. The peer and
+// session ID are kept only if all reports share them.
+func Merge(direction string, reports []*Report) *Report {
+	merged := &Report{Direction: direction, Files: []File{}}
+	for i, r := range reports {
+		if i == 0 {
+			merged.SessionID, merged.Peer, merged.PeerFingerprint = r.SessionID, r.Peer, r.PeerFingerprint
+			merged.StartedAt, merged.FinishedAt = r.StartedAt, r.FinishedAt
+		}
+		if r.SessionID != merged.SessionID {
+			merged.SessionID = ""
+		}
+		if r.Peer != merged.Peer {
+			merged.Peer = ""
+		}
+		if r.PeerFingerprint != merged.PeerFingerprint {
+			merged.PeerFingerprint = ""
+		}
+		if r.StartedAt.Before(merged.StartedAt) {
+			merged.StartedAt = r.StartedAt
+		}
+		if r.FinishedAt.After(merged.FinishedAt) {
+			merged.FinishedAt = r.FinishedAt
+		}
+		merged.Files = append(merged.Files, r.Files...)
+	}
+	merged.Finish(merged.FinishedAt)
+	return merged
+}
+
+// WriteFile writes the report to path as indented JSON.
+func (r *Report) WriteFile(path string) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write report: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package report
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFinish(t *testing.T) {
+	r := New("send", "Laptop")
+	r.StartedAt = time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	r.Add(File{Name: "a.bin", Size: 3000, Status: StatusSent})
+	r.Add(File{Name: "b.bin", Size: 1000, Status: StatusSent})
+	r.Add(File{Name: "c.bin", Size: 500, Status: StatusFailed, Error: "connection reset"})
+	r.Add(File{Name: "d.bin", Size: 200, Status: StatusRejected})
+	r.Finish(r.StartedAt.Add(2 * time.Second))
+
+	if r.Transferred != 2 || r.Failed != 1 || r.Skipped != 1 {
+		t.Errorf("counts = %d/%d/%d, want 2/1/1", r.Transferred, r.Failed, r.Skipped)
+	}
+	if r.Bytes != 4000 {
+		t.Errorf("Bytes = %d, want 4000", r.Bytes)
+	}
+	if r.DurationSeconds != 2 || r.BytesPerSecond != 2000 {
+		t.Errorf("duration %.1fs at %.1f B/s, want 2s at 2000 B/s", r.DurationSeconds, r.BytesPerSecond)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	first := New("receive", "Phone")
+	first.SessionID, first.StartedAt = "s1", start
+	first.Add(File{Name: "a.txt", Size: 10, Status: StatusReceived})
+	first.Finish(start.Add(time.Second))
+	second := New("receive", "Phone")
+	second.SessionID, second.StartedAt = "s2", start.Add(5*time.Second)
+	second.Add(File{Name: "b.txt", Size: 20, Status: StatusFailed})
+	second.Finish(start.Add(10 * time.Second))
+
+	merged := Merge("receive", []*Report{first, second})
+	if merged.Peer != "Phone" || merged.SessionID != "" {
+		t.Errorf("peer %q, session %q: want shared peer and no session", merged.Peer, merged.SessionID)
+	}
+	if !merged.StartedAt.Equal(start) || merged.Duration() != 10*time.Second {
+		t.Errorf("span %s from %s, want 10s from %s", merged.Duration(), merged.StartedAt, start)
+	}
+	if len(merged.Files) != 2 || merged.Transferred != 1 || merged.Failed != 1 || merged.Bytes != 10 {
+		t.Errorf("unexpected totals: %+v", merged)
+	}
+}
+
+func TestWriteFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "report.json")
+	r := New("receive", "")
+	r.Finish(r.StartedAt)
+	if err := r.WriteFile(path); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded map[string]any
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("report is not valid JSON: %v", err)
+	}
+	if files, ok := decoded["files"].([]any); !ok || len(files) != 0 {
+		t.Errorf("files = %v, want an empty list", decoded["files"])
+	}
+}
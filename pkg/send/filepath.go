@@ -1,13 +1,19 @@
 package send
 
 import (
+	"context"
 	"os"
 	"path/filepath"
 )
 
-func getFilesWithRelativePaths(paths []string) (map[string]string, error) {
+// getFilesWithRelativePaths maps the files of paths, walking directories, to
+// the names they are sent under. A walk of a large tree stops when ctx ends.
+func getFilesWithRelativePaths(ctx context.Context, paths []string, excludes []string) (map[string]string, error) {
 	result := make(map[string]string)
 	for _, p := range paths {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
 		p = filepath.Clean(p)
 		info, err := os.Stat(p)
 		if err != nil {
@@ -19,6 +25,18 @@ func getFilesWithRelativePaths(paths []string) (map[string]string, error) {
 				if err != nil {
 					return err
 				}
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+				if path == p {
+					return nil
+				}
+				if rel, err := filepath.Rel(p, path); err == nil && isExcluded(rel, excludes) {
+					if fInfo.IsDir() {
+						return filepath.SkipDir
+					}
+					return nil
+				}
 				if !fInfo.IsDir() {
 					rel, err := filepath.Rel(baseDir, path)
 					if err == nil {
@@ -38,3 +56,19 @@ func getFilesWithRelativePaths(paths []string) (map[string]string, error) {
 	}
 	return result, nil
 }
+
+// Summarize returns the number of files and their combined size that a send
+// of paths would transfer, walking directories and honouring excludes.
+func Summarize(paths []string, excludes []string) (int, int64, error) {
+	fileMap, err := getFilesWithRelativePaths(context.Background(), paths, excludes)
+	if err != nil {
+		return 0, 0, err
+	}
+	var total int64
+	for p := range fileMap {
+		if info, err := os.Stat(p); err == nil {
+			total += info.Size()
+		}
+	}
+	return len(fileMap), total, nil
+}
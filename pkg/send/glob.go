@@ -0,0 +1,169 @@
+package send
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ExpandGlobs expands shell-style patterns (including "**" for any number of
+// directories) into a sorted list of matching files. Plain paths without glob
+// metacharacters are passed through unchanged so directories keep working.
+// Any path matching one of the exclude patterns is dropped.
+func ExpandGlobs(patterns, excludes []string) ([]string, error) {
+	result, _, err := ExpandGlobsWithNames(patterns, excludes)
+	return result, err
+}
+
+// ExpandGlobsWithNames is ExpandGlobs that also returns the name each file
+// matched by a pattern is sent under: its path below the pattern's leading
+// directories without metacharacters, so "src/**/*.go" keeps the tree under
+// src and files of the same name in different directories stay apart. Pass
+// the names to WithFileNames. Plain paths are not in the map.
+func ExpandGlobsWithNames(patterns, excludes []string) ([]string, map[string]string, error) {
+	var result []string
+	names := make(map[string]string)
+	seen := make(map[string]bool)
+
+	add := func(p string) {
+		if !seen[p] {
+			seen[p] = true
+			result = append(result, p)
+		}
+	}
+
+	for _, p := range patterns {
+		if !hasGlobMeta(p) {
+			if !isExcluded(p, excludes) {
+				add(p)
+			}
+			continue
+		}
+
+		matches, err := expandGlob(p, excludes)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(matches) == 0 {
+			return nil, nil, fmt.Errorf("pattern %q matched no files", p)
+		}
+		for m, name := range matches {
+			if !seen[m] {
+				names[m] = name
+			}
+			add(m)
+		}
+	}
+
+	sort.Strings(result)
+	return result, names, nil
+}
+
+// expandGlob walks the static prefix of a single pattern and returns every
+// regular file whose path matches the remaining pattern segments, mapped to
+// its path relative to that prefix.
+func expandGlob(pattern string, excludes []string) (map[string]string, error) {
+	slashPattern := filepath.ToSlash(filepath.Clean(pattern))
+	segments := strings.Split(slashPattern, "/")
+
+	static := 0
+	for static < len(segments) && !hasGlobMeta(segments[static]) {
+		static++
+	}
+
+	root := strings.Join(segments[:static], "/")
+	if root == "" {
+		if strings.HasPrefix(slashPattern, "/") {
+			root = "/"
+		} else {
+			root = "."
+		}
+	}
+	rest := segments[static:]
+
+	matches := make(map[string]string)
+	err := filepath.WalkDir(filepath.FromSlash(root), func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(filepath.FromSlash(root), p)
+		if err != nil {
+			return nil
+		}
+		rel = filepath.ToSlash(rel)
+		if d.IsDir() {
+			if rel != "." && isExcluded(rel, excludes) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if matchSegments(rest, strings.Split(rel, "/")) && !isExcluded(rel, excludes) {
+			matches[p] = rel
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to expand pattern %q: %w", pattern, err)
+	}
+	return matches, nil
+}
+
+// isExcluded reports whether p matches any exclude pattern. p is a path below
+// the directory or pattern root being sent, or a path as given for one sent
+// by itself. Patterns without a slash are compared against the base name
+// only; patterns with a slash are compared against all of p, so "build/*"
+// skips the files directly in a build directory at the top of a send.
+func isExcluded(p string, excludes []string) bool {
+	if len(excludes) == 0 {
+		return false
+	}
+	slashPath := filepath.ToSlash(filepath.Clean(p))
+	base := path.Base(slashPath)
+	for _, ex := range excludes {
+		ex = filepath.ToSlash(ex)
+		if !strings.Contains(ex, "/") {
+			if ok, _ := path.Match(ex, base); ok {
+				return true
+			}
+			continue
+		}
+		if matchSegments(strings.Split(path.Clean(ex), "/"), strings.Split(slashPath, "/")) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchSegments matches path segments against pattern segments, where a "**"
+// segment matches zero or more whole segments.
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			pattern = pattern[1:]
+			if len(pattern) == 0 {
+				return true
+			}
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pattern, name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, err := path.Match(pattern[0], name[0]); err != nil || !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
+
+func hasGlobMeta(p string) bool {
+	return strings.ContainsAny(p, "*?[")
+}
@@ -0,0 +1,113 @@
+package send
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func writeTree(t *testing.T, root string, files ...string) {
+	t.Helper()
+	for _, f := range files {
+		p := filepath.Join(root, filepath.FromSlash(f))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+		if err := os.WriteFile(p, []byte(f), 0644); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+}
+
+func TestExpandGlobs_DoubleStarAndExclude(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root,
+		"photos/a.jpg",
+		"photos/2024/b.jpg",
+		"photos/2024/c.raw",
+		"photos/2024/trip/d.jpg",
+		"photos/notes.txt",
+	)
+
+	got, names, err := ExpandGlobsWithNames([]string{filepath.Join(root, "photos", "**", "*.jpg")}, []string{"*.raw", "2024/trip/*"})
+	if err != nil {
+		t.Fatalf("ExpandGlobs: %v", err)
+	}
+
+	want := []string{
+		filepath.Join(root, "photos", "2024", "b.jpg"),
+		filepath.Join(root, "photos", "a.jpg"),
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	// Matches keep their place below the pattern's fixed root.
+	wantNames := map[string]string{want[0]: "2024/b.jpg", want[1]: "a.jpg"}
+	if !reflect.DeepEqual(names, wantNames) {
+		t.Errorf("names %v, want %v", names, wantNames)
+	}
+}
+
+func TestExpandGlobs_LiteralPassThrough(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, "dir/x.txt")
+
+	dir := filepath.Join(root, "dir")
+	got, err := ExpandGlobs([]string{dir}, nil)
+	if err != nil {
+		t.Fatalf("ExpandGlobs: %v", err)
+	}
+	if len(got) != 1 || got[0] != dir {
+		t.Errorf("expected literal directory to pass through, got %v", got)
+	}
+}
+
+func TestExpandGlobs_NoMatch(t *testing.T) {
+	root := t.TempDir()
+	if _, err := ExpandGlobs([]string{filepath.Join(root, "*.jpg")}, nil); err == nil {
+		t.Error("expected error for pattern matching no files")
+	}
+}
+
+func TestGetFilesWithRelativePaths_Excludes(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root,
+		"proj/main.go",
+		"proj/build/out.bin",
+		"proj/cache.tmp",
+		"proj/dist/app",
+	)
+
+	got, err := getFilesWithRelativePaths(context.Background(), []string{filepath.Join(root, "proj")}, []string{"build", "*.tmp", "dist/*"})
+	if err != nil {
+		t.Fatalf("getFilesWithRelativePaths: %v", err)
+	}
+	if len(got) != 1 {
+		t.Fatalf("expected 1 file after excludes, got %v", got)
+	}
+	if name := got[filepath.Join(root, "proj", "main.go")]; name != "proj/main.go" {
+		t.Errorf("unexpected remote name %q", name)
+	}
+}
+
+func TestMatchSegments(t *testing.T) {
+	tests := []struct {
+		pattern, name string
+		want          bool
+	}{
+		{"**/*.jpg", "a.jpg", true},
+		{"**/*.jpg", "x/y/a.jpg", true},
+		{"x/**", "x/y/z", true},
+		{"x/*/a.jpg", "x/y/z/a.jpg", false},
+		{"*.jpg", "x/a.jpg", false},
+	}
+	for _, tt := range tests {
+		got := matchSegments(strings.Split(tt.pattern, "/"), strings.Split(tt.name, "/"))
+		if got != tt.want {
+			t.Errorf("matchSegments(%q, %q) = %v, want %v", tt.pattern, tt.name, got, tt.want)
+		}
+	}
+}
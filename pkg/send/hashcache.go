@@ -0,0 +1,117 @@
+package send
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// hashEntry is a file's SHA-256 as of the size and modification time it
+// had when it was hashed.
+type hashEntry struct {
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"modTime"`
+	SHA256  string    `json:"sha256"`
+}
+
+// HashCache remembers the SHA-256 of files by absolute path, so unchanged
+// files are not read again. An entry is used only while the file's size
+// and modification time are those it was hashed at.
+type HashCache struct {
+	path   string
+	logger *zap.SugaredLogger
+
+	mu      sync.Mutex
+	entries map[string]hashEntry
+	dirty   bool
+}
+
+// DefaultHashCacheFile returns where the hash cache is kept, in the user's
+// cache directory.
+func DefaultHashCacheFile() string {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		cacheDir = os.TempDir()
+	}
+	return filepath.Join(cacheDir, "localgo", "hashes.json")
+}
+
+// NewHashCache loads the hash cache at path. An unreadable cache is started
+// afresh, and with an empty path the cache is kept in memory only.
+func NewHashCache(path string, logger *zap.SugaredLogger) *HashCache {
+	if logger == nil {
+		logger = zap.NewNop().Sugar()
+	}
+	c := &HashCache{path: path, logger: logger, entries: make(map[string]hashEntry)}
+	if path == "" {
+		return c
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logger.Warnf("Failed to read hash cache: %v", err)
+		}
+		return c
+	}
+	if err := json.Unmarshal(data, &c.entries); err != nil || c.entries == nil {
+		logger.Warnf("Ignoring corrupt hash cache %s", path)
+		c.entries = make(map[string]hashEntry)
+	}
+	return c
+}
+
+// Sum returns the hex SHA-256 of the file at path, reading the file only
+// if it changed since it was last hashed.
+func (c *HashCache) Sum(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	info, err := os.Stat(abs)
+	if err != nil {
+		return "", err
+	}
+	c.mu.Lock()
+	entry, ok := c.entries[abs]
+	c.mu.Unlock()
+	if ok && entry.Size == info.Size() && entry.ModTime.Equal(info.ModTime()) {
+		return entry.SHA256, nil
+	}
+	sum, err := hashFile(abs)
+	if err != nil {
+		return "", err
+	}
+	c.mu.Lock()
+	c.entries[abs] = hashEntry{Size: info.Size(), ModTime: info.ModTime(), SHA256: sum}
+	c.dirty = true
+	c.mu.Unlock()
+	return sum, nil
+}
+
+// Save writes the cache back to its file if anything was hashed, dropping
+// files that no longer exist.
+func (c *HashCache) Save() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.path == "" || !c.dirty {
+		return nil
+	}
+	for path := range c.entries {
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			delete(c.entries, path)
+		}
+	}
+	data, err := json.Marshal(c.entries)
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(c.path, data); err != nil {
+		return err
+	}
+	c.dirty = false
+	return nil
+}
@@ -0,0 +1,62 @@
+package send
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func sha256Hex(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestHashCache(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "f.txt")
+	gone := filepath.Join(dir, "gone.txt")
+	os.WriteFile(file, []byte("aaaa"), 0644)
+	os.WriteFile(gone, []byte("x"), 0644)
+	cachePath := filepath.Join(dir, "cache", "hashes.json")
+
+	c := NewHashCache(cachePath, nil)
+	if sum, err := c.Sum(file); err != nil || sum != sha256Hex("aaaa") {
+		t.Fatalf("Sum = %q, %v", sum, err)
+	}
+	c.Sum(gone)
+
+	// Same size and time: the stored hash is used without reading the file.
+	info, _ := os.Stat(file)
+	os.WriteFile(file, []byte("bbbb"), 0644)
+	os.Chtimes(file, info.ModTime(), info.ModTime())
+	if sum, _ := c.Sum(file); sum != sha256Hex("aaaa") {
+		t.Error("an unchanged file was hashed again")
+	}
+
+	os.Remove(gone)
+	if err := c.Save(); err != nil {
+		t.Fatal(err)
+	}
+	reloaded := NewHashCache(cachePath, nil)
+	if len(reloaded.entries) != 1 {
+		t.Errorf("reloaded %d entries, want 1 (deleted files dropped)", len(reloaded.entries))
+	}
+	if sum, _ := reloaded.Sum(file); sum != sha256Hex("aaaa") {
+		t.Error("the saved hash was not used after reloading")
+	}
+
+	// A new modification time means the file is read again.
+	later := info.ModTime().Add(time.Minute)
+	os.Chtimes(file, later, later)
+	if sum, _ := reloaded.Sum(file); sum != sha256Hex("bbbb") {
+		t.Error("a modified file was not hashed again")
+	}
+
+	os.WriteFile(cachePath, []byte("{not json"), 0644)
+	if c := NewHashCache(cachePath, nil); len(c.entries) != 0 {
+		t.Error("a corrupt cache was not discarded")
+	}
+}
@@ -0,0 +1,204 @@
+package send
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bethropolis/localgo/pkg/model"
+)
+
+// LedgerEntry is a file as it was when it was delivered.
+type LedgerEntry struct {
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"modTime"`
+	SHA256  string    `json:"sha256"`
+	SentAt  time.Time `json:"sentAt"`
+}
+
+// Ledger remembers which files were delivered to which devices, so a send
+// can skip files the receiver already has. Devices are keyed by
+// LedgerTarget and files by absolute path.
+type Ledger struct {
+	path   string
+	hashes *HashCache
+
+	mu      sync.Mutex
+	targets map[string]map[string]LedgerEntry
+}
+
+// DefaultLedgerFile returns where the ledger is kept, in the XDG state
+// directory, or "" if there is no home directory.
+func DefaultLedgerFile() string {
+	if xdgState := os.Getenv("XDG_STATE_HOME"); xdgState != "" {
+		return filepath.Join(xdgState, "localgo", "sent.json")
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		return filepath.Join(home, ".local", "state", "localgo", "sent.json")
+	}
+	return ""
+}
+
+// OpenLedger reads the ledger at path. A missing file is an empty ledger.
+// Files are hashed through hashes, which is saved with the ledger; if nil,
+// hashes are kept in memory only.
+func OpenLedger(path string, hashes *HashCache) (*Ledger, error) {
+	if hashes == nil {
+		hashes = NewHashCache("", nil)
+	}
+	l := &Ledger{path: path, hashes: hashes, targets: make(map[string]map[string]LedgerEntry)}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return l, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read send ledger: %w", err)
+	}
+	if err := json.Unmarshal(data, &l.targets); err != nil {
+		return nil, fmt.Errorf("failed to parse send ledger %s: %w", path, err)
+	}
+	if l.targets == nil {
+		l.targets = make(map[string]map[string]LedgerEntry)
+	}
+	return l, nil
+}
+
+// LedgerTarget identifies a device in the ledger: its fingerprint, or its
+// address when the fingerprint is unknown.
+func LedgerTarget(device *model.Device) string {
+	if device.Fingerprint != "" {
+		return strings.ToLower(device.Fingerprint)
+	}
+	return net.JoinHostPort(device.IP, strconv.Itoa(device.Port))
+}
+
+// Delivered reports whether the file at path was delivered to target as it
+// is now. A file whose size and modification time match is taken as
+// delivered; one whose modification time changed is hashed and compared.
+func (l *Ledger) Delivered(target, path string) bool {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+	info, err := os.Stat(abs)
+	if err != nil {
+		return false
+	}
+	l.mu.Lock()
+	entry, ok := l.targets[target][abs]
+	l.mu.Unlock()
+	if !ok || entry.Size != info.Size() {
+		return false
+	}
+	if entry.ModTime.Equal(info.ModTime()) {
+		return true
+	}
+	sum, err := l.hashes.Sum(abs)
+	if err != nil || sum != entry.SHA256 {
+		return false
+	}
+	// Same content, touched since: remember the new time to skip hashing.
+	entry.ModTime = info.ModTime()
+	l.mu.Lock()
+	l.targets[target][abs] = entry
+	l.mu.Unlock()
+	return true
+}
+
+// Record notes that the file at path was delivered to target.
+func (l *Ledger) Record(target, path string) error {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	info, err := os.Stat(abs)
+	if err != nil {
+		return err
+	}
+	sum, err := l.hashes.Sum(abs)
+	if err != nil {
+		return err
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.targets[target] == nil {
+		l.targets[target] = make(map[string]LedgerEntry)
+	}
+	l.targets[target][abs] = LedgerEntry{Size: info.Size(), ModTime: info.ModTime(), SHA256: sum, SentAt: time.Now()}
+	return nil
+}
+
+// Save writes the ledger and its hash cache back to their files.
+func (l *Ledger) Save() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	data, err := json.MarshalIndent(l.targets, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(l.path, data); err != nil {
+		return err
+	}
+	return l.hashes.Save()
+}
+
+// Pending returns the files a send of paths to target would include, minus
+// those already delivered, mapped to the names they are sent under.
+func (l *Ledger) Pending(target string, paths, excludes []string) (map[string]string, error) {
+	fileMap, err := getFilesWithRelativePaths(context.Background(), paths, excludes)
+	if err != nil {
+		return nil, err
+	}
+	for path := range fileMap {
+		if l.Delivered(target, path) {
+			delete(fileMap, path)
+		}
+	}
+	return fileMap, nil
+}
+
+// writeFileAtomic writes data to path through a temporary file, so a crash
+// leaves the previous copy intact.
+func writeFileAtomic(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
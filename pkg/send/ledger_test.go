@@ -0,0 +1,108 @@
+package send
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bethropolis/localgo/pkg/config"
+	"github.com/bethropolis/localgo/pkg/crypto"
+	"github.com/bethropolis/localgo/pkg/model"
+)
+
+func TestSendToDevice_Ledger(t *testing.T) {
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.txt")
+	b := filepath.Join(dir, "b.txt")
+	os.WriteFile(a, []byte("alpha"), 0644)
+	os.WriteFile(b, []byte("beta"), 0644)
+
+	var offered []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/localsend/v2/prepare-upload":
+			var req model.PrepareUploadRequestDto
+			json.NewDecoder(r.Body).Decode(&req)
+			files := make(map[string]string)
+			for id, f := range req.Files {
+				offered = append(offered, f.FileName)
+				files[id] = "token"
+			}
+			json.NewEncoder(w).Encode(model.PrepareUploadResponseDto{SessionID: "session", Files: files})
+		case "/api/localsend/v2/upload":
+			io.Copy(io.Discard, r.Body)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	host := strings.TrimPrefix(server.URL, "http://")
+	port, _ := strconv.Atoi(strings.Split(host, ":")[1])
+	cfg := &config.Config{SecurityContext: &crypto.StoredSecurityContext{}}
+	device := &model.Device{IP: strings.Split(host, ":")[0], Port: port, Protocol: model.ProtocolTypeHTTP, Fingerprint: "ABCD1234"}
+	ledgerPath := filepath.Join(dir, "state", "sent.json")
+	hashes := NewHashCache(filepath.Join(dir, "state", "hashes.json"), nil)
+
+	send := func() *SendResult {
+		t.Helper()
+		ledger, err := OpenLedger(ledgerPath, hashes)
+		if err != nil {
+			t.Fatal(err)
+		}
+		offered = nil
+		var result SendResult
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := SendToDevice(ctx, cfg, device, []string{dir + "/a.txt", b}, testLoggerSend, WithLedger(ledger), WithResult(&result)); err != nil {
+			t.Fatalf("SendToDevice failed: %v", err)
+		}
+		if err := ledger.Save(); err != nil {
+			t.Fatal(err)
+		}
+		return &result
+	}
+
+	if r := send(); r.Count(FileSent) != 2 || len(offered) != 2 {
+		t.Fatalf("first send: %d sent, %v offered; want both", r.Count(FileSent), offered)
+	}
+
+	// Unchanged files are not offered again, even when only touched.
+	later := time.Now().Add(time.Hour)
+	os.Chtimes(a, later, later)
+	r := send()
+	if r.Count(FileDuplicate) != 2 || offered != nil {
+		t.Fatalf("second send: %d duplicates, %v offered; want both skipped without a session", r.Count(FileDuplicate), offered)
+	}
+
+	// A changed file is sent again; the other is still a duplicate.
+	os.WriteFile(b, []byte("beta, edited"), 0644)
+	r = send()
+	if !slices.Equal(offered, []string{"b.txt"}) || r.Count(FileDuplicate) != 1 || r.Count(FileSent) != 1 {
+		t.Fatalf("third send: offered %v, %d duplicate(s), %d sent; want only b.txt sent", offered, r.Count(FileDuplicate), r.Count(FileSent))
+	}
+
+	// Another device has received nothing yet.
+	ledger, _ := OpenLedger(ledgerPath, nil)
+	if ledger.Delivered("other", a) {
+		t.Error("a file was taken as delivered to a device it was never sent to")
+	}
+
+	os.WriteFile(filepath.Join(dir, "c.txt"), []byte("gamma"), 0644)
+	pending, err := ledger.Pending(LedgerTarget(device), []string{dir}, []string{"state"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 1 || pending[filepath.Join(dir, "c.txt")] != filepath.Base(dir)+"/c.txt" {
+		t.Errorf("Pending = %v, want only c.txt", pending)
+	}
+}
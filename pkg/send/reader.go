@@ -0,0 +1,100 @@
+package send
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/bethropolis/localgo/pkg/config"
+	"github.com/bethropolis/localgo/pkg/model"
+	"go.uber.org/zap"
+)
+
+// SizeUnknown, given as the size of a stream, makes NewStream spool it to a
+// temporary file to learn its size, as the receiver needs it up front.
+const SizeUnknown = -1
+
+// ErrEmptyStream is returned by NewStream for a stream of unknown size that
+// turns out to have no data.
+var ErrEmptyStream = errors.New("stream is empty")
+
+// Stream is generated or piped content to be sent as a file.
+type Stream struct {
+	name  string
+	size  int64
+	r     io.Reader
+	spool *os.File // temporary copy of a stream of unknown size
+}
+
+// NewStream prepares r to be sent as a file called name. If size is
+// SizeUnknown, r is read to the end into a temporary file, which Close
+// removes; otherwise exactly size bytes must be readable from r.
+func NewStream(name string, size int64, r io.Reader) (*Stream, error) {
+	if name == "" || strings.ContainsAny(name, `/\`) || name == "." || name == ".." {
+		return nil, fmt.Errorf("invalid file name: %q", name)
+	}
+	if size >= 0 {
+		return &Stream{name: name, size: size, r: r}, nil
+	}
+	if size != SizeUnknown {
+		return nil, fmt.Errorf("invalid size: %d", size)
+	}
+	tmp, err := os.CreateTemp("", "localgo-stream-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp file for stream: %w", err)
+	}
+	s := &Stream{name: name, spool: tmp, r: tmp}
+	s.size, err = io.Copy(tmp, r)
+	if err == nil {
+		_, err = tmp.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		s.Close()
+		return nil, fmt.Errorf("failed to read stream: %w", err)
+	}
+	if s.size == 0 {
+		s.Close()
+		return nil, ErrEmptyStream
+	}
+	return s, nil
+}
+
+// Name returns the file name the stream is sent as.
+func (s *Stream) Name() string {
+	return s.name
+}
+
+// Size returns the number of bytes the stream is sent with.
+func (s *Stream) Size() int64 {
+	return s.size
+}
+
+// Option adds the stream to a send. A stream can be read only once, so it
+// must be passed to a single send.
+func (s *Stream) Option() SendOption {
+	return WithStream(s.name, s.r, s.size)
+}
+
+// Close removes the temporary copy of a stream of unknown size.
+func (s *Stream) Close() error {
+	if s.spool == nil {
+		return nil
+	}
+	s.spool.Close()
+	return os.Remove(s.spool.Name())
+}
+
+// SendReader sends the content of r to device as a single file called name,
+// without it having to exist on disk. size is the number of bytes r yields,
+// or SizeUnknown.
+func SendReader(ctx context.Context, cfg *config.Config, device *model.Device, name string, size int64, r io.Reader, logger *zap.SugaredLogger, opts ...SendOption) error {
+	stream, err := NewStream(name, size, r)
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+	return SendToDevice(ctx, cfg, device, nil, logger, append(opts, stream.Option())...)
+}
@@ -0,0 +1,103 @@
+package send
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bethropolis/localgo/pkg/config"
+	"github.com/bethropolis/localgo/pkg/crypto"
+	"github.com/bethropolis/localgo/pkg/model"
+)
+
+func TestSendReader_UnknownSize(t *testing.T) {
+	content := "generated report"
+
+	var gotName string
+	var gotSize int64
+	var gotBody string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/localsend/v2/prepare-upload":
+			var req model.PrepareUploadRequestDto
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, "Bad Request", http.StatusBadRequest)
+				return
+			}
+			files := make(map[string]string)
+			for id, f := range req.Files {
+				gotName, gotSize = f.FileName, f.Size
+				files[id] = "token"
+			}
+			json.NewEncoder(w).Encode(model.PrepareUploadResponseDto{SessionID: "session", Files: files})
+		case "/api/localsend/v2/upload":
+			body, _ := io.ReadAll(r.Body)
+			gotBody = string(body)
+			w.WriteHeader(http.StatusOK)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	host := strings.TrimPrefix(server.URL, "http://")
+	port, _ := strconv.Atoi(strings.Split(host, ":")[1])
+	cfg := &config.Config{SecurityContext: &crypto.StoredSecurityContext{}}
+	device := &model.Device{IP: strings.Split(host, ":")[0], Port: port, Protocol: model.ProtocolTypeHTTP}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// A pipe has no length, so the size is learned by spooling.
+	pr, pw := io.Pipe()
+	go func() {
+		io.WriteString(pw, content)
+		pw.Close()
+	}()
+	if err := SendReader(ctx, cfg, device, "report.txt", SizeUnknown, pr, testLoggerSend); err != nil {
+		t.Fatalf("SendReader failed: %v", err)
+	}
+	if gotName != "report.txt" || gotSize != int64(len(content)) {
+		t.Errorf("unexpected file dto: name=%q size=%d", gotName, gotSize)
+	}
+	if gotBody != content {
+		t.Errorf("unexpected upload body: %q", gotBody)
+	}
+}
+
+func TestNewStream(t *testing.T) {
+	for _, name := range []string{"", ".", "..", "a/b", `a\b`} {
+		if _, err := NewStream(name, 1, strings.NewReader("x")); err == nil {
+			t.Errorf("NewStream(%q) accepted the name", name)
+		}
+	}
+	if _, err := NewStream("a", -2, strings.NewReader("x")); err == nil {
+		t.Error("NewStream accepted a negative size")
+	}
+	if _, err := NewStream("a", SizeUnknown, strings.NewReader("")); !errors.Is(err, ErrEmptyStream) {
+		t.Errorf("empty stream: err = %v, want ErrEmptyStream", err)
+	}
+
+	stream, err := NewStream("a", SizeUnknown, strings.NewReader("hello"))
+	if err != nil {
+		t.Fatalf("NewStream: %v", err)
+	}
+	if stream.Size() != 5 {
+		t.Errorf("Size = %d, want 5", stream.Size())
+	}
+	spool := stream.spool.Name()
+	if err := stream.Close(); err != nil {
+		t.Errorf("Close: %v", err)
+	}
+	if _, err := os.Stat(spool); !os.IsNotExist(err) {
+		t.Errorf("spool file %s not removed", spool)
+	}
+}
@@ -0,0 +1,93 @@
+package send
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/bethropolis/localgo/pkg/model"
+)
+
+// AmbiguousRecipientError is returned when several devices match the
+// requested alias or fingerprint prefix.
+type AmbiguousRecipientError struct {
+	Alias       string
+	Fingerprint string
+	Candidates  []*model.Device
+}
+
+func (e *AmbiguousRecipientError) Error() string {
+	if e.Fingerprint != "" {
+		return fmt.Sprintf("%d devices match %s; use a longer fingerprint prefix", len(e.Candidates), recipientLabel(e.Alias, e.Fingerprint))
+	}
+	return fmt.Sprintf("%d devices share the alias '%s'; specify a fingerprint to choose one", len(e.Candidates), e.Alias)
+}
+
+// recipientLabel describes a recipient lookup for log and error messages.
+func recipientLabel(alias, fingerprint string) string {
+	switch {
+	case fingerprint == "":
+		return fmt.Sprintf("'%s'", alias)
+	case alias == "":
+		return fmt.Sprintf("with fingerprint %s", fingerprint)
+	default:
+		return fmt.Sprintf("'%s' with fingerprint %s", alias, fingerprint)
+	}
+}
+
+// matchesRecipient reports whether device has the given alias (if any) and,
+// when fingerprint is set, a fingerprint starting with it (case-insensitive).
+func matchesRecipient(device *model.Device, alias, fingerprint string) bool {
+	if alias != "" && device.Alias != alias {
+		return false
+	}
+	if fingerprint == "" {
+		return true
+	}
+	return strings.HasPrefix(strings.ToLower(device.Fingerprint), strings.ToLower(fingerprint))
+}
+
+// addCandidate appends device unless the same device is already listed.
+// Devices are identified by fingerprint, or by address if they have none.
+func addCandidate(candidates []*model.Device, device *model.Device) []*model.Device {
+	for _, c := range candidates {
+		if candidateKey(c) == candidateKey(device) {
+			return candidates
+		}
+	}
+	return append(candidates, device)
+}
+
+func candidateKey(d *model.Device) string {
+	if d.Fingerprint != "" {
+		return d.Fingerprint
+	}
+	return net.JoinHostPort(d.IP, strconv.Itoa(d.Port))
+}
+
+// resolveRecipient returns the single matching candidate, nil if there are
+// none, or an AmbiguousRecipientError if more than one device matched.
+func resolveRecipient(alias, fingerprint string, candidates []*model.Device) (*model.Device, error) {
+	switch len(candidates) {
+	case 0:
+		return nil, nil
+	case 1:
+		return candidates[0], nil
+	default:
+		return nil, &AmbiguousRecipientError{Alias: alias, Fingerprint: fingerprint, Candidates: candidates}
+	}
+}
+
+// ChooseRecipient picks the device with alias and fingerprint prefix from
+// devices already known, such as those a running server has seen. It
+// returns nil if none match and an AmbiguousRecipientError if several do.
+func ChooseRecipient(devices []*model.Device, alias, fingerprint string) (*model.Device, error) {
+	var candidates []*model.Device
+	for _, d := range devices {
+		if matchesRecipient(d, alias, fingerprint) {
+			candidates = addCandidate(candidates, d)
+		}
+	}
+	return resolveRecipient(alias, fingerprint, candidates)
+}
@@ -0,0 +1,62 @@
+package send
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/bethropolis/localgo/pkg/model"
+)
+
+func TestResolveRecipient_Duplicates(t *testing.T) {
+	a := &model.Device{Alias: "Pixel", IP: "192.168.1.10", Port: 53317, Fingerprint: "AAAA1111"}
+	b := &model.Device{Alias: "Pixel", IP: "192.168.1.11", Port: 53317, Fingerprint: "BBBB2222"}
+
+	var candidates []*model.Device
+	for _, d := range []*model.Device{a, b, a} {
+		if matchesRecipient(d, "Pixel", "") {
+			candidates = addCandidate(candidates, d)
+		}
+	}
+
+	_, err := resolveRecipient("Pixel", "", candidates)
+	var ambiguous *AmbiguousRecipientError
+	if !errors.As(err, &ambiguous) {
+		t.Fatalf("expected AmbiguousRecipientError, got %v", err)
+	}
+	if len(ambiguous.Candidates) != 2 {
+		t.Errorf("expected 2 unique candidates, got %d", len(ambiguous.Candidates))
+	}
+}
+
+func TestResolveRecipient_FingerprintPrefix(t *testing.T) {
+	devices := []*model.Device{
+		{Alias: "Pixel", IP: "192.168.1.10", Fingerprint: "AAAA1111"},
+		{Alias: "Pixel", IP: "192.168.1.11", Fingerprint: "BBBB2222"},
+		{Alias: "Laptop", IP: "192.168.1.12", Fingerprint: "bbbb3333"},
+	}
+
+	var candidates []*model.Device
+	for _, d := range devices {
+		if matchesRecipient(d, "Pixel", "bbbb") {
+			candidates = addCandidate(candidates, d)
+		}
+	}
+
+	got, err := resolveRecipient("Pixel", "bbbb", candidates)
+	if err != nil {
+		t.Fatalf("resolveRecipient: %v", err)
+	}
+	if got == nil || got.IP != "192.168.1.11" {
+		t.Errorf("expected device at 192.168.1.11, got %+v", got)
+	}
+}
+
+func TestMatchesRecipient_FingerprintOnly(t *testing.T) {
+	d := &model.Device{Alias: "Renamed", Fingerprint: "AB12CD34EF"}
+	if !matchesRecipient(d, "", "ab12cd34") {
+		t.Error("expected fingerprint prefix to match without an alias")
+	}
+	if matchesRecipient(d, "", "ab12ff") {
+		t.Error("expected different prefix not to match")
+	}
+}
@@ -0,0 +1,95 @@
+package send
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Errors for a send the receiver ended. The errors returned by SendToDevice
+// wrap them, so callers can tell them apart with errors.Is.
+var (
+	ErrDeclined  = errors.New("recipient declined")
+	ErrBusy      = errors.New("recipient busy")
+	ErrCancelled = errors.New("session cancelled by recipient")
+)
+
+// Outcome names how the receiver ended a send that failed with err:
+// "declined", "busy" or "cancelled", or "" if the receiver did not end it.
+func Outcome(err error) string {
+	switch {
+	case errors.Is(err, ErrDeclined):
+		return "declined"
+	case errors.Is(err, ErrBusy):
+		return "busy"
+	case errors.Is(err, ErrCancelled):
+		return "cancelled"
+	}
+	return ""
+}
+
+// FileStatus is the outcome of a single file in a send.
+type FileStatus string
+
+const (
+	FileSent      FileStatus = "sent"
+	FileFailed    FileStatus = "failed"
+	FileRejected  FileStatus = "rejected"  // declined by the receiver at prepare-upload
+	FileSkipped   FileStatus = "skipped"   // not attempted after an earlier failure
+	FileDuplicate FileStatus = "duplicate" // already delivered, per the ledger
+)
+
+// FileResult records what happened to one file.
+type FileResult struct {
+	Name   string // name announced to the receiver
+	Path   string // local path, empty for in-memory files
+	Size   int64
+	Status FileStatus
+	Err    error
+}
+
+// SendResult collects per-file outcomes for a send, in upload order.
+type SendResult struct {
+	Files []FileResult
+}
+
+// Count returns the number of files with the given status.
+func (r *SendResult) Count(status FileStatus) int {
+	n := 0
+	for _, f := range r.Files {
+		if f.Status == status {
+			n++
+		}
+	}
+	return n
+}
+
+// PartialFailureError is returned when one or more uploads failed. Result
+// holds the outcome of every file, including the ones that succeeded.
+type PartialFailureError struct {
+	Result *SendResult
+}
+
+func (e *PartialFailureError) Error() string {
+	failed := e.Result.Count(FileFailed)
+	first := e.firstError()
+	if errors.Is(first, ErrCancelled) {
+		return fmt.Sprintf("%v after %d of %d files", ErrCancelled, e.Result.Count(FileSent), len(e.Result.Files))
+	}
+	if first != nil {
+		return fmt.Sprintf("encountered %d upload errors, first error: %v", failed, first)
+	}
+	return fmt.Sprintf("encountered %d upload errors", failed)
+}
+
+func (e *PartialFailureError) Unwrap() error {
+	return e.firstError()
+}
+
+func (e *PartialFailureError) firstError() error {
+	for _, f := range e.Result.Files {
+		if f.Status == FileFailed && f.Err != nil {
+			return fmt.Errorf("failed to upload %s: %w", f.Name, f.Err)
+		}
+	}
+	return nil
+}
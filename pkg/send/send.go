@@ -1,37 +1,61 @@
 package send
 
 import (
+	"bufio"
 	"bytes"
 	"context"
-	"crypto/sha256"
-	"crypto/tls"
-	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"maps"
 	"net"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
+	"slices"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/bethropolis/localgo/pkg/capture"
 	"github.com/bethropolis/localgo/pkg/cli"
+	"github.com/bethropolis/localgo/pkg/compression"
 	"github.com/bethropolis/localgo/pkg/config"
 	"github.com/bethropolis/localgo/pkg/discovery"
+	"github.com/bethropolis/localgo/pkg/httputil"
 	"github.com/bethropolis/localgo/pkg/metadata"
 	"github.com/bethropolis/localgo/pkg/model"
 	"github.com/bethropolis/localgo/pkg/network"
+	"github.com/bethropolis/localgo/pkg/storage"
 	"github.com/google/uuid"
 	"go.uber.org/zap"
 )
 
+// duplicateGracePeriod is how long SendFiles keeps listening after the first
+// multicast match, to catch other devices announcing the same alias.
+const duplicateGracePeriod = 500 * time.Millisecond
+
 // SendOption configures the send pipeline.
 type SendOption func(*sendConfig)
 
 type sendConfig struct {
-	memFiles []memFile
+	memFiles    []memFile
+	streams     []streamFile
+	providers   []storage.FileProvider
+	progress    func(sent, total int64)
+	excludes    []string
+	remoteName  string
+	fileNames   map[string]string
+	failFast    bool
+	result      *SendResult
+	fingerprint string
+	benchmark   bool
+	ledger      *Ledger
+	transport   http.RoundTripper
 }
 
 type memFile struct {
@@ -39,6 +63,29 @@ type memFile struct {
 	content []byte
 }
 
+type streamFile struct {
+	name string
+	r    io.Reader
+	size int64
+}
+
+// WithStream adds a file read from r (for example stdin) to the send. The
+// receiver needs the size up front, so exactly size bytes must be readable
+// from r; a shorter or longer stream fails the upload.
+func WithStream(name string, r io.Reader, size int64) SendOption {
+	return func(c *sendConfig) {
+		c.streams = append(c.streams, streamFile{name: name, r: r, size: size})
+	}
+}
+
+// WithFileProvider adds a file read through p, such as content generated
+// on the fly, to the send. The receiver is given its SHA-256 when p knows it.
+func WithFileProvider(p storage.FileProvider) SendOption {
+	return func(c *sendConfig) {
+		c.providers = append(c.providers, p)
+	}
+}
+
 // WithInMemoryFile adds an in-memory file (no disk I/O) to the send.
 func WithInMemoryFile(name string, content []byte) SendOption {
 	return func(c *sendConfig) {
@@ -46,17 +93,135 @@ func WithInMemoryFile(name string, content []byte) SendOption {
 	}
 }
 
-// SendFiles sends files or directories to a recipient.
+// WithExcludes skips files and directories matching any of the given glob
+// patterns while walking directories.
+func WithExcludes(patterns ...string) SendOption {
+	return func(c *sendConfig) {
+		c.excludes = append(c.excludes, patterns...)
+	}
+}
+
+// WithRemoteName overrides the file name announced to the recipient. It only
+// applies when exactly one file is being sent; the local file is untouched.
+func WithRemoteName(name string) SendOption {
+	return func(c *sendConfig) {
+		c.remoteName = name
+	}
+}
+
+// WithFileNames sets the names files of the send are announced under, by
+// path, such as those ExpandGlobsWithNames returns. Other files keep their
+// usual names.
+func WithFileNames(names map[string]string) SendOption {
+	return func(c *sendConfig) {
+		if c.fileNames == nil {
+			c.fileNames = make(map[string]string)
+		}
+		maps.Copy(c.fileNames, names)
+	}
+}
+
+// WithFailFast stops starting new uploads after the first failed one. The
+// remaining files are reported as skipped.
+func WithFailFast() SendOption {
+	return func(c *sendConfig) {
+		c.failFast = true
+	}
+}
+
+// WithResult fills r with the per-file outcome of the send. It is populated
+// whether or not the send returns an error, once the receiver has answered
+// the prepare-upload request.
+func WithResult(r *SendResult) SendOption {
+	return func(c *sendConfig) {
+		c.result = r
+	}
+}
+
+// WithProgress calls fn with the bytes uploaded so far across all files
+// of the send, and the total, as the uploads proceed. fn may be called from
+// several goroutines at once.
+func WithProgress(fn func(sent, total int64)) SendOption {
+	return func(c *sendConfig) {
+		c.progress = fn
+	}
+}
+
+// WithLedger skips files the ledger says were already delivered to the
+// recipient, reporting them as FileDuplicate, and records the files sent.
+// The caller saves the ledger.
+func WithLedger(l *Ledger) SendOption {
+	return func(c *sendConfig) {
+		c.ledger = l
+	}
+}
+
+// WithRecipientFingerprint restricts alias lookups in SendFiles to devices
+// whose fingerprint starts with fp, to choose between devices sharing an alias.
+func WithRecipientFingerprint(fp string) SendOption {
+	return func(c *sendConfig) {
+		c.fingerprint = fp
+	}
+}
+
+// WithTransport sends every request of the send through rt instead of the
+// shared transport, such as to reach the recipient through a relay. The
+// recipient's certificate is then not pinned: rt is trusted to reach it.
+func WithTransport(rt http.RoundTripper) SendOption {
+	return func(c *sendConfig) {
+		c.transport = rt
+	}
+}
+
+// maxPreviewBytes bounds the image previews sent with one prepare-upload
+// request, which receivers limit in size.
+const maxPreviewBytes = 256 << 10
+
+// ErrBenchmarkUnsupported is returned by a WithBenchmark send when the
+// receiver has no speed test endpoints.
+var ErrBenchmarkUnsupported = errors.New("receiver does not support speed tests (it must run a recent LocalGo)")
+
+// WithBenchmark sends to the receiver's speed test endpoints instead, which
+// take the same prepare-upload and upload requests but discard the data.
+// Only LocalGo receivers have them.
+func WithBenchmark() SendOption {
+	return func(c *sendConfig) {
+		c.benchmark = true
+	}
+}
+
+// SendFiles sends files or directories to a recipient found by alias. The
+// alias may be empty when WithRecipientFingerprint identifies the device.
 func SendFiles(ctx context.Context, cfg *config.Config, filePaths []string, recipientAlias string, recipientPort int, logger *zap.SugaredLogger, opts ...SendOption) error {
 	if logger == nil {
 		logger = zap.NewNop().Sugar()
 	}
 
-	logger.Infof("Searching for recipient '%s'...", recipientAlias)
+	var sc sendConfig
+	for _, opt := range opts {
+		opt(&sc)
+	}
+	targetDevice, err := FindRecipient(ctx, cfg, recipientAlias, sc.fingerprint, recipientPort, logger)
+	if err != nil {
+		return err
+	}
+	return SendToDevice(ctx, cfg, targetDevice, filePaths, logger, opts...)
+}
 
-	if recipientPort == 0 {
-		recipientPort = config.DefaultPort
+// FindRecipient looks for the device with recipientAlias and, if fingerprint
+// is set, a fingerprint starting with it. It tries multicast discovery first,
+// then scans the local subnets on recipientPort (0 means the port the device
+// last advertised). Devices sharing the alias give an AmbiguousRecipientError.
+func FindRecipient(ctx context.Context, cfg *config.Config, recipientAlias, fingerprint string, recipientPort int, logger *zap.SugaredLogger) (*model.Device, error) {
+	if logger == nil {
+		logger = zap.NewNop().Sugar()
 	}
+	if recipientAlias == "" && fingerprint == "" {
+		return nil, fmt.Errorf("no recipient alias or fingerprint given")
+	}
+	label := recipientLabel(recipientAlias, fingerprint)
+
+	logger.Infof("Searching for recipient %s...", label)
 
 	var targetDevice *model.Device
 
@@ -79,18 +244,30 @@ func SendFiles(ctx context.Context, cfg *config.Config, filePaths []string, reci
 	discoverySvc := discovery.NewService(discoverySvcConfig, multicast, logger)
 	discoverySvc.SetPeerCache(peerCache)
 
-	foundChan := make(chan *model.Device, 1)
-	discoverySvc.AddDeviceHandler(func(device *model.Device) {
-		if device.Alias == recipientAlias {
+	// Collect every matching device rather than the first, so that devices
+	// sharing an alias are detected instead of one being picked at random.
+	var candidatesMu sync.Mutex
+	var candidates []*model.Device
+	foundChan := make(chan struct{}, 1)
+
+	multicastCtx, cancelMulticast := context.WithTimeout(ctx, 1500*time.Millisecond)
+	defer cancelMulticast()
+
+	deviceEvents, _ := discoverySvc.Subscribe(multicastCtx)
+	go func() {
+		for ev := range deviceEvents {
+			if ev.Type != discovery.DeviceFound || !matchesRecipient(ev.Device, recipientAlias, fingerprint) {
+				continue
+			}
+			candidatesMu.Lock()
+			candidates = addCandidate(candidates, ev.Device)
+			candidatesMu.Unlock()
 			select {
-			case foundChan <- device:
+			case foundChan <- struct{}{}:
 			default:
 			}
 		}
-	})
-
-	multicastCtx, cancelMulticast := context.WithTimeout(ctx, 1500*time.Millisecond)
-	defer cancelMulticast()
+	}()
 
 	err := discoverySvc.Start(multicastCtx, cfg.ToMulticastDto(false))
 	if err != nil {
@@ -98,28 +275,59 @@ func SendFiles(ctx context.Context, cfg *config.Config, filePaths []string, reci
 	}
 
 	select {
-	case device := <-foundChan:
-		logger.Infof("Discovered recipient via multicast: %s (%s)", device.Alias, device.IP)
-		targetDevice = device
-		discoverySvc.Stop()
+	case <-foundChan:
+		// Give other devices with the same alias a moment to answer.
+		grace := time.NewTimer(duplicateGracePeriod)
+		select {
+		case <-grace.C:
+		case <-multicastCtx.Done():
+			grace.Stop()
+		}
 	case <-multicastCtx.Done():
 		logger.Info("Multicast discovery timed out, falling back to HTTP scan...")
-		discoverySvc.Stop()
+	}
+	discoverySvc.Stop()
+
+	candidatesMu.Lock()
+	targetDevice, err = resolveRecipient(recipientAlias, fingerprint, candidates)
+	candidatesMu.Unlock()
+	if err != nil {
+		return nil, err
 	}
 
 	if targetDevice != nil {
+		logger.Infof("Discovered recipient via multicast: %s (%s)", targetDevice.Alias, targetDevice.IP)
 		if err := verifyDeviceFingerprint(peerCache, targetDevice); err != nil {
-			return err
+			return nil, err
 		}
-		return SendToDevice(ctx, cfg, targetDevice, filePaths, logger, opts...)
+		return targetDevice, nil
+	}
+
+	// Multicast found nothing; scan on the port the recipient last advertised
+	// rather than guessing, unless the caller asked for a specific one.
+	if recipientPort == 0 {
+		recipientPort = scanPortFor(peerCache.GetPeers(), recipientAlias, fingerprint, config.DefaultPort)
 	}
+	logger.Infof("Scanning subnet for %s on port %d", label, recipientPort)
 
 	registerDto := cfg.ToRegisterDto()
-	httpFallback := discovery.NewHTTPDiscovery(nil, registerDto, nil, logger)
+	// The recipient is usually among the hosts in the neighbor table, which
+	// are probed first; then the rest of the subnet need not be swept.
+	scanConfig := discovery.DefaultHTTPDiscoveryConfig()
+	scanConfig.SkipSweep = func(found []*model.Device) bool {
+		return slices.ContainsFunc(found, func(d *model.Device) bool {
+			return matchesRecipient(d, recipientAlias, fingerprint)
+		})
+	}
+	httpFallback := discovery.NewHTTPDiscovery(scanConfig, registerDto, nil, logger)
 
 	localIPs, err := network.GetLocalIPAddresses()
 	if err != nil {
-		return fmt.Errorf("failed to get local IPs: %w", err)
+		return nil, fmt.Errorf("failed to get local IPs: %w", err)
+	}
+	if cfg.SourceIP != nil {
+		// Only the subnet of the source address is reachable from it.
+		localIPs = []net.IP{cfg.SourceIP}
 	}
 
 	var ips []net.IP
@@ -137,27 +345,30 @@ func SendFiles(ctx context.Context, cfg *config.Config, filePaths []string, reci
 
 	foundDevices, err := httpFallback.ScanNetwork(scanCtx, ips, recipientPort)
 	if err != nil {
-		return fmt.Errorf("HTTP discovery failed: %w", err)
+		return nil, fmt.Errorf("HTTP discovery failed: %w", err)
 	}
 
+	candidates = nil
 	for _, device := range foundDevices {
-		if device.Alias == recipientAlias {
-			targetDevice = device
-			break
+		if matchesRecipient(device, recipientAlias, fingerprint) {
+			candidates = addCandidate(candidates, device)
 		}
 	}
 
+	targetDevice, err = resolveRecipient(recipientAlias, fingerprint, candidates)
+	if err != nil {
+		return nil, err
+	}
 	if targetDevice == nil {
-		return fmt.Errorf("recipient '%s' not found on network after scan", recipientAlias)
+		return nil, fmt.Errorf("recipient %s not found on network after scan", label)
 	}
 
 	logger.Infof("Discovered recipient via HTTP Scan: %s (%s)", targetDevice.Alias, targetDevice.IP)
 
 	if err := verifyDeviceFingerprint(peerCache, targetDevice); err != nil {
-		return err
+		return nil, err
 	}
-
-	return SendToDevice(ctx, cfg, targetDevice, filePaths, logger, opts...)
+	return targetDevice, nil
 }
 
 func SendToDevice(ctx context.Context, cfg *config.Config, device *model.Device, filePaths []string, logger *zap.SugaredLogger, opts ...SendOption) error {
@@ -165,76 +376,96 @@ func SendToDevice(ctx context.Context, cfg *config.Config, device *model.Device,
 		logger = zap.NewNop().Sugar()
 	}
 
-	client := &http.Client{}
+	var sc sendConfig
+	for _, opt := range opts {
+		opt(&sc)
+	}
+
+	// Requests go through the shared transport, so the several requests of
+	// a send, and later sends to the same device, reuse their connections.
+	client := &http.Client{Transport: capture.Transport(httputil.Transport())}
+	if sc.transport != nil {
+		client.Transport = capture.Transport(sc.transport)
+	}
 	scheme := "http"
 
-	if device.Protocol == "" {
-		addr := net.JoinHostPort(device.IP, strconv.Itoa(device.Port))
-		dialer := &net.Dialer{Timeout: 2 * time.Second}
-		conn, err := tls.DialWithDialer(dialer, "tcp", addr, &tls.Config{InsecureSkipVerify: true})
+	// A device known by address alone (e.g. send --ip) is asked for its
+	// /info over HTTPS: any answer means it serves HTTPS, and the info gives
+	// the fingerprint VerifyConnection pins the TLS certificate to. Asking
+	// over HTTP rather than dialing goes through the proxy, if one is set.
+	if device.Protocol == "" || (device.Protocol == model.ProtocolTypeHTTPS && device.Fingerprint == "") {
+		infoURL := fmt.Sprintf("https://%s/api/localsend/v2/info", network.URLHost(device.IP, device.Port))
+		infoCtx, cancelInfo := context.WithTimeout(httputil.WithDialTimeout(ctx, 2*time.Second), 5*time.Second)
+		req, err := http.NewRequestWithContext(infoCtx, http.MethodGet, infoURL, nil)
 		if err == nil {
-			conn.Close()
-			device.Protocol = model.ProtocolTypeHTTPS
-		}
-	}
-
-	// When auto-detected HTTPS with no known fingerprint (e.g. send --ip),
-	// fetch it via /info so VerifyConnection can pin the TLS certificate.
-	if device.Protocol == model.ProtocolTypeHTTPS && device.Fingerprint == "" {
-		infoAddr := net.JoinHostPort(device.IP, strconv.Itoa(device.Port))
-		infoURL := fmt.Sprintf("https://%s/api/localsend/v2/info", infoAddr)
-		infoClient := &http.Client{
-			Timeout: 5 * time.Second,
-			Transport: &http.Transport{
-				TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-			},
-		}
-		defer infoClient.CloseIdleConnections()
-		if resp, err := infoClient.Get(infoURL); err == nil {
-			var info model.InfoDto
-			if json.NewDecoder(resp.Body).Decode(&info) == nil {
-				device.Fingerprint = info.Fingerprint
+			if resp, err := client.Do(req); err == nil {
+				device.Protocol = model.ProtocolTypeHTTPS
+				var info model.InfoDto
+				if resp.StatusCode == http.StatusOK && json.NewDecoder(resp.Body).Decode(&info) == nil && device.Fingerprint == "" {
+					device.Fingerprint = info.Fingerprint
+					if device.Version == "" {
+						device.Version = info.Version
+					}
+				}
+				resp.Body.Close()
 			}
-			resp.Body.Close()
 		}
+		cancelInfo()
 	}
 
 	if device.Protocol == model.ProtocolTypeHTTPS {
 		scheme = "https"
-		tlsConfig := &tls.Config{
-			InsecureSkipVerify: true,
-		}
-		if device.Fingerprint != "" {
-			expectedFingerprint := device.Fingerprint
-			tlsConfig.VerifyConnection = func(state tls.ConnectionState) error {
-				if len(state.PeerCertificates) == 0 {
-					return fmt.Errorf("no peer certificates presented")
-				}
-				cert := state.PeerCertificates[0]
-				hash := sha256.Sum256(cert.Raw)
-				actual := hex.EncodeToString(hash[:])
-				if !strings.EqualFold(actual, expectedFingerprint) {
-					return fmt.Errorf("TLS certificate fingerprint mismatch: expected %s, got %s", expectedFingerprint, actual)
-				}
-				return nil
-			}
+		if device.Fingerprint != "" && sc.transport == nil {
+			client.Transport = capture.Transport(httputil.PinnedTransport(device.Fingerprint))
 		}
-		tr := &http.Transport{
-			TLSClientConfig: tlsConfig,
+	}
+
+	fileMap, err := getFilesWithRelativePaths(ctx, filePaths, sc.excludes)
+	if err != nil {
+		return fmt.Errorf("failed to process file paths: %w", err)
+	}
+	for filePath := range fileMap {
+		if name, ok := sc.fileNames[filePath]; ok {
+			fileMap[filePath] = name
 		}
-		client.Transport = tr
-		defer tr.CloseIdleConnections()
 	}
 
-	var sc sendConfig
-	for _, opt := range opts {
-		opt(&sc)
+	if sc.remoteName != "" {
+		if len(fileMap) != 1 {
+			return fmt.Errorf("a remote file name can only be set when sending a single file, got %d", len(fileMap))
+		}
+		for filePath := range fileMap {
+			fileMap[filePath] = sc.remoteName
+		}
 	}
 
-	fileMap, err := getFilesWithRelativePaths(filePaths)
-	if err != nil {
-		return fmt.Errorf("failed to process file paths: %w", err)
+	// Files the recipient already has are left out of the session.
+	var duplicates []FileResult
+	ledgerTarget := ""
+	if sc.ledger != nil {
+		ledgerTarget = LedgerTarget(device)
+		for filePath, remoteName := range fileMap {
+			if !sc.ledger.Delivered(ledgerTarget, filePath) {
+				continue
+			}
+			var size int64
+			if fi, err := os.Stat(filePath); err == nil {
+				size = fi.Size()
+			}
+			logger.Infof("Skipping %s: already delivered", remoteName)
+			duplicates = append(duplicates, FileResult{Name: remoteName, Path: filePath, Size: size, Status: FileDuplicate})
+			delete(fileMap, filePath)
+		}
+		slices.SortFunc(duplicates, func(a, b FileResult) int { return strings.Compare(a.Name, b.Name) })
+		if len(fileMap) == 0 && len(sc.memFiles) == 0 && len(sc.streams) == 0 && len(sc.providers) == 0 {
+			if sc.result != nil {
+				sc.result.Files = append(sc.result.Files, duplicates...)
+			}
+			return nil
+		}
 	}
+	// sourcePaths maps stripped copies back to the files they were made from.
+	sourcePaths := make(map[string]string)
 
 	// Strip EXIF/metadata from image files in private mode.
 	// StripTo writes a stripped copy to a temp file; the original is never modified.
@@ -248,6 +479,9 @@ func SendToDevice(ctx context.Context, cfg *config.Config, device *model.Device,
 
 	if cfg.Private {
 		for filePath, remoteName := range fileMap {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
 			isImg, _ := metadata.IsImageFile(filePath)
 			if !isImg {
 				continue
@@ -266,6 +500,7 @@ func SendToDevice(ctx context.Context, cfg *config.Config, device *model.Device,
 			}
 
 			stripped = append(stripped, strippedFile{tempPath: tmpPath})
+			sourcePaths[tmpPath] = filePath
 			fileMap[tmpPath] = remoteName
 			delete(fileMap, filePath)
 		}
@@ -273,9 +508,16 @@ func SendToDevice(ctx context.Context, cfg *config.Config, device *model.Device,
 
 	filesDtoMap := make(map[string]model.FileDto)
 	filePathMap := make(map[string]string)
-	memReaders := make(map[string]*memReadSeekCloser)
+	memReaders := make(map[string]io.ReadCloser) // in-memory files and streams
+	providerMap := make(map[string]storage.FileProvider)
+	previewBudget := maxPreviewBytes
 
 	for filePath, remoteName := range fileMap {
+		// Detecting types and making previews reads every file; a large
+		// send stops here too when ctx ends.
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		fileInfo, err := os.Stat(filePath)
 		if err != nil {
 			return fmt.Errorf("failed to get file info for %s: %w", filePath, err)
@@ -309,6 +551,15 @@ func SendToDevice(ctx context.Context, cfg *config.Config, device *model.Device,
 			FileType: contentType,
 			Metadata: metadataPtr,
 		}
+		if cfg.SendPreviews && strings.HasPrefix(contentType, "image/") && previewBudget > 0 {
+			preview, err := metadata.Preview(filePath)
+			if err != nil {
+				logger.Debugf("No preview for %s: %v", remoteName, err)
+			} else if preview != "" && len(preview) <= previewBudget {
+				fileDto.Preview = &preview
+				previewBudget -= len(preview)
+			}
+		}
 
 		filesDtoMap[fileDto.ID] = fileDto
 		filePathMap[fileDto.ID] = filePath
@@ -336,6 +587,67 @@ func SendToDevice(ctx context.Context, cfg *config.Config, device *model.Device,
 		memReaders[id] = &memReadSeekCloser{bytes.NewReader(mf.content)}
 	}
 
+	for _, sf := range sc.streams {
+		id := uuid.NewString()
+		br := bufio.NewReader(sf.r)
+		head, _ := br.Peek(512)
+		contentType := http.DetectContentType(head)
+
+		remoteName := sf.name
+		if cfg.Private {
+			remoteName = anonymizeFileName(contentType)
+		}
+
+		filesDtoMap[id] = model.FileDto{
+			ID:       id,
+			FileName: remoteName,
+			Size:     sf.size,
+			FileType: contentType,
+		}
+		memReaders[id] = io.NopCloser(br)
+	}
+
+	for _, p := range sc.providers {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		info, err := p.Stat()
+		if err != nil {
+			return fmt.Errorf("failed to get file info: %w", err)
+		}
+		r, err := p.Open()
+		if err != nil {
+			return fmt.Errorf("failed to open %s for detection: %w", info.Name, err)
+		}
+		head := make([]byte, 512)
+		n, _ := io.ReadFull(r, head)
+		r.Close()
+		contentType := http.DetectContentType(head[:n])
+
+		remoteName := info.Name
+		var metadataPtr *model.FileMetadata
+		if cfg.Private {
+			remoteName = anonymizeFileName(contentType)
+		} else if !info.ModTime.IsZero() {
+			modTime := info.ModTime.Format(time.RFC3339)
+			metadataPtr = &model.FileMetadata{Modified: &modTime}
+		}
+		fileDto := model.FileDto{
+			ID:       uuid.NewString(),
+			FileName: remoteName,
+			Size:     info.Size,
+			FileType: contentType,
+			Metadata: metadataPtr,
+		}
+		if sum, err := p.Hash(); err != nil {
+			return fmt.Errorf("failed to hash %s: %w", info.Name, err)
+		} else if sum != "" {
+			fileDto.SHA256 = &sum
+		}
+		filesDtoMap[fileDto.ID] = fileDto
+		providerMap[fileDto.ID] = p
+	}
+
 	infoAlias := cfg.Alias
 	infoDeviceModel := cfg.DeviceModel
 	infoDeviceType := cfg.DeviceType
@@ -350,35 +662,85 @@ func SendToDevice(ctx context.Context, cfg *config.Config, device *model.Device,
 		fingerprint = cfg.SecurityContext.CertificateHash
 	}
 
-	prepareDto := model.PrepareUploadRequestDto{
-		Info: model.InfoDto{
-			Alias:       infoAlias,
-			Version:     config.ProtocolVersion,
-			DeviceModel: infoDeviceModel,
-			DeviceType:  infoDeviceType,
-			Fingerprint: fingerprint,
-			Port:        device.Port,
-			Protocol:    model.ProtocolType(scheme),
-			Download:    true,
-		},
-		Files: filesDtoMap,
-	}
+	// Legacy LocalSend releases speak v1: a send-request with less sender
+	// information, answered with tokens alone, and uploads to /send.
+	legacy := !sc.benchmark && !device.Supports(model.FeatureSessions)
+	hostPort := network.URLHost(device.IP, device.Port)
+	var apiURL string
+	prepare := func() (*http.Response, error) {
+		var body any
+		endpoint := "/prepare-upload"
+		switch {
+		case sc.benchmark:
+			apiURL = fmt.Sprintf("%s://%s/api/localgo/v1/bench", scheme, hostPort)
+		case legacy:
+			apiURL = fmt.Sprintf("%s://%s/api/localsend/v1", scheme, hostPort)
+			endpoint = "/send-request"
+		default:
+			apiURL = fmt.Sprintf("%s://%s/api/localsend/v2", scheme, hostPort)
+		}
+		if legacy {
+			legacyFiles := make(map[string]model.FileV1Dto, len(filesDtoMap))
+			for id, dto := range filesDtoMap {
+				legacyFiles[id] = dto.ToFileV1Dto()
+			}
+			body = model.SendRequestV1Dto{
+				Info: model.InfoV1Dto{
+					Alias:       infoAlias,
+					DeviceModel: infoDeviceModel,
+					DeviceType:  infoDeviceType,
+				},
+				Files: legacyFiles,
+			}
+		} else {
+			body = model.PrepareUploadRequestDto{
+				Info: model.InfoDto{
+					Alias:       infoAlias,
+					Version:     config.ProtocolVersion,
+					DeviceModel: infoDeviceModel,
+					DeviceType:  infoDeviceType,
+					Fingerprint: fingerprint,
+					Port:        device.Port,
+					Protocol:    model.ProtocolType(scheme),
+					Download:    true,
+				},
+				Files: filesDtoMap,
+			}
+		}
 
-	jsonData, err := json.Marshal(prepareDto)
-	if err != nil {
-		return fmt.Errorf("failed to marshal prepare dto: %w", err)
-	}
+		jsonData, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal prepare dto: %w", err)
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, apiURL+endpoint, bytes.NewBuffer(jsonData))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create prepare request: %w", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
 
-	url := fmt.Sprintf("%s://%s/api/localsend/v2/prepare-upload", scheme, net.JoinHostPort(device.IP, strconv.Itoa(device.Port)))
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewBuffer(jsonData))
-	if err != nil {
-		return fmt.Errorf("failed to create prepare request: %w", err)
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("failed to send prepare request: %w", err)
+		}
+		return resp, nil
 	}
-	req.Header.Set("Content-Type", "application/json")
 
-	resp, err := client.Do(req)
+	resp, err := prepare()
 	if err != nil {
-		return fmt.Errorf("failed to send prepare request: %w", err)
+		return err
+	}
+	// A device reached by address alone has not reported its protocol
+	// version. If it lacks the v2 API, ask /info which version it speaks.
+	if resp.StatusCode == http.StatusNotFound && device.Version == "" && !sc.benchmark {
+		device.Version = probeVersion(ctx, client, scheme, hostPort)
+		if !device.Supports(model.FeatureSessions) {
+			resp.Body.Close()
+			logger.Infof("Receiver speaks protocol v%s, retrying with it", device.Version)
+			legacy = true
+			if resp, err = prepare(); err != nil {
+				return err
+			}
+		}
 	}
 	defer resp.Body.Close()
 
@@ -386,22 +748,113 @@ func SendToDevice(ctx context.Context, cfg *config.Config, device *model.Device,
 	// and no file upload is needed (content was in the Preview field).
 	if resp.StatusCode == http.StatusNoContent {
 		logger.Info("Clipboard message accepted by receiver, no upload needed")
+		if sc.result != nil {
+			sc.result.Files = append(sc.result.Files, duplicates...)
+			for _, fileID := range sortedFileIDs(filesDtoMap) {
+				dto := filesDtoMap[fileID]
+				sc.result.Files = append(sc.result.Files, FileResult{Name: dto.FileName, Path: filePathMap[fileID], Size: dto.Size, Status: FileSent})
+			}
+		}
 		return nil
 	}
 
+	if sc.benchmark && resp.StatusCode == http.StatusNotFound {
+		return ErrBenchmarkUnsupported
+	}
+	switch resp.StatusCode {
+	case http.StatusForbidden:
+		return fmt.Errorf("%w (%s)", ErrDeclined, resp.Status)
+	case http.StatusConflict:
+		// A receiver that held the request for a while says when to retry.
+		if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && secs > 0 {
+			return fmt.Errorf("%w (%s, try again in %s)", ErrBusy, resp.Status, time.Duration(secs)*time.Second)
+		}
+		return fmt.Errorf("%w (%s)", ErrBusy, resp.Status)
+	}
 	if resp.StatusCode != http.StatusOK {
 		return fmt.Errorf("prepare request failed with status: %s", resp.Status)
 	}
 
 	var prepareResponse model.PrepareUploadResponseDto
-	if err := json.NewDecoder(resp.Body).Decode(&prepareResponse); err != nil {
+	if legacy {
+		err = json.NewDecoder(resp.Body).Decode(&prepareResponse.Files)
+	} else {
+		err = json.NewDecoder(resp.Body).Decode(&prepareResponse)
+	}
+	if err != nil {
 		return fmt.Errorf("failed to decode prepare response: %w", err)
 	}
 
-	mp := cli.NewMultiProgress(int64(len(prepareResponse.Files)))
+	// A receiver that decodes compressed uploads says so on its response;
+	// only then, and only when asked to, are matching files compressed.
+	var encoding string
+	if cfg.Compress {
+		encoding = compression.Negotiate(resp.Header.Get("Accept-Encoding"))
+	}
+	policy := cfg.CompressionPolicy()
+	encodingFor := func(fileID string) string {
+		dto := filesDtoMap[fileID]
+		if encoding == "" || !policy.Compressible(dto.FileName, dto.FileType, dto.Size) {
+			return ""
+		}
+		return encoding
+	}
+
+	var totalSize int64
+	for fileID := range prepareResponse.Files {
+		totalSize += filesDtoMap[fileID].Size
+	}
+	mp := cli.NewSessionProgress("send", prepareResponse.SessionID, len(prepareResponse.Files), totalSize)
+	// addBar adds the progress bar of a file, feeding WithProgress too.
+	var progressMu sync.Mutex
+	var sentTotal int64
+	addBar := func(name string, size int64) func(int64) {
+		track := mp.AddBar(name, size)
+		if sc.progress == nil {
+			return track
+		}
+		var last int64 // a retried upload counts from 0 again
+		return func(n int64) {
+			track(n)
+			progressMu.Lock()
+			sentTotal += n - last
+			last = n
+			sent := sentTotal
+			progressMu.Unlock()
+			sc.progress(sent, totalSize)
+		}
+	}
+
+	// Outcomes are recorded per file ID; files the receiver did not hand out a
+	// token for were declined at prepare-upload time.
+	fileIDs := sortedFileIDs(filesDtoMap)
+	results := make(map[string]FileResult, len(fileIDs))
+	var resultsMu sync.Mutex
+	setResult := func(fileID string, status FileStatus, err error) {
+		dto := filesDtoMap[fileID]
+		resultsMu.Lock()
+		results[fileID] = FileResult{Name: dto.FileName, Path: filePathMap[fileID], Size: dto.Size, Status: status, Err: err}
+		resultsMu.Unlock()
+	}
+	for _, fileID := range fileIDs {
+		if _, ok := prepareResponse.Files[fileID]; !ok {
+			logger.Infof("Receiver declined file: %s", filesDtoMap[fileID].FileName)
+			cli.EmitEvent(cli.ProgressEvent{Event: cli.EventFileRejected, Direction: "send", SessionID: prepareResponse.SessionID, File: filesDtoMap[fileID].FileName})
+			setResult(fileID, FileRejected, nil)
+		}
+	}
+	for fileID := range prepareResponse.Files {
+		if _, ok := filesDtoMap[fileID]; !ok {
+			logger.Warnf("Server responded with unknown file ID: %s", fileID)
+		}
+	}
+
+	// With fail-fast, the first failure stops uploads that have not started;
+	// uploads already in flight are left to finish.
+	uploadCtx, cancelUploads := context.WithCancel(ctx)
+	defer cancelUploads()
 
 	var wg sync.WaitGroup
-	errCh := make(chan error, len(prepareResponse.Files))
 
 	concurrency := cfg.Concurrency
 	if concurrency <= 0 {
@@ -409,69 +862,217 @@ func SendToDevice(ctx context.Context, cfg *config.Config, device *model.Device,
 	}
 	sem := make(chan struct{}, concurrency)
 
-	for fileID, token := range prepareResponse.Files {
+	// upload runs one transfer under the concurrency limit and records its outcome.
+	upload := func(fileID, name string, do func() error) {
+		defer wg.Done()
+
+		select {
+		case sem <- struct{}{}:
+		case <-uploadCtx.Done():
+			setResult(fileID, FileSkipped, nil)
+			return
+		}
+		defer func() { <-sem }()
+
+		if uploadCtx.Err() != nil {
+			setResult(fileID, FileSkipped, nil)
+			return
+		}
+
+		if err := do(); err != nil {
+			logger.Errorf("Failed to upload %s: %v", name, err)
+			cli.EmitEvent(cli.ProgressEvent{Event: cli.EventFileFailed, Direction: "send", SessionID: prepareResponse.SessionID, File: name, Error: err.Error()})
+			setResult(fileID, FileFailed, err)
+			// Once the receiver has cancelled, no other upload can succeed.
+			if sc.failFast || errors.Is(err, ErrCancelled) {
+				cancelUploads()
+			}
+			return
+		}
+		setResult(fileID, FileSent, nil)
+	}
+
+	// Upload in a stable order so progress output is predictable.
+	for _, fileID := range fileIDs {
+		token, accepted := prepareResponse.Files[fileID]
+		if !accepted {
+			continue
+		}
 		if reader, ok := memReaders[fileID]; ok {
 			displayName := filesDtoMap[fileID].FileName
 			fileSize := filesDtoMap[fileID].Size
-			trackProgress := mp.AddBar(displayName, fileSize)
+			trackProgress := addBar(displayName, fileSize)
 
 			wg.Add(1)
-			go func(fID, tkn string, rdr *memReadSeekCloser, sz int64, name string, track func(int64)) {
-				defer wg.Done()
-
-				sem <- struct{}{}
-				defer func() { <-sem }()
-
-				logger.Infof("Uploading in-memory file: %s", name)
-				err := uploadStream(ctx, client, device, rdr, sz, fID, prepareResponse.SessionID, tkn, scheme, track, logger)
-				if err != nil {
-					logger.Errorf("Failed to upload %s: %v", name, err)
-					errCh <- fmt.Errorf("failed to upload %s: %w", name, err)
+			go upload(fileID, displayName, func() error {
+				logger.Infof("Uploading stream: %s", displayName)
+				seeker, ok := reader.(io.Seeker)
+				if !ok {
+					// A stream is read once; it can't be sent again.
+					return uploadStream(ctx, client, apiURL, reader, fileSize, fileID, prepareResponse.SessionID, token, encodingFor(fileID), trackProgress, logger)
 				}
-			}(fileID, token, reader, fileSize, displayName, trackProgress)
+				return withUploadRetry(ctx, logger, func() error {
+					if _, err := seeker.Seek(0, io.SeekStart); err != nil {
+						return err
+					}
+					return uploadStream(ctx, client, apiURL, reader, fileSize, fileID, prepareResponse.SessionID, token, encodingFor(fileID), trackProgress, logger)
+				})
+			})
+		} else if p, ok := providerMap[fileID]; ok {
+			displayName := filesDtoMap[fileID].FileName
+			trackProgress := addBar(displayName, filesDtoMap[fileID].Size)
+
+			wg.Add(1)
+			go upload(fileID, displayName, func() error {
+				logger.Infof("Uploading file: %s", displayName)
+				return uploadProvider(ctx, client, apiURL, p, fileID, prepareResponse.SessionID, token, encodingFor(fileID), trackProgress, logger)
+			})
 		} else if filePath, exists := filePathMap[fileID]; exists {
 			var fileSize int64
 			if fi, err := os.Stat(filePath); err == nil {
 				fileSize = fi.Size()
 			}
-			trackProgress := mp.AddBar(filepath.Base(filePath), fileSize)
+			trackProgress := addBar(filepath.Base(filePath), fileSize)
 
 			wg.Add(1)
-			go func(fID, tkn, fPath string, track func(int64)) {
-				defer wg.Done()
-
-				sem <- struct{}{}
-				defer func() { <-sem }()
-
-				logger.Infof("Uploading file: %s", filepath.Base(fPath))
-				err := uploadFile(ctx, client, device, fPath, fID, prepareResponse.SessionID, tkn, scheme, track, logger)
-				if err != nil {
-					logger.Errorf("Failed to upload file %s: %v", filepath.Base(fPath), err)
-					errCh <- fmt.Errorf("failed to upload %s: %w", filepath.Base(fPath), err)
-				}
-			}(fileID, token, filePath, trackProgress)
-		} else {
-			logger.Warnf("Server responded with unknown file ID: %s", fileID)
-			continue
+			go upload(fileID, filepath.Base(filePath), func() error {
+				logger.Infof("Uploading file: %s", filepath.Base(filePath))
+				return uploadProvider(ctx, client, apiURL, storage.DiskFile(filePath), fileID, prepareResponse.SessionID, token, encodingFor(fileID), trackProgress, logger)
+			})
 		}
 	}
 
 	wg.Wait()
 	mp.ForceComplete()
 	mp.Wait()
-	close(errCh)
 
-	var uploadErrors []error
-	for err := range errCh {
-		uploadErrors = append(uploadErrors, err)
+	// Stopped before every file was sent: tell the receiver, so it does
+	// not keep the session open waiting for the rest.
+	if ctx.Err() != nil {
+		for _, r := range results {
+			if r.Status == FileFailed || r.Status == FileSkipped {
+				cancelSession(ctx, client, apiURL, prepareResponse.SessionID, logger)
+				break
+			}
+		}
 	}
 
-	if len(uploadErrors) > 0 {
-		return fmt.Errorf("encountered %d upload errors, first error: %w", len(uploadErrors), uploadErrors[0])
+	result := sc.result
+	if result == nil {
+		result = &SendResult{}
+	}
+	result.Files = append(result.Files, duplicates...)
+	for _, fileID := range fileIDs {
+		result.Files = append(result.Files, results[fileID])
+	}
+	if sc.ledger != nil {
+		for _, f := range result.Files {
+			if f.Status != FileSent || f.Path == "" {
+				continue
+			}
+			path := f.Path
+			if source, ok := sourcePaths[path]; ok {
+				path = source
+			}
+			if err := sc.ledger.Record(ledgerTarget, path); err != nil {
+				logger.Warnf("Failed to record %s in the send ledger: %v", f.Name, err)
+			}
+		}
 	}
 
+	if result.Count(FileFailed) > 0 {
+		return &PartialFailureError{Result: result}
+	}
+
+	cli.EmitEvent(cli.ProgressEvent{Event: cli.EventSessionCompleted, Direction: "send", SessionID: prepareResponse.SessionID, Files: len(prepareResponse.Files), Total: totalSize})
 	logger.Info("All files uploaded successfully!")
 	return nil
 }
 
+// scanPortFor returns the port most recently advertised by a cached peer
+// matching the alias and fingerprint, or fallback if none is known.
+func scanPortFor(peers []*model.Device, alias, fingerprint string, fallback int) int {
+	var best *model.Device
+	for _, p := range peers {
+		if !matchesRecipient(p, alias, fingerprint) || p.Port == 0 {
+			continue
+		}
+		if best == nil || p.GetLastSeen().After(best.GetLastSeen()) {
+			best = p
+		}
+	}
+	if best == nil {
+		return fallback
+	}
+	return best.Port
+}
 
+// cancelTimeout bounds the cancel request sent when a send is stopped.
+const cancelTimeout = 2 * time.Second
+
+// cancelSession tells the receiver under apiURL that the session is
+// abandoned. ctx has usually ended by then, so the request only keeps its
+// values and gets a short timeout of its own. A v1 session has no ID; the
+// receiver cancels the sender's session.
+func cancelSession(ctx context.Context, client *http.Client, apiURL, sessionID string, logger *zap.SugaredLogger) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), cancelTimeout)
+	defer cancel()
+	cancelURL := apiURL + "/cancel"
+	if sessionID != "" {
+		cancelURL += "?sessionId=" + url.QueryEscape(sessionID)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, cancelURL, nil)
+	if err != nil {
+		return
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		logger.Debugf("Failed to cancel session %s on the receiver: %v", sessionID, err)
+		return
+	}
+	resp.Body.Close()
+	logger.Infof("Cancelled session %s on the receiver", sessionID)
+}
+
+// probeVersion returns the protocol version the device at hostPort reports
+// on /info: its v2 version, "1.0" if it only has the v1 API, or "" if
+// neither answers.
+func probeVersion(ctx context.Context, client *http.Client, scheme, hostPort string) string {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	get := func(path string) (string, bool) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s://%s/api/localsend/%s/info", scheme, hostPort, path), nil)
+		if err != nil {
+			return "", false
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return "", false
+		}
+		defer resp.Body.Close()
+		var info model.InfoDto
+		if resp.StatusCode != http.StatusOK || json.NewDecoder(resp.Body).Decode(&info) != nil {
+			return "", false
+		}
+		return info.Version, true
+	}
+	if version, ok := get("v2"); ok {
+		return version
+	}
+	if _, ok := get("v1"); ok {
+		return "1.0"
+	}
+	return ""
+}
+
+// sortedFileIDs returns the IDs in files ordered by remote file name.
+func sortedFileIDs(files map[string]model.FileDto) []string {
+	ids := make([]string, 0, len(files))
+	for id := range files {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return files[ids[i]].FileName < files[ids[j]].FileName
+	})
+	return ids
+}
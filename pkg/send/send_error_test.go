@@ -3,12 +3,15 @@ package send
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -130,3 +133,289 @@ func TestSendFiles_PartialUploadError(t *testing.T) {
 		t.Errorf("expected error about encountering upload errors, got: %v", err)
 	}
 }
+
+func newResultTestServer(t *testing.T, accept func(name string) bool, failUpload bool) (*model.Device, func()) {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/localsend/v2/prepare-upload":
+			var req model.PrepareUploadRequestDto
+			json.NewDecoder(r.Body).Decode(&req)
+
+			respFiles := make(map[string]string)
+			for id, f := range req.Files {
+				if accept(f.FileName) {
+					respFiles[id] = "token"
+				}
+			}
+			json.NewEncoder(w).Encode(model.PrepareUploadResponseDto{SessionID: "sess", Files: respFiles})
+		case "/api/localsend/v2/upload":
+			if failUpload {
+				http.Error(w, "Upload failed", http.StatusInternalServerError)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		}
+	}))
+
+	host := strings.TrimPrefix(server.URL, "http://")
+	port, _ := strconv.Atoi(strings.Split(host, ":")[1])
+	device := &model.Device{
+		IP:       strings.Split(host, ":")[0],
+		Port:     port,
+		Protocol: model.ProtocolTypeHTTP,
+	}
+	return device, server.Close
+}
+
+func TestSendToDevice_ReportsRejectedFiles(t *testing.T) {
+	tempDir := t.TempDir()
+	keep := filepath.Join(tempDir, "a.txt")
+	drop := filepath.Join(tempDir, "b.txt")
+	os.WriteFile(keep, []byte("a"), 0644)
+	os.WriteFile(drop, []byte("b"), 0644)
+
+	device, closeServer := newResultTestServer(t, func(name string) bool { return name == "a.txt" }, false)
+	defer closeServer()
+
+	cfg := &config.Config{SecurityContext: &crypto.StoredSecurityContext{}}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var result SendResult
+	if err := SendToDevice(ctx, cfg, device, []string{keep, drop}, testLoggerSendErrors, WithResult(&result)); err != nil {
+		t.Fatalf("SendToDevice failed: %v", err)
+	}
+
+	if len(result.Files) != 2 {
+		t.Fatalf("expected 2 results, got %d", len(result.Files))
+	}
+	if result.Files[0].Name != "a.txt" || result.Files[0].Status != FileSent {
+		t.Errorf("unexpected result for a.txt: %+v", result.Files[0])
+	}
+	if result.Files[1].Name != "b.txt" || result.Files[1].Status != FileRejected {
+		t.Errorf("unexpected result for b.txt: %+v", result.Files[1])
+	}
+}
+
+func TestSendToDevice_FailFastSkipsRemaining(t *testing.T) {
+	tempDir := t.TempDir()
+	var paths []string
+	for _, name := range []string{"a.txt", "b.txt", "c.txt"} {
+		p := filepath.Join(tempDir, name)
+		os.WriteFile(p, []byte(name), 0644)
+		paths = append(paths, p)
+	}
+
+	device, closeServer := newResultTestServer(t, func(string) bool { return true }, true)
+	defer closeServer()
+
+	cfg := &config.Config{SecurityContext: &crypto.StoredSecurityContext{}, Concurrency: 1}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var result SendResult
+	err := SendToDevice(ctx, cfg, device, paths, testLoggerSendErrors, WithResult(&result), WithFailFast())
+
+	var partial *PartialFailureError
+	if !errors.As(err, &partial) {
+		t.Fatalf("expected PartialFailureError, got %v", err)
+	}
+	if result.Count(FileFailed) != 1 {
+		t.Errorf("expected 1 failed file, got %d", result.Count(FileFailed))
+	}
+	if result.Count(FileSkipped) != 2 {
+		t.Errorf("expected 2 skipped files, got %d", result.Count(FileSkipped))
+	}
+}
+
+func TestSendToDevice_ReceiverOutcomes(t *testing.T) {
+	tempDir := t.TempDir()
+	var paths []string
+	for _, name := range []string{"a.txt", "b.txt", "c.txt"} {
+		p := filepath.Join(tempDir, name)
+		os.WriteFile(p, []byte(name), 0644)
+		paths = append(paths, p)
+	}
+
+	tests := []struct {
+		name          string
+		prepareStatus int
+		uploadStatus  int
+		want          error
+		outcome       string
+	}{
+		{"declined", http.StatusForbidden, 0, ErrDeclined, "declined"},
+		{"busy", http.StatusConflict, 0, ErrBusy, "busy"},
+		{"cancelled mid-way", http.StatusOK, http.StatusForbidden, ErrCancelled, "cancelled"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/api/localsend/v2/prepare-upload":
+					if tt.prepareStatus != http.StatusOK {
+						w.WriteHeader(tt.prepareStatus)
+						return
+					}
+					var req model.PrepareUploadRequestDto
+					json.NewDecoder(r.Body).Decode(&req)
+					resp := model.PrepareUploadResponseDto{SessionID: "s1", Files: map[string]string{}}
+					for id := range req.Files {
+						resp.Files[id] = "token"
+					}
+					json.NewEncoder(w).Encode(resp)
+				case "/api/localsend/v2/upload":
+					io.Copy(io.Discard, r.Body)
+					w.WriteHeader(tt.uploadStatus)
+				}
+			}))
+			defer server.Close()
+
+			host := strings.TrimPrefix(server.URL, "http://")
+			port, _ := strconv.Atoi(strings.Split(host, ":")[1])
+			device := &model.Device{IP: strings.Split(host, ":")[0], Port: port, Protocol: model.ProtocolTypeHTTP}
+			cfg := &config.Config{SecurityContext: &crypto.StoredSecurityContext{}, Concurrency: 1}
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			var result SendResult
+			err := SendToDevice(ctx, cfg, device, paths, testLoggerSendErrors, WithResult(&result))
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, err)
+			}
+			if got := Outcome(err); got != tt.outcome {
+				t.Errorf("expected outcome %q, got %q", tt.outcome, got)
+			}
+			if tt.outcome == "cancelled" && (result.Count(FileFailed) != 1 || result.Count(FileSkipped) != 2) {
+				t.Errorf("expected the first upload to fail and the rest to be skipped, got %+v", result.Files)
+			}
+		})
+	}
+}
+
+func TestSendToDevice_RetriesBusyUploads(t *testing.T) {
+	tempDir := t.TempDir()
+	p := filepath.Join(tempDir, "a.txt")
+	os.WriteFile(p, []byte("hello"), 0644)
+
+	var uploads int
+	var mu sync.Mutex
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/localsend/v2/prepare-upload":
+			var req model.PrepareUploadRequestDto
+			json.NewDecoder(r.Body).Decode(&req)
+			resp := model.PrepareUploadResponseDto{SessionID: "s1", Files: map[string]string{}}
+			for id := range req.Files {
+				resp.Files[id] = "token"
+			}
+			json.NewEncoder(w).Encode(resp)
+		case "/api/localsend/v2/upload":
+			body, _ := io.ReadAll(r.Body)
+			mu.Lock()
+			uploads++
+			first := uploads == 1
+			mu.Unlock()
+			if first {
+				// At the receiver's limit of parallel uploads.
+				w.Header().Set("Retry-After", "1")
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+			if string(body) != "hello" {
+				t.Errorf("retried upload body = %q, want %q", body, "hello")
+			}
+		}
+	}))
+	defer server.Close()
+
+	host := strings.TrimPrefix(server.URL, "http://")
+	port, _ := strconv.Atoi(strings.Split(host, ":")[1])
+	device := &model.Device{IP: strings.Split(host, ":")[0], Port: port, Protocol: model.ProtocolTypeHTTP}
+	cfg := &config.Config{SecurityContext: &crypto.StoredSecurityContext{}, Concurrency: 1}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := SendToDevice(ctx, cfg, device, []string{p}, testLoggerSendErrors); err != nil {
+		t.Fatalf("expected the busy upload to be retried, got %v", err)
+	}
+	if uploads != 2 {
+		t.Errorf("expected 2 upload attempts, got %d", uploads)
+	}
+}
+
+func TestSendToDevice_ContextBoundsPrepare(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "a.txt")
+	os.WriteFile(p, []byte("hello"), 0644)
+
+	// A receiver that never answers, like one waiting for its user.
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	host := strings.TrimPrefix(server.URL, "http://")
+	port, _ := strconv.Atoi(strings.Split(host, ":")[1])
+	device := &model.Device{IP: strings.Split(host, ":")[0], Port: port, Protocol: model.ProtocolTypeHTTP}
+	cfg := &config.Config{SecurityContext: &crypto.StoredSecurityContext{}}
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err := SendToDevice(ctx, cfg, device, []string{p}, testLoggerSendErrors)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the deadline to end the send, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("send took %s to give up", elapsed)
+	}
+}
+
+func TestSendToDevice_CancelledUploadCancelsSession(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "a.txt")
+	os.WriteFile(p, []byte("hello"), 0644)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cancelled := make(chan string, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/localsend/v2/prepare-upload":
+			var req model.PrepareUploadRequestDto
+			json.NewDecoder(r.Body).Decode(&req)
+			resp := model.PrepareUploadResponseDto{SessionID: "s1", Files: map[string]string{}}
+			for id := range req.Files {
+				resp.Files[id] = "token"
+			}
+			json.NewEncoder(w).Encode(resp)
+		case "/api/localsend/v2/upload":
+			io.ReadAll(r.Body)
+			// The sender is stopped while the upload is in flight.
+			cancel()
+			<-r.Context().Done()
+		case "/api/localsend/v2/cancel":
+			cancelled <- r.URL.Query().Get("sessionId")
+		}
+	}))
+	defer server.Close()
+
+	host := strings.TrimPrefix(server.URL, "http://")
+	port, _ := strconv.Atoi(strings.Split(host, ":")[1])
+	device := &model.Device{IP: strings.Split(host, ":")[0], Port: port, Protocol: model.ProtocolTypeHTTP}
+	cfg := &config.Config{SecurityContext: &crypto.StoredSecurityContext{}}
+
+	err := SendToDevice(ctx, cfg, device, []string{p}, testLoggerSendErrors)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected the cancellation to end the send, got %v", err)
+	}
+	select {
+	case id := <-cancelled:
+		if id != "s1" {
+			t.Errorf("cancelled session %q, want s1", id)
+		}
+	default:
+		t.Error("receiver was not told the session was cancelled")
+	}
+}
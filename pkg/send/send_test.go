@@ -1,8 +1,14 @@
 package send
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
+	"crypto/rand"
 	"encoding/json"
+	"errors"
+	"image"
+	"image/png"
 	"io"
 	"net/http"
 	"net/http/httptest"
@@ -10,12 +16,15 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/bethropolis/localgo/pkg/config"
 	"github.com/bethropolis/localgo/pkg/crypto"
+	"github.com/bethropolis/localgo/pkg/metadata"
 	"github.com/bethropolis/localgo/pkg/model"
+	"github.com/bethropolis/localgo/pkg/storage"
 	"go.uber.org/zap"
 )
 
@@ -114,3 +123,436 @@ func TestSendFiles_HappyPath(t *testing.T) {
 		t.Fatalf("SendToDevice failed: %v", err)
 	}
 }
+
+func TestSendToDevice_RemoteName(t *testing.T) {
+	tempDir := t.TempDir()
+	filePath := filepath.Join(tempDir, "report_final_v3.pdf")
+	if err := os.WriteFile(filePath, []byte("pdf"), 0644); err != nil {
+		t.Fatalf("failed to write test file: %v", err)
+	}
+
+	var gotName string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/localsend/v2/prepare-upload":
+			var req model.PrepareUploadRequestDto
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, "Bad Request", http.StatusBadRequest)
+				return
+			}
+			files := make(map[string]string)
+			for id, f := range req.Files {
+				gotName = f.FileName
+				files[id] = "token"
+			}
+			json.NewEncoder(w).Encode(model.PrepareUploadResponseDto{SessionID: "session", Files: files})
+		case "/api/localsend/v2/upload":
+			io.Copy(io.Discard, r.Body)
+			w.WriteHeader(http.StatusOK)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	host := strings.TrimPrefix(server.URL, "http://")
+	port, _ := strconv.Atoi(strings.Split(host, ":")[1])
+	cfg := &config.Config{
+		Alias: "Sender",
+		SecurityContext: &crypto.StoredSecurityContext{
+			CertificateHash: "hash",
+		},
+	}
+	device := &model.Device{
+		IP:       strings.Split(host, ":")[0],
+		Port:     port,
+		Protocol: model.ProtocolTypeHTTP,
+		Alias:    "Receiver",
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err := SendToDevice(ctx, cfg, device, []string{filePath}, testLoggerSend, WithRemoteName("report.pdf"))
+	if err != nil {
+		t.Fatalf("SendToDevice failed: %v", err)
+	}
+	if gotName != "report.pdf" {
+		t.Errorf("expected remote name report.pdf, got %q", gotName)
+	}
+	if _, err := os.Stat(filePath); err != nil {
+		t.Errorf("local file should be untouched: %v", err)
+	}
+}
+
+func TestScanPortFor(t *testing.T) {
+	now := time.Now()
+	peers := []*model.Device{
+		{Alias: "Phone", Port: 53317, LastSeen: now.Add(-time.Hour)},
+		{Alias: "Phone", Port: 53318, LastSeen: now},
+		{Alias: "Laptop", Port: 8080, LastSeen: now},
+	}
+
+	if got := scanPortFor(peers, "Phone", "", config.DefaultPort); got != 53318 {
+		t.Errorf("expected most recently seen port 53318, got %d", got)
+	}
+	if got := scanPortFor(peers, "Tablet", "", config.DefaultPort); got != config.DefaultPort {
+		t.Errorf("expected default port for unknown alias, got %d", got)
+	}
+}
+
+func TestSendToDevice_Stream(t *testing.T) {
+	content := "streamed backup data"
+
+	var gotName string
+	var gotSize int64
+	var gotBody string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/localsend/v2/prepare-upload":
+			var req model.PrepareUploadRequestDto
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, "Bad Request", http.StatusBadRequest)
+				return
+			}
+			files := make(map[string]string)
+			for id, f := range req.Files {
+				gotName, gotSize = f.FileName, f.Size
+				files[id] = "token"
+			}
+			json.NewEncoder(w).Encode(model.PrepareUploadResponseDto{SessionID: "session", Files: files})
+		case "/api/localsend/v2/upload":
+			body, _ := io.ReadAll(r.Body)
+			gotBody = string(body)
+			w.WriteHeader(http.StatusOK)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	host := strings.TrimPrefix(server.URL, "http://")
+	port, _ := strconv.Atoi(strings.Split(host, ":")[1])
+	cfg := &config.Config{SecurityContext: &crypto.StoredSecurityContext{}}
+	device := &model.Device{
+		IP:       strings.Split(host, ":")[0],
+		Port:     port,
+		Protocol: model.ProtocolTypeHTTP,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	stream := io.MultiReader(strings.NewReader(content))
+	err := SendToDevice(ctx, cfg, device, nil, testLoggerSend, WithStream("backup.tar", stream, int64(len(content))))
+	if err != nil {
+		t.Fatalf("SendToDevice failed: %v", err)
+	}
+	if gotName != "backup.tar" || gotSize != int64(len(content)) {
+		t.Errorf("unexpected file dto: name=%q size=%d", gotName, gotSize)
+	}
+	if gotBody != content {
+		t.Errorf("unexpected upload body: %q", gotBody)
+	}
+}
+
+func TestSendToDevice_Benchmark(t *testing.T) {
+	const content = "benchmark data"
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		switch r.URL.Path {
+		case "/api/localgo/v1/bench/prepare-upload":
+			var req model.PrepareUploadRequestDto
+			json.NewDecoder(r.Body).Decode(&req)
+			files := make(map[string]string)
+			for id := range req.Files {
+				files[id] = "token"
+			}
+			json.NewEncoder(w).Encode(model.PrepareUploadResponseDto{SessionID: "session", Files: files})
+		case "/api/localgo/v1/bench/upload":
+			io.Copy(io.Discard, r.Body)
+			w.WriteHeader(http.StatusOK)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	host := strings.TrimPrefix(server.URL, "http://")
+	port, _ := strconv.Atoi(strings.Split(host, ":")[1])
+	cfg := &config.Config{SecurityContext: &crypto.StoredSecurityContext{}}
+	device := &model.Device{IP: strings.Split(host, ":")[0], Port: port, Protocol: model.ProtocolTypeHTTP}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err := SendToDevice(ctx, cfg, device, nil, testLoggerSend, WithStream("bench.bin", strings.NewReader(content), int64(len(content))), WithBenchmark())
+	if err != nil {
+		t.Fatalf("SendToDevice failed: %v", err)
+	}
+	if len(paths) != 2 || paths[1] != "/api/localgo/v1/bench/upload" {
+		t.Errorf("unexpected requests: %v", paths)
+	}
+
+	// A plain LocalSend receiver has no speed test endpoints.
+	server.Config.Handler = http.NotFoundHandler()
+	err = SendToDevice(ctx, cfg, device, nil, testLoggerSend, WithStream("bench.bin", strings.NewReader(content), int64(len(content))), WithBenchmark())
+	if !errors.Is(err, ErrBenchmarkUnsupported) {
+		t.Errorf("got %v, want ErrBenchmarkUnsupported", err)
+	}
+}
+
+func TestSendToDevice_Compress(t *testing.T) {
+	dir := t.TempDir()
+	text := strings.Repeat("a line of text that compresses well\n", 2000)
+	binary := make([]byte, 64*1024)
+	rand.Read(binary)
+	os.WriteFile(filepath.Join(dir, "notes.txt"), []byte(text), 0644)
+	os.WriteFile(filepath.Join(dir, "photo.bin"), binary, 0644)
+
+	for _, advertise := range []bool{true, false} {
+		var mu sync.Mutex
+		encodings := make(map[string]string)
+		names := make(map[string]string)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/api/localsend/v2/prepare-upload":
+				var req model.PrepareUploadRequestDto
+				json.NewDecoder(r.Body).Decode(&req)
+				files := make(map[string]string)
+				for id, f := range req.Files {
+					names[id] = f.FileName
+					files[id] = "token-" + id
+				}
+				if advertise {
+					w.Header().Set("Accept-Encoding", "gzip")
+				}
+				json.NewEncoder(w).Encode(model.PrepareUploadResponseDto{SessionID: "session", Files: files})
+			case "/api/localsend/v2/upload":
+				var body io.Reader = r.Body
+				if r.Header.Get("Content-Encoding") == "gzip" {
+					zr, err := gzip.NewReader(r.Body)
+					if err != nil {
+						http.Error(w, err.Error(), http.StatusBadRequest)
+						return
+					}
+					body = zr
+				}
+				data, _ := io.ReadAll(body)
+				name := names[r.URL.Query().Get("fileId")]
+				want, _ := os.ReadFile(filepath.Join(dir, name))
+				if !bytes.Equal(data, want) {
+					http.Error(w, "content mismatch", http.StatusBadRequest)
+					return
+				}
+				mu.Lock()
+				encodings[name] = r.Header.Get("Content-Encoding")
+				mu.Unlock()
+			}
+		}))
+
+		host := strings.TrimPrefix(server.URL, "http://")
+		port, _ := strconv.Atoi(strings.Split(host, ":")[1])
+		cfg := &config.Config{
+			SecurityContext: &crypto.StoredSecurityContext{},
+			Compress:        true,
+			CompressMinSize: 1024,
+			CompressTypes:   []string{"text/*"},
+		}
+		device := &model.Device{IP: "127.0.0.1", Port: port, Protocol: model.ProtocolTypeHTTP}
+		err := SendToDevice(context.Background(), cfg, device, []string{filepath.Join(dir, "notes.txt"), filepath.Join(dir, "photo.bin")}, testLoggerSend)
+		server.Close()
+		if err != nil {
+			t.Fatalf("advertised=%v: SendToDevice failed: %v", advertise, err)
+		}
+
+		wantText := ""
+		if advertise {
+			wantText = "gzip"
+		}
+		if encodings["notes.txt"] != wantText {
+			t.Errorf("advertised=%v: notes.txt sent with encoding %q, want %q", advertise, encodings["notes.txt"], wantText)
+		}
+		if encodings["photo.bin"] != "" {
+			t.Errorf("advertised=%v: photo.bin compressed with %q", advertise, encodings["photo.bin"])
+		}
+	}
+}
+
+func TestSendToDevice_Previews(t *testing.T) {
+	dir := t.TempDir()
+	img := image.NewRGBA(image.Rect(0, 0, 300, 200))
+	var buf bytes.Buffer
+	png.Encode(&buf, img)
+	os.WriteFile(filepath.Join(dir, "photo.png"), buf.Bytes(), 0644)
+	os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("hello"), 0644)
+
+	for _, enabled := range []bool{true, false} {
+		previews := make(map[string]*string)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/api/localsend/v2/prepare-upload" {
+				var req model.PrepareUploadRequestDto
+				json.NewDecoder(r.Body).Decode(&req)
+				for _, f := range req.Files {
+					previews[f.FileName] = f.Preview
+				}
+				// Decline every file: only the request matters here.
+				json.NewEncoder(w).Encode(model.PrepareUploadResponseDto{SessionID: "session", Files: map[string]string{}})
+			}
+		}))
+
+		host := strings.TrimPrefix(server.URL, "http://")
+		port, _ := strconv.Atoi(strings.Split(host, ":")[1])
+		cfg := &config.Config{SecurityContext: &crypto.StoredSecurityContext{}, SendPreviews: enabled}
+		device := &model.Device{IP: "127.0.0.1", Port: port, Protocol: model.ProtocolTypeHTTP}
+		err := SendToDevice(context.Background(), cfg, device, []string{filepath.Join(dir, "photo.png"), filepath.Join(dir, "notes.txt")}, testLoggerSend)
+		server.Close()
+		if err != nil {
+			t.Fatalf("enabled=%v: SendToDevice failed: %v", enabled, err)
+		}
+
+		if previews["notes.txt"] != nil {
+			t.Errorf("enabled=%v: expected no preview for a text file", enabled)
+		}
+		if !enabled {
+			if previews["photo.png"] != nil {
+				t.Error("expected no preview when previews are off")
+			}
+			continue
+		}
+		if previews["photo.png"] == nil {
+			t.Fatal("expected a preview for photo.png")
+		}
+		if _, err := metadata.DecodePreview(*previews["photo.png"]); err != nil {
+			t.Errorf("preview does not decode: %v", err)
+		}
+	}
+}
+
+func TestSendToDevice_LegacyV1(t *testing.T) {
+	tempDir := t.TempDir()
+	filePath := filepath.Join(tempDir, "photo.png")
+	if err := os.WriteFile(filePath, []byte("\x89PNG\r\n\x1a\nimage"), 0644); err != nil {
+		t.Fatalf("failed to write test file: %v", err)
+	}
+
+	for _, version := range []string{"1.0", ""} {
+		t.Run("version="+version, func(t *testing.T) {
+			var gotRequest model.SendRequestV1Dto
+			var uploaded []byte
+			var v2Requests int
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/api/localsend/v1/info":
+					json.NewEncoder(w).Encode(model.InfoV1Dto{Alias: "Old Phone", DeviceType: model.DeviceTypeMobile})
+				case "/api/localsend/v1/send-request":
+					if err := json.NewDecoder(r.Body).Decode(&gotRequest); err != nil {
+						http.Error(w, "Bad Request", http.StatusBadRequest)
+						return
+					}
+					files := make(map[string]string)
+					for id := range gotRequest.Files {
+						files[id] = "token-" + id
+					}
+					json.NewEncoder(w).Encode(files)
+				case "/api/localsend/v1/send":
+					if r.URL.Query().Get("token") != "token-"+r.URL.Query().Get("fileId") {
+						http.Error(w, "Invalid token", http.StatusForbidden)
+						return
+					}
+					uploaded, _ = io.ReadAll(r.Body)
+					w.WriteHeader(http.StatusOK)
+				default:
+					v2Requests++
+					http.NotFound(w, r)
+				}
+			}))
+			defer server.Close()
+
+			host := strings.TrimPrefix(server.URL, "http://")
+			port, _ := strconv.Atoi(strings.Split(host, ":")[1])
+			cfg := &config.Config{Alias: "Sender"}
+			device := &model.Device{
+				IP:       strings.Split(host, ":")[0],
+				Port:     port,
+				Protocol: model.ProtocolTypeHTTP,
+				Version:  version,
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := SendToDevice(ctx, cfg, device, []string{filePath}, testLoggerSend); err != nil {
+				t.Fatalf("SendToDevice failed: %v", err)
+			}
+
+			if gotRequest.Info.Alias != "Sender" || len(gotRequest.Files) != 1 {
+				t.Fatalf("unexpected send-request: %+v", gotRequest)
+			}
+			for _, f := range gotRequest.Files {
+				if f.FileName != "photo.png" || f.FileType != model.LegacyFileTypeImage {
+					t.Errorf("expected photo.png as a v1 image, got %+v", f)
+				}
+			}
+			if string(uploaded) != "\x89PNG\r\n\x1a\nimage" {
+				t.Errorf("uploaded %q", uploaded)
+			}
+			if version != "" && v2Requests > 0 {
+				t.Errorf("made %d v2 requests to a peer that reported version %s", v2Requests, version)
+			}
+		})
+	}
+}
+
+func TestSendToDevice_FileProvider(t *testing.T) {
+	content := "generated archive"
+
+	var got model.FileDto
+	var gotBody string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/localsend/v2/prepare-upload":
+			var req model.PrepareUploadRequestDto
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, "Bad Request", http.StatusBadRequest)
+				return
+			}
+			files := make(map[string]string)
+			for id, f := range req.Files {
+				got = f
+				files[id] = "token"
+			}
+			json.NewEncoder(w).Encode(model.PrepareUploadResponseDto{SessionID: "session", Files: files})
+		case "/api/localsend/v2/upload":
+			body, _ := io.ReadAll(r.Body)
+			gotBody = string(body)
+			w.WriteHeader(http.StatusOK)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	host := strings.TrimPrefix(server.URL, "http://")
+	port, _ := strconv.Atoi(strings.Split(host, ":")[1])
+	cfg := &config.Config{SecurityContext: &crypto.StoredSecurityContext{}}
+	device := &model.Device{IP: strings.Split(host, ":")[0], Port: port, Protocol: model.ProtocolTypeHTTP}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	provider := storage.GeneratedFile("archive.zip", int64(len(content)), func(w io.Writer) error {
+		_, err := io.WriteString(w, content)
+		return err
+	})
+	if err := SendToDevice(ctx, cfg, device, nil, testLoggerSend, WithFileProvider(provider)); err != nil {
+		t.Fatalf("SendToDevice failed: %v", err)
+	}
+	want, _ := provider.Hash()
+	if got.FileName != "archive.zip" || got.Size != int64(len(content)) || got.SHA256 == nil || *got.SHA256 != want {
+		t.Errorf("unexpected file dto: %+v", got)
+	}
+	if gotBody != content {
+		t.Errorf("unexpected upload body: %q", gotBody)
+	}
+}
@@ -6,16 +6,19 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"net"
 	"net/http"
-	"os"
 	"strconv"
 	"time"
 
-	"github.com/bethropolis/localgo/pkg/model"
+	"github.com/bethropolis/localgo/pkg/compression"
+	"github.com/bethropolis/localgo/pkg/storage"
 	"go.uber.org/zap"
 )
 
+// maxUploadRetryAfter caps how long an upload turned down as busy waits
+// before it is tried again, whatever the receiver asks for.
+const maxUploadRetryAfter = 10 * time.Second
+
 // memReadSeekCloser wraps a *bytes.Reader to implement io.ReadSeekCloser.
 type memReadSeekCloser struct {
 	*bytes.Reader
@@ -23,37 +26,73 @@ type memReadSeekCloser struct {
 
 func (m *memReadSeekCloser) Close() error { return nil }
 
-// fileReader is satisfied by both *os.File and *memReadSeekCloser.
-type fileReader interface {
-	io.ReadSeeker
-	io.Closer
-}
-
-func uploadFile(ctx context.Context, client *http.Client, device *model.Device, filePath, fileID, sessionID, token, scheme string, trackProgress func(int64), logger *zap.SugaredLogger) error {
+// uploadProvider uploads the content of p, opening it afresh for each
+// attempt.
+func uploadProvider(ctx context.Context, client *http.Client, apiURL string, p storage.FileProvider, fileID, sessionID, token, encoding string, trackProgress func(int64), logger *zap.SugaredLogger) error {
 	if logger == nil {
 		logger = zap.NewNop().Sugar()
 	}
 
-	file, err := os.Open(filePath)
-	if err != nil {
-		return fmt.Errorf("failed to open file: %w", err)
-	}
-	defer file.Close()
+	return withUploadRetry(ctx, logger, func() error {
+		info, err := p.Stat()
+		if err != nil {
+			return fmt.Errorf("failed to get file stats: %w", err)
+		}
+		r, err := p.Open()
+		if err != nil {
+			return fmt.Errorf("failed to open file: %w", err)
+		}
+		defer r.Close()
 
-	stat, err := file.Stat()
-	if err != nil {
-		return fmt.Errorf("failed to get file stats: %w", err)
-	}
+		return uploadStream(ctx, client, apiURL, r, info.Size, fileID, sessionID, token, encoding, trackProgress, logger)
+	})
+}
+
+// uploadBusyError is returned by uploadStream when the receiver turned the
+// upload down with 429 Too Many Requests: it is already taking as many
+// files of the session at once as it allows.
+type uploadBusyError struct {
+	status     string
+	retryAfter time.Duration
+}
+
+func (e *uploadBusyError) Error() string {
+	return fmt.Sprintf("receiver busy with other uploads of the session (%s)", e.status)
+}
 
-	return uploadStream(ctx, client, device, file, stat.Size(), fileID, sessionID, token, scheme, trackProgress, logger)
+// withUploadRetry runs attempt, and runs it again after the wait the
+// receiver asks for each time it fails with an uploadBusyError. Another
+// upload of the session finishing frees a slot, so it stops only on
+// success, another error, or ctx ending.
+func withUploadRetry(ctx context.Context, logger *zap.SugaredLogger, attempt func() error) error {
+	for {
+		err := attempt()
+		var busy *uploadBusyError
+		if !errors.As(err, &busy) {
+			return err
+		}
+		logger.Debugf("Upload deferred by receiver, retrying in %s", busy.retryAfter)
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("upload aborted: %w", ctx.Err())
+		case <-time.After(busy.retryAfter):
+		}
+	}
 }
 
-func uploadStream(ctx context.Context, client *http.Client, device *model.Device, r fileReader, size int64, fileID, sessionID, token, scheme string, trackProgress func(int64), logger *zap.SugaredLogger) error {
+// uploadStream uploads size bytes from r to the upload endpoint under apiURL,
+// e.g. https://192.168.1.2:53317/api/localsend/v2. Without a sessionID, it
+// uploads to the v1 /send endpoint under a v1 apiURL. With an encoding, the
+// bytes are compressed on the way; progress still counts the bytes of r.
+func uploadStream(ctx context.Context, client *http.Client, apiURL string, r io.ReadCloser, size int64, fileID, sessionID, token, encoding string, trackProgress func(int64), logger *zap.SugaredLogger) error {
 	if logger == nil {
 		logger = zap.NewNop().Sugar()
 	}
 
-	url := fmt.Sprintf("%s://%s/api/localsend/v2/upload?sessionId=%s&fileId=%s&token=%s", scheme, net.JoinHostPort(device.IP, strconv.Itoa(device.Port)), sessionID, fileID, token)
+	url := fmt.Sprintf("%s/upload?sessionId=%s&fileId=%s&token=%s", apiURL, sessionID, fileID, token)
+	if sessionID == "" {
+		url = fmt.Sprintf("%s/send?fileId=%s&token=%s", apiURL, fileID, token)
+	}
 
 	var body io.ReadCloser = io.NopCloser(r)
 	if trackProgress != nil {
@@ -67,16 +106,32 @@ func uploadStream(ctx context.Context, client *http.Client, device *model.Device
 	body = NewIdleTimeoutReader(body, 15*time.Second, cancel)
 	defer body.Close()
 
+	contentLength := size
+	if encoding != "" {
+		compressed, err := compressBody(body, encoding)
+		if err != nil {
+			return err
+		}
+		body, contentLength = compressed, -1
+	}
+
 	req, err := http.NewRequestWithContext(uploadCtx, http.MethodPost, url, body)
 	if err != nil {
 		cancel()
 		return fmt.Errorf("failed to create upload request: %w", err)
 	}
 	req.Header.Set("Content-Type", "application/octet-stream")
-	req.ContentLength = size
+	if encoding != "" {
+		req.Header.Set("Content-Encoding", encoding)
+	}
+	req.ContentLength = contentLength
 
 	resp, err := client.Do(req)
 	if err != nil {
+		if ctx.Err() != nil {
+			// The send itself was stopped or ran out of time.
+			return fmt.Errorf("upload aborted: %w", ctx.Err())
+		}
 		if errors.Is(err, context.Canceled) {
 			return fmt.Errorf("upload stalled: no data transmitted for 15s")
 		}
@@ -84,11 +139,38 @@ func uploadStream(ctx context.Context, client *http.Client, device *model.Device
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("upload request failed with status: %s", resp.Status)
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return nil
+	case http.StatusForbidden, http.StatusNotFound, http.StatusConflict:
+		// The session or its tokens are gone: the receiver cancelled it.
+		return fmt.Errorf("%w (%s)", ErrCancelled, resp.Status)
+	case http.StatusTooManyRequests:
+		retryAfter := time.Second
+		if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && secs > 0 {
+			retryAfter = min(time.Duration(secs)*time.Second, maxUploadRetryAfter)
+		}
+		return &uploadBusyError{status: resp.Status, retryAfter: retryAfter}
 	}
+	return fmt.Errorf("upload request failed with status: %s", resp.Status)
+}
 
-	return nil
+// compressBody returns a reader of src compressed with encoding. The
+// compression runs as the request reads, and stops if the request gives up.
+func compressBody(src io.Reader, encoding string) (io.ReadCloser, error) {
+	pr, pw := io.Pipe()
+	zw, err := compression.NewWriter(pw, encoding)
+	if err != nil {
+		return nil, err
+	}
+	go func() {
+		_, err := io.Copy(zw, src)
+		if err == nil {
+			err = zw.Close()
+		}
+		pw.CloseWithError(err)
+	}()
+	return pr, nil
 }
 
 // IdleTimeoutReader wraps an io.ReadCloser and cancels the context if no data
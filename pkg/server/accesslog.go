@@ -0,0 +1,160 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bethropolis/localgo/pkg/config"
+)
+
+// accessLog writes one record per HTTP request. Only the URL path is logged:
+// query strings carry PINs and upload tokens.
+type accessLog struct {
+	w           io.Writer
+	format      string
+	fingerprint func(ip string) string // may be nil
+	mu          sync.Mutex
+}
+
+// accessRecord is a single access log entry.
+type accessRecord struct {
+	Time        time.Time `json:"time"`
+	IP          string    `json:"ip"`
+	Fingerprint string    `json:"fingerprint,omitempty"`
+	Method      string    `json:"method"`
+	Path        string    `json:"path"`
+	Proto       string    `json:"proto"`
+	Status      int       `json:"status"`
+	Bytes       int64     `json:"bytes"`
+	DurationMs  float64   `json:"duration_ms"`
+}
+
+// newAccessLog returns an access log writing to w in format (see
+// config.AccessLogCommon). fingerprint, if set, resolves a peer IP to the
+// fingerprint of a known device.
+func newAccessLog(w io.Writer, format string, fingerprint func(ip string) string) *accessLog {
+	return &accessLog{w: w, format: format, fingerprint: fingerprint}
+}
+
+// Middleware logs every request passing through next. It has the shape of a
+// mux.MiddlewareFunc.
+func (a *accessLog) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ip, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			ip = r.RemoteAddr
+		}
+		// Look the peer up before and after: an upload's session may end
+		// with the request, a prepare-upload's only begins with it.
+		fingerprint := a.lookup(ip)
+
+		sw := &statusWriter{ResponseWriter: w}
+		next.ServeHTTP(sw, r)
+
+		if fingerprint == "" {
+			fingerprint = a.lookup(ip)
+		}
+		a.write(accessRecord{
+			Time:        start,
+			IP:          ip,
+			Fingerprint: fingerprint,
+			Method:      r.Method,
+			Path:        r.URL.Path,
+			Proto:       r.Proto,
+			Status:      sw.statusCode(),
+			Bytes:       sw.bytes,
+			DurationMs:  float64(time.Since(start).Microseconds()) / 1000,
+		})
+	})
+}
+
+func (a *accessLog) lookup(ip string) string {
+	if a.fingerprint == nil {
+		return ""
+	}
+	return a.fingerprint(ip)
+}
+
+func (a *accessLog) write(rec accessRecord) {
+	var line []byte
+	if a.format == config.AccessLogJSON {
+		data, err := json.Marshal(rec)
+		if err != nil {
+			return
+		}
+		line = append(data, '\n')
+	} else {
+		line = []byte(formatCommon(rec))
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	_, _ = a.w.Write(line)
+}
+
+// formatCommon renders rec in Common Log Format, with the peer fingerprint in
+// the user field and the duration appended.
+func formatCommon(rec accessRecord) string {
+	user := "-"
+	if rec.Fingerprint != "" {
+		user = rec.Fingerprint
+	}
+	// Paths are sent by the client; quote-escape them so a line can't be forged.
+	path := strings.NewReplacer(`"`, `\"`, "\n", `\n`, "\r", `\r`).Replace(rec.Path)
+	return fmt.Sprintf("%s - %s [%s] \"%s %s %s\" %d %d %.3fms\n",
+		rec.IP, user, rec.Time.Format("02/Jan/2006:15:04:05 -0700"),
+		rec.Method, path, rec.Proto, rec.Status, rec.Bytes, rec.DurationMs)
+}
+
+// statusWriter records the status code and body size of a response.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *statusWriter) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(p)
+	w.bytes += int64(n)
+	return n, err
+}
+
+// ReadFrom keeps the underlying writer's ReadFrom, and so sendfile, in use.
+func (w *statusWriter) ReadFrom(src io.Reader) (int64, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := io.Copy(w.ResponseWriter, src)
+	w.bytes += n
+	return n, err
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// statusCode is the status sent, or 200 if the handler wrote nothing.
+func (w *statusWriter) statusCode() int {
+	if w.status == 0 {
+		return http.StatusOK
+	}
+	return w.status
+}
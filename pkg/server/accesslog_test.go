@@ -0,0 +1,69 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bethropolis/localgo/pkg/config"
+)
+
+func TestAccessLog(t *testing.T) {
+	fingerprints := map[string]string{"192.168.1.20": "3f9a1c2b7d4e8f60"}
+	lookup := func(ip string) string { return fingerprints[ip] }
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("hello"))
+	})
+
+	serve := func(format, remoteAddr string) string {
+		var buf bytes.Buffer
+		h := newAccessLog(&buf, format, lookup).Middleware(handler)
+		req := httptest.NewRequest(http.MethodPost, "/api/localsend/v2/prepare-upload?pin=1234", nil)
+		req.RemoteAddr = remoteAddr
+		h.ServeHTTP(httptest.NewRecorder(), req)
+		return buf.String()
+	}
+
+	t.Run("common", func(t *testing.T) {
+		line := serve(config.AccessLogCommon, "192.168.1.20:40000")
+		if !strings.HasPrefix(line, "192.168.1.20 - 3f9a1c2b7d4e8f60 [") {
+			t.Errorf("unexpected line start: %q", line)
+		}
+		if !strings.Contains(line, `"POST /api/localsend/v2/prepare-upload HTTP/1.1" 201 5 `) {
+			t.Errorf("request, status or size missing: %q", line)
+		}
+		if strings.Contains(line, "pin") {
+			t.Errorf("query string was logged: %q", line)
+		}
+	})
+
+	t.Run("common unknown peer", func(t *testing.T) {
+		line := serve(config.AccessLogCommon, "192.168.1.99:40000")
+		if !strings.HasPrefix(line, "192.168.1.99 - - [") {
+			t.Errorf("unexpected line start: %q", line)
+		}
+	})
+
+	t.Run("json", func(t *testing.T) {
+		line := serve(config.AccessLogJSON, "192.168.1.20:40000")
+		var rec accessRecord
+		if err := json.Unmarshal([]byte(line), &rec); err != nil {
+			t.Fatalf("invalid JSON line %q: %v", line, err)
+		}
+		if rec.IP != "192.168.1.20" || rec.Fingerprint != "3f9a1c2b7d4e8f60" || rec.Method != http.MethodPost ||
+			rec.Path != "/api/localsend/v2/prepare-upload" || rec.Status != http.StatusCreated || rec.Bytes != 5 {
+			t.Errorf("unexpected record: %+v", rec)
+		}
+	})
+}
+
+func TestFormatCommon_EscapesPath(t *testing.T) {
+	line := formatCommon(accessRecord{IP: "10.0.0.1", Method: "GET", Path: "/a\" 200 0\n10.0.0.2", Proto: "HTTP/1.1", Status: 404})
+	if strings.Count(line, "\n") != 1 || !strings.Contains(line, `/a\" 200 0\n10.0.0.2`) {
+		t.Errorf("path not escaped: %q", line)
+	}
+}
@@ -0,0 +1,71 @@
+package server
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// listenAdminSocket listens on the unix socket at path for the admin API.
+// The socket is only usable by the user running the server, which is what
+// authorizes its clients, so they need no token. A socket left behind by a
+// server that is gone is replaced; one a server still answers on is not.
+//
+// The socket is created in a new directory only the user can enter, made
+// private there and then moved into place, so no other user can connect in
+// the moment between creating it and restricting its mode.
+func listenAdminSocket(path string) (net.Listener, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("admin socket: %w", err)
+	}
+	if info, err := os.Lstat(path); err == nil {
+		if info.Mode().Type() != os.ModeSocket {
+			return nil, fmt.Errorf("admin socket: %s exists and is not a socket", path)
+		}
+		if conn, err := net.DialTimeout("unix", path, time.Second); err == nil {
+			conn.Close()
+			return nil, fmt.Errorf("admin socket: %s is in use by another server", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, fmt.Errorf("admin socket: %w", err)
+		}
+	}
+	// Short names, as socket paths are limited to about 100 bytes.
+	private, err := os.MkdirTemp(filepath.Dir(path), ".s")
+	if err != nil {
+		return nil, fmt.Errorf("admin socket: %w", err)
+	}
+	defer os.RemoveAll(private)
+	tmp := filepath.Join(private, "s")
+	ln, err := net.Listen("unix", tmp)
+	if err != nil {
+		return nil, fmt.Errorf("admin socket: %w", err)
+	}
+	ln.(*net.UnixListener).SetUnlinkOnClose(false)
+	if err := os.Chmod(tmp, 0600); err != nil {
+		ln.Close()
+		return nil, fmt.Errorf("admin socket: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		ln.Close()
+		return nil, fmt.Errorf("admin socket: %w", err)
+	}
+	return &socketListener{Listener: ln, path: path}, nil
+}
+
+// socketListener removes its socket file when closed, which the listener
+// itself would do under the name it was created with.
+type socketListener struct {
+	net.Listener
+	path string
+	once sync.Once
+}
+
+func (l *socketListener) Close() error {
+	err := l.Listener.Close()
+	l.once.Do(func() { os.Remove(l.path) })
+	return err
+}
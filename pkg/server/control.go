@@ -0,0 +1,300 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net"
+	"slices"
+	"time"
+
+	"github.com/bethropolis/localgo/pkg/config"
+	"github.com/bethropolis/localgo/pkg/controlpb"
+	"github.com/bethropolis/localgo/pkg/model"
+	"github.com/bethropolis/localgo/pkg/queue"
+	"github.com/bethropolis/localgo/pkg/report"
+	"github.com/bethropolis/localgo/pkg/server/services"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// controlServer serves the gRPC control interface: the admin API, and
+// answering incoming transfers, for GUIs written in other languages.
+type controlServer struct {
+	controlpb.UnimplementedControlServer
+	s *Server
+}
+
+// listenControl listens where config.ControlGRPC says and returns the gRPC
+// server to serve on it. Over TCP every call must carry the admin token; a
+// unix socket is only usable by the user running the server.
+func (s *Server) listenControl() (*grpc.Server, net.Listener, error) {
+	network, addr, err := config.ParseControlGRPC(s.config.ControlGRPC)
+	if err != nil {
+		return nil, nil, err
+	}
+	var ln net.Listener
+	var opts []grpc.ServerOption
+	if network == "unix" {
+		ln, err = listenAdminSocket(addr)
+	} else {
+		ln, err = net.Listen(network, addr)
+		opts = append(opts,
+			grpc.UnaryInterceptor(func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+				if err := s.checkControlToken(ctx); err != nil {
+					return nil, err
+				}
+				return handler(ctx, req)
+			}),
+			grpc.StreamInterceptor(func(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+				if err := s.checkControlToken(ss.Context()); err != nil {
+					return err
+				}
+				return handler(srv, ss)
+			}),
+		)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	g := grpc.NewServer(opts...)
+	controlpb.RegisterControlServer(g, &controlServer{s: s})
+	return g, ln, nil
+}
+
+// checkControlToken rejects calls that do not carry the admin token in
+// their authorization metadata.
+func (s *Server) checkControlToken(ctx context.Context) error {
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, auth := range md.Get("authorization") {
+		if config.CheckAdminToken(s.config.AdminTokenPath, auth) {
+			return nil
+		}
+	}
+	return status.Error(codes.Unauthenticated, "missing or wrong admin token")
+}
+
+func (c *controlServer) GetStatus(context.Context, *controlpb.GetStatusRequest) (*controlpb.GetStatusResponse, error) {
+	resp := &controlpb.GetStatusResponse{}
+	for _, session := range c.s.receiveService.Status() {
+		resp.Sessions = append(resp.Sessions, controlSession(session))
+	}
+	for _, r := range c.s.receiveService.Recent() {
+		resp.Recent = append(resp.Recent, controlReport(r))
+	}
+	var until time.Time
+	resp.QuickSave, until = c.s.receiveService.QuickSave()
+	if resp.QuickSave && !until.IsZero() {
+		resp.QuickSaveUntil = timestamppb.New(until)
+	}
+	return resp, nil
+}
+
+func (c *controlServer) CancelSession(_ context.Context, req *controlpb.CancelSessionRequest) (*controlpb.CancelSessionResponse, error) {
+	if req.GetSessionId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "session_id is required")
+	}
+	if c.s.receiveService.GetSessionByID(req.GetSessionId()) == nil {
+		return nil, status.Error(codes.NotFound, "session not found")
+	}
+	c.s.logger.Infof("Canceling session %s at control client request.", req.GetSessionId())
+	c.s.receiveService.CloseSession(req.GetSessionId())
+	return &controlpb.CancelSessionResponse{}, nil
+}
+
+func (c *controlServer) ListDevices(context.Context, *controlpb.ListDevicesRequest) (*controlpb.ListDevicesResponse, error) {
+	resp := &controlpb.ListDevicesResponse{}
+	for _, d := range c.s.registryService.GetDevices() {
+		d = d.Snapshot()
+		resp.Devices = append(resp.Devices, &controlpb.Device{
+			Alias:       d.Alias,
+			Fingerprint: d.Fingerprint,
+			Ip:          d.IP,
+			Port:        int32(d.Port),
+			Protocol:    string(d.Protocol),
+			DeviceModel: stringValue(d.DeviceModel),
+			DeviceType:  string(d.DeviceType),
+			LastSeen:    timestamppb.New(d.LastSeen),
+			Available:   d.Available,
+		})
+	}
+	return resp, nil
+}
+
+func (c *controlServer) Send(_ context.Context, req *controlpb.SendRequest) (*controlpb.SendJob, error) {
+	job, err := c.s.queue.Add(queue.Job{
+		Files:          req.GetFiles(),
+		Excludes:       req.GetExcludes(),
+		SkipDuplicates: req.GetSkipDuplicates(),
+		To:             req.GetTo(),
+		IP:             req.GetIp(),
+		Fingerprint:    req.GetFingerprint(),
+		Port:           int(req.GetPort()),
+		Priority:       int(req.GetPriority()),
+		MaxAttempts:    int(req.GetMaxAttempts()),
+	})
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return controlJob(job), nil
+}
+
+func (c *controlServer) ListSendJobs(context.Context, *controlpb.ListSendJobsRequest) (*controlpb.ListSendJobsResponse, error) {
+	resp := &controlpb.ListSendJobsResponse{}
+	for _, job := range c.s.queue.List() {
+		resp.Jobs = append(resp.Jobs, controlJob(job))
+	}
+	return resp, nil
+}
+
+func (c *controlServer) ListTransferRequests(context.Context, *controlpb.ListTransferRequestsRequest) (*controlpb.ListTransferRequestsResponse, error) {
+	resp := &controlpb.ListTransferRequestsResponse{}
+	for _, req := range c.s.receiveService.Approvals().Pending() {
+		resp.Requests = append(resp.Requests, controlTransferRequest(req))
+	}
+	return resp, nil
+}
+
+func (c *controlServer) AnswerTransferRequest(_ context.Context, req *controlpb.AnswerTransferRequestRequest) (*controlpb.AnswerTransferRequestResponse, error) {
+	err := c.s.receiveService.Approvals().Answer(req.GetId(), req.GetAccept(), req.GetFileIds())
+	if errors.Is(err, services.ErrUnknownTransferRequest) {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return &controlpb.AnswerTransferRequestResponse{}, nil
+}
+
+func (c *controlServer) WatchEvents(req *controlpb.WatchEventsRequest, stream grpc.ServerStreamingServer[controlpb.Event]) error {
+	events, unsubscribe := c.s.events.Subscribe()
+	defer unsubscribe()
+	if req.GetAnswerTransfers() {
+		defer c.s.receiveService.Approvals().AddAnswerer()()
+	}
+	// Send the headers now, so the client knows it is subscribed.
+	if err := stream.SendHeader(nil); err != nil {
+		return err
+	}
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case <-c.s.shutdownCtx.Done():
+			return nil
+		case e := <-events:
+			if err := stream.Send(controlEvent(e)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func controlSession(s model.SessionStatusDto) *controlpb.Session {
+	session := &controlpb.Session{
+		SessionId: s.SessionID,
+		Sender:    s.Sender,
+		SenderIp:  s.SenderIP,
+		StartedAt: timestamppb.New(s.StartedAt),
+		Size:      s.Size,
+		Bytes:     s.Bytes,
+	}
+	for _, f := range s.Files {
+		session.Files = append(session.Files, &controlpb.FileStatus{Name: f.Name, Size: f.Size, Bytes: f.Bytes, Status: f.Status})
+	}
+	return session
+}
+
+func controlReport(r *report.Report) *controlpb.Report {
+	return &controlpb.Report{
+		SessionId:   r.SessionID,
+		Peer:        r.Peer,
+		StartedAt:   timestamppb.New(r.StartedAt),
+		FinishedAt:  timestamppb.New(r.FinishedAt),
+		Transferred: int32(r.Transferred),
+		Failed:      int32(r.Failed),
+		Skipped:     int32(r.Skipped),
+		Bytes:       r.Bytes,
+		Reason:      r.Reason,
+	}
+}
+
+func controlJob(j queue.Job) *controlpb.SendJob {
+	return &controlpb.SendJob{
+		Id:          int64(j.ID),
+		Files:       j.Files,
+		Recipient:   j.Recipient(),
+		Priority:    int32(j.Priority),
+		Status:      j.Status,
+		Attempts:    int32(j.Attempts),
+		MaxAttempts: int32(j.MaxAttempts),
+		Error:       j.Error,
+		CreatedAt:   timestamppb.New(j.CreatedAt),
+	}
+}
+
+func controlDevice(d *services.EventDevice) *controlpb.Device {
+	if d == nil {
+		return nil
+	}
+	return &controlpb.Device{
+		Alias:       d.Alias,
+		Fingerprint: d.Fingerprint,
+		Ip:          d.IP,
+		DeviceModel: stringValue(d.DeviceModel),
+		DeviceType:  string(d.DeviceType),
+	}
+}
+
+func controlTransferRequest(req *services.TransferRequest) *controlpb.TransferRequest {
+	if req == nil {
+		return nil
+	}
+	tr := &controlpb.TransferRequest{
+		Id:         req.ID,
+		Sender:     controlDevice(req.Sender),
+		ReceivedAt: timestamppb.New(req.ReceivedAt),
+		ExpiresAt:  timestamppb.New(req.ExpiresAt),
+	}
+	ids := make([]string, 0, len(req.Files))
+	for id := range req.Files {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	for _, id := range ids {
+		f := req.Files[id]
+		tr.Files = append(tr.Files, &controlpb.OfferedFile{
+			Id:      id,
+			Name:    f.FileName,
+			Size:    f.Size,
+			Type:    f.FileType,
+			Preview: stringValue(f.Preview),
+		})
+	}
+	return tr
+}
+
+func controlEvent(e services.Event) *controlpb.Event {
+	return &controlpb.Event{
+		Id:              e.ID,
+		Type:            e.Type,
+		Time:            timestamppb.New(e.Time),
+		SessionId:       e.SessionID,
+		File:            e.File,
+		Path:            e.Path,
+		Files:           int32(e.Files),
+		Bytes:           e.Bytes,
+		Total:           e.Total,
+		Device:          controlDevice(e.Device),
+		TransferRequest: controlTransferRequest(e.Request),
+	}
+}
+
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -0,0 +1,131 @@
+package server
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bethropolis/localgo/pkg/config"
+	"github.com/bethropolis/localgo/pkg/controlpb"
+	"github.com/bethropolis/localgo/pkg/crypto"
+	"github.com/bethropolis/localgo/pkg/history"
+	"github.com/bethropolis/localgo/pkg/model"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestStart_ControlGRPC(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "control.sock")
+	cfg := &config.Config{
+		Alias:           "Test",
+		Port:            0,
+		HistoryFile:     history.DisabledSentinel,
+		DownloadDir:     t.TempDir(),
+		SecurityContext: &crypto.StoredSecurityContext{},
+		ControlGRPC:     "unix:" + socket,
+	}
+	srv := NewServer(cfg, zap.NewNop().Sugar())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ready := make(chan struct{}, 1)
+	errCh := make(chan error, 1)
+	go func() { errCh <- srv.Start(ctx, ready) }()
+	select {
+	case <-ready:
+	case err := <-errCh:
+		t.Fatalf("server failed to start: %v", err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("server did not become ready")
+	}
+
+	conn, err := grpc.NewClient("unix://"+socket, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	client := controlpb.NewControlClient(conn)
+	callCtx, callCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer callCancel()
+
+	if _, err := client.GetStatus(callCtx, &controlpb.GetStatusRequest{}); err != nil {
+		t.Fatalf("GetStatus over the socket: %v", err)
+	}
+	if _, err := client.CancelSession(callCtx, &controlpb.CancelSessionRequest{SessionId: "nope"}); status.Code(err) != codes.NotFound {
+		t.Errorf("cancelling an unknown session: %v, want NotFound", err)
+	}
+
+	// A client watching with answer_transfers answers incoming transfers.
+	stream, err := client.WatchEvents(callCtx, &controlpb.WatchEventsRequest{AnswerTransfers: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := stream.Header(); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]model.FileDto{"a": {ID: "a", FileName: "a.txt", Size: 1, FileType: "text/plain"}}
+	accepted := make(chan map[string]model.FileDto, 1)
+	go func() {
+		got, _ := srv.receiveService.Approvals().Ask(context.Background(), model.DeviceInfo{Alias: "Phone"}, files, time.Minute)
+		accepted <- got
+	}()
+	e, err := stream.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := e.GetTransferRequest()
+	if e.GetType() != "transfer_requested" || req.GetSender().GetAlias() != "Phone" || len(req.GetFiles()) != 1 || req.GetFiles()[0].GetName() != "a.txt" {
+		t.Fatalf("unexpected event: %v", e)
+	}
+	list, err := client.ListTransferRequests(callCtx, &controlpb.ListTransferRequestsRequest{})
+	if err != nil || len(list.GetRequests()) != 1 {
+		t.Fatalf("ListTransferRequests = %v, %v; want the request", list, err)
+	}
+	if _, err := client.AnswerTransferRequest(callCtx, &controlpb.AnswerTransferRequestRequest{Id: req.GetId(), Accept: true}); err != nil {
+		t.Fatal(err)
+	}
+	if got := <-accepted; len(got) != 1 {
+		t.Errorf("accepted %v, want a.txt", got)
+	}
+	if _, err := client.AnswerTransferRequest(callCtx, &controlpb.AnswerTransferRequestRequest{Id: req.GetId()}); status.Code(err) != codes.NotFound {
+		t.Errorf("answering twice: %v, want NotFound", err)
+	}
+
+	cancel()
+	if err := <-errCh; err != nil {
+		t.Errorf("server shutdown failed: %v", err)
+	}
+	if _, err := stream.Recv(); err == nil {
+		t.Error("event stream still open after shutdown")
+	}
+}
+
+func TestCheckControlToken(t *testing.T) {
+	path := filepath.Join(t.TempDir(), config.AdminTokenFile)
+	token, err := config.LoadOrCreateAdminToken(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := &Server{config: &config.Config{AdminTokenPath: path}}
+
+	for _, tc := range []struct {
+		name string
+		md   metadata.MD
+		ok   bool
+	}{
+		{"no metadata", nil, false},
+		{"wrong token", metadata.Pairs("authorization", "Bearer nope"), false},
+		{"bare token", metadata.Pairs("authorization", token), false},
+		{"token", metadata.Pairs("authorization", "Bearer "+token), true},
+	} {
+		ctx := metadata.NewIncomingContext(context.Background(), tc.md)
+		err := srv.checkControlToken(ctx)
+		if (err == nil) != tc.ok || (err != nil && status.Code(err) != codes.Unauthenticated) {
+			t.Errorf("%s: %v", tc.name, err)
+		}
+	}
+}
@@ -0,0 +1,210 @@
+//go:build linux
+
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"path/filepath"
+
+	"github.com/bethropolis/localgo/pkg/cli"
+	"github.com/bethropolis/localgo/pkg/queue"
+	"github.com/bethropolis/localgo/pkg/server/services"
+	"github.com/godbus/dbus/v5"
+	"github.com/godbus/dbus/v5/introspect"
+)
+
+// The D-Bus interface lets desktop applets and file manager extensions,
+// such as a "Send with LocalGo" menu item, use a running server.
+const (
+	dbusName      = "io.github.bethropolis.LocalGo"
+	dbusPath      = dbus.ObjectPath("/io/github/bethropolis/LocalGo")
+	dbusInterface = "io.github.bethropolis.LocalGo1"
+)
+
+// dbusIntrospection describes the interface to D-Bus tools and bindings.
+const dbusIntrospection = `
+<node>
+  <interface name="` + dbusInterface + `">
+    <!-- Queue files to be sent to a device: an alias, a fingerprint or an
+         IP address. Files are absolute paths or file:// URIs. -->
+    <method name="SendFile">
+      <arg name="files" type="as" direction="in"/>
+      <arg name="device" type="s" direction="in"/>
+      <arg name="job_id" type="x" direction="out"/>
+    </method>
+    <!-- The devices seen since the server started. -->
+    <method name="ListDevices">
+      <arg name="devices" type="a(sssisb)" direction="out">
+        <annotation name="org.freedesktop.DBus.Documentation" value="alias, fingerprint, ip, port, device type, available"/>
+      </arg>
+    </method>
+    <signal name="TransferStarted">
+      <arg name="session_id" type="s"/>
+      <arg name="sender" type="s"/>
+      <arg name="files" type="i"/>
+      <arg name="total" type="x"/>
+    </signal>
+    <signal name="FileReceived">
+      <arg name="session_id" type="s"/>
+      <arg name="name" type="s"/>
+      <arg name="path" type="s"/>
+    </signal>
+    <signal name="TransferCompleted">
+      <arg name="session_id" type="s"/>
+    </signal>
+    <signal name="TransferCancelled">
+      <arg name="session_id" type="s"/>
+    </signal>
+    <signal name="DeviceDiscovered">
+      <arg name="alias" type="s"/>
+      <arg name="fingerprint" type="s"/>
+      <arg name="ip" type="s"/>
+    </signal>
+    <signal name="DeviceLost">
+      <arg name="alias" type="s"/>
+      <arg name="fingerprint" type="s"/>
+      <arg name="ip" type="s"/>
+    </signal>
+  </interface>` + introspect.IntrospectDataString + `</node>`
+
+// dbusService holds the methods exported on D-Bus; every exported method of
+// it is callable.
+type dbusService struct {
+	s *Server
+}
+
+// dbusDevice is a device as ListDevices returns it.
+type dbusDevice struct {
+	Alias       string
+	Fingerprint string
+	IP          string
+	Port        int32
+	DeviceType  string
+	Available   bool
+}
+
+func (d dbusService) SendFile(files []string, device string) (int64, *dbus.Error) {
+	job := queue.Job{Files: make([]string, 0, len(files))}
+	for _, f := range files {
+		if u, err := url.Parse(f); err == nil && u.Scheme == "file" {
+			f = u.Path
+		}
+		job.Files = append(job.Files, filepath.Clean(f))
+	}
+	switch {
+	case net.ParseIP(device) != nil:
+		job.IP = device
+	case d.isFingerprint(device):
+		job.Fingerprint = device
+	default:
+		job.To = device
+	}
+	added, err := d.s.queue.Add(job)
+	if err != nil {
+		return 0, dbus.MakeFailedError(err)
+	}
+	return int64(added.ID), nil
+}
+
+func (d dbusService) isFingerprint(s string) bool {
+	for _, dev := range d.s.registryService.GetDevices() {
+		if dev.Snapshot().Fingerprint == s {
+			return true
+		}
+	}
+	return false
+}
+
+func (d dbusService) ListDevices() ([]dbusDevice, *dbus.Error) {
+	devices := []dbusDevice{}
+	for _, dev := range d.s.registryService.GetDevices() {
+		dev = dev.Snapshot()
+		devices = append(devices, dbusDevice{
+			Alias:       dev.Alias,
+			Fingerprint: dev.Fingerprint,
+			IP:          dev.IP,
+			Port:        int32(dev.Port),
+			DeviceType:  string(dev.DeviceType),
+			Available:   dev.Available,
+		})
+	}
+	return devices, nil
+}
+
+// startDBus publishes the D-Bus interface on the session bus and emits its
+// signals until the server shuts down. It returns a function that takes
+// the interface off the bus.
+func (s *Server) startDBus() (func(), error) {
+	conn, err := dbus.ConnectSessionBus()
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to the session bus: %w", err)
+	}
+	if err := conn.Export(dbusService{s: s}, dbusPath, dbusInterface); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if err := conn.Export(introspect.Introspectable(dbusIntrospection), dbusPath, "org.freedesktop.DBus.Introspectable"); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	reply, err := conn.RequestName(dbusName, dbus.NameFlagDoNotQueue)
+	if err == nil && reply != dbus.RequestNameReplyPrimaryOwner {
+		err = errors.New("another LocalGo server already owns it")
+	}
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to claim %s: %w", dbusName, err)
+	}
+
+	events, unsubscribe := s.events.Subscribe()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			select {
+			case <-s.shutdownCtx.Done():
+				return
+			case e, ok := <-events:
+				if !ok {
+					return
+				}
+				if name, args := dbusSignal(e); name != "" {
+					if err := conn.Emit(dbusPath, dbusInterface+"."+name, args...); err != nil {
+						s.logger.Debugf("Failed to emit D-Bus signal %s: %v", name, err)
+					}
+				}
+			}
+		}
+	}()
+	return func() {
+		unsubscribe()
+		<-done
+		conn.Close()
+	}, nil
+}
+
+// dbusSignal returns the D-Bus signal for an event, or "" if it has none.
+func dbusSignal(e services.Event) (string, []any) {
+	device := e.Device
+	if device == nil {
+		device = &services.EventDevice{}
+	}
+	switch e.Type {
+	case cli.EventSessionStarted:
+		return "TransferStarted", []any{e.SessionID, device.Alias, int32(e.Files), e.Total}
+	case cli.EventFileCompleted:
+		return "FileReceived", []any{e.SessionID, e.File, e.Path}
+	case cli.EventSessionCompleted:
+		return "TransferCompleted", []any{e.SessionID}
+	case cli.EventSessionCancelled:
+		return "TransferCancelled", []any{e.SessionID}
+	case services.EventDeviceDiscovered:
+		return "DeviceDiscovered", []any{device.Alias, device.Fingerprint, device.IP}
+	case services.EventDeviceLost:
+		return "DeviceLost", []any{device.Alias, device.Fingerprint, device.IP}
+	}
+	return "", nil
+}
@@ -0,0 +1,160 @@
+//go:build linux
+
+package server
+
+import (
+	"bufio"
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bethropolis/localgo/pkg/cli"
+	"github.com/bethropolis/localgo/pkg/config"
+	"github.com/bethropolis/localgo/pkg/crypto"
+	"github.com/bethropolis/localgo/pkg/history"
+	"github.com/bethropolis/localgo/pkg/server/services"
+	"github.com/godbus/dbus/v5"
+	"go.uber.org/zap"
+)
+
+// privateSessionBus starts a session bus for the test and points
+// DBUS_SESSION_BUS_ADDRESS at it.
+func privateSessionBus(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("dbus-daemon"); err != nil {
+		t.Skip("dbus-daemon not installed")
+	}
+	cmd := exec.Command("dbus-daemon", "--session", "--nofork", "--print-address=1")
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		cmd.Process.Kill()
+		cmd.Wait()
+	})
+	addr, err := bufio.NewReader(out).ReadString('\n')
+	if err != nil {
+		t.Fatalf("dbus-daemon printed no address: %v", err)
+	}
+	addr = strings.TrimSpace(addr)
+	t.Setenv("DBUS_SESSION_BUS_ADDRESS", addr)
+	return addr
+}
+
+func TestStart_DBus(t *testing.T) {
+	addr := privateSessionBus(t)
+	cfg := &config.Config{
+		Alias:           "Test",
+		Port:            0,
+		HistoryFile:     history.DisabledSentinel,
+		DownloadDir:     t.TempDir(),
+		SecurityContext: &crypto.StoredSecurityContext{},
+		DBus:            true,
+	}
+	srv := NewServer(cfg, zap.NewNop().Sugar())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ready := make(chan struct{}, 1)
+	errCh := make(chan error, 1)
+	go func() { errCh <- srv.Start(ctx, ready) }()
+	select {
+	case <-ready:
+	case err := <-errCh:
+		t.Fatalf("server failed to start: %v", err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("server did not become ready")
+	}
+	if srv.stopDBus == nil {
+		t.Fatal("D-Bus interface not published")
+	}
+
+	conn, err := dbus.Connect(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	obj := conn.Object(dbusName, dbusPath)
+
+	var devices []dbusDevice
+	if err := obj.Call(dbusInterface+".ListDevices", 0).Store(&devices); err != nil || len(devices) != 0 {
+		t.Errorf("ListDevices = %v, %v; want none", devices, err)
+	}
+
+	file := filepath.Join(t.TempDir(), "a.txt")
+	if err := os.WriteFile(file, []byte("a"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var id int64
+	if err := obj.Call(dbusInterface+".SendFile", 0, []string{"file://" + file}, "192.0.2.1").Store(&id); err != nil {
+		t.Fatalf("SendFile: %v", err)
+	}
+	if jobs := srv.queue.List(); len(jobs) != 1 || int64(jobs[0].ID) != id || jobs[0].IP != "192.0.2.1" || jobs[0].Files[0] != file {
+		t.Errorf("queued %+v, want job %d sending %s to 192.0.2.1", jobs, id, file)
+	}
+	if err := obj.Call(dbusInterface+".SendFile", 0, []string{"a.txt"}, "Phone").Err; err == nil {
+		t.Error("SendFile accepted a relative path")
+	}
+
+	if err := conn.AddMatchSignal(dbus.WithMatchInterface(dbusInterface)); err != nil {
+		t.Fatal(err)
+	}
+	signals := make(chan *dbus.Signal, 4)
+	conn.Signal(signals)
+	srv.events.Publish(services.Event{Type: cli.EventSessionCompleted, SessionID: "s1"})
+	select {
+	case sig := <-signals:
+		if sig.Name != dbusInterface+".TransferCompleted" || len(sig.Body) != 1 || sig.Body[0] != "s1" {
+			t.Errorf("unexpected signal %s %v", sig.Name, sig.Body)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("no signal for a completed session")
+	}
+
+	// A second server does not take over the name.
+	if stop, err := NewServer(cfg, zap.NewNop().Sugar()).startDBus(); err == nil {
+		stop()
+		t.Error("second server claimed the bus name")
+	}
+
+	cancel()
+	if err := <-errCh; err != nil {
+		t.Errorf("server shutdown failed: %v", err)
+	}
+}
+
+func TestStartDBus_StopBeforeShutdown(t *testing.T) {
+	privateSessionBus(t)
+	cfg := &config.Config{
+		Alias:           "Test",
+		HistoryFile:     history.DisabledSentinel,
+		DownloadDir:     t.TempDir(),
+		SecurityContext: &crypto.StoredSecurityContext{},
+		DBus:            true,
+	}
+	srv := NewServer(cfg, zap.NewNop().Sugar())
+	stop, err := srv.startDBus()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Ending the subscription while the server still runs must end the
+	// signal loop rather than spin on the closed channel.
+	stopped := make(chan struct{})
+	go func() {
+		stop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("stop did not return")
+	}
+}
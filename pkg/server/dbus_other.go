@@ -0,0 +1,10 @@
+//go:build !linux
+
+package server
+
+import "errors"
+
+// startDBus fails where there is no desktop session bus to publish on.
+func (s *Server) startDBus() (func(), error) {
+	return nil, errors.New("D-Bus is only available on Linux")
+}
@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"strings"
+
+	"github.com/bethropolis/localgo/pkg/cli"
+	"github.com/bethropolis/localgo/pkg/config"
+	"github.com/bethropolis/localgo/pkg/model"
+)
+
+// acceptDecision resolves how a prepare-upload request is handled using the
+// configured accept rules. Without a matching rule the transfer is accepted
+// when AutoAccept is set and prompted otherwise, and the PIN is required
+// whenever one is configured. While quick save is on, transfers that would
+// be prompted are accepted instead; reject rules and the PIN still apply.
+// Skip rules are not considered here; see skipFiles. verified tells whether
+// the sender proved it holds fingerprint; only then can rules naming its
+// fingerprint or trusting it waive the prompt or the PIN.
+func (h *ReceiveHandler) acceptDecision(fingerprint string, verified bool, files map[string]model.FileDto) (config.AcceptAction, bool) {
+	action, pinRequired := h.ruleDecision(fingerprint, verified, files)
+	if action == config.AcceptActionPrompt {
+		if quickSave, _ := h.receiveService.QuickSave(); quickSave {
+			action = config.AcceptActionAccept
+		}
+	}
+	return action, pinRequired
+}
+
+func (h *ReceiveHandler) ruleDecision(fingerprint string, verified bool, files map[string]model.FileDto) (config.AcceptAction, bool) {
+	action := config.AcceptActionPrompt
+	if h.config.AutoAccept {
+		action = config.AcceptActionAccept
+	}
+	pinRequired := h.config.PIN != ""
+	if len(h.config.AcceptRules) == 0 {
+		return action, pinRequired
+	}
+
+	facts := config.TransferFacts{Fingerprint: fingerprint, Verified: verified}
+	for _, f := range files {
+		facts.TotalSize += f.Size
+		if hasExecutableExt(f.FileName) {
+			facts.Executable = true
+		}
+	}
+
+	rule := h.config.MatchAcceptRule(facts)
+	if rule == nil {
+		return action, pinRequired
+	}
+	h.logger.Debugf("Accept rule matched: action=%s", rule.Action)
+	if rule.PIN != nil {
+		pinRequired = *rule.PIN && h.config.PIN != ""
+	}
+	return rule.Action, pinRequired
+}
+
+// skipFiles removes the files matched by a skip rule from files, each judged
+// on its own, and returns how many it removed.
+func (h *ReceiveHandler) skipFiles(fingerprint string, verified bool, files map[string]model.FileDto) int {
+	skipped := 0
+	for id, f := range files {
+		facts := config.TransferFacts{Fingerprint: fingerprint, Verified: verified, TotalSize: f.Size, Executable: hasExecutableExt(f.FileName)}
+		if h.config.SkipsFile(facts) {
+			h.logger.Debugf("Accept rule skipped file %s", cli.Sanitize(f.FileName))
+			delete(files, id)
+			skipped++
+		}
+	}
+	return skipped
+}
+
+// senderAllowed reports whether the sender with alias may start a transfer,
+// which is always the case unless AllowedSenders is set. An entry that is a
+// fingerprint prefix only matches a sender whose certificate, with
+// fingerprint verified, has it; an alias is taken on the sender's word.
+func (h *ReceiveHandler) senderAllowed(alias, verified string) bool {
+	if len(h.config.AllowedSenders) == 0 {
+		return true
+	}
+	for _, allowed := range h.config.AllowedSenders {
+		if config.IsFingerprintPrefix(allowed) {
+			if verified != "" && strings.HasPrefix(verified, strings.ToLower(allowed)) {
+				return true
+			}
+		} else if alias == allowed {
+			return true
+		}
+	}
+	return false
+}
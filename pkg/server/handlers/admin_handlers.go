@@ -0,0 +1,322 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/bethropolis/localgo/pkg/config"
+	"github.com/bethropolis/localgo/pkg/httputil"
+	"github.com/bethropolis/localgo/pkg/model"
+	"github.com/bethropolis/localgo/pkg/queue"
+	"github.com/bethropolis/localgo/pkg/server/services"
+	"github.com/bethropolis/localgo/pkg/transfer"
+	"go.uber.org/zap"
+)
+
+// AdminHandler handles local control requests under /api/localgo. These
+// routes only answer the machine the server runs on; see LocalOnly.
+type AdminHandler struct {
+	receiveService *services.ReceiveService
+	sendService    *services.SendService
+	registry       *services.RegistryService
+	queue          *queue.Queue
+	transfers      *transfer.Manager
+	events         *services.EventBroker
+	logger         *zap.SugaredLogger
+}
+
+// NewAdminHandler creates a new AdminHandler.
+func NewAdminHandler(receiveService *services.ReceiveService, sendService *services.SendService, registry *services.RegistryService, sendQueue *queue.Queue, events *services.EventBroker, logger *zap.SugaredLogger) *AdminHandler {
+	return &AdminHandler{
+		receiveService: receiveService,
+		sendService:    sendService,
+		registry:       registry,
+		queue:          sendQueue,
+		events:         events,
+		logger:         logger,
+	}
+}
+
+// SetTransfers makes the handler list and cancel the transfers of m.
+func (h *AdminHandler) SetTransfers(m *transfer.Manager) {
+	h.transfers = m
+}
+
+// LocalOnly rejects requests that do not come from a loopback address or
+// that carry an Origin header, so neither LAN peers nor web pages can reach
+// the admin API.
+func LocalOnly(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, _, err := net.SplitHostPort(r.RemoteAddr)
+		ip := net.ParseIP(host)
+		if err != nil || ip == nil || !ip.IsLoopback() || r.Header.Get("Origin") != "" {
+			httputil.RespondError(w, http.StatusForbidden, "Forbidden")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RequireToken rejects requests that do not carry the bearer token stored at
+// path, so other users on the machine cannot use the admin API. The file is
+// read for every request, so a rotated token takes effect at once.
+func RequireToken(path string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !config.CheckAdminToken(path, r.Header.Get("Authorization")) {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				httputil.RespondError(w, http.StatusUnauthorized, "Unauthorized")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// QuickSaveHandler handles /v1/quick-save: GET reports the state, POST
+// enables quick save (for ?duration= if given) and DELETE disables it.
+func (h *AdminHandler) QuickSaveHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		var d time.Duration
+		if ds := r.URL.Query().Get("duration"); ds != "" {
+			parsed, err := time.ParseDuration(ds)
+			if err != nil || parsed <= 0 {
+				httputil.RespondError(w, http.StatusBadRequest, "Invalid duration")
+				return
+			}
+			d = parsed
+		}
+		h.receiveService.EnableQuickSave(d)
+		if d > 0 {
+			h.logger.Infof("Quick save enabled for %s", d)
+		} else {
+			h.logger.Info("Quick save enabled")
+		}
+	case http.MethodDelete:
+		h.receiveService.DisableQuickSave()
+		h.logger.Info("Quick save disabled")
+	default:
+		httputil.RespondError(w, http.StatusMethodNotAllowed, "Method Not Allowed")
+		return
+	}
+	httputil.RespondJSON(w, http.StatusOK, h.quickSaveStatus())
+}
+
+func (h *AdminHandler) quickSaveStatus() model.QuickSaveDto {
+	enabled, until := h.receiveService.QuickSave()
+	dto := model.QuickSaveDto{Enabled: enabled}
+	if enabled && !until.IsZero() {
+		s := until.Format(time.RFC3339)
+		dto.Until = &s
+	}
+	return dto
+}
+
+// StatusHandler handles GET /v1/status: the active receive sessions with
+// per-file progress, the files being shared, and the last sessions to end.
+func (h *AdminHandler) StatusHandler(w http.ResponseWriter, r *http.Request) {
+	httputil.RespondJSON(w, http.StatusOK, model.ServerStatusDto{
+		Sessions:  h.receiveService.Status(),
+		Sharing:   h.sendService.Status(),
+		Recent:    h.receiveService.Recent(),
+		QuickSave: h.quickSaveStatus(),
+	})
+}
+
+// SessionHandler handles GET /v1/session?id=: the progress of one active
+// receive session, for clients following a single transfer. It answers 404
+// once the session has ended.
+func (h *AdminHandler) SessionHandler(w http.ResponseWriter, r *http.Request) {
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		httputil.RespondError(w, http.StatusBadRequest, "Missing id parameter")
+		return
+	}
+	status, ok := h.receiveService.SessionStatus(id)
+	if !ok {
+		httputil.RespondError(w, http.StatusNotFound, "Session not found")
+		return
+	}
+	httputil.RespondJSON(w, http.StatusOK, status)
+}
+
+// DevicesHandler handles GET /v1/devices: every device the server has heard
+// from, by discovery or registration, with when it was last seen.
+func (h *AdminHandler) DevicesHandler(w http.ResponseWriter, r *http.Request) {
+	devices := h.registry.GetDevices()
+	snapshots := make([]*model.Device, 0, len(devices))
+	for _, d := range devices {
+		snapshots = append(snapshots, d.Snapshot())
+	}
+	httputil.RespondJSON(w, http.StatusOK, snapshots)
+}
+
+// QueueHandler handles /v1/queue: GET lists the send queue, POST adds the
+// job in the body and answers with it as queued, and DELETE ?id= removes a
+// job, stopping it if it is being sent, and answers with the list.
+func (h *AdminHandler) QueueHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		var job queue.Job
+		if err := json.NewDecoder(r.Body).Decode(&job); err != nil {
+			httputil.RespondError(w, http.StatusBadRequest, "Invalid job")
+			return
+		}
+		added, err := h.queue.Add(job)
+		if err != nil {
+			httputil.RespondError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		httputil.RespondJSON(w, http.StatusOK, added)
+		return
+	case http.MethodDelete:
+		id, ok := jobID(w, r)
+		if !ok || !h.respondQueueError(w, h.queue.Remove(id)) {
+			return
+		}
+	default:
+		httputil.RespondError(w, http.StatusMethodNotAllowed, "Method Not Allowed")
+		return
+	}
+	httputil.RespondJSON(w, http.StatusOK, h.queue.List())
+}
+
+// QueueRetryHandler handles POST /v1/queue/retry?id=: a failed job gets a
+// fresh set of attempts, and a job waiting to be retried runs right away.
+func (h *AdminHandler) QueueRetryHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := jobID(w, r)
+	if !ok || !h.respondQueueError(w, h.queue.Retry(id)) {
+		return
+	}
+	httputil.RespondJSON(w, http.StatusOK, h.queue.List())
+}
+
+// QueuePriorityHandler handles POST /v1/queue/priority?id=&priority=,
+// changing the priority of a waiting job.
+func (h *AdminHandler) QueuePriorityHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := jobID(w, r)
+	if !ok {
+		return
+	}
+	priority, err := strconv.Atoi(r.URL.Query().Get("priority"))
+	if err != nil {
+		httputil.RespondError(w, http.StatusBadRequest, "Invalid priority")
+		return
+	}
+	_, err = h.queue.SetPriority(id, priority)
+	if !h.respondQueueError(w, err) {
+		return
+	}
+	httputil.RespondJSON(w, http.StatusOK, h.queue.List())
+}
+
+// TransfersHandler handles /v1/transfers: GET lists the running and recent
+// transfers in both directions, and DELETE ?id= cancels one and answers with
+// the list. A cancelled send of a queued job counts as a failed attempt;
+// remove the job to stop it for good.
+func (h *AdminHandler) TransfersHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodDelete:
+		id, err := strconv.Atoi(r.URL.Query().Get("id"))
+		if err != nil {
+			httputil.RespondError(w, http.StatusBadRequest, "Invalid transfer ID")
+			return
+		}
+		if err := h.transfers.Cancel(id); err != nil {
+			httputil.RespondError(w, http.StatusNotFound, err.Error())
+			return
+		}
+	default:
+		httputil.RespondError(w, http.StatusMethodNotAllowed, "Method Not Allowed")
+		return
+	}
+	httputil.RespondJSON(w, http.StatusOK, h.transfers.List())
+}
+
+// jobID parses the id query parameter, answering 400 if it is missing or
+// invalid.
+func jobID(w http.ResponseWriter, r *http.Request) (int, bool) {
+	id, err := strconv.Atoi(r.URL.Query().Get("id"))
+	if err != nil {
+		httputil.RespondError(w, http.StatusBadRequest, "Invalid job ID")
+		return 0, false
+	}
+	return id, true
+}
+
+// respondQueueError answers with the status for a queue error and reports
+// whether there was none.
+func (h *AdminHandler) respondQueueError(w http.ResponseWriter, err error) bool {
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, queue.ErrNotFound):
+		httputil.RespondError(w, http.StatusNotFound, err.Error())
+	default:
+		httputil.RespondError(w, http.StatusConflict, err.Error())
+	}
+	return false
+}
+
+// eventKeepAlive is how often an idle event stream sends a comment line, so
+// clients and proxies can tell a quiet server from a dead connection.
+const eventKeepAlive = 15 * time.Second
+
+// EventsHandler handles GET /events: a Server-Sent Events stream of session,
+// progress and discovery events, one JSON object per event, until the client
+// disconnects or the server stops.
+func (h *AdminHandler) EventsHandler(w http.ResponseWriter, r *http.Request) {
+	rc := http.NewResponseController(w)
+	// The stream is long-lived; drop any deadlines left on the connection.
+	_ = rc.SetReadDeadline(time.Time{})
+	_ = rc.SetWriteDeadline(time.Time{})
+
+	events, unsubscribe := h.events.Subscribe()
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	if _, err := io.WriteString(w, ": connected\n\n"); err != nil {
+		return
+	}
+	if err := rc.Flush(); err != nil {
+		h.logger.Warnf("Event stream not supported by response writer: %v", err)
+		return
+	}
+
+	keepAlive := time.NewTicker(eventKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepAlive.C:
+			if _, err := io.WriteString(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+		case e := <-events:
+			data, err := json.Marshal(e)
+			if err != nil {
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", e.ID, e.Type, data); err != nil {
+				return
+			}
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,324 @@
+package handlers_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bethropolis/localgo/pkg/config"
+	"github.com/bethropolis/localgo/pkg/model"
+	"github.com/bethropolis/localgo/pkg/queue"
+	"github.com/bethropolis/localgo/pkg/server/handlers"
+	"github.com/bethropolis/localgo/pkg/server/services"
+	"github.com/bethropolis/localgo/pkg/transfer"
+)
+
+func TestLocalOnly(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	handler := handlers.LocalOnly(next)
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		origin     string
+		wantStatus int
+	}{
+		{"IPv4 loopback", "127.0.0.1:50000", "", http.StatusOK},
+		{"IPv6 loopback", "[::1]:50000", "", http.StatusOK},
+		{"LAN peer", "192.168.1.100:50000", "", http.StatusForbidden},
+		{"browser on localhost", "127.0.0.1:50000", "http://localhost:8080", http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodGet, "/api/localgo/v1/quick-save", nil)
+			req.RemoteAddr = tt.remoteAddr
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tt.wantStatus {
+				t.Errorf("got status %d, want %d", rr.Code, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestRequireToken(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	path := filepath.Join(t.TempDir(), config.AdminTokenFile)
+	token, err := config.LoadOrCreateAdminToken(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := handlers.RequireToken(path)(next)
+	do := func(auth string) int {
+		req, _ := http.NewRequest(http.MethodGet, "/api/localgo/v1/status", nil)
+		if auth != "" {
+			req.Header.Set("Authorization", auth)
+		}
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	if code := do("Bearer " + token); code != http.StatusOK {
+		t.Errorf("with the token: status = %d, want %d", code, http.StatusOK)
+	}
+	for _, auth := range []string{"", "Bearer wrong", token} {
+		if code := do(auth); code != http.StatusUnauthorized {
+			t.Errorf("Authorization %q: status = %d, want %d", auth, code, http.StatusUnauthorized)
+		}
+	}
+
+	rotated, err := config.RotateAdminToken(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if code := do("Bearer " + token); code != http.StatusUnauthorized {
+		t.Errorf("old token after rotation: status = %d, want %d", code, http.StatusUnauthorized)
+	}
+	if code := do("Bearer " + rotated); code != http.StatusOK {
+		t.Errorf("new token after rotation: status = %d, want %d", code, http.StatusOK)
+	}
+}
+
+func TestAdminHandler_QuickSave(t *testing.T) {
+	receiveService := services.NewReceiveService()
+	defer receiveService.Close()
+	handler := handlers.NewAdminHandler(receiveService, nil, nil, nil, nil, testLogger)
+
+	do := func(method, query string) (int, model.QuickSaveDto) {
+		req, _ := http.NewRequest(method, "/api/localgo/v1/quick-save"+query, nil)
+		rr := httptest.NewRecorder()
+		handler.QuickSaveHandler(rr, req)
+		var dto model.QuickSaveDto
+		json.NewDecoder(rr.Body).Decode(&dto)
+		return rr.Code, dto
+	}
+
+	if code, dto := do(http.MethodGet, ""); code != http.StatusOK || dto.Enabled {
+		t.Errorf("expected quick save off initially, got %d %+v", code, dto)
+	}
+	if code, dto := do(http.MethodPost, "?duration=10m"); code != http.StatusOK || !dto.Enabled || dto.Until == nil {
+		t.Errorf("expected quick save on with expiry, got %d %+v", code, dto)
+	}
+	if on, _ := receiveService.QuickSave(); !on {
+		t.Error("expected receive service to have quick save on")
+	}
+	if code, _ := do(http.MethodPost, "?duration=soon"); code != http.StatusBadRequest {
+		t.Errorf("expected 400 for invalid duration, got %d", code)
+	}
+	if code, dto := do(http.MethodDelete, ""); code != http.StatusOK || dto.Enabled {
+		t.Errorf("expected quick save off after DELETE, got %d %+v", code, dto)
+	}
+}
+
+func TestAdminHandler_Session(t *testing.T) {
+	receiveService := services.NewReceiveService()
+	defer receiveService.Close()
+	handler := handlers.NewAdminHandler(receiveService, nil, nil, nil, nil, testLogger)
+
+	sender := model.DeviceInfo{Alias: "Phone", IP: "192.168.1.20"}
+	session, _ := receiveService.CreateSession(sender, map[string]model.FileDto{
+		"a": {ID: "a", FileName: "a.bin", Size: 100},
+		"b": {ID: "b", FileName: "b.bin", Size: 300},
+	})
+	receiveService.ClaimFile(session.SessionID, "a", session.Files["a"].Token, sender.IP)
+	receiveService.TrackFile(session.SessionID, "a", nil)(40)
+
+	do := func(query string) (int, model.SessionStatusDto) {
+		req, _ := http.NewRequest(http.MethodGet, "/api/localgo/v1/session"+query, nil)
+		rr := httptest.NewRecorder()
+		handler.SessionHandler(rr, req)
+		var dto model.SessionStatusDto
+		json.NewDecoder(rr.Body).Decode(&dto)
+		return rr.Code, dto
+	}
+
+	code, dto := do("?id=" + session.SessionID)
+	if code != http.StatusOK {
+		t.Fatalf("got status %d, want %d", code, http.StatusOK)
+	}
+	if dto.SessionID != session.SessionID || dto.Size != 400 || dto.Bytes != 40 || len(dto.Files) != 2 {
+		t.Errorf("unexpected session status: %+v", dto)
+	}
+	if code, _ := do("?id=unknown"); code != http.StatusNotFound {
+		t.Errorf("got status %d for an unknown session, want %d", code, http.StatusNotFound)
+	}
+	if code, _ := do(""); code != http.StatusBadRequest {
+		t.Errorf("got status %d without an id, want %d", code, http.StatusBadRequest)
+	}
+}
+
+func TestAdminHandler_Devices(t *testing.T) {
+	registry := services.NewRegistryService()
+	seen := time.Now().Add(-5 * time.Minute).Truncate(time.Second)
+	device := &model.Device{IP: "192.168.1.20", Port: 53317, Alias: "Phone", Fingerprint: "AAAA1111", LastSeen: seen}
+	registry.RegisterDevice(device)
+	handler := handlers.NewAdminHandler(nil, nil, registry, nil, nil, testLogger)
+
+	req, _ := http.NewRequest(http.MethodGet, "/api/localgo/v1/devices", nil)
+	rr := httptest.NewRecorder()
+	handler.DevicesHandler(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got status %d, want %d", rr.Code, http.StatusOK)
+	}
+	var devices []*model.Device
+	if err := json.NewDecoder(rr.Body).Decode(&devices); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(devices) != 1 || devices[0].Alias != "Phone" || devices[0].Fingerprint != "AAAA1111" {
+		t.Fatalf("unexpected devices: %+v", devices)
+	}
+	if !devices[0].LastSeen.Equal(seen) {
+		t.Errorf("got last seen %v, want %v", devices[0].LastSeen, seen)
+	}
+}
+
+func TestAdminHandler_Queue(t *testing.T) {
+	sendQueue := queue.New(queue.Options{}, nil, nil)
+	handler := handlers.NewAdminHandler(nil, nil, nil, sendQueue, nil, testLogger)
+	do := func(method, target, body string, h http.HandlerFunc) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, target, strings.NewReader(body))
+		rr := httptest.NewRecorder()
+		h(rr, req)
+		return rr
+	}
+
+	rr := do(http.MethodPost, "/api/localgo/v1/queue", `{"files":["/tmp/a.txt"],"to":"Phone"}`, handler.QueueHandler)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("adding a job: got status %d: %s", rr.Code, rr.Body)
+	}
+	var job queue.Job
+	if err := json.NewDecoder(rr.Body).Decode(&job); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if job.ID == 0 || job.Status != queue.StatusQueued {
+		t.Fatalf("unexpected job: %+v", job)
+	}
+
+	if rr := do(http.MethodPost, "/api/localgo/v1/queue", `{"files":["a.txt"],"to":"Phone"}`, handler.QueueHandler); rr.Code != http.StatusBadRequest {
+		t.Errorf("adding a relative path: got status %d, want %d", rr.Code, http.StatusBadRequest)
+	}
+	if rr := do(http.MethodPost, "/api/localgo/v1/queue/priority?id=1&priority=7", "", handler.QueuePriorityHandler); rr.Code != http.StatusOK {
+		t.Errorf("changing the priority: got status %d: %s", rr.Code, rr.Body)
+	}
+	if rr := do(http.MethodPost, "/api/localgo/v1/queue/retry?id=99", "", handler.QueueRetryHandler); rr.Code != http.StatusNotFound {
+		t.Errorf("retrying a missing job: got status %d, want %d", rr.Code, http.StatusNotFound)
+	}
+
+	rr = do(http.MethodGet, "/api/localgo/v1/queue", "", handler.QueueHandler)
+	var jobs []queue.Job
+	if err := json.NewDecoder(rr.Body).Decode(&jobs); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(jobs) != 1 || jobs[0].Priority != 7 {
+		t.Fatalf("unexpected queue: %+v", jobs)
+	}
+
+	rr = do(http.MethodDelete, "/api/localgo/v1/queue?id=1", "", handler.QueueHandler)
+	jobs = nil
+	if err := json.NewDecoder(rr.Body).Decode(&jobs); err != nil || len(jobs) != 0 {
+		t.Fatalf("removing the job: got %+v, %v", jobs, err)
+	}
+}
+
+func TestAdminHandler_Transfers(t *testing.T) {
+	transfers := transfer.NewManager(0)
+	handler := handlers.NewAdminHandler(nil, nil, nil, nil, nil, testLogger)
+	handler.SetTransfers(transfers)
+	h := transfers.Start(context.Background(), transfer.Send, "Phone", func(ctx context.Context, _ func(transfer.Progress)) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	req, _ := http.NewRequest(http.MethodDelete, "/api/localgo/v1/transfers?id=99", nil)
+	rr := httptest.NewRecorder()
+	handler.TransfersHandler(rr, req)
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("cancelling a missing transfer: got status %d, want %d", rr.Code, http.StatusNotFound)
+	}
+
+	req, _ = http.NewRequest(http.MethodDelete, fmt.Sprintf("/api/localgo/v1/transfers?id=%d", h.ID()), nil)
+	rr = httptest.NewRecorder()
+	handler.TransfersHandler(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("cancelling: got status %d: %s", rr.Code, rr.Body)
+	}
+	h.Wait()
+
+	req, _ = http.NewRequest(http.MethodGet, "/api/localgo/v1/transfers", nil)
+	rr = httptest.NewRecorder()
+	handler.TransfersHandler(rr, req)
+	var list []transfer.Info
+	if err := json.NewDecoder(rr.Body).Decode(&list); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(list) != 1 || list[0].Peer != "Phone" || list[0].Status != transfer.StatusCancelled {
+		t.Errorf("unexpected transfers: %+v", list)
+	}
+}
+
+func TestAdminHandler_Events(t *testing.T) {
+	events := services.NewEventBroker()
+	handler := handlers.NewAdminHandler(nil, nil, nil, nil, events, testLogger)
+	srv := httptest.NewServer(http.HandlerFunc(handler.EventsHandler))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("got Content-Type %q, want text/event-stream", ct)
+	}
+
+	reader := bufio.NewReader(resp.Body)
+	readLine := func() string {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("reading stream: %v", err)
+		}
+		return strings.TrimSuffix(line, "\n")
+	}
+
+	// The handler subscribes before sending the opening comment.
+	if line := readLine(); line != ": connected" {
+		t.Fatalf("unexpected first line %q", line)
+	}
+	readLine()
+
+	events.Publish(services.Event{Type: services.EventDeviceDiscovered, Device: &services.EventDevice{Alias: "Phone"}})
+
+	if line := readLine(); line != "id: 1" {
+		t.Errorf("unexpected id line %q", line)
+	}
+	if line := readLine(); line != "event: "+services.EventDeviceDiscovered {
+		t.Errorf("unexpected event line %q", line)
+	}
+	data, ok := strings.CutPrefix(readLine(), "data: ")
+	if !ok {
+		t.Fatal("missing data line")
+	}
+	var e services.Event
+	if err := json.Unmarshal([]byte(data), &e); err != nil || e.Device == nil || e.Device.Alias != "Phone" {
+		t.Errorf("unexpected event data %q (%v)", data, err)
+	}
+}
@@ -0,0 +1,232 @@
+package handlers
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/bethropolis/localgo/pkg/cli"
+	"github.com/bethropolis/localgo/pkg/config"
+	"github.com/bethropolis/localgo/pkg/httputil"
+	"github.com/bethropolis/localgo/pkg/model"
+	"github.com/bethropolis/localgo/pkg/validate"
+	"github.com/charmbracelet/huh"
+	"github.com/google/uuid"
+)
+
+const (
+	// maxBenchmarkSize bounds how much data a single speed test may send.
+	maxBenchmarkSize = 10 << 30 // 10 GiB
+
+	// benchmarkExpiry is how long an accepted speed test waits for its upload.
+	benchmarkExpiry = time.Minute
+)
+
+// benchmarkSession is an accepted speed test: one upload of size bytes from
+// the sender, which is read and discarded.
+type benchmarkSession struct {
+	sender  model.DeviceInfo
+	fileID  string
+	token   string
+	size    int64
+	expires time.Time
+}
+
+// BenchmarkPrepareHandler handles POST /api/localgo/v1/bench/prepare-upload.
+// It takes the same request as /prepare-upload and applies the same sender
+// filter, accept rules and PIN, but the upload that follows is discarded.
+func (h *ReceiveHandler) BenchmarkPrepareHandler(w http.ResponseWriter, r *http.Request) {
+	if h.shutdownCtx.Err() != nil {
+		httputil.RespondError(w, http.StatusServiceUnavailable, "Server shutting down")
+		return
+	}
+
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1024*1024))
+	var requestDto model.PrepareUploadRequestDto
+	if err := decoder.Decode(&requestDto); err != nil {
+		respondDecodeError(w, err)
+		return
+	}
+	defer r.Body.Close()
+	if err := validate.PrepareUpload(&requestDto); err != nil {
+		respondInvalid(w, err)
+		return
+	}
+
+	if len(requestDto.Files) != 1 {
+		httputil.RespondError(w, http.StatusBadRequest, "A speed test sends exactly one file")
+		return
+	}
+	var fileID string
+	var file model.FileDto
+	for id, f := range requestDto.Files {
+		fileID, file = id, f
+	}
+	if file.Size <= 0 || file.Size > maxBenchmarkSize {
+		httputil.RespondError(w, http.StatusBadRequest, fmt.Sprintf("Speed test size must be between 1 byte and %s", cli.FormatBytes(maxBenchmarkSize)))
+		return
+	}
+
+	if !h.senderAllowed(requestDto.Info.Alias, httputil.VerifiedFingerprint(r)) {
+		h.logger.Infof("Rejected speed test from %s: not an allowed sender", cli.Sanitize(requestDto.Info.Alias))
+		httputil.RespondError(w, http.StatusForbidden, "Rejected")
+		return
+	}
+	action, pinRequired := h.acceptDecision(requestDto.Info.Fingerprint, httputil.IsVerifiedSender(r, requestDto.Info.Fingerprint), requestDto.Files)
+	if pinRequired {
+		pin := r.URL.Query().Get("pin")
+		if subtle.ConstantTimeCompare([]byte(pin), []byte(h.config.PIN)) != 1 {
+			httputil.RespondError(w, http.StatusUnauthorized, "Invalid PIN")
+			return
+		}
+	}
+	if action == config.AcceptActionReject {
+		httputil.RespondError(w, http.StatusForbidden, "Rejected")
+		return
+	}
+
+	senderIP, _, _ := net.SplitHostPort(r.RemoteAddr)
+	sender := model.DeviceInfo{
+		Alias:       cli.Sanitize(requestDto.Info.Alias),
+		Version:     requestDto.Info.Version,
+		DeviceModel: requestDto.Info.DeviceModel,
+		DeviceType:  requestDto.Info.DeviceType,
+		Fingerprint: requestDto.Info.Fingerprint,
+		IP:          senderIP,
+	}
+
+	if action == config.AcceptActionPrompt {
+		h.promptMutex.Lock()
+		accepted := h.promptForBenchmark(sender, file.Size)
+		h.promptMutex.Unlock()
+		if !accepted {
+			httputil.RespondError(w, http.StatusForbidden, "Rejected")
+			return
+		}
+	}
+
+	session := &benchmarkSession{
+		sender:  sender,
+		fileID:  fileID,
+		token:   uuid.NewString(),
+		size:    file.Size,
+		expires: time.Now().Add(benchmarkExpiry),
+	}
+	sessionID := uuid.NewString()
+	h.benchMu.Lock()
+	if h.benchmarks == nil {
+		h.benchmarks = make(map[string]*benchmarkSession)
+	}
+	for id, s := range h.benchmarks {
+		if time.Now().After(s.expires) {
+			delete(h.benchmarks, id)
+		}
+	}
+	h.benchmarks[sessionID] = session
+	h.benchMu.Unlock()
+
+	h.logger.Infof("Accepted %s speed test from %s (%s)", cli.FormatBytes(file.Size), sender.Alias, senderIP)
+	httputil.RespondJSON(w, http.StatusOK, model.PrepareUploadResponseDto{
+		SessionID: sessionID,
+		Files:     map[string]string{fileID: session.token},
+	})
+}
+
+// BenchmarkUploadHandler handles POST /api/localgo/v1/bench/upload. It reads
+// the body exactly as /upload does and discards it.
+func (h *ReceiveHandler) BenchmarkUploadHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	sessionID := query.Get("sessionId")
+	reqIP, _, _ := net.SplitHostPort(r.RemoteAddr)
+
+	// Each speed test is used once, so an authorized request takes it out of
+	// the table right away. Others leave it, so they cannot cancel it.
+	h.benchMu.Lock()
+	session := h.benchmarks[sessionID]
+	valid := session != nil && !time.Now().After(session.expires) && session.sender.IP == reqIP &&
+		query.Get("fileId") == session.fileID &&
+		subtle.ConstantTimeCompare([]byte(query.Get("token")), []byte(session.token)) == 1
+	if valid {
+		delete(h.benchmarks, sessionID)
+	}
+	h.benchMu.Unlock()
+
+	if !valid {
+		httputil.RespondError(w, http.StatusForbidden, "Invalid session")
+		return
+	}
+	if r.ContentLength >= 0 && r.ContentLength != session.size {
+		httputil.RespondError(w, http.StatusBadRequest, "Body size does not match declared file size")
+		return
+	}
+
+	started := time.Now()
+	body := &shutdownAwareReader{Reader: &exactSizeReader{r: r.Body, remaining: session.size}, ctx: h.shutdownCtx}
+	defer r.Body.Close()
+	if _, err := io.Copy(io.Discard, body); err != nil {
+		h.logger.Warnf("Speed test from %s failed: %v", session.sender.Alias, err)
+		httputil.RespondError(w, http.StatusBadRequest, "Upload failed")
+		return
+	}
+	elapsed := time.Since(started)
+
+	summary := fmt.Sprintf("Speed test from %s: %s in %s", session.sender.Alias, cli.FormatBytes(session.size), cli.FormatDuration(elapsed))
+	if elapsed > 0 {
+		summary += fmt.Sprintf(" (%s/s)", cli.FormatBytes(int64(float64(session.size)/elapsed.Seconds())))
+	}
+	h.logger.Info(summary)
+	if !h.config.Quiet {
+		cli.PrintInfo("%s", summary)
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// BenchmarkCancelHandler handles POST /api/localgo/v1/bench/cancel. Only the
+// sender of a speed test can cancel it.
+func (h *ReceiveHandler) BenchmarkCancelHandler(w http.ResponseWriter, r *http.Request) {
+	sessionID := r.URL.Query().Get("sessionId")
+	reqIP, _, _ := net.SplitHostPort(r.RemoteAddr)
+	h.benchMu.Lock()
+	if s := h.benchmarks[sessionID]; s != nil && s.sender.IP == reqIP {
+		delete(h.benchmarks, sessionID)
+	}
+	h.benchMu.Unlock()
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *ReceiveHandler) promptForBenchmark(sender model.DeviceInfo, size int64) bool {
+	if cli.Headless() {
+		return false
+	}
+	cli.Notify("LocalGo: Speed Test",
+		fmt.Sprintf("%s wants to run a %s speed test", sender.Alias, cli.FormatBytes(size)))
+
+	desc := fmt.Sprintf("From: %s (IP: %s)\n\nSize: %s\n\nThe data is discarded, nothing is saved.", sender.Alias, sender.IP, cli.FormatBytes(size))
+
+	var accept bool = true
+	form := huh.NewForm(
+		huh.NewGroup(
+			huh.NewConfirm().
+				Title("Accept Speed Test?").
+				Description(desc).
+				Value(&accept).
+				Affirmative("Accept").
+				Negative("Reject"),
+		),
+	).WithTheme(huh.ThemeCharm())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if err := form.RunWithContext(ctx); err != nil {
+		fmt.Fprintf(os.Stderr, "\n%s Speed test automatically rejected.\n", cli.WarningStyle.Render(cli.IconWarning))
+		return false
+	}
+	return accept
+}
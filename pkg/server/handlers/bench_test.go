@@ -0,0 +1,94 @@
+package handlers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/bethropolis/localgo/pkg/config"
+	"github.com/bethropolis/localgo/pkg/model"
+	"github.com/bethropolis/localgo/pkg/server/handlers"
+)
+
+func prepareBenchmark(t *testing.T, handler *handlers.ReceiveHandler, files map[string]model.FileDto) (*httptest.ResponseRecorder, model.PrepareUploadResponseDto) {
+	t.Helper()
+	body, _ := json.Marshal(model.PrepareUploadRequestDto{Info: model.InfoDto{Alias: "Bencher"}, Files: files})
+	req, _ := http.NewRequest(http.MethodPost, "/api/localgo/v1/bench/prepare-upload", bytes.NewReader(body))
+	req.RemoteAddr = "192.168.1.100:12345"
+	rr := httptest.NewRecorder()
+	handler.BenchmarkPrepareHandler(rr, req)
+
+	var resp model.PrepareUploadResponseDto
+	if rr.Code == http.StatusOK {
+		if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+	}
+	return rr, resp
+}
+
+func uploadBenchmark(handler *handlers.ReceiveHandler, sessionID, token, data string) int {
+	req, _ := http.NewRequest(http.MethodPost, "/api/localgo/v1/bench/upload?sessionId="+sessionID+"&fileId=bench&token="+token, strings.NewReader(data))
+	req.RemoteAddr = "192.168.1.100:12345"
+	rr := httptest.NewRecorder()
+	handler.BenchmarkUploadHandler(rr, req)
+	return rr.Code
+}
+
+func TestBenchmarkHandlers(t *testing.T) {
+	handler, _, tempDir := setupReceiveHandler(t, &config.Config{AutoAccept: true, Quiet: true})
+	file := map[string]model.FileDto{"bench": {ID: "bench", FileName: "localgo-bench.bin", Size: 8}}
+
+	two := map[string]model.FileDto{"a": {ID: "a", FileName: "a", Size: 1}, "b": {ID: "b", FileName: "b", Size: 1}}
+	if rr, _ := prepareBenchmark(t, handler, two); rr.Code != http.StatusBadRequest {
+		t.Errorf("two files: got %d, want %d", rr.Code, http.StatusBadRequest)
+	}
+
+	_, resp := prepareBenchmark(t, handler, file)
+	if code := uploadBenchmark(handler, resp.SessionID, "wrong", "01234567"); code != http.StatusForbidden {
+		t.Errorf("wrong token: got %d, want %d", code, http.StatusForbidden)
+	}
+	req, _ := http.NewRequest(http.MethodPost, "/api/localgo/v1/bench/upload?sessionId="+resp.SessionID+"&fileId=bench&token="+resp.Files["bench"], strings.NewReader("01234567"))
+	req.RemoteAddr = "192.168.1.200:12345"
+	rr := httptest.NewRecorder()
+	handler.BenchmarkUploadHandler(rr, req)
+	if rr.Code != http.StatusForbidden {
+		t.Errorf("other IP: got %d, want %d", rr.Code, http.StatusForbidden)
+	}
+	// Unauthorized attempts leave the session to its sender, who uses it up.
+	if code := uploadBenchmark(handler, resp.SessionID, resp.Files["bench"], "01234567"); code != http.StatusOK {
+		t.Errorf("after unauthorized attempts: got %d, want %d", code, http.StatusOK)
+	}
+	if code := uploadBenchmark(handler, resp.SessionID, resp.Files["bench"], "01234567"); code != http.StatusForbidden {
+		t.Errorf("reused session: got %d, want %d", code, http.StatusForbidden)
+	}
+
+	_, resp = prepareBenchmark(t, handler, file)
+	if code := uploadBenchmark(handler, resp.SessionID, resp.Files["bench"], "short"); code != http.StatusBadRequest {
+		t.Errorf("wrong size: got %d, want %d", code, http.StatusBadRequest)
+	}
+
+	_, resp = prepareBenchmark(t, handler, file)
+	if code := uploadBenchmark(handler, resp.SessionID, resp.Files["bench"], "01234567"); code != http.StatusOK {
+		t.Errorf("valid upload: got %d, want %d", code, http.StatusOK)
+	}
+	if entries, _ := os.ReadDir(tempDir); len(entries) != 0 {
+		t.Errorf("speed test data should be discarded, found %d file(s) in the download directory", len(entries))
+	}
+}
+
+func TestBenchmarkPrepareHandler_RejectRule(t *testing.T) {
+	cfg := &config.Config{
+		AutoAccept:  true,
+		AcceptRules: []config.AcceptRule{{Action: config.AcceptActionReject}},
+	}
+	handler, _, _ := setupReceiveHandler(t, cfg)
+	file := map[string]model.FileDto{"bench": {ID: "bench", FileName: "localgo-bench.bin", Size: 8}}
+	if rr, _ := prepareBenchmark(t, handler, file); rr.Code != http.StatusForbidden {
+		t.Errorf("got %d, want %d", rr.Code, http.StatusForbidden)
+	}
+}
@@ -0,0 +1,19 @@
+package handlers
+
+import (
+	"github.com/bethropolis/localgo/pkg/storage"
+)
+
+// dedupe sets opts up to hard-link a received file to an identical one
+// already received, found by the hashes recorded in the history, and
+// returns where the file's hash will be left for the history. Without a
+// history there is nothing to find, and encrypted files never match.
+func (h *ReceiveHandler) dedupe(opts *storage.SaveOptions) *string {
+	sum := new(string)
+	if !h.config.Dedupe || h.historyLog == nil || h.config.EncryptTo != "" {
+		return sum
+	}
+	opts.Hashed = func(s string) { *sum = s }
+	opts.Existing = h.historyLog.Find
+	return sum
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/bethropolis/localgo/pkg/httputil"
 	"github.com/bethropolis/localgo/pkg/model"
 	"github.com/bethropolis/localgo/pkg/server/services"
+	"github.com/bethropolis/localgo/pkg/validate"
 	"go.uber.org/zap"
 )
 
@@ -90,10 +91,15 @@ func (h *DiscoveryHandler) RegisterHandler(w http.ResponseWriter, r *http.Reques
 	err := json.NewDecoder(r.Body).Decode(&requestDto)
 	if err != nil {
 		h.logger.Infof("Error decoding /register request from %s: %v", r.RemoteAddr, err)
-		httputil.RespondError(w, http.StatusBadRequest, "Request body malformed")
+		respondDecodeError(w, err)
 		return
 	}
 	defer r.Body.Close()
+	if err := validate.Register(&requestDto); err != nil {
+		h.logger.Infof("Invalid /register request from %s: %v", r.RemoteAddr, err)
+		respondInvalid(w, err)
+		return
+	}
 
 	if requestDto.Fingerprint == h.config.GetFingerprint() {
 		h.logger.Info("Received /register request from self, ignoring.")
@@ -5,8 +5,9 @@ import (
 	"fmt"
 	"io"
 	"net/http"
-	"os"
+	"strconv"
 
+	"github.com/bethropolis/localgo/pkg/compression"
 	"github.com/bethropolis/localgo/pkg/config"
 	"github.com/bethropolis/localgo/pkg/httputil"
 	"github.com/bethropolis/localgo/pkg/model"
@@ -100,13 +101,19 @@ func (h *DownloadHandler) DownloadHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	localPath, ok := session.FilePaths[fileId]
+	provider, ok := session.Providers[fileId]
 	if !ok {
 		httputil.RespondError(w, http.StatusInternalServerError, "File path mapping missing")
 		return
 	}
 
-	file, err := os.Open(localPath)
+	info, err := provider.Stat()
+	if err != nil {
+		h.logger.Errorf("Failed to stat file for download: %v", err)
+		httputil.RespondError(w, http.StatusInternalServerError, "Failed to read file")
+		return
+	}
+	file, err := provider.Open()
 	if err != nil {
 		h.logger.Errorf("Failed to open file for download: %v", err)
 		httputil.RespondError(w, http.StatusInternalServerError, "Failed to read file")
@@ -115,14 +122,60 @@ func (h *DownloadHandler) DownloadHandler(w http.ResponseWriter, r *http.Request
 	defer file.Close()
 
 	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", fileDto.FileName))
-	w.Header().Set("Content-Type", fileDto.FileType)
-	w.Header().Set("Content-Length", fmt.Sprintf("%d", fileDto.Size))
-	w.WriteHeader(http.StatusOK)
+	if fileDto.FileType != "" {
+		w.Header().Set("Content-Type", fileDto.FileType)
+	}
 
-	_, err = io.Copy(w, file)
-	if err != nil {
-		h.logger.Errorf("Failed to write file to response: %v", err)
+	if h.serveCompressed(w, r, fileDto, file) {
+		return
+	}
+
+	// ServeContent answers Range requests, so an interrupted download can
+	// resume, and hands the file to the connection, which sends it with
+	// sendfile(2) when it is not encrypted. Content that cannot seek, such
+	// as a generated file, is sent whole.
+	if seeker, ok := file.(io.ReadSeeker); ok {
+		http.ServeContent(w, r, fileDto.FileName, info.ModTime, seeker)
 	} else {
-		h.logger.Infof("Successfully sent file: %s", fileDto.FileName)
+		w.Header().Set("Content-Length", strconv.FormatInt(info.Size, 10))
+		if r.Method == http.MethodHead {
+			return
+		}
+		if _, err := io.Copy(w, file); err != nil {
+			h.logger.Warnf("Download of %s failed: %v", fileDto.FileName, err)
+			return
+		}
+	}
+	h.logger.Infof("Served file: %s", fileDto.FileName)
+}
+
+// serveCompressed sends the whole file compressed, when compression is on,
+// the client accepts a coding and the file is worth it. It reports whether
+// it handled the request; ranges are always served uncompressed, since they
+// are ranges of the file as stored.
+func (h *DownloadHandler) serveCompressed(w http.ResponseWriter, r *http.Request, fileDto model.FileDto, file io.Reader) bool {
+	if !h.config.Compress || r.Method != http.MethodGet || r.Header.Get("Range") != "" {
+		return false
+	}
+	encoding := compression.Negotiate(r.Header.Get("Accept-Encoding"))
+	if encoding == "" || !h.config.CompressionPolicy().Compressible(fileDto.FileName, fileDto.FileType, fileDto.Size) {
+		return false
+	}
+	zw, err := compression.NewWriter(w, encoding)
+	if err != nil {
+		return false
+	}
+	w.Header().Set("Content-Encoding", encoding)
+	w.Header().Add("Vary", "Accept-Encoding")
+	w.WriteHeader(http.StatusOK)
+	if _, err := io.Copy(zw, file); err != nil {
+		h.logger.Warnf("Compressed download of %s failed: %v", fileDto.FileName, err)
+		return true
+	}
+	if err := zw.Close(); err != nil {
+		h.logger.Warnf("Compressed download of %s failed: %v", fileDto.FileName, err)
+		return true
 	}
+	h.logger.Infof("Served file: %s (%s)", fileDto.FileName, encoding)
+	return true
 }
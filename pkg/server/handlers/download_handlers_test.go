@@ -1,17 +1,22 @@
 package handlers_test
 
 import (
+	"compress/gzip"
 	"encoding/json"
+	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/bethropolis/localgo/pkg/config"
 	"github.com/bethropolis/localgo/pkg/model"
 	"github.com/bethropolis/localgo/pkg/server/handlers"
 	"github.com/bethropolis/localgo/pkg/server/services"
+	"github.com/bethropolis/localgo/pkg/storage"
 	"go.uber.org/zap"
 )
 
@@ -133,6 +138,84 @@ func TestDownloadHandler_Success(t *testing.T) {
 	}
 }
 
+func TestDownloadHandler_Range(t *testing.T) {
+	handler, sendService, tempDir := setupDownloadHandler(t, nil)
+
+	filePath := filepath.Join(tempDir, "video.mp4")
+	if err := os.WriteFile(filePath, []byte("0123456789"), 0644); err != nil {
+		t.Fatalf("failed to write test file: %v", err)
+	}
+	files := map[string]model.FileDto{"file1": {ID: "file1", FileName: "video.mp4", Size: 10, FileType: "video/mp4"}}
+	session, _ := sendService.CreateSession(files, map[string]string{"file1": filePath})
+
+	// A resumed download asks for the rest of the file.
+	req, _ := http.NewRequest(http.MethodGet, "/v2/download?sessionId="+session.SessionID+"&fileId=file1", nil)
+	req.Header.Set("Range", "bytes=6-")
+	rr := httptest.NewRecorder()
+	handler.DownloadHandler(rr, req)
+
+	if rr.Code != http.StatusPartialContent {
+		t.Fatalf("status %d, want %d", rr.Code, http.StatusPartialContent)
+	}
+	if rr.Body.String() != "6789" {
+		t.Errorf("body %q, want %q", rr.Body.String(), "6789")
+	}
+	if got := rr.Header().Get("Content-Range"); got != "bytes 6-9/10" {
+		t.Errorf("Content-Range %q, want %q", got, "bytes 6-9/10")
+	}
+	if got := rr.Header().Get("Content-Type"); got != "video/mp4" {
+		t.Errorf("Content-Type %q, want video/mp4", got)
+	}
+	if rr.Header().Get("Accept-Ranges") != "bytes" {
+		t.Error("expected Accept-Ranges: bytes")
+	}
+}
+
+func TestDownloadHandler_Compressed(t *testing.T) {
+	handler, sendService, tempDir := setupDownloadHandler(t, &config.Config{
+		Compress:        true,
+		CompressMinSize: 1024,
+		CompressTypes:   []string{"text/*"},
+	})
+	content := strings.Repeat("compressible text\n", 500)
+	filePath := filepath.Join(tempDir, "notes.txt")
+	if err := os.WriteFile(filePath, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write test file: %v", err)
+	}
+	files := map[string]model.FileDto{"file1": {ID: "file1", FileName: "notes.txt", Size: int64(len(content)), FileType: "text/plain; charset=utf-8"}}
+	session, _ := sendService.CreateSession(files, map[string]string{"file1": filePath})
+	url := "/v2/download?sessionId=" + session.SessionID + "&fileId=file1"
+
+	req, _ := http.NewRequest(http.MethodGet, url, nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rr := httptest.NewRecorder()
+	handler.DownloadHandler(rr, req)
+
+	if rr.Code != http.StatusOK || rr.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("status %d, Content-Encoding %q; want 200 gzip", rr.Code, rr.Header().Get("Content-Encoding"))
+	}
+	if rr.Body.Len() >= len(content) {
+		t.Errorf("compressed body is %d bytes, file is %d", rr.Body.Len(), len(content))
+	}
+	zr, err := gzip.NewReader(rr.Body)
+	if err != nil {
+		t.Fatalf("body is not gzip: %v", err)
+	}
+	if got, _ := io.ReadAll(zr); string(got) != content {
+		t.Error("decompressed body does not match the file")
+	}
+
+	// A range is of the file as stored, so it is never compressed.
+	req, _ = http.NewRequest(http.MethodGet, url, nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	req.Header.Set("Range", "bytes=0-9")
+	rr = httptest.NewRecorder()
+	handler.DownloadHandler(rr, req)
+	if rr.Code != http.StatusPartialContent || rr.Header().Get("Content-Encoding") != "" || rr.Body.String() != content[:10] {
+		t.Errorf("range request: status %d, Content-Encoding %q, body %q", rr.Code, rr.Header().Get("Content-Encoding"), rr.Body.String())
+	}
+}
+
 func TestDownloadHandler_MissingParams(t *testing.T) {
 	handler, _, _ := setupDownloadHandler(t, nil)
 
@@ -158,3 +241,29 @@ func TestDownloadHandler_InvalidSession(t *testing.T) {
 		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusNotFound)
 	}
 }
+
+func TestDownloadHandler_GeneratedFile(t *testing.T) {
+	handler, sendService, _ := setupDownloadHandler(t, nil)
+
+	content := "generated on request"
+	provider := storage.GeneratedFile("report.txt", int64(len(content)), func(w io.Writer) error {
+		_, err := io.WriteString(w, content)
+		return err
+	})
+	files := map[string]model.FileDto{"file1": {ID: "file1", FileName: "report.txt", Size: int64(len(content)), FileType: "text/plain"}}
+	session, _ := sendService.CreateProviderSession(files, map[string]storage.FileProvider{"file1": provider})
+
+	req, _ := http.NewRequest(http.MethodGet, "/v2/download?sessionId="+session.SessionID+"&fileId=file1", nil)
+	rr := httptest.NewRecorder()
+	handler.DownloadHandler(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status %d, want 200", rr.Code)
+	}
+	if rr.Body.String() != content {
+		t.Errorf("body %q, want %q", rr.Body.String(), content)
+	}
+	if got := rr.Header().Get("Content-Length"); got != fmt.Sprint(len(content)) {
+		t.Errorf("Content-Length %q, want %d", got, len(content))
+	}
+}
@@ -0,0 +1,34 @@
+package handlers
+
+import (
+	"io"
+
+	"github.com/bethropolis/localgo/pkg/crypto"
+	"github.com/bethropolis/localgo/pkg/storage"
+)
+
+// saveOptions returns the steps received files are saved with: the scan,
+// and encryption at rest to config.EncryptTo.
+func (h *ReceiveHandler) saveOptions() *storage.SaveOptions {
+	opts := &storage.SaveOptions{Scanner: h.scanner()}
+	if h.config.EncryptTo != "" {
+		recipient, err := crypto.ParseRecipient(h.config.EncryptTo)
+		opts.Encrypt = func(w io.Writer) (io.WriteCloser, error) {
+			// A bad key fails the save rather than storing the file in the clear.
+			if err != nil {
+				return nil, err
+			}
+			return crypto.NewEncryptWriter(w, recipient)
+		}
+	}
+	return opts
+}
+
+// storedName returns the name a received file is saved under, which shows
+// when it is encrypted at rest.
+func (h *ReceiveHandler) storedName(name string) string {
+	if h.config.EncryptTo != "" {
+		return name + crypto.EncryptedExt
+	}
+	return name
+}
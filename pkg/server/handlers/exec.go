@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"os/exec"
@@ -21,18 +22,11 @@ func (h *ReceiveHandler) runExecHook(filePath, fileName, senderAlias, senderIP s
 	hook = strings.ReplaceAll(hook, "%a", senderAlias)
 	hook = strings.ReplaceAll(hook, "%i", senderIP)
 
+	h.hooks.Add(1)
 	go func() {
+		defer h.hooks.Done()
 		h.logger.Infof("Running exec hook: %s", hook)
-		var cmd *exec.Cmd
-		if h.config.Shell != "" {
-			if parts := strings.Fields(h.config.Shell); len(parts) > 0 {
-				cmd = exec.Command(parts[0], append(parts[1:], hook)...)
-			}
-		} else if runtime.GOOS == "windows" {
-			cmd = exec.Command("cmd", "/c", hook)
-		} else {
-			cmd = exec.Command("sh", "-c", hook)
-		}
+		cmd := h.shellCommand(context.Background(), hook)
 		cmd.Env = append(os.Environ(),
 			"LOCALGO_FILE="+filePath,
 			"LOCALGO_NAME="+fileName,
@@ -48,3 +42,31 @@ func (h *ReceiveHandler) runExecHook(filePath, fileName, senderAlias, senderIP s
 		}
 	}()
 }
+
+// shellCommand returns a command running line with the configured shell,
+// or the platform's default one.
+func (h *ReceiveHandler) shellCommand(ctx context.Context, line string) *exec.Cmd {
+	if parts := strings.Fields(h.config.Shell); len(parts) > 0 {
+		return exec.CommandContext(ctx, parts[0], append(parts[1:], line)...)
+	}
+	if runtime.GOOS == "windows" {
+		return exec.CommandContext(ctx, "cmd", "/c", line)
+	}
+	return exec.CommandContext(ctx, "sh", "-c", line)
+}
+
+// WaitHooks blocks until running exec hooks and manifest writes finish or
+// ctx is done, so a stopping server does not cut off the hook for the last
+// received file.
+func (h *ReceiveHandler) WaitHooks(ctx context.Context) {
+	done := make(chan struct{})
+	go func() {
+		h.hooks.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		h.logger.Warn("Timed out waiting for exec hooks to finish")
+	}
+}
@@ -5,10 +5,7 @@ import (
 )
 
 func (h *ReceiveHandler) logTransfer(senderAlias, senderIP, fileName, filePath string, size int64, fileType, status string) {
-	if h.historyLog == nil {
-		return
-	}
-	entry := history.Entry{
+	h.logEntry(history.Entry{
 		SenderAlias: senderAlias,
 		SenderIP:    senderIP,
 		FileName:    fileName,
@@ -16,6 +13,12 @@ func (h *ReceiveHandler) logTransfer(senderAlias, senderIP, fileName, filePath s
 		FileSize:    size,
 		FileType:    fileType,
 		Status:      status,
+	})
+}
+
+func (h *ReceiveHandler) logEntry(entry history.Entry) {
+	if h.historyLog == nil {
+		return
 	}
 	if err := h.historyLog.Log(entry); err != nil {
 		h.logger.Errorf("Failed to log transfer history: %v", err)
@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"github.com/bethropolis/localgo/pkg/config"
+	"github.com/bethropolis/localgo/pkg/report"
+	"github.com/bethropolis/localgo/pkg/storage"
+)
+
+// WriteManifest writes a manifest of the files a receive session saved to
+// the download directory, in the format set by config.Manifest. Files are
+// hashed in the background; WaitHooks waits for it.
+func (h *ReceiveHandler) WriteManifest(r *report.Report) {
+	if h.config.Manifest == "" || r.Direction != "receive" || r.Transferred == 0 {
+		return
+	}
+	h.hooks.Add(1)
+	go func() {
+		defer h.hooks.Done()
+		dir := h.config.DownloadDir
+		m, err := report.NewManifest(r, dir)
+		if err != nil {
+			h.logger.Errorf("Failed to write manifest for session %s: %v", r.SessionID, err)
+			return
+		}
+		if len(m.Files) == 0 {
+			return
+		}
+		name := "localgo-manifest-" + r.FinishedAt.Format("20060102-150405")
+		if h.config.Manifest == config.ManifestSHA256 {
+			err = m.WriteSHA256(storage.ResolveDuplicateFilename(dir, name+".sha256"))
+		} else {
+			err = m.WriteJSON(storage.ResolveDuplicateFilename(dir, name+".json"))
+		}
+		if err != nil {
+			h.logger.Errorf("Failed to write manifest for session %s: %v", r.SessionID, err)
+			return
+		}
+		h.logger.Infof("Wrote manifest of %d file(s) from session %s", len(m.Files), r.SessionID)
+	}()
+}
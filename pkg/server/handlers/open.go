@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bethropolis/localgo/pkg/cli"
+	"github.com/bethropolis/localgo/pkg/config"
+)
+
+// folderReopenInterval stops a multi-file transfer from opening the same
+// folder once per file in folder mode.
+const folderReopenInterval = time.Minute
+
+// inertExts are the media and document types opened in file mode: their
+// default handlers show them without running anything in them. Anything
+// else is never auto-opened.
+var inertExts = map[string]bool{
+	".jpg": true, ".jpeg": true, ".png": true, ".gif": true, ".webp": true,
+	".heic": true, ".heif": true, ".avif": true, ".bmp": true, ".tif": true,
+	".tiff": true,
+
+	".mp3": true, ".m4a": true, ".aac": true, ".flac": true, ".wav": true,
+	".ogg": true, ".opus": true,
+
+	".mp4": true, ".m4v": true, ".mov": true, ".mkv": true, ".webm": true,
+	".avi": true,
+
+	".pdf": true, ".txt": true, ".md": true, ".csv": true, ".rtf": true,
+	".epub": true, ".docx": true, ".xlsx": true, ".pptx": true, ".odt": true,
+	".ods": true, ".odp": true,
+}
+
+// executableExts are types that run code, or can, when opened. They make a
+// transfer executable for accept rules.
+var executableExts = map[string]bool{
+	".exe": true, ".msi": true, ".bat": true, ".cmd": true, ".com": true,
+	".scr": true, ".pif": true, ".cpl": true, ".vbs": true, ".vbe": true,
+	".js": true, ".jse": true, ".wsf": true, ".wsh": true, ".ws": true,
+	".ps1": true, ".psm1": true, ".lnk": true, ".url": true, ".reg": true,
+	".hta": true, ".chm": true, ".msc": true, ".inf": true, ".jar": true,
+	".jnlp": true, ".application": true, ".msix": true, ".appx": true,
+	".appinstaller": true, ".html": true, ".htm": true, ".svg": true,
+	".iso": true, ".img": true, ".vhd": true, ".vhdx": true,
+	".docm": true, ".xlsm": true, ".pptm": true,
+	".sh": true, ".bash": true, ".zsh": true, ".csh": true, ".command": true,
+	".terminal": true, ".workflow": true, ".scpt": true, ".applescript": true,
+	".run": true, ".bin": true, ".appimage": true, ".desktop": true,
+	".deb": true, ".rpm": true, ".apk": true, ".app": true, ".pkg": true,
+	".dmg": true, ".py": true, ".pl": true, ".rb": true,
+}
+
+// executableMagic are file signatures of native executables and scripts.
+var executableMagic = [][]byte{
+	[]byte("\x7fELF"),        // ELF
+	[]byte("MZ"),             // PE (Windows)
+	{0xfe, 0xed, 0xfa, 0xce}, // Mach-O 32-bit
+	{0xfe, 0xed, 0xfa, 0xcf}, // Mach-O 64-bit
+	{0xce, 0xfa, 0xed, 0xfe}, // Mach-O 32-bit, little endian
+	{0xcf, 0xfa, 0xed, 0xfe}, // Mach-O 64-bit, little endian
+	{0xca, 0xfe, 0xba, 0xbe}, // Mach-O universal
+	[]byte("#!"),             // script with interpreter line
+}
+
+// openedFolders remembers when each folder was opened in folder mode within
+// the last folderReopenInterval.
+type openedFolders struct {
+	mu   sync.Mutex
+	last map[string]time.Time
+}
+
+// open reports whether dir should be opened now, and if so records it.
+// Folders opened longer ago than folderReopenInterval are forgotten.
+func (o *openedFolders) open(dir string, now time.Time) bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if last, seen := o.last[dir]; seen && now.Sub(last) < folderReopenInterval {
+		return false
+	}
+	if o.last == nil {
+		o.last = make(map[string]time.Time)
+	}
+	for d, last := range o.last {
+		if now.Sub(last) >= folderReopenInterval {
+			delete(o.last, d)
+		}
+	}
+	o.last[dir] = now
+	return true
+}
+
+// openReceived opens a successfully received file, or its folder, according
+// to the configured open mode. Only media and documents are opened.
+func (h *ReceiveHandler) openReceived(filePath string) {
+	switch h.config.OpenMode {
+	case config.OpenModeFile:
+		if !isInert(filePath) {
+			h.logger.Warnf("Not auto-opening %s: not a known media or document type", filepath.Base(filePath))
+			return
+		}
+		if isExecutable(filePath) {
+			h.logger.Warnf("Not auto-opening %s: looks like an executable", filepath.Base(filePath))
+			return
+		}
+		h.openPath(filePath)
+	case config.OpenModeFolder:
+		dir := filepath.Dir(filePath)
+		if h.openedFolders.open(dir, time.Now()) {
+			h.openPath(dir)
+		}
+	}
+}
+
+// openPath opens path with the platform's default handler in the background.
+func (h *ReceiveHandler) openPath(path string) {
+	if cli.Headless() {
+		return
+	}
+	go func() {
+		var cmd string
+		switch runtime.GOOS {
+		case "windows":
+			cmd = "explorer.exe"
+		case "darwin":
+			cmd = "open"
+		default:
+			if _, err := exec.LookPath("xdg-open"); err != nil {
+				h.logger.Debugf("xdg-open not found in PATH, skip opening %s", path)
+				return
+			}
+			cmd = "xdg-open"
+		}
+		if err := exec.Command(cmd, path).Run(); err != nil {
+			h.logger.Debugf("Failed to open %s: %v", path, err)
+		}
+	}()
+}
+
+// isExecutable reports whether path looks like something that would run
+// code when opened: a known executable extension, the executable permission
+// bit, or a native binary or script signature.
+func isExecutable(path string) bool {
+	if hasExecutableExt(path) {
+		return true
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return true // can't tell; err on the side of not opening
+	}
+	if runtime.GOOS != "windows" && info.Mode().Perm()&0111 != 0 {
+		return true
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return true
+	}
+	defer f.Close()
+	head := make([]byte, 4)
+	n, _ := f.Read(head)
+	for _, magic := range executableMagic {
+		if n >= len(magic) && bytes.Equal(head[:len(magic)], magic) {
+			return true
+		}
+	}
+	return false
+}
+
+// isInert reports whether name has the extension of a media or document
+// type that is safe to open.
+func isInert(name string) bool {
+	return inertExts[strings.ToLower(filepath.Ext(name))]
+}
+
+// hasExecutableExt reports whether name has an extension that runs code
+// when opened.
+func hasExecutableExt(name string) bool {
+	return executableExts[strings.ToLower(filepath.Ext(name))]
+}
@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestIsExecutable(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string, data []byte, perm os.FileMode) string {
+		p := filepath.Join(dir, name)
+		if err := os.WriteFile(p, data, perm); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+		return p
+	}
+
+	tests := []struct {
+		name string
+		path string
+		want bool
+	}{
+		{"photo", write("photo.jpg", []byte{0xff, 0xd8, 0xff, 0xe0}, 0644), false},
+		{"text", write("notes.txt", []byte("hello"), 0644), false},
+		{"windows exe", write("setup.EXE", []byte("data"), 0644), true},
+		{"shell script", write("run.sh", []byte("echo hi"), 0644), true},
+		{"elf without extension", write("tool", []byte("\x7fELF\x02\x01"), 0644), true},
+		{"shebang without extension", write("script", []byte("#!/bin/sh\n"), 0644), true},
+		{"missing file", filepath.Join(dir, "missing.txt"), true},
+	}
+	if runtime.GOOS != "windows" {
+		tests = append(tests, struct {
+			name string
+			path string
+			want bool
+		}{"exec bit", write("data.txt", []byte("hello"), 0755), true})
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isExecutable(tt.path); got != tt.want {
+				t.Errorf("isExecutable(%s) = %v, want %v", filepath.Base(tt.path), got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsInert(t *testing.T) {
+	for name, want := range map[string]bool{
+		"photo.JPG":     true,
+		"report.pdf":    true,
+		"notes.txt":     true,
+		"page.html":     false,
+		"drawing.svg":   false,
+		"link.url":      false,
+		"disk.iso":      false,
+		"budget.xlsm":   false,
+		"archive.zip":   false,
+		"no-extension":  false,
+		"help.chm":      false,
+		"run.workflow":  false,
+		"Setup.appx":    false,
+		"launch.jnlp":   false,
+		"macro.docm":    false,
+		"script.scpt":   false,
+		"unknown.xyz12": false,
+	} {
+		if got := isInert(name); got != want {
+			t.Errorf("isInert(%s) = %v, want %v", name, got, want)
+		}
+	}
+	for _, name := range []string{"page.html", "link.url", "disk.iso", "macro.docm", "Setup.appx"} {
+		if !hasExecutableExt(name) {
+			t.Errorf("hasExecutableExt(%s) = false, want true", name)
+		}
+	}
+}
+
+func TestOpenedFolders(t *testing.T) {
+	var o openedFolders
+	now := time.Now()
+	if !o.open("/a", now) {
+		t.Error("first open of a folder refused")
+	}
+	if o.open("/a", now.Add(time.Second)) {
+		t.Error("folder reopened within folderReopenInterval")
+	}
+	if !o.open("/b", now.Add(folderReopenInterval)) {
+		t.Error("another folder refused")
+	}
+	if _, kept := o.last["/a"]; kept {
+		t.Error("folder opened longer ago than folderReopenInterval still remembered")
+	}
+}
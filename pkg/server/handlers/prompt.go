@@ -4,17 +4,31 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/bethropolis/localgo/pkg/cli"
+	"github.com/bethropolis/localgo/pkg/clipboard"
+	"github.com/bethropolis/localgo/pkg/metadata"
 	"github.com/bethropolis/localgo/pkg/model"
 	"github.com/charmbracelet/huh"
+	"golang.org/x/term"
 )
 
-func (h *ReceiveHandler) promptUserForAcceptance(sender model.DeviceInfo, files map[string]model.FileDto) bool {
-	if cli.IsContainer() {
-		return false
+// previewWidth is how many terminal cells wide image previews are shown.
+const previewWidth = 32
+
+// transferRequestTimeout is how long a transfer waits for a control client
+// to answer it before it is declined.
+const transferRequestTimeout = 90 * time.Second
+
+// promptUserForAcceptance asks whether to accept files and returns the ones
+// the user chose, which is none when the transfer is rejected. With more than
+// one file the user may pick a subset.
+func (h *ReceiveHandler) promptUserForAcceptance(sender model.DeviceInfo, files map[string]model.FileDto) map[string]model.FileDto {
+	if cli.Headless() {
+		return nil
 	}
 
 	fileCount := len(files)
@@ -57,19 +71,62 @@ func (h *ReceiveHandler) promptUserForAcceptance(sender model.DeviceInfo, files
 	if totalSize > 0 {
 		sb.WriteString(fmt.Sprintf("\nTotal Size: %s", cli.FormatBytes(totalSize)))
 	}
+	if name, art := imagePreview(files); art != "" {
+		sb.WriteString(fmt.Sprintf("\n\nPreview of %s:\n%s", cli.Sanitize(name), art))
+	}
 
-	var accept bool = true
-
-	form := huh.NewForm(
-		huh.NewGroup(
-			huh.NewConfirm().
-				Title("Accept Incoming File Transfer?").
-				Description(sb.String()).
-				Value(&accept).
-				Affirmative("Accept").
-				Negative("Reject"),
-		),
-	).WithTheme(huh.ThemeCharm())
+	const (
+		choiceAll    = "all"
+		choiceSome   = "some"
+		choiceReject = "reject"
+	)
+	choice := choiceAll
+	accept := true
+	var form *huh.Form
+	var selected []string
+	if fileCount == 1 {
+		form = huh.NewForm(
+			huh.NewGroup(
+				huh.NewConfirm().
+					Title("Accept Incoming File Transfer?").
+					Description(sb.String()).
+					Value(&accept).
+					Affirmative("Accept").
+					Negative("Reject"),
+			),
+		)
+	} else {
+		ids := make([]string, 0, fileCount)
+		for id := range files {
+			ids = append(ids, id)
+		}
+		sort.Slice(ids, func(i, j int) bool { return files[ids[i]].FileName < files[ids[j]].FileName })
+		options := make([]huh.Option[string], 0, fileCount)
+		for _, id := range ids {
+			label := fmt.Sprintf("%s (%s)", cli.Sanitize(files[id].FileName), cli.FormatBytes(files[id].Size))
+			options = append(options, huh.NewOption(label, id).Selected(true))
+		}
+		form = huh.NewForm(
+			huh.NewGroup(
+				huh.NewSelect[string]().
+					Title("Accept Incoming File Transfer?").
+					Description(sb.String()).
+					Options(
+						huh.NewOption("Accept all", choiceAll),
+						huh.NewOption("Choose files…", choiceSome),
+						huh.NewOption("Reject", choiceReject),
+					).
+					Value(&choice),
+			),
+			huh.NewGroup(
+				huh.NewMultiSelect[string]().
+					Title("Files to accept").
+					Options(options...).
+					Value(&selected),
+			).WithHideFunc(func() bool { return choice != choiceSome }),
+		)
+	}
+	form = form.WithTheme(huh.ThemeCharm())
 
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
@@ -77,14 +134,49 @@ func (h *ReceiveHandler) promptUserForAcceptance(sender model.DeviceInfo, files
 	err := form.RunWithContext(ctx)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "\n%s Transfer automatically rejected.\n", cli.WarningStyle.Render(cli.IconWarning))
-		return false
+		return nil
 	}
 
-	return accept
+	if !accept {
+		return nil
+	}
+	switch choice {
+	case choiceAll:
+		return files
+	case choiceSome:
+		accepted := make(map[string]model.FileDto, len(selected))
+		for _, id := range selected {
+			accepted[id] = files[id]
+		}
+		return accepted
+	}
+	return nil
+}
+
+// imagePreview renders the image preview of the first file, by name, that
+// has one, returning the file's name and the rendering.
+func imagePreview(files map[string]model.FileDto) (string, string) {
+	names := make([]string, 0, len(files))
+	previews := make(map[string]string)
+	for _, f := range files {
+		if f.Preview != nil && metadata.IsImagePreview(*f.Preview) {
+			names = append(names, f.FileName)
+			previews[f.FileName] = *f.Preview
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		img, err := metadata.DecodePreview(previews[name])
+		if err != nil {
+			continue
+		}
+		return name, cli.RenderImage(img, previewWidth)
+	}
+	return "", ""
 }
 
 func (h *ReceiveHandler) promptForClipboard(alias, remoteAddr, message string) bool {
-	if cli.IsContainer() {
+	if cli.Headless() {
 		return false
 	}
 	cli.Notify("LocalGo: Clipboard Message",
@@ -97,6 +189,11 @@ func (h *ReceiveHandler) promptForClipboard(alias, remoteAddr, message string) b
 
 	desc := fmt.Sprintf("From: %s (IP: %s)\n\nClipboard:\n%s", cli.Sanitize(alias), remoteAddr, cli.Sanitize(truncated))
 
+	affirmative := "Accept & Copy"
+	if h.config.PrintMessages {
+		affirmative = "Accept & Print"
+	}
+
 	var accept bool = true
 	form := huh.NewForm(
 		huh.NewGroup(
@@ -104,7 +201,7 @@ func (h *ReceiveHandler) promptForClipboard(alias, remoteAddr, message string) b
 				Title("Accept Clipboard?").
 				Description(desc).
 				Value(&accept).
-				Affirmative("Accept & Copy").
+				Affirmative(affirmative).
 				Negative("Reject"),
 		),
 	).WithTheme(huh.ThemeCharm())
@@ -119,3 +216,36 @@ func (h *ReceiveHandler) promptForClipboard(alias, remoteAddr, message string) b
 	}
 	return accept
 }
+
+// offerClipboardCopy asks whether to copy a printed text message to the
+// clipboard, when someone is at the terminal to answer.
+func (h *ReceiveHandler) offerClipboardCopy(message string) {
+	if cli.Headless() || !term.IsTerminal(int(os.Stdin.Fd())) {
+		return
+	}
+	h.promptMutex.Lock()
+	defer h.promptMutex.Unlock()
+
+	copyText := true
+	form := huh.NewForm(
+		huh.NewGroup(
+			huh.NewConfirm().
+				Title("Copy message to clipboard?").
+				Value(&copyText).
+				Affirmative("Copy").
+				Negative("Skip"),
+		),
+	).WithTheme(huh.ThemeCharm())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if err := form.RunWithContext(ctx); err != nil || !copyText {
+		return
+	}
+	if err := clipboard.Write(message); err != nil {
+		h.logger.Warnf("Clipboard write failed: %v", err)
+		return
+	}
+	cli.PrintSuccess("Copied to clipboard")
+}
@@ -4,25 +4,35 @@ import (
 	"context"
 	"crypto/subtle"
 	"encoding/json"
+	"errors"
 	"net"
 	"net/http"
 	"os"
-	"os/exec"
-	"runtime"
+	"path"
+	"path/filepath"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/bethropolis/localgo/pkg/cli"
 	"github.com/bethropolis/localgo/pkg/clipboard"
+	"github.com/bethropolis/localgo/pkg/compression"
 	"github.com/bethropolis/localgo/pkg/config"
 	"github.com/bethropolis/localgo/pkg/history"
 	"github.com/bethropolis/localgo/pkg/httputil"
 	"github.com/bethropolis/localgo/pkg/model"
 	"github.com/bethropolis/localgo/pkg/server/services"
 	"github.com/bethropolis/localgo/pkg/storage"
+	"github.com/bethropolis/localgo/pkg/validate"
 	"go.uber.org/zap"
 )
 
+// maxSessionWaiters is how many transfers session_wait holds at once while
+// another session is active; any more are refused straight away.
+const maxSessionWaiters = 4
+
 // maxTextSize is the maximum bytes read from a text/plain body before
 // falling back to saving as a file (prevents memory exhaustion).
 const maxTextSize = 1 * 1024 * 1024 // 1 MB
@@ -33,8 +43,16 @@ type ReceiveHandler struct {
 	receiveService *services.ReceiveService
 	logger         *zap.SugaredLogger
 	historyLog     *history.Logger
+	storage        storage.Backend
 	promptMutex    sync.Mutex
 	shutdownCtx    context.Context
+	hooks          sync.WaitGroup // running exec hooks and manifest writes
+	sessionWaiters atomic.Int32   // transfers held by session_wait
+
+	benchMu    sync.Mutex
+	benchmarks map[string]*benchmarkSession // accepted speed tests by session ID
+
+	openedFolders openedFolders // folders opened in folder mode, to open each once per burst
 }
 
 // NewReceiveHandler creates a new ReceiveHandler.
@@ -44,10 +62,17 @@ func NewReceiveHandler(cfg *config.Config, receiveService *services.ReceiveServi
 		receiveService: receiveService,
 		logger:         logger,
 		historyLog:     historyLog,
+		storage:        storage.Disk,
 		shutdownCtx:    shutdownCtx,
 	}
 }
 
+// SetStorageBackend makes the handler save received files to b instead of
+// the local disk. Call it before the handler serves requests.
+func (h *ReceiveHandler) SetStorageBackend(b storage.Backend) {
+	h.storage = b
+}
+
 // PrepareUploadHandlerV2 handles POST /v2/prepare-upload requests.
 func (h *ReceiveHandler) PrepareUploadHandlerV2(w http.ResponseWriter, r *http.Request) {
 	h.logger.Info("Received /prepare-upload request")
@@ -56,15 +81,6 @@ func (h *ReceiveHandler) PrepareUploadHandlerV2(w http.ResponseWriter, r *http.R
 		return
 	}
 
-	// --- PIN Check ---
-	if h.config.PIN != "" {
-		pin := r.URL.Query().Get("pin")
-		if subtle.ConstantTimeCompare([]byte(pin), []byte(h.config.PIN)) != 1 {
-			httputil.RespondError(w, http.StatusUnauthorized, "Invalid PIN")
-			return
-		}
-	}
-
 	// --- Decode Request ---
 	// Limit request body to prevent memory exhaustion from massive JSON (1 MB limit)
 	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1024*1024))
@@ -72,32 +88,159 @@ func (h *ReceiveHandler) PrepareUploadHandlerV2(w http.ResponseWriter, r *http.R
 	err := decoder.Decode(&requestDto)
 	if err != nil {
 		h.logger.Errorf("Error decoding /prepare-upload request from %s: %v", r.RemoteAddr, err)
-		httputil.RespondError(w, http.StatusBadRequest, "Request body malformed")
+		respondDecodeError(w, err)
+		return
+	}
+	defer r.Body.Close()
+
+	session := h.prepareUpload(w, r, requestDto)
+	if session == nil {
+		return
+	}
+
+	// --- Respond ---
+	responseDto := model.PrepareUploadResponseDto{
+		SessionID: session.SessionID,
+		Files:     fileTokens(session),
+	}
+	// Tell senders that uploads may be compressed (RFC 7694).
+	w.Header().Set("Accept-Encoding", compression.Gzip)
+	httputil.RespondJSON(w, http.StatusOK, responseDto)
+}
+
+// SendRequestHandlerV1 handles POST /v1/send-request, the v1 prepare-upload
+// sent by legacy LocalSend releases. It answers with the file tokens alone:
+// v1 has no session IDs.
+func (h *ReceiveHandler) SendRequestHandlerV1(w http.ResponseWriter, r *http.Request) {
+	h.logger.Info("Received /v1/send-request request")
+	if r.Method != http.MethodPost {
+		httputil.RespondError(w, http.StatusMethodNotAllowed, "Method Not Allowed")
+		return
+	}
+
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1024*1024))
+	var legacyDto model.SendRequestV1Dto
+	if err := decoder.Decode(&legacyDto); err != nil {
+		h.logger.Errorf("Error decoding /v1/send-request request from %s: %v", r.RemoteAddr, err)
+		respondDecodeError(w, err)
 		return
 	}
 	defer r.Body.Close()
 
+	requestDto := model.PrepareUploadRequestDto{
+		Info: model.InfoDto{
+			Alias:       legacyDto.Info.Alias,
+			Version:     "1.0",
+			DeviceModel: legacyDto.Info.DeviceModel,
+			DeviceType:  legacyDto.Info.DeviceType,
+		},
+		Files: make(map[string]model.FileDto, len(legacyDto.Files)),
+	}
+	for id, f := range legacyDto.Files {
+		requestDto.Files[id] = f.ToFileDto()
+	}
+
+	session := h.prepareUpload(w, r, requestDto)
+	if session == nil {
+		return
+	}
+	httputil.RespondJSON(w, http.StatusOK, fileTokens(session))
+}
+
+// fileTokens maps the IDs of the files of session to their upload tokens.
+func fileTokens(session *services.ActiveReceiveSession) map[string]string {
+	tokens := make(map[string]string, len(session.Files))
+	for fileID, file := range session.Files {
+		tokens[fileID] = file.Token
+	}
+	return tokens
+}
+
+// prepareUpload decides on a prepare-upload request and, when it is
+// accepted, creates its session. It returns nil once it has responded
+// itself: on rejection, on error, or when nothing is left to upload.
+func (h *ReceiveHandler) prepareUpload(w http.ResponseWriter, r *http.Request, requestDto model.PrepareUploadRequestDto) *services.ActiveReceiveSession {
+	if err := validate.PrepareUpload(&requestDto); err != nil {
+		h.logger.Warnf("Rejected transfer from %s: %v", cli.Sanitize(requestDto.Info.Alias), err)
+		respondInvalid(w, err)
+		return nil
+	}
+
+	// --- Sender Filter ---
+	if !h.senderAllowed(requestDto.Info.Alias, httputil.VerifiedFingerprint(r)) {
+		h.logger.Infof("Rejected transfer from %s: not an allowed sender", cli.Sanitize(requestDto.Info.Alias))
+		httputil.RespondError(w, http.StatusForbidden, "Rejected")
+		return nil
+	}
+
+	verified := httputil.IsVerifiedSender(r, requestDto.Info.Fingerprint)
+
+	// --- Per-file Skip Rules ---
+	// Files matched by a skip rule get no token; the sender uploads the rest.
+	requested := len(requestDto.Files)
+	if skipped := h.skipFiles(requestDto.Info.Fingerprint, verified, requestDto.Files); skipped > 0 {
+		h.logger.Infof("Skipping %d of %d files from %s by accept rule", skipped, requested, cli.Sanitize(requestDto.Info.Alias))
+		if skipped == requested {
+			httputil.RespondError(w, http.StatusForbidden, "Rejected")
+			return nil
+		}
+	}
+
+	// --- Accept Rules & PIN Check ---
+	action, pinRequired := h.acceptDecision(requestDto.Info.Fingerprint, verified, requestDto.Files)
+	if pinRequired {
+		pin := r.URL.Query().Get("pin")
+		if subtle.ConstantTimeCompare([]byte(pin), []byte(h.config.PIN)) != 1 {
+			httputil.RespondError(w, http.StatusUnauthorized, "Invalid PIN")
+			return nil
+		}
+	}
+	if action == config.AcceptActionReject {
+		h.logger.Infof("Transfer from %s rejected by accept rule", cli.Sanitize(requestDto.Info.Alias))
+		httputil.RespondError(w, http.StatusForbidden, "Rejected")
+		return nil
+	}
+
 	// Sanitize filenames: strip control characters to prevent UI spoofing
-	// and terminal escape injection on display.
+	// and terminal escape injection on display. Validation made sure
+	// something is left.
 	for id, f := range requestDto.Files {
 		f.FileName = sanitizeName(f.FileName)
-		if f.FileName == "" {
-			h.logger.Warnf("Rejected transfer from %s: file '%s' has empty name after sanitization", cli.Sanitize(requestDto.Info.Alias), id)
-			httputil.RespondError(w, http.StatusBadRequest, "Invalid filename")
-			return
-		}
 		requestDto.Files[id] = f
 	}
 
 	if len(requestDto.Files) == 0 {
 		h.logger.Info("Received empty file list on prepare-upload, returning 204 Finished")
 		w.WriteHeader(http.StatusNoContent)
-		return
+		return nil
+	}
+
+	// --- Target Path ---
+	// Files go to the folder the sender asked for when target_roots allows
+	// it; their names then carry the folder like those of a folder transfer.
+	if requestDto.TargetPath != "" {
+		if dir, ok := h.config.TargetDir(sanitizeName(requestDto.TargetPath)); ok {
+			for id, f := range requestDto.Files {
+				f.FileName = path.Join(dir, filepath.ToSlash(f.FileName))
+				requestDto.Files[id] = f
+			}
+		} else {
+			h.logger.Warnf("Ignoring target path %q from %s: not within target_roots", cli.Sanitize(requestDto.TargetPath), cli.Sanitize(requestDto.Info.Alias))
+		}
 	}
 
 	// Extract IP from RemoteAddr early (used by clipboard path and elsewhere)
 	senderIP, _, _ := net.SplitHostPort(r.RemoteAddr)
 
+	sender := model.DeviceInfo{
+		Alias:       cli.Sanitize(requestDto.Info.Alias),
+		Version:     requestDto.Info.Version,
+		DeviceModel: requestDto.Info.DeviceModel,
+		DeviceType:  requestDto.Info.DeviceType,
+		Fingerprint: requestDto.Info.Fingerprint,
+		IP:          senderIP,
+	}
+
 	// --- Clipboard Message Detection ---
 	// The official LocalSend embeds clipboard text in the Preview field.
 	// Only short-circuit when it's a single clipboard message (full content
@@ -117,18 +260,37 @@ func (h *ReceiveHandler) PrepareUploadHandlerV2(w http.ResponseWriter, r *http.R
 
 	if clipboardMessage != "" {
 		h.logger.Infof("Clipboard message from %s", cli.Sanitize(requestDto.Info.Alias))
-		if !h.config.AutoAccept {
-			h.promptMutex.Lock()
-			accepted := h.promptForClipboard(cli.Sanitize(requestDto.Info.Alias), r.RemoteAddr, clipboardMessage)
-			h.promptMutex.Unlock()
+		if action == config.AcceptActionPrompt {
+			files, answered := h.receiveService.Approvals().Ask(r.Context(), sender, requestDto.Files, transferRequestTimeout)
+			accepted := len(files) > 0
+			if !answered {
+				h.promptMutex.Lock()
+				accepted = h.promptForClipboard(cli.Sanitize(requestDto.Info.Alias), r.RemoteAddr, clipboardMessage)
+				h.promptMutex.Unlock()
+			}
 			if !accepted {
 				httputil.RespondError(w, http.StatusForbidden, "Rejected")
-				return
+				return nil
 			}
 		}
 
 		sanitizedAlias := cli.Sanitize(requestDto.Info.Alias)
 
+		if h.config.PrintMessages {
+			h.logger.Infof("Text message from %s printed", sanitizedAlias)
+			cli.PrintMessage(sanitizedAlias, clipboardMessage)
+			h.logTransfer(sanitizedAlias, senderIP, clipboardFileID, "<terminal>", int64(len(clipboardMessage)), "text/plain", history.StatusClipboard)
+			h.runExecHook("<terminal>", clipboardFileID, sanitizedAlias, senderIP, int64(len(clipboardMessage)))
+			h.receiveService.CompleteMessage(services.ReceivedFile{FileName: clipboardFileID, Path: "<terminal>", Size: int64(len(clipboardMessage)), Sender: sender})
+			w.WriteHeader(http.StatusNoContent)
+			// Ask about the clipboard after responding, so the sender is
+			// not kept waiting on it.
+			if !h.config.NoClipboard {
+				go h.offerClipboardCopy(clipboardMessage)
+			}
+			return nil
+		}
+
 		if !h.config.NoClipboard {
 			if err := clipboard.Write(clipboardMessage); err != nil {
 				h.logger.Warnf("Clipboard write failed (%v), saving text as file instead", err)
@@ -136,96 +298,134 @@ func (h *ReceiveHandler) PrepareUploadHandlerV2(w http.ResponseWriter, r *http.R
 				h.logger.Infof("Clipboard message from %s accepted and copied", sanitizedAlias)
 				h.logTransfer(sanitizedAlias, senderIP, clipboardFileID, "<clipboard>", int64(len(clipboardMessage)), "text/plain", history.StatusClipboard)
 				h.runExecHook("<clipboard>", clipboardFileID, sanitizedAlias, senderIP, int64(len(clipboardMessage)))
+				h.receiveService.CompleteMessage(services.ReceivedFile{FileName: clipboardFileID, Path: "<clipboard>", Size: int64(len(clipboardMessage)), Sender: sender})
 				w.WriteHeader(http.StatusNoContent)
-				return
+				return nil
 			}
 		}
 
 		// Fallback: save as file (NoClipboard mode or clipboard write failed)
-		clipboardPath := storage.ResolveDuplicateFilename(h.config.DownloadDir, "clipboard.txt")
-		if err := os.WriteFile(clipboardPath, []byte(clipboardMessage), 0600); err != nil {
+		clipboardPath := storage.ResolveDuplicateFilename(h.config.DownloadDir, h.storedName("clipboard.txt"))
+		err := h.storage.Save(strings.NewReader(clipboardMessage), clipboardPath, int64(len(clipboardMessage)), nil, nil, nil, h.saveOptions(), nil, h.logger)
+		if h.quarantined(w, err, sender, clipboardFileID, int64(len(clipboardMessage)), "text/plain") {
+			return nil
+		}
+		if err == nil {
+			err = os.Chmod(clipboardPath, 0600)
+		}
+		if err != nil {
 			h.logger.Errorf("Failed to save clipboard text to %s: %v", clipboardPath, err)
 			httputil.RespondError(w, http.StatusInternalServerError, "Failed to save clipboard")
-			return
+			return nil
 		}
 		h.logger.Infof("Clipboard message from %s saved to %s", sanitizedAlias, clipboardPath)
 		h.logTransfer(sanitizedAlias, senderIP, clipboardFileID, clipboardPath, int64(len(clipboardMessage)), "text/plain", history.StatusClipboard)
 		h.runExecHook(clipboardPath, clipboardFileID, sanitizedAlias, senderIP, int64(len(clipboardMessage)))
+		h.receiveService.CompleteMessage(services.ReceivedFile{FileName: clipboardFileID, Path: clipboardPath, Size: int64(len(clipboardMessage)), Sender: sender})
 		w.WriteHeader(http.StatusNoContent)
-		return
+		return nil
 	}
 
 	// --- Check Disk Space ---
 	var totalSize int64
 	for _, f := range requestDto.Files {
-		if f.Size < 0 {
-			h.logger.Warnf("Rejected transfer from %s: file '%s' has negative size (%d)", cli.Sanitize(requestDto.Info.Alias), cli.Sanitize(f.FileName), f.Size)
-			httputil.RespondError(w, http.StatusBadRequest, "Invalid file size")
-			return
+		if h.config.MaxBodySize > 0 && f.Size > h.config.MaxBodySize {
+			h.logger.Warnf("Rejected transfer from %s: file '%s' exceeds the size limit (%s > %s)",
+				cli.Sanitize(requestDto.Info.Alias), cli.Sanitize(f.FileName), cli.FormatBytes(f.Size), cli.FormatBytes(h.config.MaxBodySize))
+			httputil.RespondError(w, http.StatusRequestEntityTooLarge, "File too large")
+			return nil
 		}
 		totalSize += f.Size
 	}
 
-	freeSpace, fsErr := storage.CheckFreeSpace(h.config.DownloadDir)
+	freeSpace, fsErr := h.storage.FreeSpace(h.config.DownloadDir)
 	if fsErr == nil {
 		const safetyBuffer = 50 * 1024 * 1024
 		if freeSpace < uint64(totalSize)+safetyBuffer {
 			h.logger.Warnf("Rejected transfer from %s: Insufficient disk space (Required: %s, Available: %s)",
 				cli.Sanitize(requestDto.Info.Alias), cli.FormatBytes(totalSize), cli.FormatBytes(int64(freeSpace)))
 			httputil.RespondError(w, http.StatusBadRequest, "Insufficient storage space on receiver")
-			return
+			return nil
 		}
 	}
 
 	h.logger.Infof("PrepareUpload request from %s (%s) for %d files:", cli.Sanitize(requestDto.Info.Alias), r.RemoteAddr, len(requestDto.Files))
 
-	sender := model.DeviceInfo{
-		Alias:       cli.Sanitize(requestDto.Info.Alias),
-		Version:     requestDto.Info.Version,
-		DeviceModel: requestDto.Info.DeviceModel,
-		DeviceType:  requestDto.Info.DeviceType,
-		Fingerprint: requestDto.Info.Fingerprint,
-		IP:          senderIP,
-	}
-
 	// --- Interactive Accept/Reject Prompt ---
-	if !h.config.AutoAccept {
-		h.promptMutex.Lock()
-		accepted := h.promptUserForAcceptance(sender, requestDto.Files)
-		h.promptMutex.Unlock()
+	if action == config.AcceptActionPrompt {
+		// A connected control client answers in place of the terminal.
+		accepted, answered := h.receiveService.Approvals().Ask(r.Context(), sender, requestDto.Files, transferRequestTimeout)
+		if !answered {
+			h.promptMutex.Lock()
+			accepted = h.promptUserForAcceptance(sender, requestDto.Files)
+			h.promptMutex.Unlock()
+		}
 
-		if !accepted {
+		if len(accepted) == 0 {
 			h.logger.Infof("Transfer rejected by user")
 			httputil.RespondError(w, http.StatusForbidden, "Rejected") // 403 Forbidden
-			return
+			return nil
 		}
+		if len(accepted) < len(requestDto.Files) {
+			h.logger.Infof("User accepted %d of %d files", len(accepted), len(requestDto.Files))
+		}
+		requestDto.Files = accepted
 	}
 
 	// --- Simulate Acceptance & Create Session ---
-	session, err := h.receiveService.CreateSession(sender, requestDto.Files)
+	session, err := h.createSession(r.Context(), sender, requestDto.Files)
+	if errors.Is(err, services.ErrNotAccepting) {
+		httputil.RespondError(w, http.StatusServiceUnavailable, "Server shutting down")
+		return nil
+	}
 	if err != nil {
+		if h.config.SessionWait > 0 {
+			// Parked: the sender may try again once the active session
+			// has had time to finish.
+			w.Header().Set("Retry-After", strconv.Itoa(int(max(h.config.SessionWait, time.Second)/time.Second)))
+		}
 		httputil.RespondError(w, http.StatusConflict, "Blocked by another session") // 409 Conflict
-		return
-	}
-
-	responseTokens := make(map[string]string)
-	for fileID, file := range session.Files {
-		responseTokens[fileID] = file.Token
+		return nil
 	}
 
 	h.logger.Infof("Created SessionID: %s and File Tokens. Awaiting /upload requests.", session.SessionID)
+	return session
+}
 
-	// --- Respond ---
-	responseDto := model.PrepareUploadResponseDto{
-		SessionID: session.SessionID,
-		Files:     responseTokens,
+// createSession creates the session of an accepted transfer. With
+// session_wait set, a transfer arriving while another session is active is
+// held until that session ends, for at most session_wait and with no more
+// than maxSessionWaiters held at once; it gets ErrSessionActive otherwise.
+func (h *ReceiveHandler) createSession(ctx context.Context, sender model.DeviceInfo, files map[string]model.FileDto) (*services.ActiveReceiveSession, error) {
+	ended := h.receiveService.SessionEnded()
+	session, err := h.receiveService.CreateSession(sender, files)
+	if !errors.Is(err, services.ErrSessionActive) || h.config.SessionWait <= 0 {
+		return session, err
 	}
-	httputil.RespondJSON(w, http.StatusOK, responseDto)
-}
+	if h.sessionWaiters.Add(1) > maxSessionWaiters {
+		h.sessionWaiters.Add(-1)
+		return nil, err
+	}
+	defer h.sessionWaiters.Add(-1)
 
-// PrepareUploadHandlerV1 handles POST /v1/prepare-upload requests (older protocol).
-func (h *ReceiveHandler) PrepareUploadHandlerV1(w http.ResponseWriter, r *http.Request) {
-	h.PrepareUploadHandlerV2(w, r)
+	h.logger.Infof("Holding transfer from %s until the active session ends (up to %s)", sender.Alias, h.config.SessionWait)
+	timer := time.NewTimer(h.config.SessionWait)
+	defer timer.Stop()
+	for errors.Is(err, services.ErrSessionActive) {
+		select {
+		case <-ended:
+		case <-timer.C:
+			h.logger.Infof("Transfer from %s still blocked after %s", sender.Alias, h.config.SessionWait)
+			return nil, err
+		case <-ctx.Done():
+			return nil, err
+		case <-h.shutdownCtx.Done():
+			return nil, services.ErrNotAccepting
+		}
+		ended = h.receiveService.SessionEnded()
+		session, err = h.receiveService.CreateSession(sender, files)
+	}
+	return session, err
 }
 
 // CancelHandler handles POST /v2/cancel requests.
@@ -246,25 +446,8 @@ func (h *ReceiveHandler) CancelHandler(w http.ResponseWriter, r *http.Request) {
 	if session != nil {
 		h.logger.Infof("Canceling session %s at user request.", reqSessionId)
 		h.receiveService.CloseSession(reqSessionId)
-		if h.config.OpenDir && !cli.IsContainer() {
-			go func() {
-				var cmd string
-				var args []string
-				if runtime.GOOS == "windows" {
-					cmd = "explorer.exe"
-					args = []string{h.config.DownloadDir}
-				} else if runtime.GOOS == "darwin" {
-					cmd = "open"
-					args = []string{h.config.DownloadDir}
-				} else if _, err := exec.LookPath("xdg-open"); err == nil {
-					cmd = "xdg-open"
-					args = []string{h.config.DownloadDir}
-				} else {
-					h.logger.Debugf("xdg-open not found in PATH, skip opening download dir")
-					return
-				}
-				exec.Command(cmd, args...).Run()
-			}()
+		if h.config.OpenMode == config.OpenModeDir {
+			h.openPath(h.config.DownloadDir)
 		}
 	} else {
 		// Session is already gone (completed or previously cancelled).
@@ -275,6 +458,26 @@ func (h *ReceiveHandler) CancelHandler(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 }
 
+// CancelHandlerV1 handles POST /v1/cancel requests. Without a session ID,
+// it cancels the session of the sender's address.
+func (h *ReceiveHandler) CancelHandlerV1(w http.ResponseWriter, r *http.Request) {
+	h.logger.Info("Received /v1/cancel request")
+	if r.Method != http.MethodPost {
+		httputil.RespondError(w, http.StatusMethodNotAllowed, "Method Not Allowed")
+		return
+	}
+
+	senderIP, _, _ := net.SplitHostPort(r.RemoteAddr)
+	if sessionID := h.receiveService.SessionIDForSender(senderIP); sessionID != "" {
+		h.logger.Infof("Canceling session %s at user request.", sessionID)
+		h.receiveService.CloseSession(sessionID)
+		if h.config.OpenMode == config.OpenModeDir {
+			h.openPath(h.config.DownloadDir)
+		}
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
 // sanitizeName strips ASCII control characters (0x00–0x1F) from filenames
 // to prevent UI spoofing and terminal escape injection on display.
 func sanitizeName(name string) string {
@@ -285,3 +488,20 @@ func sanitizeName(name string) string {
 		return r
 	}, name)
 }
+
+// respondInvalid reports a request that decoded but broke a constraint of
+// its DTO.
+func respondInvalid(w http.ResponseWriter, err error) {
+	httputil.RespondError(w, http.StatusBadRequest, "Invalid request: "+err.Error())
+}
+
+// respondDecodeError reports a JSON body that failed to decode: 413 when it
+// ran past a size limit, 400 otherwise.
+func respondDecodeError(w http.ResponseWriter, err error) {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		httputil.RespondError(w, http.StatusRequestEntityTooLarge, "Request body too large")
+		return
+	}
+	httputil.RespondError(w, http.StatusBadRequest, "Request body malformed")
+}
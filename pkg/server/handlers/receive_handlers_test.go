@@ -2,8 +2,10 @@ package handlers_test
 
 import (
 	"bytes"
+	"compress/gzip"
 	"context"
 	"encoding/json"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"os"
@@ -11,9 +13,15 @@ import (
 	"runtime"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/bethropolis/localgo/pkg/cli"
 	"github.com/bethropolis/localgo/pkg/config"
+	"github.com/bethropolis/localgo/pkg/crypto"
+	"github.com/bethropolis/localgo/pkg/history"
+	"github.com/bethropolis/localgo/pkg/httputil"
 	"github.com/bethropolis/localgo/pkg/model"
+	"github.com/bethropolis/localgo/pkg/report"
 	"github.com/bethropolis/localgo/pkg/server/handlers"
 	"github.com/bethropolis/localgo/pkg/server/services"
 	"go.uber.org/zap"
@@ -73,6 +81,9 @@ func TestPrepareUploadHandlerV2_Success(t *testing.T) {
 	if token, ok := respDto.Files["file1"]; !ok || token == "" {
 		t.Errorf("expected token for file1")
 	}
+	if got := rr.Header().Get("Accept-Encoding"); got != "gzip" {
+		t.Errorf("expected compressed uploads to be offered with Accept-Encoding: gzip, got %q", got)
+	}
 }
 
 func TestPrepareUploadHandlerV2_PINValidation(t *testing.T) {
@@ -115,6 +126,227 @@ func TestPrepareUploadHandlerV2_PINValidation(t *testing.T) {
 	}
 }
 
+func TestPrepareUploadHandlerV2_AcceptRules(t *testing.T) {
+	yes, no := true, false
+	cfg := &config.Config{
+		PIN:                 "1234",
+		TrustedFingerprints: []string{"trusted-fingerprint"},
+		AcceptRules: []config.AcceptRule{
+			{Fingerprints: []string{"blocked-fingerprint"}, Action: config.AcceptActionReject, PIN: &no},
+			{Trusted: &yes, Action: config.AcceptActionAccept, PIN: &no},
+			{MaxSize: 1 << 20, Action: config.AcceptActionAccept},
+		},
+	}
+
+	tests := []struct {
+		name        string
+		fingerprint string
+		size        int64
+		verified    bool // the sender presented the certificate
+		pin         string
+		wantStatus  int
+	}{
+		{"trusted device skips PIN", "trusted-fingerprint-1", 10, true, "", http.StatusOK},
+		{"copied trusted fingerprint needs PIN", "trusted-fingerprint-1", 10, false, "", http.StatusUnauthorized},
+		{"unknown device needs PIN", "unknown-fingerprint", 10, true, "", http.StatusUnauthorized},
+		{"unknown device with PIN", "unknown-fingerprint", 10, false, "1234", http.StatusOK},
+		{"blocked device", "blocked-fingerprint-1", 10, false, "", http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler, _, _ := setupReceiveHandler(t, cfg)
+
+			reqDto := model.PrepareUploadRequestDto{
+				Info:  model.InfoDto{Alias: "Sender", Fingerprint: tt.fingerprint},
+				Files: map[string]model.FileDto{"file1": {ID: "file1", FileName: "test.txt", Size: tt.size}},
+			}
+			body, _ := json.Marshal(reqDto)
+			req, _ := http.NewRequest(http.MethodPost, "/v2/prepare-upload?pin="+tt.pin, bytes.NewReader(body))
+			req.RemoteAddr = "192.168.1.100:12345"
+			if tt.verified {
+				req = httputil.WithVerifiedFingerprint(req, tt.fingerprint)
+			}
+			rr := httptest.NewRecorder()
+
+			handler.PrepareUploadHandlerV2(rr, req)
+
+			if status := rr.Code; status != tt.wantStatus {
+				t.Errorf("handler returned wrong status code: got %v want %v", status, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestPrepareUploadHandlerV2_SkipRules(t *testing.T) {
+	yes := true
+	cfg := &config.Config{
+		AutoAccept: true,
+		AcceptRules: []config.AcceptRule{
+			{Executable: &yes, Action: config.AcceptActionSkip},
+			{MinSize: 1 << 20, Action: config.AcceptActionSkip},
+		},
+	}
+	handler, receiveService, _ := setupReceiveHandler(t, cfg)
+
+	prepare := func(files map[string]model.FileDto) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(model.PrepareUploadRequestDto{Info: model.InfoDto{Alias: "Sender"}, Files: files})
+		req, _ := http.NewRequest(http.MethodPost, "/v2/prepare-upload", bytes.NewReader(body))
+		req.RemoteAddr = "192.168.1.100:12345"
+		rr := httptest.NewRecorder()
+		handler.PrepareUploadHandlerV2(rr, req)
+		return rr
+	}
+
+	rr := prepare(map[string]model.FileDto{
+		"doc":   {ID: "doc", FileName: "notes.txt", Size: 10},
+		"setup": {ID: "setup", FileName: "setup.exe", Size: 10},
+		"video": {ID: "video", FileName: "movie.mp4", Size: 2 << 20},
+	})
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var resp model.PrepareUploadResponseDto
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(resp.Files) != 1 || resp.Files["doc"] == "" {
+		t.Errorf("expected a token for doc only, got %v", resp.Files)
+	}
+	receiveService.CloseSession(resp.SessionID)
+
+	rr = prepare(map[string]model.FileDto{"setup": {ID: "setup", FileName: "setup.exe", Size: 10}})
+	if rr.Code != http.StatusForbidden {
+		t.Errorf("expected 403 when every file is skipped, got %d", rr.Code)
+	}
+}
+
+func TestPrepareUploadHandlerV2_TargetPath(t *testing.T) {
+	tests := []struct {
+		name       string
+		targetPath string
+		wantPath   string
+	}{
+		{"allowed", "Photos/trip", filepath.Join("Photos", "trip", "a.jpg")},
+		{"outside the roots", "Documents", "a.jpg"},
+		{"escaping the download dir", "Photos/../../etc", "a.jpg"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{AutoAccept: true, TargetRoots: []string{"Photos"}}
+			handler, _, tempDir := setupReceiveHandler(t, cfg)
+
+			body, _ := json.Marshal(model.PrepareUploadRequestDto{
+				Info:       model.InfoDto{Alias: "Sender"},
+				Files:      map[string]model.FileDto{"f": {ID: "f", FileName: "a.jpg", Size: 5}},
+				TargetPath: tt.targetPath,
+			})
+			req, _ := http.NewRequest(http.MethodPost, "/v2/prepare-upload", bytes.NewReader(body))
+			req.RemoteAddr = "127.0.0.1:9999"
+			rr := httptest.NewRecorder()
+			handler.PrepareUploadHandlerV2(rr, req)
+			var resp model.PrepareUploadResponseDto
+			if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil || rr.Code != http.StatusOK {
+				t.Fatalf("prepare failed: %d %s", rr.Code, rr.Body.String())
+			}
+
+			req, _ = http.NewRequest(http.MethodPost, "/v2/upload?sessionId="+resp.SessionID+"&fileId=f&token="+resp.Files["f"], strings.NewReader("hello"))
+			req.RemoteAddr = "127.0.0.1:9999"
+			rr = httptest.NewRecorder()
+			handler.UploadHandlerV2(rr, req)
+			if rr.Code != http.StatusOK {
+				t.Fatalf("upload failed: %d %s", rr.Code, rr.Body.String())
+			}
+			if _, err := os.Stat(filepath.Join(tempDir, tt.wantPath)); err != nil {
+				t.Errorf("expected the file at %s: %v", tt.wantPath, err)
+			}
+		})
+	}
+}
+
+func TestPrepareUploadHandlerV2_QuickSave(t *testing.T) {
+	yes := true
+	cfg := &config.Config{
+		AcceptRules: []config.AcceptRule{
+			{Executable: &yes, Action: config.AcceptActionReject},
+		},
+	}
+
+	tests := []struct {
+		name       string
+		fileName   string
+		wantStatus int
+	}{
+		// Without quick save this transfer would be prompted.
+		{"prompted transfer is accepted", "photo.jpg", http.StatusOK},
+		{"reject rule still applies", "setup.exe", http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler, receiveService, _ := setupReceiveHandler(t, cfg)
+			receiveService.EnableQuickSave(0)
+
+			reqDto := model.PrepareUploadRequestDto{
+				Info:  model.InfoDto{Alias: "Sender"},
+				Files: map[string]model.FileDto{"file1": {ID: "file1", FileName: tt.fileName, Size: 10}},
+			}
+			body, _ := json.Marshal(reqDto)
+			req, _ := http.NewRequest(http.MethodPost, "/v2/prepare-upload", bytes.NewReader(body))
+			req.RemoteAddr = "192.168.1.100:12345"
+			rr := httptest.NewRecorder()
+
+			handler.PrepareUploadHandlerV2(rr, req)
+
+			if status := rr.Code; status != tt.wantStatus {
+				t.Errorf("handler returned wrong status code: got %v want %v", status, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestPrepareUploadHandlerV2_AllowedSenders(t *testing.T) {
+	const phone = "3f9a1c2b7d4e8f60aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"
+	tests := []struct {
+		name       string
+		alias      string
+		verified   string // fingerprint the sender proves, if any
+		wantStatus int
+	}{
+		{"allowed alias", "MyPhone", "", http.StatusOK},
+		{"other alias", "Stranger", "", http.StatusForbidden},
+		{"allowed fingerprint", "Anything", phone, http.StatusOK},
+		{"claimed fingerprint", "Anything", "", http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler, _, _ := setupReceiveHandler(t, &config.Config{
+				AutoAccept:     true,
+				AllowedSenders: []string{"MyPhone", "3F9A1C2B7D4E8F60"},
+			})
+
+			reqDto := model.PrepareUploadRequestDto{
+				Info:  model.InfoDto{Alias: tt.alias, Fingerprint: phone},
+				Files: map[string]model.FileDto{"file1": {ID: "file1", FileName: "test.txt", Size: 10}},
+			}
+			body, _ := json.Marshal(reqDto)
+			req, _ := http.NewRequest(http.MethodPost, "/v2/prepare-upload", bytes.NewReader(body))
+			req.RemoteAddr = "192.168.1.100:12345"
+			if tt.verified != "" {
+				req = httputil.WithVerifiedFingerprint(req, tt.verified)
+			}
+			rr := httptest.NewRecorder()
+
+			handler.PrepareUploadHandlerV2(rr, req)
+
+			if status := rr.Code; status != tt.wantStatus {
+				t.Errorf("handler returned wrong status code: got %v want %v", status, tt.wantStatus)
+			}
+		})
+	}
+}
+
 func TestPrepareUploadHandlerV2_RejectsConcurrentSessions(t *testing.T) {
 	handler, receiveService, _ := setupReceiveHandler(t, nil)
 
@@ -134,6 +366,51 @@ func TestPrepareUploadHandlerV2_RejectsConcurrentSessions(t *testing.T) {
 	if status := rr.Code; status != http.StatusConflict {
 		t.Errorf("handler returned wrong status code for concurrent session: got %v want %v", status, http.StatusConflict)
 	}
+	if ra := rr.Header().Get("Retry-After"); ra != "" {
+		t.Errorf("Retry-After = %q without session_wait, want none", ra)
+	}
+}
+
+func TestPrepareUploadHandlerV2_SessionWait(t *testing.T) {
+	prepare := func(handler *handlers.ReceiveHandler) *httptest.ResponseRecorder {
+		reqDto := model.PrepareUploadRequestDto{
+			Files: map[string]model.FileDto{"file1": {ID: "file1", FileName: "test.txt", Size: 10}},
+		}
+		body, _ := json.Marshal(reqDto)
+		req, _ := http.NewRequest(http.MethodPost, "/v2/prepare-upload", bytes.NewReader(body))
+		req.RemoteAddr = "192.168.1.101:12345"
+		rr := httptest.NewRecorder()
+		handler.PrepareUploadHandlerV2(rr, req)
+		return rr
+	}
+
+	t.Run("held until the active session ends", func(t *testing.T) {
+		handler, receiveService, _ := setupReceiveHandler(t, &config.Config{AutoAccept: true, SessionWait: 5 * time.Second})
+		active, _ := receiveService.CreateSession(model.DeviceInfo{IP: "192.168.1.100"}, map[string]model.FileDto{"f": {ID: "f"}})
+		time.AfterFunc(100*time.Millisecond, func() { receiveService.CloseSession(active.SessionID) })
+
+		start := time.Now()
+		rr := prepare(handler)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("status = %d, want %d once the active session ended; body: %s", rr.Code, http.StatusOK, rr.Body.String())
+		}
+		if waited := time.Since(start); waited < 100*time.Millisecond {
+			t.Errorf("answered after %s, before the active session ended", waited)
+		}
+	})
+
+	t.Run("parked when the wait runs out", func(t *testing.T) {
+		handler, receiveService, _ := setupReceiveHandler(t, &config.Config{AutoAccept: true, SessionWait: 100 * time.Millisecond})
+		receiveService.CreateSession(model.DeviceInfo{IP: "192.168.1.100"}, map[string]model.FileDto{"f": {ID: "f"}})
+
+		rr := prepare(handler)
+		if rr.Code != http.StatusConflict {
+			t.Fatalf("status = %d, want %d", rr.Code, http.StatusConflict)
+		}
+		if ra := rr.Header().Get("Retry-After"); ra != "1" {
+			t.Errorf("Retry-After = %q, want %q", ra, "1")
+		}
+	})
 }
 
 func TestUploadHandlerV2_PathTraversalRejection(t *testing.T) {
@@ -223,6 +500,358 @@ func TestUploadHandlerV2_Success(t *testing.T) {
 	}
 }
 
+func TestUploadHandlerV2_Manifest(t *testing.T) {
+	handler, receiveService, tempDir := setupReceiveHandler(t, &config.Config{AutoAccept: true, Manifest: config.ManifestJSON})
+	receiveService.AddSummaryHandler(handler.WriteManifest)
+
+	files := map[string]model.FileDto{
+		"file1": {ID: "file1", FileName: "notes.txt", Size: 9},
+	}
+	session, _ := receiveService.CreateSession(model.DeviceInfo{Alias: "Laptop", IP: "192.168.1.100", Fingerprint: "abc123"}, files)
+	req, _ := http.NewRequest(http.MethodPost, "/v2/upload?sessionId="+session.SessionID+"&fileId=file1&token="+session.Files["file1"].Token, strings.NewReader("test data"))
+	req.RemoteAddr = "192.168.1.100:12345"
+	rr := httptest.NewRecorder()
+	handler.UploadHandlerV2(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("upload status = %d, want %d", rr.Code, http.StatusOK)
+	}
+	handler.WaitHooks(context.Background())
+
+	paths, _ := filepath.Glob(filepath.Join(tempDir, "localgo-manifest-*.json"))
+	if len(paths) != 1 {
+		t.Fatalf("manifests = %v, want one", paths)
+	}
+	data, err := os.ReadFile(paths[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var m report.Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		t.Fatalf("decoding manifest: %v", err)
+	}
+	if m.SessionID != session.SessionID || m.Sender != "Laptop" || m.SenderFingerprint != "abc123" {
+		t.Errorf("manifest session %q from %q (%q), want %q from Laptop (abc123)", m.SessionID, m.Sender, m.SenderFingerprint, session.SessionID)
+	}
+	want := report.ManifestFile{Name: "notes.txt", Path: "notes.txt", Size: 9, SHA256: "916f0027a575074ce72a331777c3478d6513f786a591bd892da1a577bf2335f9"}
+	if len(m.Files) != 1 || m.Files[0] != want {
+		t.Errorf("manifest files = %+v, want [%+v]", m.Files, want)
+	}
+}
+
+func TestUploadHandlerV2_ScanQuarantines(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the scan command is a POSIX shell script")
+	}
+	cfg := &config.Config{AutoAccept: true, ScanCmd: `! grep -q EICAR "$LOCALGO_FILE"`}
+	handler, receiveService, tempDir := setupReceiveHandler(t, cfg)
+
+	files := map[string]model.FileDto{
+		"clean": {ID: "clean", FileName: "clean.bin", Size: 5},
+		"bad":   {ID: "bad", FileName: "bad.bin", Size: 10},
+	}
+	contents := map[string]string{"clean": "hello", "bad": "EICAR test"}
+	session, _ := receiveService.CreateSession(model.DeviceInfo{Alias: "Laptop", IP: "192.168.1.100"}, files)
+	codes := make(map[string]int)
+	for id := range files {
+		req, _ := http.NewRequest(http.MethodPost, "/v2/upload?sessionId="+session.SessionID+"&fileId="+id+"&token="+session.Files[id].Token, strings.NewReader(contents[id]))
+		req.RemoteAddr = "192.168.1.100:12345"
+		rr := httptest.NewRecorder()
+		handler.UploadHandlerV2(rr, req)
+		codes[id] = rr.Code
+	}
+
+	if codes["clean"] != http.StatusOK {
+		t.Errorf("clean upload status = %d, want %d", codes["clean"], http.StatusOK)
+	}
+	if codes["bad"] != http.StatusUnprocessableEntity {
+		t.Errorf("infected upload status = %d, want %d", codes["bad"], http.StatusUnprocessableEntity)
+	}
+	if _, err := os.Stat(filepath.Join(tempDir, "clean.bin")); err != nil {
+		t.Errorf("clean file not saved: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(tempDir, "bad.bin")); !os.IsNotExist(err) {
+		t.Error("infected file saved to the download directory")
+	}
+	if data, err := os.ReadFile(filepath.Join(tempDir, ".quarantine", "bad.bin")); err != nil || string(data) != contents["bad"] {
+		t.Errorf("quarantined file = %q, %v; want %q", data, err, contents["bad"])
+	}
+}
+
+func TestUploadHandlerV2_ScanDoesNotExpandFileName(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the scan command is a POSIX shell script")
+	}
+	// Were the name put in the command line, $(echo y) would run and the
+	// file would not be found.
+	cfg := &config.Config{AutoAccept: true, ScanCmd: `test -f %f`}
+	handler, receiveService, tempDir := setupReceiveHandler(t, cfg)
+
+	name := "x$(echo y).bin"
+	files := map[string]model.FileDto{"f": {ID: "f", FileName: name, Size: 5}}
+	session, _ := receiveService.CreateSession(model.DeviceInfo{Alias: "Laptop", IP: "192.168.1.100"}, files)
+	req, _ := http.NewRequest(http.MethodPost, "/v2/upload?sessionId="+session.SessionID+"&fileId=f&token="+session.Files["f"].Token, strings.NewReader("hello"))
+	req.RemoteAddr = "192.168.1.100:12345"
+	rr := httptest.NewRecorder()
+	handler.UploadHandlerV2(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("upload status = %d, want %d: %s", rr.Code, http.StatusOK, rr.Body.String())
+	}
+	if _, err := os.Stat(filepath.Join(tempDir, name)); err != nil {
+		t.Errorf("file not saved under its own name: %v", err)
+	}
+}
+
+func TestUploadHandlerV2_EncryptAtRest(t *testing.T) {
+	id, err := crypto.GenerateIdentity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler, receiveService, tempDir := setupReceiveHandler(t, &config.Config{AutoAccept: true, EncryptTo: id.Recipient().String()})
+
+	files := map[string]model.FileDto{
+		"file1": {ID: "file1", FileName: "secret.txt", Size: 9},
+	}
+	session, _ := receiveService.CreateSession(model.DeviceInfo{IP: "192.168.1.100"}, files)
+	req, _ := http.NewRequest(http.MethodPost, "/v2/upload?sessionId="+session.SessionID+"&fileId=file1&token="+session.Files["file1"].Token, strings.NewReader("test data"))
+	req.RemoteAddr = "192.168.1.100:12345"
+	rr := httptest.NewRecorder()
+	handler.UploadHandlerV2(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("upload status = %d, want %d", rr.Code, http.StatusOK)
+	}
+
+	if _, err := os.Stat(filepath.Join(tempDir, "secret.txt")); !os.IsNotExist(err) {
+		t.Error("file saved in the clear")
+	}
+	f, err := os.Open(filepath.Join(tempDir, "secret.txt"+crypto.EncryptedExt))
+	if err != nil {
+		t.Fatalf("encrypted file not saved: %v", err)
+	}
+	defer f.Close()
+	plain, err := crypto.NewDecryptReader(f, id)
+	if err != nil {
+		t.Fatalf("NewDecryptReader: %v", err)
+	}
+	if data, err := io.ReadAll(plain); err != nil || string(data) != "test data" {
+		t.Errorf("decrypted = %q, %v; want %q", data, err, "test data")
+	}
+}
+
+func TestUploadHandlerV2_ScanBeforeEncrypt(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the scan command is a POSIX shell script")
+	}
+	id, err := crypto.GenerateIdentity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The scanner must see the content, not the ciphertext.
+	cfg := &config.Config{AutoAccept: true, ScanCmd: `! grep -q EICAR "$LOCALGO_FILE"`, EncryptTo: id.Recipient().String()}
+	handler, receiveService, tempDir := setupReceiveHandler(t, cfg)
+
+	files := map[string]model.FileDto{"bad": {ID: "bad", FileName: "bad.bin", Size: 10}}
+	session, _ := receiveService.CreateSession(model.DeviceInfo{Alias: "Laptop", IP: "192.168.1.100"}, files)
+	req, _ := http.NewRequest(http.MethodPost, "/v2/upload?sessionId="+session.SessionID+"&fileId=bad&token="+session.Files["bad"].Token, strings.NewReader("EICAR test"))
+	req.RemoteAddr = "192.168.1.100:12345"
+	rr := httptest.NewRecorder()
+	handler.UploadHandlerV2(rr, req)
+
+	if rr.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("infected upload status = %d, want %d", rr.Code, http.StatusUnprocessableEntity)
+	}
+	if _, err := os.Stat(filepath.Join(tempDir, "bad.bin"+crypto.EncryptedExt)); !os.IsNotExist(err) {
+		t.Error("infected file saved to the download directory")
+	}
+	entries, _ := os.ReadDir(tempDir)
+	for _, e := range entries {
+		if strings.HasPrefix(e.Name(), ".localgo-scan-") {
+			t.Errorf("staging file %s left behind", e.Name())
+		}
+	}
+	f, err := os.Open(filepath.Join(tempDir, ".quarantine", "bad.bin"+crypto.EncryptedExt))
+	if err != nil {
+		t.Fatalf("file not quarantined: %v", err)
+	}
+	defer f.Close()
+	plain, err := crypto.NewDecryptReader(f, id)
+	if err != nil {
+		t.Fatalf("quarantined file is not encrypted: %v", err)
+	}
+	if data, err := io.ReadAll(plain); err != nil || string(data) != "EICAR test" {
+		t.Errorf("decrypted = %q, %v; want %q", data, err, "EICAR test")
+	}
+}
+
+func TestUploadHandlerV2_Dedupe(t *testing.T) {
+	tempDir := t.TempDir()
+	historyLog, err := history.NewLogger(filepath.Join(t.TempDir(), "history.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer historyLog.Close()
+	receiveService := services.NewReceiveService()
+	cfg := &config.Config{DownloadDir: tempDir, AutoAccept: true, Dedupe: true}
+	handler := handlers.NewReceiveHandler(cfg, receiveService, historyLog, context.Background(), testLogger)
+
+	for _, name := range []string{"a.txt", "b.txt"} {
+		files := map[string]model.FileDto{"file1": {ID: "file1", FileName: name, Size: 9}}
+		session, _ := receiveService.CreateSession(model.DeviceInfo{IP: "192.168.1.100"}, files)
+		req, _ := http.NewRequest(http.MethodPost, "/v2/upload?sessionId="+session.SessionID+"&fileId=file1&token="+session.Files["file1"].Token, strings.NewReader("test data"))
+		req.RemoteAddr = "192.168.1.100:12345"
+		rr := httptest.NewRecorder()
+		handler.UploadHandlerV2(rr, req)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("upload of %s: status = %d, want %d", name, rr.Code, http.StatusOK)
+		}
+		receiveService.CloseSession(session.SessionID)
+	}
+
+	a, errA := os.Stat(filepath.Join(tempDir, "a.txt"))
+	b, errB := os.Stat(filepath.Join(tempDir, "b.txt"))
+	if errA != nil || errB != nil {
+		t.Fatalf("files not saved: %v, %v", errA, errB)
+	}
+	if !os.SameFile(a, b) {
+		t.Error("identical files were stored twice, want a hard link")
+	}
+}
+
+func TestUploadHandlerV2_ParallelUploads(t *testing.T) {
+	handler, receiveService, tempDir := setupReceiveHandler(t, nil)
+
+	files := map[string]model.FileDto{
+		"file1": {ID: "file1", FileName: "dup.txt", Size: 5},
+		"file2": {ID: "file2", FileName: "dup.txt", Size: 5},
+	}
+	session, _ := receiveService.CreateSession(model.DeviceInfo{IP: "192.168.1.100"}, files)
+
+	// Both uploads are claimed before either body arrives, so they run at
+	// the same time and must not be saved to the same path.
+	contents := map[string]string{"file1": "first", "file2": "secnd"}
+	writers := make(map[string]*io.PipeWriter)
+	var reqs []*http.Request
+	// The session is live: read its tokens before any upload claims a file.
+	for id := range files {
+		pr, pw := io.Pipe()
+		writers[id] = pw
+		req, _ := http.NewRequest(http.MethodPost, "/v2/upload?sessionId="+session.SessionID+"&fileId="+id+"&token="+session.Files[id].Token, pr)
+		req.ContentLength = int64(len(contents[id]))
+		req.RemoteAddr = "192.168.1.100:12345"
+		reqs = append(reqs, req)
+	}
+	codes := make(chan int, len(files))
+	for _, req := range reqs {
+		go func() {
+			rr := httptest.NewRecorder()
+			handler.UploadHandlerV2(rr, req)
+			codes <- rr.Code
+		}()
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		status, _ := receiveService.SessionStatus(session.SessionID)
+		receiving := 0
+		for _, f := range status.Files {
+			if f.Status == "receiving" {
+				receiving++
+			}
+		}
+		if receiving == len(files) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%d of %d uploads claimed", receiving, len(files))
+		}
+		time.Sleep(time.Millisecond)
+	}
+	for id, pw := range writers {
+		pw.Write([]byte(contents[id]))
+		pw.Close()
+	}
+	for range files {
+		if code := <-codes; code != http.StatusOK {
+			t.Errorf("upload status = %d, want %d", code, http.StatusOK)
+		}
+	}
+
+	got := make(map[string]bool)
+	for _, name := range []string{"dup.txt", "dup (1).txt"} {
+		data, err := os.ReadFile(filepath.Join(tempDir, name))
+		if err != nil {
+			t.Fatalf("reading %s: %v", name, err)
+		}
+		got[string(data)] = true
+	}
+	if !got["first"] || !got["secnd"] {
+		t.Errorf("saved contents = %v, want both uploads kept", got)
+	}
+}
+
+func TestUploadHandlerV2_MaxSessionUploads(t *testing.T) {
+	handler, receiveService, _ := setupReceiveHandler(t, nil)
+	receiveService.SetMaxUploads(1)
+
+	files := map[string]model.FileDto{
+		"file1": {ID: "file1", FileName: "a.txt", Size: 4},
+		"file2": {ID: "file2", FileName: "b.txt", Size: 4},
+	}
+	session, _ := receiveService.CreateSession(model.DeviceInfo{IP: "192.168.1.100"}, files)
+	if _, _, err := receiveService.ClaimFile(session.SessionID, "file1", session.Files["file1"].Token, "192.168.1.100"); err != nil {
+		t.Fatalf("ClaimFile: %v", err)
+	}
+
+	req, _ := http.NewRequest(http.MethodPost, "/v2/upload?sessionId="+session.SessionID+"&fileId=file2&token="+session.Files["file2"].Token, strings.NewReader("data"))
+	req.RemoteAddr = "192.168.1.100:12345"
+	rr := httptest.NewRecorder()
+	handler.UploadHandlerV2(rr, req)
+
+	if rr.Code != http.StatusTooManyRequests {
+		t.Fatalf("status = %d, want %d while another upload of the session runs", rr.Code, http.StatusTooManyRequests)
+	}
+	if ra := rr.Header().Get("Retry-After"); ra == "" {
+		t.Error("expected a Retry-After header")
+	}
+}
+
+func TestUploadHandlerV2_SessionCancelledMidUpload(t *testing.T) {
+	handler, receiveService, tempDir := setupReceiveHandler(t, nil)
+
+	files := map[string]model.FileDto{
+		"file1": {ID: "file1", FileName: "big.bin", Size: 100},
+	}
+	session, _ := receiveService.CreateSession(model.DeviceInfo{IP: "192.168.1.100"}, files)
+
+	pr, pw := io.Pipe()
+	defer pw.Close()
+	req, _ := http.NewRequest(http.MethodPost, "/v2/upload?sessionId="+session.SessionID+"&fileId=file1&token="+session.Files["file1"].Token, pr)
+	req.ContentLength = 100
+	req.RemoteAddr = "192.168.1.100:12345"
+	rr := httptest.NewRecorder()
+	done := make(chan struct{})
+	go func() {
+		handler.UploadHandlerV2(rr, req)
+		close(done)
+	}()
+
+	pw.Write([]byte("0123")) // returns once the upload has read it
+	receiveService.CloseSession(session.SessionID)
+	go pw.Write([]byte("4567"))
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("upload kept going after its session was cancelled")
+	}
+	if rr.Code != http.StatusConflict {
+		t.Errorf("status = %d, want %d", rr.Code, http.StatusConflict)
+	}
+	entries, _ := os.ReadDir(tempDir)
+	for _, e := range entries {
+		t.Errorf("unexpected file left behind: %s", e.Name())
+	}
+}
+
 func TestCancelHandler(t *testing.T) {
 	handler, receiveService, _ := setupReceiveHandler(t, nil)
 
@@ -316,6 +945,46 @@ func TestUploadHandlerV2_TextPlain_NoClipboard(t *testing.T) {
 	}
 }
 
+func TestPrepareUploadHandlerV2_PrintMessages(t *testing.T) {
+	cfg := &config.Config{
+		AutoAccept:    true,
+		NoClipboard:   true,
+		PrintMessages: true,
+	}
+	handler, receiveService, tempDir := setupReceiveHandler(t, cfg)
+
+	var received services.ReceivedFile
+	receiveService.AddFileHandler(func(f services.ReceivedFile) { received = f })
+	var printed bytes.Buffer
+	cli.SetPrintOutput(&printed)
+	defer cli.SetPrintOutput(os.Stdout)
+
+	text := "see you at 6"
+	reqDto := model.PrepareUploadRequestDto{
+		Info:  model.InfoDto{Alias: "Phone"},
+		Files: map[string]model.FileDto{"msg": {ID: "msg", FileName: "msg.txt", Size: int64(len(text)), FileType: "text/plain", Preview: &text}},
+	}
+	body, _ := json.Marshal(reqDto)
+	req, _ := http.NewRequest(http.MethodPost, "/v2/prepare-upload", bytes.NewReader(body))
+	req.RemoteAddr = "192.168.1.100:12345"
+	rr := httptest.NewRecorder()
+
+	handler.PrepareUploadHandlerV2(rr, req)
+
+	if rr.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if !strings.Contains(printed.String(), "Message from Phone") || !strings.Contains(printed.String(), text) {
+		t.Errorf("expected the message to be printed, got %q", printed.String())
+	}
+	if received.Path != "<terminal>" {
+		t.Errorf("expected the message to be completed as printed, got path %q", received.Path)
+	}
+	if entries, _ := os.ReadDir(tempDir); len(entries) != 0 {
+		t.Errorf("expected nothing in the download directory, got %d entries", len(entries))
+	}
+}
+
 func TestUploadHandlerV2_TextPlain_PathTraversal_Returns400(t *testing.T) {
 	cfg := &config.Config{
 		AutoAccept:  true,
@@ -470,3 +1139,191 @@ func TestPrepareUploadHandlerV2_NegativeFileSize_Returns400(t *testing.T) {
 		t.Errorf("expected 400 Bad Request for negative file size, got %v (body: %s)", status, rr.Body.String())
 	}
 }
+
+func TestPrepareUploadHandlerV2_MaxBodySize_Returns413(t *testing.T) {
+	handler, _, _ := setupReceiveHandler(t, &config.Config{AutoAccept: true, MaxBodySize: 100})
+
+	files := map[string]model.FileDto{
+		"f1": {ID: "f1", FileName: "small.txt", Size: 100},
+		"f2": {ID: "f2", FileName: "big.bin", Size: 101},
+	}
+	body, _ := json.Marshal(model.PrepareUploadRequestDto{Files: files})
+
+	req, _ := http.NewRequest(http.MethodPost, "/v2/prepare-upload", bytes.NewReader(body))
+	req.RemoteAddr = "192.168.1.100:12345"
+	rr := httptest.NewRecorder()
+
+	handler.PrepareUploadHandlerV2(rr, req)
+
+	if status := rr.Code; status != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected 413 for a file over MaxBodySize, got %v (body: %s)", status, rr.Body.String())
+	}
+}
+
+func TestPrepareUploadHandlerV2_OversizedBody_Returns413(t *testing.T) {
+	handler, _, _ := setupReceiveHandler(t, nil)
+
+	body := `{"info":{"alias":"` + strings.Repeat("a", 2*1024*1024) + `"},"files":{}}`
+	req, _ := http.NewRequest(http.MethodPost, "/v2/prepare-upload", strings.NewReader(body))
+	req.RemoteAddr = "192.168.1.100:12345"
+	rr := httptest.NewRecorder()
+
+	handler.PrepareUploadHandlerV2(rr, req)
+
+	if status := rr.Code; status != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected 413 for an oversized request body, got %v", status)
+	}
+}
+
+func TestUploadHandlerV2_SizeMismatch_Returns400(t *testing.T) {
+	// io.MultiReader hides the length, so those requests are sent chunked.
+	tests := []struct {
+		name string
+		body io.Reader
+	}{
+		{"declared length too long", strings.NewReader("0123456789ABC")},
+		{"declared length too short", strings.NewReader("01234")},
+		{"chunked body too long", io.MultiReader(strings.NewReader("0123456789ABC"))},
+		{"chunked body too short", io.MultiReader(strings.NewReader("01234"))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler, receiveService, tempDir := setupReceiveHandler(t, nil)
+
+			files := map[string]model.FileDto{
+				"f1": {ID: "f1", FileName: "sized.bin", Size: 10},
+			}
+			session, _ := receiveService.CreateSession(model.DeviceInfo{IP: "192.168.1.100"}, files)
+
+			req, _ := http.NewRequest(http.MethodPost,
+				"/v2/upload?sessionId="+session.SessionID+"&fileId=f1&token="+session.Files["f1"].Token, tt.body)
+			req.RemoteAddr = "192.168.1.100:12345"
+			rr := httptest.NewRecorder()
+
+			handler.UploadHandlerV2(rr, req)
+
+			if status := rr.Code; status != http.StatusBadRequest {
+				t.Errorf("expected 400 for a body not matching the declared size, got %v (body: %s)", status, rr.Body.String())
+			}
+			if _, err := os.Stat(filepath.Join(tempDir, "sized.bin")); !os.IsNotExist(err) {
+				t.Errorf("expected no file to be saved, stat error: %v", err)
+			}
+		})
+	}
+}
+
+func TestUploadHandlerV2_Compressed(t *testing.T) {
+	content := strings.Repeat("line of a log file\n", 1000)
+	gzipped := func(s string) []byte {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		zw.Write([]byte(s))
+		zw.Close()
+		return buf.Bytes()
+	}
+	corrupt := gzipped(content)
+	corrupt[len(corrupt)/2] ^= 0xff
+
+	tests := []struct {
+		name     string
+		encoding string
+		body     []byte
+		want     int
+	}{
+		{"gzip", "gzip", gzipped(content), http.StatusOK},
+		{"decodes to the wrong size", "gzip", gzipped(content + "extra"), http.StatusBadRequest},
+		{"corrupt", "gzip", corrupt, http.StatusBadRequest},
+		{"unsupported coding", "br", []byte(content), http.StatusUnsupportedMediaType},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler, receiveService, tempDir := setupReceiveHandler(t, nil)
+			files := map[string]model.FileDto{
+				"f1": {ID: "f1", FileName: "app.log", Size: int64(len(content))},
+			}
+			session, _ := receiveService.CreateSession(model.DeviceInfo{IP: "192.168.1.100"}, files)
+
+			req, _ := http.NewRequest(http.MethodPost,
+				"/v2/upload?sessionId="+session.SessionID+"&fileId=f1&token="+session.Files["f1"].Token, bytes.NewReader(tt.body))
+			req.Header.Set("Content-Encoding", tt.encoding)
+			req.RemoteAddr = "192.168.1.100:12345"
+			rr := httptest.NewRecorder()
+
+			handler.UploadHandlerV2(rr, req)
+
+			if rr.Code != tt.want {
+				t.Fatalf("got status %d, want %d (body: %s)", rr.Code, tt.want, rr.Body.String())
+			}
+			saved, err := os.ReadFile(filepath.Join(tempDir, "app.log"))
+			if tt.want != http.StatusOK {
+				if err == nil {
+					t.Error("expected no file to be saved")
+				}
+				return
+			}
+			if err != nil || string(saved) != content {
+				t.Errorf("saved file does not match the original (err %v, %d bytes)", err, len(saved))
+			}
+		})
+	}
+}
+
+// TestV1Handlers_SendRequestUploadCancel walks a legacy LocalSend transfer
+// through the v1 endpoints, which carry no session IDs.
+func TestV1Handlers_SendRequestUploadCancel(t *testing.T) {
+	handler, receiveService, tempDir := setupReceiveHandler(t, nil)
+
+	body := `{"info":{"alias":"Old Phone","deviceModel":"Pixel","deviceType":"mobile"},` +
+		`"files":{"f1":{"id":"f1","fileName":"photo.jpg","size":5,"fileType":"image"},` +
+		`"f2":{"id":"f2","fileName":"notes.bin","size":3,"fileType":"other"}}}`
+	req, _ := http.NewRequest(http.MethodPost, "/v1/send-request", strings.NewReader(body))
+	req.RemoteAddr = "192.168.1.100:12345"
+	rr := httptest.NewRecorder()
+	handler.SendRequestHandlerV1(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("send-request: got %d, want 200 (body: %s)", rr.Code, rr.Body.String())
+	}
+	var tokens map[string]string
+	if err := json.NewDecoder(rr.Body).Decode(&tokens); err != nil {
+		t.Fatalf("send-request response is not a map of tokens: %v", err)
+	}
+	if len(tokens) != 2 || tokens["f1"] == "" || tokens["f2"] == "" {
+		t.Fatalf("expected a token for each file, got %v", tokens)
+	}
+	session := receiveService.GetSession()
+	if session == nil || session.Files["f1"].Dto.FileType != "image/jpeg" {
+		t.Fatalf("expected a session with f1 as image/jpeg, got %+v", session)
+	}
+
+	upload := func(fileID, token, remoteAddr, data string) int {
+		req, _ := http.NewRequest(http.MethodPost, "/v1/send?fileId="+fileID+"&token="+token, strings.NewReader(data))
+		req.RemoteAddr = remoteAddr
+		rr := httptest.NewRecorder()
+		handler.SendHandlerV1(rr, req)
+		return rr.Code
+	}
+	if code := upload("f1", tokens["f1"], "192.168.1.200:12345", "hello"); code != http.StatusForbidden {
+		t.Errorf("upload from another address: got %d, want 403", code)
+	}
+	if code := upload("f1", "wrong", "192.168.1.100:12345", "hello"); code != http.StatusForbidden {
+		t.Errorf("upload with a wrong token: got %d, want 403", code)
+	}
+	if code := upload("f1", tokens["f1"], "192.168.1.100:12345", "hello"); code != http.StatusOK {
+		t.Fatalf("upload: got %d, want 200", code)
+	}
+	if got, err := os.ReadFile(filepath.Join(tempDir, "photo.jpg")); err != nil || string(got) != "hello" {
+		t.Errorf("photo.jpg = %q, %v; want \"hello\"", got, err)
+	}
+
+	req, _ = http.NewRequest(http.MethodPost, "/v1/cancel", nil)
+	req.RemoteAddr = "192.168.1.100:12345"
+	rr = httptest.NewRecorder()
+	handler.CancelHandlerV1(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Errorf("cancel: got %d, want 200", rr.Code)
+	}
+	if receiveService.GetSession() != nil {
+		t.Error("expected cancel to close the sender's session")
+	}
+}
@@ -11,9 +11,12 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/bethropolis/localgo/pkg/cli"
 	"github.com/bethropolis/localgo/pkg/clipboard"
+	"github.com/bethropolis/localgo/pkg/compression"
 	"github.com/bethropolis/localgo/pkg/history"
 	"github.com/bethropolis/localgo/pkg/httputil"
+	"github.com/bethropolis/localgo/pkg/metadata"
 	"github.com/bethropolis/localgo/pkg/model"
 	"github.com/bethropolis/localgo/pkg/server/services"
 	"github.com/bethropolis/localgo/pkg/storage"
@@ -37,12 +40,56 @@ func (h *ReceiveHandler) UploadHandlerV2(w http.ResponseWriter, r *http.Request)
 	reqSessionId := query.Get("sessionId")
 	reqFileId := query.Get("fileId")
 	reqToken := query.Get("token")
-	reqIP, _, _ := net.SplitHostPort(r.RemoteAddr)
 
 	if reqSessionId == "" || reqFileId == "" || reqToken == "" {
 		httputil.RespondError(w, http.StatusBadRequest, "Missing query parameters (sessionId, fileId, token)")
 		return
 	}
+	h.receiveUpload(w, r, reqSessionId, reqFileId, reqToken)
+}
+
+// SendHandlerV1 handles POST /v1/send, the v1 upload. v1 senders are not
+// told a session ID, so the upload is matched to the session of the
+// sender's address.
+func (h *ReceiveHandler) SendHandlerV1(w http.ResponseWriter, r *http.Request) {
+	if h.shutdownCtx.Err() != nil {
+		h.logger.Warn("Rejecting /v1/send — server is shutting down")
+		httputil.RespondError(w, http.StatusServiceUnavailable, "Server shutting down")
+		return
+	}
+
+	h.logger.Info("Received /v1/send request")
+	if r.Method != http.MethodPost {
+		httputil.RespondError(w, http.StatusMethodNotAllowed, "Method Not Allowed")
+		return
+	}
+
+	query := r.URL.Query()
+	reqFileId := query.Get("fileId")
+	reqToken := query.Get("token")
+	if reqFileId == "" || reqToken == "" {
+		httputil.RespondError(w, http.StatusBadRequest, "Missing query parameters (fileId, token)")
+		return
+	}
+	reqIP, _, _ := net.SplitHostPort(r.RemoteAddr)
+	reqSessionId := h.receiveService.SessionIDForSender(reqIP)
+	if reqSessionId == "" {
+		httputil.RespondError(w, http.StatusForbidden, "No session for this sender")
+		return
+	}
+	h.receiveUpload(w, r, reqSessionId, reqFileId, reqToken)
+}
+
+// receiveUpload saves the body of an upload to a file of session
+// reqSessionId, once its token checks out.
+func (h *ReceiveHandler) receiveUpload(w http.ResponseWriter, r *http.Request, reqSessionId, reqFileId, reqToken string) {
+	reqIP, _, _ := net.SplitHostPort(r.RemoteAddr)
+	encoding := r.Header.Get("Content-Encoding")
+	if !compression.Supported(encoding) {
+		w.Header().Set("Accept-Encoding", compression.Gzip)
+		httputil.RespondError(w, http.StatusUnsupportedMediaType, "Unsupported content encoding")
+		return
+	}
 
 	// --- Atomic Claim: validates session, IP, fileId, token under mutex ---
 	dto, sender, err := h.receiveService.ClaimFile(reqSessionId, reqFileId, reqToken, reqIP)
@@ -57,6 +104,9 @@ func (h *ReceiveHandler) UploadHandlerV2(w http.ResponseWriter, r *http.Request)
 			httputil.RespondError(w, http.StatusForbidden, "Invalid fileId or token")
 		case errors.Is(err, services.ErrAlreadyUploading), errors.Is(err, services.ErrAlreadyCompleted):
 			httputil.RespondError(w, http.StatusConflict, "File already being uploaded")
+		case errors.Is(err, services.ErrTooManyUploads):
+			w.Header().Set("Retry-After", "1")
+			httputil.RespondError(w, http.StatusTooManyRequests, "Too many uploads in this session")
 		default:
 			httputil.RespondError(w, http.StatusForbidden, "Invalid request")
 		}
@@ -66,8 +116,10 @@ func (h *ReceiveHandler) UploadHandlerV2(w http.ResponseWriter, r *http.Request)
 	// --- File Saving ---
 	// Normalize incoming filenames: convert Windows backslashes to forward
 	// slashes so cross-OS directory transfers create correct subdirectories.
+	// The path is reserved, so a file of the same name uploaded in parallel
+	// is numbered instead of overwriting this one.
 	rawFileName := filepath.ToSlash(dto.FileName)
-	destinationPath := storage.ResolveDuplicateFilename(h.config.DownloadDir, rawFileName)
+	destinationPath := h.receiveService.ReserveDestination(reqSessionId, reqFileId, h.config.DownloadDir, h.storedName(rawFileName))
 
 	// Path traversal prevention: ensure the resolved path is still within DownloadDir
 	cleanPath := filepath.Clean(destinationPath)
@@ -81,37 +133,49 @@ func (h *ReceiveHandler) UploadHandlerV2(w http.ResponseWriter, r *http.Request)
 
 	h.logger.Infof("Starting save for file: %s (ID: %s) to %s", dto.FileName, reqFileId, destinationPath)
 
-	var trackProgress func(int64)
+	var trackBar func(int64)
 	progress := h.receiveService.GetSessionProgress(reqSessionId)
-	if !h.config.Quiet && progress != nil {
+	if (!h.config.Quiet || cli.JSONProgressEnabled()) && progress != nil {
 		displayName := dto.FileName
-		if dto.Preview != nil && *dto.Preview != "" {
+		if dto.Preview != nil && *dto.Preview != "" && !metadata.IsImagePreview(*dto.Preview) {
 			preview := *dto.Preview
 			if len(preview) > 20 {
 				preview = preview[:20] + "…"
 			}
 			displayName = preview
 		}
-		trackProgress = progress.AddBar(displayName, dto.Size)
-	}
-
-	// --- Progress Callback ---
-	onProgress := func(bytesWritten int64) {
-		if trackProgress != nil {
-			trackProgress(bytesWritten)
-		}
+		trackBar = progress.AddBar(displayName, dto.Size)
 	}
+	onProgress := h.receiveService.TrackFile(reqSessionId, reqFileId, trackBar)
 
 	// --- Body Size Limit ---
-	// Cap body to the declared file size to prevent disk DoS.
+	// The body must be exactly the size declared in prepare-upload: a longer
+	// one could fill the disk, a shorter one would save a truncated file.
 	if dto.Size < 0 {
 		h.receiveService.FailFile(reqSessionId, reqFileId)
 		httputil.RespondError(w, http.StatusBadRequest, "Invalid file size")
 		return
 	}
-	bodyReader := io.LimitReader(r.Body, dto.Size)
-	bodyReader = &shutdownAwareReader{Reader: bodyReader, ctx: h.shutdownCtx}
+	// A compressed body's length says nothing of the file's; the decoded
+	// bytes are held to the declared size instead.
+	decoded, _ := compression.NewReader(r.Body, encoding)
+	if compression.Identity(encoding) && r.ContentLength >= 0 && r.ContentLength != dto.Size {
+		h.logger.Warnf("Rejected upload of %s: body is %d bytes, declared %d", dto.FileName, r.ContentLength, dto.Size)
+		h.receiveService.FailFile(reqSessionId, reqFileId)
+		httputil.RespondError(w, http.StatusBadRequest, "Body size does not match declared file size")
+		return
+	}
+	// The body is read until the server shuts down or the session ends: a
+	// cancelled session stops its uploads, and their partial files are
+	// removed.
+	sessionCtx := h.receiveService.SessionContext(reqSessionId)
+	uploadCtx, stopUpload := context.WithCancel(h.shutdownCtx)
+	defer stopUpload()
+	defer context.AfterFunc(sessionCtx, stopUpload)()
+	var bodyReader io.Reader = &exactSizeReader{r: decoded, remaining: dto.Size}
+	bodyReader = &shutdownAwareReader{Reader: bodyReader, ctx: uploadCtx}
 	defer r.Body.Close()
+	defer decoded.Close()
 
 	var modified, accessed *string
 	if dto.Metadata != nil {
@@ -125,8 +189,19 @@ func (h *ReceiveHandler) UploadHandlerV2(w http.ResponseWriter, r *http.Request)
 		textBytes, readErr := io.ReadAll(limited)
 
 		if readErr != nil {
+			if h.sessionCancelled(w, sessionCtx, dto.FileName) {
+				return
+			}
 			h.logger.Errorf("Error reading text body for clipboard (file %s): %v", dto.FileName, readErr)
 			h.receiveService.FailFile(reqSessionId, reqFileId)
+			if errors.Is(readErr, errSizeMismatch) {
+				httputil.RespondError(w, http.StatusBadRequest, "Body size does not match declared file size")
+				return
+			}
+			if errors.Is(readErr, compression.ErrCorrupt) {
+				httputil.RespondError(w, http.StatusBadRequest, "Corrupt compressed body")
+				return
+			}
 			httputil.RespondError(w, http.StatusInternalServerError, "Failed to read text content")
 			return
 		}
@@ -142,7 +217,7 @@ func (h *ReceiveHandler) UploadHandlerV2(w http.ResponseWriter, r *http.Request)
 			}
 			h.logger.Infof("Copied text to clipboard from %s: %q", dto.FileName, preview)
 			onProgress(dto.Size)
-			h.receiveService.CompleteFile(reqSessionId, reqFileId)
+			h.receiveService.CompleteFile(reqSessionId, reqFileId, "<clipboard>")
 			h.logTransfer(sender.Alias, sender.IP, rawFileName, "<clipboard>", int64(len(textBytes)), dto.FileType, history.StatusClipboard)
 			h.runExecHook("<clipboard>", rawFileName, sender.Alias, sender.IP, int64(len(textBytes)))
 			w.WriteHeader(http.StatusOK)
@@ -152,71 +227,126 @@ func (h *ReceiveHandler) UploadHandlerV2(w http.ResponseWriter, r *http.Request)
 		}
 
 		// Fall-back: save the full stream as a file.
-		if err := h.saveTextAsFileTo(sender, reqSessionId, reqFileId, rawFileName, bodyReader, textBytes, modified, accessed, onProgress); err != nil {
+		savedPath, err := h.saveTextAsFileTo(sender, rawFileName, destinationPath, bodyReader, textBytes, modified, accessed, onProgress)
+		if err != nil {
+			cli.EmitEvent(cli.ProgressEvent{Event: cli.EventFileFailed, Direction: "receive", SessionID: reqSessionId, File: dto.FileName, Error: err.Error()})
 			h.receiveService.FailFile(reqSessionId, reqFileId)
-			if strings.Contains(err.Error(), "invalid filename") {
-				httputil.RespondError(w, http.StatusBadRequest, "Invalid filename")
+			if h.quarantined(w, err, sender, rawFileName, int64(len(textBytes)), dto.FileType) {
+				return
+			}
+			if h.sessionCancelled(w, sessionCtx, dto.FileName) {
+				return
+			}
+			if errors.Is(err, errSizeMismatch) {
+				httputil.RespondError(w, http.StatusBadRequest, "Body size does not match declared file size")
+				return
+			}
+			if errors.Is(err, compression.ErrCorrupt) {
+				httputil.RespondError(w, http.StatusBadRequest, "Corrupt compressed body")
 				return
 			}
 			httputil.RespondError(w, http.StatusInternalServerError, "Failed to save file")
 			return
 		}
-		h.receiveService.CompleteFile(reqSessionId, reqFileId)
+		h.receiveService.CompleteFile(reqSessionId, reqFileId, savedPath)
 		w.WriteHeader(http.StatusOK)
 		return
 	}
 
 	// --- Binary File Save ---
-	err = storage.SaveStreamToFileWithMetadata(bodyReader, destinationPath, dto.Size, modified, accessed, dto.SHA256, onProgress, h.logger)
+	opts := h.saveOptions()
+	sum := h.dedupe(opts)
+	err = h.storage.Save(bodyReader, destinationPath, dto.Size, modified, accessed, dto.SHA256, opts, onProgress, h.logger)
 	if err != nil {
-		h.logger.Errorf("Error saving file %s (ID: %s): %v", dto.FileName, reqFileId, err)
+		cli.EmitEvent(cli.ProgressEvent{Event: cli.EventFileFailed, Direction: "receive", SessionID: reqSessionId, File: dto.FileName, Error: err.Error()})
 		h.receiveService.FailFile(reqSessionId, reqFileId)
+		if h.quarantined(w, err, sender, rawFileName, dto.Size, dto.FileType) {
+			return
+		}
+		h.logger.Errorf("Error saving file %s (ID: %s): %v", dto.FileName, reqFileId, err)
 		h.logTransfer(sender.Alias, sender.IP, rawFileName, destinationPath, dto.Size, dto.FileType, history.StatusFailed)
+		if h.sessionCancelled(w, sessionCtx, dto.FileName) {
+			return
+		}
+		if errors.Is(err, errSizeMismatch) {
+			httputil.RespondError(w, http.StatusBadRequest, "Body size does not match declared file size")
+			return
+		}
+		if errors.Is(err, compression.ErrCorrupt) {
+			httputil.RespondError(w, http.StatusBadRequest, "Corrupt compressed body")
+			return
+		}
+		if errors.Is(err, storage.ErrInsufficientSpace) {
+			httputil.RespondError(w, http.StatusInsufficientStorage, "Insufficient storage space on receiver")
+			return
+		}
 		httputil.RespondError(w, http.StatusInternalServerError, "Failed to save file")
 		return
 	}
 
 	// --- Success ---
 	h.logger.Infof("Finished saving file: %s (ID: %s)", dto.FileName, reqFileId)
-	h.receiveService.CompleteFile(reqSessionId, reqFileId)
-	h.logTransfer(sender.Alias, sender.IP, rawFileName, destinationPath, dto.Size, dto.FileType, history.StatusReceived)
+	h.receiveService.CompleteFile(reqSessionId, reqFileId, destinationPath)
+	h.logEntry(history.Entry{
+		SenderAlias: sender.Alias,
+		SenderIP:    sender.IP,
+		FileName:    rawFileName,
+		FilePath:    destinationPath,
+		FileSize:    dto.Size,
+		FileType:    dto.FileType,
+		Status:      history.StatusReceived,
+		SHA256:      *sum,
+	})
 	h.runExecHook(destinationPath, rawFileName, sender.Alias, sender.IP, dto.Size)
+	h.openReceived(destinationPath)
 	w.WriteHeader(http.StatusOK)
 }
 
-// saveTextAsFileTo saves text content as a file when clipboard is unavailable or text is too large.
-// Returns nil on success; caller writes HTTP status and calls CompleteFile.
-func (h *ReceiveHandler) saveTextAsFileTo(sender model.DeviceInfo, reqSessionId, reqFileId, rawFileName string, bodyReader io.Reader, textBytes []byte, modified, accessed *string, onProgress func(int64)) error {
+// saveTextAsFileTo saves text content to destinationPath, the path reserved
+// for the upload, when clipboard is unavailable or text is too large.
+// Returns the saved path on success; caller writes HTTP status and calls CompleteFile.
+func (h *ReceiveHandler) saveTextAsFileTo(sender model.DeviceInfo, rawFileName, destinationPath string, bodyReader io.Reader, textBytes []byte, modified, accessed *string, onProgress func(int64)) (string, error) {
 	var combinedReader io.Reader
 	if int64(len(textBytes)) > maxTextSize {
 		combinedReader = io.MultiReader(bytes.NewReader(textBytes), bodyReader)
 	} else {
 		combinedReader = bytes.NewReader(textBytes)
 	}
-	destinationPath := storage.ResolveDuplicateFilename(h.config.DownloadDir, rawFileName)
-	cleanPath := filepath.Clean(destinationPath)
-	if !strings.HasPrefix(cleanPath, filepath.Clean(h.config.DownloadDir)+string(filepath.Separator)) &&
-		cleanPath != filepath.Clean(h.config.DownloadDir) {
-		h.logger.Errorf("Path traversal attempt detected in text fallback: %s", rawFileName)
-		return fmt.Errorf("invalid filename")
-	}
-	savErr := storage.SaveStreamToFileWithMetadata(
-		combinedReader, destinationPath, int64(len(textBytes)), modified, accessed, nil, onProgress, h.logger,
+	savErr := h.storage.Save(
+		combinedReader, destinationPath, int64(len(textBytes)), modified, accessed, nil, h.saveOptions(), onProgress, h.logger,
 	)
+	var scanErr *storage.ScanError
+	if errors.As(savErr, &scanErr) {
+		return "", savErr
+	}
 	if savErr != nil {
 		h.logger.Errorf("Error saving text file %s: %v", rawFileName, savErr)
 		h.logTransfer(sender.Alias, sender.IP, rawFileName, destinationPath, int64(len(textBytes)), "text/plain", history.StatusFailed)
-		return fmt.Errorf("failed to save file: %w", savErr)
+		return "", fmt.Errorf("failed to save file: %w", savErr)
 	}
 	h.logger.Infof("Saved text as file: %s", destinationPath)
 	h.logTransfer(sender.Alias, sender.IP, rawFileName, destinationPath, int64(len(textBytes)), "text/plain", history.StatusReceived)
 	h.runExecHook(destinationPath, rawFileName, sender.Alias, sender.IP, int64(len(textBytes)))
-	return nil
+	h.openReceived(destinationPath)
+	return destinationPath, nil
+}
+
+// sessionCancelled answers an upload that failed because its session ended
+// while the body was being read, and reports whether it did so. The sender
+// is told with 409, as for any upload to a session that is gone.
+func (h *ReceiveHandler) sessionCancelled(w http.ResponseWriter, sessionCtx context.Context, fileName string) bool {
+	if sessionCtx.Err() == nil {
+		return false
+	}
+	h.logger.Infof("Upload of %s stopped: session cancelled", fileName)
+	httputil.RespondError(w, http.StatusConflict, "Session cancelled")
+	return true
 }
 
 // shutdownAwareReader aborts Read when the shutdown context is cancelled,
 // allowing in-flight uploads to terminate promptly on Ctrl+C so the server
 // shuts down within the graceful timeout instead of hitting deadline exceeded.
+// Uploads also pass a context that ends with their session.
 type shutdownAwareReader struct {
 	io.Reader
 	ctx context.Context
@@ -228,3 +358,33 @@ func (r *shutdownAwareReader) Read(p []byte) (int, error) {
 	}
 	return r.Reader.Read(p)
 }
+
+// errSizeMismatch means an upload body was longer or shorter than the file
+// size declared in prepare-upload.
+var errSizeMismatch = errors.New("upload body does not match declared size")
+
+// exactSizeReader reads exactly remaining bytes from r, failing with
+// errSizeMismatch if r ends early or has data past that point.
+type exactSizeReader struct {
+	r         io.Reader
+	remaining int64
+}
+
+func (e *exactSizeReader) Read(p []byte) (int, error) {
+	if e.remaining <= 0 {
+		var extra [1]byte
+		if n, _ := e.r.Read(extra[:]); n > 0 {
+			return 0, errSizeMismatch
+		}
+		return 0, io.EOF
+	}
+	if int64(len(p)) > e.remaining {
+		p = p[:e.remaining]
+	}
+	n, err := e.r.Read(p)
+	e.remaining -= int64(n)
+	if err == io.EOF && e.remaining > 0 {
+		err = errSizeMismatch
+	}
+	return n, err
+}
@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/bethropolis/localgo/pkg/cli"
+	"github.com/bethropolis/localgo/pkg/history"
+	"github.com/bethropolis/localgo/pkg/httputil"
+	"github.com/bethropolis/localgo/pkg/model"
+	"github.com/bethropolis/localgo/pkg/storage"
+)
+
+// scanTimeout bounds how long the scan command may take for one file. A
+// scan that runs out of time fails the file.
+const scanTimeout = 5 * time.Minute
+
+// scanner returns the scanner received files are checked with before they
+// are kept, or nil if no scan command is set.
+func (h *ReceiveHandler) scanner() *storage.Scanner {
+	if h.config.ScanCmd == "" {
+		return nil
+	}
+	dir := h.config.QuarantineDir
+	if dir == "" {
+		dir = filepath.Join(h.config.DownloadDir, ".quarantine")
+	}
+	return &storage.Scanner{Scan: h.scanFile, QuarantineDir: dir}
+}
+
+// scanFile runs the scan command on the file at path. The file passes if
+// the command exits with status 0; anything else, including a command that
+// cannot be run, fails it.
+func (h *ReceiveHandler) scanFile(path string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), scanTimeout)
+	defer cancel()
+	cmd := h.shellCommand(ctx, strings.ReplaceAll(h.config.ScanCmd, "%f", h.scanFileRef()))
+	cmd.Env = append(os.Environ(), "LOCALGO_FILE="+path)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		if out := strings.TrimSpace(string(output)); out != "" {
+			return fmt.Errorf("%w: %s", err, lastLine(out))
+		}
+		return err
+	}
+	h.logger.Debugf("Scan passed for %s, output: %s", path, string(output))
+	return nil
+}
+
+// scanFileRef is what %f stands for in the scan command: a quoted reference
+// to $LOCALGO_FILE rather than the path itself. The path holds the sender's
+// file name, which must never be parsed by the shell.
+func (h *ReceiveHandler) scanFileRef() string {
+	if h.config.Shell == "" && runtime.GOOS == "windows" {
+		return `"%LOCALGO_FILE%"`
+	}
+	return `"$LOCALGO_FILE"`
+}
+
+// quarantined answers an upload whose file failed the scan and reports
+// whether it did so. The failure is recorded in the history and the user
+// is notified.
+func (h *ReceiveHandler) quarantined(w http.ResponseWriter, err error, sender model.DeviceInfo, fileName string, size int64, fileType string) bool {
+	var scanErr *storage.ScanError
+	if !errors.As(err, &scanErr) {
+		return false
+	}
+	where := "removed"
+	if scanErr.Path != "" {
+		where = "moved to " + scanErr.Path
+	}
+	h.logger.Warnf("File %s from %s failed the scan (%v); %s", fileName, sender.Alias, scanErr.Err, where)
+	h.logTransfer(sender.Alias, sender.IP, fileName, scanErr.Path, size, fileType, history.StatusQuarantined)
+	cli.Notify("LocalGo: File Quarantined", fmt.Sprintf("%s from %s failed the scan and was %s", fileName, sender.Alias, where))
+	httputil.RespondError(w, http.StatusUnprocessableEntity, "File failed the receiver's scan")
+	return true
+}
+
+// lastLine returns the last line of s, where scanners print their verdict.
+func lastLine(s string) string {
+	if i := strings.LastIndexByte(s, '\n'); i >= 0 {
+		return strings.TrimSpace(s[i+1:])
+	}
+	return s
+}
@@ -0,0 +1,111 @@
+package server
+
+import (
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/bethropolis/localgo/pkg/httputil"
+)
+
+const (
+	// maxHeaderBytes caps request line plus headers. LocalSend requests carry
+	// a handful of short headers and query parameters.
+	maxHeaderBytes = 16 * 1024
+
+	// maxControlBodySize caps JSON bodies on control endpoints; a
+	// prepare-upload listing thousands of files still fits.
+	maxControlBodySize = 1024 * 1024
+
+	// rateLimitIdle is how long a client's bucket is kept after its last request.
+	rateLimitIdle = time.Minute
+)
+
+// withBodyLimit rejects request bodies larger than n bytes. Reads past the
+// limit fail with *http.MaxBytesError, which handlers report as 413.
+func withBodyLimit(n int64, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ContentLength > n {
+			httputil.RespondError(w, http.StatusRequestEntityTooLarge, "Request body too large")
+			return
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, n)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// rateLimiter is a per-IP token bucket: each client may burst up to twice the
+// rate, then is held to rate requests per second.
+type rateLimiter struct {
+	rate      float64
+	burst     float64
+	mu        sync.Mutex
+	clients   map[string]*tokenBucket
+	lastSweep time.Time
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// newRateLimiter returns a limiter allowing perSecond requests per second per
+// IP, or nil (no limit) if perSecond is 0.
+func newRateLimiter(perSecond int) *rateLimiter {
+	if perSecond <= 0 {
+		return nil
+	}
+	return &rateLimiter{
+		rate:    float64(perSecond),
+		burst:   float64(2 * perSecond),
+		clients: make(map[string]*tokenBucket),
+	}
+}
+
+// allow takes a token from ip's bucket, reporting false if it is empty.
+func (l *rateLimiter) allow(ip string, now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Sub(l.lastSweep) > rateLimitIdle {
+		for k, b := range l.clients {
+			if now.Sub(b.last) > rateLimitIdle {
+				delete(l.clients, k)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	b, ok := l.clients[ip]
+	if !ok {
+		b = &tokenBucket{tokens: l.burst, last: now}
+		l.clients[ip] = b
+	}
+	b.tokens = min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// limit wraps next with the rate limit. A nil limiter passes every request.
+func (l *rateLimiter) limit(next http.Handler) http.Handler {
+	if l == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ip, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			ip = r.RemoteAddr
+		}
+		if !l.allow(ip, time.Now()) {
+			w.Header().Set("Retry-After", "1")
+			httputil.RespondError(w, http.StatusTooManyRequests, "Too many requests")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,92 @@
+package server
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRateLimiter(t *testing.T) {
+	l := newRateLimiter(2) // burst of 4
+	now := time.Now()
+
+	for i := 0; i < 4; i++ {
+		if !l.allow("10.0.0.1", now) {
+			t.Fatalf("request %d within the burst was limited", i+1)
+		}
+	}
+	if l.allow("10.0.0.1", now) {
+		t.Error("request past the burst was allowed")
+	}
+	if !l.allow("10.0.0.2", now) {
+		t.Error("another IP shares the first IP's bucket")
+	}
+	if !l.allow("10.0.0.1", now.Add(500*time.Millisecond)) {
+		t.Error("bucket did not refill at the configured rate")
+	}
+	if l.allow("10.0.0.1", now.Add(500*time.Millisecond)) {
+		t.Error("bucket refilled faster than the configured rate")
+	}
+
+	// Idle buckets are dropped on the next sweep.
+	l.allow("10.0.0.3", now.Add(2*rateLimitIdle))
+	if _, ok := l.clients["10.0.0.1"]; ok {
+		t.Error("idle bucket was not swept")
+	}
+}
+
+func TestRateLimiter_Limit(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	if newRateLimiter(0) != nil {
+		t.Fatal("expected a rate of 0 to disable limiting")
+	}
+
+	h := newRateLimiter(1).limit(ok)
+	codes := make([]int, 3)
+	for i := range codes {
+		req := httptest.NewRequest(http.MethodGet, "/api/localsend/v2/info", nil)
+		req.RemoteAddr = "192.168.1.50:4000"
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+		codes[i] = rr.Code
+	}
+	if codes[0] != http.StatusOK || codes[1] != http.StatusOK || codes[2] != http.StatusTooManyRequests {
+		t.Errorf("got status codes %v, want [200 200 429]", codes)
+	}
+}
+
+func TestWithBodyLimit(t *testing.T) {
+	var readErr error
+	h := withBodyLimit(8, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, readErr = io.ReadAll(r.Body)
+	}))
+
+	tests := []struct {
+		name     string
+		body     io.Reader
+		wantCode int
+		wantErr  bool
+	}{
+		{"within limit", strings.NewReader("12345678"), http.StatusOK, false},
+		{"declared too large", strings.NewReader("123456789"), http.StatusRequestEntityTooLarge, false},
+		{"chunked too large", io.MultiReader(strings.NewReader("123456789")), http.StatusOK, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			readErr = nil
+			req := httptest.NewRequest(http.MethodPost, "/", tt.body)
+			rr := httptest.NewRecorder()
+			h.ServeHTTP(rr, req)
+			if rr.Code != tt.wantCode {
+				t.Errorf("got status %d, want %d", rr.Code, tt.wantCode)
+			}
+			if (readErr != nil) != tt.wantErr {
+				t.Errorf("read error = %v, want error: %v", readErr, tt.wantErr)
+			}
+		})
+	}
+}
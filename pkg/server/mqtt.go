@@ -0,0 +1,127 @@
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/bethropolis/localgo/pkg/cli"
+	"github.com/bethropolis/localgo/pkg/config"
+	"github.com/bethropolis/localgo/pkg/server/services"
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/google/uuid"
+)
+
+// mqttStatusTopic, under the topic prefix, holds "online" while the server
+// runs and "offline" once it stops or loses the broker, for Home Assistant
+// availability.
+const mqttStatusTopic = "status"
+
+// mqttWait bounds how long publishing the final status may hold up
+// shutdown.
+const mqttWait = 2 * time.Second
+
+// mqttOptions returns the options of the client that publishes to
+// config.MQTTBroker, failing if the settings are wrong.
+func (s *Server) mqttOptions() (*mqtt.ClientOptions, error) {
+	broker, err := config.ParseMQTTBroker(s.config.MQTTBroker)
+	if err != nil {
+		return nil, err
+	}
+	status := s.mqttTopic() + "/" + mqttStatusTopic
+	opts := mqtt.NewClientOptions().
+		AddBroker(broker.String()).
+		SetClientID("localgo-"+uuid.NewString()[:8]).
+		SetUsername(s.config.MQTTUsername).
+		SetPassword(s.config.MQTTPassword).
+		SetConnectRetry(true).
+		SetAutoReconnect(true).
+		SetWill(status, "offline", 1, true).
+		SetOnConnectHandler(func(c mqtt.Client) {
+			s.logger.Infof("Connected to MQTT broker %s", broker.Redacted())
+			c.Publish(status, 1, true, "online")
+		}).
+		SetConnectionLostHandler(func(_ mqtt.Client, err error) {
+			s.logger.Warnf("Lost the MQTT broker, reconnecting: %v", err)
+		})
+	if s.config.MQTTCAFile != "" {
+		pem, err := os.ReadFile(s.config.MQTTCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read MQTT CA file: %w", err)
+		}
+		roots := x509.NewCertPool()
+		if !roots.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in MQTT CA file %s", s.config.MQTTCAFile)
+		}
+		opts.SetTLSConfig(&tls.Config{RootCAs: roots, MinVersion: tls.VersionTLS12})
+	}
+	return opts, nil
+}
+
+// startMQTT connects with opts and publishes server events until the server
+// shuts down: each event as JSON to the topic prefix followed by its type,
+// such as localgo/file_completed. Progress events are left out. A broker
+// that cannot be reached is retried in the background. It returns a
+// function that publishes "offline" and disconnects.
+func (s *Server) startMQTT(opts *mqtt.ClientOptions) func() {
+	prefix := s.mqttTopic()
+	status := prefix + "/" + mqttStatusTopic
+	client := mqtt.NewClient(opts)
+	client.Connect()
+
+	events, unsubscribe := s.events.Subscribe()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			select {
+			case <-s.shutdownCtx.Done():
+				return
+			case e, ok := <-events:
+				if !ok {
+					return
+				}
+				if e.Type == cli.EventFileProgress {
+					continue
+				}
+				s.publishMQTT(client, prefix+"/"+e.Type, e)
+			}
+		}
+	}()
+	return func() {
+		unsubscribe()
+		<-done
+		// Disconnect waits out its quiesce time even without a broker.
+		var quiesce time.Duration
+		if client.IsConnectionOpen() {
+			client.Publish(status, 1, true, "offline").WaitTimeout(mqttWait)
+			quiesce = 250 * time.Millisecond
+		}
+		client.Disconnect(uint(quiesce / time.Millisecond))
+	}
+}
+
+func (s *Server) mqttTopic() string {
+	if s.config.MQTTTopic == "" {
+		return config.DefaultMQTTTopic
+	}
+	return s.config.MQTTTopic
+}
+
+// publishMQTT publishes e to topic without waiting for the broker, which
+// may be away; messages published meanwhile are sent once it is back.
+func (s *Server) publishMQTT(client mqtt.Client, topic string, e services.Event) {
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+	token := client.Publish(topic, 1, false, payload)
+	go func() {
+		if token.Wait(); token.Error() != nil {
+			s.logger.Debugf("Failed to publish %s to MQTT: %v", topic, token.Error())
+		}
+	}()
+}
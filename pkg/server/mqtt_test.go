@@ -0,0 +1,210 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/bethropolis/localgo/pkg/cli"
+	"github.com/bethropolis/localgo/pkg/config"
+	"github.com/bethropolis/localgo/pkg/crypto"
+	"github.com/bethropolis/localgo/pkg/history"
+	"github.com/bethropolis/localgo/pkg/server/services"
+	"github.com/eclipse/paho.mqtt.golang/packets"
+	"go.uber.org/zap"
+)
+
+// fakeBroker accepts one MQTT client and passes on what it publishes.
+func fakeBroker(t *testing.T) (addr string, connects <-chan *packets.ConnectPacket, published <-chan *packets.PublishPacket) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	connCh := make(chan *packets.ConnectPacket, 1)
+	pubCh := make(chan *packets.PublishPacket, 16)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			p, err := packets.ReadPacket(conn)
+			if err != nil {
+				return
+			}
+			switch p := p.(type) {
+			case *packets.ConnectPacket:
+				connCh <- p
+				packets.NewControlPacket(packets.Connack).Write(conn)
+			case *packets.PublishPacket:
+				pubCh <- p
+				if p.Qos == 1 {
+					ack := packets.NewControlPacket(packets.Puback).(*packets.PubackPacket)
+					ack.MessageID = p.MessageID
+					ack.Write(conn)
+				}
+			case *packets.PingreqPacket:
+				packets.NewControlPacket(packets.Pingresp).Write(conn)
+			case *packets.DisconnectPacket:
+				return
+			}
+		}
+	}()
+	return ln.Addr().String(), connCh, pubCh
+}
+
+func nextPublish(t *testing.T, ch <-chan *packets.PublishPacket) *packets.PublishPacket {
+	t.Helper()
+	select {
+	case p := <-ch:
+		return p
+	case <-time.After(5 * time.Second):
+		t.Fatal("nothing published")
+		return nil
+	}
+}
+
+func TestStart_MQTT(t *testing.T) {
+	addr, connects, published := fakeBroker(t)
+	cfg := &config.Config{
+		Alias:           "Test",
+		Port:            0,
+		HistoryFile:     history.DisabledSentinel,
+		DownloadDir:     t.TempDir(),
+		SecurityContext: &crypto.StoredSecurityContext{},
+		MQTTBroker:      "tcp://" + addr,
+		MQTTTopic:       "home/nas",
+		MQTTUsername:    "localgo",
+		MQTTPassword:    "secret",
+	}
+	srv := NewServer(cfg, zap.NewNop().Sugar())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ready := make(chan struct{}, 1)
+	errCh := make(chan error, 1)
+	go func() { errCh <- srv.Start(ctx, ready) }()
+	select {
+	case <-ready:
+	case err := <-errCh:
+		t.Fatalf("server failed to start: %v", err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("server did not become ready")
+	}
+
+	select {
+	case c := <-connects:
+		if c.Username != "localgo" || string(c.Password) != "secret" || c.WillTopic != "home/nas/status" || string(c.WillMessage) != "offline" || !c.WillRetain {
+			t.Errorf("unexpected connect: %v", c)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("server did not connect to the broker")
+	}
+	if p := nextPublish(t, published); p.TopicName != "home/nas/status" || string(p.Payload) != "online" || !p.Retain {
+		t.Errorf("first publish %s %q, want retained online status", p.TopicName, p.Payload)
+	}
+
+	// Progress is left out; other events are published as JSON.
+	srv.events.Publish(services.Event{Type: cli.EventFileProgress, SessionID: "s1", Bytes: 1})
+	srv.events.Publish(services.Event{Type: cli.EventFileCompleted, SessionID: "s1", File: "a.jpg", Path: "/photos/a.jpg"})
+	p := nextPublish(t, published)
+	var e services.Event
+	if err := json.Unmarshal(p.Payload, &e); err != nil {
+		t.Fatal(err)
+	}
+	if p.TopicName != "home/nas/file_completed" || p.Retain || e.File != "a.jpg" || e.Path != "/photos/a.jpg" {
+		t.Errorf("published %s %s, want the completed file", p.TopicName, p.Payload)
+	}
+
+	cancel()
+	if err := <-errCh; err != nil {
+		t.Errorf("server shutdown failed: %v", err)
+	}
+	if p := nextPublish(t, published); p.TopicName != "home/nas/status" || string(p.Payload) != "offline" || !p.Retain {
+		t.Errorf("last publish %s %q, want retained offline status", p.TopicName, p.Payload)
+	}
+}
+
+func TestStartMQTT_StopBeforeShutdown(t *testing.T) {
+	addr, _, published := fakeBroker(t)
+	cfg := &config.Config{
+		Alias:           "Test",
+		HistoryFile:     history.DisabledSentinel,
+		DownloadDir:     t.TempDir(),
+		SecurityContext: &crypto.StoredSecurityContext{},
+		MQTTBroker:      "tcp://" + addr,
+	}
+	srv := NewServer(cfg, zap.NewNop().Sugar())
+	opts, err := srv.mqttOptions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stop := srv.startMQTT(opts)
+	nextPublish(t, published) // online
+
+	// Ending the subscription while the server still runs must not publish
+	// empty events from the closed channel.
+	stopped := make(chan struct{})
+	go func() {
+		stop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("stop did not return")
+	}
+	if p := nextPublish(t, published); p.TopicName != "localgo/status" || string(p.Payload) != "offline" {
+		t.Errorf("published %s %q after stop, want the offline status", p.TopicName, p.Payload)
+	}
+}
+
+func TestStart_MQTTBrokerAway(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+	cfg := &config.Config{
+		Alias:           "Test",
+		Port:            0,
+		HistoryFile:     history.DisabledSentinel,
+		DownloadDir:     t.TempDir(),
+		SecurityContext: &crypto.StoredSecurityContext{},
+		MQTTBroker:      fmt.Sprintf("tcp://%s", addr),
+	}
+	srv := NewServer(cfg, zap.NewNop().Sugar())
+
+	// The server runs, and stops, without the broker.
+	ctx, cancel := context.WithCancel(context.Background())
+	ready := make(chan struct{}, 1)
+	errCh := make(chan error, 1)
+	go func() { errCh <- srv.Start(ctx, ready) }()
+	select {
+	case <-ready:
+	case err := <-errCh:
+		t.Fatalf("server failed to start: %v", err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("server did not become ready")
+	}
+	cancel()
+	select {
+	case err := <-errCh:
+		if err != nil {
+			t.Errorf("server shutdown failed: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("shutdown held up by the missing broker")
+	}
+
+	cfg.MQTTBroker = "http://" + addr
+	if err := NewServer(cfg, zap.NewNop().Sugar()).Start(context.Background(), nil); err == nil {
+		t.Error("started with an http:// broker")
+	}
+}
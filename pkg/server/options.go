@@ -0,0 +1,62 @@
+package server
+
+import (
+	"net"
+
+	"github.com/bethropolis/localgo/pkg/server/services"
+	"github.com/bethropolis/localgo/pkg/storage"
+	"github.com/bethropolis/localgo/pkg/transfer"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+)
+
+// Option configures a Server built by NewServer.
+type Option func(*Server)
+
+// WithReceiveService makes the server track incoming sessions in rs, such
+// as one shared with other components. Events and the upload limit are
+// still wired to it by NewServer.
+func WithReceiveService(rs *services.ReceiveService) Option {
+	return func(s *Server) {
+		s.receiveService = rs
+	}
+}
+
+// WithStorageBackend makes the server save received files to b instead of
+// the local disk.
+func WithStorageBackend(b storage.Backend) Option {
+	return func(s *Server) {
+		s.storage = b
+	}
+}
+
+// WithLogger replaces the logger passed to NewServer.
+func WithLogger(logger *zap.SugaredLogger) Option {
+	return func(s *Server) {
+		s.logger = logger
+	}
+}
+
+// WithMiddleware adds middleware around every route of the HTTP/S API, as
+// Use does.
+func WithMiddleware(mw ...mux.MiddlewareFunc) Option {
+	return func(s *Server) {
+		_ = s.Use(mw...)
+	}
+}
+
+// WithTransferManager makes the server run its sends and receives through
+// m, such as one the embedding program starts its own transfers with.
+func WithTransferManager(m *transfer.Manager) Option {
+	return func(s *Server) {
+		s.transfers = m
+	}
+}
+
+// WithListener makes Start serve on ln instead of binding the configured
+// port, as SetListener does.
+func WithListener(ln net.Listener) Option {
+	return func(s *Server) {
+		s.listener = ln
+	}
+}
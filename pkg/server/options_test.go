@@ -0,0 +1,190 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bethropolis/localgo/pkg/config"
+	"github.com/bethropolis/localgo/pkg/crypto"
+	"github.com/bethropolis/localgo/pkg/history"
+	"github.com/bethropolis/localgo/pkg/model"
+	"github.com/bethropolis/localgo/pkg/server/services"
+	"github.com/bethropolis/localgo/pkg/storage"
+	"github.com/bethropolis/localgo/pkg/transfer"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+)
+
+// memoryBackend keeps saved files in memory.
+type memoryBackend struct {
+	mu    sync.Mutex
+	files map[string][]byte
+}
+
+func (m *memoryBackend) Save(stream io.Reader, filePath string, _ int64, _, _, _ *string, _ *storage.SaveOptions, _ func(int64), _ *zap.SugaredLogger) error {
+	data, err := io.ReadAll(stream)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.files[filePath] = data
+	return nil
+}
+
+func (m *memoryBackend) FreeSpace(string) (uint64, error) {
+	return 1 << 40, nil
+}
+
+func TestNewServer_Options(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	cfg := &config.Config{
+		Alias:           "Test",
+		AutoAccept:      true,
+		HistoryFile:     history.DisabledSentinel,
+		SessionFile:     history.DisabledSentinel,
+		DownloadDir:     t.TempDir(),
+		SecurityContext: &crypto.StoredSecurityContext{},
+	}
+	backend := &memoryBackend{files: make(map[string][]byte)}
+	receive := services.NewReceiveService()
+	tagged := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Embedder", "yes")
+			next.ServeHTTP(w, r)
+		})
+	}
+	srv := NewServer(cfg, nil,
+		WithLogger(zap.NewNop().Sugar()),
+		WithListener(ln),
+		WithReceiveService(receive),
+		WithStorageBackend(backend),
+		WithMiddleware(tagged),
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ready := make(chan struct{}, 1)
+	errCh := make(chan error, 1)
+	go func() { errCh <- srv.Start(ctx, ready) }()
+	defer func() {
+		cancel()
+		<-errCh
+	}()
+	select {
+	case <-ready:
+	case err := <-errCh:
+		t.Fatalf("server failed to start: %v", err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("server did not become ready")
+	}
+	base := fmt.Sprintf("http://%s/api/localsend/v2", ln.Addr())
+
+	body, _ := json.Marshal(model.PrepareUploadRequestDto{
+		Info:  model.InfoDto{Alias: "Sender", Fingerprint: "sender-fp"},
+		Files: map[string]model.FileDto{"f1": {ID: "f1", FileName: "hello.bin", Size: 5, FileType: "application/octet-stream"}},
+	})
+	resp, err := http.Post(base+"/prepare-upload", "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("prepare-upload: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("prepare-upload status = %d", resp.StatusCode)
+	}
+	if resp.Header.Get("X-Embedder") != "yes" {
+		t.Error("middleware not applied")
+	}
+	var prepared model.PrepareUploadResponseDto
+	if err := json.NewDecoder(resp.Body).Decode(&prepared); err != nil {
+		t.Fatalf("decode prepare-upload response: %v", err)
+	}
+	if receive.GetSessionByID(prepared.SessionID) == nil {
+		t.Error("session not tracked by the injected receive service")
+	}
+
+	url := fmt.Sprintf("%s/upload?sessionId=%s&fileId=f1&token=%s", base, prepared.SessionID, prepared.Files["f1"])
+	up, err := http.Post(url, "application/octet-stream", strings.NewReader("hello"))
+	if err != nil {
+		t.Fatalf("upload: %v", err)
+	}
+	up.Body.Close()
+	if up.StatusCode != http.StatusOK {
+		t.Fatalf("upload status = %d", up.StatusCode)
+	}
+	// The session is tracked as a receive by the server's transfer manager.
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		list := srv.Transfers().List()
+		if len(list) == 1 && list[0].Direction == transfer.Receive && list[0].Status == transfer.StatusDone {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("transfers = %+v, want one finished receive", list)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	backend.mu.Lock()
+	defer backend.mu.Unlock()
+	if len(backend.files) != 1 {
+		t.Fatalf("backend has %d files, want 1", len(backend.files))
+	}
+	for path, data := range backend.files {
+		if !strings.HasSuffix(path, "hello.bin") || string(data) != "hello" {
+			t.Errorf("backend saved %q = %q", path, data)
+		}
+	}
+}
+
+func TestServer_Use(t *testing.T) {
+	cfg := &config.Config{
+		Alias:           "Test",
+		HistoryFile:     history.DisabledSentinel,
+		SessionFile:     history.DisabledSentinel,
+		DownloadDir:     t.TempDir(),
+		SecurityContext: &crypto.StoredSecurityContext{},
+	}
+	var order []string
+	mark := func(name string) mux.MiddlewareFunc {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				order = append(order, name)
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+	srv := NewServer(cfg, zap.NewNop().Sugar(), WithMiddleware(mark("option")))
+	if err := srv.Use(mark("first"), mark("second")); err != nil {
+		t.Fatalf("Use before Start: %v", err)
+	}
+	srv.configureRoutes()
+	if err := srv.Use(mark("late")); !errors.Is(err, ErrRoutesConfigured) {
+		t.Errorf("Use after routes are configured = %v, want ErrRoutesConfigured", err)
+	}
+
+	rec := httptest.NewRecorder()
+	srv.muxRouter.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/localsend/v2/info", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("info status = %d", rec.Code)
+	}
+	if got := strings.Join(order, ","); got != "option,first,second" {
+		t.Errorf("middleware ran as %s, want option,first,second", got)
+	}
+	if rec.Header().Get("X-Frame-Options") != "DENY" {
+		t.Error("security headers missing")
+	}
+}
@@ -0,0 +1,54 @@
+package server
+
+import (
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/bethropolis/localgo/pkg/config"
+	"github.com/bethropolis/localgo/pkg/httputil"
+	"github.com/bethropolis/localgo/pkg/relay"
+)
+
+// relayClient returns the client that registers with config.Relay, failing
+// if the settings are wrong.
+func (s *Server) relayClient() (*relay.Client, error) {
+	u, err := config.ParseRelay(s.config.Relay)
+	if err != nil {
+		return nil, err
+	}
+	if s.config.RelayFingerprint == "" {
+		s.logger.Warnf("relay_fingerprint is not set: any server at %s is trusted as the relay", u.Host)
+	}
+	cert, err := s.certificate()
+	if err != nil {
+		return nil, err
+	}
+	return relay.NewClient(u, s.config.RelayFingerprint, cert, httputil.Transport(), s.logger.Named("relay")), nil
+}
+
+// startRelay registers with the relay in the background until the server
+// shuts down, serving requests forwarded by it with h. Only the LocalSend
+// API is offered there: the admin API stays on this machine.
+//
+// Each request carries the fingerprint the relay verified for its sender,
+// which stands in for the sender's certificate and address: see
+// relay.CallerHeader.
+func (s *Server) startRelay(c *relay.Client, h http.Handler) {
+	api := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(path.Clean(r.URL.Path), "/api/localsend/") {
+			http.NotFound(w, r)
+			return
+		}
+		caller := strings.ToLower(r.Header.Get(relay.CallerHeader))
+		if !config.ValidFingerprint(caller) {
+			httputil.RespondError(w, http.StatusForbidden, "Unknown sender")
+			return
+		}
+		r.Header.Del(relay.CallerHeader)
+		r = httputil.WithVerifiedFingerprint(r, caller)
+		r.RemoteAddr = relay.CallerAddr(caller)
+		h.ServeHTTP(w, r)
+	})
+	go c.Serve(s.shutdownCtx, api)
+}
@@ -9,19 +9,32 @@ import (
 	"encoding/hex"
 	"errors"
 	"fmt"
+	"io"
 	"net"
 	"net/http"
+	"os"
+	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/bethropolis/localgo/pkg/capture"
 	"github.com/bethropolis/localgo/pkg/cli"
 	"github.com/bethropolis/localgo/pkg/config"
 	"github.com/bethropolis/localgo/pkg/history"
 	"github.com/bethropolis/localgo/pkg/httputil"
+	"github.com/bethropolis/localgo/pkg/model"
+	"github.com/bethropolis/localgo/pkg/queue"
+	"github.com/bethropolis/localgo/pkg/relay"
+	"github.com/bethropolis/localgo/pkg/send"
 	"github.com/bethropolis/localgo/pkg/server/handlers"
 	"github.com/bethropolis/localgo/pkg/server/services"
+	"github.com/bethropolis/localgo/pkg/storage"
+	"github.com/bethropolis/localgo/pkg/transfer"
+	mqtt "github.com/eclipse/paho.mqtt.golang"
 	"github.com/gorilla/mux"
 	"go.uber.org/zap"
+	"google.golang.org/grpc"
 )
 
 // Server manages the HTTP/S server lifecycle.
@@ -29,33 +42,131 @@ type Server struct {
 	config          *config.Config
 	httpServer      *http.Server
 	muxRouter       *mux.Router
+	adminServer     *http.Server // serves the admin API on config.AdminSocket
+	adminRouter     *mux.Router
+	controlServer   *grpc.Server // serves the control interface on config.ControlGRPC
+	stopDBus        func()       // takes the D-Bus interface off the bus
+	stopMQTT        func()       // disconnects from config.MQTTBroker
 	receiveService  *services.ReceiveService
 	sendService     *services.SendService
 	registryService *services.RegistryService
+	queue           *queue.Queue
+	transfers       *transfer.Manager
+	ledgerMu        sync.Mutex
+	ledger          *send.Ledger // opened by the first job that skips duplicates
+	events          *services.EventBroker
 	logger          *zap.SugaredLogger
 	historyLog      *history.Logger // closed in Shutdown()
+	accessLogFile   *os.File        // closed in Shutdown()
+	receiveHandler  *handlers.ReceiveHandler
+	storage         storage.Backend // nil saves to disk
+	middlewareMu    sync.Mutex
+	middleware      []mux.MiddlewareFunc // see Use
+	routed          bool                 // routes configured; Use is refused
+	listener        net.Listener         // inherited socket; see SetListener
 	shutdownCtx     context.Context
 	shutdownCancel  context.CancelFunc
 }
 
-// NewServer creates a new Server instance.
-func NewServer(cfg *config.Config, logger *zap.SugaredLogger) *Server {
-	httputil.SetLogger(logger)
-	router := mux.NewRouter()
-	receiveService := services.NewReceiveService()
-	sendService := services.NewSendService()
-	registryService := services.NewRegistryService()
+// NewServer creates a new Server instance. Options replace the components
+// it would otherwise build itself.
+func NewServer(cfg *config.Config, logger *zap.SugaredLogger, opts ...Option) *Server {
+	if logger == nil {
+		logger = zap.NewNop().Sugar()
+	}
 	shutdownCtx, shutdownCancel := context.WithCancel(context.Background())
-	return &Server{
+	s := &Server{
 		config:          cfg,
-		muxRouter:       router,
-		receiveService:  receiveService,
-		sendService:     sendService,
-		registryService: registryService,
+		muxRouter:       mux.NewRouter(),
+		receiveService:  services.NewReceiveService(),
+		sendService:     services.NewSendService(),
+		registryService: services.NewRegistryService(),
+		events:          services.NewEventBroker(),
 		logger:          logger,
 		shutdownCtx:     shutdownCtx,
 		shutdownCancel:  shutdownCancel,
 	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.transfers == nil {
+		s.transfers = transfer.NewManager(cfg.QueueWorkers)
+	}
+
+	httputil.SetLogger(s.logger.Named("httputil"))
+	if cfg.CopyBufferSize > 0 {
+		storage.SetBufferSize(int(cfg.CopyBufferSize))
+	}
+	storage.SetSync(cfg.Fsync)
+	s.receiveService.SetEventBroker(s.events)
+	s.receiveService.SetMaxUploads(cfg.MaxSessionUploads)
+	s.registryService.SetEventBroker(s.events)
+	s.trackReceives()
+	s.queue = queue.New(queue.Options{Workers: cfg.QueueWorkers}, s.sendQueued, s.logger.Named("queue"))
+	return s
+}
+
+// sendQueued sends a job from the send queue. A recipient given by alias or
+// fingerprint is looked up among the devices the server has seen, then by
+// discovery as send does.
+func (s *Server) sendQueued(ctx context.Context, job queue.Job) error {
+	logger := s.logger.Named("queue")
+	var device *model.Device
+	if job.IP != "" {
+		device = &model.Device{Alias: job.IP, IP: job.IP, Port: job.Port}
+		if device.Port == 0 {
+			device.Port = config.DefaultPort
+		}
+	} else {
+		known := s.registryService.GetDevices()
+		for i, d := range known {
+			known[i] = d.Snapshot()
+		}
+		var err error
+		device, err = send.ChooseRecipient(known, job.To, job.Fingerprint)
+		if err != nil {
+			return err
+		}
+		if device == nil {
+			if device, err = send.FindRecipient(ctx, s.config, job.To, job.Fingerprint, job.Port, logger); err != nil {
+				return err
+			}
+		}
+	}
+	opts := []send.SendOption{send.WithExcludes(job.Excludes...), send.WithFileNames(job.Names)}
+	if job.SkipDuplicates {
+		ledger, err := s.sendLedger()
+		if err != nil {
+			return err
+		}
+		opts = append(opts, send.WithLedger(ledger))
+		defer func() {
+			if err := ledger.Save(); err != nil {
+				logger.Warnf("Failed to save send ledger: %v", err)
+			}
+		}()
+	}
+	h := s.transfers.Start(ctx, transfer.Send, device.Alias, func(ctx context.Context, progress func(transfer.Progress)) error {
+		opts := append(opts, send.WithProgress(func(sent, total int64) {
+			progress(transfer.Progress{Bytes: sent, Total: total})
+		}))
+		return send.SendToDevice(ctx, s.config, device, job.Files, logger, opts...)
+	})
+	return h.Wait()
+}
+
+// sendLedger returns the ledger of delivered files shared by queued jobs.
+func (s *Server) sendLedger() (*send.Ledger, error) {
+	s.ledgerMu.Lock()
+	defer s.ledgerMu.Unlock()
+	if s.ledger == nil {
+		ledger, err := send.OpenLedger(send.DefaultLedgerFile(), send.NewHashCache(send.DefaultHashCacheFile(), s.logger))
+		if err != nil {
+			return nil, err
+		}
+		s.ledger = ledger
+	}
+	return s.ledger, nil
 }
 
 // securityMiddleware adds security headers and validates CORS origins.
@@ -87,14 +198,29 @@ func securityMiddleware(next http.Handler) http.Handler {
 // configureRoutes sets up the API routes.
 func (s *Server) configureRoutes() {
 	s.muxRouter.Use(securityMiddleware)
+	s.middlewareMu.Lock()
+	s.muxRouter.Use(s.middleware...)
+	s.routed = true
+	s.middlewareMu.Unlock()
 	apiRouter := s.muxRouter.PathPrefix("/api/localsend").Subrouter()
+	if s.config.StrictProtocol {
+		apiRouter.Use(withStrictProtocol)
+	}
+
+	// Control endpoints are rate limited per IP and take small JSON bodies.
+	// Uploads and downloads are authorized by session tokens and bounded by
+	// the declared file size instead.
+	limiter := newRateLimiter(s.config.RateLimit)
+	control := func(d time.Duration, h http.HandlerFunc) http.Handler {
+		return limiter.limit(withBodyLimit(maxControlBodySize, withDeadline(d, h)))
+	}
 
 	// Discovery Handlers (Phase 1)
-	discoveryHandler := handlers.NewDiscoveryHandler(s.config, s.registryService, s.sendService, s.logger)
-	apiRouter.HandleFunc("/v1/info", discoveryHandler.InfoHandler).Methods("GET")
-	apiRouter.HandleFunc("/v2/info", discoveryHandler.InfoHandler).Methods("GET")
-	apiRouter.HandleFunc("/v1/register", discoveryHandler.RegisterHandler).Methods("POST")
-	apiRouter.HandleFunc("/v2/register", discoveryHandler.RegisterHandler).Methods("POST")
+	discoveryHandler := handlers.NewDiscoveryHandler(s.config, s.registryService, s.sendService, s.logger.Named("handlers"))
+	apiRouter.Handle("/v1/info", control(controlTimeout, discoveryHandler.InfoHandler)).Methods("GET")
+	apiRouter.Handle("/v2/info", control(controlTimeout, discoveryHandler.InfoHandler)).Methods("GET")
+	apiRouter.Handle("/v1/register", control(controlTimeout, discoveryHandler.RegisterHandler)).Methods("POST")
+	apiRouter.Handle("/v2/register", control(controlTimeout, discoveryHandler.RegisterHandler)).Methods("POST")
 
 	// Receive Handlers (Phase 2)
 	path := s.config.HistoryFile
@@ -111,37 +237,141 @@ func (s *Server) configureRoutes() {
 		}
 	}
 
-	receiveHandler := handlers.NewReceiveHandler(s.config, s.receiveService, s.historyLog, s.shutdownCtx, s.logger)
-	apiRouter.HandleFunc("/v1/prepare-upload", receiveHandler.PrepareUploadHandlerV1).Methods("POST")
-	apiRouter.HandleFunc("/v2/prepare-upload", receiveHandler.PrepareUploadHandlerV2).Methods("POST")
-	apiRouter.HandleFunc("/v2/upload", receiveHandler.UploadHandlerV2).Methods("POST")
-	apiRouter.HandleFunc("/v2/cancel", receiveHandler.CancelHandler).Methods("POST")
+	receiveHandler := handlers.NewReceiveHandler(s.config, s.receiveService, s.historyLog, s.shutdownCtx, s.logger.Named("handlers"))
+	if s.storage != nil {
+		receiveHandler.SetStorageBackend(s.storage)
+	}
+	s.receiveHandler = receiveHandler
+	if s.config.Manifest != "" {
+		s.receiveService.AddSummaryHandler(receiveHandler.WriteManifest)
+	}
+	apiRouter.Handle("/v2/prepare-upload", control(promptTimeout, receiveHandler.PrepareUploadHandlerV2)).Methods("POST")
+	apiRouter.Handle("/v2/upload", withIdleDeadline(transferIdleTimeout, receiveHandler.UploadHandlerV2)).Methods("POST")
+	apiRouter.Handle("/v2/cancel", control(controlTimeout, receiveHandler.CancelHandler)).Methods("POST")
+	// Legacy LocalSend releases speak v1, which has no session IDs.
+	apiRouter.Handle("/v1/send-request", control(promptTimeout, receiveHandler.SendRequestHandlerV1)).Methods("POST")
+	apiRouter.Handle("/v1/send", withIdleDeadline(transferIdleTimeout, receiveHandler.SendHandlerV1)).Methods("POST")
+	apiRouter.Handle("/v1/cancel", control(controlTimeout, receiveHandler.CancelHandlerV1)).Methods("POST")
 
 	// Download Handlers
-	downloadHandler := handlers.NewDownloadHandler(s.config, s.sendService, s.logger)
-	apiRouter.HandleFunc("/v2/prepare-download", downloadHandler.PrepareDownloadHandler).Methods("POST")
-	apiRouter.HandleFunc("/v2/download", downloadHandler.DownloadHandler).Methods("GET")
+	downloadHandler := handlers.NewDownloadHandler(s.config, s.sendService, s.logger.Named("handlers"))
+	apiRouter.Handle("/v2/prepare-download", control(controlTimeout, downloadHandler.PrepareDownloadHandler)).Methods("POST")
+	apiRouter.Handle("/v2/download", withIdleDeadline(transferIdleTimeout, downloadHandler.DownloadHandler)).Methods("GET")
+
+	// Speed test handlers: the receive path with the data discarded
+	benchRouter := s.muxRouter.PathPrefix("/api/localgo/v1/bench").Subrouter()
+	benchRouter.Handle("/prepare-upload", control(promptTimeout, receiveHandler.BenchmarkPrepareHandler)).Methods("POST")
+	benchRouter.Handle("/upload", withIdleDeadline(transferIdleTimeout, receiveHandler.BenchmarkUploadHandler)).Methods("POST")
+	benchRouter.Handle("/cancel", control(controlTimeout, receiveHandler.BenchmarkCancelHandler)).Methods("POST")
+
+	// Admin Handlers (loopback only, with the admin token)
+	if s.config.AdminTokenPath == "" {
+		s.logger.Warn("No admin API token file configured, the admin API will refuse all requests")
+	} else if _, err := config.LoadOrCreateAdminToken(s.config.AdminTokenPath); err != nil {
+		s.logger.Warnf("Failed to create the admin API token, the admin API will refuse all requests: %v", err)
+	}
+	adminRouter := s.muxRouter.PathPrefix("/api/localgo").Subrouter()
+	adminRouter.Use(handlers.LocalOnly, handlers.RequireToken(s.config.AdminTokenPath))
+	adminHandler := handlers.NewAdminHandler(s.receiveService, s.sendService, s.registryService, s.queue, s.events, s.logger.Named("handlers"))
+	adminHandler.SetTransfers(s.transfers)
+	adminRoutes := func(r *mux.Router) {
+		r.Handle("/v1/quick-save", control(controlTimeout, adminHandler.QuickSaveHandler)).Methods("GET", "POST", "DELETE")
+		r.Handle("/v1/status", control(controlTimeout, adminHandler.StatusHandler)).Methods("GET")
+		r.Handle("/v1/session", control(controlTimeout, adminHandler.SessionHandler)).Methods("GET")
+		r.Handle("/v1/devices", control(controlTimeout, adminHandler.DevicesHandler)).Methods("GET")
+		r.Handle("/v1/queue", control(controlTimeout, adminHandler.QueueHandler)).Methods("GET", "POST", "DELETE")
+		r.Handle("/v1/queue/retry", control(controlTimeout, adminHandler.QueueRetryHandler)).Methods("POST")
+		r.Handle("/v1/queue/priority", control(controlTimeout, adminHandler.QueuePriorityHandler)).Methods("POST")
+		r.Handle("/v1/transfers", control(controlTimeout, adminHandler.TransfersHandler)).Methods("GET", "DELETE")
+		r.HandleFunc("/events", adminHandler.EventsHandler).Methods("GET")
+	}
+	adminRoutes(adminRouter)
+
+	// The admin socket serves the same routes; its permissions stand in for
+	// the loopback check and the token.
+	if s.config.AdminSocket != "" {
+		s.adminRouter = mux.NewRouter()
+		adminRoutes(s.adminRouter.PathPrefix("/api/localgo").Subrouter())
+	}
 
 	s.logger.Info("Configured API routes.")
 }
 
+// withAccessLog wraps h with the configured access log. It wraps the whole
+// router rather than being registered with Use so that requests matching no
+// route are logged too.
+func (s *Server) withAccessLog(h http.Handler) http.Handler {
+	var w io.Writer
+	switch path := s.config.AccessLog; path {
+	case "":
+		return h
+	case "-":
+		w = os.Stderr
+	default:
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			s.logger.Warnf("Failed to create access log directory for %s: %v", path, err)
+			return h
+		}
+		f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+		if err != nil {
+			s.logger.Warnf("Failed to open access log at %s: %v", path, err)
+			return h
+		}
+		s.accessLogFile = f
+		w = f
+		s.logger.Infof("HTTP access log will be written to %s", path)
+	}
+	return newAccessLog(w, s.config.AccessLogFormat, s.peerFingerprint).Middleware(h)
+}
+
+// peerFingerprint returns the fingerprint of a sender or registered device at
+// ip, or "" if the peer is unknown.
+func (s *Server) peerFingerprint(ip string) string {
+	if fp := s.receiveService.SenderFingerprint(ip); fp != "" {
+		return fp
+	}
+	return s.registryService.FingerprintByIP(ip)
+}
+
 // Start runs the HTTP/S server.
 func (s *Server) Start(ctx context.Context, readyChan chan<- struct{}) error {
+	if s.config.ScanCmd != "" && s.config.EncryptTo != "" {
+		return errors.New("scan and encrypt_to cannot be used together: files encrypted at rest cannot be scanned")
+	}
+	var mqttOpts *mqtt.ClientOptions
+	if s.config.MQTTBroker != "" {
+		opts, err := s.mqttOptions()
+		if err != nil {
+			return fmt.Errorf("mqtt: %w", err)
+		}
+		mqttOpts = opts
+	}
+	var relayClient *relay.Client
+	if s.config.Relay != "" {
+		c, err := s.relayClient()
+		if err != nil {
+			return fmt.Errorf("relay: %w", err)
+		}
+		relayClient = c
+	}
 	s.configureRoutes()
 
 	addr := fmt.Sprintf("0.0.0.0:%d", s.config.Port)
 	s.httpServer = &http.Server{
 		Addr:              addr,
-		Handler:           s.muxRouter,
-		ReadTimeout:       0, // body timeout handled by MaxBytesReader / LimitReader
-		WriteTimeout:      300 * time.Second,
-		ReadHeaderTimeout: 30 * time.Second,
+		Handler:           s.withAccessLog(capture.Handler(s.muxRouter)),
+		ReadHeaderTimeout: 30 * time.Second, // body and response deadlines are set per route, see timeouts.go
+		MaxHeaderBytes:    maxHeaderBytes,
 		IdleTimeout:       120 * time.Second,
 		BaseContext:       func(net.Listener) context.Context { return s.shutdownCtx },
 	}
 
-	ln, err := net.Listen("tcp", addr)
-	if err != nil {
+	configuredPort := s.config.Port
+	ln := s.listener
+	var err error
+	if ln != nil {
+		s.logger.Infof("Using inherited listener on %s", ln.Addr())
+	} else if ln, err = net.Listen("tcp", addr); err != nil {
 		// Port occupied – retry with port 0 (OS assigns free port)
 		s.logger.Warnf("Port %d is busy, binding to a free port", s.config.Port)
 		cli.Notify("LocalGo: Port Changed",
@@ -152,38 +382,35 @@ func (s *Server) Start(ctx context.Context, readyChan chan<- struct{}) error {
 		if err != nil {
 			return fmt.Errorf("failed to bind port: %w", err)
 		}
+	}
 
-		actualPort := ln.Addr().(*net.TCPAddr).Port
+	// Adopt the bound port: it differs from the configured one when that was
+	// 0 (pick any free port) or busy, and is what discovery must announce.
+	if actualPort := ln.Addr().(*net.TCPAddr).Port; actualPort != s.config.Port {
 		s.config.Port = actualPort
 		addr = fmt.Sprintf("0.0.0.0:%d", actualPort)
 		s.httpServer.Addr = addr
 		s.logger.Infof("Server bound to port %d", actualPort)
 	}
+	s.recoverSessions(configuredPort)
 
-	serverErrChan := make(chan error, 1)
+	serverErrChan := make(chan error, 3)
 
 	if s.config.HttpsEnabled {
 		s.logger.Infof("Starting HTTPS server on %s with alias %s", addr, s.config.Alias)
-		var cert tls.Certificate
-		var err error
-		if s.config.CustomTLSCertPath != "" && s.config.CustomTLSKeyPath != "" {
-			cert, err = tls.LoadX509KeyPair(s.config.CustomTLSCertPath, s.config.CustomTLSKeyPath)
-			if err == nil && len(cert.Certificate) > 0 {
-				if leaf, parseErr := x509.ParseCertificate(cert.Certificate[0]); parseErr == nil {
-					hash := sha256.Sum256(leaf.Raw)
-					s.config.SetCustomFingerprint(hex.EncodeToString(hash[:]))
-					s.logger.Infof("Using custom TLS certificate, fingerprint: %.16s...", hex.EncodeToString(hash[:]))
-				}
-			}
-		} else {
-			cert, err = tls.X509KeyPair([]byte(s.config.SecurityContext.Certificate), []byte(s.config.SecurityContext.PrivateKey))
-		}
+		cert, err := s.certificate()
 		if err != nil {
-			return fmt.Errorf("failed to load TLS key pair: %w", err)
+			return err
 		}
+		// Offering h2 lets a sender multiplex its uploads over one
+		// connection; Serve enables HTTP/2 for configs that list it.
+		// Senders may present their certificate, which proves the
+		// fingerprint they report; see httputil.VerifiedFingerprint.
 		s.httpServer.TLSConfig = &tls.Config{
 			Certificates: []tls.Certificate{cert},
+			ClientAuth:   tls.RequestClientCert,
 			MinVersion:   tls.VersionTLS12,
+			NextProtos:   []string{"h2", "http/1.1"},
 		}
 
 		tlsListener := tls.NewListener(ln, s.httpServer.TLSConfig)
@@ -202,6 +429,64 @@ func (s *Server) Start(ctx context.Context, readyChan chan<- struct{}) error {
 		}()
 	}
 
+	if s.adminRouter != nil {
+		adminLn, err := listenAdminSocket(s.config.AdminSocket)
+		if err != nil {
+			s.httpServer.Close()
+			return err
+		}
+		s.adminServer = &http.Server{
+			Handler:           s.adminRouter,
+			ReadHeaderTimeout: 30 * time.Second,
+			BaseContext:       func(net.Listener) context.Context { return s.shutdownCtx },
+		}
+		s.logger.Infof("Serving the admin API on %s", s.config.AdminSocket)
+		go func() {
+			if err := s.adminServer.Serve(adminLn); err != nil && err != http.ErrServerClosed {
+				serverErrChan <- err
+			}
+		}()
+	}
+
+	if s.config.ControlGRPC != "" {
+		g, controlLn, err := s.listenControl()
+		if err != nil {
+			s.httpServer.Close()
+			if s.adminServer != nil {
+				s.adminServer.Close()
+			}
+			return fmt.Errorf("control interface: %w", err)
+		}
+		s.controlServer = g
+		s.logger.Infof("Serving the gRPC control interface on %s", s.config.ControlGRPC)
+		go func() {
+			if err := g.Serve(controlLn); err != nil {
+				serverErrChan <- err
+			}
+		}()
+	}
+
+	if s.config.DBus {
+		// Desktop integration is optional: without a session bus, such as
+		// under a system service, the server runs without it.
+		if stop, err := s.startDBus(); err != nil {
+			s.logger.Warnf("D-Bus interface not available: %v", err)
+		} else {
+			s.stopDBus = stop
+			s.logger.Info("Published the D-Bus interface on the session bus")
+		}
+	}
+
+	if mqttOpts != nil {
+		s.stopMQTT = s.startMQTT(mqttOpts)
+	}
+
+	if relayClient != nil {
+		s.startRelay(relayClient, s.httpServer.Handler)
+	}
+
+	go s.queue.Run(s.shutdownCtx)
+
 	// Signal that the port is successfully bound
 	if readyChan != nil {
 		readyChan <- struct{}{}
@@ -217,12 +502,37 @@ func (s *Server) Start(ctx context.Context, readyChan chan<- struct{}) error {
 	}
 }
 
+// certificate returns the server's TLS certificate, advertising the
+// fingerprint of a custom one.
+func (s *Server) certificate() (tls.Certificate, error) {
+	cert, err := s.config.TLSCertificate()
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("failed to load TLS key pair: %w", err)
+	}
+	if s.config.Identity == nil && s.config.CustomTLSCertPath != "" && s.config.CustomTLSKeyPath != "" && len(cert.Certificate) > 0 {
+		if leaf, parseErr := x509.ParseCertificate(cert.Certificate[0]); parseErr == nil {
+			hash := sha256.Sum256(leaf.Raw)
+			s.config.SetCustomFingerprint(hex.EncodeToString(hash[:]))
+			s.logger.Infof("Using custom TLS certificate, fingerprint: %.16s...", hex.EncodeToString(hash[:]))
+		}
+	}
+	return cert, nil
+}
+
 // Shutdown gracefully shuts down the server.
 func (s *Server) Shutdown(ctx context.Context) error {
 	if s.httpServer == nil {
 		return nil
 	}
 
+	if s.config.DrainTimeout > 0 && s.receiveService != nil {
+		s.drain(s.config.DrainTimeout)
+	}
+
+	if n := s.queue.Pending(); n > 0 {
+		s.logger.Warnf("Dropping %d queued send(s)", n)
+	}
+
 	// Cancel the shutdown context so in-flight handlers can abort early
 	if s.shutdownCancel != nil {
 		s.shutdownCancel()
@@ -242,6 +552,27 @@ func (s *Server) Shutdown(ctx context.Context) error {
 			return fmt.Errorf("server shutdown failed: %w", err)
 		}
 	}
+	if s.adminServer != nil {
+		// Event streams only end with the shutdown context, cancelled above.
+		if err := s.adminServer.Shutdown(shutdownCtx); err != nil {
+			s.logger.Warnf("Admin socket shutdown failed: %v", err)
+		}
+		s.adminServer = nil
+	}
+	if s.controlServer != nil {
+		s.stopControl(shutdownCtx)
+	}
+	if s.stopDBus != nil {
+		s.stopDBus()
+		s.stopDBus = nil
+	}
+	if s.stopMQTT != nil {
+		s.stopMQTT()
+		s.stopMQTT = nil
+	}
+	if s.receiveHandler != nil {
+		s.receiveHandler.WaitHooks(shutdownCtx)
+	}
 	s.logger.Info("Server stopped.")
 	s.httpServer = nil
 	if s.receiveService != nil {
@@ -253,9 +584,144 @@ func (s *Server) Shutdown(ctx context.Context) error {
 		}
 		s.historyLog = nil
 	}
+	if s.accessLogFile != nil {
+		if err := s.accessLogFile.Close(); err != nil {
+			s.logger.Warnf("Failed to close access log: %v", err)
+		}
+		s.accessLogFile = nil
+	}
+	return nil
+}
+
+// stopControl stops the control interface, waiting until ctx ends for calls
+// in progress. Event streams end with the shutdown context.
+func (s *Server) stopControl(ctx context.Context) {
+	done := make(chan struct{})
+	go func() {
+		s.controlServer.GracefulStop()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		s.controlServer.Stop()
+	}
+	s.controlServer = nil
+}
+
+// drain refuses new transfers and waits up to timeout for active sessions to
+// finish, so stopping the server doesn't cut off a transfer in progress.
+func (s *Server) drain(timeout time.Duration) {
+	s.receiveService.StopAccepting()
+	if s.receiveService.ActiveSessions() == 0 {
+		return
+	}
+	s.logger.Infof("Waiting up to %s for active transfers to finish", timeout)
+
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-deadline.C:
+			s.logger.Warnf("%d transfer(s) still active after %s, stopping anyway", s.receiveService.ActiveSessions(), timeout)
+			return
+		case <-ticker.C:
+			if s.receiveService.ActiveSessions() == 0 {
+				s.logger.Info("Active transfers finished")
+				return
+			}
+		}
+	}
+}
+
+// GetReceiveService returns the ReceiveService instance.
+func (s *Server) GetReceiveService() *services.ReceiveService {
+	return s.receiveService
+}
+
+// recoverSessions starts saving receive sessions to the session file and
+// deals with those left by a previous run on the same port: interrupted
+// uploads are logged and recorded as failed in the history, and unexpired
+// sessions are restored so their senders can retry.
+func (s *Server) recoverSessions(port int) {
+	path := s.config.SessionFile
+	if path == history.DisabledSentinel {
+		return
+	}
+	if path == "" {
+		// Without a fixed port there is no telling which run a file belongs to.
+		if port == 0 {
+			return
+		}
+		if path = services.DefaultSessionFile(port); path == "" {
+			return
+		}
+	}
+
+	s.receiveService.SetSessionStore(services.NewSessionStore(path, s.logger))
+	interrupted, err := s.receiveService.RecoverSessions()
+	if err != nil {
+		s.logger.Warnf("Failed to recover receive sessions: %v", err)
+		return
+	}
+	for _, f := range interrupted {
+		s.logger.Warnf("Upload of %s from %s was interrupted at %d of %d bytes; partial file removed",
+			f.FileName, f.Sender.Alias, f.Bytes, f.Size)
+		if s.historyLog != nil {
+			err := s.historyLog.Log(history.Entry{
+				SenderAlias: f.Sender.Alias,
+				SenderIP:    f.Sender.IP,
+				FileName:    f.FileName,
+				FilePath:    f.Path,
+				FileSize:    f.Size,
+				FileType:    f.FileType,
+				Status:      history.StatusFailed,
+			})
+			if err != nil {
+				s.logger.Errorf("Failed to log transfer history: %v", err)
+			}
+		}
+	}
+	if n := s.receiveService.ActiveSessions(); n > 0 {
+		s.logger.Infof("Restored %d receive session(s) from %s", n, path)
+	}
+}
+
+// SetListener makes Start serve on ln, such as a socket passed by systemd,
+// instead of binding the configured port. Call it before Start.
+func (s *Server) SetListener(ln net.Listener) {
+	s.listener = ln
+}
+
+// ErrRoutesConfigured is returned by Use once Start has set up the routes.
+var ErrRoutesConfigured = errors.New("routes are already configured")
+
+// Use adds middleware, such as authentication, logging or metrics, around
+// every route of the HTTP/S API. It runs after the server's own security
+// headers, in the order added. Call it before Start; once the routes are
+// configured it returns ErrRoutesConfigured.
+func (s *Server) Use(mw ...mux.MiddlewareFunc) error {
+	s.middlewareMu.Lock()
+	defer s.middlewareMu.Unlock()
+	if s.routed {
+		return ErrRoutesConfigured
+	}
+	s.middleware = append(s.middleware, mw...)
 	return nil
 }
 
+// Events returns the broker that server activity events are published to.
+func (s *Server) Events() *services.EventBroker {
+	return s.events
+}
+
+// GetRegistryService returns the RegistryService instance.
+func (s *Server) GetRegistryService() *services.RegistryService {
+	return s.registryService
+}
+
 // GetSendService returns the SendService instance.
 func (s *Server) GetSendService() *services.SendService {
 	return s.sendService
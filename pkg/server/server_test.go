@@ -0,0 +1,244 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bethropolis/localgo/pkg/config"
+	"github.com/bethropolis/localgo/pkg/crypto"
+	"github.com/bethropolis/localgo/pkg/history"
+	"github.com/bethropolis/localgo/pkg/httputil"
+	"go.uber.org/zap"
+)
+
+func TestStart_RandomPort(t *testing.T) {
+	cfg := &config.Config{
+		Alias:           "Test",
+		Port:            0,
+		HttpsEnabled:    false,
+		HistoryFile:     history.DisabledSentinel,
+		DownloadDir:     t.TempDir(),
+		SecurityContext: &crypto.StoredSecurityContext{},
+	}
+	srv := NewServer(cfg, zap.NewNop().Sugar())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ready := make(chan struct{}, 1)
+	errCh := make(chan error, 1)
+	go func() { errCh <- srv.Start(ctx, ready) }()
+
+	select {
+	case <-ready:
+	case err := <-errCh:
+		t.Fatalf("server failed to start: %v", err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("server did not become ready")
+	}
+
+	if cfg.Port == 0 {
+		t.Fatal("expected the bound port to be written back to the config")
+	}
+	if dto := cfg.ToRegisterDto(); dto.Port != cfg.Port {
+		t.Errorf("register dto announces port %d, want %d", dto.Port, cfg.Port)
+	}
+
+	resp, err := http.Get(fmt.Sprintf("http://127.0.0.1:%d/api/localsend/v2/info", cfg.Port))
+	if err != nil {
+		t.Fatalf("server not reachable on bound port %d: %v", cfg.Port, err)
+	}
+	resp.Body.Close()
+
+	cancel()
+	if err := <-errCh; err != nil {
+		t.Errorf("server shutdown failed: %v", err)
+	}
+}
+
+func TestStart_AdminSocket(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "admin.sock")
+	cfg := &config.Config{
+		Alias:           "Test",
+		Port:            0,
+		HistoryFile:     history.DisabledSentinel,
+		DownloadDir:     t.TempDir(),
+		SecurityContext: &crypto.StoredSecurityContext{},
+		AdminSocket:     socket,
+	}
+	srv := NewServer(cfg, zap.NewNop().Sugar())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ready := make(chan struct{}, 1)
+	errCh := make(chan error, 1)
+	go func() { errCh <- srv.Start(ctx, ready) }()
+	select {
+	case <-ready:
+	case err := <-errCh:
+		t.Fatalf("server failed to start: %v", err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("server did not become ready")
+	}
+
+	if info, err := os.Stat(socket); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("socket not private: %v, %v", info, err)
+	}
+	client := &http.Client{Transport: &http.Transport{DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+		var d net.Dialer
+		return d.DialContext(ctx, "unix", socket)
+	}}}
+	resp, err := client.Get("http://localgo/api/localgo/v1/status")
+	if err != nil {
+		t.Fatalf("admin API not reachable on the socket: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("status over the socket = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+	// Over TCP the token is still required.
+	resp, err = http.Get(fmt.Sprintf("http://127.0.0.1:%d/api/localgo/v1/status", cfg.Port))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("status over TCP without a token = %d, want %d", resp.StatusCode, http.StatusUnauthorized)
+	}
+
+	cancel()
+	if err := <-errCh; err != nil {
+		t.Errorf("server shutdown failed: %v", err)
+	}
+	if _, err := os.Lstat(socket); !os.IsNotExist(err) {
+		t.Errorf("socket left behind after shutdown: %v", err)
+	}
+
+	// A socket left by a server that is gone is replaced; other files are not.
+	stale, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+	ln, err := listenAdminSocket(socket)
+	if err != nil {
+		t.Fatalf("stale socket not replaced: %v", err)
+	}
+	if info, err := os.Lstat(socket); err != nil {
+		t.Error(err)
+	} else if info.Mode().Perm() != 0600 {
+		t.Errorf("socket mode = %v, want 0600", info.Mode().Perm())
+	}
+	if entries, _ := os.ReadDir(filepath.Dir(socket)); len(entries) != 1 {
+		t.Errorf("socket directory holds %d entries, want only the socket", len(entries))
+	}
+	if _, err := listenAdminSocket(socket); err == nil {
+		t.Error("listened on a socket another server is using")
+	}
+	ln.Close()
+	if err := os.WriteFile(socket, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := listenAdminSocket(socket); err == nil {
+		t.Error("replaced a file that is not a socket")
+	}
+}
+
+func TestStart_InheritedListener(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	port := ln.Addr().(*net.TCPAddr).Port
+
+	cfg := &config.Config{
+		Alias:           "Test",
+		Port:            config.DefaultPort,
+		HttpsEnabled:    false,
+		HistoryFile:     history.DisabledSentinel,
+		SessionFile:     history.DisabledSentinel,
+		DownloadDir:     t.TempDir(),
+		SecurityContext: &crypto.StoredSecurityContext{},
+	}
+	srv := NewServer(cfg, zap.NewNop().Sugar())
+	srv.SetListener(ln)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ready := make(chan struct{}, 1)
+	errCh := make(chan error, 1)
+	go func() { errCh <- srv.Start(ctx, ready) }()
+
+	select {
+	case <-ready:
+	case err := <-errCh:
+		t.Fatalf("server failed to start: %v", err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("server did not become ready")
+	}
+
+	if cfg.Port != port {
+		t.Errorf("expected the inherited listener's port %d, got %d", port, cfg.Port)
+	}
+	resp, err := http.Get(fmt.Sprintf("http://127.0.0.1:%d/api/localsend/v2/info", port))
+	if err != nil {
+		t.Fatalf("server not reachable on inherited listener: %v", err)
+	}
+	resp.Body.Close()
+
+	cancel()
+	if err := <-errCh; err != nil {
+		t.Errorf("server shutdown failed: %v", err)
+	}
+}
+
+func TestStart_HTTPSNegotiatesHTTP2(t *testing.T) {
+	security, err := crypto.GenerateSecurityContext("Test", zap.NewNop().Sugar())
+	if err != nil {
+		t.Fatalf("generate security context: %v", err)
+	}
+	cfg := &config.Config{
+		Alias:           "Test",
+		Port:            0,
+		HttpsEnabled:    true,
+		HistoryFile:     history.DisabledSentinel,
+		SessionFile:     history.DisabledSentinel,
+		DownloadDir:     t.TempDir(),
+		SecurityContext: security,
+	}
+	srv := NewServer(cfg, zap.NewNop().Sugar())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ready := make(chan struct{}, 1)
+	errCh := make(chan error, 1)
+	go func() { errCh <- srv.Start(ctx, ready) }()
+
+	select {
+	case <-ready:
+	case err := <-errCh:
+		t.Fatalf("server failed to start: %v", err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("server did not become ready")
+	}
+
+	client := &http.Client{Transport: httputil.PinnedTransport(security.CertificateHash)}
+	for i := range 2 {
+		resp, err := client.Get(fmt.Sprintf("https://127.0.0.1:%d/api/localsend/v2/info", cfg.Port))
+		if err != nil {
+			t.Fatalf("request %d: %v", i, err)
+		}
+		resp.Body.Close()
+		if resp.ProtoMajor != 2 {
+			t.Errorf("request %d used %s, want HTTP/2", i, resp.Proto)
+		}
+	}
+	client.CloseIdleConnections()
+
+	cancel()
+	if err := <-errCh; err != nil {
+		t.Errorf("server shutdown failed: %v", err)
+	}
+}
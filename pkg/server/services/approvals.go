@@ -0,0 +1,145 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/bethropolis/localgo/pkg/model"
+	"github.com/google/uuid"
+)
+
+// EventTransferRequested is published when an incoming transfer is held for
+// a control client to answer.
+const EventTransferRequested = "transfer_requested"
+
+// ErrUnknownTransferRequest is returned when answering a transfer request
+// that was never made, or was already answered or expired.
+var ErrUnknownTransferRequest = errors.New("no such transfer request")
+
+// TransferRequest is an incoming transfer waiting to be accepted or
+// declined by a control client.
+type TransferRequest struct {
+	ID         string                   `json:"id"`
+	Sender     *EventDevice             `json:"sender"`
+	Files      map[string]model.FileDto `json:"files"`
+	ReceivedAt time.Time                `json:"receivedAt"`
+	ExpiresAt  time.Time                `json:"expiresAt"` // declined if not answered by then
+
+	answer chan []string // the accepted file IDs; nil declines
+}
+
+// Approvals holds incoming transfers for control clients to answer, such as
+// a GUI, instead of the terminal prompt. Transfers are only held while a
+// client that answers them is connected.
+type Approvals struct {
+	mu        sync.Mutex
+	answerers int
+	pending   map[string]*TransferRequest
+	events    *EventBroker
+}
+
+func newApprovals() *Approvals {
+	return &Approvals{pending: make(map[string]*TransferRequest)}
+}
+
+// AddAnswerer registers a client that answers transfer requests. Call the
+// returned function when it goes away.
+func (a *Approvals) AddAnswerer() func() {
+	a.mu.Lock()
+	a.answerers++
+	a.mu.Unlock()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			a.mu.Lock()
+			a.answerers--
+			a.mu.Unlock()
+		})
+	}
+}
+
+// Ask holds a transfer from sender until a client answers it, timeout
+// passes or ctx ends, and returns the files accepted: none if it was
+// declined or not answered in time. ok is false, and nothing is held, when
+// no client answers transfers; the caller prompts as usual then.
+func (a *Approvals) Ask(ctx context.Context, sender model.DeviceInfo, files map[string]model.FileDto, timeout time.Duration) (accepted map[string]model.FileDto, ok bool) {
+	now := time.Now()
+	req := &TransferRequest{
+		ID:         uuid.NewString(),
+		Sender:     NewEventDevice(sender),
+		Files:      files,
+		ReceivedAt: now,
+		ExpiresAt:  now.Add(timeout),
+		answer:     make(chan []string, 1),
+	}
+	a.mu.Lock()
+	if a.answerers == 0 {
+		a.mu.Unlock()
+		return nil, false
+	}
+	a.pending[req.ID] = req
+	a.mu.Unlock()
+	defer func() {
+		a.mu.Lock()
+		delete(a.pending, req.ID)
+		a.mu.Unlock()
+	}()
+
+	a.events.Publish(Event{Type: EventTransferRequested, Device: req.Sender, Files: len(files), Request: req})
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case ids := <-req.answer:
+		accepted = make(map[string]model.FileDto, len(ids))
+		for _, id := range ids {
+			if f, ok := files[id]; ok {
+				accepted[id] = f
+			}
+		}
+		return accepted, true
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+	return nil, true
+}
+
+// Pending returns the transfers waiting for an answer, oldest first.
+func (a *Approvals) Pending() []*TransferRequest {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	list := make([]*TransferRequest, 0, len(a.pending))
+	for _, req := range a.pending {
+		list = append(list, req)
+	}
+	slices.SortFunc(list, func(x, y *TransferRequest) int { return x.ReceivedAt.Compare(y.ReceivedAt) })
+	return list
+}
+
+// Answer accepts the files fileIDs of the transfer request id, all of them
+// if fileIDs is empty, or declines it.
+func (a *Approvals) Answer(id string, accept bool, fileIDs []string) error {
+	a.mu.Lock()
+	req, ok := a.pending[id]
+	if ok {
+		delete(a.pending, id)
+	}
+	a.mu.Unlock()
+	if !ok {
+		return ErrUnknownTransferRequest
+	}
+	var ids []string
+	if accept {
+		ids = fileIDs
+		if len(ids) == 0 {
+			for fid := range req.Files {
+				ids = append(ids, fid)
+			}
+		}
+	}
+	req.answer <- ids
+	return nil
+}
@@ -0,0 +1,90 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/bethropolis/localgo/pkg/model"
+)
+
+func TestApprovals(t *testing.T) {
+	a := newApprovals()
+	a.events = NewEventBroker()
+	sender := model.DeviceInfo{Alias: "Phone", IP: "192.168.1.20"}
+	files := map[string]model.FileDto{
+		"a": {ID: "a", FileName: "a.txt", Size: 1},
+		"b": {ID: "b", FileName: "b.txt", Size: 2},
+	}
+
+	// Without a client answering, nothing is held.
+	if _, ok := a.Ask(context.Background(), sender, files, time.Second); ok {
+		t.Fatal("transfer held with no client to answer it")
+	}
+
+	done := a.AddAnswerer()
+	events, unsubscribe := a.events.Subscribe()
+	defer unsubscribe()
+
+	type result struct {
+		accepted map[string]model.FileDto
+		ok       bool
+	}
+	ask := func(timeout time.Duration) <-chan result {
+		ch := make(chan result, 1)
+		go func() {
+			accepted, ok := a.Ask(context.Background(), sender, files, timeout)
+			ch <- result{accepted, ok}
+		}()
+		return ch
+	}
+
+	res := ask(time.Minute)
+	e := nextEvent(t, events)
+	if e.Type != EventTransferRequested || e.Request == nil || e.Device.Alias != "Phone" || e.Files != 2 {
+		t.Fatalf("unexpected event: %+v", e)
+	}
+	if pending := a.Pending(); len(pending) != 1 || pending[0].ID != e.Request.ID {
+		t.Fatalf("pending = %+v, want the request", pending)
+	}
+	if err := a.Answer(e.Request.ID, true, []string{"b", "unknown"}); err != nil {
+		t.Fatal(err)
+	}
+	if r := <-res; !r.ok || len(r.accepted) != 1 || r.accepted["b"].FileName != "b.txt" {
+		t.Errorf("accepted %+v, %v; want only b", r.accepted, r.ok)
+	}
+	if err := a.Answer(e.Request.ID, true, nil); !errors.Is(err, ErrUnknownTransferRequest) {
+		t.Errorf("answering twice: %v, want ErrUnknownTransferRequest", err)
+	}
+
+	// Accepting without file IDs accepts every file; declining accepts none.
+	res = ask(time.Minute)
+	e = nextEvent(t, events)
+	a.Answer(e.Request.ID, true, nil)
+	if r := <-res; len(r.accepted) != 2 {
+		t.Errorf("accepted %d files, want 2", len(r.accepted))
+	}
+	res = ask(time.Minute)
+	e = nextEvent(t, events)
+	a.Answer(e.Request.ID, false, []string{"a"})
+	if r := <-res; !r.ok || len(r.accepted) != 0 {
+		t.Errorf("declined transfer accepted %+v", r.accepted)
+	}
+
+	// A request not answered in time is declined and forgotten.
+	res = ask(10 * time.Millisecond)
+	nextEvent(t, events)
+	if r := <-res; !r.ok || len(r.accepted) != 0 {
+		t.Errorf("expired transfer accepted %+v", r.accepted)
+	}
+	if pending := a.Pending(); len(pending) != 0 {
+		t.Errorf("expired request still pending: %+v", pending)
+	}
+
+	done()
+	done()
+	if _, ok := a.Ask(context.Background(), sender, files, time.Second); ok {
+		t.Error("transfer held after the client went away")
+	}
+}
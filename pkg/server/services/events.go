@@ -0,0 +1,125 @@
+package services
+
+import (
+	"sync"
+	"time"
+
+	"github.com/bethropolis/localgo/pkg/model"
+)
+
+// Device events. EventDeviceDiscovered is published when a peer device is
+// first seen, and EventDeviceLost once it has not been seen for a while and
+// is forgotten. The session and file events reuse the cli.Event* names of
+// the NDJSON progress stream.
+const (
+	EventDeviceDiscovered = "device_discovered"
+	EventDeviceLost       = "device_lost"
+)
+
+// eventBuffer is how many events a subscriber may fall behind before further
+// events are dropped for it.
+const eventBuffer = 64
+
+// Event describes server activity: session lifecycle, transfer progress and
+// device discovery.
+type Event struct {
+	ID        uint64           `json:"id"`
+	Type      string           `json:"type"`
+	Time      time.Time        `json:"time"`
+	SessionID string           `json:"sessionId,omitempty"`
+	File      string           `json:"file,omitempty"`
+	Path      string           `json:"path,omitempty"`
+	Files     int              `json:"files,omitempty"`
+	Bytes     int64            `json:"bytes,omitempty"`
+	Total     int64            `json:"total,omitempty"`
+	Device    *EventDevice     `json:"device,omitempty"`
+	Request   *TransferRequest `json:"request,omitempty"` // for EventTransferRequested
+}
+
+// EventDevice identifies the peer an event is about.
+type EventDevice struct {
+	Alias       string           `json:"alias"`
+	Fingerprint string           `json:"fingerprint,omitempty"`
+	IP          string           `json:"ip,omitempty"`
+	DeviceModel *string          `json:"deviceModel,omitempty"`
+	DeviceType  model.DeviceType `json:"deviceType,omitempty"`
+}
+
+// NewEventDevice converts a session sender to an EventDevice.
+func NewEventDevice(info model.DeviceInfo) *EventDevice {
+	return &EventDevice{
+		Alias:       info.Alias,
+		Fingerprint: info.Fingerprint,
+		IP:          info.IP,
+		DeviceModel: info.DeviceModel,
+		DeviceType:  info.DeviceType,
+	}
+}
+
+// NewEventDeviceFromDevice converts a discovered device to an EventDevice.
+func NewEventDeviceFromDevice(device *model.Device) *EventDevice {
+	return &EventDevice{
+		Alias:       device.Alias,
+		Fingerprint: device.Fingerprint,
+		IP:          device.IP,
+		DeviceModel: device.DeviceModel,
+		DeviceType:  device.DeviceType,
+	}
+}
+
+// EventBroker fans events out to subscribers. Publishing never blocks: a
+// subscriber that falls behind misses events rather than stalling transfers.
+// A nil *EventBroker discards everything.
+type EventBroker struct {
+	mu     sync.Mutex
+	nextID uint64
+	subs   map[chan Event]struct{}
+}
+
+// NewEventBroker creates an EventBroker with no subscribers.
+func NewEventBroker() *EventBroker {
+	return &EventBroker{subs: make(map[chan Event]struct{})}
+}
+
+// Publish stamps e with an ID and time and delivers it to every subscriber.
+func (b *EventBroker) Publish(e Event) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if len(b.subs) == 0 {
+		return
+	}
+	b.nextID++
+	e.ID = b.nextID
+	e.Time = time.Now().UTC()
+	for ch := range b.subs {
+		select {
+		case ch <- e:
+		default:
+		}
+	}
+}
+
+// Subscribe returns a channel of future events and a function that ends the
+// subscription and closes the channel.
+func (b *EventBroker) Subscribe() (<-chan Event, func()) {
+	if b == nil {
+		return make(chan Event), func() {}
+	}
+	ch := make(chan Event, eventBuffer)
+	b.mu.Lock()
+	b.subs[ch] = struct{}{}
+	b.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			b.mu.Lock()
+			delete(b.subs, ch)
+			b.mu.Unlock()
+			close(ch)
+		})
+	}
+}
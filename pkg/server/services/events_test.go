@@ -0,0 +1,118 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bethropolis/localgo/pkg/cli"
+	"github.com/bethropolis/localgo/pkg/model"
+)
+
+func nextEvent(t *testing.T, ch <-chan Event) Event {
+	t.Helper()
+	select {
+	case e := <-ch:
+		return e
+	case <-time.After(time.Second):
+		t.Fatal("no event published")
+		return Event{}
+	}
+}
+
+func TestEventBroker(t *testing.T) {
+	b := NewEventBroker()
+	b.Publish(Event{Type: "unseen"}) // no subscribers yet
+
+	ch, unsubscribe := b.Subscribe()
+	b.Publish(Event{Type: "first"})
+	b.Publish(Event{Type: "second"})
+
+	first, second := nextEvent(t, ch), nextEvent(t, ch)
+	if first.Type != "first" || second.Type != "second" || second.ID != first.ID+1 || first.Time.IsZero() {
+		t.Errorf("unexpected events: %+v, %+v", first, second)
+	}
+
+	// A subscriber that falls behind loses events instead of blocking.
+	for i := 0; i < eventBuffer+10; i++ {
+		b.Publish(Event{Type: "flood"})
+	}
+	if len(ch) != eventBuffer {
+		t.Errorf("expected a full buffer of %d events, got %d", eventBuffer, len(ch))
+	}
+
+	unsubscribe()
+	unsubscribe()
+	for range ch {
+	}
+	b.Publish(Event{Type: "after"}) // must not panic on the closed channel
+
+	var nilBroker *EventBroker
+	nilBroker.Publish(Event{Type: "dropped"})
+}
+
+func TestReceiveService_Events(t *testing.T) {
+	svc := NewReceiveService()
+	defer svc.Close()
+	b := NewEventBroker()
+	svc.SetEventBroker(b)
+	ch, unsubscribe := b.Subscribe()
+	defer unsubscribe()
+
+	sender := model.DeviceInfo{Alias: "Phone", IP: "192.168.1.100", Fingerprint: "abc123"}
+	session, err := svc.CreateSession(sender, map[string]model.FileDto{
+		"f1": {ID: "f1", FileName: "a.txt", Size: 3},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	started := nextEvent(t, ch)
+	if started.Type != cli.EventSessionStarted || started.SessionID != session.SessionID || started.Files != 1 ||
+		started.Total != 3 || started.Device == nil || started.Device.Fingerprint != "abc123" {
+		t.Errorf("unexpected session_started event: %+v", started)
+	}
+
+	svc.PublishProgress(session.SessionID, "a", "a.txt", 1, 3)
+	if e := nextEvent(t, ch); e.Type != cli.EventFileProgress || e.Bytes != 1 || e.Total != 3 {
+		t.Errorf("unexpected file_progress event: %+v", e)
+	}
+
+	svc.CompleteFile(session.SessionID, "f1", "/tmp/a.txt")
+	if e := nextEvent(t, ch); e.Type != cli.EventFileCompleted || e.File != "a.txt" || e.Path != "/tmp/a.txt" {
+		t.Errorf("unexpected file_completed event: %+v", e)
+	}
+	if e := nextEvent(t, ch); e.Type != cli.EventSessionCompleted || e.SessionID != session.SessionID {
+		t.Errorf("unexpected session_completed event: %+v", e)
+	}
+}
+
+func TestRegistryService_DeviceDiscoveredEvent(t *testing.T) {
+	svc := NewRegistryService()
+	b := NewEventBroker()
+	svc.SetEventBroker(b)
+	ch, unsubscribe := b.Subscribe()
+	defer unsubscribe()
+
+	device := &model.Device{Alias: "Laptop", IP: "192.168.1.5", Fingerprint: "fp1"}
+	svc.RegisterDevice(device)
+	svc.RegisterDevice(device)
+
+	if e := nextEvent(t, ch); e.Type != EventDeviceDiscovered || e.Device.Alias != "Laptop" {
+		t.Errorf("unexpected event: %+v", e)
+	}
+	if len(ch) != 0 {
+		t.Error("re-registering a known device published another event")
+	}
+	if fp := svc.FingerprintByIP("192.168.1.5"); fp != "fp1" {
+		t.Errorf("FingerprintByIP = %q, want fp1", fp)
+	}
+
+	svc.RemoveDevice(device)
+	svc.RemoveDevice(device)
+	if e := nextEvent(t, ch); e.Type != EventDeviceLost || e.Device.Fingerprint != "fp1" {
+		t.Errorf("unexpected event: %+v", e)
+	}
+	if len(ch) != 0 || len(svc.GetDevices()) != 0 {
+		t.Error("removing a device twice published another event or kept it")
+	}
+}
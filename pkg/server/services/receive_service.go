@@ -1,13 +1,17 @@
 package services
 
 import (
+	"context"
 	"errors"
-	"fmt"
+	"sort"
 	"sync"
 	"time"
 
 	"github.com/bethropolis/localgo/pkg/cli"
+	"github.com/bethropolis/localgo/pkg/metadata"
 	"github.com/bethropolis/localgo/pkg/model"
+	"github.com/bethropolis/localgo/pkg/report"
+	"github.com/bethropolis/localgo/pkg/storage"
 	"github.com/google/uuid"
 )
 
@@ -26,22 +30,42 @@ var (
 	ErrInvalidFileToken = errors.New("invalid file or token")
 	ErrAlreadyUploading = errors.New("already uploading")
 	ErrAlreadyCompleted = errors.New("already completed")
+	ErrTooManyUploads   = errors.New("too many uploads in session")
+	ErrNotAccepting     = errors.New("not accepting new sessions")
+	ErrSessionActive    = errors.New("another session is already active")
 )
 
+// sessionExpiry is how long a session may stay open before it is dropped.
+const sessionExpiry = 10 * time.Minute
+
+// maxRecentReports is how many reports of ended sessions Status keeps.
+const maxRecentReports = 10
+
+// progressEventInterval bounds how often TrackFile publishes file_progress
+// events for each upload.
+const progressEventInterval = 250 * time.Millisecond
+
 // ActiveReceiveSession represents an active file receiving session.
 type ActiveReceiveSession struct {
 	SessionID string
 	Sender    model.DeviceInfo
 	Files     map[string]ActiveFile
 	CreatedAt time.Time
-	Progress  *cli.MultiProgress
+	Progress  cli.Progress
+	Restored  bool // recovered from the session store after a restart
+
+	report *report.Report  // outcome so far; finished when the session ends
+	ctx    context.Context // cancelled when the session ends
+	stop   context.CancelFunc
 }
 
 // ActiveFile represents a file in an active session.
 type ActiveFile struct {
-	Dto   model.FileDto
-	Token string
-	State FileTransferState
+	Dto         model.FileDto
+	Token       string
+	State       FileTransferState
+	Destination string // where the file is being saved while uploading
+	Received    int64  // bytes saved so far while uploading
 }
 
 // ReceiveService manages file receiving sessions.
@@ -50,23 +74,189 @@ type ReceiveService struct {
 	sessionMutex sync.RWMutex
 	stopCh       chan struct{}
 	closeOnce    sync.Once
+
+	quickSaveMu    sync.Mutex
+	quickSave      bool
+	quickSaveUntil time.Time // zero means until disabled
+
+	lastActivity       time.Time      // guarded by sessionMutex
+	stopped            bool           // guarded by sessionMutex; see StopAccepting
+	reserved           map[string]int // guarded by sessionMutex; files being saved at each destination
+	storeKept          bool           // guarded by sessionMutex; set by CloseAllSessions, which leaves the store as is
+	completionHandlers []func(sessionID string)
+	startHandlers      []func(*ActiveReceiveSession)
+	fileHandlers       []func(ReceivedFile)
+	summaryHandlers    []func(*report.Report)
+	handlersMu         sync.RWMutex
+
+	recentMu sync.Mutex
+	recent   []*report.Report // reports of the last sessions to end, oldest first
+
+	endedMu sync.Mutex
+	ended   chan struct{} // closed, and replaced, whenever a session ends
+
+	events     *EventBroker // set once before serving; nil discards events
+	approvals  *Approvals
+	store      *SessionStore // set once before serving; nil keeps sessions in memory only
+	dirty      chan struct{} // signals persistLoop that the sessions changed
+	persisted  chan struct{} // closed once persistLoop has saved the last change
+	maxUploads int           // set once before serving; files of a session uploaded at a time, 0 = unlimited
+}
+
+// ReceivedFile describes a file, or text message, that finished arriving.
+type ReceivedFile struct {
+	SessionID string // empty for text messages sent without a session
+	FileName  string // name requested by the sender
+	Path      string // where it was saved, "<clipboard>", or "<terminal>" for a printed message
+	Size      int64
+	Sender    model.DeviceInfo
 }
 
 // NewReceiveService creates a new ReceiveService.
 func NewReceiveService() *ReceiveService {
 	s := &ReceiveService{
-		sessions: make(map[string]*ActiveReceiveSession),
-		stopCh:   make(chan struct{}),
+		sessions:     make(map[string]*ActiveReceiveSession),
+		reserved:     make(map[string]int),
+		stopCh:       make(chan struct{}),
+		ended:        make(chan struct{}),
+		lastActivity: time.Now(),
+		approvals:    newApprovals(),
 	}
 	go s.cleanupLoop()
 	return s
 }
 
-// Close stops the cleanup loop and releases resources.
+// SetEventBroker makes the service publish session and file events to b.
+// Call it before the server starts.
+func (s *ReceiveService) SetEventBroker(b *EventBroker) {
+	s.events = b
+	s.approvals.events = b
+}
+
+// Approvals returns the incoming transfers held for control clients to
+// answer.
+func (s *ReceiveService) Approvals() *Approvals {
+	return s.approvals
+}
+
+// SetSessionStore makes the service save its sessions to st whenever they
+// change. Saving happens in the background; Close writes any change not yet
+// saved. Call it, and RecoverSessions, before the server starts.
+func (s *ReceiveService) SetSessionStore(st *SessionStore) {
+	s.store = st
+	s.dirty = make(chan struct{}, 1)
+	s.persisted = make(chan struct{})
+	go s.persistLoop()
+}
+
+// SetMaxUploads limits how many files of one session may be uploaded at a
+// time; ClaimFile refuses more with ErrTooManyUploads. 0 means no limit.
+// Call it before the server starts.
+func (s *ReceiveService) SetMaxUploads(n int) {
+	s.maxUploads = n
+}
+
+// persistLocked schedules saving the sessions to the store. Callers hold
+// sessionMutex.
+func (s *ReceiveService) persistLocked() {
+	if s.store != nil {
+		select {
+		case s.dirty <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// setDestinationLocked records path as where a file is saved, "" for
+// nowhere, keeping the reserved destinations in step. Callers hold
+// sessionMutex.
+func (s *ReceiveService) setDestinationLocked(session *ActiveReceiveSession, fileID, path string) {
+	file := session.Files[fileID]
+	s.releaseLocked(file)
+	file.Destination = path
+	if path != "" {
+		s.reserved[path]++
+	}
+	session.Files[fileID] = file
+}
+
+// releaseLocked frees the destinations of files leaving their session.
+// Callers hold sessionMutex.
+func (s *ReceiveService) releaseLocked(files ...ActiveFile) {
+	for _, f := range files {
+		if f.Destination == "" {
+			continue
+		}
+		if s.reserved[f.Destination] <= 1 {
+			delete(s.reserved, f.Destination)
+		} else {
+			s.reserved[f.Destination]--
+		}
+	}
+}
+
+// releaseSessionLocked frees the destinations of the files of a session
+// being removed. Callers hold sessionMutex.
+func (s *ReceiveService) releaseSessionLocked(session *ActiveReceiveSession) {
+	for _, f := range session.Files {
+		s.releaseLocked(f)
+	}
+}
+
+// PublishProgress reports bytes of file received so far in a session.
+func (s *ReceiveService) PublishProgress(sessionID, fileID, file string, bytes, total int64) {
+	s.recordProgress(sessionID, fileID, bytes)
+	s.events.Publish(Event{Type: cli.EventFileProgress, SessionID: sessionID, File: file, Bytes: bytes, Total: total})
+}
+
+// TrackFile returns the progress callback for the upload of a claimed file.
+// Given the bytes saved so far, it records them on the session, where
+// Status and SessionStatus report them, and publishes a file_progress event
+// at most every progressEventInterval. bar, if not nil, is called with every
+// update too, such as to draw a progress bar for the file.
+func (s *ReceiveService) TrackFile(sessionID, fileID string, bar func(int64)) func(int64) {
+	s.sessionMutex.RLock()
+	var dto model.FileDto
+	if session, ok := s.sessions[sessionID]; ok {
+		dto = session.Files[fileID].Dto
+	}
+	s.sessionMutex.RUnlock()
+
+	var lastPublished time.Time
+	return func(bytes int64) {
+		if bar != nil {
+			bar(bytes)
+		}
+		s.recordProgress(sessionID, fileID, bytes)
+		// CompleteFile reports the end of the upload.
+		if bytes < dto.Size && time.Since(lastPublished) >= progressEventInterval {
+			lastPublished = time.Now()
+			s.events.Publish(Event{Type: cli.EventFileProgress, SessionID: sessionID, File: dto.FileName, Bytes: bytes, Total: dto.Size})
+		}
+	}
+}
+
+// recordProgress sets the bytes received so far of a file being uploaded.
+func (s *ReceiveService) recordProgress(sessionID, fileID string, bytes int64) {
+	s.sessionMutex.Lock()
+	defer s.sessionMutex.Unlock()
+	if session, ok := s.sessions[sessionID]; ok {
+		if f, ok := session.Files[fileID]; ok {
+			f.Received = bytes
+			session.Files[fileID] = f
+		}
+	}
+}
+
+// Close stops the cleanup loop and releases resources, waiting for the
+// sessions to be saved.
 func (s *ReceiveService) Close() {
 	s.closeOnce.Do(func() {
 		close(s.stopCh)
 	})
+	if s.store != nil {
+		<-s.persisted
+	}
 }
 
 // cleanupLoop periodically checks and expires stale sessions
@@ -79,33 +269,66 @@ func (s *ReceiveService) cleanupLoop() {
 			return
 		case <-ticker.C:
 			s.sessionMutex.Lock()
+			var ended []*report.Report
 			for id, session := range s.sessions {
-				if time.Since(session.CreatedAt) > 10*time.Minute {
+				if time.Since(session.CreatedAt) > sessionExpiry {
 					if session.Progress != nil {
 						session.Progress.ForceComplete()
 						go session.Progress.Wait()
 					}
 					delete(s.sessions, id)
+					s.releaseSessionLocked(session)
+					session.end()
+					ended = append(ended, session.finishReport("session expired"))
+					s.events.Publish(Event{Type: cli.EventSessionCancelled, SessionID: id})
 				}
 			}
+			if len(ended) > 0 {
+				s.persistLocked()
+			}
 			s.sessionMutex.Unlock()
+			s.notifySummary(ended...)
 		}
 	}
 }
 
 // CreateSession creates a new receive session.
-// Returns an error if another session is already active (409 Blocked by another session).
+// Returns ErrSessionActive if another session is already active (409 Blocked by another session).
 func (s *ReceiveService) CreateSession(sender model.DeviceInfo, files map[string]model.FileDto) (*ActiveReceiveSession, error) {
+	var ended []*report.Report
+	var started *ActiveReceiveSession
+	defer func() {
+		s.notifySummary(ended...)
+		if started != nil {
+			s.notifyStart(started)
+		}
+	}()
 	s.sessionMutex.Lock()
 	defer s.sessionMutex.Unlock()
 
+	if s.stopped {
+		return nil, ErrNotAccepting
+	}
+	// A restored session only waits for its sender to retry; a new transfer
+	// supersedes it unless that retry is under way.
+	for id, session := range s.sessions {
+		if session.Restored && !session.uploading() {
+			delete(s.sessions, id)
+			s.releaseSessionLocked(session)
+			session.end()
+			ended = append(ended, session.finishReport("superseded by a new transfer"))
+			s.events.Publish(Event{Type: cli.EventSessionCancelled, SessionID: id})
+		}
+	}
 	if len(s.sessions) > 0 {
-		return nil, fmt.Errorf("another session is already active")
+		return nil, ErrSessionActive
 	}
 
 	sessionId := uuid.NewString()
 	sessionFiles := make(map[string]ActiveFile)
+	var totalSize int64
 	for fileId, fileDto := range files {
+		totalSize += fileDto.Size
 		token := uuid.NewString()
 		sessionFiles[fileId] = ActiveFile{
 			Dto:   fileDto,
@@ -118,10 +341,22 @@ func (s *ReceiveService) CreateSession(sender model.DeviceInfo, files map[string
 		Sender:    sender,
 		Files:     sessionFiles,
 		CreatedAt: time.Now(),
-		Progress:  cli.NewMultiProgress(int64(len(files))),
+		Progress:  cli.NewSessionProgress("receive", sessionId, len(files), totalSize),
+		report:    newSessionReport(sessionId, sender),
 	}
+	session.ctx, session.stop = context.WithCancel(context.Background())
 
 	s.sessions[sessionId] = session
+	s.lastActivity = time.Now()
+	s.persistLocked()
+	s.events.Publish(Event{
+		Type:      cli.EventSessionStarted,
+		SessionID: sessionId,
+		Files:     len(files),
+		Total:     totalSize,
+		Device:    NewEventDevice(sender),
+	})
+	started = s.copySession(session)
 
 	return session, nil
 }
@@ -151,6 +386,35 @@ func (s *ReceiveService) GetSessionByID(sessionID string) *ActiveReceiveSession
 	return nil
 }
 
+// SenderFingerprint returns the fingerprint of the sender of an active
+// session from ip, or "" if there is none.
+func (s *ReceiveService) SenderFingerprint(ip string) string {
+	s.sessionMutex.RLock()
+	defer s.sessionMutex.RUnlock()
+
+	for _, session := range s.sessions {
+		if session.Sender.IP == ip {
+			return session.Sender.Fingerprint
+		}
+	}
+	return ""
+}
+
+// SessionIDForSender returns the ID of the active session whose sender is
+// at ip, or "" if there is none. v1 senders, which are not told a session
+// ID, are matched to their session by it.
+func (s *ReceiveService) SessionIDForSender(ip string) string {
+	s.sessionMutex.RLock()
+	defer s.sessionMutex.RUnlock()
+
+	for id, session := range s.sessions {
+		if session.Sender.IP == ip {
+			return id
+		}
+	}
+	return ""
+}
+
 func (s *ReceiveService) copySession(orig *ActiveReceiveSession) *ActiveReceiveSession {
 	copySession := &ActiveReceiveSession{
 		SessionID: orig.SessionID,
@@ -158,6 +422,7 @@ func (s *ReceiveService) copySession(orig *ActiveReceiveSession) *ActiveReceiveS
 		Files:     make(map[string]ActiveFile, len(orig.Files)),
 		CreatedAt: orig.CreatedAt,
 		Progress:  orig.Progress,
+		Restored:  orig.Restored,
 	}
 	for k, v := range orig.Files {
 		copySession.Files[k] = v
@@ -165,12 +430,40 @@ func (s *ReceiveService) copySession(orig *ActiveReceiveSession) *ActiveReceiveS
 	return copySession
 }
 
+// SessionContext returns a context that is cancelled when the session ends,
+// however it ends. Uploads read their body under it, so cancelling the
+// session stops them. For a session that is not active, the context is
+// already cancelled.
+func (s *ReceiveService) SessionContext(sessionID string) context.Context {
+	s.sessionMutex.RLock()
+	defer s.sessionMutex.RUnlock()
+	if session, ok := s.sessions[sessionID]; ok && session.ctx != nil {
+		return session.ctx
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	return ctx
+}
+
+// end cancels the session's context. Callers hold sessionMutex and have
+// just removed the session.
+func (session *ActiveReceiveSession) end() {
+	if session.stop != nil {
+		session.stop()
+	}
+}
+
 // CloseSession closes a specific session.
 func (s *ReceiveService) CloseSession(sessionID string) {
 	s.sessionMutex.Lock()
 	session, ok := s.sessions[sessionID]
+	var ended *report.Report
 	if ok {
 		delete(s.sessions, sessionID)
+		s.releaseSessionLocked(session)
+		session.end()
+		ended = session.finishReport("cancelled")
+		s.persistLocked()
 	}
 	s.sessionMutex.Unlock()
 
@@ -178,6 +471,11 @@ func (s *ReceiveService) CloseSession(sessionID string) {
 		session.Progress.ForceComplete()
 		session.Progress.Wait()
 	}
+	if ok {
+		cli.EmitEvent(cli.ProgressEvent{Event: cli.EventSessionCancelled, Direction: "receive", SessionID: sessionID})
+		s.events.Publish(Event{Type: cli.EventSessionCancelled, SessionID: sessionID})
+		s.notifySummary(ended)
+	}
 }
 
 // ClaimFile atomically validates session, sender IP, file ID, and token,
@@ -205,31 +503,254 @@ func (s *ReceiveService) ClaimFile(sessionID, fileID, token, senderIP string) (m
 	case FileDone:
 		return model.FileDto{}, model.DeviceInfo{}, ErrAlreadyCompleted
 	}
+	if s.maxUploads > 0 && session.uploads() >= s.maxUploads {
+		return model.FileDto{}, model.DeviceInfo{}, ErrTooManyUploads
+	}
 	file.State = FileUploading
 	session.Files[fileID] = file
 	return file.Dto, session.Sender, nil
 }
 
-// CompleteFile removes the file from the session after a successful upload.
-// If no files remain, the session is cleaned up and the progress bar completes.
-func (s *ReceiveService) CompleteFile(sessionID, fileID string) {
+// SetFileDestination records where a claimed file is being saved, so an
+// upload cut off by a crash can be found and cleaned up after a restart.
+func (s *ReceiveService) SetFileDestination(sessionID, fileID, path string) {
+	s.sessionMutex.Lock()
+	defer s.sessionMutex.Unlock()
+
+	session, ok := s.sessions[sessionID]
+	if !ok {
+		return
+	}
+	if _, ok := session.Files[fileID]; !ok {
+		return
+	}
+	s.setDestinationLocked(session, fileID, path)
+	s.persistLocked()
+}
+
+// ReserveDestination picks where a claimed file of a session is saved: name
+// under dir, numbered like storage.ResolveDuplicateFilename if that is taken
+// on disk or by another upload in progress, and records it as with
+// SetFileDestination. Uploads running in parallel are so never given the
+// same path.
+func (s *ReceiveService) ReserveDestination(sessionID, fileID, dir, name string) string {
+	s.sessionMutex.Lock()
+	defer s.sessionMutex.Unlock()
+
+	path := storage.ResolveFreeFilename(dir, name, func(p string) bool { return s.reserved[p] > 0 })
+
+	if session, ok := s.sessions[sessionID]; ok {
+		if _, ok := session.Files[fileID]; ok {
+			s.setDestinationLocked(session, fileID, path)
+			s.persistLocked()
+		}
+	}
+	return path
+}
+
+// uploading reports whether any file of the session is being uploaded.
+func (session *ActiveReceiveSession) uploading() bool {
+	return session.uploads() > 0
+}
+
+// uploads returns how many files of the session are being uploaded.
+func (session *ActiveReceiveSession) uploads() int {
+	n := 0
+	for _, f := range session.Files {
+		if f.State == FileUploading {
+			n++
+		}
+	}
+	return n
+}
+
+// CompleteFile removes the file from the session after a successful upload
+// saved at savedPath. If no files remain, the session is cleaned up and the
+// progress bar completes.
+func (s *ReceiveService) CompleteFile(sessionID, fileID, savedPath string) {
 	s.sessionMutex.Lock()
 	session, ok := s.sessions[sessionID]
 	if !ok {
 		s.sessionMutex.Unlock()
 		return
 	}
+	received := ReceivedFile{
+		SessionID: sessionID,
+		FileName:  session.Files[fileID].Dto.FileName,
+		Path:      savedPath,
+		Size:      session.Files[fileID].Dto.Size,
+		Sender:    session.Sender,
+	}
+	s.releaseLocked(session.Files[fileID])
 	delete(session.Files, fileID)
+	if session.report != nil {
+		session.report.Add(report.File{Name: received.FileName, Path: savedPath, Size: received.Size, Status: report.StatusReceived})
+	}
 	sessionEmpty := len(session.Files) == 0
+	var ended *report.Report
 	if sessionEmpty {
 		delete(s.sessions, sessionID)
+		session.end()
+		ended = session.finishReport("")
 	}
+	s.lastActivity = time.Now()
+	s.persistLocked()
 	s.sessionMutex.Unlock()
 
+	s.events.Publish(Event{Type: cli.EventFileCompleted, SessionID: sessionID, File: received.FileName, Path: savedPath, Bytes: received.Size})
+	s.notifyFile(received)
 	if sessionEmpty && session.Progress != nil {
 		session.Progress.ForceComplete()
 		go session.Progress.Wait()
 	}
+	if sessionEmpty {
+		cli.EmitEvent(cli.ProgressEvent{Event: cli.EventSessionCompleted, Direction: "receive", SessionID: sessionID})
+		s.events.Publish(Event{Type: cli.EventSessionCompleted, SessionID: sessionID})
+		s.notifySummary(ended)
+		s.notifyCompletion(sessionID)
+	}
+}
+
+// CompleteMessage records a text message received without a session, such
+// as clipboard text sent in prepare-upload, and notifies file and completion
+// handlers with an empty session ID.
+func (s *ReceiveService) CompleteMessage(received ReceivedFile) {
+	s.sessionMutex.Lock()
+	s.lastActivity = time.Now()
+	s.sessionMutex.Unlock()
+	s.events.Publish(Event{Type: cli.EventFileCompleted, File: received.FileName, Path: received.Path, Bytes: received.Size, Device: NewEventDevice(received.Sender)})
+	s.notifyFile(received)
+	s.notifyCompletion("")
+}
+
+// AddFileHandler registers fn to run after each file or text message has
+// been received, before any completion handler for its session.
+func (s *ReceiveService) AddFileHandler(fn func(ReceivedFile)) {
+	s.handlersMu.Lock()
+	defer s.handlersMu.Unlock()
+	s.fileHandlers = append(s.fileHandlers, fn)
+}
+
+func (s *ReceiveService) notifyFile(received ReceivedFile) {
+	s.handlersMu.RLock()
+	defer s.handlersMu.RUnlock()
+	for _, fn := range s.fileHandlers {
+		fn(received)
+	}
+}
+
+// AddStartHandler registers fn to run with a copy of each session as it is
+// created.
+func (s *ReceiveService) AddStartHandler(fn func(*ActiveReceiveSession)) {
+	s.handlersMu.Lock()
+	defer s.handlersMu.Unlock()
+	s.startHandlers = append(s.startHandlers, fn)
+}
+
+func (s *ReceiveService) notifyStart(session *ActiveReceiveSession) {
+	s.handlersMu.RLock()
+	defer s.handlersMu.RUnlock()
+	for _, fn := range s.startHandlers {
+		fn(session)
+	}
+}
+
+// AddCompletionHandler registers fn to run after a session has received all
+// of its files, or after a session-less text message arrives.
+func (s *ReceiveService) AddCompletionHandler(fn func(sessionID string)) {
+	s.handlersMu.Lock()
+	defer s.handlersMu.Unlock()
+	s.completionHandlers = append(s.completionHandlers, fn)
+}
+
+func (s *ReceiveService) notifyCompletion(sessionID string) {
+	s.handlersMu.RLock()
+	defer s.handlersMu.RUnlock()
+	for _, fn := range s.completionHandlers {
+		fn(sessionID)
+	}
+}
+
+// AddSummaryHandler registers fn to receive the report of each session when
+// it ends, whether completed, cancelled or expired. For a completed session
+// it runs before the completion handlers.
+func (s *ReceiveService) AddSummaryHandler(fn func(*report.Report)) {
+	s.handlersMu.Lock()
+	defer s.handlersMu.Unlock()
+	s.summaryHandlers = append(s.summaryHandlers, fn)
+}
+
+// SessionEnded returns a channel that is closed when the next session ends.
+// Take it before calling CreateSession, so a session ending in between is
+// not missed, and call CreateSession again once it is closed: another
+// sender may have started a session first.
+func (s *ReceiveService) SessionEnded() <-chan struct{} {
+	s.endedMu.Lock()
+	defer s.endedMu.Unlock()
+	return s.ended
+}
+
+func (s *ReceiveService) notifySummary(reports ...*report.Report) {
+	if len(reports) > 0 {
+		s.endedMu.Lock()
+		close(s.ended)
+		s.ended = make(chan struct{})
+		s.endedMu.Unlock()
+	}
+	s.handlersMu.RLock()
+	defer s.handlersMu.RUnlock()
+	for _, r := range reports {
+		if r == nil {
+			continue
+		}
+		s.recentMu.Lock()
+		s.recent = append(s.recent, r)
+		if len(s.recent) > maxRecentReports {
+			s.recent = s.recent[len(s.recent)-maxRecentReports:]
+		}
+		s.recentMu.Unlock()
+		for _, fn := range s.summaryHandlers {
+			fn(r)
+		}
+	}
+}
+
+// newSessionReport starts the report of a session from sender.
+func newSessionReport(sessionID string, sender model.DeviceInfo) *report.Report {
+	r := report.New("receive", sender.Alias)
+	r.SessionID = sessionID
+	r.PeerFingerprint = sender.Fingerprint
+	return r
+}
+
+// finishReport completes the report of a session that has ended, counting
+// the files it never received as failed with reason. Callers hold
+// sessionMutex.
+func (session *ActiveReceiveSession) finishReport(reason string) *report.Report {
+	if session.report == nil {
+		return nil
+	}
+	remaining := make([]ActiveFile, 0, len(session.Files))
+	for _, f := range session.Files {
+		remaining = append(remaining, f)
+	}
+	sort.Slice(remaining, func(i, j int) bool { return remaining[i].Dto.FileName < remaining[j].Dto.FileName })
+	for _, f := range remaining {
+		session.report.Add(report.File{Name: f.Dto.FileName, Size: f.Dto.Size, Status: report.StatusFailed, Error: reason})
+	}
+	session.report.Finish(time.Now())
+	return session.report
+}
+
+// IdleFor returns how long it has been since a session was last created or
+// a file last received, or zero while a session is active.
+func (s *ReceiveService) IdleFor() time.Duration {
+	s.sessionMutex.RLock()
+	defer s.sessionMutex.RUnlock()
+	if len(s.sessions) > 0 {
+		return 0
+	}
+	return time.Since(s.lastActivity)
 }
 
 // FailFile resets the file state back to pending so the sender can retry.
@@ -245,14 +766,19 @@ func (s *ReceiveService) FailFile(sessionID, fileID string) {
 	if !ok {
 		return
 	}
+	s.setDestinationLocked(session, fileID, "")
+	file = session.Files[fileID]
 	file.State = FilePending
+	file.Received = 0
 	session.Files[fileID] = file
+	s.persistLocked()
+	s.events.Publish(Event{Type: cli.EventFileFailed, SessionID: sessionID, File: file.Dto.FileName})
 }
 
-// GetSessionProgress returns the MultiProgress for a session (or nil).
+// GetSessionProgress returns the Progress for a session (or nil).
 // The Progress pointer is assigned at session creation and never mutated,
 // so this is safe to read under RLock.
-func (s *ReceiveService) GetSessionProgress(sessionID string) *cli.MultiProgress {
+func (s *ReceiveService) GetSessionProgress(sessionID string) cli.Progress {
 	s.sessionMutex.RLock()
 	defer s.sessionMutex.RUnlock()
 	if session, ok := s.sessions[sessionID]; ok {
@@ -261,17 +787,48 @@ func (s *ReceiveService) GetSessionProgress(sessionID string) *cli.MultiProgress
 	return nil
 }
 
-// CloseAllSessions force-completes progress bars and removes all active sessions.
+// StopAccepting makes CreateSession fail with ErrNotAccepting, so active
+// sessions can finish while the server shuts down.
+func (s *ReceiveService) StopAccepting() {
+	s.sessionMutex.Lock()
+	defer s.sessionMutex.Unlock()
+	s.stopped = true
+}
+
+// ActiveSessions returns the number of sessions still receiving files.
+func (s *ReceiveService) ActiveSessions() int {
+	s.sessionMutex.RLock()
+	defer s.sessionMutex.RUnlock()
+	return len(s.sessions)
+}
+
+// CloseAllSessions force-completes progress bars and removes all active
+// sessions. The session store is left as is, so a restarted server can
+// restore them.
 func (s *ReceiveService) CloseAllSessions() {
+	var ended []*report.Report
+	defer func() { s.notifySummary(ended...) }()
+	if s.store != nil {
+		s.store.mu.Lock()
+		defer s.store.mu.Unlock()
+	}
 	s.sessionMutex.Lock()
 	defer s.sessionMutex.Unlock()
 
+	// Save the sessions as they are now; changes after this are not.
+	if s.store != nil && !s.storeKept {
+		s.store.save(snapshotSessions(s.sessions))
+		s.storeKept = true
+	}
 	for id, session := range s.sessions {
 		if session.Progress != nil {
 			session.Progress.ForceComplete()
 			go session.Progress.Wait()
 		}
 		delete(s.sessions, id)
+		s.releaseSessionLocked(session)
+		session.end()
+		ended = append(ended, session.finishReport("server stopped"))
 	}
 }
 
@@ -283,11 +840,16 @@ func (s *ReceiveService) RemoveFileFromSession(sessionID, fileID string) {
 		s.sessionMutex.Unlock()
 		return
 	}
+	s.releaseLocked(session.Files[fileID])
 	delete(session.Files, fileID)
 	sessionEmpty := len(session.Files) == 0
+	var ended *report.Report
 	if sessionEmpty {
 		delete(s.sessions, sessionID)
+		session.end()
+		ended = session.finishReport("")
 	}
+	s.persistLocked()
 	s.sessionMutex.Unlock()
 
 	// Gracefully stop the progress bar rendering goroutine when the session ends
@@ -295,4 +857,118 @@ func (s *ReceiveService) RemoveFileFromSession(sessionID, fileID string) {
 		session.Progress.ForceComplete()
 		go session.Progress.Wait()
 	}
+	s.notifySummary(ended)
+}
+
+// EnableQuickSave auto-accepts incoming transfers for d, or until
+// DisableQuickSave is called when d is zero.
+func (s *ReceiveService) EnableQuickSave(d time.Duration) {
+	s.quickSaveMu.Lock()
+	defer s.quickSaveMu.Unlock()
+	s.quickSave = true
+	s.quickSaveUntil = time.Time{}
+	if d > 0 {
+		s.quickSaveUntil = time.Now().Add(d)
+	}
+}
+
+// DisableQuickSave turns quick save off.
+func (s *ReceiveService) DisableQuickSave() {
+	s.quickSaveMu.Lock()
+	defer s.quickSaveMu.Unlock()
+	s.quickSave = false
+	s.quickSaveUntil = time.Time{}
+}
+
+// QuickSave reports whether quick save is active and when it expires
+// (zero if it stays on until disabled). An expired window reads as off.
+func (s *ReceiveService) QuickSave() (bool, time.Time) {
+	s.quickSaveMu.Lock()
+	defer s.quickSaveMu.Unlock()
+	if s.quickSave && !s.quickSaveUntil.IsZero() && !time.Now().Before(s.quickSaveUntil) {
+		s.quickSave = false
+		s.quickSaveUntil = time.Time{}
+	}
+	return s.quickSave, s.quickSaveUntil
+}
+
+// Status returns the active sessions with the progress of each file, oldest
+// session first.
+func (s *ReceiveService) Status() []model.SessionStatusDto {
+	s.sessionMutex.RLock()
+	defer s.sessionMutex.RUnlock()
+
+	sessions := make([]model.SessionStatusDto, 0, len(s.sessions))
+	for _, session := range s.sessions {
+		sessions = append(sessions, session.status())
+	}
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].StartedAt.Before(sessions[j].StartedAt) })
+	return sessions
+}
+
+// SessionStatus returns the progress of the active session sessionID, and
+// false if there is no such session.
+func (s *ReceiveService) SessionStatus(sessionID string) (model.SessionStatusDto, bool) {
+	s.sessionMutex.RLock()
+	defer s.sessionMutex.RUnlock()
+
+	session, ok := s.sessions[sessionID]
+	if !ok {
+		return model.SessionStatusDto{}, false
+	}
+	return session.status(), true
+}
+
+// status reports the session's progress: its files that ended, then the rest
+// by name. Callers hold sessionMutex.
+func (session *ActiveReceiveSession) status() model.SessionStatusDto {
+	status := model.SessionStatusDto{
+		SessionID: session.SessionID,
+		Sender:    session.Sender.Alias,
+		SenderIP:  session.Sender.IP,
+		StartedAt: session.CreatedAt,
+		Restored:  session.Restored,
+		Files:     []model.FileStatusDto{},
+	}
+	if session.report != nil {
+		for _, f := range session.report.Files {
+			bytes := int64(0)
+			if f.Status == report.StatusReceived {
+				bytes = f.Size
+			}
+			status.Files = append(status.Files, model.FileStatusDto{Name: f.Name, Size: f.Size, Bytes: bytes, Status: f.Status})
+		}
+	}
+	pending := make([]model.FileStatusDto, 0, len(session.Files))
+	for _, f := range session.Files {
+		state := "pending"
+		if f.State == FileUploading {
+			state = "receiving"
+		}
+		file := model.FileStatusDto{Name: f.Dto.FileName, Size: f.Dto.Size, Bytes: f.Received, Status: state}
+		if f.Dto.Preview != nil && metadata.IsImagePreview(*f.Dto.Preview) {
+			file.Preview = *f.Dto.Preview
+		}
+		pending = append(pending, file)
+	}
+	sort.Slice(pending, func(i, j int) bool { return pending[i].Name < pending[j].Name })
+	status.Files = append(status.Files, pending...)
+	for _, f := range status.Files {
+		status.Size += f.Size
+		status.Bytes += f.Bytes
+	}
+	return status
+}
+
+// Recent returns the reports of the sessions that ended last, most recent
+// first.
+func (s *ReceiveService) Recent() []*report.Report {
+	s.recentMu.Lock()
+	defer s.recentMu.Unlock()
+
+	recent := make([]*report.Report, len(s.recent))
+	for i, r := range s.recent {
+		recent[len(s.recent)-1-i] = r
+	}
+	return recent
 }
@@ -1,10 +1,15 @@
 package services
 
 import (
+	"path/filepath"
+	"reflect"
 	"sync"
 	"testing"
+	"time"
 
+	"github.com/bethropolis/localgo/pkg/cli"
 	"github.com/bethropolis/localgo/pkg/model"
+	"github.com/bethropolis/localgo/pkg/report"
 )
 
 func TestReceiveService_CreateSession(t *testing.T) {
@@ -128,6 +133,24 @@ func TestReceiveService_CloseSession(t *testing.T) {
 	}
 }
 
+func TestReceiveService_SessionContext(t *testing.T) {
+	svc := NewReceiveService()
+	files := map[string]model.FileDto{"file1": {ID: "file1", FileName: "test.txt"}}
+	session, _ := svc.CreateSession(model.DeviceInfo{Alias: "TestSender"}, files)
+
+	ctx := svc.SessionContext(session.SessionID)
+	if ctx.Err() != nil {
+		t.Fatal("expected the context of an active session to be live")
+	}
+	svc.CloseSession(session.SessionID)
+	if ctx.Err() == nil {
+		t.Error("expected closing the session to cancel its context")
+	}
+	if svc.SessionContext("unknown").Err() == nil {
+		t.Error("expected the context of an unknown session to be cancelled")
+	}
+}
+
 func TestReceiveService_ClaimFile_Success(t *testing.T) {
 	svc := NewReceiveService()
 	sender := model.DeviceInfo{Alias: "Alice", IP: "192.168.1.10"}
@@ -224,6 +247,106 @@ func TestReceiveService_ClaimFile_Concurrent(t *testing.T) {
 	}
 }
 
+func TestReceiveService_ClaimFile_MaxUploads(t *testing.T) {
+	svc := NewReceiveService()
+	svc.SetMaxUploads(2)
+	files := map[string]model.FileDto{
+		"f1": {ID: "f1", FileName: "a.txt", Size: 1},
+		"f2": {ID: "f2", FileName: "b.txt", Size: 1},
+		"f3": {ID: "f3", FileName: "c.txt", Size: 1},
+	}
+	session, _ := svc.CreateSession(model.DeviceInfo{IP: "10.0.0.1"}, files)
+	claim := func(id string) error {
+		_, _, err := svc.ClaimFile(session.SessionID, id, session.Files[id].Token, "10.0.0.1")
+		return err
+	}
+
+	if err := claim("f1"); err != nil {
+		t.Fatalf("first claim: %v", err)
+	}
+	if err := claim("f2"); err != nil {
+		t.Fatalf("second claim: %v", err)
+	}
+	if err := claim("f3"); err != ErrTooManyUploads {
+		t.Fatalf("third claim: got %v, want %v", err, ErrTooManyUploads)
+	}
+
+	// A finished upload frees its slot.
+	svc.CompleteFile(session.SessionID, "f1", "/tmp/a.txt")
+	if err := claim("f3"); err != nil {
+		t.Errorf("claim after an upload finished: %v", err)
+	}
+}
+
+func TestReceiveService_ReserveDestination(t *testing.T) {
+	svc := NewReceiveService()
+	dir := t.TempDir()
+	files := map[string]model.FileDto{
+		"f1": {ID: "f1", FileName: "photo.jpg", Size: 1},
+		"f2": {ID: "f2", FileName: "photo.jpg", Size: 1},
+	}
+	session, _ := svc.CreateSession(model.DeviceInfo{IP: "10.0.0.1"}, files)
+
+	first := svc.ReserveDestination(session.SessionID, "f1", dir, "photo.jpg")
+	second := svc.ReserveDestination(session.SessionID, "f2", dir, "photo.jpg")
+	if first != filepath.Join(dir, "photo.jpg") {
+		t.Errorf("first destination = %s, want %s", first, filepath.Join(dir, "photo.jpg"))
+	}
+	if second != filepath.Join(dir, "photo (1).jpg") {
+		t.Errorf("second destination = %s, want %s while the first is uploading", second, filepath.Join(dir, "photo (1).jpg"))
+	}
+
+	// A failed upload gives its path up.
+	svc.FailFile(session.SessionID, "f1")
+	if again := svc.ReserveDestination(session.SessionID, "f1", dir, "photo.jpg"); again != first {
+		t.Errorf("destination after failure = %s, want %s", again, first)
+	}
+
+	// So does a session that ends.
+	svc.CloseSession(session.SessionID)
+	next, _ := svc.CreateSession(model.DeviceInfo{IP: "10.0.0.1"}, files)
+	if again := svc.ReserveDestination(next.SessionID, "f2", dir, "photo.jpg"); again != first {
+		t.Errorf("destination after the session closed = %s, want %s", again, first)
+	}
+}
+
+func TestReceiveService_TrackFile(t *testing.T) {
+	svc := NewReceiveService()
+	b := NewEventBroker()
+	svc.SetEventBroker(b)
+	sender := model.DeviceInfo{IP: "10.0.0.1"}
+	session, _ := svc.CreateSession(sender, map[string]model.FileDto{
+		"f1": {ID: "f1", FileName: "a.bin", Size: 10},
+	})
+	svc.ClaimFile(session.SessionID, "f1", session.Files["f1"].Token, sender.IP)
+	ch, unsubscribe := b.Subscribe()
+	defer unsubscribe()
+
+	var drawn []int64
+	track := svc.TrackFile(session.SessionID, "f1", func(n int64) { drawn = append(drawn, n) })
+	track(4)
+	track(6) // within progressEventInterval of the first: recorded, not published
+
+	status, ok := svc.SessionStatus(session.SessionID)
+	if !ok || status.Bytes != 6 || status.Size != 10 || status.Files[0].Bytes != 6 {
+		t.Errorf("unexpected session status: %+v", status)
+	}
+	if !reflect.DeepEqual(drawn, []int64{4, 6}) {
+		t.Errorf("bar got %v, want every update", drawn)
+	}
+	if e := nextEvent(t, ch); e.Type != cli.EventFileProgress || e.File != "a.bin" || e.Bytes != 4 || e.Total != 10 {
+		t.Errorf("unexpected file_progress event: %+v", e)
+	}
+	select {
+	case e := <-ch:
+		t.Errorf("unexpected second event: %+v", e)
+	default:
+	}
+	if _, ok := svc.SessionStatus("unknown"); ok {
+		t.Error("expected no status for an unknown session")
+	}
+}
+
 func TestReceiveService_CompleteFile(t *testing.T) {
 	svc := NewReceiveService()
 	sender := model.DeviceInfo{Alias: "Alice", IP: "192.168.1.10"}
@@ -232,7 +355,7 @@ func TestReceiveService_CompleteFile(t *testing.T) {
 	}
 	session, _ := svc.CreateSession(sender, files)
 
-	svc.CompleteFile(session.SessionID, "f1")
+	svc.CompleteFile(session.SessionID, "f1", "/tmp/f1")
 
 	// File should be gone
 	up := svc.GetSessionByID(session.SessionID)
@@ -264,3 +387,190 @@ func TestReceiveService_RemoveFileFromSession(t *testing.T) {
 		t.Error("Expected session to be nil after removing all files")
 	}
 }
+
+func TestReceiveService_QuickSave(t *testing.T) {
+	svc := NewReceiveService()
+	defer svc.Close()
+
+	if on, _ := svc.QuickSave(); on {
+		t.Fatal("quick save should start off")
+	}
+
+	svc.EnableQuickSave(0)
+	if on, until := svc.QuickSave(); !on || !until.IsZero() {
+		t.Errorf("expected quick save on without expiry, got on=%v until=%v", on, until)
+	}
+
+	svc.EnableQuickSave(20 * time.Millisecond)
+	if on, until := svc.QuickSave(); !on || until.IsZero() {
+		t.Errorf("expected quick save on with expiry, got on=%v until=%v", on, until)
+	}
+	time.Sleep(30 * time.Millisecond)
+	if on, _ := svc.QuickSave(); on {
+		t.Error("quick save should expire")
+	}
+
+	svc.EnableQuickSave(0)
+	svc.DisableQuickSave()
+	if on, _ := svc.QuickSave(); on {
+		t.Error("quick save should be off after DisableQuickSave")
+	}
+}
+
+func TestReceiveService_CompletionAndIdle(t *testing.T) {
+	svc := NewReceiveService()
+	defer svc.Close()
+
+	var completed, paths []string
+	svc.AddCompletionHandler(func(sessionID string) {
+		completed = append(completed, sessionID)
+	})
+	svc.AddFileHandler(func(f ReceivedFile) {
+		paths = append(paths, f.Path)
+	})
+
+	session, err := svc.CreateSession(model.DeviceInfo{IP: "192.168.1.100"}, map[string]model.FileDto{
+		"a": {ID: "a", FileName: "a.txt"},
+		"b": {ID: "b", FileName: "b.txt"},
+	})
+	if err != nil {
+		t.Fatalf("CreateSession failed: %v", err)
+	}
+	time.Sleep(5 * time.Millisecond)
+	if idle := svc.IdleFor(); idle != 0 {
+		t.Errorf("expected no idle time during an active session, got %v", idle)
+	}
+
+	svc.CompleteFile(session.SessionID, "a", "/dl/a.txt")
+	if len(completed) != 0 {
+		t.Fatalf("session should not complete before all files arrive, got %v", completed)
+	}
+	svc.CompleteFile(session.SessionID, "b", "/dl/b.txt")
+	if len(completed) != 1 || completed[0] != session.SessionID {
+		t.Fatalf("expected completion for %s, got %v", session.SessionID, completed)
+	}
+
+	time.Sleep(5 * time.Millisecond)
+	if idle := svc.IdleFor(); idle <= 0 {
+		t.Errorf("expected idle time after the session ended, got %v", idle)
+	}
+
+	svc.CompleteMessage(ReceivedFile{Path: "<clipboard>"})
+	if len(completed) != 2 || completed[1] != "" {
+		t.Errorf("expected a session-less completion, got %v", completed)
+	}
+	if want := []string{"/dl/a.txt", "/dl/b.txt", "<clipboard>"}; !reflect.DeepEqual(paths, want) {
+		t.Errorf("expected received paths %v, got %v", want, paths)
+	}
+}
+
+func TestReceiveService_StopAccepting(t *testing.T) {
+	svc := NewReceiveService()
+	sender := model.DeviceInfo{Alias: "Alice", IP: "192.168.1.10"}
+	files := map[string]model.FileDto{"file1": {ID: "file1", FileName: "a.txt"}}
+
+	if _, err := svc.CreateSession(sender, files); err != nil {
+		t.Fatalf("CreateSession failed: %v", err)
+	}
+	if n := svc.ActiveSessions(); n != 1 {
+		t.Errorf("Expected 1 active session, got %d", n)
+	}
+
+	svc.StopAccepting()
+	other := model.DeviceInfo{Alias: "Bob", IP: "192.168.1.11"}
+	if _, err := svc.CreateSession(other, files); err != ErrNotAccepting {
+		t.Errorf("Expected ErrNotAccepting, got %v", err)
+	}
+	if n := svc.ActiveSessions(); n != 1 {
+		t.Errorf("Expected the running session to continue, got %d active", n)
+	}
+}
+
+func TestReceiveService_SummaryHandler(t *testing.T) {
+	svc := NewReceiveService()
+	defer svc.Close()
+
+	var summaries []*report.Report
+	var completedAfterSummary bool
+	svc.AddSummaryHandler(func(r *report.Report) {
+		summaries = append(summaries, r)
+	})
+	svc.AddCompletionHandler(func(sessionID string) {
+		completedAfterSummary = len(summaries) == 1
+	})
+
+	sender := model.DeviceInfo{Alias: "Phone", IP: "192.168.1.100"}
+	session, err := svc.CreateSession(sender, map[string]model.FileDto{
+		"a": {ID: "a", FileName: "a.txt", Size: 10},
+		"b": {ID: "b", FileName: "b.txt", Size: 20},
+	})
+	if err != nil {
+		t.Fatalf("CreateSession failed: %v", err)
+	}
+	svc.CompleteFile(session.SessionID, "a", "/dl/a.txt")
+	svc.CompleteFile(session.SessionID, "b", "/dl/b.txt")
+	if len(summaries) != 1 || !completedAfterSummary {
+		t.Fatalf("expected one summary before completion, got %d", len(summaries))
+	}
+	if r := summaries[0]; r.Direction != "receive" || r.Peer != "Phone" || r.SessionID != session.SessionID || r.Transferred != 2 || r.Bytes != 30 {
+		t.Errorf("unexpected summary: %+v", r)
+	}
+
+	// A cancelled session reports the files it never received as failed.
+	session, err = svc.CreateSession(sender, map[string]model.FileDto{
+		"c": {ID: "c", FileName: "c.txt", Size: 5},
+		"d": {ID: "d", FileName: "d.txt", Size: 7},
+	})
+	if err != nil {
+		t.Fatalf("CreateSession failed: %v", err)
+	}
+	svc.CompleteFile(session.SessionID, "c", "/dl/c.txt")
+	svc.CloseSession(session.SessionID)
+	if len(summaries) != 2 {
+		t.Fatalf("expected a summary for the cancelled session, got %d", len(summaries))
+	}
+	if r := summaries[1]; r.Transferred != 1 || r.Failed != 1 || r.Files[1].Name != "d.txt" || r.Files[1].Error != "cancelled" {
+		t.Errorf("unexpected summary: %+v", r)
+	}
+}
+
+func TestReceiveService_Status(t *testing.T) {
+	svc := NewReceiveService()
+	defer svc.Close()
+
+	sender := model.DeviceInfo{Alias: "Phone", IP: "192.168.1.100"}
+	session, err := svc.CreateSession(sender, map[string]model.FileDto{
+		"a": {ID: "a", FileName: "a.txt", Size: 10},
+		"b": {ID: "b", FileName: "b.txt", Size: 20},
+		"c": {ID: "c", FileName: "c.txt", Size: 30},
+	})
+	if err != nil {
+		t.Fatalf("CreateSession failed: %v", err)
+	}
+	svc.CompleteFile(session.SessionID, "a", "/dl/a.txt")
+	if _, _, err := svc.ClaimFile(session.SessionID, "b", session.Files["b"].Token, sender.IP); err != nil {
+		t.Fatalf("ClaimFile failed: %v", err)
+	}
+	svc.PublishProgress(session.SessionID, "b", "b.txt", 5, 20)
+
+	sessions := svc.Status()
+	if len(sessions) != 1 || sessions[0].Sender != "Phone" {
+		t.Fatalf("unexpected sessions: %+v", sessions)
+	}
+	want := []model.FileStatusDto{
+		{Name: "a.txt", Size: 10, Bytes: 10, Status: report.StatusReceived},
+		{Name: "b.txt", Size: 20, Bytes: 5, Status: "receiving"},
+		{Name: "c.txt", Size: 30, Bytes: 0, Status: "pending"},
+	}
+	if !reflect.DeepEqual(sessions[0].Files, want) {
+		t.Errorf("got files %+v, want %+v", sessions[0].Files, want)
+	}
+
+	svc.CloseSession(session.SessionID)
+	if sessions := svc.Status(); len(sessions) != 0 {
+		t.Errorf("expected no sessions after closing, got %d", len(sessions))
+	}
+	if recent := svc.Recent(); len(recent) != 1 || recent[0].SessionID != session.SessionID || recent[0].Transferred != 1 {
+		t.Errorf("unexpected recent reports: %+v", recent)
+	}
+}
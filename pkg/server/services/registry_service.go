@@ -11,6 +11,7 @@ import (
 type RegistryService struct {
 	devices      map[string]*model.Device
 	devicesMutex sync.RWMutex
+	events       *EventBroker // set once before serving; nil discards events
 }
 
 // NewRegistryService creates a new RegistryService.
@@ -20,10 +21,19 @@ func NewRegistryService() *RegistryService {
 	}
 }
 
+// SetEventBroker makes the registry publish an event for each newly
+// registered device and each one removed. Call it before the server starts.
+func (s *RegistryService) SetEventBroker(b *EventBroker) {
+	s.events = b
+}
+
 // RegisterDevice adds or updates a device in the registry.
 func (s *RegistryService) RegisterDevice(device *model.Device) {
 	s.devicesMutex.Lock()
 	defer s.devicesMutex.Unlock()
+	if _, known := s.devices[device.Fingerprint]; !known {
+		s.events.Publish(Event{Type: EventDeviceDiscovered, Device: NewEventDeviceFromDevice(device)})
+	}
 	s.devices[device.Fingerprint] = device
 }
 
@@ -39,6 +49,31 @@ func (s *RegistryService) GetDevices() []*model.Device {
 	return devices
 }
 
+// FingerprintByIP returns the fingerprint of a registered device at ip, or ""
+// if none is known.
+func (s *RegistryService) FingerprintByIP(ip string) string {
+	s.devicesMutex.RLock()
+	defer s.devicesMutex.RUnlock()
+
+	for _, dev := range s.devices {
+		if dev.IP == ip {
+			return dev.Fingerprint
+		}
+	}
+	return ""
+}
+
+// RemoveDevice removes a device that has gone offline from the registry,
+// unless it has registered again since it was last seen.
+func (s *RegistryService) RemoveDevice(device *model.Device) {
+	s.devicesMutex.Lock()
+	defer s.devicesMutex.Unlock()
+	if dev, known := s.devices[device.Fingerprint]; known && !dev.GetLastSeen().After(device.GetLastSeen()) {
+		delete(s.devices, device.Fingerprint)
+		s.events.Publish(Event{Type: EventDeviceLost, Device: NewEventDeviceFromDevice(device)})
+	}
+}
+
 // CleanupStaleDevices removes devices that haven't been seen recently.
 func (s *RegistryService) CleanupStaleDevices(staleThreshold time.Duration) {
 	s.devicesMutex.Lock()
@@ -47,6 +82,7 @@ func (s *RegistryService) CleanupStaleDevices(staleThreshold time.Duration) {
 	for fingerprint, dev := range s.devices {
 		if dev.IsStale(staleThreshold) {
 			delete(s.devices, fingerprint)
+			s.events.Publish(Event{Type: EventDeviceLost, Device: NewEventDeviceFromDevice(dev)})
 		}
 	}
 }
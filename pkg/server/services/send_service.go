@@ -2,9 +2,12 @@ package services
 
 import (
 	"fmt"
+	"maps"
+	"sort"
 	"sync"
 
 	"github.com/bethropolis/localgo/pkg/model"
+	"github.com/bethropolis/localgo/pkg/storage"
 	"github.com/google/uuid"
 )
 
@@ -12,7 +15,8 @@ import (
 type ActiveSendSession struct {
 	SessionID string
 	Files     map[string]model.FileDto
-	FilePaths map[string]string // Maps fileID to local absolute path
+	FilePaths map[string]string               // Maps fileID to local absolute path
+	Providers map[string]storage.FileProvider // Maps fileID to its content
 }
 
 // SendService manages file sending sessions.
@@ -26,8 +30,22 @@ func NewSendService() *SendService {
 	return &SendService{}
 }
 
-// CreateSession creates a new send session.
+// CreateSession creates a new send session offering files on disk.
 func (s *SendService) CreateSession(files map[string]model.FileDto, filePaths map[string]string) (*ActiveSendSession, error) {
+	providers := make(map[string]storage.FileProvider, len(filePaths))
+	for id, path := range filePaths {
+		providers[id] = storage.DiskFile(path)
+	}
+	return s.createSession(files, filePaths, providers)
+}
+
+// CreateProviderSession creates a new send session offering files read
+// through providers, which need not be on disk.
+func (s *SendService) CreateProviderSession(files map[string]model.FileDto, providers map[string]storage.FileProvider) (*ActiveSendSession, error) {
+	return s.createSession(files, map[string]string{}, providers)
+}
+
+func (s *SendService) createSession(files map[string]model.FileDto, filePaths map[string]string, providers map[string]storage.FileProvider) (*ActiveSendSession, error) {
 	s.sessionMutex.Lock()
 	defer s.sessionMutex.Unlock()
 
@@ -40,6 +58,7 @@ func (s *SendService) CreateSession(files map[string]model.FileDto, filePaths ma
 		SessionID: sessionId,
 		Files:     files,
 		FilePaths: filePaths,
+		Providers: providers,
 	}
 
 	return s.currentSession, nil
@@ -55,18 +74,7 @@ func (s *SendService) GetSession() *ActiveSendSession {
 		return nil
 	}
 
-	copySession := &ActiveSendSession{
-		SessionID: s.currentSession.SessionID,
-		Files:     make(map[string]model.FileDto, len(s.currentSession.Files)),
-		FilePaths: make(map[string]string, len(s.currentSession.FilePaths)),
-	}
-	for k, v := range s.currentSession.Files {
-		copySession.Files[k] = v
-	}
-	for k, v := range s.currentSession.FilePaths {
-		copySession.FilePaths[k] = v
-	}
-	return copySession
+	return s.currentSession.copy()
 }
 
 // GetSessionByID returns the session if the ID matches.
@@ -76,25 +84,42 @@ func (s *SendService) GetSessionByID(sessionID string) *ActiveSendSession {
 	defer s.sessionMutex.RUnlock()
 
 	if s.currentSession != nil && s.currentSession.SessionID == sessionID {
-		copySession := &ActiveSendSession{
-			SessionID: s.currentSession.SessionID,
-			Files:     make(map[string]model.FileDto, len(s.currentSession.Files)),
-			FilePaths: make(map[string]string, len(s.currentSession.FilePaths)),
-		}
-		for k, v := range s.currentSession.Files {
-			copySession.Files[k] = v
-		}
-		for k, v := range s.currentSession.FilePaths {
-			copySession.FilePaths[k] = v
-		}
-		return copySession
+		return s.currentSession.copy()
 	}
 	return nil
 }
 
+// copy returns a shallow copy of the session, so callers can read its maps
+// without holding the lock.
+func (a *ActiveSendSession) copy() *ActiveSendSession {
+	return &ActiveSendSession{
+		SessionID: a.SessionID,
+		Files:     maps.Clone(a.Files),
+		FilePaths: maps.Clone(a.FilePaths),
+		Providers: maps.Clone(a.Providers),
+	}
+}
+
 // CloseSession closes the current session.
 func (s *SendService) CloseSession() {
 	s.sessionMutex.Lock()
 	defer s.sessionMutex.Unlock()
 	s.currentSession = nil
 }
+
+// Status returns the files the current session offers for download, or nil
+// if nothing is shared.
+func (s *SendService) Status() *model.ShareStatusDto {
+	s.sessionMutex.RLock()
+	defer s.sessionMutex.RUnlock()
+
+	if s.currentSession == nil {
+		return nil
+	}
+	status := &model.ShareStatusDto{SessionID: s.currentSession.SessionID, Files: []model.FileStatusDto{}}
+	for _, f := range s.currentSession.Files {
+		status.Files = append(status.Files, model.FileStatusDto{Name: f.FileName, Size: f.Size, Status: "offered"})
+	}
+	sort.Slice(status.Files, func(i, j int) bool { return status.Files[i].Name < status.Files[j].Name })
+	return status
+}
@@ -0,0 +1,231 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/bethropolis/localgo/pkg/model"
+	"github.com/bethropolis/localgo/pkg/storage"
+	"go.uber.org/zap"
+)
+
+// SessionStore keeps a copy of the active receive sessions in a JSON file,
+// rewritten shortly after they change, so that sessions cut off by a crash
+// or kill can be reported and cleaned up after a restart.
+type SessionStore struct {
+	path   string
+	logger *zap.SugaredLogger
+	mu     sync.Mutex // held from taking a snapshot until it is written, so an older one never replaces a newer one
+}
+
+// storedSession is the on-disk form of an ActiveReceiveSession.
+type storedSession struct {
+	SessionID string           `json:"sessionId"`
+	Sender    model.DeviceInfo `json:"sender"`
+	CreatedAt time.Time        `json:"createdAt"`
+	Files     []storedFile     `json:"files"`
+}
+
+type storedFile struct {
+	ID          string        `json:"id"`
+	Token       string        `json:"token"`
+	Dto         model.FileDto `json:"file"`
+	Destination string        `json:"destination,omitempty"` // set while uploading
+}
+
+// InterruptedFile is an upload that was in progress when the server stopped.
+type InterruptedFile struct {
+	SessionID string
+	FileName  string
+	Path      string // where the file was being saved
+	Bytes     int64  // received before the interruption
+	Size      int64
+	FileType  string
+	Sender    model.DeviceInfo
+}
+
+// NewSessionStore returns a store writing to path. Write errors are logged
+// to logger rather than failing transfers.
+func NewSessionStore(path string, logger *zap.SugaredLogger) *SessionStore {
+	return &SessionStore{path: path, logger: logger}
+}
+
+// DefaultSessionFile returns the session file for a server on port, kept in
+// the XDG state directory, or "" if there is no home directory.
+func DefaultSessionFile(port int) string {
+	name := fmt.Sprintf("sessions-%d.json", port)
+	if xdgState := os.Getenv("XDG_STATE_HOME"); xdgState != "" {
+		return filepath.Join(xdgState, "localgo", name)
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		return filepath.Join(home, ".local", "state", "localgo", name)
+	}
+	return ""
+}
+
+// sessionSaveDelay is how long the store waits after a change before saving,
+// so the changes of a busy session are written together.
+const sessionSaveDelay = 250 * time.Millisecond
+
+// snapshotSessions returns the on-disk form of sessions.
+func snapshotSessions(sessions map[string]*ActiveReceiveSession) []storedSession {
+	stored := make([]storedSession, 0, len(sessions))
+	for _, session := range sessions {
+		ss := storedSession{SessionID: session.SessionID, Sender: session.Sender, CreatedAt: session.CreatedAt}
+		for id, f := range session.Files {
+			ss.Files = append(ss.Files, storedFile{ID: id, Token: f.Token, Dto: f.Dto, Destination: f.Destination})
+		}
+		stored = append(stored, ss)
+	}
+	return stored
+}
+
+// save replaces the stored sessions with stored, removing the file when
+// there are none. The file is written to a temporary name and renamed so a
+// crash mid-write leaves the previous copy intact.
+func (st *SessionStore) save(stored []storedSession) {
+	if len(stored) == 0 {
+		if err := os.Remove(st.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			st.logger.Warnf("Failed to remove session file %s: %v", st.path, err)
+		}
+		return
+	}
+
+	data, err := json.Marshal(stored)
+	if err != nil {
+		st.logger.Warnf("Failed to encode sessions: %v", err)
+		return
+	}
+
+	if err := os.MkdirAll(filepath.Dir(st.path), 0700); err != nil {
+		st.logger.Warnf("Failed to create session file directory: %v", err)
+		return
+	}
+	tmp := st.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		st.logger.Warnf("Failed to write session file %s: %v", tmp, err)
+		return
+	}
+	if err := os.Rename(tmp, st.path); err != nil {
+		st.logger.Warnf("Failed to replace session file %s: %v", st.path, err)
+	}
+}
+
+// load reads the stored sessions. A missing file means there are none.
+func (st *SessionStore) load() ([]storedSession, error) {
+	data, err := os.ReadFile(st.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var stored []storedSession
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return nil, fmt.Errorf("invalid session file %s: %w", st.path, err)
+	}
+	return stored, nil
+}
+
+// persistLoop saves the sessions sessionSaveDelay after they change, until
+// the service is closed, when any change not yet saved is written.
+func (s *ReceiveService) persistLoop() {
+	defer close(s.persisted)
+	for {
+		select {
+		case <-s.stopCh:
+			select {
+			case <-s.dirty:
+				s.writeSessions()
+			default:
+			}
+			return
+		case <-s.dirty:
+		}
+		select {
+		case <-s.stopCh:
+		case <-time.After(sessionSaveDelay):
+		}
+		s.writeSessions()
+	}
+}
+
+// writeSessions saves a snapshot of the sessions to the store. The snapshot
+// is taken under a read lock and written without holding sessionMutex.
+func (s *ReceiveService) writeSessions() {
+	s.store.mu.Lock()
+	defer s.store.mu.Unlock()
+	s.sessionMutex.RLock()
+	if s.storeKept {
+		s.sessionMutex.RUnlock()
+		return
+	}
+	stored := snapshotSessions(s.sessions)
+	s.sessionMutex.RUnlock()
+	s.store.save(stored)
+}
+
+// RecoverSessions loads the sessions saved by a previous run. Uploads that
+// were in progress have their partial files removed and are returned so the
+// caller can report them. Sessions that have not expired are restored with
+// their files pending, letting the sender retry with the same tokens until
+// a new transfer supersedes them.
+func (s *ReceiveService) RecoverSessions() ([]InterruptedFile, error) {
+	if s.store == nil {
+		return nil, nil
+	}
+	stored, err := s.store.load()
+
+	s.sessionMutex.Lock()
+	defer s.sessionMutex.Unlock()
+	// A missing or unreadable file is dropped either way, so the next save
+	// starts clean.
+	defer s.persistLocked()
+	if err != nil {
+		return nil, err
+	}
+
+	var interrupted []InterruptedFile
+	for _, ss := range stored {
+		session := &ActiveReceiveSession{
+			SessionID: ss.SessionID,
+			Sender:    ss.Sender,
+			Files:     make(map[string]ActiveFile, len(ss.Files)),
+			CreatedAt: ss.CreatedAt,
+			Restored:  true,
+			report:    newSessionReport(ss.SessionID, ss.Sender),
+		}
+		session.ctx, session.stop = context.WithCancel(context.Background())
+		for _, f := range ss.Files {
+			if f.Destination != "" {
+				i := InterruptedFile{
+					SessionID: ss.SessionID,
+					FileName:  f.Dto.FileName,
+					Path:      f.Destination,
+					Size:      f.Dto.Size,
+					FileType:  f.Dto.FileType,
+					Sender:    ss.Sender,
+				}
+				partial := storage.PartialPath(f.Destination)
+				if info, err := os.Stat(partial); err == nil {
+					i.Bytes = info.Size()
+					if err := os.Remove(partial); err != nil {
+						s.store.logger.Warnf("Failed to remove partial file %s: %v", partial, err)
+					}
+				}
+				interrupted = append(interrupted, i)
+			}
+			session.Files[f.ID] = ActiveFile{Dto: f.Dto, Token: f.Token, State: FilePending}
+		}
+		if len(session.Files) > 0 && time.Since(session.CreatedAt) <= sessionExpiry {
+			s.sessions[session.SessionID] = session
+		}
+	}
+	return interrupted, nil
+}
@@ -0,0 +1,104 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bethropolis/localgo/pkg/model"
+	"github.com/bethropolis/localgo/pkg/storage"
+	"go.uber.org/zap"
+)
+
+func TestReceiveService_RecoverSessions(t *testing.T) {
+	dir := t.TempDir()
+	storePath := filepath.Join(dir, "state", "sessions.json")
+	sender := model.DeviceInfo{Alias: "TestSender", IP: "192.168.1.100"}
+	files := map[string]model.FileDto{
+		"file1": {ID: "file1", FileName: "big.bin", Size: 1000},
+		"file2": {ID: "file2", FileName: "small.txt", Size: 10},
+	}
+
+	// First run: one upload is in progress when the process dies.
+	first := NewReceiveService()
+	first.SetSessionStore(NewSessionStore(storePath, zap.NewNop().Sugar()))
+	session, err := first.CreateSession(sender, files)
+	if err != nil {
+		t.Fatalf("CreateSession failed: %v", err)
+	}
+	dest := filepath.Join(dir, "big.bin")
+	if _, _, err := first.ClaimFile(session.SessionID, "file1", session.Files["file1"].Token, sender.IP); err != nil {
+		t.Fatalf("ClaimFile failed: %v", err)
+	}
+	first.SetFileDestination(session.SessionID, "file1", dest)
+	if err := os.WriteFile(storage.PartialPath(dest), make([]byte, 400), 0600); err != nil {
+		t.Fatal(err)
+	}
+	first.Close()
+
+	// Second run.
+	second := NewReceiveService()
+	defer second.Close()
+	second.SetSessionStore(NewSessionStore(storePath, zap.NewNop().Sugar()))
+	interrupted, err := second.RecoverSessions()
+	if err != nil {
+		t.Fatalf("RecoverSessions failed: %v", err)
+	}
+	if len(interrupted) != 1 {
+		t.Fatalf("expected 1 interrupted file, got %d", len(interrupted))
+	}
+	if got := interrupted[0]; got.FileName != "big.bin" || got.Path != dest || got.Bytes != 400 || got.Size != 1000 || got.Sender.Alias != "TestSender" {
+		t.Errorf("unexpected interrupted file: %+v", got)
+	}
+	if _, err := os.Stat(storage.PartialPath(dest)); !os.IsNotExist(err) {
+		t.Error("expected the partial file to be removed")
+	}
+
+	restored := second.GetSessionByID(session.SessionID)
+	if restored == nil {
+		t.Fatal("expected the session to be restored")
+	}
+	if !restored.Restored || len(restored.Files) != 2 {
+		t.Errorf("unexpected restored session: %+v", restored)
+	}
+	// The sender can retry the interrupted file with its original token.
+	if _, _, err := second.ClaimFile(session.SessionID, "file1", session.Files["file1"].Token, sender.IP); err != nil {
+		t.Errorf("ClaimFile after restart failed: %v", err)
+	}
+	second.FailFile(session.SessionID, "file1")
+
+	// A new transfer supersedes the restored session.
+	if _, err := second.CreateSession(sender, map[string]model.FileDto{"f": {ID: "f", FileName: "new.txt", Size: 1}}); err != nil {
+		t.Fatalf("CreateSession after restart failed: %v", err)
+	}
+	if second.GetSessionByID(session.SessionID) != nil {
+		t.Error("expected the restored session to be dropped")
+	}
+}
+
+func TestReceiveService_RecoverSessions_Expired(t *testing.T) {
+	storePath := filepath.Join(t.TempDir(), "sessions.json")
+	store := NewSessionStore(storePath, zap.NewNop().Sugar())
+	store.save(snapshotSessions(map[string]*ActiveReceiveSession{
+		"old": {
+			SessionID: "old",
+			Files:     map[string]ActiveFile{"f": {Dto: model.FileDto{ID: "f", FileName: "a.txt"}, Token: "t"}},
+			CreatedAt: time.Now().Add(-2 * sessionExpiry),
+		},
+	}))
+
+	svc := NewReceiveService()
+	defer svc.Close()
+	svc.SetSessionStore(store)
+	if _, err := svc.RecoverSessions(); err != nil {
+		t.Fatalf("RecoverSessions failed: %v", err)
+	}
+	if svc.ActiveSessions() != 0 {
+		t.Error("expected the expired session not to be restored")
+	}
+	svc.Close()
+	if _, err := os.Stat(storePath); !os.IsNotExist(err) {
+		t.Error("expected the session file to be removed")
+	}
+}
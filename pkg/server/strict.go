@@ -0,0 +1,154 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// strictErrors are the errors the LocalSend v2 protocol documents for each
+// endpoint, by status code, with the message the reference app sends. In
+// strict mode an endpoint listed here answers with one of these or with
+// strictUnknown.
+var strictErrors = map[string]map[int]string{
+	"/v2/prepare-upload": {
+		http.StatusBadRequest:      "Invalid body",
+		http.StatusUnauthorized:    "PIN required",
+		http.StatusForbidden:       "Rejected",
+		http.StatusConflict:        "Blocked by another session",
+		http.StatusTooManyRequests: "Too many requests",
+	},
+	"/v2/upload": {
+		http.StatusBadRequest: "Missing parameters",
+		http.StatusForbidden:  "Invalid token or IP address",
+		http.StatusConflict:   "Blocked by another session",
+	},
+	"/v2/cancel": {
+		http.StatusBadRequest: "Missing parameters",
+	},
+	"/v2/prepare-download": {
+		http.StatusUnauthorized:    "PIN required",
+		http.StatusForbidden:       "Rejected",
+		http.StatusTooManyRequests: "Too many requests",
+	},
+	"/v2/download": {
+		http.StatusBadRequest: "Missing parameters",
+		http.StatusForbidden:  "Invalid token or IP address",
+	},
+}
+
+// strictUnknown is the error for anything the protocol does not document.
+const strictUnknown = "Unknown error by receiver"
+
+// withStrictProtocol answers errors on the LocalSend API with the status
+// codes and {"message": ...} bodies of the protocol, as the reference app
+// does, instead of LocalGo's own. LocalGo extensions to responses, such as
+// offering compressed uploads, are left out.
+func withStrictProtocol(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sw := &strictWriter{ResponseWriter: w}
+		next.ServeHTTP(sw, r)
+		if sw.status >= 400 {
+			status, message := strictError(strings.TrimPrefix(r.URL.Path, "/api/localsend"), r, sw.status, errorMessage(sw.body.Bytes()))
+			w.Header().Del("Content-Length")
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(status)
+			json.NewEncoder(w).Encode(map[string]string{"message": message})
+		}
+	})
+}
+
+// strictError maps an error response LocalGo gave on endpoint to the one the
+// protocol documents.
+func strictError(endpoint string, r *http.Request, status int, message string) (int, string) {
+	documented, ok := strictErrors[endpoint]
+	if !ok {
+		return status, message
+	}
+	switch {
+	case status == http.StatusRequestEntityTooLarge:
+		// An oversized request is an invalid body to the protocol.
+		status = http.StatusBadRequest
+	case status == http.StatusBadRequest && endpoint == "/v2/upload" && !strings.HasPrefix(message, "Missing"):
+		// The protocol's 400 is for missing parameters only; a body that
+		// fails to save is the receiver's error.
+		status = http.StatusInternalServerError
+	case status == http.StatusBadRequest && message == "Insufficient storage space on receiver":
+		status = http.StatusInternalServerError
+	case status == http.StatusForbidden && message == "Invalid session ID":
+		// The session finished or was cancelled: it is no longer the one
+		// uploads are taken for.
+		status = http.StatusConflict
+	case status == http.StatusNotFound && endpoint == "/v2/prepare-download":
+		// Nothing is shared: the request is turned down.
+		status = http.StatusForbidden
+	case status == http.StatusNotFound && endpoint == "/v2/download":
+		status = http.StatusForbidden
+	}
+	if status == http.StatusUnauthorized && r.URL.Query().Get("pin") != "" {
+		return status, "Invalid PIN"
+	}
+	if message, ok := documented[status]; ok {
+		return status, message
+	}
+	return http.StatusInternalServerError, strictUnknown
+}
+
+// errorMessage returns the message of a LocalGo error body, or the body
+// itself if it is not one.
+func errorMessage(body []byte) string {
+	var e struct {
+		Error string `json:"error"`
+	}
+	if json.Unmarshal(body, &e) == nil && e.Error != "" {
+		return e.Error
+	}
+	return strings.TrimSpace(string(body))
+}
+
+// strictWriter passes successful responses through and holds back error
+// responses for withStrictProtocol to rewrite.
+type strictWriter struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (w *strictWriter) WriteHeader(code int) {
+	if w.status != 0 {
+		return
+	}
+	w.status = code
+	w.Header().Del("Accept-Encoding")
+	if code < 400 {
+		w.ResponseWriter.WriteHeader(code)
+	}
+}
+
+func (w *strictWriter) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.status >= 400 {
+		return w.body.Write(p)
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+// ReadFrom keeps the underlying writer's ReadFrom, and so sendfile, in use.
+func (w *strictWriter) ReadFrom(src io.Reader) (int64, error) {
+	if w.status == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.status >= 400 {
+		return w.body.ReadFrom(src)
+	}
+	return io.Copy(w.ResponseWriter, src)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (w *strictWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,108 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bethropolis/localgo/pkg/config"
+	"github.com/bethropolis/localgo/pkg/crypto"
+	"github.com/bethropolis/localgo/pkg/history"
+	"go.uber.org/zap"
+)
+
+// TestStrictProtocol_Conformance checks the responses of a strict server
+// against the status codes and messages of the LocalSend v2 protocol.
+func TestStrictProtocol_Conformance(t *testing.T) {
+	cfg := &config.Config{
+		Alias:           "Strict",
+		AutoAccept:      true,
+		StrictProtocol:  true,
+		MaxBodySize:     1024,
+		HistoryFile:     history.DisabledSentinel,
+		DownloadDir:     t.TempDir(),
+		SecurityContext: &crypto.StoredSecurityContext{},
+	}
+	srv := NewServer(cfg, zap.NewNop().Sugar())
+	srv.configureRoutes()
+	ts := httptest.NewServer(srv.muxRouter)
+	defer ts.Close()
+
+	post := func(path, body string) (*http.Response, string) {
+		t.Helper()
+		resp, err := http.Post(ts.URL+"/api/localsend"+path, "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("POST %s: %v", path, err)
+		}
+		defer resp.Body.Close()
+		var e struct {
+			Message string `json:"message"`
+		}
+		data, _ := io.ReadAll(resp.Body)
+		if resp.StatusCode >= 400 {
+			if err := json.Unmarshal(data, &e); err != nil {
+				t.Errorf("POST %s: error body %q is not {\"message\": ...}", path, data)
+			}
+			return resp, e.Message
+		}
+		return resp, string(data)
+	}
+	prepare := func(files string) string {
+		return `{"info":{"alias":"Peer","version":"2.1","deviceType":"desktop","fingerprint":"fp"},"files":{` + files + `}}`
+	}
+	file := `"f1":{"id":"f1","fileName":"a.txt","size":5,"fileType":"application/octet-stream"}`
+
+	check := func(name string, resp *http.Response, message string, wantStatus int, wantMessage string) {
+		t.Helper()
+		if resp.StatusCode != wantStatus || (wantMessage != "" && message != wantMessage) {
+			t.Errorf("%s: got %d %q, want %d %q", name, resp.StatusCode, message, wantStatus, wantMessage)
+		}
+	}
+
+	resp, msg := post("/v2/prepare-upload", "{not json")
+	check("malformed prepare-upload", resp, msg, http.StatusBadRequest, "Invalid body")
+	resp, msg = post("/v2/prepare-upload", prepare(`"big":{"id":"big","fileName":"big.bin","size":4096,"fileType":"application/octet-stream"}`))
+	check("file over the size limit", resp, msg, http.StatusBadRequest, "Invalid body")
+	resp, msg = post("/v2/prepare-upload", prepare(""))
+	check("nothing to upload", resp, msg, http.StatusNoContent, "")
+
+	cfg.PIN = "1234"
+	resp, msg = post("/v2/prepare-upload", prepare(file))
+	check("missing PIN", resp, msg, http.StatusUnauthorized, "PIN required")
+	resp, msg = post("/v2/prepare-upload?pin=0000", prepare(file))
+	check("wrong PIN", resp, msg, http.StatusUnauthorized, "Invalid PIN")
+	cfg.PIN = ""
+
+	resp, body := post("/v2/prepare-upload", prepare(file))
+	check("accepted prepare-upload", resp, "", http.StatusOK, "")
+	if got := resp.Header.Get("Accept-Encoding"); got != "" {
+		t.Errorf("strict prepare-upload offers Accept-Encoding: %s", got)
+	}
+	var session struct {
+		SessionID string            `json:"sessionId"`
+		Files     map[string]string `json:"files"`
+	}
+	if err := json.Unmarshal([]byte(body), &session); err != nil || session.Files["f1"] == "" {
+		t.Fatalf("prepare-upload response %q has no token for f1", body)
+	}
+	resp, msg = post("/v2/prepare-upload", prepare(file))
+	check("second session", resp, msg, http.StatusConflict, "Blocked by another session")
+
+	resp, msg = post("/v2/upload?sessionId="+session.SessionID, "hello")
+	check("upload without parameters", resp, msg, http.StatusBadRequest, "Missing parameters")
+	resp, msg = post("/v2/upload?sessionId="+session.SessionID+"&fileId=f1&token=wrong", "hello")
+	check("upload with a wrong token", resp, msg, http.StatusForbidden, "Invalid token or IP address")
+	resp, msg = post("/v2/upload?sessionId="+session.SessionID+"&fileId=f1&token="+session.Files["f1"], "hello!")
+	check("upload of the wrong size", resp, msg, http.StatusInternalServerError, "Unknown error by receiver")
+
+	resp, _ = post("/v2/cancel?sessionId="+session.SessionID, "")
+	check("cancel", resp, "", http.StatusOK, "")
+	resp, msg = post("/v2/upload?sessionId="+session.SessionID+"&fileId=f1&token="+session.Files["f1"], "hello")
+	check("upload to a cancelled session", resp, msg, http.StatusConflict, "Blocked by another session")
+
+	resp, msg = post("/v2/prepare-download", "")
+	check("prepare-download with nothing shared", resp, msg, http.StatusForbidden, "Rejected")
+}
@@ -0,0 +1,146 @@
+package server
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Per-route deadlines. The http.Server itself only bounds header reads and
+// idle keep-alives; a server-wide read or write timeout would cut off any
+// upload or download that takes longer than it, however healthy.
+const (
+	controlTimeout      = 30 * time.Second // info, register, cancel, admin
+	promptTimeout       = 2 * time.Minute  // prepare-upload may wait on the accept prompt
+	transferIdleTimeout = 60 * time.Second // upload/download: max time without any bytes moving
+)
+
+// idleCopyChunk is how much of a file idleWriter.ReadFrom sends between
+// deadline extensions.
+const idleCopyChunk = 256 * 1024
+
+// deadlineRefreshInterval limits how often transfer deadlines are pushed
+// forward, so busy transfers don't reset them on every small read or write.
+// Short idle timeouts are refreshed at least four times per timeout.
+const deadlineRefreshInterval = time.Second
+
+// responseReserve is how long before a request deadline withDeadline ends the
+// request context, leaving a handler that waited on it time to respond.
+const responseReserve = 5 * time.Second
+
+// withDeadline bounds the whole request, body and response included. The
+// request context ends shortly before the deadline, so work a handler waits
+// on, such as an accept prompt, gives up while the response can still be
+// written.
+func withDeadline(d time.Duration, next http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rc := http.NewResponseController(w)
+		deadline := time.Now().Add(d)
+		_ = rc.SetReadDeadline(deadline)
+		_ = rc.SetWriteDeadline(deadline)
+		ctx, cancel := context.WithDeadline(r.Context(), deadline.Add(-min(responseReserve, d/4)))
+		defer cancel()
+		next(w, r.WithContext(ctx))
+	})
+}
+
+// withIdleDeadline lets a request run as long as data keeps flowing: the
+// read and write deadlines are pushed forward whenever the body is read or
+// the response written, and only expire after idle without progress. Both
+// move together so the response to a long upload isn't written past a
+// deadline set when the request started.
+func withIdleDeadline(idle time.Duration, next http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		d := &idleDeadline{rc: http.NewResponseController(w), idle: idle}
+		d.extend()
+
+		r.Body = &idleReader{ReadCloser: r.Body, deadline: d}
+		next(&idleWriter{ResponseWriter: w, deadline: d}, r)
+	})
+}
+
+// idleDeadline pushes a connection's read and write deadlines forward.
+type idleDeadline struct {
+	rc       *http.ResponseController
+	idle     time.Duration
+	mu       sync.Mutex
+	extended time.Time
+}
+
+func (d *idleDeadline) extend() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	now := time.Now()
+	if now.Sub(d.extended) < min(deadlineRefreshInterval, d.idle/4) {
+		return
+	}
+	d.extended = now
+	_ = d.rc.SetReadDeadline(now.Add(d.idle))
+	_ = d.rc.SetWriteDeadline(now.Add(d.idle))
+}
+
+// idleReader extends the deadlines as the request body is read.
+type idleReader struct {
+	io.ReadCloser
+	deadline *idleDeadline
+}
+
+func (r *idleReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	if n > 0 {
+		r.deadline.extend()
+	}
+	return n, err
+}
+
+// idleWriter extends the deadlines as the response is written.
+type idleWriter struct {
+	http.ResponseWriter
+	deadline *idleDeadline
+}
+
+func (w *idleWriter) Write(p []byte) (int, error) {
+	w.deadline.extend()
+	return w.ResponseWriter.Write(p)
+}
+
+// ReadFrom passes files on to the connection in chunks, extending the
+// deadlines between them. The connection can then send them with
+// sendfile(2), which is lost if io.Copy goes through Write.
+func (w *idleWriter) ReadFrom(src io.Reader) (int64, error) {
+	// Unwrap io.CopyN's limit, which sendfile understands only one deep.
+	r, remaining := src, int64(-1)
+	lr, limited := src.(*io.LimitedReader)
+	if limited {
+		r, remaining = lr.R, lr.N
+	}
+	var total int64
+	var err error
+	for remaining != 0 {
+		chunk := int64(idleCopyChunk)
+		if remaining > 0 {
+			chunk = min(chunk, remaining)
+		}
+		w.deadline.extend()
+		var n int64
+		n, err = io.Copy(w.ResponseWriter, &io.LimitedReader{R: r, N: chunk})
+		total += n
+		if remaining > 0 {
+			remaining -= n
+		}
+		if err != nil || n < chunk {
+			break
+		}
+	}
+	if limited {
+		lr.N = remaining
+	}
+	return total, err
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (w *idleWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,168 @@
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// slowBody writes chunks separated by pause, then closes.
+func slowBody(chunks int, pause time.Duration) io.Reader {
+	pr, pw := io.Pipe()
+	go func() {
+		for i := 0; i < chunks; i++ {
+			time.Sleep(pause)
+			if _, err := pw.Write([]byte("chunk")); err != nil {
+				return
+			}
+		}
+		pw.Close()
+	}()
+	return pr
+}
+
+// readResult is what a test handler observed while reading the body.
+type readResult struct {
+	n   int64
+	err error
+}
+
+func readingHandler(results chan<- readResult) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		n, err := io.Copy(io.Discard, r.Body)
+		results <- readResult{n, err}
+		if err != nil {
+			http.Error(w, "read failed", http.StatusRequestTimeout)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+func waitResult(t *testing.T, results <-chan readResult) readResult {
+	t.Helper()
+	select {
+	case res := <-results:
+		return res
+	case <-time.After(5 * time.Second):
+		t.Fatal("handler did not finish")
+		return readResult{}
+	}
+}
+
+func TestWithIdleDeadline(t *testing.T) {
+	const idle = 300 * time.Millisecond
+
+	results := make(chan readResult, 1)
+	srv := httptest.NewServer(withIdleDeadline(idle, readingHandler(results)))
+	defer srv.Close()
+
+	t.Run("steady transfer outlives the idle timeout", func(t *testing.T) {
+		// 8 chunks 150ms apart take well over idle in total, which also
+		// checks that the response is still written after a long body read.
+		resp, err := http.Post(srv.URL, "application/octet-stream", slowBody(8, 150*time.Millisecond))
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		resp.Body.Close()
+		res := waitResult(t, results)
+		if res.err != nil || resp.StatusCode != http.StatusOK {
+			t.Fatalf("expected success, got status %d, read error %v", resp.StatusCode, res.err)
+		}
+		if want := int64(8 * len("chunk")); res.n != want {
+			t.Errorf("read %d bytes, want %d", res.n, want)
+		}
+	})
+
+	t.Run("stalled transfer is cut off", func(t *testing.T) {
+		resp, err := http.Post(srv.URL, "application/octet-stream", slowBody(2, 3*idle))
+		if err == nil {
+			resp.Body.Close()
+		}
+		if res := waitResult(t, results); res.err == nil {
+			t.Error("expected the stalled body read to fail")
+		}
+	})
+}
+
+func TestWithIdleDeadline_ServesFileRanges(t *testing.T) {
+	content := make([]byte, 3*idleCopyChunk+100)
+	for i := range content {
+		content[i] = byte(i % 251)
+	}
+	path := filepath.Join(t.TempDir(), "big.bin")
+	os.WriteFile(path, content, 0o644)
+
+	srv := httptest.NewServer(withIdleDeadline(time.Second, func(w http.ResponseWriter, r *http.Request) {
+		f, err := os.Open(path)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer f.Close()
+		http.ServeContent(w, r, "big.bin", time.Time{}, f)
+	}))
+	defer srv.Close()
+
+	for _, tt := range []struct {
+		rangeHeader string
+		want        []byte
+	}{
+		{"", content},
+		{"bytes=100-", content[100:]},
+		{fmt.Sprintf("bytes=10-%d", idleCopyChunk+9), content[10 : idleCopyChunk+10]},
+	} {
+		req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+		if tt.rangeHeader != "" {
+			req.Header.Set("Range", tt.rangeHeader)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		got, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil || !bytes.Equal(got, tt.want) {
+			t.Errorf("Range %q: got %d bytes (%v), want %d", tt.rangeHeader, len(got), err, len(tt.want))
+		}
+	}
+}
+
+func TestWithDeadline(t *testing.T) {
+	results := make(chan readResult, 1)
+	srv := httptest.NewServer(withDeadline(300*time.Millisecond, readingHandler(results)))
+	defer srv.Close()
+
+	// Steady but slow: a fixed deadline still ends it.
+	resp, err := http.Post(srv.URL, "application/octet-stream", slowBody(8, 100*time.Millisecond))
+	if err == nil {
+		resp.Body.Close()
+	}
+	if res := waitResult(t, results); res.err == nil {
+		t.Error("expected the body read to hit the request deadline")
+	}
+}
+
+func TestWithDeadline_EndsContextBeforeDeadline(t *testing.T) {
+	// A handler waiting on its context still gets its response out.
+	srv := httptest.NewServer(withDeadline(400*time.Millisecond, func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+		w.WriteHeader(http.StatusConflict)
+	}))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusConflict {
+		t.Errorf("status = %d, want the handler's %d", resp.StatusCode, http.StatusConflict)
+	}
+}
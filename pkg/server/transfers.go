@@ -0,0 +1,68 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/bethropolis/localgo/pkg/report"
+	"github.com/bethropolis/localgo/pkg/server/services"
+	"github.com/bethropolis/localgo/pkg/transfer"
+)
+
+// receiveProgressInterval is how often the progress of a receive is
+// passed to its transfer handle.
+const receiveProgressInterval = 500 * time.Millisecond
+
+// receiveTracker runs each receive session as a transfer of the server's
+// manager, so receives are listed and cancelled alongside sends.
+type receiveTracker struct {
+	s    *Server
+	ends sync.Map // session ID -> chan *report.Report
+}
+
+// trackReceives registers the tracker with the receive service.
+func (s *Server) trackReceives() {
+	t := &receiveTracker{s: s}
+	s.receiveService.AddStartHandler(t.start)
+	s.receiveService.AddSummaryHandler(t.end)
+}
+
+func (t *receiveTracker) start(session *services.ActiveReceiveSession) {
+	id := session.SessionID
+	ended := make(chan *report.Report, 1)
+	t.ends.Store(id, ended)
+	t.s.transfers.Start(t.s.shutdownCtx, transfer.Receive, session.Sender.Alias, func(ctx context.Context, progress func(transfer.Progress)) error {
+		ticker := time.NewTicker(receiveProgressInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case r := <-ended:
+				if r.Failed > 0 {
+					return fmt.Errorf("%d of %d file(s) not received", r.Failed, len(r.Files))
+				}
+				return nil
+			case <-ctx.Done():
+				t.s.receiveService.CloseSession(id)
+				return ctx.Err()
+			case <-ticker.C:
+				if st, ok := t.s.receiveService.SessionStatus(id); ok {
+					progress(transfer.Progress{Bytes: st.Bytes, Total: st.Size})
+				}
+			}
+		}
+	})
+}
+
+func (t *receiveTracker) end(r *report.Report) {
+	if ended, ok := t.ends.LoadAndDelete(r.SessionID); ok {
+		ended.(chan *report.Report) <- r
+	}
+}
+
+// Transfers returns the manager the server runs its sends and receives
+// through.
+func (s *Server) Transfers() *transfer.Manager {
+	return s.transfers
+}
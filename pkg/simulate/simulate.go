@@ -0,0 +1,225 @@
+// Package simulate runs fake LocalSend devices in this process, for testing
+// discovery, scripts and load without extra hardware. Each device is a real
+// LocalGo server with its own alias and TLS identity that announces itself
+// over multicast and accepts every transfer.
+package simulate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/bethropolis/localgo/pkg/config"
+	"github.com/bethropolis/localgo/pkg/crypto"
+	"github.com/bethropolis/localgo/pkg/discovery"
+	"github.com/bethropolis/localgo/pkg/history"
+	"github.com/bethropolis/localgo/pkg/model"
+	"github.com/bethropolis/localgo/pkg/server"
+	"go.uber.org/zap"
+)
+
+// MaxCount bounds how many devices one simulation runs.
+const MaxCount = 100
+
+// Options describes the devices to simulate.
+type Options struct {
+	Count            int
+	Prefix           string // aliases are "<Prefix> 1", "<Prefix> 2", ...; generated if empty
+	Port             int    // port of the first device, the rest follow; 0 means any free ports
+	HTTPS            bool
+	MulticastGroup   string
+	MulticastPort    int    // UDP port for discovery; 0 disables multicast
+	Interface        string // multicast interface, if set
+	AnnounceInterval time.Duration
+	Dir              string // received files go in a subdirectory per device; a temporary directory if empty
+}
+
+// Device is one simulated device.
+type Device struct {
+	Alias       string             `json:"alias"`
+	DeviceModel string             `json:"deviceModel"`
+	DeviceType  model.DeviceType   `json:"deviceType"`
+	Fingerprint string             `json:"fingerprint"`
+	Port        int                `json:"port"`
+	Protocol    model.ProtocolType `json:"protocol"`
+	Dir         string             `json:"dir"` // where the device saves what it receives
+
+	discovery *discovery.Service
+	cancel    context.CancelFunc
+	done      <-chan error
+}
+
+// Simulation is a set of running devices.
+type Simulation struct {
+	Devices []*Device
+	tempDir string // removed by Stop
+}
+
+// profiles are the kinds of device handed out in turn, so a discovery UI
+// shows a realistic mix.
+var profiles = []struct {
+	deviceType  model.DeviceType
+	deviceModel string
+}{
+	{model.DeviceTypeMobile, "Android"},
+	{model.DeviceTypeDesktop, "Linux"},
+	{model.DeviceTypeMobile, "iPhone"},
+	{model.DeviceTypeDesktop, "Windows"},
+	{model.DeviceTypeWeb, "Firefox"},
+	{model.DeviceTypeDesktop, "macOS"},
+	{model.DeviceTypeHeadless, "Raspberry Pi"},
+	{model.DeviceTypeServer, "NAS"},
+}
+
+// Start starts opts.Count devices. They run until ctx is done or Stop is
+// called; if any fails to start, those already started are stopped.
+func Start(ctx context.Context, opts Options, logger *zap.SugaredLogger) (*Simulation, error) {
+	if opts.Count < 1 || opts.Count > MaxCount {
+		return nil, fmt.Errorf("count must be between 1 and %d", MaxCount)
+	}
+	if strings.ContainsAny(opts.Prefix, `/\`) {
+		return nil, fmt.Errorf("invalid alias prefix %q", opts.Prefix)
+	}
+	if logger == nil {
+		logger = zap.NewNop().Sugar()
+	}
+
+	sim := &Simulation{}
+	dir := opts.Dir
+	if dir == "" {
+		tmp, err := os.MkdirTemp("", "localgo-simulate-*")
+		if err != nil {
+			return nil, fmt.Errorf("failed to create a directory for received files: %w", err)
+		}
+		sim.tempDir, dir = tmp, tmp
+	}
+
+	aliases := make(map[string]bool)
+	for i := 0; i < opts.Count; i++ {
+		alias := fmt.Sprintf("%s %d", opts.Prefix, i+1)
+		if opts.Prefix == "" {
+			// There are far more word pairs than MaxCount, so this ends.
+			for alias = config.GenerateAlias(); aliases[alias]; alias = config.GenerateAlias() {
+			}
+		}
+		aliases[alias] = true
+
+		port := 0
+		if opts.Port > 0 {
+			port = opts.Port + i
+		}
+		device, err := startDevice(ctx, opts, alias, i, port, dir, logger.Named(alias))
+		if err != nil {
+			sim.Stop()
+			return nil, fmt.Errorf("failed to start %s: %w", alias, err)
+		}
+		sim.Devices = append(sim.Devices, device)
+	}
+	return sim, nil
+}
+
+func startDevice(ctx context.Context, opts Options, alias string, index, port int, dir string, logger *zap.SugaredLogger) (*Device, error) {
+	security, err := crypto.GenerateSecurityContext(alias, logger)
+	if err != nil {
+		return nil, err
+	}
+	profile := profiles[index%len(profiles)]
+	deviceModel := profile.deviceModel
+	cfg := &config.Config{
+		Alias:             alias,
+		Port:              port,
+		HttpsEnabled:      opts.HTTPS,
+		MulticastGroup:    opts.MulticastGroup,
+		DeviceModel:       &deviceModel,
+		DeviceType:        profile.deviceType,
+		SecurityContext:   security,
+		RandomFingerprint: security.CertificateHash,
+		DownloadDir:       filepath.Join(dir, alias),
+		AutoAccept:        true,
+		NoClipboard:       true,
+		Quiet:             true,
+		HistoryFile:       history.DisabledSentinel,
+		SessionFile:       history.DisabledSentinel,
+	}
+	if err := os.MkdirAll(cfg.DownloadDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create download directory: %w", err)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	srv := server.NewServer(cfg, logger)
+	done := make(chan error, 1)
+	ready := make(chan struct{}, 1)
+	go func() {
+		done <- srv.Start(ctx, ready)
+	}()
+	select {
+	case err := <-done:
+		cancel()
+		return nil, err
+	case <-ready:
+	}
+
+	// The server has replaced cfg.Port with the port it bound.
+	device := &Device{
+		Alias:       alias,
+		DeviceModel: deviceModel,
+		DeviceType:  profile.deviceType,
+		Fingerprint: cfg.GetFingerprint(),
+		Port:        cfg.Port,
+		Protocol:    cfg.Protocol(),
+		Dir:         cfg.DownloadDir,
+		cancel:      cancel,
+		done:        done,
+	}
+	if opts.MulticastPort == 0 {
+		return device, nil
+	}
+
+	svcConfig := discovery.DefaultServiceConfig()
+	svcConfig.MulticastConfig.Port = opts.MulticastPort
+	svcConfig.MulticastConfig.MulticastAddr = fmt.Sprintf("%s:%d", opts.MulticastGroup, opts.MulticastPort)
+	svcConfig.MulticastConfig.InterfaceName = opts.Interface
+	if opts.AnnounceInterval > 0 {
+		svcConfig.AnnounceInterval = opts.AnnounceInterval
+	}
+	multicast := discovery.NewMulticastDiscovery(svcConfig.MulticastConfig, cfg.ToMulticastDto(false), logger)
+	multicast.SetHTTPDiscoverer(discovery.NewHTTPDiscovery(nil, cfg.ToRegisterDto(), nil, logger))
+	device.discovery = discovery.NewService(svcConfig, multicast, logger)
+	if err := device.discovery.Start(ctx, cfg.ToMulticastDto(false)); err != nil {
+		device.discovery = nil
+		device.stop()
+		return nil, fmt.Errorf("discovery service failed: %w", err)
+	}
+	return device, nil
+}
+
+// stop shuts the device down and waits for its server to finish.
+func (d *Device) stop() error {
+	if d.discovery != nil {
+		d.discovery.Stop()
+	}
+	d.cancel()
+	return <-d.done
+}
+
+// Stop shuts every device down and waits for them to finish, then removes
+// the temporary directory of received files, if one was created.
+func (sim *Simulation) Stop() error {
+	var errs []error
+	for _, d := range sim.Devices {
+		if err := d.stop(); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", d.Alias, err))
+		}
+	}
+	sim.Devices = nil
+	if sim.tempDir != "" {
+		if err := os.RemoveAll(sim.tempDir); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,59 @@
+package simulate
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/bethropolis/localgo/pkg/model"
+)
+
+func TestStart(t *testing.T) {
+	sim, err := Start(context.Background(), Options{Count: 3, Prefix: "Sim"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tempDir := sim.tempDir
+
+	fingerprints := make(map[string]bool)
+	for i, d := range sim.Devices {
+		if want := fmt.Sprintf("Sim %d", i+1); d.Alias != want {
+			t.Errorf("device %d: alias %q, want %q", i, d.Alias, want)
+		}
+		resp, err := http.Get(fmt.Sprintf("http://127.0.0.1:%d/api/localsend/v2/info", d.Port))
+		if err != nil {
+			t.Fatalf("%s: %v", d.Alias, err)
+		}
+		var info model.InfoDto
+		err = json.NewDecoder(resp.Body).Decode(&info)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatalf("%s: %v", d.Alias, err)
+		}
+		if info.Alias != d.Alias || info.Fingerprint != d.Fingerprint || info.DeviceType != d.DeviceType {
+			t.Errorf("%s answered %+v", d.Alias, info)
+		}
+		fingerprints[d.Fingerprint] = true
+	}
+	if len(fingerprints) != 3 {
+		t.Errorf("got %d distinct fingerprints, want 3", len(fingerprints))
+	}
+
+	if err := sim.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(tempDir); !os.IsNotExist(err) {
+		t.Errorf("Stop should remove %s", tempDir)
+	}
+}
+
+func TestStart_InvalidOptions(t *testing.T) {
+	for _, opts := range []Options{{Count: 0}, {Count: MaxCount + 1}, {Count: 1, Prefix: "a/b"}} {
+		if _, err := Start(context.Background(), opts, nil); err == nil {
+			t.Errorf("Start(%+v) should fail", opts)
+		}
+	}
+}
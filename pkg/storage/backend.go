@@ -0,0 +1,30 @@
+package storage
+
+import (
+	"io"
+
+	"go.uber.org/zap"
+)
+
+// Backend is where the receiver writes files. Paths are those the receiver
+// chose under its download directory; a backend may map them elsewhere.
+type Backend interface {
+	// Save writes stream to filePath, with the semantics of
+	// SaveStreamToFileWithMetadata.
+	Save(stream io.Reader, filePath string, fileSize int64, modified, accessed, expectedSha256 *string, opts *SaveOptions, onProgress func(bytesWritten int64), logger *zap.SugaredLogger) error
+	// FreeSpace returns the bytes available for files saved under dir.
+	FreeSpace(dir string) (uint64, error)
+}
+
+// Disk is the Backend that saves to the local file system.
+var Disk Backend = diskBackend{}
+
+type diskBackend struct{}
+
+func (diskBackend) Save(stream io.Reader, filePath string, fileSize int64, modified, accessed, expectedSha256 *string, opts *SaveOptions, onProgress func(bytesWritten int64), logger *zap.SugaredLogger) error {
+	return SaveStreamToFileWithMetadata(stream, filePath, fileSize, modified, accessed, expectedSha256, opts, onProgress, logger)
+}
+
+func (diskBackend) FreeSpace(dir string) (uint64, error) {
+	return CheckFreeSpace(dir)
+}
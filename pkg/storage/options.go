@@ -0,0 +1,119 @@
+package storage
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// SaveOptions are optional steps of a save.
+type SaveOptions struct {
+	// Scanner checks the file before it is moved into place.
+	Scanner *Scanner
+	// Encrypt wraps the file being written, such as to encrypt it at
+	// rest. The writer it returns is closed once everything is written.
+	// With a Scanner too, the content is staged in the clear and scanned
+	// first, then encrypted.
+	Encrypt func(w io.Writer) (io.WriteCloser, error)
+	// Hashed, if set, is given the SHA-256 of the content, hex-encoded.
+	Hashed func(sha256 string)
+	// Existing looks up a file already saved with the content's SHA-256
+	// and size. If it has the same content, the new file is hard-linked to
+	// it instead of being stored again. It returns "" if there is none.
+	Existing func(sha256 string, size int64) string
+}
+
+func (o *SaveOptions) scanner() *Scanner {
+	if o == nil || o.Scanner == nil || o.Scanner.Scan == nil {
+		return nil
+	}
+	return o.Scanner
+}
+
+func (o *SaveOptions) wantsHash() bool {
+	return o != nil && (o.Hashed != nil || o.Existing != nil)
+}
+
+// linkExisting hard-links filePath to a saved file with the same content as
+// tempPath, if Existing finds one, and reports whether it did.
+func (o *SaveOptions) linkExisting(tempPath, filePath, sum string, size int64) bool {
+	if o == nil || o.Existing == nil {
+		return false
+	}
+	existing := o.Existing(sum, size)
+	if existing == "" || !sameContent(existing, tempPath) {
+		return false
+	}
+	return os.Link(existing, filePath) == nil
+}
+
+// sameContent reports whether the files at a and b hold the same bytes.
+func sameContent(a, b string) bool {
+	fa, err := os.Open(a)
+	if err != nil {
+		return false
+	}
+	defer fa.Close()
+	fb, err := os.Open(b)
+	if err != nil {
+		return false
+	}
+	defer fb.Close()
+	bufA, bufB := make([]byte, 64*1024), make([]byte, 64*1024)
+	for {
+		na, errA := io.ReadFull(fa, bufA)
+		nb, errB := io.ReadFull(fb, bufB)
+		if na != nb || !bytes.Equal(bufA[:na], bufB[:nb]) {
+			return false
+		}
+		if errA != nil || errB != nil {
+			return errA == errB && (errA == io.EOF || errA == io.ErrUnexpectedEOF)
+		}
+	}
+}
+
+// Scanner checks a received file, such as with a virus scanner, before it
+// is moved into place.
+type Scanner struct {
+	// Scan is given the complete temporary file and returns an error if
+	// the file must not be kept.
+	Scan func(path string) error
+	// QuarantineDir is where files that fail the scan are moved.
+	QuarantineDir string
+}
+
+// ScanError is returned by a save whose file failed its scan.
+type ScanError struct {
+	Path string // where the file was quarantined; "" if it was removed instead
+	Err  error  // why the scan failed
+}
+
+func (e *ScanError) Error() string {
+	return fmt.Sprintf("file failed the scan: %v", e.Err)
+}
+
+func (e *ScanError) Unwrap() error {
+	return e.Err
+}
+
+// quarantine moves tempPath, the file that would have been saved as
+// filePath, into the quarantine directory under filePath's name. A file
+// that cannot be moved there is left for the caller to remove, and ""
+// is returned.
+func (s *Scanner) quarantine(tempPath, filePath string) string {
+	if s.QuarantineDir == "" {
+		return ""
+	}
+	if err := os.MkdirAll(s.QuarantineDir, 0700); err != nil {
+		return ""
+	}
+	// Nobody should run or open a quarantined file by accident.
+	_ = os.Chmod(tempPath, 0600)
+	dest := ResolveDuplicateFilename(s.QuarantineDir, filepath.Base(filePath))
+	if err := os.Rename(tempPath, dest); err != nil {
+		return ""
+	}
+	return dest
+}
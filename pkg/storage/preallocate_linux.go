@@ -0,0 +1,23 @@
+//go:build linux
+
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// preallocate reserves size bytes of disk for f without changing its
+// length, so a large file is laid out in one piece and a full disk is
+// found before the transfer starts. Filesystems that cannot preallocate
+// are left to allocate as the file is written.
+func preallocate(f *os.File, size int64) error {
+	err := unix.Fallocate(int(f.Fd()), unix.FALLOC_FL_KEEP_SIZE, 0, size)
+	if errors.Is(err, unix.ENOSPC) || errors.Is(err, unix.EDQUOT) {
+		return fmt.Errorf("%w for %d bytes", ErrInsufficientSpace, size)
+	}
+	return nil
+}
@@ -0,0 +1,11 @@
+//go:build !linux
+
+package storage
+
+import "os"
+
+// preallocate is a no-op where fallocate is not available; the file is
+// allocated as it is written.
+func preallocate(f *os.File, size int64) error {
+	return nil
+}
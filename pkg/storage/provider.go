@@ -0,0 +1,134 @@
+package storage
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// FileInfo describes the content a FileProvider offers.
+type FileInfo struct {
+	Name    string // base name announced to the peer
+	Size    int64
+	ModTime time.Time // zero if unknown
+}
+
+// FileProvider is the source of a file that is sent or offered for
+// download. Transfer code reads files only through it, so content need not
+// live on disk.
+type FileProvider interface {
+	// Open returns the content from its start. Each call returns a new
+	// reader, so a failed upload can be tried again. A reader that also
+	// implements io.Seeker lets downloads be resumed.
+	Open() (io.ReadCloser, error)
+	// Stat describes the content without reading it.
+	Stat() (FileInfo, error)
+	// Hash returns the hex SHA-256 of the content, or "" if it is too
+	// costly to learn.
+	Hash() (string, error)
+}
+
+// DiskFile provides the file at path.
+func DiskFile(path string) FileProvider {
+	return diskFile(path)
+}
+
+type diskFile string
+
+func (f diskFile) Open() (io.ReadCloser, error) {
+	return os.Open(string(f))
+}
+
+func (f diskFile) Stat() (FileInfo, error) {
+	fi, err := os.Stat(string(f))
+	if err != nil {
+		return FileInfo{}, err
+	}
+	if fi.IsDir() {
+		return FileInfo{}, fmt.Errorf("%s is a directory", string(f))
+	}
+	return FileInfo{Name: filepath.Base(string(f)), Size: fi.Size(), ModTime: fi.ModTime()}, nil
+}
+
+func (f diskFile) Hash() (string, error) {
+	return hashProvider(f)
+}
+
+// MemoryFile provides content held in memory.
+func MemoryFile(name string, content []byte) FileProvider {
+	return &memoryFile{name: name, content: content, modTime: time.Now()}
+}
+
+type memoryFile struct {
+	name    string
+	content []byte
+	modTime time.Time
+}
+
+func (f *memoryFile) Open() (io.ReadCloser, error) {
+	return readSeekNopCloser{bytes.NewReader(f.content)}, nil
+}
+
+func (f *memoryFile) Stat() (FileInfo, error) {
+	return FileInfo{Name: f.name, Size: int64(len(f.content)), ModTime: f.modTime}, nil
+}
+
+func (f *memoryFile) Hash() (string, error) {
+	sum := sha256.Sum256(f.content)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// GeneratedFile provides content written by generate each time it is
+// opened, such as a zip built on the fly. size is the exact number of bytes
+// generate writes; the peer is told it up front. generate must write the
+// same content every time.
+func GeneratedFile(name string, size int64, generate func(w io.Writer) error) FileProvider {
+	return &generatedFile{name: name, size: size, generate: generate}
+}
+
+type generatedFile struct {
+	name     string
+	size     int64
+	generate func(w io.Writer) error
+}
+
+func (f *generatedFile) Open() (io.ReadCloser, error) {
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(f.generate(pw))
+	}()
+	return pr, nil
+}
+
+func (f *generatedFile) Stat() (FileInfo, error) {
+	return FileInfo{Name: f.name, Size: f.size}, nil
+}
+
+func (f *generatedFile) Hash() (string, error) {
+	return hashProvider(f)
+}
+
+// hashProvider reads the content of p to hash it.
+func hashProvider(p FileProvider) (string, error) {
+	r, err := p.Open()
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+type readSeekNopCloser struct {
+	io.ReadSeeker
+}
+
+func (readSeekNopCloser) Close() error { return nil }
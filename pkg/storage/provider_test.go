@@ -0,0 +1,61 @@
+package storage
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileProviders(t *testing.T) {
+	content := []byte("provided content")
+	sum := sha256.Sum256(content)
+	want := hex.EncodeToString(sum[:])
+
+	path := filepath.Join(t.TempDir(), "disk.txt")
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		t.Fatal(err)
+	}
+	generated := 0
+	providers := map[string]FileProvider{
+		"disk.txt":   DiskFile(path),
+		"memory.txt": MemoryFile("memory.txt", content),
+		"gen.txt": GeneratedFile("gen.txt", int64(len(content)), func(w io.Writer) error {
+			generated++
+			_, err := w.Write(content)
+			return err
+		}),
+	}
+	for name, p := range providers {
+		info, err := p.Stat()
+		if err != nil || info.Name != name || info.Size != int64(len(content)) {
+			t.Errorf("%s: Stat = %+v, %v", name, info, err)
+		}
+		// Every Open starts from the beginning, so uploads can be retried.
+		for range 2 {
+			r, err := p.Open()
+			if err != nil {
+				t.Fatalf("%s: Open: %v", name, err)
+			}
+			data, err := io.ReadAll(r)
+			r.Close()
+			if err != nil || string(data) != string(content) {
+				t.Errorf("%s: read %q, %v", name, data, err)
+			}
+		}
+		if got, err := p.Hash(); err != nil || got != want {
+			t.Errorf("%s: Hash = %s, %v; want %s", name, got, err, want)
+		}
+	}
+	if generated != 3 {
+		t.Errorf("generated %d times, want once per Open and once for Hash", generated)
+	}
+}
+
+func TestDiskFile_Directory(t *testing.T) {
+	if _, err := DiskFile(t.TempDir()).Stat(); err == nil {
+		t.Error("Stat of a directory succeeded")
+	}
+}
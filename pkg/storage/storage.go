@@ -1,9 +1,11 @@
 package storage
 
 import (
+	"bufio"
 	"crypto/rand"
 	"crypto/sha256"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"hash"
 	"io"
@@ -11,6 +13,7 @@ import (
 	"path/filepath"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"go.uber.org/zap"
@@ -32,6 +35,53 @@ var largeBufferPool = sync.Pool{
 	},
 }
 
+const (
+	// DefaultBufferSize is how much of a received file is gathered in
+	// memory before it is written to disk.
+	DefaultBufferSize = 1024 * 1024
+	// MinBufferSize and MaxBufferSize bound SetBufferSize.
+	MinBufferSize = 4 * 1024
+	MaxBufferSize = 64 * 1024 * 1024
+	// ProgressInterval is the least time between two progress callbacks
+	// while a file is being saved.
+	ProgressInterval = 100 * time.Millisecond
+)
+
+// ErrInsufficientSpace is returned when there is not enough disk space to
+// save a file of the declared size.
+var ErrInsufficientSpace = errors.New("not enough disk space")
+
+var (
+	// writerPool holds the disk write buffers, all of the current buffer size.
+	writerPool atomic.Pointer[sync.Pool]
+	// syncFiles makes saved files durable before they are reported done.
+	syncFiles atomic.Bool
+)
+
+func init() {
+	SetBufferSize(DefaultBufferSize)
+}
+
+// SetBufferSize sets how much of a received file is gathered in memory
+// before it is written to disk, clamped to MinBufferSize..MaxBufferSize.
+// Larger buffers mean fewer write calls on fast links. Transfers already
+// under way keep the buffer they started with.
+func SetBufferSize(n int) {
+	n = min(max(n, MinBufferSize), MaxBufferSize)
+	writerPool.Store(&sync.Pool{
+		New: func() interface{} {
+			return bufio.NewWriterSize(nil, n)
+		},
+	})
+}
+
+// SetSync sets whether each saved file, and the directory it is moved
+// into, is flushed to disk before the save returns. This survives a crash
+// or power loss at some cost in speed.
+func SetSync(on bool) {
+	syncFiles.Store(on)
+}
+
 // CheckFreeSpace returns the available bytes on the volume containing the specified path.
 func CheckFreeSpace(dirPath string) (uint64, error) {
 	cleanPath := filepath.Clean(dirPath)
@@ -50,24 +100,31 @@ func EnsureDirExists(path string) error {
 	return nil
 }
 
+// PartialPath returns the temporary file a transfer to filePath is written
+// to until it completes.
+func PartialPath(filePath string) string {
+	return filePath + ".tmp"
+}
+
 // SaveStreamToFile saves an io.Reader stream to a specified file path.
 // It creates necessary directories.
 // It reports progress via the onProgress callback (bytes written).
 func SaveStreamToFile(stream io.Reader, filePath string, onProgress func(bytesWritten int64)) error {
-	return SaveStreamToFileWithMetadata(stream, filePath, 0, nil, nil, nil, onProgress, nil)
+	return SaveStreamToFileWithMetadata(stream, filePath, 0, nil, nil, nil, nil, onProgress, nil)
 }
 
 // SaveStreamToFileWithMetadata saves an io.Reader stream and restores optional timestamps.
 // If expectedSha256 is provided, the stream is verified against it after the copy succeeds.
+// opts, if provided, adds steps such as scanning or encrypting the file.
 // fileSize is used to select an optimal copy buffer size.
-func SaveStreamToFileWithMetadata(stream io.Reader, filePath string, fileSize int64, modified *string, accessed *string, expectedSha256 *string, onProgress func(bytesWritten int64), logger *zap.SugaredLogger) error {
+func SaveStreamToFileWithMetadata(stream io.Reader, filePath string, fileSize int64, modified *string, accessed *string, expectedSha256 *string, opts *SaveOptions, onProgress func(bytesWritten int64), logger *zap.SugaredLogger) error {
 	dir := filepath.Dir(filePath)
 	if err := EnsureDirExists(dir); err != nil {
 		return err
 	}
 
 	// Write to a temporary file first, then atomically rename on success
-	tempPath := filePath + ".tmp"
+	tempPath := PartialPath(filePath)
 	outFile, err := os.Create(tempPath)
 	if err != nil {
 		return fmt.Errorf("failed to create temp file: %w", err)
@@ -81,9 +138,54 @@ func SaveStreamToFileWithMetadata(stream io.Reader, filePath string, fileSize in
 		}
 	}()
 
+	if fileSize > 0 {
+		if err := preallocate(outFile, fileSize); err != nil {
+			return err
+		}
+	}
+
+	// Encrypt what is written, if asked to, before it reaches the file. A
+	// file that is also scanned is received in the clear into a private
+	// staging file instead, so the scanner sees its content, and encrypted
+	// into place once scanned.
+	var fileOut io.Writer = outFile
+	var encrypter io.WriteCloser
+	var staged *os.File
+	if opts != nil && opts.Encrypt != nil && opts.scanner() != nil {
+		if staged, err = os.CreateTemp(dir, ".localgo-scan-*"); err != nil {
+			return fmt.Errorf("failed to create staging file: %w", err)
+		}
+		defer func() {
+			staged.Close()
+			_ = os.Remove(staged.Name())
+		}()
+		fileOut = staged
+	} else if opts != nil && opts.Encrypt != nil {
+		if encrypter, err = opts.Encrypt(outFile); err != nil {
+			return fmt.Errorf("failed to start encryption: %w", err)
+		}
+		fileOut = encrypter
+	}
+
+	// Gather writes into one large buffer, unless the file is known to be
+	// small enough that it would not be filled.
+	var out io.Writer = fileOut
+	var buffered *bufio.Writer
+	if fileSize <= 0 || fileSize > 64*1024 {
+		pool := writerPool.Load()
+		buffered = pool.Get().(*bufio.Writer)
+		buffered.Reset(fileOut)
+		defer func() {
+			buffered.Reset(nil)
+			pool.Put(buffered)
+		}()
+		out = buffered
+	}
+
 	progressWriter := &ProgressWriter{
-		Writer:     outFile,
+		Writer:     out,
 		OnProgress: onProgress,
+		Interval:   ProgressInterval,
 	}
 
 	// Select buffer pool based on file size
@@ -97,23 +199,51 @@ func SaveStreamToFileWithMetadata(stream io.Reader, filePath string, fileSize in
 	// Optional SHA-256 hashing via TeeReader
 	var hasher hash.Hash
 	var hashingReader io.Reader = stream
-	if expectedSha256 != nil && *expectedSha256 != "" {
+	if (expectedSha256 != nil && *expectedSha256 != "") || opts.wantsHash() {
 		hasher = sha256.New()
 		hashingReader = io.TeeReader(stream, hasher)
 	}
 
-	_, err = io.CopyBuffer(progressWriter, hashingReader, *bufPtr)
+	written, err := io.CopyBuffer(progressWriter, hashingReader, *bufPtr)
 	if err != nil {
 		return fmt.Errorf("failed to copy stream: %w", err)
 	}
+	if buffered != nil {
+		if err := buffered.Flush(); err != nil {
+			return fmt.Errorf("failed to write temp file: %w", err)
+		}
+	}
+	if encrypter != nil {
+		if err := encrypter.Close(); err != nil {
+			return fmt.Errorf("failed to write temp file: %w", err)
+		}
+	}
+	progressWriter.Report()
+
+	// The encrypted copy is what is kept, or quarantined if the scan fails.
+	var scanErr error
+	if staged != nil {
+		scanErr = opts.Scanner.Scan(staged.Name())
+		if err := encryptStaged(staged, outFile, opts.Encrypt); err != nil {
+			return fmt.Errorf("failed to encrypt temp file: %w", err)
+		}
+	}
 
+	if syncFiles.Load() {
+		if err := outFile.Sync(); err != nil {
+			return fmt.Errorf("failed to sync temp file: %w", err)
+		}
+	}
 	if err := outFile.Close(); err != nil {
 		return fmt.Errorf("failed to close temp file: %w", err)
 	}
 
 	// Verify SHA-256 checksum if the sender provided one
+	var calculatedHash string
 	if hasher != nil {
-		calculatedHash := hex.EncodeToString(hasher.Sum(nil))
+		calculatedHash = hex.EncodeToString(hasher.Sum(nil))
+	}
+	if expectedSha256 != nil && *expectedSha256 != "" {
 		if calculatedHash != *expectedSha256 {
 			return fmt.Errorf("integrity violation: SHA-256 mismatch (got %s, expected %s)", calculatedHash, *expectedSha256)
 		}
@@ -122,6 +252,32 @@ func SaveStreamToFileWithMetadata(stream io.Reader, filePath string, fileSize in
 		}
 	}
 
+	if scanner := opts.scanner(); scanner != nil {
+		if staged == nil {
+			scanErr = scanner.Scan(tempPath)
+		}
+		if scanErr != nil {
+			quarantined := scanner.quarantine(tempPath, filePath)
+			if quarantined != "" {
+				cleanup = false
+			}
+			return &ScanError{Path: quarantined, Err: scanErr}
+		}
+	}
+
+	if opts.wantsHash() {
+		if opts.Hashed != nil {
+			opts.Hashed(calculatedHash)
+		}
+		// The copy is dropped; the linked file keeps its own timestamps.
+		if opts.linkExisting(tempPath, filePath, calculatedHash, written) {
+			if logger != nil {
+				logger.Infow("Linked to an identical file", "path", filePath)
+			}
+			return nil
+		}
+	}
+
 	// Apply timestamps to the temp file before promotion
 	if modified != nil || accessed != nil {
 		mtime := time.Now()
@@ -161,6 +317,11 @@ func SaveStreamToFileWithMetadata(stream io.Reader, filePath string, fileSize in
 		return fmt.Errorf("failed to finalize transfer: %w", err)
 	}
 	cleanup = false
+	if syncFiles.Load() {
+		if err := syncDir(dir); err != nil && logger != nil {
+			logger.Warnw("Failed to sync directory", "path", dir, "error", err)
+		}
+	}
 
 	if logger != nil {
 		logger.Infow("Successfully saved stream", "path", filePath)
@@ -168,37 +329,77 @@ func SaveStreamToFileWithMetadata(stream io.Reader, filePath string, fileSize in
 	return nil
 }
 
+// encryptStaged writes the content of staged, encrypted with encrypt, to out.
+func encryptStaged(staged *os.File, out io.Writer, encrypt func(io.Writer) (io.WriteCloser, error)) error {
+	if _, err := staged.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	w, err := encrypt(out)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(w, staged); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
+
 // ProgressWriter is a wrapper around io.Writer that calls a callback on Write.
+// With Interval set, the callback runs at most once per interval; call
+// Report at the end to deliver the final count.
 type ProgressWriter struct {
 	Writer       io.Writer
 	BytesWritten int64
 	OnProgress   func(bytesWritten int64)
+	Interval     time.Duration
+
+	lastReport time.Time
 }
 
 // Write implements the io.Writer interface.
 func (pw *ProgressWriter) Write(p []byte) (n int, err error) {
 	n, err = pw.Writer.Write(p)
 	pw.BytesWritten += int64(n)
+	if pw.OnProgress != nil && (pw.Interval <= 0 || time.Since(pw.lastReport) >= pw.Interval) {
+		pw.Report()
+	}
+	return n, err
+}
+
+// Report calls the callback with the bytes written so far.
+func (pw *ProgressWriter) Report() {
 	if pw.OnProgress != nil {
+		pw.lastReport = time.Now()
 		pw.OnProgress(pw.BytesWritten)
 	}
-	return n, err
 }
 
 // ResolveDuplicateFilename finds an available filename by appending numbers if the file exists.
 func ResolveDuplicateFilename(dir, baseName string) string {
+	return ResolveFreeFilename(dir, baseName, nil)
+}
+
+// ResolveFreeFilename is ResolveDuplicateFilename that also passes over
+// paths for which inUse reports true, such as those that uploads still in
+// progress will be saved to.
+func ResolveFreeFilename(dir, baseName string, inUse func(path string) bool) string {
 	ext := filepath.Ext(baseName)
 	nameWithoutExt := strings.TrimSuffix(baseName, ext)
+	free := func(path string) bool {
+		_, err := os.Stat(path)
+		return os.IsNotExist(err) && (inUse == nil || !inUse(path))
+	}
 
 	candidate := filepath.Join(dir, baseName)
-	if _, err := os.Stat(candidate); os.IsNotExist(err) {
+	if free(candidate) {
 		return candidate
 	}
 
 	for i := 1; i <= 999; i++ {
 		newName := fmt.Sprintf("%s (%d)%s", nameWithoutExt, i, ext)
 		candidate = filepath.Join(dir, newName)
-		if _, err := os.Stat(candidate); os.IsNotExist(err) {
+		if free(candidate) {
 			return candidate
 		}
 	}
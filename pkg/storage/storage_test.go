@@ -1,6 +1,12 @@
 package storage
 
 import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
@@ -76,6 +82,7 @@ func TestSaveStreamToFileWithMetadata(t *testing.T) {
 		&accTime,
 		nil,
 		nil,
+		nil,
 		testLogger,
 	)
 	if err != nil {
@@ -113,6 +120,151 @@ func TestSaveStreamToFile_ProgressCallback(t *testing.T) {
 	}
 }
 
+// trickleReader returns its data a few bytes at a time, as a slow network
+// connection would.
+type trickleReader struct {
+	r     io.Reader
+	chunk int
+}
+
+func (t *trickleReader) Read(p []byte) (int, error) {
+	return t.r.Read(p[:min(len(p), t.chunk)])
+}
+
+func TestSaveStreamToFile_BufferedAndThrottled(t *testing.T) {
+	defer SetBufferSize(DefaultBufferSize)
+	SetBufferSize(1)
+	if size := writerPool.Load().Get().(*bufio.Writer).Size(); size != MinBufferSize {
+		t.Errorf("buffer size %d, want it raised to %d", size, MinBufferSize)
+	}
+	SetBufferSize(256 * 1024)
+
+	content := bytes.Repeat([]byte("0123456789abcdef"), 64*1024) // 1 MiB
+	filePath := filepath.Join(t.TempDir(), "big.bin")
+	var calls int
+	var last int64
+	err := SaveStreamToFileWithMetadata(&trickleReader{bytes.NewReader(content), 512}, filePath, int64(len(content)), nil, nil, nil, nil, func(n int64) {
+		calls++
+		last = n
+	}, testLogger)
+	if err != nil {
+		t.Fatalf("SaveStreamToFileWithMetadata failed: %v", err)
+	}
+
+	got, _ := os.ReadFile(filePath)
+	if !bytes.Equal(got, content) {
+		t.Error("saved content differs from the stream")
+	}
+	if last != int64(len(content)) {
+		t.Errorf("last progress %d, want %d", last, len(content))
+	}
+	// 2048 writes of 512 bytes, but far fewer callbacks.
+	if calls > 100 {
+		t.Errorf("progress callback ran %d times, want it throttled", calls)
+	}
+}
+
+func TestSaveStreamToFile_ShortStreamWithSync(t *testing.T) {
+	defer SetSync(false)
+	SetSync(true)
+
+	// Space reserved for the declared size must not pad a shorter file.
+	filePath := filepath.Join(t.TempDir(), "short.bin")
+	err := SaveStreamToFileWithMetadata(strings.NewReader("only this"), filePath, 1<<20, nil, nil, nil, nil, nil, testLogger)
+	if err != nil {
+		t.Fatalf("SaveStreamToFileWithMetadata failed: %v", err)
+	}
+	got, _ := os.ReadFile(filePath)
+	if string(got) != "only this" {
+		t.Errorf("saved %d bytes, want %q", len(got), "only this")
+	}
+}
+
+func TestSaveStreamToFile_Scan(t *testing.T) {
+	dir := t.TempDir()
+	quarantineDir := filepath.Join(dir, "quarantine")
+	var scanned []string
+	opts := &SaveOptions{Scanner: &Scanner{
+		Scan: func(path string) error {
+			scanned = append(scanned, path)
+			data, _ := os.ReadFile(path)
+			if strings.Contains(string(data), "virus") {
+				return errors.New("virus found")
+			}
+			return nil
+		},
+		QuarantineDir: quarantineDir,
+	}}
+
+	clean := filepath.Join(dir, "clean.txt")
+	if err := SaveStreamToFileWithMetadata(strings.NewReader("hello"), clean, 5, nil, nil, nil, opts, nil, testLogger); err != nil {
+		t.Fatalf("saving a clean file: %v", err)
+	}
+	if len(scanned) != 1 || scanned[0] != PartialPath(clean) {
+		t.Errorf("scanned %v, want the temp file %s", scanned, PartialPath(clean))
+	}
+
+	infected := filepath.Join(dir, "bad.exe")
+	err := SaveStreamToFileWithMetadata(strings.NewReader("a virus"), infected, 7, nil, nil, nil, opts, nil, testLogger)
+	var scanErr *ScanError
+	if !errors.As(err, &scanErr) {
+		t.Fatalf("saving an infected file: err = %v, want a *ScanError", err)
+	}
+	if want := filepath.Join(quarantineDir, "bad.exe"); scanErr.Path != want {
+		t.Errorf("quarantined at %q, want %q", scanErr.Path, want)
+	}
+	if data, _ := os.ReadFile(scanErr.Path); string(data) != "a virus" {
+		t.Errorf("quarantined content = %q, want %q", data, "a virus")
+	}
+	for _, path := range []string{infected, PartialPath(infected)} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("%s exists after a failed scan", path)
+		}
+	}
+}
+
+func TestSaveStreamToFile_LinksExisting(t *testing.T) {
+	dir := t.TempDir()
+	first := filepath.Join(dir, "first.bin")
+	if err := SaveStreamToFile(strings.NewReader("same content"), first, nil); err != nil {
+		t.Fatalf("saving the first file: %v", err)
+	}
+
+	var sum string
+	opts := &SaveOptions{
+		Hashed:   func(s string) { sum = s },
+		Existing: func(string, int64) string { return first },
+	}
+	second := filepath.Join(dir, "second.bin")
+	if err := SaveStreamToFileWithMetadata(strings.NewReader("same content"), second, 12, nil, nil, nil, opts, nil, testLogger); err != nil {
+		t.Fatalf("saving the second file: %v", err)
+	}
+	if want := fmt.Sprintf("%x", sha256.Sum256([]byte("same content"))); sum != want {
+		t.Errorf("Hashed got %q, want %q", sum, want)
+	}
+	a, _ := os.Stat(first)
+	b, err := os.Stat(second)
+	if err != nil || !os.SameFile(a, b) {
+		t.Errorf("second file is not a link to the first (err %v)", err)
+	}
+	if _, err := os.Stat(PartialPath(second)); !os.IsNotExist(err) {
+		t.Errorf("temp file left behind")
+	}
+
+	// A lookup that finds a file with other content must not link to it.
+	third := filepath.Join(dir, "third.bin")
+	if err := SaveStreamToFileWithMetadata(strings.NewReader("more content"), third, 12, nil, nil, nil, opts, nil, testLogger); err != nil {
+		t.Fatalf("saving the third file: %v", err)
+	}
+	c, _ := os.Stat(third)
+	if os.SameFile(a, c) {
+		t.Error("file with other content was linked")
+	}
+	if data, _ := os.ReadFile(third); string(data) != "more content" {
+		t.Errorf("third file = %q, want %q", data, "more content")
+	}
+}
+
 func TestSaveStreamToFile_NestedDir(t *testing.T) {
 	tmpDir := t.TempDir()
 	filePath := tmpDir + "/nested/dir/test.txt"
@@ -148,6 +300,7 @@ func TestSaveStreamToFile_InvalidTimestamp(t *testing.T) {
 		nil,
 		nil,
 		nil,
+		nil,
 		testLogger,
 	)
 	if err != nil {
@@ -2,7 +2,11 @@
 
 package storage
 
-import "golang.org/x/sys/unix"
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
 
 func getAvailableBytes(path string) (uint64, error) {
 	var stat unix.Statfs_t
@@ -11,3 +15,14 @@ func getAvailableBytes(path string) (uint64, error) {
 	}
 	return stat.Bavail * uint64(stat.Bsize), nil
 }
+
+// syncDir flushes a directory's entries to disk, so a file renamed into it
+// survives a crash.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
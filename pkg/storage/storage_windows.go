@@ -31,3 +31,9 @@ func getAvailableBytes(path string) (uint64, error) {
 	}
 	return uint64(freeBytes), nil
 }
+
+// syncDir is a no-op on Windows, where directory handles cannot be
+// flushed.
+func syncDir(dir string) error {
+	return nil
+}
@@ -0,0 +1,332 @@
+// Package transfer tracks the transfers a process runs, in either
+// direction. Each is started through a Manager, which bounds how many sends
+// run at once, and is followed and stopped through its Handle. The daemon,
+// the admin API and the CLI share the one Manager rather than each keeping
+// their own loops.
+package transfer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Direction is which way a transfer goes.
+type Direction string
+
+const (
+	Send    Direction = "send"
+	Receive Direction = "receive"
+)
+
+// Status is where a transfer is in its lifecycle.
+type Status string
+
+const (
+	StatusWaiting   Status = "waiting" // for a free slot
+	StatusRunning   Status = "running"
+	StatusDone      Status = "done"
+	StatusFailed    Status = "failed"
+	StatusCancelled Status = "cancelled"
+)
+
+// maxFinished bounds how many finished transfers are kept for listing.
+const maxFinished = 100
+
+// ErrNotFound is returned for a transfer ID the manager does not have.
+var ErrNotFound = errors.New("no such transfer")
+
+// Progress is how much of a transfer has moved.
+type Progress struct {
+	Bytes int64 `json:"bytes"`
+	Total int64 `json:"total"`
+}
+
+// Info describes a transfer.
+type Info struct {
+	ID         int       `json:"id"`
+	Direction  Direction `json:"direction"`
+	Peer       string    `json:"peer"`
+	Status     Status    `json:"status"`
+	Progress             // last reported
+	Error      string    `json:"error,omitempty"`
+	StartedAt  time.Time `json:"startedAt"`
+	FinishedAt time.Time `json:"finishedAt,omitzero"`
+}
+
+// Func does the work of a transfer. It calls report as data moves and
+// returns when the transfer ends, early if ctx does.
+type Func func(ctx context.Context, report func(Progress)) error
+
+// Manager starts transfers and keeps track of them.
+type Manager struct {
+	maxSends int // sends that may run at once; 0 for no limit
+
+	mu        sync.Mutex
+	transfers []*Handle // in the order they were started
+	nextID    int
+	sending   int             // sends holding a slot
+	waiting   []chan struct{} // sends waiting for a slot, oldest first; closed when given one
+}
+
+// NewManager returns a manager that runs at most maxSends sends at once;
+// further sends wait for a slot and get one in the order they were started.
+// 0 means no limit. Receives are not limited: the receiver admits sessions
+// itself.
+func NewManager(maxSends int) *Manager {
+	return &Manager{maxSends: max(maxSends, 0), nextID: 1}
+}
+
+// Start runs fn as a new transfer with peer, in the background, and returns
+// its handle. The transfer stops when ctx ends or it is cancelled.
+func (m *Manager) Start(ctx context.Context, dir Direction, peer string, fn Func) *Handle {
+	ctx, cancel := context.WithCancel(ctx)
+	h := &Handle{
+		cancel:   cancel,
+		progress: make(chan Progress, 1),
+		done:     make(chan struct{}),
+		info: Info{
+			Direction: dir,
+			Peer:      peer,
+			Status:    StatusWaiting,
+			StartedAt: time.Now(),
+		},
+	}
+	m.mu.Lock()
+	h.info.ID = m.nextID
+	m.nextID++
+	m.transfers = append(m.transfers, h)
+	var slot chan struct{}
+	if dir == Send && m.maxSends > 0 {
+		slot = make(chan struct{})
+		if m.sending < m.maxSends {
+			m.sending++
+			close(slot)
+		} else {
+			m.waiting = append(m.waiting, slot)
+		}
+	}
+	m.mu.Unlock()
+
+	go m.run(ctx, h, fn, slot)
+	return h
+}
+
+// run waits for slot, if the transfer needs one, then runs fn.
+func (m *Manager) run(ctx context.Context, h *Handle, fn Func, slot chan struct{}) {
+	defer close(h.done)
+	if slot != nil {
+		select {
+		case <-slot:
+		case <-ctx.Done():
+			m.abandon(slot)
+			h.finish(ctx.Err(), true)
+			m.prune()
+			return
+		}
+		defer m.release()
+	}
+	h.setStatus(StatusRunning)
+	err := fn(ctx, h.report)
+	h.finish(err, ctx.Err() != nil)
+	m.prune()
+}
+
+// release gives up a send's slot to the longest waiting send, if any.
+func (m *Manager) release() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.releaseLocked()
+}
+
+func (m *Manager) releaseLocked() {
+	if len(m.waiting) > 0 {
+		close(m.waiting[0])
+		m.waiting = m.waiting[1:]
+		return
+	}
+	m.sending--
+}
+
+// abandon drops a send that stopped waiting for slot, passing the slot on
+// if it was given one meanwhile.
+func (m *Manager) abandon(slot chan struct{}) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if i := slices.Index(m.waiting, slot); i >= 0 {
+		m.waiting = slices.Delete(m.waiting, i, i+1)
+		return
+	}
+	m.releaseLocked()
+}
+
+// Get returns the transfer with the given ID.
+func (m *Manager) Get(id int) (*Handle, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, h := range m.transfers {
+		if h.info.ID == id {
+			return h, nil
+		}
+	}
+	return nil, fmt.Errorf("transfer %d: %w", id, ErrNotFound)
+}
+
+// List describes the transfers, running and recently finished, oldest
+// first.
+func (m *Manager) List() []Info {
+	m.mu.Lock()
+	handles := slices.Clone(m.transfers)
+	m.mu.Unlock()
+	infos := make([]Info, len(handles))
+	for i, h := range handles {
+		infos[i] = h.Info()
+	}
+	return infos
+}
+
+// Active returns how many transfers are waiting or running.
+func (m *Manager) Active() int {
+	n := 0
+	for _, info := range m.List() {
+		if !info.Status.Finished() {
+			n++
+		}
+	}
+	return n
+}
+
+// Cancel stops the transfer with the given ID.
+func (m *Manager) Cancel(id int) error {
+	h, err := m.Get(id)
+	if err != nil {
+		return err
+	}
+	h.Cancel()
+	return nil
+}
+
+// prune drops the oldest finished transfers beyond maxFinished.
+func (m *Manager) prune() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	finished := 0
+	for _, h := range m.transfers {
+		if h.Info().Status.Finished() {
+			finished++
+		}
+	}
+	m.transfers = slices.DeleteFunc(m.transfers, func(h *Handle) bool {
+		if finished > maxFinished && h.Info().Status.Finished() {
+			finished--
+			return true
+		}
+		return false
+	})
+}
+
+// Finished reports whether a transfer with status s has ended.
+func (s Status) Finished() bool {
+	return s == StatusDone || s == StatusFailed || s == StatusCancelled
+}
+
+// Handle follows and controls one transfer.
+type Handle struct {
+	cancel   context.CancelFunc
+	progress chan Progress // holds the latest report not yet received
+	done     chan struct{}
+
+	mu   sync.Mutex
+	info Info
+	err  error
+}
+
+// ID returns the transfer's ID within its manager.
+func (h *Handle) ID() int {
+	return h.info.ID // set before the handle is shared
+}
+
+// Info describes the transfer now.
+func (h *Handle) Info() Info {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.info
+}
+
+// Progress returns a channel of progress reports. Reports not received in
+// time are replaced by newer ones, so a slow reader sees the latest. The
+// channel is closed when the transfer ends.
+func (h *Handle) Progress() <-chan Progress {
+	return h.progress
+}
+
+// Done returns a channel that is closed when the transfer ends.
+func (h *Handle) Done() <-chan struct{} {
+	return h.done
+}
+
+// Err returns the error the transfer ended with, or nil if it succeeded or
+// has not ended.
+func (h *Handle) Err() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.err
+}
+
+// Cancel stops the transfer. It does not wait for it to end.
+func (h *Handle) Cancel() {
+	h.cancel()
+}
+
+// Wait waits for the transfer to end and returns Err.
+func (h *Handle) Wait() error {
+	<-h.done
+	return h.Err()
+}
+
+func (h *Handle) setStatus(s Status) {
+	h.mu.Lock()
+	h.info.Status = s
+	h.mu.Unlock()
+}
+
+// report records p and offers it on the progress channel, replacing a
+// report the reader has not taken yet.
+func (h *Handle) report(p Progress) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.info.Status.Finished() {
+		return
+	}
+	h.info.Progress = p
+	select {
+	case <-h.progress:
+	default:
+	}
+	h.progress <- p
+}
+
+// finish records the outcome. stopped tells whether the transfer's
+// context had ended, making a failure a cancellation.
+func (h *Handle) finish(err error, stopped bool) {
+	h.mu.Lock()
+	switch {
+	case err == nil:
+		h.info.Status = StatusDone
+	case stopped:
+		h.info.Status = StatusCancelled
+	default:
+		h.info.Status = StatusFailed
+	}
+	if err != nil {
+		h.info.Error = err.Error()
+	}
+	h.err = err
+	h.info.FinishedAt = time.Now()
+	close(h.progress)
+	h.mu.Unlock()
+	h.cancel()
+}
@@ -0,0 +1,141 @@
+package transfer
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestManager_LimitsSends(t *testing.T) {
+	m := NewManager(1)
+	started, release := make(chan struct{}), make(chan struct{})
+	first := m.Start(context.Background(), Send, "a", func(ctx context.Context, _ func(Progress)) error {
+		close(started)
+		<-release
+		return nil
+	})
+	second := m.Start(context.Background(), Send, "b", func(ctx context.Context, _ func(Progress)) error {
+		return nil
+	})
+	// Receives are admitted by the receiver, not the manager.
+	receive := m.Start(context.Background(), Receive, "c", func(ctx context.Context, _ func(Progress)) error {
+		return nil
+	})
+	if err := receive.Wait(); err != nil {
+		t.Fatalf("receive: %v", err)
+	}
+
+	<-started
+	if got := second.Info().Status; got != StatusWaiting {
+		t.Errorf("second send is %s while the first runs, want waiting", got)
+	}
+	close(release)
+	if err := first.Wait(); err != nil {
+		t.Fatalf("first send: %v", err)
+	}
+	if err := second.Wait(); err != nil {
+		t.Fatalf("second send: %v", err)
+	}
+	if first.Info().Status != StatusDone || second.Info().Status != StatusDone {
+		t.Errorf("statuses %s, %s; want done", first.Info().Status, second.Info().Status)
+	}
+	if m.Active() != 0 {
+		t.Errorf("Active = %d after all ended", m.Active())
+	}
+}
+
+func TestManager_SendsWaitInOrder(t *testing.T) {
+	m := NewManager(1)
+	release := make(chan struct{})
+	first := m.Start(context.Background(), Send, "a", func(ctx context.Context, _ func(Progress)) error {
+		<-release
+		return nil
+	})
+
+	var mu sync.Mutex
+	var order []string
+	var handles []*Handle
+	for _, peer := range []string{"b", "c", "d", "e"} {
+		handles = append(handles, m.Start(context.Background(), Send, peer, func(ctx context.Context, _ func(Progress)) error {
+			mu.Lock()
+			order = append(order, peer)
+			mu.Unlock()
+			return nil
+		}))
+	}
+	// A send that gives up waiting leaves the others their turn.
+	handles[1].Cancel()
+	if err := handles[1].Wait(); !errors.Is(err, context.Canceled) {
+		t.Fatalf("cancelled send ended with %v", err)
+	}
+
+	close(release)
+	first.Wait()
+	for _, h := range handles {
+		h.Wait()
+	}
+	if want := []string{"b", "d", "e"}; !slices.Equal(order, want) {
+		t.Errorf("sends ran in order %v, want %v", order, want)
+	}
+}
+
+func TestHandle_ProgressAndCancel(t *testing.T) {
+	m := NewManager(0)
+	reported := make(chan struct{})
+	h := m.Start(context.Background(), Send, "peer", func(ctx context.Context, report func(Progress)) error {
+		report(Progress{Bytes: 1, Total: 10})
+		report(Progress{Bytes: 5, Total: 10})
+		close(reported)
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	<-reported
+	// Unread reports are replaced by the latest.
+	if p := <-h.Progress(); p.Bytes != 5 {
+		t.Errorf("progress %+v, want the latest report", p)
+	}
+
+	if err := m.Cancel(h.ID()); err != nil {
+		t.Fatalf("Cancel: %v", err)
+	}
+	select {
+	case <-h.Done():
+	case <-time.After(time.Second):
+		t.Fatal("transfer did not end when cancelled")
+	}
+	if !errors.Is(h.Err(), context.Canceled) || h.Info().Status != StatusCancelled {
+		t.Errorf("ended with %v as %s, want cancelled", h.Err(), h.Info().Status)
+	}
+	if _, ok := <-h.Progress(); ok {
+		t.Error("progress channel open after the transfer ended")
+	}
+	if err := m.Cancel(99); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Cancel(99) = %v, want ErrNotFound", err)
+	}
+}
+
+func TestManager_Failure(t *testing.T) {
+	m := NewManager(0)
+	boom := errors.New("boom")
+	h := m.Start(context.Background(), Send, "peer", func(context.Context, func(Progress)) error { return boom })
+	if err := h.Wait(); !errors.Is(err, boom) {
+		t.Errorf("Wait = %v, want boom", err)
+	}
+	list := m.List()
+	if len(list) != 1 || list[0].Status != StatusFailed || list[0].Error != "boom" || list[0].Peer != "peer" {
+		t.Errorf("List = %+v", list)
+	}
+}
+
+func TestManager_PrunesFinished(t *testing.T) {
+	m := NewManager(0)
+	for range maxFinished + 10 {
+		m.Start(context.Background(), Send, "peer", func(context.Context, func(Progress)) error { return nil }).Wait()
+	}
+	if n := len(m.List()); n != maxFinished {
+		t.Errorf("%d transfers kept, want %d", n, maxFinished)
+	}
+}
@@ -0,0 +1,162 @@
+// Package validate checks the DTOs other devices send before they are acted
+// on: lengths of the strings a peer chooses, port ranges, the protocol
+// enum, and the file counts and sizes of a transfer. The checks are pure
+// functions of the DTO, so they are cheap to fuzz.
+package validate
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/bethropolis/localgo/pkg/model"
+)
+
+// Limits on incoming DTOs. Lengths are in bytes.
+const (
+	MaxAliasLength       = 256
+	MaxFingerprintLength = 128
+	MaxFieldLength       = 256  // version, device model and type, file IDs and types
+	MaxFileNameLength    = 4096 // file names may carry the folders of a folder transfer
+	MaxFiles             = 10000
+	MaxFileSize          = 1 << 50 // 1 PiB
+	MaxTotalSize         = 1 << 52
+)
+
+// Error is a constraint a DTO breaks. Handlers answer it with 400.
+type Error struct {
+	Field  string // JSON path, such as files.abc.size
+	Reason string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: %s", e.Field, e.Reason)
+}
+
+func invalid(field, format string, a ...any) *Error {
+	return &Error{Field: field, Reason: fmt.Sprintf(format, a...)}
+}
+
+// Register checks a /register request. The port and protocol may be
+// missing, as from LocalSend v1.
+func Register(dto *model.RegisterDto) error {
+	if err := device(dto.Alias, dto.Fingerprint, dto.Version, dto.DeviceModel, dto.DeviceType, true); err != nil {
+		return err
+	}
+	return endpoint(dto.Port, dto.Protocol)
+}
+
+// Multicast checks a multicast announcement or response. The port and
+// protocol may be missing, as from LocalSend v1.
+func Multicast(dto *model.MulticastDto) error {
+	if err := device(dto.Alias, dto.Fingerprint, dto.Version, dto.DeviceModel, dto.DeviceType, true); err != nil {
+		return err
+	}
+	return endpoint(dto.Port, dto.Protocol)
+}
+
+// PrepareUpload checks a prepare-upload request. The sender's alias and
+// fingerprint may be empty, as a v1 send-request has no fingerprint; no
+// files at all is valid too, and is answered as a finished transfer.
+func PrepareUpload(dto *model.PrepareUploadRequestDto) error {
+	info := dto.Info
+	if err := device(info.Alias, info.Fingerprint, info.Version, info.DeviceModel, info.DeviceType, false); err != nil {
+		return prefix("info", err)
+	}
+	if len(dto.TargetPath) > MaxFileNameLength {
+		return invalid("targetPath", "longer than %d bytes", MaxFileNameLength)
+	}
+	if len(dto.Files) > MaxFiles {
+		return invalid("files", "more than %d files", MaxFiles)
+	}
+	var total int64
+	for id, f := range dto.Files {
+		if err := file(id, f); err != nil {
+			return err
+		}
+		if f.Size > MaxTotalSize-total {
+			return invalid("files", "more than %d bytes in total", int64(MaxTotalSize))
+		}
+		total += f.Size
+	}
+	return nil
+}
+
+// device checks the fields every device DTO has.
+func device(alias, fingerprint, version string, deviceModel *string, deviceType model.DeviceType, required bool) error {
+	switch {
+	case required && alias == "":
+		return invalid("alias", "missing")
+	case len(alias) > MaxAliasLength:
+		return invalid("alias", "longer than %d bytes", MaxAliasLength)
+	case required && fingerprint == "":
+		return invalid("fingerprint", "missing")
+	case len(fingerprint) > MaxFingerprintLength:
+		return invalid("fingerprint", "longer than %d bytes", MaxFingerprintLength)
+	case len(version) > MaxFieldLength:
+		return invalid("version", "longer than %d bytes", MaxFieldLength)
+	case deviceModel != nil && len(*deviceModel) > MaxFieldLength:
+		return invalid("deviceModel", "longer than %d bytes", MaxFieldLength)
+	case len(deviceType) > MaxFieldLength:
+		// Unknown types are allowed: newer versions of the protocol add
+		// them, and they are shown as desktops.
+		return invalid("deviceType", "longer than %d bytes", MaxFieldLength)
+	}
+	return nil
+}
+
+// endpoint checks the port and protocol a device is reached on. Zero and
+// empty mean not given.
+func endpoint(port int, protocol model.ProtocolType) error {
+	if port < 0 || port > 65535 {
+		return invalid("port", "%d out of range", port)
+	}
+	switch protocol {
+	case "", model.ProtocolTypeHTTP, model.ProtocolTypeHTTPS:
+		return nil
+	}
+	return invalid("protocol", "%q is not http or https", protocol)
+}
+
+// file checks one file of a prepare-upload request, listed under id.
+func file(id string, f model.FileDto) error {
+	field := "files." + id
+	switch {
+	case id == "" || len(id) > MaxFieldLength:
+		return invalid("files", "file ID empty or longer than %d bytes", MaxFieldLength)
+	case !printable(f.FileName):
+		// Control characters are stripped from names; one with nothing
+		// else would be left without a name.
+		return invalid(field+".fileName", "empty")
+	case len(f.FileName) > MaxFileNameLength:
+		return invalid(field+".fileName", "longer than %d bytes", MaxFileNameLength)
+	case f.Size < 0:
+		return invalid(field+".size", "negative")
+	case f.Size > MaxFileSize:
+		return invalid(field+".size", "larger than %d bytes", int64(MaxFileSize))
+	case len(f.FileType) > MaxFieldLength:
+		return invalid(field+".fileType", "longer than %d bytes", MaxFieldLength)
+	case f.SHA256 != nil && *f.SHA256 != "" && !isSHA256(*f.SHA256):
+		return invalid(field+".sha256", "not a hex SHA-256 hash")
+	}
+	return nil
+}
+
+// printable reports whether s has a character that is not a control
+// character.
+func printable(s string) bool {
+	return strings.IndexFunc(s, func(r rune) bool { return r > 0x1F && r != 0x7F }) >= 0
+}
+
+func isSHA256(s string) bool {
+	b, err := hex.DecodeString(s)
+	return err == nil && len(b) == 32
+}
+
+// prefix puts the path of the DTO err was found in before its field.
+func prefix(path string, err error) error {
+	if e, ok := err.(*Error); ok {
+		return &Error{Field: path + "." + e.Field, Reason: e.Reason}
+	}
+	return err
+}
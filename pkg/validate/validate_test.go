@@ -0,0 +1,136 @@
+package validate
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/bethropolis/localgo/pkg/model"
+)
+
+func TestRegister(t *testing.T) {
+	valid := model.RegisterDto{Alias: "Phone", Fingerprint: "fp", Version: "2.1", DeviceType: "toaster", Port: 53317, Protocol: model.ProtocolTypeHTTPS}
+	if err := Register(&valid); err != nil {
+		t.Fatalf("valid request rejected: %v", err)
+	}
+	v1 := model.RegisterDto{Alias: "Old", Fingerprint: "fp"}
+	if err := Register(&v1); err != nil {
+		t.Fatalf("v1 request rejected: %v", err)
+	}
+
+	tests := []struct {
+		edit  func(*model.RegisterDto)
+		field string
+	}{
+		{func(d *model.RegisterDto) { d.Alias = "" }, "alias"},
+		{func(d *model.RegisterDto) { d.Alias = strings.Repeat("a", MaxAliasLength+1) }, "alias"},
+		{func(d *model.RegisterDto) { d.Fingerprint = "" }, "fingerprint"},
+		{func(d *model.RegisterDto) { d.Port = 70000 }, "port"},
+		{func(d *model.RegisterDto) { d.Port = -1 }, "port"},
+		{func(d *model.RegisterDto) { d.Protocol = "ftp" }, "protocol"},
+		{func(d *model.RegisterDto) { m := strings.Repeat("m", MaxFieldLength+1); d.DeviceModel = &m }, "deviceModel"},
+	}
+	for _, tt := range tests {
+		dto := valid
+		tt.edit(&dto)
+		var verr *Error
+		if err := Register(&dto); !errors.As(err, &verr) || verr.Field != tt.field {
+			t.Errorf("Register(%+v) = %v, want an error in %s", dto, err, tt.field)
+		}
+	}
+}
+
+func TestPrepareUpload(t *testing.T) {
+	hash := strings.Repeat("ab", 32)
+	valid := func() model.PrepareUploadRequestDto {
+		return model.PrepareUploadRequestDto{
+			Info: model.InfoDto{Alias: "Phone", Fingerprint: "fp"},
+			Files: map[string]model.FileDto{
+				"a": {ID: "a", FileName: "photos/a.jpg", Size: 100, FileType: "image/jpeg", SHA256: &hash},
+				"b": {ID: "b", FileName: "b.txt", Size: 0, FileType: "text/plain"},
+			},
+		}
+	}
+	dto := valid()
+	if err := PrepareUpload(&dto); err != nil {
+		t.Fatalf("valid request rejected: %v", err)
+	}
+	// A v1 send-request has no fingerprint, and an empty one is finished.
+	empty := model.PrepareUploadRequestDto{Info: model.InfoDto{Alias: "Old"}}
+	if err := PrepareUpload(&empty); err != nil {
+		t.Fatalf("empty request rejected: %v", err)
+	}
+
+	bad := "xyz"
+	tests := []struct {
+		edit  func(*model.PrepareUploadRequestDto)
+		field string
+	}{
+		{func(d *model.PrepareUploadRequestDto) { d.Info.Alias = strings.Repeat("a", MaxAliasLength+1) }, "info.alias"},
+		{func(d *model.PrepareUploadRequestDto) { d.Files["a"] = model.FileDto{FileName: "a", Size: -1} }, "files.a.size"},
+		{func(d *model.PrepareUploadRequestDto) {
+			d.Files["a"] = model.FileDto{FileName: "a", Size: MaxFileSize + 1}
+		}, "files.a.size"},
+		{func(d *model.PrepareUploadRequestDto) { d.Files["a"] = model.FileDto{FileName: "\x1b\x07"} }, "files.a.fileName"},
+		{func(d *model.PrepareUploadRequestDto) { d.Files["a"] = model.FileDto{FileName: "a", SHA256: &bad} }, "files.a.sha256"},
+		{func(d *model.PrepareUploadRequestDto) { d.Files[""] = model.FileDto{FileName: "a"} }, "files"},
+		{func(d *model.PrepareUploadRequestDto) { d.TargetPath = strings.Repeat("p", MaxFileNameLength+1) }, "targetPath"},
+		{func(d *model.PrepareUploadRequestDto) {
+			for i := range 5 {
+				d.Files[string(rune('c'+i))] = model.FileDto{FileName: "big", Size: MaxFileSize}
+			}
+		}, "files"},
+	}
+	for _, tt := range tests {
+		dto := valid()
+		tt.edit(&dto)
+		var verr *Error
+		if err := PrepareUpload(&dto); !errors.As(err, &verr) || verr.Field != tt.field {
+			t.Errorf("PrepareUpload = %v, want an error in %s", err, tt.field)
+		}
+	}
+}
+
+// FuzzPrepareUpload checks that any request that decodes is either
+// rejected or safe to act on.
+func FuzzPrepareUpload(f *testing.F) {
+	f.Add([]byte(`{"info":{"alias":"Phone","fingerprint":"fp"},"files":{"a":{"id":"a","fileName":"a.txt","size":5,"fileType":"text/plain"}}}`))
+	f.Add([]byte(`{"info":{"alias":""},"files":{"":{"fileName":"\u001b","size":-1}}}`))
+	f.Add([]byte(`{"files":{"a":{"fileName":"a","size":1125899906842624},"b":{"fileName":"b","size":1125899906842624}}}`))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var dto model.PrepareUploadRequestDto
+		if json.Unmarshal(data, &dto) != nil {
+			return
+		}
+		if PrepareUpload(&dto) != nil {
+			return
+		}
+		var total int64
+		for id, file := range dto.Files {
+			if id == "" || !printable(file.FileName) || file.Size < 0 || file.Size > MaxFileSize {
+				t.Fatalf("accepted file %q: %+v", id, file)
+			}
+			total += file.Size
+		}
+		if total < 0 || total > MaxTotalSize {
+			t.Fatalf("accepted %d bytes in total", total)
+		}
+	})
+}
+
+// FuzzMulticast checks that an accepted announcement names a device that
+// can be contacted.
+func FuzzMulticast(f *testing.F) {
+	f.Add([]byte(`{"alias":"Phone","fingerprint":"fp","port":53317,"protocol":"https","announce":true}`))
+	f.Add([]byte(`{"alias":"Phone","fingerprint":"fp","port":-5,"protocol":"gopher"}`))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var dto model.MulticastDto
+		if json.Unmarshal(data, &dto) != nil || Multicast(&dto) != nil {
+			return
+		}
+		if dto.Alias == "" || dto.Fingerprint == "" || dto.Port < 0 || dto.Port > 65535 {
+			t.Fatalf("accepted %+v", dto)
+		}
+	})
+}
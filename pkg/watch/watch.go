@@ -0,0 +1,224 @@
+// Package watch reports files dropped into a directory once they have
+// finished being written, so they can be sent on automatically.
+package watch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"go.uber.org/zap"
+)
+
+const (
+	// DefaultSettle is how long a file must stay unchanged before it is
+	// considered written.
+	DefaultSettle = 2 * time.Second
+	// DefaultRetryDelay is the wait before a file that could not be handled
+	// is reported again.
+	DefaultRetryDelay = 30 * time.Second
+)
+
+// partialSuffixes mark files that browsers and editors are still writing
+// and will rename when done.
+var partialSuffixes = []string{".part", ".partial", ".crdownload", ".download", ".tmp", ".swp", "~"}
+
+// Options configures a Watcher.
+type Options struct {
+	Settle       time.Duration // DefaultSettle if 0
+	RetryDelay   time.Duration // DefaultRetryDelay if 0
+	Excludes     []string      // glob patterns of file names to ignore
+	SkipExisting bool          // ignore files already in the directory at start
+	pollInterval time.Duration // how often pending files are checked; Settle/4 if 0
+}
+
+// HandleFunc is given files that have finished being written, in name
+// order, and returns those that should be reported again after the retry
+// delay.
+type HandleFunc func(ctx context.Context, paths []string) (retry []string)
+
+// pending is a file seen changing, waiting to settle.
+type pending struct {
+	size    int64
+	modTime time.Time
+	since   time.Time // when it was last seen changing, or when to retry
+}
+
+// Watcher watches the files directly in one directory. Subdirectories,
+// hidden files and files with a partial-download suffix are ignored.
+type Watcher struct {
+	dir     string
+	opts    Options
+	logger  *zap.SugaredLogger
+	pending map[string]*pending
+}
+
+// New returns a watcher for dir, which must be an existing directory.
+func New(dir string, opts Options, logger *zap.SugaredLogger) (*Watcher, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("not a directory: %s", dir)
+	}
+	for _, pattern := range opts.Excludes {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid exclude pattern %q: %w", pattern, err)
+		}
+	}
+	if opts.Settle <= 0 {
+		opts.Settle = DefaultSettle
+	}
+	if opts.RetryDelay <= 0 {
+		opts.RetryDelay = DefaultRetryDelay
+	}
+	if opts.pollInterval <= 0 {
+		opts.pollInterval = max(opts.Settle/4, 10*time.Millisecond)
+	}
+	if logger == nil {
+		logger = zap.NewNop().Sugar()
+	}
+	return &Watcher{dir: dir, opts: opts, logger: logger, pending: make(map[string]*pending)}, nil
+}
+
+// Ignored reports whether a file name is skipped by the watcher.
+func (w *Watcher) Ignored(name string) bool {
+	if strings.HasPrefix(name, ".") {
+		return true
+	}
+	lower := strings.ToLower(name)
+	for _, suffix := range partialSuffixes {
+		if strings.HasSuffix(lower, suffix) {
+			return true
+		}
+	}
+	for _, pattern := range w.opts.Excludes {
+		if ok, _ := filepath.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Run watches the directory until ctx is done, calling handle with each
+// batch of files that have settled. handle runs on Run's goroutine; changes
+// made meanwhile are picked up afterwards.
+func (w *Watcher) Run(ctx context.Context, handle HandleFunc) error {
+	fsw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to start watching: %w", err)
+	}
+	defer fsw.Close()
+	if err := fsw.Add(w.dir); err != nil {
+		return fmt.Errorf("failed to watch %s: %w", w.dir, err)
+	}
+	if !w.opts.SkipExisting {
+		w.scan()
+	}
+
+	ticker := time.NewTicker(w.opts.pollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-fsw.Events:
+			if !ok {
+				return nil
+			}
+			if event.Has(fsnotify.Create) || event.Has(fsnotify.Write) {
+				w.touch(event.Name, time.Now())
+			}
+		case err, ok := <-fsw.Errors:
+			if !ok {
+				return nil
+			}
+			if errors.Is(err, fsnotify.ErrEventOverflow) {
+				// Events were lost; look at everything again.
+				w.logger.Warnf("Missed file events in %s, rescanning", w.dir)
+				w.scan()
+				continue
+			}
+			w.logger.Warnf("Watching %s: %v", w.dir, err)
+		case <-ticker.C:
+			ready := w.settled(time.Now())
+			if len(ready) == 0 {
+				continue
+			}
+			retry := handle(ctx, ready)
+			if ctx.Err() != nil {
+				return nil
+			}
+			due := time.Now().Add(w.opts.RetryDelay)
+			for _, path := range retry {
+				w.touch(path, due)
+			}
+		}
+	}
+}
+
+// scan marks every file in the directory as pending.
+func (w *Watcher) scan() {
+	entries, err := os.ReadDir(w.dir)
+	if err != nil {
+		w.logger.Warnf("Failed to read %s: %v", w.dir, err)
+		return
+	}
+	now := time.Now()
+	for _, e := range entries {
+		w.touch(filepath.Join(w.dir, e.Name()), now)
+	}
+}
+
+// touch marks path as changed at since, unless it is ignored.
+func (w *Watcher) touch(path string, since time.Time) {
+	if filepath.Dir(path) != filepath.Clean(w.dir) || w.Ignored(filepath.Base(path)) {
+		return
+	}
+	if p := w.pending[path]; p != nil {
+		p.since = since
+		return
+	}
+	w.pending[path] = &pending{size: -1, since: since}
+}
+
+// settled returns the pending files that have not changed for the settle
+// time and stops tracking them. Files that are gone or are directories are
+// dropped.
+func (w *Watcher) settled(now time.Time) []string {
+	var ready []string
+	for path, p := range w.pending {
+		info, err := os.Stat(path)
+		if err != nil || !info.Mode().IsRegular() {
+			delete(w.pending, path)
+			continue
+		}
+		if info.Size() != p.size || !info.ModTime().Equal(p.modTime) {
+			p.size, p.modTime = info.Size(), info.ModTime()
+			if p.since.Before(now) {
+				p.since = now
+			}
+			continue
+		}
+		if now.Sub(p.since) < w.opts.Settle {
+			continue
+		}
+		// A writer holding the file exclusively (as on Windows) is not done.
+		f, err := os.Open(path)
+		if err != nil {
+			continue
+		}
+		f.Close()
+		delete(w.pending, path)
+		ready = append(ready, path)
+	}
+	slices.Sort(ready)
+	return ready
+}
@@ -0,0 +1,142 @@
+package watch
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recorder collects the batches handed to a watcher.
+type recorder struct {
+	mu      sync.Mutex
+	batches [][]string
+	retry   map[string]int // times to ask for a path again
+}
+
+func (r *recorder) handle(ctx context.Context, paths []string) []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.batches = append(r.batches, paths)
+	var retry []string
+	for _, p := range paths {
+		if r.retry[p] > 0 {
+			r.retry[p]--
+			retry = append(retry, p)
+		}
+	}
+	return retry
+}
+
+func (r *recorder) names() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var names []string
+	for _, b := range r.batches {
+		for _, p := range b {
+			names = append(names, filepath.Base(p))
+		}
+	}
+	return names
+}
+
+func run(t *testing.T, dir string, opts Options, r *recorder) {
+	t.Helper()
+	opts.pollInterval = 10 * time.Millisecond
+	w, err := New(dir, opts, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if err := w.Run(ctx, r.handle); err != nil {
+			t.Error(err)
+		}
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+}
+
+func waitForNames(t *testing.T, r *recorder, n int) []string {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		names := r.names()
+		if len(names) >= n {
+			return names
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("got %v, want %d file(s)", names, n)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestWatcher_ReportsSettledFiles(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "existing.txt"), []byte("old"), 0o644)
+	r := &recorder{}
+	run(t, dir, Options{Settle: 100 * time.Millisecond, Excludes: []string{"*.log"}}, r)
+
+	os.WriteFile(filepath.Join(dir, ".hidden"), []byte("x"), 0o644)
+	os.WriteFile(filepath.Join(dir, "movie.mkv.part"), []byte("x"), 0o644)
+	os.WriteFile(filepath.Join(dir, "debug.log"), []byte("x"), 0o644)
+	os.Mkdir(filepath.Join(dir, "sub"), 0o755)
+
+	// A file still being written is held back until it stops changing.
+	growing := filepath.Join(dir, "growing.bin")
+	f, err := os.Create(growing)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range 5 {
+		f.Write([]byte("chunk"))
+		time.Sleep(40 * time.Millisecond)
+		for _, name := range r.names() {
+			if name == "growing.bin" {
+				t.Fatal("a file was reported while it was still being written")
+			}
+		}
+	}
+	f.Close()
+
+	names := waitForNames(t, r, 2)
+	time.Sleep(200 * time.Millisecond)
+	names = r.names()
+	if len(names) != 2 || names[0] != "existing.txt" || names[1] != "growing.bin" {
+		t.Errorf("reported %v, want [existing.txt growing.bin]", names)
+	}
+}
+
+func TestWatcher_SkipExistingAndRetry(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "existing.txt"), []byte("old"), 0o644)
+	target := filepath.Join(dir, "new.txt")
+	r := &recorder{retry: map[string]int{target: 1}}
+	run(t, dir, Options{Settle: 20 * time.Millisecond, RetryDelay: 50 * time.Millisecond, SkipExisting: true}, r)
+
+	time.Sleep(50 * time.Millisecond)
+	os.WriteFile(target, []byte("new"), 0o644)
+	names := waitForNames(t, r, 2)
+	if names[0] != "new.txt" || names[1] != "new.txt" {
+		t.Errorf("reported %v, want new.txt twice", names)
+	}
+}
+
+func TestNew_RejectsBadInput(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "f")
+	os.WriteFile(file, nil, 0o644)
+	if _, err := New(file, Options{}, nil); err == nil {
+		t.Error("watching a file was accepted")
+	}
+	if _, err := New(dir, Options{Excludes: []string{"["}}, nil); err == nil {
+		t.Error("an invalid exclude pattern was accepted")
+	}
+}
@@ -0,0 +1,101 @@
+// Package wol wakes sleeping devices with Wake-on-LAN magic packets.
+package wol
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+)
+
+// Port is the UDP port magic packets are sent to, the discard port most
+// network cards listen on.
+const Port = 9
+
+// MagicPacket returns the magic packet for mac: six 0xFF bytes followed by
+// the address sixteen times.
+func MagicPacket(mac net.HardwareAddr) ([]byte, error) {
+	if len(mac) != 6 {
+		return nil, fmt.Errorf("wake-on-LAN needs a 6-byte MAC address, got %s", mac)
+	}
+	packet := make([]byte, 0, 102)
+	for i := 0; i < 6; i++ {
+		packet = append(packet, 0xFF)
+	}
+	for i := 0; i < 16; i++ {
+		packet = append(packet, mac...)
+	}
+	return packet, nil
+}
+
+// Wake broadcasts the magic packet for mac on the local network. If the
+// device's last address ip is known, the packet is also sent to the
+// broadcast address of the local subnet holding it, which reaches it when
+// this machine has several networks.
+func Wake(mac net.HardwareAddr, ip net.IP) error {
+	packet, err := MagicPacket(mac)
+	if err != nil {
+		return err
+	}
+	addrs := []string{net.JoinHostPort(net.IPv4bcast.String(), strconv.Itoa(Port))}
+	if ip != nil {
+		if bcast := subnetBroadcast(ip); bcast != nil {
+			addrs = append(addrs, net.JoinHostPort(bcast.String(), strconv.Itoa(Port)))
+		}
+	}
+	return sendPacket(packet, addrs)
+}
+
+// sendPacket sends packet to every address, succeeding if any send does.
+func sendPacket(packet []byte, addrs []string) error {
+	var errs []error
+	for _, addr := range addrs {
+		conn, err := net.Dial("udp4", addr)
+		if err == nil {
+			_, err = conn.Write(packet)
+			conn.Close()
+		}
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) == len(addrs) {
+		return fmt.Errorf("failed to send the magic packet: %w", errors.Join(errs...))
+	}
+	return nil
+}
+
+// subnetBroadcast returns the broadcast address of the local IPv4 subnet
+// that contains ip, or nil if no interface is on it.
+func subnetBroadcast(ip net.IP) net.IP {
+	ip = ip.To4()
+	if ip == nil {
+		return nil
+	}
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return nil
+	}
+	for _, addr := range addrs {
+		ipnet, ok := addr.(*net.IPNet)
+		if !ok || ipnet.IP.To4() == nil || ipnet.IP.IsLoopback() || !ipnet.Contains(ip) {
+			continue
+		}
+		return broadcastAddr(ipnet)
+	}
+	return nil
+}
+
+// broadcastAddr returns the broadcast address of an IPv4 network.
+func broadcastAddr(n *net.IPNet) net.IP {
+	ip := n.IP.To4()
+	mask := n.Mask
+	if len(mask) == net.IPv6len {
+		mask = mask[12:]
+	}
+	bcast := make(net.IP, net.IPv4len)
+	for i := range bcast {
+		bcast[i] = ip[i] | ^mask[i]
+	}
+	return bcast
+}
@@ -0,0 +1,65 @@
+package wol
+
+import (
+	"bytes"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestMagicPacket(t *testing.T) {
+	mac, _ := net.ParseMAC("a4:bb:6d:12:34:56")
+	packet, err := MagicPacket(mac)
+	if err != nil {
+		t.Fatalf("MagicPacket failed: %v", err)
+	}
+	if len(packet) != 102 || !bytes.Equal(packet[:6], bytes.Repeat([]byte{0xFF}, 6)) {
+		t.Fatalf("unexpected header in %x", packet)
+	}
+	if !bytes.Equal(packet[6:], bytes.Repeat(mac, 16)) {
+		t.Errorf("unexpected body in %x", packet)
+	}
+
+	long, _ := net.ParseMAC("02:00:5e:10:00:00:00:01")
+	if _, err := MagicPacket(long); err == nil {
+		t.Error("expected an error for an 8-byte address")
+	}
+}
+
+func TestBroadcastAddr(t *testing.T) {
+	for cidr, want := range map[string]string{
+		"192.168.1.23/24": "192.168.1.255",
+		"10.1.2.3/8":      "10.255.255.255",
+		"172.16.5.4/22":   "172.16.7.255",
+	} {
+		ip, n, _ := net.ParseCIDR(cidr)
+		n.IP = ip
+		if got := broadcastAddr(n).String(); got != want {
+			t.Errorf("broadcastAddr(%s) = %s, want %s", cidr, got, want)
+		}
+	}
+}
+
+func TestSendPacket(t *testing.T) {
+	conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("udp unavailable: %v", err)
+	}
+	defer conn.Close()
+
+	mac, _ := net.ParseMAC("a4:bb:6d:12:34:56")
+	packet, _ := MagicPacket(mac)
+	// The first address fails; the packet still goes out to the second.
+	if err := sendPacket(packet, []string{"256.0.0.1:9", conn.LocalAddr().String()}); err != nil {
+		t.Fatalf("sendPacket failed: %v", err)
+	}
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	buf := make([]byte, 200)
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatalf("no packet received: %v", err)
+	}
+	if !bytes.Equal(buf[:n], packet) {
+		t.Errorf("received %x, want %x", buf[:n], packet)
+	}
+}